@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vindexchain/core/internal/admin"
+	"github.com/vindexchain/core/internal/config"
+)
+
+func adminCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "admin",
+		Short: "Operate a running node through its admin API",
+		Long: `Operate a running node through the admin listener at
+VINDEX_ADMIN_LISTEN_ADDR, a unix socket by default. Over TCP the listener
+requires an API key or JWT with the admin scope, sent with --token.`,
+	}
+	cmd.PersistentFlags().String("addr", "", "admin listener, unix://path or host:port (defaults to VINDEX_ADMIN_LISTEN_ADDR)")
+	cmd.PersistentFlags().String("token", "", "admin API key or JWT for TCP listeners (defaults to VINDEX_ADMIN_API_KEY)")
+	cmd.PersistentFlags().Duration("timeout", 5*time.Minute, "how long to wait for the node")
+
+	prune := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete old state versions now",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			keepRecent, _ := cmd.Flags().GetInt64("keep-recent")
+			return adminRequest(cmd, http.MethodPost, "/admin/prune", map[string]int64{"keep_recent": keepRecent})
+		},
+	}
+	prune.Flags().Int64("keep-recent", 0, "versions to keep, the latest included (defaults to the pruning options)")
+
+	pprof := &cobra.Command{
+		Use:   "pprof [profile]",
+		Short: "Save a pprof profile: profile (CPU), heap, goroutine, allocs, block, mutex or trace",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			seconds, _ := cmd.Flags().GetInt("seconds")
+			output, _ := cmd.Flags().GetString("output")
+			if output == "" {
+				output = args[0] + ".pprof"
+			}
+			path := "/debug/pprof/" + args[0]
+			if args[0] == "profile" || args[0] == "trace" {
+				path += "?seconds=" + strconv.Itoa(seconds)
+			}
+			resp, err := adminDo(cmd, http.MethodGet, path, nil)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode >= 400 {
+				return printResponse(resp)
+			}
+			f, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, resp.Body); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+			fmt.Printf("Saved %s profile to %s\n", args[0], output)
+			return nil
+		},
+	}
+	pprof.Flags().Int("seconds", 30, "duration of CPU profiles and traces")
+	pprof.Flags().StringP("output", "o", "", "output file (defaults to [profile].pprof)")
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "status",
+			Short: "Show the height, halt height, mempool acceptance and log level",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return adminRequest(cmd, http.MethodGet, "/admin/status", nil)
+			},
+		},
+		&cobra.Command{
+			Use:   "halt [height]",
+			Short: "Stop the node after committing a height; 0 cancels",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				height, err := strconv.ParseInt(args[0], 10, 64)
+				if err != nil || height < 0 {
+					return fmt.Errorf("invalid height %q", args[0])
+				}
+				return adminRequest(cmd, http.MethodPost, "/admin/halt", map[string]int64{"height": height})
+			},
+		},
+		&cobra.Command{
+			Use:       "mempool [on|off]",
+			Short:     "Start or stop accepting new transactions",
+			Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+			ValidArgs: []string{"on", "off"},
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return adminRequest(cmd, http.MethodPost, "/admin/mempool", map[string]bool{"accept": args[0] == "on"})
+			},
+		},
+		&cobra.Command{
+			Use:   "snapshot",
+			Short: "Take a state snapshot at the latest height",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return adminRequest(cmd, http.MethodPost, "/admin/snapshot", nil)
+			},
+		},
+		prune,
+		&cobra.Command{
+			Use:   "log-level [level]",
+			Short: "Change the log level: debug, info, warn or error",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return adminRequest(cmd, http.MethodPut, "/admin/log-level", map[string]string{"level": args[0]})
+			},
+		},
+		&cobra.Command{
+			Use:   "reload",
+			Short: "Reload the log level and rate limits from the node's --config file",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return adminRequest(cmd, http.MethodPost, "/admin/reload", nil)
+			},
+		},
+		&cobra.Command{
+			Use:   "goroutines",
+			Short: "Print the stacks of all goroutines",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				resp, err := adminDo(cmd, http.MethodGet, "/debug/pprof/goroutine?debug=2", nil)
+				if err != nil {
+					return err
+				}
+				defer resp.Body.Close()
+				if resp.StatusCode >= 400 {
+					return printResponse(resp)
+				}
+				_, err = io.Copy(os.Stdout, resp.Body)
+				return err
+			},
+		},
+		pprof,
+	)
+	return cmd
+}
+
+// adminRequest sends body as JSON to the admin API and prints the response
+func adminRequest(cmd *cobra.Command, method, path string, body interface{}) error {
+	resp, err := adminDo(cmd, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return printResponse(resp)
+}
+
+// adminDo sends a request to the admin listener named by --addr or the
+// config
+func adminDo(cmd *cobra.Command, method, path string, body interface{}) (*http.Response, error) {
+	addr, _ := cmd.Flags().GetString("addr")
+	token, _ := cmd.Flags().GetString("token")
+	if addr == "" || token == "" {
+		cfg := config.LoadConfig()
+		if addr == "" {
+			addr = cfg.AdminListenAddr
+		}
+		if token == "" {
+			token = cfg.AdminAPIKey
+		}
+	}
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		payload = bytes.NewReader(data)
+	}
+	// The host is ignored; the dialer connects to addr
+	req, err := http.NewRequest(method, "http://admin"+path, payload)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" && !admin.IsUnix(addr) {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: admin.Dialer(addr)},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the admin API at %s: %w", addr, err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vindexchain/core/internal/accounts"
+)
+
+const (
+	defaultNodeURL    = "http://localhost:1317"
+	defaultKeyringDir = "./config/keys"
+)
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// addNodeFlag adds the --node flag used by commands that talk to a running node
+func addNodeFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().String("node", defaultNodeURL, "REST API address of the node")
+}
+
+// addKeyringFlag adds the --keyring-dir flag used by commands that sign
+func addKeyringFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().String("keyring-dir", defaultKeyringDir, "directory holding the account keys")
+}
+
+// signingKey loads an account key from the keyring by name
+func signingKey(cmd *cobra.Command, name string) (*accounts.Key, error) {
+	dir, _ := cmd.Flags().GetString("keyring-dir")
+	if dir == "" {
+		dir = defaultKeyringDir
+	}
+	return accounts.LoadKey(dir, name)
+}
+
+func nodeURL(cmd *cobra.Command, path string) string {
+	node, _ := cmd.Flags().GetString("node")
+	if node == "" {
+		node = defaultNodeURL
+	}
+	return strings.TrimRight(node, "/") + "/api/v1" + path
+}
+
+// postJSON posts body to the node API and prints the response
+func postJSON(cmd *cobra.Command, path string, body interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := httpClient.Post(nodeURL(cmd, path), "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to reach node: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return printResponse(resp)
+}
+
+// postSigned signs msg with key at the account's next sequence, as the node
+// reports it, and posts the signed message to the node API
+func postSigned(cmd *cobra.Command, path string, key *accounts.Key, msg accounts.Msg) error {
+	var account struct {
+		Sequence uint64 `json:"sequence"`
+	}
+	if err := fetchJSON(cmd, "/accounts/"+key.Address()+"/sequence", &account); err != nil {
+		return err
+	}
+	signed, err := key.Sign(ChainID, account.Sequence, msg)
+	if err != nil {
+		return err
+	}
+	return postJSON(cmd, path, signed)
+}
+
+// getJSON queries the node API and prints the response
+func getJSON(cmd *cobra.Command, path string) error {
+	resp, err := httpClient.Get(nodeURL(cmd, path))
+	if err != nil {
+		return fmt.Errorf("failed to reach node: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return printResponse(resp)
+}
+
+// fetchJSON queries the node API and decodes the response into out
+func fetchJSON(cmd *cobra.Command, path string, out interface{}) error {
+	resp, err := httpClient.Get(nodeURL(cmd, path))
+	if err != nil {
+		return fmt.Errorf("failed to reach node: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var body struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&body) == nil && body.Error != "" {
+			return fmt.Errorf("node returned %s: %s", resp.Status, body.Error)
+		}
+		return fmt.Errorf("node returned %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func printResponse(resp *http.Response) error {
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	if json.Indent(&out, data, "", "  ") != nil {
+		out.Reset()
+		out.Write(data)
+	}
+	fmt.Fprintln(os.Stdout, out.String())
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("node returned %s", resp.Status)
+	}
+	return nil
+}
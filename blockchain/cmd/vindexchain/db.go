@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vindexchain/core/internal/config"
+	"github.com/vindexchain/core/internal/database"
+)
+
+const migrationTimeout = 10 * time.Minute
+
+func dbCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Manage the indexer database",
+	}
+	cmd.PersistentFlags().String("database-url", "", "database to manage (defaults to VINDEX_DATABASE_URL)")
+
+	migrate := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply, roll back and inspect schema migrations",
+		Long: `Manage the SQL schema of the indexer database. Migrations are embedded in
+the binary, applied in version order and recorded with their checksums in
+the schema_migrations table. Only SQL backends (postgres://) have a schema;
+file:// and mem:// need no migrations.`,
+	}
+
+	down := &cobra.Command{
+		Use:     "down",
+		Aliases: []string{"rollback"},
+		Short:   "Roll back the latest applied migrations",
+		Long: `Roll back the latest applied migrations in reverse version order.
+Migrations without a down file cannot be rolled back; 0001_kv_store holds
+the chain's key-value data on the Postgres backend and is never dropped.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			steps, _ := cmd.Flags().GetInt("steps")
+			if steps < 1 {
+				return fmt.Errorf("--steps must be at least 1")
+			}
+			return withMigrator(cmd, func(ctx context.Context, migrator *database.Migrator) error {
+				rolledBack, err := migrator.Down(ctx, steps)
+				for _, m := range rolledBack {
+					fmt.Printf("Rolled back %04d_%s\n", m.Version, m.Name)
+				}
+				if err == nil && len(rolledBack) == 0 {
+					fmt.Println("No migrations to roll back")
+				}
+				return err
+			})
+		},
+	}
+	down.Flags().Int("steps", 1, "number of migrations to roll back")
+
+	migrate.AddCommand(
+		&cobra.Command{
+			Use:   "up [version]",
+			Short: "Apply pending migrations, up to version if given",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				var target int64
+				if len(args) == 1 {
+					var err error
+					if target, err = strconv.ParseInt(args[0], 10, 64); err != nil || target <= 0 {
+						return fmt.Errorf("invalid version %q", args[0])
+					}
+				}
+				return withMigrator(cmd, func(ctx context.Context, migrator *database.Migrator) error {
+					applied, err := migrator.Up(ctx, target)
+					for _, m := range applied {
+						fmt.Printf("Applied %04d_%s\n", m.Version, m.Name)
+					}
+					if err == nil && len(applied) == 0 {
+						fmt.Println("Schema is up to date")
+					}
+					return err
+				})
+			},
+		},
+		down,
+		&cobra.Command{
+			Use:   "status",
+			Short: "List migrations and whether they are applied",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return withMigrator(cmd, func(ctx context.Context, migrator *database.Migrator) error {
+					statuses, err := migrator.Status(ctx)
+					if err != nil {
+						return err
+					}
+					w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+					fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED\tCHECKSUM")
+					for _, s := range statuses {
+						status, applied := "pending", "-"
+						if s.AppliedAt != nil {
+							status, applied = "applied", s.AppliedAt.Format("2006-01-02 15:04:05")
+						}
+						switch {
+						case s.Missing:
+							status = "unknown"
+						case s.Modified:
+							status = "modified"
+						}
+						fmt.Fprintf(w, "%04d\t%s\t%s\t%s\t%.12s\n", s.Version, s.Name, status, applied, s.Checksum)
+					}
+					return w.Flush()
+				})
+			},
+		},
+		&cobra.Command{
+			Use:   "verify",
+			Short: "Check that applied migrations match this binary and none are pending",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return withMigrator(cmd, func(ctx context.Context, migrator *database.Migrator) error {
+					if err := migrator.Verify(ctx, true); err != nil {
+						return err
+					}
+					fmt.Println("Schema is up to date and matches this binary")
+					return nil
+				})
+			},
+		},
+	)
+
+	cmd.AddCommand(migrate)
+	return cmd
+}
+
+// withMigrator opens the database named by --database-url or the config and
+// runs fn with its migrator
+func withMigrator(cmd *cobra.Command, fn func(ctx context.Context, migrator *database.Migrator) error) error {
+	url, _ := cmd.Flags().GetString("database-url")
+	if url == "" {
+		url = config.LoadConfig().DatabaseURL
+	}
+	db, err := database.Initialize(url)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := database.NewMigrator(db, logger)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), migrationTimeout)
+	defer cancel()
+	return fn(ctx, migrator)
+}
+
+// prepareDatabase brings the SQL schema up to date when autoMigrate is set,
+// and otherwise refuses to start on a schema that is outdated or was
+// changed. Backends without SQL need neither.
+func prepareDatabase(db database.Database, autoMigrate bool) error {
+	migrator, err := database.NewMigrator(db, logger)
+	if errors.Is(err, database.ErrNoSQL) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), migrationTimeout)
+	defer cancel()
+	if !autoMigrate {
+		return migrator.Verify(ctx, true)
+	}
+	_, err = migrator.Up(ctx, 0)
+	return err
+}
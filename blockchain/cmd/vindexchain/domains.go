@@ -0,0 +1,427 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vindexchain/core/internal/bank"
+	"github.com/vindexchain/core/internal/domains"
+)
+
+func domainsTxCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "domains",
+		Short: ".vindex name service transaction subcommands",
+	}
+
+	register := &cobra.Command{
+		Use:   "register [owner-key] [name]",
+		Short: "Register a .vindex name",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := signingKey(cmd, args[0])
+			if err != nil {
+				return err
+			}
+			years, _ := cmd.Flags().GetUint32("years")
+			msg := domains.MsgRegisterDomain{Owner: key.Address(), Name: args[1], Years: years}
+			if err := msg.ValidateBasic(); err != nil {
+				return err
+			}
+			return postSigned(cmd, "/domains/register", key, msg)
+		},
+	}
+	register.Flags().Uint32("years", 1, "registration period in years")
+
+	renew := &cobra.Command{
+		Use:   "renew [sender-key] [name]",
+		Short: "Extend a .vindex registration",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := signingKey(cmd, args[0])
+			if err != nil {
+				return err
+			}
+			years, _ := cmd.Flags().GetUint32("years")
+			msg := domains.MsgRenewDomain{Sender: key.Address(), Name: args[1], Years: years}
+			if err := msg.ValidateBasic(); err != nil {
+				return err
+			}
+			return postSigned(cmd, "/domains/renew", key, msg)
+		},
+	}
+	renew.Flags().Uint32("years", 1, "years to add to the registration")
+
+	claimReserved := &cobra.Command{
+		Use:   "claim-reserved [owner-key] [name] [signature]",
+		Short: "Register a reserved name with a claim authority signature",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := signingKey(cmd, args[0])
+			if err != nil {
+				return err
+			}
+			years, _ := cmd.Flags().GetUint32("years")
+			msg := domains.MsgClaimReservedName{Owner: key.Address(), Name: args[1], Years: years, Signature: args[2]}
+			if err := msg.ValidateBasic(); err != nil {
+				return err
+			}
+			return postSigned(cmd, "/domains/reserved/claim", key, msg)
+		},
+	}
+	claimReserved.Flags().Uint32("years", 1, "registration period the signature was issued for")
+
+	setRecords := &cobra.Command{
+		Use:   "set-records [sender-key] [name]",
+		Short: "Replace the records of a name or subdomain",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := signingKey(cmd, args[0])
+			if err != nil {
+				return err
+			}
+			address, _ := cmd.Flags().GetString("address")
+			addresses, _ := cmd.Flags().GetStringToString("chain-address")
+			text, _ := cmd.Flags().GetStringToString("text")
+			contentHash, _ := cmd.Flags().GetString("content-hash")
+
+			msg := domains.MsgSetRecords{
+				Sender: key.Address(),
+				Name:   args[1],
+				Records: domains.Records{
+					Address:     address,
+					Addresses:   addresses,
+					Text:        text,
+					ContentHash: contentHash,
+				},
+			}
+			if err := msg.ValidateBasic(); err != nil {
+				return err
+			}
+			return postSigned(cmd, "/domains/records", key, msg)
+		},
+	}
+	setRecords.Flags().String("address", "", "primary vindex1... address")
+	setRecords.Flags().StringToString("chain-address", nil, "addresses on other chains, e.g. eth=0xabc,btc=bc1q...")
+	setRecords.Flags().StringToString("text", nil, "text records, e.g. avatar=https://...,email=alice@example.com")
+	setRecords.Flags().String("content-hash", "", "content hash (ipfs://, ipns://, ar://, bzz:// or 0x...)")
+
+	commit := &cobra.Command{
+		Use:   "commit-bid [bidder-key] [name] [amount] [salt]",
+		Short: "Place a sealed bid in a premium name auction",
+		Long: `Place a sealed bid in a premium name auction. Only the commitment is
+sent; keep the amount and salt to reveal the bid later. The deposit may be
+larger than the bid to hide its real size.`,
+		Args: cobra.ExactArgs(4),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := signingKey(cmd, args[0])
+			if err != nil {
+				return err
+			}
+			amount, err := parseAmount(args[2])
+			if err != nil {
+				return err
+			}
+			deposit, _ := cmd.Flags().GetUint64("deposit")
+			if deposit == 0 {
+				deposit = amount
+			}
+			commitment, err := domains.BidCommitment(args[1], key.Address(), amount, args[3])
+			if err != nil {
+				return err
+			}
+			msg := domains.MsgCommitBid{Bidder: key.Address(), Name: args[1], Commitment: commitment, Deposit: deposit}
+			if err := msg.ValidateBasic(); err != nil {
+				return err
+			}
+			return postSigned(cmd, "/domains/auctions/commit", key, msg)
+		},
+	}
+	commit.Flags().Uint64("deposit", 0, "amount escrowed with the bid (defaults to the bid amount)")
+
+	cmd.AddCommand(
+		register,
+		renew,
+		claimReserved,
+		setRecords,
+		commit,
+		&cobra.Command{
+			Use:   "create-subdomain [sender-key] [name] [owner]",
+			Short: "Create a subdomain such as pay.alice.vindex",
+			Args:  cobra.ExactArgs(3),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				key, err := signingKey(cmd, args[0])
+				if err != nil {
+					return err
+				}
+				msg := domains.MsgCreateSubdomain{Sender: key.Address(), Name: args[1], Owner: args[2]}
+				if err := msg.ValidateBasic(); err != nil {
+					return err
+				}
+				return postSigned(cmd, "/domains/subdomains/create", key, msg)
+			},
+		},
+		&cobra.Command{
+			Use:   "delete-subdomain [sender-key] [name]",
+			Short: "Delete a subdomain",
+			Args:  cobra.ExactArgs(2),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				key, err := signingKey(cmd, args[0])
+				if err != nil {
+					return err
+				}
+				msg := domains.MsgDeleteSubdomain{Sender: key.Address(), Name: args[1]}
+				if err := msg.ValidateBasic(); err != nil {
+					return err
+				}
+				return postSigned(cmd, "/domains/subdomains/delete", key, msg)
+			},
+		},
+		&cobra.Command{
+			Use:   "set-primary [sender-key] [name]",
+			Short: "Set the name returned by reverse lookups of the sender",
+			Args:  cobra.ExactArgs(2),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				key, err := signingKey(cmd, args[0])
+				if err != nil {
+					return err
+				}
+				msg := domains.MsgSetPrimaryName{Sender: key.Address(), Name: args[1]}
+				if err := msg.ValidateBasic(); err != nil {
+					return err
+				}
+				return postSigned(cmd, "/domains/primary", key, msg)
+			},
+		},
+		&cobra.Command{
+			Use:   "transfer [sender-key] [name] [recipient]",
+			Short: "Transfer a name to another address",
+			Args:  cobra.ExactArgs(3),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				key, err := signingKey(cmd, args[0])
+				if err != nil {
+					return err
+				}
+				msg := domains.MsgTransferDomain{Sender: key.Address(), Name: args[1], Recipient: args[2]}
+				if err := msg.ValidateBasic(); err != nil {
+					return err
+				}
+				return postSigned(cmd, "/domains/transfer", key, msg)
+			},
+		},
+		&cobra.Command{
+			Use:   "list [sender-key] [name] [price]",
+			Short: "List a name for sale at a fixed price",
+			Args:  cobra.ExactArgs(3),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				key, err := signingKey(cmd, args[0])
+				if err != nil {
+					return err
+				}
+				price, err := parseAmount(args[2])
+				if err != nil {
+					return err
+				}
+				msg := domains.MsgListDomain{Sender: key.Address(), Name: args[1], Price: price}
+				if err := msg.ValidateBasic(); err != nil {
+					return err
+				}
+				return postSigned(cmd, "/domains/list", key, msg)
+			},
+		},
+		&cobra.Command{
+			Use:   "cancel-listing [sender-key] [name]",
+			Short: "Withdraw a name from sale",
+			Args:  cobra.ExactArgs(2),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				key, err := signingKey(cmd, args[0])
+				if err != nil {
+					return err
+				}
+				msg := domains.MsgCancelListing{Sender: key.Address(), Name: args[1]}
+				if err := msg.ValidateBasic(); err != nil {
+					return err
+				}
+				return postSigned(cmd, "/domains/listings/cancel", key, msg)
+			},
+		},
+		&cobra.Command{
+			Use:   "buy [buyer-key] [name] [max-price]",
+			Short: "Buy a listed name",
+			Args:  cobra.ExactArgs(3),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				key, err := signingKey(cmd, args[0])
+				if err != nil {
+					return err
+				}
+				maxPrice, err := parseAmount(args[2])
+				if err != nil {
+					return err
+				}
+				msg := domains.MsgBuyDomain{Buyer: key.Address(), Name: args[1], MaxPrice: maxPrice}
+				if err := msg.ValidateBasic(); err != nil {
+					return err
+				}
+				return postSigned(cmd, "/domains/buy", key, msg)
+			},
+		},
+		&cobra.Command{
+			Use:   "start-auction [sender-key] [name]",
+			Short: "Open a sealed-bid auction for a premium name",
+			Args:  cobra.ExactArgs(2),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				key, err := signingKey(cmd, args[0])
+				if err != nil {
+					return err
+				}
+				msg := domains.MsgStartAuction{Sender: key.Address(), Name: args[1]}
+				if err := msg.ValidateBasic(); err != nil {
+					return err
+				}
+				return postSigned(cmd, "/domains/auctions/start", key, msg)
+			},
+		},
+		&cobra.Command{
+			Use:   "reveal-bid [bidder-key] [name] [amount] [salt]",
+			Short: "Reveal a sealed bid",
+			Args:  cobra.ExactArgs(4),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				key, err := signingKey(cmd, args[0])
+				if err != nil {
+					return err
+				}
+				amount, err := parseAmount(args[2])
+				if err != nil {
+					return err
+				}
+				msg := domains.MsgRevealBid{Bidder: key.Address(), Name: args[1], Amount: amount, Salt: args[3]}
+				if err := msg.ValidateBasic(); err != nil {
+					return err
+				}
+				return postSigned(cmd, "/domains/auctions/reveal", key, msg)
+			},
+		},
+		&cobra.Command{
+			Use:   "finalize-auction [sender-key] [name]",
+			Short: "Settle an auction whose reveal window has closed",
+			Args:  cobra.ExactArgs(2),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				key, err := signingKey(cmd, args[0])
+				if err != nil {
+					return err
+				}
+				msg := domains.MsgFinalizeAuction{Sender: key.Address(), Name: args[1]}
+				if err := msg.ValidateBasic(); err != nil {
+					return err
+				}
+				return postSigned(cmd, "/domains/auctions/finalize", key, msg)
+			},
+		},
+	)
+
+	return cmd
+}
+
+func domainsQueryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "domains",
+		Short: ".vindex name service query subcommands",
+	}
+
+	price := &cobra.Command{
+		Use:   "price [name]",
+		Short: "Show the registration and renewal price of a name",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			years, _ := cmd.Flags().GetUint32("years")
+			return getJSON(cmd, fmt.Sprintf("/domains/%s/price?years=%d", url.PathEscape(args[0]), years))
+		},
+	}
+	price.Flags().Uint32("years", 1, "registration period in years")
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "domain [name]",
+			Short: "Show a name and whether it can be registered",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return getJSON(cmd, "/domains/"+url.PathEscape(args[0]))
+			},
+		},
+		&cobra.Command{
+			Use:   "owned [owner]",
+			Short: "List the names held by an address",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return getJSON(cmd, "/domains?owner="+url.QueryEscape(args[0]))
+			},
+		},
+		&cobra.Command{
+			Use:   "resolve [name]",
+			Short: "Resolve a name or subdomain to its records",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return getJSON(cmd, "/domains/resolve/"+url.PathEscape(args[0]))
+			},
+		},
+		&cobra.Command{
+			Use:   "reverse [address]",
+			Short: "Show the primary name of an address",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return getJSON(cmd, "/domains/reverse/"+url.PathEscape(args[0]))
+			},
+		},
+		&cobra.Command{
+			Use:   "listings",
+			Short: "List names for sale",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return getJSON(cmd, "/domains/listings")
+			},
+		},
+		&cobra.Command{
+			Use:   "auctions",
+			Short: "List open premium name auctions",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return getJSON(cmd, "/domains/auctions")
+			},
+		},
+		&cobra.Command{
+			Use:   "reserved",
+			Short: "List reserved and trademark-protected names",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return getJSON(cmd, "/domains/reserved")
+			},
+		},
+		price,
+	)
+
+	return cmd
+}
+
+// resolveRecipient accepts either a vindex1 address or a .vindex name and
+// returns the address to send to
+func resolveRecipient(cmd *cobra.Command, recipient string) (string, error) {
+	if strings.HasPrefix(recipient, "vindex1") {
+		return recipient, bank.ValidateAddress(recipient)
+	}
+	if _, err := domains.ParseName(recipient); err != nil {
+		return "", fmt.Errorf("recipient %q is neither an address nor a .vindex name", recipient)
+	}
+
+	var resolution domains.Resolution
+	if err := fetchJSON(cmd, "/domains/resolve/"+url.PathEscape(recipient), &resolution); err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", recipient, err)
+	}
+	if resolution.Records.Address == "" {
+		return "", fmt.Errorf("%s has no address record", resolution.Name)
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Resolved %s to %s\n", resolution.Name, resolution.Records.Address)
+	return resolution.Records.Address, nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/core/internal/light"
+	"github.com/vindexchain/core/internal/types"
+)
+
+const lightUpdateInterval = 30 * time.Second
+
+func lightCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "light [chain-id]",
+		Short: "Run a light client proxy that verifies every response",
+		Long: `Run a light client that verifies headers from a primary node, starting from
+a header you trust, and cross-checks them with witness nodes. It serves the
+node API on --laddr: queries are answered only with values whose Merkle
+proofs check out against a verified app hash, and transactions are forwarded
+to the primary.
+
+Get the trusted height and hash from a source you trust, not from the
+primary itself.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runLight,
+	}
+	cmd.Flags().String("primary", defaultNodeURL, "REST API address of the node to fetch headers and proofs from")
+	cmd.Flags().StringSlice("witnesses", nil, "REST API addresses of nodes to cross-check headers with")
+	cmd.Flags().Int64("trusted-height", 0, "height of the trusted header")
+	cmd.Flags().String("trusted-hash", "", "hex hash of the trusted header")
+	cmd.Flags().Duration("trusting-period", 14*24*time.Hour, "how long a verified header can be trusted; must be shorter than the unbonding period")
+	cmd.Flags().String("trust-level", "1/3", "share of the trusted validators that must sign a header to skip to it")
+	cmd.Flags().Bool("sequential", false, "verify every header instead of skipping")
+	cmd.Flags().String("laddr", "127.0.0.1:8888", "address to serve the verified API on")
+	cmd.Flags().String("store", "./data/light/lightblocks.json", "file to keep verified headers in")
+
+	return cmd
+}
+
+func runLight(cmd *cobra.Command, args []string) error {
+	chainID := ChainID
+	if len(args) == 1 {
+		chainID = args[0]
+	}
+	primaryURL, _ := cmd.Flags().GetString("primary")
+	witnessURLs, _ := cmd.Flags().GetStringSlice("witnesses")
+	trustedHeight, _ := cmd.Flags().GetInt64("trusted-height")
+	trustedHashHex, _ := cmd.Flags().GetString("trusted-hash")
+	trustingPeriod, _ := cmd.Flags().GetDuration("trusting-period")
+	trustLevelStr, _ := cmd.Flags().GetString("trust-level")
+	sequential, _ := cmd.Flags().GetBool("sequential")
+	laddr, _ := cmd.Flags().GetString("laddr")
+	storePath, _ := cmd.Flags().GetString("store")
+
+	trustedHash, err := hex.DecodeString(strings.TrimPrefix(trustedHashHex, "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid trusted hash: %w", err)
+	}
+	trustLevel, err := parseTrustLevel(trustLevelStr)
+	if err != nil {
+		return err
+	}
+	mode := light.ModeSkipping
+	if sequential {
+		mode = light.ModeSequential
+	}
+
+	witnesses := make([]light.Provider, 0, len(witnessURLs))
+	for _, url := range witnessURLs {
+		witnesses = append(witnesses, light.NewHTTPProvider(chainID, url))
+	}
+	store, err := light.NewStore(storePath, 0)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	client, err := light.NewClient(ctx, &light.Config{
+		ChainID: chainID,
+		TrustOptions: light.TrustOptions{
+			Period: trustingPeriod,
+			Height: trustedHeight,
+			Hash:   trustedHash,
+		},
+		Primary:    light.NewHTTPProvider(chainID, primaryURL),
+		Witnesses:  witnesses,
+		Store:      store,
+		Mode:       mode,
+		TrustLevel: trustLevel,
+		Logger:     logger,
+	})
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to start light client: %w", err)
+	}
+
+	proxy, err := light.NewProxy(&light.ProxyConfig{
+		Client:     client,
+		PrimaryURL: primaryURL,
+		Logger:     logger,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Keep the trusted header fresh so it does not expire between queries
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(lightUpdateInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), lightUpdateInterval)
+				if _, err := client.Update(ctx, time.Now()); err != nil {
+					logger.Warn("Failed to update light client", zap.Error(err))
+					if errors.Is(err, light.ErrLightClientAttack) {
+						logger.Error("Fork detected; stop trusting the primary and the disagreeing witness until resolved")
+					}
+				}
+				cancel()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	server := &http.Server{Addr: laddr, Handler: proxy}
+	go func() {
+		logger.Info("Light client proxy listening",
+			zap.String("addr", laddr),
+			zap.String("chain_id", chainID),
+			zap.String("primary", primaryURL),
+			zap.Int("witnesses", len(witnesses)),
+		)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Fatal("Light client proxy failed", zap.Error(err))
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	close(done)
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutdownCancel()
+	return server.Shutdown(shutdownCtx)
+}
+
+// parseTrustLevel parses a fraction such as "1/3"
+func parseTrustLevel(s string) (types.Fraction, error) {
+	num, den, ok := strings.Cut(s, "/")
+	if !ok {
+		return types.Fraction{}, fmt.Errorf("invalid trust level %q, expected a fraction such as 1/3", s)
+	}
+	n, err1 := strconv.ParseInt(num, 10, 64)
+	d, err2 := strconv.ParseInt(den, 10, 64)
+	if err1 != nil || err2 != nil {
+		return types.Fraction{}, fmt.Errorf("invalid trust level %q", s)
+	}
+	lvl := types.Fraction{Numerator: n, Denominator: d}
+	return lvl, light.ValidateTrustLevel(lvl)
+}
@@ -2,30 +2,48 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 
+	"github.com/vindexchain/core/internal/accounts"
+	"github.com/vindexchain/core/internal/admin"
+	"github.com/vindexchain/core/internal/app"
 	"github.com/vindexchain/core/internal/api"
+	"github.com/vindexchain/core/internal/auth"
+	"github.com/vindexchain/core/internal/bank"
 	"github.com/vindexchain/core/internal/blockchain"
+	"github.com/vindexchain/core/internal/blocksync"
+	"github.com/vindexchain/core/internal/cache"
 	"github.com/vindexchain/core/internal/config"
 	"github.com/vindexchain/core/internal/consensus"
 	"github.com/vindexchain/core/internal/database"
+	"github.com/vindexchain/core/internal/dex"
 	"github.com/vindexchain/core/internal/domains"
+	"github.com/vindexchain/core/internal/indexer"
+	"github.com/vindexchain/core/internal/kv"
 	"github.com/vindexchain/core/internal/monitoring"
 	"github.com/vindexchain/core/internal/p2p"
+	"github.com/vindexchain/core/internal/service"
+	"github.com/vindexchain/core/internal/snapshots"
 	"github.com/vindexchain/core/internal/staking"
+	"github.com/vindexchain/core/internal/state"
+	"github.com/vindexchain/core/internal/statesync"
+	"github.com/vindexchain/core/internal/tlsutil"
 	"github.com/vindexchain/core/internal/tokens"
+	"github.com/vindexchain/core/internal/wal"
 	"github.com/vindexchain/core/internal/websocket"
 )
 
@@ -44,14 +62,17 @@ const (
 )
 
 var (
-	logger *zap.Logger
-	cfg    *config.Config
+	logger   *zap.Logger
+	logLevel = zap.NewAtomicLevel() // changed at runtime through the admin API
+	cfg      *config.Config
 )
 
 func main() {
 	// Initialize logger
 	var err error
-	logger, err = zap.NewProduction()
+	logConfig := zap.NewProductionConfig()
+	logConfig.Level = logLevel
+	logger, err = logConfig.Build()
 	if err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
@@ -64,7 +85,7 @@ func main() {
 		Long: `VindexChain is a next-generation Proof of Stake blockchain
 designed for high performance, low fees, and regulatory compliance.
 Built specifically for the Puerto Rican market while maintaining global accessibility.`,
-		Run: runNode,
+		RunE: runNode,
 	}
 
 	// Add subcommands
@@ -72,9 +93,15 @@ Built specifically for the Puerto Rican market while maintaining global accessib
 		initCmd(),
 		startCmd(),
 		versionCmd(),
+		showNodeIDCmd(),
 		keysCmd(),
 		txCmd(),
 		queryCmd(),
+		snapshotsCmd(),
+		lightCmd(),
+		dbCmd(),
+		apikeysCmd(),
+		adminCmd(),
 	)
 
 	// Add flags
@@ -93,28 +120,121 @@ Built specifically for the Puerto Rican market while maintaining global accessib
 	}
 }
 
-func runNode(cmd *cobra.Command, args []string) {
+func runNode(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
 	logger.Info("Starting VindexChain node", 
 		zap.String("chain_id", ChainID),
 		zap.String("version", "1.0.0"),
 	)
 
-	// Load configuration
+	// Load configuration, from the environment and the --config file
+	configFile, _ := cmd.Flags().GetString("config")
+	if configFile != "" {
+		if err := config.LoadEnvFile(configFile); err != nil {
+			return fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
 	cfg = config.LoadConfig()
+	if seedMode, err := cmd.Flags().GetBool("seed-mode"); err == nil && seedMode {
+		cfg.SeedMode = true
+	}
+	if cmd.Flags().Changed("block-sync") {
+		cfg.BlockSync, _ = cmd.Flags().GetBool("block-sync")
+	}
+	if cmd.Flags().Changed("state-sync") {
+		cfg.StateSync, _ = cmd.Flags().GetBool("state-sync")
+	}
+	if cmd.Flags().Changed("pruning") {
+		cfg.Pruning, _ = cmd.Flags().GetString("pruning")
+	}
+	if cmd.Flags().Changed("database-url") {
+		cfg.DatabaseURL, _ = cmd.Flags().GetString("database-url")
+	}
+	if cmd.Flags().Changed("auto-migrate") {
+		cfg.AutoMigrate, _ = cmd.Flags().GetBool("auto-migrate")
+	}
+	if cmd.Flags().Changed("tx-index") {
+		cfg.TxIndex, _ = cmd.Flags().GetBool("tx-index")
+	}
+	if cmd.Flags().Changed("tx-index-keys") {
+		cfg.TxIndexKeys, _ = cmd.Flags().GetString("tx-index-keys")
+	}
+	if cmd.Flags().Changed("log-level") {
+		cfg.LogLevel, _ = cmd.Flags().GetString("log-level")
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	if err := logLevel.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		return fmt.Errorf("invalid log level: %w", err)
+	}
+	trustHash, err := hex.DecodeString(strings.TrimPrefix(cfg.StateSyncTrustHash, "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid state sync trust hash: %w", err)
+	}
 	
-	// Initialize database
+	// Services start after those they depend on and stop in reverse
+	// order; a failing service shuts the node down cleanly
+	supervisor := service.NewSupervisor(&service.Config{
+		StopTimeout: cfg.ServiceStopTimeout,
+		Logger:      logger,
+	})
+	
+	// Initialize the indexer database. It only serves queries over indexed
+	// blocks and events; the state store is the source of truth. The URL
+	// scheme picks the backend: postgres://, file:// or mem://.
 	db, err := database.Initialize(cfg.DatabaseURL)
 	if err != nil {
-		logger.Fatal("Failed to initialize database", zap.Error(err))
+		return fmt.Errorf("failed to initialize database: %w", err)
 	}
 	defer db.Close()
+	if err := prepareDatabase(db, cfg.AutoMigrate); err != nil {
+		return fmt.Errorf("database schema is not ready: %w", err)
+	}
 
+	// Initialize the DEX trade and candle indexer. It persists the trades of
+	// every block before the state commits it.
+	dexIndexer := indexer.NewDexIndexer(&indexer.Config{
+		Database: db,
+		Logger:   logger,
+	})
+
+	// Open the consensus write-ahead log, then the authenticated
+	// application state, which logs the start of every block to it and
+	// indexes the block's DEX trades before committing; the app hash of
+	// every block is the state root
+	consensusWAL, err := wal.Open(&wal.Config{Path: cfg.ConsensusWALFile, Logger: logger})
+	if err != nil {
+		return fmt.Errorf("failed to open consensus write-ahead log: %w", err)
+	}
+	defer consensusWAL.Close() // for returns before the supervisor starts
+	supervisor.Add(&service.Service{
+		Name: "wal",
+		Stop: func(context.Context) error { return consensusWAL.Close() },
+	})
+	appState, err := openStateStore(cfg, func(version int64) error {
+		if err := consensusWAL.BeginBlock(version); err != nil {
+			return err
+		}
+		return dexIndexer.Flush(version)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open state store: %w", err)
+	}
+	defer appState.Close()
+	
 	// Initialize monitoring
 	monitoring := monitoring.NewMonitoring(&monitoring.Config{
 		PrometheusAddr: ":8080",
 		Logger:         logger,
 	})
-	go monitoring.Start()
+	supervisor.Add(&service.Service{
+		Name: "monitoring",
+		Run: func(context.Context) error {
+			monitoring.Start()
+			return nil
+		},
+	})
 
 	// Initialize blockchain core
 	bc := blockchain.NewBlockchain(&blockchain.Config{
@@ -124,76 +244,330 @@ func runNode(cmd *cobra.Command, args []string) {
 		InitialSupply:   InitialSupply,
 		BlockTime:       BlockTime,
 		Database:        db,
+		State:           appState,
 		Logger:          logger,
 		AutoBurnRate:    AutoBurnRate,
 		AutoBurnThreshold: AutoBurnThreshold,
 	})
 
+	// A block cut off by a crash is rolled back from the state and the
+	// block store, and fetched and applied again once the node runs
+	if _, err := wal.Recover(consensusWAL, appState, bc, logger); err != nil {
+		return fmt.Errorf("failed to recover from the write-ahead log: %w", err)
+	}
+
 	// Initialize consensus engine
 	consensus := consensus.NewPoSConsensus(&consensus.Config{
 		MinValidators:   MinValidators,
 		MaxValidators:   MaxValidators,
 		UnbondingPeriod: UnbondingPeriod,
 		Blockchain:      bc,
+		WAL:             consensusWAL, // proposals and votes, replayed when restarting mid-height
 		Logger:          logger,
 	})
 
 	// Initialize staking module
 	stakingModule := staking.NewStakingModule(bc, consensus, logger)
 
+	// Initialize the ledger with the genesis allocations. It is the only
+	// source of balances; once the chain has committed a version the stored
+	// ledger replaces the allocations.
+	bankGenesis, err := bank.LoadGenesis(cfg.GenesisFile)
+	if err != nil {
+		return fmt.Errorf("failed to load bank genesis: %w", err)
+	}
+	bankKeeper := bank.NewKeeper(logger)
+	if err := bankKeeper.InitGenesis(bankGenesis); err != nil {
+		return fmt.Errorf("failed to allocate genesis balances: %w", err)
+	}
+
+	// Account sequences and the signatures of the messages accounts send
+	accountKeeper := accounts.NewKeeper(&accounts.Config{ChainID: ChainID, Logger: logger})
+
+	// Initialize DEX
+	swapFeeBps := uint64(30)    // 0.30% per hop
+	swapBurnShare := uint64(20) // 20% of swap fees burned
+	dexKeeper := dex.NewKeeper(bankKeeper, &dex.Config{
+		SwapFeeBps: &swapFeeBps,
+		BurnShare:  &swapBurnShare,
+		Logger:     logger,
+	})
+
+	dexKeeper.AddTradeListener(dexIndexer.HandleTrade)
+
+	// Initialize the transaction and event indexer. Only the configured
+	// event attributes are searchable; tx.hash and tx.height always are.
+	var txIndexer *indexer.TxIndexer
+	if cfg.TxIndex {
+		txIndexer = indexer.NewTxIndexer(&indexer.TxConfig{
+			DB:        db,
+			IndexKeys: strings.Split(cfg.TxIndexKeys, ","),
+			Logger:    logger,
+		})
+	}
+
 	// Initialize token factory
-	tokenFactory := tokens.NewTokenFactory(bc, &tokens.Config{
+	tokenFactory := tokens.NewTokenFactory(bankKeeper, &tokens.Config{
 		CreationFee:     100000000000, // $100 in OC$ (9 decimals)
 		LiquidityShare:  50,           // 50% to liquidity
 		ValidatorShare:  20,           // 20% to validators
 		DevTeamShare:    20,           // 20% to dev team
 		LPShare:         10,           // 10% to LP
+		FeeDenom:        NativeDenom,
+		Pools:           dexKeeper,
+		LiquidityLockPeriod: cfg.TokenLiquidityLockPeriod,
 		Logger:          logger,
 	})
 
-	// Initialize domain system
-	domainSystem := domains.NewDomainSystem(bc, &domains.Config{
+	// Initialize domain system with the reserved names from genesis. A
+	// genesis without a domains section starts without reserved names.
+	domainGenesis, err := domains.LoadGenesis(cfg.GenesisFile)
+	if err != nil {
+		return fmt.Errorf("failed to load domain genesis: %w", err)
+	}
+	domainSystem := domains.NewDomainSystem(bankKeeper, &domains.Config{
 		RegistrationFee: 1000000000, // 1 OC$ (9 decimals)
 		RenewalFee:      1000000000, // 1 OC$ per year
+		BurnShare:       10,         // 10% of name sales burned
+		Reserved:        domainGenesis.Reserved,
+		ClaimAuthority:  domainGenesis.ClaimAuthority,
+		GovernanceKey:   domainGenesis.GovernanceKey,
 		Logger:          logger,
 	})
 
+	// Keep the ledger, account sequences, tokens, pools and names in the
+	// state store, so they are part of the app hash, survive restarts and
+	// travel in snapshots. On a fresh chain the first commit stores their
+	// genesis state.
+	for _, module := range []struct {
+		prefix string
+		module state.Module
+	}{
+		{"bank/", bankKeeper},
+		{"sequences/", accountKeeper},
+		{"tokens/", tokenFactory},
+		{"dex/", dexKeeper},
+		{"domains/", domainSystem},
+	} {
+		if err := appState.RegisterModule(module.prefix, module.module); err != nil {
+			return fmt.Errorf("failed to load module state %s: %w", module.prefix, err)
+		}
+	}
+
+	// Module messages change state only inside blocks. The REST endpoints
+	// add signed messages to the mempool, proposers reap it into blocks,
+	// and every node executes a block's transactions at the block time
+	// before the block is committed.
+	txRouter := app.NewRouter(&app.Config{Accounts: accountKeeper, Logger: logger})
+	bank.RegisterRoutes(txRouter, bankKeeper)
+	tokens.RegisterRoutes(txRouter, tokenFactory)
+	dex.RegisterRoutes(txRouter, dexKeeper)
+	domains.RegisterRoutes(txRouter, domainSystem)
+	txMempool := app.NewMempool(&app.MempoolConfig{
+		Router:   txRouter,
+		Accounts: accountKeeper,
+		Logger:   logger,
+	})
+	bc.SetTxExecutor(txRouter)
+	bc.SetMempool(txMempool)
+	bc.AddBlockResultsListener(txMempool.Update)
+
+	// TLS certificates for the API, WebSocket and P2P listeners, reloaded
+	// from disk when they are rotated
+	var tlsCerts *tlsutil.Reloader
+	if cfg.TLSEnable || cfg.P2PTLS {
+		tlsCerts, err = tlsutil.NewReloader(&tlsutil.Config{
+			CertFile:     cfg.TLSCertFile,
+			KeyFile:      cfg.TLSKeyFile,
+			ClientCAFile: cfg.TLSClientCAFile,
+			Logger:       logger,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+	}
+
 	// Initialize P2P network
+	nodeKey, err := p2p.LoadOrGenNodeKey(cfg.NodeKeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load node key: %w", err)
+	}
+	// Sentries and validators can require mutual TLS from every peer
+	var p2pTLSServer, p2pTLSClient *tls.Config
+	if cfg.P2PTLS {
+		if p2pTLSServer, err = tlsCerts.ServerConfig(tls.RequireAndVerifyClientCert); err != nil {
+			return fmt.Errorf("invalid P2P TLS configuration: %w", err)
+		}
+		if p2pTLSClient, err = tlsCerts.ClientConfig(); err != nil {
+			return fmt.Errorf("invalid P2P TLS configuration: %w", err)
+		}
+	}
 	p2pNode := p2p.NewNode(&p2p.Config{
-		ListenAddr: cfg.P2PListenAddr,
-		ChainID:    ChainID,
-		Blockchain: bc,
-		Consensus:  consensus,
+		ListenAddr:      cfg.P2PListenAddr,
+		ExternalAddress: cfg.P2PExternalAddress,
+		ChainID:         ChainID,
+		Moniker:         cfg.Moniker,
+		Version:         "1.0.0",
+		NodeKey:         nodeKey,
+		PersistentPeers: cfg.PersistentPeers,
+		Seeds:           cfg.Seeds,
+		SeedMode:        cfg.SeedMode,
+		AddrBookFile:    cfg.AddrBookFile,
+
+		MaxNumInboundPeers:      cfg.MaxNumInboundPeers,
+		MaxNumOutboundPeers:     cfg.MaxNumOutboundPeers,
+		RateLimit:               p2p.RateLimit{SendRate: cfg.P2PSendRate, RecvRate: cfg.P2PRecvRate},
+		BanDuration:             cfg.P2PBanDuration,
+		MaxConcurrentHandshakes: cfg.P2PMaxHandshakes,
+		TLSServerConfig:         p2pTLSServer,
+		TLSClientConfig:         p2pTLSClient,
+
+		Logger: logger,
+	})
+
+	// Take periodic state snapshots and serve them to syncing peers
+	snapshotStore, err := snapshots.NewStore(cfg.SnapshotDir)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot store: %w", err)
+	}
+	snapshotManager := snapshots.NewManager(&snapshots.Config{
+		Store:       snapshotStore,
+		Snapshotter: appState,
+		Interval:    cfg.SnapshotInterval,
+		KeepRecent:  cfg.SnapshotKeepRecent,
+		Logger:      logger,
+	})
+	bc.AddCommitListener(snapshotManager.OnCommit)
+	if txIndexer != nil {
+		bc.AddBlockResultsListener(txIndexer.HandleBlockResults)
+	}
+
+	// Mark every block applied in the write-ahead log
+	bc.AddCommitListener(func(height int64) {
+		if err := consensusWAL.EndBlock(height, appState.AppHash()); err != nil {
+			supervisor.Fail("wal", err)
+		}
+	})
+
+	// Runtime node operations for the admin listener. Reaching the halt
+	// height shuts the node down like a signal does.
+	halted := make(chan int64, 1)
+	adminController := admin.NewController(&admin.Config{
+		State:      appState,
+		Snapshots:  snapshotManager,
+		LogLevel:   logLevel,
+		HaltHeight: cfg.HaltHeight,
+		OnHalt:     func(height int64) { halted <- height },
+		ConfigFile: configFile,
 		Logger:     logger,
 	})
+	bc.AddCommitListener(adminController.OnCommit)
+
+	// Download missing blocks from peers before joining consensus
+	blockSync := blocksync.NewReactor(&blocksync.Config{
+		ChainID: ChainID,
+		Chain:   bc,
+		Enabled: cfg.BlockSync,
+		WaitForStateSync: true,
+		SwitchToConsensus: func(height int64) {
+			logger.Info("Switching to consensus", zap.Int64("height", height))
+			go func() {
+				if err := consensus.Start(); err != nil {
+					supervisor.Fail("consensus", err)
+				}
+			}()
+		},
+		OnFailure: func(err error) {
+			supervisor.Fail("blocksync", err)
+		},
+		Logger: logger,
+	})
+	p2pNode.AddReactor(blockSync)
+
+	// Restore the latest verified snapshot on a fresh node, then hand over
+	// to block sync for the remaining blocks
+	stateSync := statesync.NewReactor(&statesync.Config{
+		ChainID:       ChainID,
+		Enabled:       cfg.StateSync,
+		TrustHeight:   cfg.StateSyncTrustHeight,
+		TrustHash:     trustHash,
+		DiscoveryTime: cfg.StateSyncDiscoveryTime,
+		Snapshots:     snapshotStore,
+		App:           appState,
+		Chain:         bc,
+		OnSynced: func(height int64) {
+			blockSync.SwitchToBlockSync()
+		},
+		Logger: logger,
+	})
+	p2pNode.AddReactor(stateSync)
 
 	// Initialize WebSocket server
 	wsServer := websocket.NewServer(bc, consensus, logger)
 
-	// Start blockchain services
-	go func() {
-		if err := bc.Start(); err != nil {
-			logger.Fatal("Failed to start blockchain", zap.Error(err))
-		}
-	}()
-
-	go func() {
-		if err := consensus.Start(); err != nil {
-			logger.Fatal("Failed to start consensus", zap.Error(err))
+	// The HTTP API and WebSocket verify client certificates when a client
+	// CA is configured, so admin routes can require them
+	var apiTLS *tls.Config
+	if cfg.TLSEnable {
+		clientAuth := tls.NoClientCert
+		if cfg.TLSClientCAFile != "" {
+			clientAuth = tls.VerifyClientCertIfGiven
 		}
-	}()
-
-	go func() {
-		if err := p2pNode.Start(); err != nil {
-			logger.Fatal("Failed to start P2P node", zap.Error(err))
+		if apiTLS, err = tlsCerts.ServerConfig(clientAuth); err != nil {
+			return fmt.Errorf("invalid TLS configuration: %w", err)
 		}
-	}()
+	}
 
-	go func() {
-		if err := wsServer.Start(cfg.WSListenAddr); err != nil {
-			logger.Fatal("Failed to start WebSocket server", zap.Error(err))
-		}
-	}()
+	// Blockchain services. Consensus is started by block sync once the
+	// node has caught up, and only stopped here.
+	supervisor.Add(&service.Service{
+		Name:  "dex-indexer",
+		Start: func(context.Context) error { return dexIndexer.Start() },
+		Stop: func(context.Context) error {
+			dexIndexer.Stop()
+			return nil
+		},
+	})
+	supervisor.Add(&service.Service{
+		Name:      "blockchain",
+		DependsOn: []string{"wal", "dex-indexer"},
+		Run:       func(context.Context) error { return bc.Start() },
+		Stop: func(context.Context) error {
+			bc.Stop()
+			return nil
+		},
+	})
+	supervisor.Add(&service.Service{
+		Name:      "p2p",
+		DependsOn: []string{"blockchain"},
+		Run:       func(context.Context) error { return p2pNode.Start() },
+		Stop: func(context.Context) error {
+			p2pNode.Stop()
+			return nil
+		},
+	})
+	supervisor.Add(&service.Service{
+		Name:      "consensus",
+		DependsOn: []string{"blockchain", "p2p"},
+		Stop: func(context.Context) error {
+			consensus.Stop()
+			return nil
+		},
+	})
+	supervisor.Add(&service.Service{
+		Name:      "websocket",
+		DependsOn: []string{"blockchain", "consensus"},
+		Run: func(context.Context) error {
+			if apiTLS != nil {
+				return wsServer.StartTLS(cfg.WSListenAddr, apiTLS)
+			}
+			return wsServer.Start(cfg.WSListenAddr)
+		},
+		Stop: func(context.Context) error {
+			wsServer.Stop()
+			return nil
+		},
+	})
 
 	// Setup HTTP API server
 	router := gin.New()
@@ -213,13 +587,100 @@ func runNode(cmd *cobra.Command, args []string) {
 		},
 	}))
 	router.Use(gin.Recovery())
+	// Client IPs, which rate limits and quotas key on, come from
+	// X-Forwarded-For only when the request came through a trusted proxy
+	if err := router.SetTrustedProxies(trustedProxies(cfg)); err != nil {
+		return fmt.Errorf("invalid VINDEX_TRUSTED_PROXIES: %w", err)
+	}
+
+	// Response cache and rate limits, in Redis when it is reachable so a
+	// fleet of API nodes shares them, and otherwise in process
+	cacheStore := cache.Open(&cache.Config{
+		RedisURL: cfg.RedisURL,
+		Size:     cfg.APICacheSize,
+		Logger:   logger,
+	})
+	defer cacheStore.Close()
+	logger.Info("API cache ready", zap.String("backend", cacheStore.Backend()))
+	cached := gin.HandlerFunc(func(c *gin.Context) { c.Next() })
+	if cfg.APICache {
+		cached = api.NewResponseCache(cacheStore, bc.Height, cfg.APICacheTTL, logger).Handler()
+	}
+
+	// CORS policy from the config, with per-route-group origins: public
+	// routes follow VINDEX_CORS_ALLOWED_ORIGINS, admin and KYC refuse
+	// cross-origin requests unless VINDEX_CORS_ROUTE_ORIGINS lists origins
+	corsPolicies, err := api.ParseCORSPolicies(corsConfig(cfg))
+	if err != nil {
+		return fmt.Errorf("invalid CORS configuration: %w", err)
+	}
+	for _, policy := range corsPolicies {
+		if policy.ExposesWrites() {
+			logger.Warn("CORS allows any origin to call write routes; set VINDEX_CORS_ALLOWED_ORIGINS to the origins of your apps",
+				zap.String("routes", policy.Prefix+"/*"),
+			)
+		}
+	}
+	corsHandler, err := api.CORS(corsPolicies)
+	if err != nil {
+		return fmt.Errorf("invalid CORS configuration: %w", err)
+	}
+	router.Use(corsHandler)
+
+	// Authenticate JWTs and API keys. Public reads, raw transaction
+	// broadcast and messages signed by their sender stay open; routes that
+	// sign with node keys need the broadcast scope, and admin and KYC
+	// routes their own scopes.
+	apiKeys, err := auth.OpenKeyStore(cfg.APIKeysFile)
+	if err != nil {
+		return fmt.Errorf("failed to load API keys: %w", err)
+	}
+	authenticator := auth.NewAuthenticator(&auth.Config{
+		JWTSecret: cfg.JWTSecret,
+		Keys:      apiKeys,
+		AdminKey:  cfg.AdminAPIKey,
+		Logger:    logger,
+	})
+	router.Use(api.Authenticate(authenticator))
+	signing := api.RequireScope(auth.ScopeBroadcast)
+	compliance := api.RequireScope(auth.ScopeCompliance)
 
-	// CORS configuration
-	corsConfig := cors.DefaultConfig()
-	corsConfig.AllowAllOrigins = true
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
-	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	router.Use(cors.New(corsConfig))
+	// Rate limit per API key, or per IP for anonymous clients, with
+	// stricter limits on route groups like broadcast and daily quotas for
+	// API key tiers
+	routeLimits, err := api.ParseRouteLimits(cfg.APIRouteRateLimits)
+	if err != nil {
+		return fmt.Errorf("invalid route rate limits: %w", err)
+	}
+	quotas, err := api.ParseQuotas(cfg.APIQuotas)
+	if err != nil {
+		return fmt.Errorf("invalid API quotas: %w", err)
+	}
+	rateLimiter := api.NewRateLimiter(&api.RateLimitConfig{
+		Store:   cacheStore,
+		Default: cache.Limit{Rate: cfg.APIRateLimit, Burst: cfg.APIRateBurst},
+		Routes:  routeLimits,
+		Quotas:  quotas,
+		Logger:  logger,
+	})
+	router.Use(rateLimiter.Handler())
+	adminController.OnReload("rate_limits", func(next *config.Config) error {
+		routeLimits, err := api.ParseRouteLimits(next.APIRouteRateLimits)
+		if err != nil {
+			return err
+		}
+		quotas, err := api.ParseQuotas(next.APIQuotas)
+		if err != nil {
+			return err
+		}
+		rateLimiter.SetLimits(cache.Limit{Rate: next.APIRateLimit, Burst: next.APIRateBurst}, routeLimits, quotas)
+		return nil
+	})
+
+	// Refuse new transactions while the operator has paused the mempool
+	router.Use(api.MempoolGate(adminController.MempoolAccepting,
+		"/api/v1/transactions/simulate", "/api/v1/admin", "/api/v1/compliance",
+	))
 
 	// Initialize API handlers
 	apiHandler := api.NewHandler(&api.Config{
@@ -231,52 +692,132 @@ func runNode(cmd *cobra.Command, args []string) {
 		P2PNode:        p2pNode,
 		Logger:         logger,
 	})
+	accountHandler := api.NewAccountHandler(accountKeeper, bankKeeper, txMempool, logger)
+	tokenAdminHandler := api.NewTokenAdminHandler(tokenFactory, txMempool, logger)
+	dexHandler := api.NewDexHandler(dexKeeper, dexIndexer, txMempool, corsPolicies, logger)
+	domainHandler := api.NewDomainHandler(domainSystem, txMempool, logger)
+	netHandler := api.NewNetHandler(p2pNode, logger)
+	statsHandler := api.NewStatsHandler(bankKeeper, NativeDenom, logger)
+	statusHandler := api.NewStatusHandler(p2pNode, blockSync, logger)
+	lightHandler := api.NewLightHandler(bc, appState, logger)
+
+	// Serve transactions from the tx indexer when it is enabled
+	var txSearchHandler *api.TxSearchHandler
+	getTransaction, getAccountTransactions := apiHandler.GetTransaction, apiHandler.GetAccountTransactions
+	if txIndexer != nil {
+		txSearchHandler = api.NewTxSearchHandler(txIndexer, logger)
+		getTransaction, getAccountTransactions = txSearchHandler.GetTransaction, txSearchHandler.GetAccountTransactions
+	}
 
 	// Register API routes
-	v1 := router.Group("/api/v1")
+	v1 := router.Group("/api/v1", lightHandler.ProveQueries())
 	{
 		// Blockchain endpoints
-		v1.GET("/status", apiHandler.GetStatus)
+		v1.GET("/status", cached, statusHandler.GetStatus)
+		v1.GET("/light_blocks/:height", lightHandler.GetLightBlock)
 		v1.GET("/blocks", apiHandler.GetBlocks)
-		v1.GET("/blocks/:height", apiHandler.GetBlock)
+		v1.GET("/blocks/:height", cached, apiHandler.GetBlock)
 		v1.GET("/transactions", apiHandler.GetTransactions)
-		v1.GET("/transactions/:hash", apiHandler.GetTransaction)
+		v1.GET("/transactions/:hash", getTransaction)
+		if txSearchHandler != nil {
+			v1.GET("/transactions/search", txSearchHandler.SearchTransactions)
+			v1.GET("/blocks/search", txSearchHandler.SearchBlocks)
+		}
 		
 		// Account endpoints
 		v1.GET("/accounts/:address", apiHandler.GetAccount)
-		v1.GET("/accounts/:address/balance", apiHandler.GetBalance)
-		v1.GET("/accounts/:address/transactions", apiHandler.GetAccountTransactions)
+		v1.GET("/accounts/:address/balance", accountHandler.GetBalance)
+		v1.GET("/accounts/:address/transactions", getAccountTransactions)
+		v1.GET("/accounts/:address/sequence", accountHandler.GetSequence)
 		
 		// Transaction endpoints
 		v1.POST("/transactions/broadcast", apiHandler.BroadcastTransaction)
+		v1.POST("/transactions/send", accountHandler.Send)
 		v1.POST("/transactions/simulate", apiHandler.SimulateTransaction)
 		
 		// Staking endpoints
 		v1.GET("/staking/validators", apiHandler.GetValidators)
 		v1.GET("/staking/validators/:address", apiHandler.GetValidator)
 		v1.GET("/staking/delegations/:address", apiHandler.GetDelegations)
-		v1.POST("/staking/delegate", apiHandler.Delegate)
-		v1.POST("/staking/undelegate", apiHandler.Undelegate)
+		v1.POST("/staking/delegate", signing, apiHandler.Delegate)
+		v1.POST("/staking/undelegate", signing, apiHandler.Undelegate)
 		
 		// Token endpoints
-		v1.GET("/tokens", apiHandler.GetTokens)
+		v1.GET("/tokens", cached, apiHandler.GetTokens)
 		v1.GET("/tokens/:denom", apiHandler.GetToken)
-		v1.POST("/tokens/create", apiHandler.CreateToken)
+		v1.POST("/tokens/create", signing, tokenAdminHandler.CreateToken)
+		v1.GET("/tokens/:denom/holders", tokenAdminHandler.GetHolders)
+		v1.POST("/tokens/mint", tokenAdminHandler.Mint)
+		v1.POST("/tokens/burn", tokenAdminHandler.Burn)
+		v1.POST("/tokens/change-admin", tokenAdminHandler.ChangeAdmin)
+		v1.POST("/tokens/renounce-admin", tokenAdminHandler.RenounceAdmin)
+		v1.POST("/tokens/freeze", tokenAdminHandler.Freeze)
+		v1.POST("/tokens/unfreeze", tokenAdminHandler.Unfreeze)
 		
 		// Domain endpoints
-		v1.GET("/domains", apiHandler.GetDomains)
-		v1.GET("/domains/:name", apiHandler.GetDomain)
-		v1.POST("/domains/register", apiHandler.RegisterDomain)
+		v1.GET("/domains", domainHandler.GetDomains)
+		v1.GET("/domains/:name", domainHandler.GetDomain)
+		v1.GET("/domains/:name/price", domainHandler.GetPrice)
+		v1.POST("/domains/register", domainHandler.RegisterDomain)
+		v1.POST("/domains/renew", domainHandler.RenewDomain)
+		v1.GET("/domains/resolve/:name", domainHandler.Resolve)
+		v1.GET("/domains/reverse/:address", domainHandler.Reverse)
+		v1.GET("/domains/:name/subdomains", domainHandler.GetSubdomains)
+		v1.POST("/domains/records", domainHandler.SetRecords)
+		v1.POST("/domains/subdomains/create", domainHandler.CreateSubdomain)
+		v1.POST("/domains/subdomains/delete", domainHandler.DeleteSubdomain)
+		v1.POST("/domains/primary", domainHandler.SetPrimaryName)
+		v1.POST("/domains/transfer", domainHandler.TransferDomain)
+		v1.GET("/domains/listings", domainHandler.GetListings)
+		v1.POST("/domains/list", domainHandler.ListDomain)
+		v1.POST("/domains/listings/cancel", domainHandler.CancelListing)
+		v1.POST("/domains/buy", domainHandler.BuyDomain)
+		v1.GET("/domains/auctions", domainHandler.GetAuctions)
+		v1.GET("/domains/auctions/:name", domainHandler.GetAuction)
+		v1.POST("/domains/auctions/start", domainHandler.StartAuction)
+		v1.POST("/domains/auctions/commit", domainHandler.CommitBid)
+		v1.POST("/domains/auctions/reveal", domainHandler.RevealBid)
+		v1.POST("/domains/auctions/finalize", domainHandler.FinalizeAuction)
+		v1.GET("/domains/reserved", domainHandler.GetReservedNames)
+		v1.POST("/domains/reserved/claim", domainHandler.ClaimReservedName)
+		v1.POST("/domains/reserved/update", domainHandler.UpdateReservedNames)
+		
+		// DEX endpoints
+		v1.GET("/dex/pools", dexHandler.GetPools)
+		v1.GET("/dex/pools/:id", dexHandler.GetPool)
+		v1.GET("/dex/pools/:id/trades", dexHandler.GetPoolTrades)
+		v1.GET("/dex/pools/:id/candles", dexHandler.GetCandles)
+		v1.GET("/dex/pools/:id/candles/stream", dexHandler.StreamCandles)
+		v1.GET("/dex/trades", dexHandler.GetTrades)
+		v1.GET("/dex/quote", dexHandler.GetQuote)
+		v1.POST("/dex/pools", dexHandler.CreatePool)
+		v1.POST("/dex/liquidity/add", dexHandler.AddLiquidity)
+		v1.POST("/dex/liquidity/remove", dexHandler.RemoveLiquidity)
+		v1.POST("/dex/swap/exact-in", dexHandler.SwapExactIn)
+		v1.POST("/dex/swap/exact-out", dexHandler.SwapExactOut)
 		
 		// Statistics endpoints
-		v1.GET("/stats/supply", apiHandler.GetSupplyStats)
-		v1.GET("/stats/burn", apiHandler.GetBurnStats)
-		v1.GET("/stats/network", apiHandler.GetNetworkStats)
+		v1.GET("/stats/supply", cached, apiHandler.GetSupplyStats)
+		v1.GET("/stats/burn", cached, statsHandler.AddLedgerBurned(), apiHandler.GetBurnStats)
+		v1.GET("/stats/network", cached, apiHandler.GetNetworkStats)
+		v1.GET("/net/peers", netHandler.GetPeers)
 		
 		// Compliance endpoints
 		v1.GET("/compliance/ofac/:address", apiHandler.CheckOFAC)
-		v1.POST("/compliance/kyc", apiHandler.SubmitKYC)
-		v1.GET("/compliance/kyc/:address", apiHandler.GetKYCStatus)
+		v1.POST("/compliance/kyc", compliance, apiHandler.SubmitKYC)
+		v1.GET("/compliance/kyc/:address", compliance, apiHandler.GetKYCStatus)
+	}
+
+	// Admin endpoints, behind mTLS as well when configured
+	adminGuards := []gin.HandlerFunc{api.RequireScope(auth.ScopeAdmin)}
+	if cfg.TLSAdminClientAuth {
+		adminGuards = append([]gin.HandlerFunc{api.RequireClientCert()}, adminGuards...)
+	}
+	admin := v1.Group("/admin", adminGuards...)
+	{
+		admin.GET("/peers/banned", netHandler.GetBannedPeers)
+		admin.POST("/peers/:id/ban", netHandler.BanPeer)
+		admin.POST("/peers/:id/unban", netHandler.UnbanPeer)
 	}
 
 	// Health check endpoint
@@ -295,41 +836,102 @@ func runNode(cmd *cobra.Command, args []string) {
 
 	// Start HTTP server
 	server := &http.Server{
-		Addr:    cfg.HTTPListenAddr,
-		Handler: router,
+		Addr:      cfg.HTTPListenAddr,
+		Handler:   router,
+		TLSConfig: apiTLS,
+	}
+
+	supervisor.Add(&service.Service{
+		Name:      "http",
+		DependsOn: []string{"blockchain", "p2p", "consensus"},
+		Run: func(context.Context) error {
+			logger.Info("Starting VindexChain HTTP API server", 
+				zap.String("addr", cfg.HTTPListenAddr),
+				zap.Bool("tls", apiTLS != nil),
+			)
+			var err error
+			if apiTLS != nil {
+				// The certificate comes from TLSConfig, which reloads it
+				err = server.ListenAndServeTLS("", "")
+			} else {
+				err = server.ListenAndServe()
+			}
+			if err == http.ErrServerClosed {
+				return nil
+			}
+			return err
+		},
+		Stop: server.Shutdown,
+	})
+
+	// Admin listener for node operations, on a unix socket or localhost by
+	// default. Over TCP it also requires the admin scope.
+	adminRouter := gin.New()
+	adminRouter.Use(gin.Recovery())
+	if err := adminRouter.SetTrustedProxies(nil); err != nil {
+		return fmt.Errorf("failed to configure admin router: %w", err)
+	}
+	if !admin.IsUnix(cfg.AdminListenAddr) {
+		adminRouter.Use(api.Authenticate(authenticator), api.RequireScope(auth.ScopeAdmin))
 	}
+	adminHandler := api.NewAdminHandler(adminController, logger)
+	adminRouter.GET("/admin/status", adminHandler.GetStatus)
+	adminRouter.POST("/admin/halt", adminHandler.SetHaltHeight)
+	adminRouter.POST("/admin/mempool", adminHandler.SetMempool)
+	adminRouter.POST("/admin/snapshot", adminHandler.CreateSnapshot)
+	adminRouter.POST("/admin/prune", adminHandler.Prune)
+	adminRouter.PUT("/admin/log-level", adminHandler.SetLogLevel)
+	adminRouter.POST("/admin/reload", adminHandler.Reload)
+	adminRouter.GET("/debug/pprof/*profile", adminHandler.Pprof)
 
-	go func() {
-		logger.Info("Starting VindexChain HTTP API server", 
-			zap.String("addr", cfg.HTTPListenAddr),
+	if !admin.IsLoopback(cfg.AdminListenAddr) {
+		logger.Warn("The admin API listens on a non-loopback address; anyone with an admin key who can reach it can halt the node",
+			zap.String("addr", cfg.AdminListenAddr),
 		)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Fatal("Failed to start HTTP server", zap.Error(err))
-		}
-	}()
+	}
+	adminListener, err := admin.Listen(cfg.AdminListenAddr)
+	if err != nil {
+		return fmt.Errorf("failed to start admin API: %w", err)
+	}
+	adminServer := &http.Server{Handler: adminRouter}
+	supervisor.Add(&service.Service{
+		Name:      "admin",
+		DependsOn: []string{"blockchain"},
+		Run: func(context.Context) error {
+			logger.Info("Starting admin API", zap.String("addr", cfg.AdminListenAddr))
+			if err := adminServer.Serve(adminListener); err != http.ErrServerClosed {
+				return err
+			}
+			return nil
+		},
+		Stop: adminServer.Shutdown,
+	})
 
-	// Wait for interrupt signal to gracefully shutdown
+	// Start the services and run until an interrupt signal, the halt height
+	// or a service failure
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	if err := supervisor.Start(context.Background()); err != nil {
+		return err
+	}
+	var failure error
+	select {
+	case <-quit:
+	case height := <-halted:
+		logger.Info("Halting at the configured height", zap.Int64("height", height))
+	case failure = <-supervisor.Failed():
+		logger.Error("Shutting down after a service failed", zap.Error(failure))
+	}
 
 	logger.Info("Shutting down VindexChain...")
-
-	// Graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	if err := server.Shutdown(ctx); err != nil {
-		logger.Error("HTTP server forced to shutdown", zap.Error(err))
+	if err := supervisor.Stop(); err != nil {
+		logger.Error("Some services did not stop cleanly", zap.Error(err))
+	}
+	if failure != nil {
+		return failure
 	}
-
-	// Stop blockchain services
-	bc.Stop()
-	consensus.Stop()
-	p2pNode.Stop()
-	wsServer.Stop()
-
 	logger.Info("VindexChain stopped gracefully")
+	return nil
 }
 
 func initCmd() *cobra.Command {
@@ -344,12 +946,41 @@ func initCmd() *cobra.Command {
 			// Initialize node configuration
 			// Implementation here
 			
-			logger.Info("Node initialized successfully")
+			// Create the node key that identifies this node to its peers
+			nodeConfig := config.LoadConfig()
+			nodeKey, err := p2p.LoadOrGenNodeKey(nodeConfig.NodeKeyFile)
+			if err != nil {
+				logger.Fatal("Failed to create node key", zap.Error(err))
+			}
+			
+			// Create a self-signed TLS certificate, for nodes without one
+			// from a CA
+			if withTLS, _ := cmd.Flags().GetBool("tls"); withTLS {
+				hosts, _ := cmd.Flags().GetStringSlice("tls-hosts")
+				validFor, _ := cmd.Flags().GetDuration("tls-valid-for")
+				generated, err := tlsutil.GenerateSelfSigned(nodeConfig.TLSCertFile, nodeConfig.TLSKeyFile, moniker, hosts, validFor)
+				if err != nil {
+					logger.Fatal("Failed to create TLS certificate", zap.Error(err))
+				}
+				if generated {
+					logger.Info("Created self-signed TLS certificate; set VINDEX_TLS_ENABLE=true to serve it, and add it to the VINDEX_TLS_CLIENT_CA_FILE of peers that should trust this node",
+						zap.String("cert", nodeConfig.TLSCertFile),
+						zap.String("key", nodeConfig.TLSKeyFile),
+					)
+				} else {
+					logger.Info("TLS certificate already exists", zap.String("cert", nodeConfig.TLSCertFile))
+				}
+			}
+			
+			logger.Info("Node initialized successfully", zap.String("node_id", string(nodeKey.ID())))
 		},
 	}
 	
 	cmd.Flags().String("chain-id", ChainID, "genesis file chain-id")
 	cmd.Flags().String("home", "", "node's home directory")
+	cmd.Flags().Bool("tls", false, "create a self-signed TLS certificate at VINDEX_TLS_CERT_FILE and VINDEX_TLS_KEY_FILE")
+	cmd.Flags().StringSlice("tls-hosts", nil, "host names and IP addresses the TLS certificate is valid for, besides localhost")
+	cmd.Flags().Duration("tls-valid-for", 365*24*time.Hour, "how long the TLS certificate is valid")
 	
 	return cmd
 }
@@ -358,12 +989,36 @@ func startCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "start",
 		Short: "Start the VindexChain node",
-		Run:   runNode,
+		RunE:  runNode,
 	}
 	
+	cmd.Flags().Bool("seed-mode", false, "only crawl the network and serve peer addresses")
+	cmd.Flags().Bool("block-sync", true, "download missing blocks from peers before joining consensus")
+	cmd.Flags().Bool("state-sync", false, "restore state from a peer snapshot when starting without any state")
+	cmd.Flags().String("database-url", "", "indexer database: postgres://..., file://./data/index.db or mem:// (defaults to VINDEX_DATABASE_URL)")
+	cmd.Flags().Bool("auto-migrate", false, "apply pending database migrations on start instead of refusing to run")
+	cmd.Flags().Bool("tx-index", true, "index transactions and events for /transactions/search")
+	cmd.Flags().String("tx-index-keys", "*", "comma-separated event attributes to index, e.g. transfer.recipient,tx.memo, or * for all")
+	cmd.Flags().String("pruning", "default", "which old state versions to delete: default, nothing, everything or custom (set VINDEX_PRUNING_KEEP_RECENT and VINDEX_PRUNING_INTERVAL)")
+	
 	return cmd
 }
 
+func showNodeIDCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show-node-id",
+		Short: "Print this node's p2p ID for use in id@host:port peer addresses",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			nodeKey, err := p2p.LoadOrGenNodeKey(config.LoadConfig().NodeKeyFile)
+			if err != nil {
+				return err
+			}
+			fmt.Println(nodeKey.ID())
+			return nil
+		},
+	}
+}
+
 func versionCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "version",
@@ -389,18 +1044,36 @@ func keysCmd() *cobra.Command {
 			Use:   "add [name]",
 			Short: "Add a new key",
 			Args:  cobra.ExactArgs(1),
-			Run: func(cmd *cobra.Command, args []string) {
-				// Implementation for adding keys
+			RunE: func(cmd *cobra.Command, args []string) error {
+				dir, _ := cmd.Flags().GetString("keyring-dir")
+				key, err := accounts.AddKey(dir, args[0])
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Created key %s\n", key.Name)
+				fmt.Printf("Address: %s\n", key.Address())
+				fmt.Printf("Saved to %s; back it up, it cannot be recovered\n", dir)
+				return nil
 			},
 		},
 		&cobra.Command{
 			Use:   "list",
 			Short: "List all keys",
-			Run: func(cmd *cobra.Command, args []string) {
-				// Implementation for listing keys
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				dir, _ := cmd.Flags().GetString("keyring-dir")
+				keys, err := accounts.ListKeys(dir)
+				if err != nil {
+					return err
+				}
+				for _, key := range keys {
+					fmt.Printf("%s\t%s\n", key.Name, key.Address())
+				}
+				return nil
 			},
 		},
 	)
+	addKeyringFlag(cmd)
 	
 	return cmd
 }
@@ -414,14 +1087,34 @@ func txCmd() *cobra.Command {
 	// Add transaction subcommands
 	cmd.AddCommand(
 		&cobra.Command{
-			Use:   "send [from] [to] [amount]",
-			Short: "Send tokens",
+			Use:   "send [from-key] [to] [amount]",
+			Short: "Send tokens to an address or a .vindex name",
 			Args:  cobra.ExactArgs(3),
-			Run: func(cmd *cobra.Command, args []string) {
-				// Implementation for sending tokens
+			RunE: func(cmd *cobra.Command, args []string) error {
+				key, err := signingKey(cmd, args[0])
+				if err != nil {
+					return err
+				}
+				amount, denom, err := parseCoin(args[2])
+				if err != nil {
+					return err
+				}
+				to, err := resolveRecipient(cmd, args[1])
+				if err != nil {
+					return err
+				}
+				msg := bank.MsgSend{From: key.Address(), To: to, Denom: denom, Amount: amount}
+				if err := msg.ValidateBasic(); err != nil {
+					return err
+				}
+				return postSigned(cmd, "/transactions/send", key, msg)
 			},
 		},
+		tokensTxCmd(),
+		domainsTxCmd(),
 	)
+	addNodeFlag(cmd)
+	addKeyringFlag(cmd)
 	
 	return cmd
 }
@@ -442,7 +1135,58 @@ func queryCmd() *cobra.Command {
 				// Implementation for querying accounts
 			},
 		},
+		tokensQueryCmd(),
+		domainsQueryCmd(),
 	)
+	addNodeFlag(cmd)
 	
 	return cmd
-}
\ No newline at end of file
+}
+
+// openStateStore opens the application state database with the configured
+// pruning strategy
+func openStateStore(cfg *config.Config, beforeCommit func(version int64) error) (*state.Store, error) {
+	pruning, err := state.NewPruningOptions(cfg.Pruning, cfg.PruningKeepRecent, cfg.PruningInterval)
+	if err != nil {
+		return nil, err
+	}
+	db, err := kv.NewBoltDB(cfg.StateDBPath)
+	if err != nil {
+		return nil, err
+	}
+	store, err := state.NewStore(&state.Config{
+		DB:           db,
+		Pruning:      pruning,
+		BeforeCommit: beforeCommit,
+		Logger:       logger,
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// trustedProxies lists the proxies of VINDEX_TRUSTED_PROXIES; none when
+// it is empty
+func trustedProxies(cfg *config.Config) []string {
+	var proxies []string
+	for _, proxy := range strings.Split(cfg.APITrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// corsConfig collects the CORS settings of the node configuration
+func corsConfig(cfg *config.Config) *api.CORSConfig {
+	return &api.CORSConfig{
+		Origins:      cfg.CORSAllowedOrigins,
+		Methods:      cfg.CORSAllowedMethods,
+		Headers:      cfg.CORSAllowedHeaders,
+		Credentials:  cfg.CORSAllowCredentials,
+		MaxAge:       cfg.CORSMaxAge,
+		RouteOrigins: cfg.CORSRouteOrigins,
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vindexchain/core/internal/tokens"
+)
+
+func tokensTxCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tokens",
+		Short: "Token factory transaction subcommands",
+	}
+
+	create := &cobra.Command{
+		Use:   "create [creator-key] [name] [symbol]",
+		Short: "Create a new factory token",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := signingKey(cmd, args[0])
+			if err != nil {
+				return err
+			}
+			decimals, _ := cmd.Flags().GetUint32("decimals")
+			initialSupply, _ := cmd.Flags().GetUint64("initial-supply")
+			maxSupply, _ := cmd.Flags().GetUint64("max-supply")
+			freezable, _ := cmd.Flags().GetBool("freezable")
+			liquidityAmount, _ := cmd.Flags().GetUint64("liquidity-amount")
+
+			msg := tokens.MsgCreateToken{
+				Creator:         key.Address(),
+				Name:            args[1],
+				Symbol:          args[2],
+				Decimals:        decimals,
+				InitialSupply:   initialSupply,
+				MaxSupply:       maxSupply,
+				Freezable:       freezable,
+				LiquidityAmount: liquidityAmount,
+			}
+			if err := msg.ValidateBasic(); err != nil {
+				return err
+			}
+			return postSigned(cmd, "/tokens/create", key, msg)
+		},
+	}
+	create.Flags().Uint32("decimals", 6, "token decimals")
+	create.Flags().Uint64("initial-supply", 0, "amount minted to the creator")
+	create.Flags().Uint64("max-supply", 0, "maximum supply enforced on every mint (0 for uncapped)")
+	create.Flags().Bool("freezable", false, "allow the admin to freeze holders")
+	create.Flags().Uint64("liquidity-amount", 0, "part of the initial supply paired with OC$ in the initial pool")
+
+	cmd.AddCommand(
+		create,
+		&cobra.Command{
+			Use:   "mint [admin-key] [denom] [amount] [recipient]",
+			Short: "Mint tokens to a recipient",
+			Args:  cobra.ExactArgs(4),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				key, err := signingKey(cmd, args[0])
+				if err != nil {
+					return err
+				}
+				amount, err := parseAmount(args[2])
+				if err != nil {
+					return err
+				}
+				msg := tokens.MsgMint{Sender: key.Address(), Denom: args[1], Amount: amount, Recipient: args[3]}
+				if err := msg.ValidateBasic(); err != nil {
+					return err
+				}
+				return postSigned(cmd, "/tokens/mint", key, msg)
+			},
+		},
+		&cobra.Command{
+			Use:   "burn [admin-key] [denom] [amount]",
+			Short: "Burn tokens from the admin balance",
+			Args:  cobra.ExactArgs(3),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				key, err := signingKey(cmd, args[0])
+				if err != nil {
+					return err
+				}
+				amount, err := parseAmount(args[2])
+				if err != nil {
+					return err
+				}
+				msg := tokens.MsgBurn{Sender: key.Address(), Denom: args[1], Amount: amount}
+				if err := msg.ValidateBasic(); err != nil {
+					return err
+				}
+				return postSigned(cmd, "/tokens/burn", key, msg)
+			},
+		},
+		&cobra.Command{
+			Use:   "change-admin [admin-key] [denom] [new-admin]",
+			Short: "Transfer admin rights to another address",
+			Args:  cobra.ExactArgs(3),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				key, err := signingKey(cmd, args[0])
+				if err != nil {
+					return err
+				}
+				msg := tokens.MsgChangeAdmin{Sender: key.Address(), Denom: args[1], NewAdmin: args[2]}
+				if err := msg.ValidateBasic(); err != nil {
+					return err
+				}
+				return postSigned(cmd, "/tokens/change-admin", key, msg)
+			},
+		},
+		&cobra.Command{
+			Use:   "renounce-admin [admin-key] [denom]",
+			Short: "Permanently give up admin rights",
+			Args:  cobra.ExactArgs(2),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				key, err := signingKey(cmd, args[0])
+				if err != nil {
+					return err
+				}
+				msg := tokens.MsgRenounceAdmin{Sender: key.Address(), Denom: args[1]}
+				if err := msg.ValidateBasic(); err != nil {
+					return err
+				}
+				return postSigned(cmd, "/tokens/renounce-admin", key, msg)
+			},
+		},
+		&cobra.Command{
+			Use:   "freeze [admin-key] [denom] [address]",
+			Short: "Freeze a holder of a freezable token",
+			Args:  cobra.ExactArgs(3),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				key, err := signingKey(cmd, args[0])
+				if err != nil {
+					return err
+				}
+				msg := tokens.MsgFreeze{Sender: key.Address(), Denom: args[1], Address: args[2]}
+				if err := msg.ValidateBasic(); err != nil {
+					return err
+				}
+				return postSigned(cmd, "/tokens/freeze", key, msg)
+			},
+		},
+		&cobra.Command{
+			Use:   "unfreeze [admin-key] [denom] [address]",
+			Short: "Unfreeze a holder",
+			Args:  cobra.ExactArgs(3),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				key, err := signingKey(cmd, args[0])
+				if err != nil {
+					return err
+				}
+				msg := tokens.MsgUnfreeze{Sender: key.Address(), Denom: args[1], Address: args[2]}
+				if err := msg.ValidateBasic(); err != nil {
+					return err
+				}
+				return postSigned(cmd, "/tokens/unfreeze", key, msg)
+			},
+		},
+	)
+
+	return cmd
+}
+
+func tokensQueryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tokens",
+		Short: "Token factory query subcommands",
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "holders [denom]",
+			Short: "List the holders of a factory token",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return getJSON(cmd, "/tokens/"+args[0]+"/holders")
+			},
+		},
+	)
+
+	return cmd
+}
+
+func parseAmount(s string) (uint64, error) {
+	amount, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || amount == 0 {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return amount, nil
+}
+
+// parseCoin splits an amount such as 1000oc into amount and denom; a bare
+// number is in the native denom
+func parseCoin(s string) (uint64, string, error) {
+	i := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	if i == -1 {
+		amount, err := parseAmount(s)
+		return amount, NativeDenom, err
+	}
+	amount, err := parseAmount(s[:i])
+	if err != nil {
+		return 0, "", err
+	}
+	return amount, s[i:], nil
+}
@@ -0,0 +1,90 @@
+package accounts
+
+import (
+	"crypto/ed25519"
+	"strings"
+
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+// AddressPrefix is the human-readable part of account addresses
+const AddressPrefix = "vindex"
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// Address returns the vindex1... address of an account key: the first 20
+// bytes of the key's SHA-256, bech32 encoded
+func Address(pubKey ed25519.PublicKey) string {
+	return bech32Encode(AddressPrefix, types.AddressFromPubKey(pubKey))
+}
+
+// bech32Encode encodes data with the bech32 checksum of BIP 173
+func bech32Encode(hrp string, data []byte) string {
+	values := convertBits(data)
+	checksum := bech32Checksum(hrp, values)
+
+	var sb strings.Builder
+	sb.Grow(len(hrp) + 1 + len(values) + len(checksum))
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, v := range append(values, checksum...) {
+		sb.WriteByte(bech32Charset[v])
+	}
+	return sb.String()
+}
+
+// convertBits regroups 8-bit bytes into 5-bit values, padding the last one
+func convertBits(data []byte) []byte {
+	var (
+		values []byte
+		acc    uint32
+		bits   uint
+	)
+	for _, b := range data {
+		acc = acc<<8 | uint32(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			values = append(values, byte(acc>>bits)&31)
+		}
+	}
+	if bits > 0 {
+		values = append(values, byte(acc<<(5-bits))&31)
+	}
+	return values
+}
+
+func bech32Checksum(hrp string, values []byte) []byte {
+	var expanded []byte
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+	expanded = append(expanded, values...)
+	expanded = append(expanded, 0, 0, 0, 0, 0, 0)
+
+	mod := bech32Polymod(expanded) ^ 1
+	checksum := make([]byte, 6)
+	for i := range checksum {
+		checksum[i] = byte(mod>>uint(5*(5-i))) & 31
+	}
+	return checksum
+}
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
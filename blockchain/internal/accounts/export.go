@@ -0,0 +1,50 @@
+package accounts
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ExportState returns the next sequence of every account that has sent a
+// message, keyed by address
+func (k *Keeper) ExportState() (map[string][]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	state := make(map[string][]byte, len(k.sequences))
+	for address, sequence := range k.sequences {
+		state[address] = strconv.AppendUint(nil, sequence, 10)
+	}
+	return state, nil
+}
+
+// ImportState replaces the account sequences with exported state
+func (k *Keeper) ImportState(state map[string][]byte) error {
+	sequences := make(map[string]uint64, len(state))
+	for key, value := range state {
+		sequence, err := strconv.ParseUint(string(value), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid sequence under %s: %w", key, err)
+		}
+		sequences[key] = sequence
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.sequences = sequences
+	k.changed = make(map[string]bool)
+	return nil
+}
+
+// ExportChanges returns the sequences that changed since the last export
+func (k *Keeper) ExportChanges() (map[string][]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	changes := make(map[string][]byte, len(k.changed))
+	for address := range k.changed {
+		changes[address] = strconv.AppendUint(nil, k.sequences[address], 10)
+	}
+	k.changed = make(map[string]bool)
+	return changes, nil
+}
@@ -0,0 +1,90 @@
+package accounts
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Config configures the account keeper
+type Config struct {
+	ChainID string
+	Logger  *zap.Logger
+}
+
+// Keeper checks the signatures of account messages and tracks each
+// account's sequence, which every accepted message uses up
+type Keeper struct {
+	chainID string
+	logger  *zap.Logger
+
+	mu        sync.Mutex
+	sequences map[string]uint64 // address -> next sequence
+	changed   map[string]bool   // addresses whose sequence changed since the last export
+}
+
+// NewKeeper creates an account keeper for a chain
+func NewKeeper(cfg *Config) *Keeper {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Keeper{
+		chainID:   cfg.ChainID,
+		logger:    logger,
+		sequences: make(map[string]uint64),
+		changed:   make(map[string]bool),
+	}
+}
+
+// ChainID returns the chain ID messages are signed for
+func (k *Keeper) ChainID() string {
+	return k.chainID
+}
+
+// Sequence returns the sequence the next message of an address must carry
+func (k *Keeper) Sequence(address string) uint64 {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.sequences[address]
+}
+
+// Verify decodes a signed message into msg, checks that its sender signed
+// it with the sender's next sequence, and uses the sequence up. The
+// sequence is used up even if the message then fails, so a signed message
+// is never executed twice.
+func (k *Keeper) Verify(signed *SignedMsg, msg Msg) error {
+	if err := k.CheckSignature(signed, msg); err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	address := msg.Signer()
+	if next := k.sequences[address]; signed.Sequence != next {
+		return fmt.Errorf("%w: %s is at sequence %d, message has %d", ErrWrongSequence, address, next, signed.Sequence)
+	}
+	k.sequences[address]++
+	k.changed[address] = true
+	return nil
+}
+
+// CheckSignature decodes a signed message into msg and checks its
+// signature for the sequence it carries, without checking or using up the
+// sequence. The mempool uses it to refuse forged transactions before they
+// reach a block.
+func (k *Keeper) CheckSignature(signed *SignedMsg, msg Msg) error {
+	if err := signed.Decode(msg); err != nil {
+		return err
+	}
+	signBytes, err := SignBytes(k.chainID, msg.Type(), signed.Sequence, signed.Msg)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(ed25519.PublicKey(signed.PubKey), signBytes, signed.Signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package accounts
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrKeyNotFound is returned for key names without a key file
+	ErrKeyNotFound = errors.New("key not found")
+	// ErrKeyExists is returned when adding a key under a name already in use
+	ErrKeyExists = errors.New("key already exists")
+)
+
+var keyNameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// Key is an account key kept in a keyring directory, one file per name
+type Key struct {
+	Name    string
+	PrivKey ed25519.PrivateKey
+}
+
+type keyJSON struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	PubKey  string `json:"pub_key"`
+	PrivKey string `json:"priv_key"`
+}
+
+// Address returns the account address of the key
+func (k *Key) Address() string {
+	return Address(k.PubKey())
+}
+
+// PubKey returns the public half of the key
+func (k *Key) PubKey() ed25519.PublicKey {
+	return k.PrivKey.Public().(ed25519.PublicKey)
+}
+
+// Sign signs msg for the given chain and sequence
+func (k *Key) Sign(chainID string, sequence uint64, msg Msg) (*SignedMsg, error) {
+	return Sign(k.PrivKey, chainID, sequence, msg)
+}
+
+// AddKey creates a new random key named name in dir
+func AddKey(dir, name string) (*Key, error) {
+	if !keyNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("invalid key name %q", name)
+	}
+	path := keyPath(dir, name)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrKeyExists, name)
+	}
+	_, privKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return nil, err
+	}
+	key := &Key{Name: name, PrivKey: privKey}
+
+	data, err := json.MarshalIndent(keyJSON{
+		Name:    name,
+		Address: key.Address(),
+		PubKey:  hex.EncodeToString(key.PubKey()),
+		PrivKey: hex.EncodeToString(privKey),
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	// O_EXCL keeps a concurrent add from overwriting the key
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("%w: %s", ErrKeyExists, name)
+		}
+		return nil, err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return key, f.Close()
+}
+
+// LoadKey reads the key named name from dir
+func LoadKey(dir, name string) (*Key, error) {
+	if !keyNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("invalid key name %q", name)
+	}
+	path := keyPath(dir, name)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s in %s", ErrKeyNotFound, name, dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var stored keyJSON
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse key %s: %w", path, err)
+	}
+	privKey, err := hex.DecodeString(stored.PrivKey)
+	if err != nil || len(privKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("key %s does not hold a valid ed25519 private key", path)
+	}
+	return &Key{Name: name, PrivKey: ed25519.PrivateKey(privKey)}, nil
+}
+
+// ListKeys returns the keys in dir ordered by name
+func ListKeys(dir string) ([]*Key, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var keys []*Key
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		key, err := LoadKey(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+	return keys, nil
+}
+
+func keyPath(dir, name string) string {
+	return filepath.Join(dir, name+".json")
+}
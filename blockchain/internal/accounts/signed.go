@@ -0,0 +1,91 @@
+package accounts
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+var (
+	// ErrInvalidSignature is returned for messages whose signature does not
+	// verify against their public key
+	ErrInvalidSignature = errors.New("invalid message signature")
+	// ErrWrongSequence is returned for messages signed with a sequence other
+	// than the account's next one, such as replayed messages
+	ErrWrongSequence = errors.New("wrong account sequence")
+	// ErrSignerMismatch is returned when the key that signed a message is not
+	// the key of the account the message acts for
+	ErrSignerMismatch = errors.New("message is not signed by its sender")
+)
+
+// Msg is a message an account signs to act on its own behalf
+type Msg interface {
+	// Type names the message, such as "tokens/mint"; it is part of the
+	// sign bytes so a signature cannot be reused for another message type
+	Type() string
+	// Signer is the address of the account that must sign the message
+	Signer() string
+}
+
+// SignedMsg is a message with the signature of its sender. Sequence is the
+// sender's next account sequence, so a signed message is accepted once.
+type SignedMsg struct {
+	Msg       json.RawMessage `json:"msg"`
+	PubKey    types.HexBytes  `json:"pub_key"`
+	Sequence  uint64          `json:"sequence"`
+	Signature types.HexBytes  `json:"signature"`
+}
+
+// signDoc is what an account signs; encoding/json writes its fields in
+// this order and compacts the message
+type signDoc struct {
+	ChainID  string          `json:"chain_id"`
+	Type     string          `json:"type"`
+	Sequence uint64          `json:"sequence"`
+	Msg      json.RawMessage `json:"msg"`
+}
+
+// SignBytes returns the bytes an account signs for a message on a chain
+func SignBytes(chainID, msgType string, sequence uint64, msg json.RawMessage) ([]byte, error) {
+	return json.Marshal(signDoc{ChainID: chainID, Type: msgType, Sequence: sequence, Msg: msg})
+}
+
+// Sign signs msg with an account key for the given chain and sequence
+func Sign(privKey ed25519.PrivateKey, chainID string, sequence uint64, msg Msg) (*SignedMsg, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	signBytes, err := SignBytes(chainID, msg.Type(), sequence, data)
+	if err != nil {
+		return nil, err
+	}
+	return &SignedMsg{
+		Msg:       data,
+		PubKey:    types.HexBytes(privKey.Public().(ed25519.PublicKey)),
+		Sequence:  sequence,
+		Signature: ed25519.Sign(privKey, signBytes),
+	}, nil
+}
+
+// Decode unmarshals the message into msg and checks that the signing key
+// belongs to the account msg names as its signer. It does not check the
+// signature; the Keeper does.
+func (s *SignedMsg) Decode(msg Msg) error {
+	if len(s.Msg) == 0 {
+		return fmt.Errorf("msg cannot be empty")
+	}
+	if err := json.Unmarshal(s.Msg, msg); err != nil {
+		return fmt.Errorf("invalid msg: %w", err)
+	}
+	if len(s.PubKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: pub_key must be a hex encoded ed25519 public key", ErrInvalidSignature)
+	}
+	if signer := Address(ed25519.PublicKey(s.PubKey)); signer != msg.Signer() {
+		return fmt.Errorf("%w: signed by %s, sent as %s", ErrSignerMismatch, signer, msg.Signer())
+	}
+	return nil
+}
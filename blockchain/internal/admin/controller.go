@@ -0,0 +1,200 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/vindexchain/blockchain/internal/config"
+	"github.com/vindexchain/blockchain/internal/snapshots"
+	"github.com/vindexchain/blockchain/internal/state"
+)
+
+var (
+	// ErrInvalidHaltHeight is returned for halt heights that were already
+	// committed
+	ErrInvalidHaltHeight = errors.New("halt height is not above the latest height")
+	// ErrNoConfigFile is returned when reloading a node started without a
+	// config file
+	ErrNoConfigFile = errors.New("the node was started without --config; there is nothing to reload")
+)
+
+// Config wires the controller to the node
+type Config struct {
+	State     *state.Store
+	Snapshots *snapshots.Manager
+	// LogLevel is the level of the node's logger
+	LogLevel zap.AtomicLevel
+	// HaltHeight stops the node after committing this height; 0 never
+	// stops it
+	HaltHeight int64
+	// OnHalt is called once, after the halt height is committed
+	OnHalt func(height int64)
+	// ConfigFile is the KEY=VALUE file the node was started with, read
+	// again on reload
+	ConfigFile string
+	Logger     *zap.Logger
+}
+
+// Controller performs the operations of the admin API on a running node
+type Controller struct {
+	config *Config
+	logger *zap.Logger
+
+	haltHeight       atomic.Int64
+	halted           atomic.Bool
+	refuseTxs        atomic.Bool
+	lastCommitHeight atomic.Int64
+
+	mu        sync.Mutex
+	reloaders map[string]func(*config.Config) error
+}
+
+// NewController creates a controller
+func NewController(cfg *Config) *Controller {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	c := &Controller{
+		config:    cfg,
+		logger:    logger,
+		reloaders: make(map[string]func(*config.Config) error),
+	}
+	c.haltHeight.Store(cfg.HaltHeight)
+	c.lastCommitHeight.Store(cfg.State.LastCommitID().Version)
+	return c
+}
+
+// Status is a summary of the settings the controller changes
+type Status struct {
+	Height           int64  `json:"height"`
+	HaltHeight       int64  `json:"halt_height"`
+	MempoolAccepting bool   `json:"mempool_accepting"`
+	LogLevel         string `json:"log_level"`
+}
+
+// Status returns the current settings
+func (c *Controller) Status() Status {
+	return Status{
+		Height:           c.lastCommitHeight.Load(),
+		HaltHeight:       c.haltHeight.Load(),
+		MempoolAccepting: c.MempoolAccepting(),
+		LogLevel:         c.config.LogLevel.Level().String(),
+	}
+}
+
+// OnCommit is called after every committed block and halts the node once
+// the halt height is reached
+func (c *Controller) OnCommit(height int64) {
+	c.lastCommitHeight.Store(height)
+	halt := c.haltHeight.Load()
+	if halt <= 0 || height < halt || !c.halted.CompareAndSwap(false, true) {
+		return
+	}
+	c.logger.Warn("Reached halt height; stopping the node", zap.Int64("height", height))
+	if c.config.OnHalt != nil {
+		c.config.OnHalt(height)
+	}
+}
+
+// SetHaltHeight schedules the node to stop after committing height. 0
+// cancels a scheduled halt.
+func (c *Controller) SetHaltHeight(height int64) error {
+	if height != 0 && height <= c.lastCommitHeight.Load() {
+		return fmt.Errorf("%w: %d <= %d", ErrInvalidHaltHeight, height, c.lastCommitHeight.Load())
+	}
+	c.haltHeight.Store(height)
+	c.logger.Info("Set halt height", zap.Int64("height", height))
+	return nil
+}
+
+// MempoolAccepting reports whether new transactions are accepted
+func (c *Controller) MempoolAccepting() bool {
+	return !c.refuseTxs.Load()
+}
+
+// SetMempoolAccepting starts or stops accepting new transactions.
+// Transactions already in the mempool are still included in blocks.
+func (c *Controller) SetMempoolAccepting(accept bool) {
+	c.refuseTxs.Store(!accept)
+	c.logger.Info("Set mempool acceptance", zap.Bool("accepting", accept))
+}
+
+// Snapshot takes a state snapshot at the latest height
+func (c *Controller) Snapshot() (*snapshots.Snapshot, error) {
+	return c.config.Snapshots.Create(c.config.State.LastCommitID().Version)
+}
+
+// Prune deletes old state versions now, keeping the keepRecent latest or,
+// for 0, as many as the pruning options keep
+func (c *Controller) Prune(keepRecent int64) (int, error) {
+	return c.config.State.Prune(keepRecent)
+}
+
+// SetLogLevel changes the level of the node's logger
+func (c *Controller) SetLogLevel(level string) error {
+	var l zapcore.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return err
+	}
+	c.config.LogLevel.SetLevel(l)
+	c.logger.Info("Set log level", zap.Stringer("level", l))
+	return nil
+}
+
+// OnReload registers a function applying the named settings of a reloaded
+// configuration. Only settings that do not affect consensus may be
+// reloaded.
+func (c *Controller) OnReload(name string, apply func(*config.Config) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reloaders[name] = apply
+}
+
+// Reload reads the config file again and applies the reloadable settings,
+// the log level and those registered with OnReload. It returns the names
+// of the settings applied; on error, the ones applied before it remain.
+func (c *Controller) Reload() ([]string, error) {
+	if c.config.ConfigFile == "" {
+		return nil, ErrNoConfigFile
+	}
+	if err := config.LoadEnvFile(c.config.ConfigFile); err != nil {
+		return nil, err
+	}
+	cfg := config.LoadConfig()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := c.SetLogLevel(cfg.LogLevel); err != nil {
+		return nil, err
+	}
+	applied := []string{"log_level"}
+
+	c.mu.Lock()
+	names := make([]string, 0, len(c.reloaders))
+	for name := range c.reloaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	reloaders := make([]func(*config.Config) error, len(names))
+	for i, name := range names {
+		reloaders[i] = c.reloaders[name]
+	}
+	c.mu.Unlock()
+
+	for i, apply := range reloaders {
+		if err := apply(cfg); err != nil {
+			return applied, fmt.Errorf("reload %s: %w", names[i], err)
+		}
+		applied = append(applied, names[i])
+	}
+	c.logger.Info("Reloaded configuration", zap.String("file", c.config.ConfigFile), zap.Strings("settings", applied))
+	return applied, nil
+}
@@ -0,0 +1,79 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// unixPrefix marks listen addresses that are unix socket paths
+const unixPrefix = "unix://"
+
+// Listen opens the admin listener on addr, either "unix://path" or
+// "host:port". A unix socket is created with mode 0600, replacing a stale
+// socket left by a previous run, so only the node's user can connect.
+func Listen(addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, unixPrefix)
+	if !ok {
+		return net.Listen("tcp", strings.TrimPrefix(addr, "tcp://"))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, errors.New("admin socket path exists and is not a socket: " + path)
+		}
+		os.Remove(path)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// IsUnix reports whether addr is a unix socket address
+func IsUnix(addr string) bool {
+	return strings.HasPrefix(addr, unixPrefix)
+}
+
+// IsLoopback reports whether addr can only be reached from this host: a
+// unix socket, or a TCP address on a loopback interface
+func IsLoopback(addr string) bool {
+	if IsUnix(addr) {
+		return true
+	}
+	host, _, err := net.SplitHostPort(strings.TrimPrefix(addr, "tcp://"))
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Dialer returns a dial function for HTTP clients of the admin listener at
+// addr, connecting to the unix socket or TCP address whatever host the
+// request names
+func Dialer(addr string) func(ctx context.Context, network, address string) (net.Conn, error) {
+	var d net.Dialer
+	if path, ok := strings.CutPrefix(addr, unixPrefix); ok {
+		return func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return d.DialContext(ctx, "unix", path)
+		}
+	}
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return d.DialContext(ctx, "tcp", strings.TrimPrefix(addr, "tcp://"))
+	}
+}
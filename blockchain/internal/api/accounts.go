@@ -0,0 +1,114 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/accounts"
+	"github.com/vindexchain/blockchain/internal/app"
+	"github.com/vindexchain/blockchain/internal/bank"
+)
+
+// AccountHandler serves account balances and the sequences clients sign
+// messages with
+type AccountHandler struct {
+	accounts *accounts.Keeper
+	bank     *bank.Keeper
+	mempool  *app.Mempool
+	logger   *zap.Logger
+}
+
+// NewAccountHandler creates a handler for the account keeper and the ledger
+func NewAccountHandler(keeper *accounts.Keeper, bankKeeper *bank.Keeper, mempool *app.Mempool, logger *zap.Logger) *AccountHandler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &AccountHandler{accounts: keeper, bank: bankKeeper, mempool: mempool, logger: logger}
+}
+
+// GetBalance handles GET /accounts/:address/balance with every non-zero
+// balance of the address in the ledger, ordered by denom
+func (h *AccountHandler) GetBalance(c *gin.Context) {
+	address := c.Param("address")
+	if err := bank.ValidateAddress(address); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	amounts := h.bank.GetBalances(address)
+	balances := make([]bank.Coin, 0, len(amounts))
+	for denom, amount := range amounts {
+		balances = append(balances, bank.Coin{Denom: denom, Amount: strconv.FormatUint(amount, 10)})
+	}
+	sort.Slice(balances, func(i, j int) bool { return balances[i].Denom < balances[j].Denom })
+	c.JSON(http.StatusOK, gin.H{
+		"address":  address,
+		"balances": balances,
+	})
+}
+
+// GetSequence handles GET /accounts/:address/sequence with the sequence and
+// chain ID the account's next signed message must carry. The sequence
+// counts the account's transactions waiting in the mempool.
+func (h *AccountHandler) GetSequence(c *gin.Context) {
+	address := c.Param("address")
+	if err := bank.ValidateAddress(address); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"address":  address,
+		"sequence": h.mempool.NextSequence(address),
+		"chain_id": h.accounts.ChainID(),
+	})
+}
+
+// Send handles POST /transactions/send with a bank.MsgSend signed by its
+// sender. The transfer executes when a block includes it.
+func (h *AccountHandler) Send(c *gin.Context) {
+	submitTx(c, h.mempool, bank.MsgSend{}.Type())
+}
+
+// submitTx reads a message signed by its sender from the request body,
+// {"msg": {...}, "pub_key": "...", "sequence": n, "signature": "..."}, and
+// adds it to the mempool as a transaction of msgType
+func submitTx(c *gin.Context, mempool *app.Mempool, msgType string) {
+	var signed accounts.SignedMsg
+	if err := c.ShouldBindJSON(&signed); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	addTx(c, mempool, app.NewTx(msgType, &signed))
+}
+
+// addTx adds a transaction to the mempool. The message executes when a
+// block includes it, so the response only carries the hash its result is
+// indexed under.
+func addTx(c *gin.Context, mempool *app.Mempool, tx *app.Tx) {
+	data, err := tx.Encode()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	hash, err := mempool.Add(data)
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, accounts.ErrInvalidSignature), errors.Is(err, accounts.ErrSignerMismatch):
+			status = http.StatusUnauthorized
+		case errors.Is(err, accounts.ErrWrongSequence), errors.Is(err, app.ErrTxInMempool):
+			status = http.StatusConflict
+		case errors.Is(err, app.ErrTxTooLarge):
+			status = http.StatusRequestEntityTooLarge
+		case errors.Is(err, app.ErrMempoolFull):
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"success": true, "type": tx.Type, "hash": hash})
+}
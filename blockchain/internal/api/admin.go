@@ -0,0 +1,178 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/admin"
+	"github.com/vindexchain/blockchain/internal/snapshots"
+	"github.com/vindexchain/blockchain/internal/state"
+)
+
+// AdminHandler serves the node operations of the admin listener
+type AdminHandler struct {
+	controller *admin.Controller
+	logger     *zap.Logger
+}
+
+type haltRequest struct {
+	Height *int64 `json:"height" binding:"required"` // 0 cancels a scheduled halt
+}
+
+type mempoolRequest struct {
+	Accept *bool `json:"accept" binding:"required"`
+}
+
+type pruneRequest struct {
+	KeepRecent int64 `json:"keep_recent"` // the pruning options' value when 0
+}
+
+type logLevelRequest struct {
+	Level string `json:"level" binding:"required"`
+}
+
+// NewAdminHandler creates a handler for the admin controller
+func NewAdminHandler(controller *admin.Controller, logger *zap.Logger) *AdminHandler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &AdminHandler{controller: controller, logger: logger}
+}
+
+// GetStatus handles GET /admin/status
+func (h *AdminHandler) GetStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.controller.Status())
+}
+
+// SetHaltHeight handles POST /admin/halt
+func (h *AdminHandler) SetHaltHeight(c *gin.Context) {
+	var req haltRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.controller.SetHaltHeight(*req.Height); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, h.controller.Status())
+}
+
+// SetMempool handles POST /admin/mempool
+func (h *AdminHandler) SetMempool(c *gin.Context) {
+	var req mempoolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.controller.SetMempoolAccepting(*req.Accept)
+	c.JSON(http.StatusOK, h.controller.Status())
+}
+
+// CreateSnapshot handles POST /admin/snapshot. It returns once the
+// snapshot is written.
+func (h *AdminHandler) CreateSnapshot(c *gin.Context) {
+	snapshot, err := h.controller.Snapshot()
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, snapshots.ErrSnapshotInProgress) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"snapshot": snapshot})
+}
+
+// Prune handles POST /admin/prune
+func (h *AdminHandler) Prune(c *gin.Context) {
+	var req pruneRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.KeepRecent < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keep_recent cannot be negative"})
+		return
+	}
+	pruned, err := h.controller.Prune(req.KeepRecent)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, state.ErrInvalidPruning) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"pruned": pruned})
+}
+
+// SetLogLevel handles PUT /admin/log-level
+func (h *AdminHandler) SetLogLevel(c *gin.Context) {
+	var req logLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.controller.SetLogLevel(req.Level); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, h.controller.Status())
+}
+
+// Reload handles POST /admin/reload
+func (h *AdminHandler) Reload(c *gin.Context) {
+	applied, err := h.controller.Reload()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "reloaded": applied})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reloaded": applied})
+}
+
+// Pprof handles GET /debug/pprof/*profile with the net/http/pprof
+// handlers, such as /debug/pprof/goroutine?debug=2 for a goroutine dump
+func (h *AdminHandler) Pprof(c *gin.Context) {
+	switch name := strings.TrimPrefix(c.Param("profile"), "/"); name {
+	case "":
+		pprof.Index(c.Writer, c.Request)
+	case "cmdline":
+		pprof.Cmdline(c.Writer, c.Request)
+	case "profile":
+		pprof.Profile(c.Writer, c.Request)
+	case "symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "trace":
+		pprof.Trace(c.Writer, c.Request)
+	default:
+		pprof.Handler(name).ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// MempoolGate is middleware refusing POST requests with 503 while
+// accepting reports false, except on routes under the exempt prefixes,
+// such as simulation and admin routes that submit no transactions
+func MempoolGate(accepting func() bool, exempt ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodPost || accepting() {
+			c.Next()
+			return
+		}
+		for _, prefix := range exempt {
+			if strings.HasPrefix(c.Request.URL.Path, prefix) {
+				c.Next()
+				return
+			}
+		}
+		c.Header("Retry-After", "60")
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "the node is not accepting transactions"})
+	}
+}
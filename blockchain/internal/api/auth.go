@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/vindexchain/blockchain/internal/auth"
+)
+
+// principalKey is the gin context key of the authenticated principal
+const principalKey = "auth.principal"
+
+// Authenticate is middleware that identifies clients sending a JWT or API
+// key as "Authorization: Bearer <token>" or "X-API-Key: <key>". Requests
+// without credentials continue anonymously; invalid credentials are
+// refused, so a client never silently loses its scopes.
+func Authenticate(authenticator *auth.Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.GetHeader("X-API-Key")
+		if bearer, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+			token = bearer
+		}
+		if token == "" {
+			c.Next()
+			return
+		}
+		principal, err := authenticator.Authenticate(strings.TrimSpace(token))
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+			return
+		}
+		c.Set(principalKey, principal)
+		c.Next()
+	}
+}
+
+// RequireScope is middleware that refuses requests whose principal lacks
+// scope. It must run after Authenticate.
+func RequireScope(scope auth.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := PrincipalFrom(c)
+		if principal == nil {
+			c.Header("WWW-Authenticate", `Bearer scope="`+string(scope)+`"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+		if !principal.Has(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "missing scope " + string(scope)})
+			return
+		}
+		c.Next()
+	}
+}
+
+// PrincipalFrom returns the authenticated principal of a request, or nil
+func PrincipalFrom(c *gin.Context) *auth.Principal {
+	if v, ok := c.Get(principalKey); ok {
+		return v.(*auth.Principal)
+	}
+	return nil
+}
+
+// RequireClientCert is middleware that refuses requests not made over TLS
+// with a client certificate the listener verified against its client CA.
+// It guards admin routes with mTLS on top of scopes.
+func RequireClientCert() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.TLS == nil || len(c.Request.TLS.VerifiedChains) == 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "client certificate required"})
+			return
+		}
+		c.Next()
+	}
+}
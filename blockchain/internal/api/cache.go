@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/cache"
+)
+
+// ResponseCache caches successful GET responses in a cache store. Entries
+// are keyed by the chain height, so a new block invalidates them on every
+// node sharing the store.
+type ResponseCache struct {
+	store  cache.Store
+	height func() int64
+	ttl    time.Duration
+	logger *zap.Logger
+}
+
+type cachedResponse struct {
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// NewResponseCache creates a response cache. height returns the latest
+// committed height; ttl bounds how long a response is served within one
+// height, for data like peers that changes between blocks.
+func NewResponseCache(store cache.Store, height func() int64, ttl time.Duration, logger *zap.Logger) *ResponseCache {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &ResponseCache{store: store, height: height, ttl: ttl, logger: logger}
+}
+
+// Handler is middleware that serves cached responses and caches 200s.
+// Requests the store cannot serve fall through to the handler.
+func (rc *ResponseCache) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet || c.GetHeader("Cache-Control") == "no-cache" {
+			c.Next()
+			return
+		}
+		ctx := c.Request.Context()
+		key := "resp:" + strconv.FormatInt(rc.height(), 10) + ":" + c.Request.URL.RequestURI()
+
+		if data, err := rc.store.Get(ctx, key); err != nil {
+			rc.logger.Warn("Response cache read failed", zap.Error(err))
+		} else if data != nil {
+			var cached cachedResponse
+			if err := json.Unmarshal(data, &cached); err == nil {
+				c.Header("X-Cache", "HIT")
+				c.Data(http.StatusOK, cached.ContentType, cached.Body)
+				c.Abort()
+				return
+			}
+		}
+
+		buf := &bufferedWriter{ResponseWriter: c.Writer}
+		c.Writer = buf
+		c.Header("X-Cache", "MISS")
+		c.Next()
+		c.Writer = buf.ResponseWriter
+		buf.flush()
+
+		if buf.Status() != http.StatusOK {
+			return
+		}
+		data, _ := json.Marshal(cachedResponse{
+			ContentType: buf.Header().Get("Content-Type"),
+			Body:        buf.body.Bytes(),
+		})
+		if err := rc.store.Set(ctx, key, data, rc.ttl); err != nil {
+			rc.logger.Warn("Response cache write failed", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// ErrInvalidCORS is returned for CORS settings that do not parse or that
+// browsers would reject
+var ErrInvalidCORS = errors.New("invalid CORS policy")
+
+// exposedHeaders are the response headers browser clients may read
+var exposedHeaders = []string{
+	"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset",
+	"X-Quota-Limit", "X-Quota-Remaining", "X-Quota-Reset",
+	"Retry-After", "X-Cache",
+}
+
+// CORSConfig holds the CORS settings of the node configuration
+type CORSConfig struct {
+	// Origins are comma-separated allowed origins. "*" allows any origin
+	// and "https://*.vindex.io" any subdomain of vindex.io.
+	Origins     string
+	Methods     string
+	Headers     string
+	Credentials bool
+	MaxAge      time.Duration
+	// RouteOrigins overrides Origins for route groups, as
+	// "prefix=origins;prefix=origins". An empty list refuses cross-origin
+	// requests, e.g. "/api/v1/admin=".
+	RouteOrigins string
+}
+
+// CORSPolicy is the CORS policy of the routes under Prefix
+type CORSPolicy struct {
+	Prefix      string
+	Origins     []string
+	Methods     []string
+	Headers     []string
+	Credentials bool
+	MaxAge      time.Duration
+}
+
+// ParseCORSPolicies returns the default policy, with an empty prefix, and
+// the route group overrides
+func ParseCORSPolicies(cfg *CORSConfig) ([]CORSPolicy, error) {
+	base := CORSPolicy{
+		Origins:     splitList(cfg.Origins),
+		Methods:     splitList(strings.ToUpper(cfg.Methods)),
+		Headers:     splitList(cfg.Headers),
+		Credentials: cfg.Credentials,
+		MaxAge:      cfg.MaxAge,
+	}
+	policies := []CORSPolicy{base}
+	for _, group := range strings.Split(cfg.RouteOrigins, ";") {
+		group = strings.TrimSpace(group)
+		if group == "" {
+			continue
+		}
+		prefix, origins, ok := strings.Cut(group, "=")
+		if !ok || !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("%w: %q is not prefix=origins", ErrInvalidCORS, group)
+		}
+		policy := base
+		policy.Prefix = strings.TrimSpace(prefix)
+		policy.Origins = splitList(origins)
+		policies = append(policies, policy)
+	}
+
+	for _, p := range policies {
+		if err := p.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return policies, nil
+}
+
+// ExposesWrites reports whether the policy lets any origin send requests
+// that change state
+func (p CORSPolicy) ExposesWrites() bool {
+	if !p.allowsAll() {
+		return false
+	}
+	for _, m := range p.Methods {
+		switch m {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			return true
+		}
+	}
+	return false
+}
+
+func (p CORSPolicy) allowsAll() bool {
+	for _, o := range p.Origins {
+		if o == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func (p CORSPolicy) validate() error {
+	name := p.Prefix
+	if name == "" {
+		name = "default"
+	}
+	if p.Credentials && p.allowsAll() {
+		return fmt.Errorf("%w: %s allows credentials from any origin; list the origins", ErrInvalidCORS, name)
+	}
+	for _, o := range p.Origins {
+		if o == "*" {
+			continue
+		}
+		if strings.Count(o, "*") > 1 {
+			return fmt.Errorf("%w: %s origin %q has more than one *", ErrInvalidCORS, name, o)
+		}
+		if !strings.HasPrefix(o, "http://") && !strings.HasPrefix(o, "https://") {
+			return fmt.Errorf("%w: %s origin %q must start with http:// or https://", ErrInvalidCORS, name, o)
+		}
+	}
+	return nil
+}
+
+func (p CORSPolicy) config() cors.Config {
+	config := cors.Config{
+		AllowMethods:     p.Methods,
+		AllowHeaders:     p.Headers,
+		ExposeHeaders:    exposedHeaders,
+		AllowCredentials: p.Credentials,
+		AllowWildcard:    true,
+		MaxAge:           p.MaxAge,
+	}
+	switch {
+	case p.allowsAll():
+		config.AllowAllOrigins = true
+	case len(p.Origins) == 0:
+		config.AllowOriginFunc = func(string) bool { return false }
+	default:
+		config.AllowOrigins = p.Origins
+	}
+	return config
+}
+
+// CORS is middleware applying the policy with the longest prefix matching
+// the request path. It runs for every request, including preflights of
+// routes that only exist for other methods.
+func CORS(policies []CORSPolicy) (gin.HandlerFunc, error) {
+	type route struct {
+		prefix  string
+		handler gin.HandlerFunc
+	}
+	routes := make([]route, 0, len(policies))
+	for _, p := range policies {
+		if err := p.validate(); err != nil {
+			return nil, err
+		}
+		config := p.config()
+		if err := config.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCORS, err)
+		}
+		routes = append(routes, route{prefix: p.Prefix, handler: cors.New(config)})
+	}
+	sort.SliceStable(routes, func(i, j int) bool { return len(routes[i].prefix) > len(routes[j].prefix) })
+
+	return func(c *gin.Context) {
+		for _, r := range routes {
+			if strings.HasPrefix(c.Request.URL.Path, r.prefix) {
+				r.handler(c)
+				return
+			}
+		}
+		c.Next()
+	}, nil
+}
+
+// CheckOrigin returns a WebSocket origin check applying the policy with the
+// longest prefix matching the request path. Requests without an Origin
+// header do not come from a browser page and are allowed.
+func CheckOrigin(policies []CORSPolicy) func(r *http.Request) bool {
+	sorted := append([]CORSPolicy(nil), policies...)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i].Prefix) > len(sorted[j].Prefix) })
+
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		for _, p := range sorted {
+			if strings.HasPrefix(r.URL.Path, p.Prefix) {
+				return p.allowsOrigin(origin)
+			}
+		}
+		return false
+	}
+}
+
+// allowsOrigin matches an origin against the policy's origins; a * in an
+// origin stands for any text
+func (p CORSPolicy) allowsOrigin(origin string) bool {
+	for _, o := range p.Origins {
+		if o == "*" || o == origin {
+			return true
+		}
+		if before, after, ok := strings.Cut(o, "*"); ok &&
+			len(origin) >= len(before)+len(after) &&
+			strings.HasPrefix(origin, before) && strings.HasSuffix(origin, after) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseCORSPolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     CORSConfig
+		want    []CORSPolicy
+		wantErr bool
+	}{
+		{
+			name: "default only",
+			cfg:  CORSConfig{Origins: "https://app.vindex.io, https://*.vindex.io", Methods: "get,post", Headers: "Content-Type"},
+			want: []CORSPolicy{{
+				Origins: []string{"https://app.vindex.io", "https://*.vindex.io"},
+				Methods: []string{"GET", "POST"},
+				Headers: []string{"Content-Type"},
+			}},
+		},
+		{
+			name: "route overrides",
+			cfg:  CORSConfig{Origins: "*", Methods: "GET", RouteOrigins: "/api/v1/admin=; /api/v1/keys=https://dash.vindex.io"},
+			want: []CORSPolicy{
+				{Origins: []string{"*"}, Methods: []string{"GET"}, Headers: []string{}},
+				{Prefix: "/api/v1/admin", Origins: []string{}, Methods: []string{"GET"}, Headers: []string{}},
+				{Prefix: "/api/v1/keys", Origins: []string{"https://dash.vindex.io"}, Methods: []string{"GET"}, Headers: []string{}},
+			},
+		},
+		{name: "credentials with any origin", cfg: CORSConfig{Origins: "*", Credentials: true}, wantErr: true},
+		{name: "credentials with any origin on a route", cfg: CORSConfig{Origins: "https://a.io", Credentials: true, RouteOrigins: "/x=*"}, wantErr: true},
+		{name: "two wildcards", cfg: CORSConfig{Origins: "https://*.*.io"}, wantErr: true},
+		{name: "no scheme", cfg: CORSConfig{Origins: "vindex.io"}, wantErr: true},
+		{name: "route without prefix", cfg: CORSConfig{RouteOrigins: "admin=https://a.io"}, wantErr: true},
+		{name: "route without origins", cfg: CORSConfig{RouteOrigins: "/admin"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCORSPolicies(&tt.cfg)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidCORS) {
+					t.Fatalf("got %+v, %v; want ErrInvalidCORS", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExposesWrites(t *testing.T) {
+	tests := []struct {
+		policy CORSPolicy
+		want   bool
+	}{
+		{CORSPolicy{Origins: []string{"*"}, Methods: []string{"GET"}}, false},
+		{CORSPolicy{Origins: []string{"*"}, Methods: []string{"GET", "POST"}}, true},
+		{CORSPolicy{Origins: []string{"https://a.io"}, Methods: []string{"POST"}}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.ExposesWrites(); got != tt.want {
+			t.Errorf("%+v ExposesWrites = %v; want %v", tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	check := CheckOrigin([]CORSPolicy{
+		{Origins: []string{"https://app.vindex.io", "https://*.vindex.io"}},
+		{Prefix: "/api/v1/admin"},
+		{Prefix: "/api/v1/dex", Origins: []string{"*"}},
+	})
+	tests := []struct {
+		path   string
+		origin string
+		want   bool
+	}{
+		{"/api/v1/blocks", "", true},
+		{"/api/v1/blocks", "https://app.vindex.io", true},
+		{"/api/v1/blocks", "https://wallet.vindex.io", true},
+		{"/api/v1/blocks", "https://evil.io", false},
+		{"/api/v1/blocks", "https://vindex.io.evil.io", false},
+		{"/api/v1/blocks", "http://wallet.vindex.io", false},
+		{"/api/v1/admin/keys", "https://app.vindex.io", false},
+		{"/api/v1/admin/keys", "", true},
+		{"/api/v1/dex/candles/ws", "https://evil.io", true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.path, nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if got := check(r); got != tt.want {
+			t.Errorf("origin %q on %s allowed = %v; want %v", tt.origin, tt.path, got, tt.want)
+		}
+	}
+}
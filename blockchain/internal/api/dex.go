@@ -0,0 +1,334 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/app"
+	"github.com/vindexchain/blockchain/internal/bank"
+	"github.com/vindexchain/blockchain/internal/dex"
+	"github.com/vindexchain/blockchain/internal/indexer"
+)
+
+// defaultCandleCount is how many candles are returned when from is omitted
+const defaultCandleCount = 500
+
+// DexHandler serves the BurnSwap AMM endpoints. Its POST endpoints take
+// messages signed by their sender and add them to the mempool; they
+// execute when a block includes them.
+type DexHandler struct {
+	keeper   *dex.Keeper
+	indexer  *indexer.DexIndexer
+	mempool  *app.Mempool
+	upgrader websocket.Upgrader
+	logger   *zap.Logger
+}
+
+// NewDexHandler creates a handler for the DEX module. The indexer may be nil,
+// in which case trade history comes from the keeper's in-memory buffer and
+// candles are unavailable. Candle streams accept the browser origins the
+// CORS policies allow.
+func NewDexHandler(keeper *dex.Keeper, dexIndexer *indexer.DexIndexer, mempool *app.Mempool, corsPolicies []CORSPolicy, logger *zap.Logger) *DexHandler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &DexHandler{
+		keeper:  keeper,
+		indexer: dexIndexer,
+		mempool: mempool,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin:     CheckOrigin(corsPolicies),
+		},
+		logger: logger,
+	}
+}
+
+// GetPools handles GET /dex/pools
+func (h *DexHandler) GetPools(c *gin.Context) {
+	pools := h.keeper.GetPools()
+	c.JSON(http.StatusOK, gin.H{
+		"pools": pools,
+		"count": len(pools),
+	})
+}
+
+// GetPool handles GET /dex/pools/:id
+func (h *DexHandler) GetPool(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid pool id"})
+		return
+	}
+
+	pool, err := h.keeper.GetPool(id)
+	if err != nil {
+		respondDexError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pool":       pool,
+		"spot_price": pool.SpotPrice(),
+	})
+}
+
+// GetTrades handles GET /dex/trades?pool_id=&cursor=&limit=
+func (h *DexHandler) GetTrades(c *gin.Context) {
+	poolID, err := strconv.ParseUint(c.DefaultQuery("pool_id", "0"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid pool_id"})
+		return
+	}
+	h.respondTrades(c, poolID)
+}
+
+// GetPoolTrades handles GET /dex/pools/:id/trades?cursor=&limit=
+func (h *DexHandler) GetPoolTrades(c *gin.Context) {
+	poolID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid pool id"})
+		return
+	}
+	if _, err := h.keeper.GetPool(poolID); err != nil {
+		respondDexError(c, err)
+		return
+	}
+	h.respondTrades(c, poolID)
+}
+
+// GetCandles handles GET /dex/pools/:id/candles?interval=&from=&to=
+// from and to are unix seconds; to defaults to now and from to 500 intervals earlier.
+func (h *DexHandler) GetCandles(c *gin.Context) {
+	poolID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid pool id"})
+		return
+	}
+	interval := c.DefaultQuery("interval", "1m")
+	d, err := indexer.ParseInterval(interval)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	to := time.Now()
+	if raw := c.Query("to"); raw != "" {
+		secs, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"})
+			return
+		}
+		to = time.Unix(secs, 0)
+	}
+	from := to.Add(-defaultCandleCount * d)
+	if raw := c.Query("from"); raw != "" {
+		secs, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+			return
+		}
+		from = time.Unix(secs, 0)
+	}
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+
+	if h.indexer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "DEX indexer is not enabled"})
+		return
+	}
+	candles, err := h.indexer.Candles(c.Request.Context(), poolID, interval, from, to)
+	if err != nil {
+		h.logger.Error("Failed to query candles", zap.Uint64("pool_id", poolID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query candles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pool_id":  poolID,
+		"interval": interval,
+		"candles":  candles,
+		"count":    len(candles),
+	})
+}
+
+// StreamCandles handles GET /dex/pools/:id/candles/stream?interval= and pushes
+// every update of the pool's current candle over a WebSocket
+func (h *DexHandler) StreamCandles(c *gin.Context) {
+	poolID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid pool id"})
+		return
+	}
+	if h.indexer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "DEX indexer is not enabled"})
+		return
+	}
+	updates, cancel, err := h.indexer.Subscribe(poolID, c.DefaultQuery("interval", "1m"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer cancel()
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		h.logger.Debug("Candle stream upgrade failed", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	// The client never sends data; reading detects when it goes away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case candle, ok := <-updates:
+			if !ok {
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := conn.WriteJSON(gin.H{"type": "candle", "data": candle}); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+func (h *DexHandler) respondTrades(c *gin.Context, poolID uint64) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+		return
+	}
+	cursor, err := strconv.ParseUint(c.DefaultQuery("cursor", "0"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+		return
+	}
+
+	if h.indexer == nil {
+		if cursor != 0 {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "trade history requires the DEX indexer"})
+			return
+		}
+		trades := h.keeper.GetTrades(poolID, limit)
+		c.JSON(http.StatusOK, gin.H{
+			"trades": trades,
+			"count":  len(trades),
+		})
+		return
+	}
+
+	trades, next, err := h.indexer.Trades(c.Request.Context(), poolID, cursor, limit)
+	if err != nil {
+		h.logger.Error("Failed to query trades", zap.Uint64("pool_id", poolID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query trades"})
+		return
+	}
+	response := gin.H{
+		"trades": trades,
+		"count":  len(trades),
+	}
+	if next != 0 {
+		response["next_cursor"] = strconv.FormatUint(next, 10)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetQuote handles GET /dex/quote?denom_in=&denom_out=&amount_in= (or &amount_out=)
+func (h *DexHandler) GetQuote(c *gin.Context) {
+	denomIn, denomOut := c.Query("denom_in"), c.Query("denom_out")
+	if denomIn == "" || denomOut == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "denom_in and denom_out are required"})
+		return
+	}
+
+	var (
+		quote *dex.Quote
+		err   error
+	)
+	switch amountIn, amountOut := c.Query("amount_in"), c.Query("amount_out"); {
+	case amountIn != "" && amountOut == "":
+		var amount uint64
+		if amount, err = strconv.ParseUint(amountIn, 10, 64); err != nil || amount == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid amount_in"})
+			return
+		}
+		quote, err = h.keeper.QuoteExactIn(denomIn, denomOut, amount)
+	case amountOut != "" && amountIn == "":
+		var amount uint64
+		if amount, err = strconv.ParseUint(amountOut, 10, 64); err != nil || amount == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid amount_out"})
+			return
+		}
+		quote, err = h.keeper.QuoteExactOut(denomIn, denomOut, amount)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "exactly one of amount_in or amount_out is required"})
+		return
+	}
+	if err != nil {
+		respondDexError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+
+// CreatePool handles POST /dex/pools
+func (h *DexHandler) CreatePool(c *gin.Context) {
+	submitTx(c, h.mempool, dex.MsgCreatePool{}.Type())
+}
+
+// AddLiquidity handles POST /dex/liquidity/add
+func (h *DexHandler) AddLiquidity(c *gin.Context) {
+	submitTx(c, h.mempool, dex.MsgAddLiquidity{}.Type())
+}
+
+// RemoveLiquidity handles POST /dex/liquidity/remove
+func (h *DexHandler) RemoveLiquidity(c *gin.Context) {
+	submitTx(c, h.mempool, dex.MsgRemoveLiquidity{}.Type())
+}
+
+// SwapExactIn handles POST /dex/swap/exact-in
+func (h *DexHandler) SwapExactIn(c *gin.Context) {
+	submitTx(c, h.mempool, dex.MsgSwapExactIn{}.Type())
+}
+
+// SwapExactOut handles POST /dex/swap/exact-out
+func (h *DexHandler) SwapExactOut(c *gin.Context) {
+	submitTx(c, h.mempool, dex.MsgSwapExactOut{}.Type())
+}
+
+func respondDexError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, dex.ErrPoolNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, dex.ErrPoolExists):
+		status = http.StatusConflict
+	case errors.Is(err, dex.ErrSlippage), errors.Is(err, dex.ErrSharesLocked),
+		errors.Is(err, dex.ErrInsufficientLiquidity), errors.Is(err, bank.ErrInsufficientFunds):
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/accounts"
+	"github.com/vindexchain/blockchain/internal/app"
+	"github.com/vindexchain/blockchain/internal/bank"
+	"github.com/vindexchain/blockchain/internal/domains"
+)
+
+// DomainHandler serves the .vindex name service endpoints. Its POST
+// endpoints take messages signed by their sender, except reserved list
+// updates, which carry a governance signature.
+type DomainHandler struct {
+	domains *domains.DomainSystem
+	mempool *app.Mempool
+	logger  *zap.Logger
+}
+
+// NewDomainHandler creates a handler for the domain system
+func NewDomainHandler(domainSystem *domains.DomainSystem, mempool *app.Mempool, logger *zap.Logger) *DomainHandler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &DomainHandler{domains: domainSystem, mempool: mempool, logger: logger}
+}
+
+// GetDomains handles GET /domains?owner=
+func (h *DomainHandler) GetDomains(c *gin.Context) {
+	list := h.domains.GetDomains(c.Query("owner"))
+	c.JSON(http.StatusOK, gin.H{
+		"domains": list,
+		"count":   len(list),
+	})
+}
+
+// GetDomain handles GET /domains/:name. Unregistered names are reported
+// too, along with the reason they cannot be registered.
+func (h *DomainHandler) GetDomain(c *gin.Context) {
+	availability, err := h.domains.Availability(c.Param("name"))
+	if err != nil {
+		respondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, availability)
+}
+
+// GetPrice handles GET /domains/:name/price?years=
+func (h *DomainHandler) GetPrice(c *gin.Context) {
+	years, err := strconv.ParseUint(c.DefaultQuery("years", "1"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid years"})
+		return
+	}
+
+	name := c.Param("name")
+	registration, err := h.domains.RegistrationPrice(name, uint32(years))
+	if err != nil {
+		respondDomainError(c, err)
+		return
+	}
+	renewal, err := h.domains.RenewalPrice(name, uint32(years))
+	if err != nil {
+		respondDomainError(c, err)
+		return
+	}
+
+	label, unicodeLabel, _ := domains.Normalize(name)
+	c.JSON(http.StatusOK, gin.H{
+		"name":               domains.FullName(label),
+		"unicode_name":       domains.FullName(unicodeLabel),
+		"years":              years,
+		"registration_price": registration,
+		"renewal_price":      renewal,
+	})
+}
+
+// RegisterDomain handles POST /domains/register
+func (h *DomainHandler) RegisterDomain(c *gin.Context) {
+	submitTx(c, h.mempool, domains.MsgRegisterDomain{}.Type())
+}
+
+// RenewDomain handles POST /domains/renew
+func (h *DomainHandler) RenewDomain(c *gin.Context) {
+	submitTx(c, h.mempool, domains.MsgRenewDomain{}.Type())
+}
+
+// Resolve handles GET /domains/resolve/:name
+func (h *DomainHandler) Resolve(c *gin.Context) {
+	resolution, err := h.domains.Resolve(c.Param("name"))
+	if err != nil {
+		respondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resolution)
+}
+
+// Reverse handles GET /domains/reverse/:address
+func (h *DomainHandler) Reverse(c *gin.Context) {
+	address := c.Param("address")
+
+	resolution, err := h.domains.ReverseLookup(address)
+	if err != nil {
+		respondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"address":      address,
+		"name":         resolution.Name,
+		"unicode_name": resolution.UnicodeName,
+	})
+}
+
+// GetSubdomains handles GET /domains/:name/subdomains
+func (h *DomainHandler) GetSubdomains(c *gin.Context) {
+	subs, err := h.domains.GetSubdomains(c.Param("name"))
+	if err != nil {
+		respondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"subdomains": subs,
+		"count":      len(subs),
+	})
+}
+
+// SetRecords handles POST /domains/records
+func (h *DomainHandler) SetRecords(c *gin.Context) {
+	submitTx(c, h.mempool, domains.MsgSetRecords{}.Type())
+}
+
+// CreateSubdomain handles POST /domains/subdomains/create
+func (h *DomainHandler) CreateSubdomain(c *gin.Context) {
+	submitTx(c, h.mempool, domains.MsgCreateSubdomain{}.Type())
+}
+
+// DeleteSubdomain handles POST /domains/subdomains/delete
+func (h *DomainHandler) DeleteSubdomain(c *gin.Context) {
+	submitTx(c, h.mempool, domains.MsgDeleteSubdomain{}.Type())
+}
+
+// SetPrimaryName handles POST /domains/primary
+func (h *DomainHandler) SetPrimaryName(c *gin.Context) {
+	submitTx(c, h.mempool, domains.MsgSetPrimaryName{}.Type())
+}
+
+// GetListings handles GET /domains/listings
+func (h *DomainHandler) GetListings(c *gin.Context) {
+	listings := h.domains.GetListings()
+	c.JSON(http.StatusOK, gin.H{
+		"listings": listings,
+		"count":    len(listings),
+	})
+}
+
+// GetAuctions handles GET /domains/auctions
+func (h *DomainHandler) GetAuctions(c *gin.Context) {
+	auctions := h.domains.GetAuctions()
+	c.JSON(http.StatusOK, gin.H{
+		"auctions": auctions,
+		"count":    len(auctions),
+	})
+}
+
+// GetAuction handles GET /domains/auctions/:name
+func (h *DomainHandler) GetAuction(c *gin.Context) {
+	auction, err := h.domains.GetAuction(c.Param("name"))
+	if err != nil {
+		respondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, auction)
+}
+
+// TransferDomain handles POST /domains/transfer
+func (h *DomainHandler) TransferDomain(c *gin.Context) {
+	submitTx(c, h.mempool, domains.MsgTransferDomain{}.Type())
+}
+
+// ListDomain handles POST /domains/list
+func (h *DomainHandler) ListDomain(c *gin.Context) {
+	submitTx(c, h.mempool, domains.MsgListDomain{}.Type())
+}
+
+// CancelListing handles POST /domains/listings/cancel
+func (h *DomainHandler) CancelListing(c *gin.Context) {
+	submitTx(c, h.mempool, domains.MsgCancelListing{}.Type())
+}
+
+// BuyDomain handles POST /domains/buy
+func (h *DomainHandler) BuyDomain(c *gin.Context) {
+	submitTx(c, h.mempool, domains.MsgBuyDomain{}.Type())
+}
+
+// StartAuction handles POST /domains/auctions/start
+func (h *DomainHandler) StartAuction(c *gin.Context) {
+	submitTx(c, h.mempool, domains.MsgStartAuction{}.Type())
+}
+
+// CommitBid handles POST /domains/auctions/commit
+func (h *DomainHandler) CommitBid(c *gin.Context) {
+	submitTx(c, h.mempool, domains.MsgCommitBid{}.Type())
+}
+
+// RevealBid handles POST /domains/auctions/reveal
+func (h *DomainHandler) RevealBid(c *gin.Context) {
+	submitTx(c, h.mempool, domains.MsgRevealBid{}.Type())
+}
+
+// FinalizeAuction handles POST /domains/auctions/finalize
+func (h *DomainHandler) FinalizeAuction(c *gin.Context) {
+	submitTx(c, h.mempool, domains.MsgFinalizeAuction{}.Type())
+}
+
+// GetReservedNames handles GET /domains/reserved
+func (h *DomainHandler) GetReservedNames(c *gin.Context) {
+	reserved := h.domains.GetReservedNames()
+	c.JSON(http.StatusOK, gin.H{
+		"reserved":     reserved,
+		"count":        len(reserved),
+		"update_nonce": h.domains.ReservedUpdateNonce(),
+	})
+}
+
+// ClaimReservedName handles POST /domains/reserved/claim
+func (h *DomainHandler) ClaimReservedName(c *gin.Context) {
+	submitTx(c, h.mempool, domains.MsgClaimReservedName{}.Type())
+}
+
+// UpdateReservedNames handles POST /domains/reserved/update. The message
+// carries a governance signature instead of an account's; the domain
+// system checks it when a block executes the update.
+func (h *DomainHandler) UpdateReservedNames(c *gin.Context) {
+	var msg domains.MsgUpdateReservedNames
+	if err := c.ShouldBindJSON(&msg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	addTx(c, h.mempool, &app.Tx{Type: msg.Type(), SignedMsg: accounts.SignedMsg{Msg: data}})
+}
+
+func respondDomainError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, domains.ErrDomainNotFound), errors.Is(err, domains.ErrNoPrimaryName),
+		errors.Is(err, domains.ErrListingNotFound), errors.Is(err, domains.ErrAuctionNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, domains.ErrUnauthorized), errors.Is(err, domains.ErrInvalidSignature):
+		status = http.StatusForbidden
+	case errors.Is(err, domains.ErrDomainTaken), errors.Is(err, domains.ErrSubdomainExists),
+		errors.Is(err, domains.ErrDomainListed):
+		status = http.StatusConflict
+	case errors.Is(err, domains.ErrDomainExpired), errors.Is(err, domains.ErrAuctionRequired),
+		errors.Is(err, domains.ErrAuctionPhase), errors.Is(err, domains.ErrInvalidBid),
+		errors.Is(err, domains.ErrNameReserved), errors.Is(err, domains.ErrConfusable),
+		errors.Is(err, bank.ErrInsufficientFunds):
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
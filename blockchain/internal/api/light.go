@@ -0,0 +1,154 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/light"
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+// LightBlockStore is the block store light blocks are served from
+type LightBlockStore interface {
+	Height() int64
+	LoadBlock(height int64) (*types.Block, *types.Commit, error)
+	LoadValidators(height int64) (*types.ValidatorSet, error)
+}
+
+// StateProver proves values of the committed application state. The value
+// under a light route's key must be the JSON the endpoint returns in the
+// route's field, so a light client can check one against the other.
+type StateProver interface {
+	// ProveState returns the value under key in the latest committed state
+	// with a proof against the app hash in the next header. Keys with no
+	// value get a proof of absence.
+	ProveState(key []byte) (*types.ValueProof, error)
+}
+
+// LightHandler serves light blocks and attaches state proofs to queries
+type LightHandler struct {
+	store  LightBlockStore
+	prover StateProver
+	routes []light.Route
+	logger *zap.Logger
+}
+
+// NewLightHandler creates a light client handler
+func NewLightHandler(store LightBlockStore, prover StateProver, logger *zap.Logger) *LightHandler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &LightHandler{store: store, prover: prover, routes: light.DefaultRoutes, logger: logger}
+}
+
+// GetLightBlock handles GET /light_blocks/:height, where height may be "latest"
+func (h *LightHandler) GetLightBlock(c *gin.Context) {
+	height := h.store.Height()
+	if param := c.Param("height"); param != "latest" {
+		var err error
+		if height, err = strconv.ParseInt(param, 10, 64); err != nil || height <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid height"})
+			return
+		}
+	}
+
+	block, commit, err := h.store.LoadBlock(height)
+	if err != nil || block == nil || commit == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "light block not found"})
+		return
+	}
+	vals, err := h.store.LoadValidators(height)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "validator set not found"})
+		return
+	}
+	header := block.Header
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"light_block": &types.LightBlock{Header: &header, Commit: commit, ValidatorSet: vals},
+	})
+}
+
+// ProveQueries is middleware that adds a "proof" field to responses of
+// light routes when the request has ?prove=true. Not-found responses get a
+// proof that the key is absent. The state can move on
+// between the handler reading it and the proof being taken; clients see a
+// mismatch and retry.
+func (h *LightHandler) ProveQueries() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet || c.Query("prove") != "true" {
+			c.Next()
+			return
+		}
+		route, ok := light.FindRoute(h.routes, c.FullPath())
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "proofs are not available for this endpoint"})
+			return
+		}
+
+		buf := &bufferedWriter{ResponseWriter: c.Writer}
+		c.Writer = buf
+		c.Next()
+		c.Writer = buf.ResponseWriter
+
+		status := buf.Status()
+		if status != http.StatusOK && status != http.StatusNotFound {
+			buf.flush()
+			return
+		}
+		var body map[string]json.RawMessage
+		if err := json.Unmarshal(buf.body.Bytes(), &body); err != nil {
+			buf.flush()
+			return
+		}
+
+		params := make(map[string]string, len(c.Params))
+		for _, p := range c.Params {
+			params[p.Key] = p.Value
+		}
+		proof, err := h.prover.ProveState(route.StateKey(params))
+		if err != nil {
+			h.logger.Error("Failed to prove state", zap.String("path", c.FullPath()), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to prove state: " + err.Error()})
+			return
+		}
+		body["proof"], _ = json.Marshal(proof)
+		c.JSON(status, body)
+	}
+}
+
+// bufferedWriter holds a handler's response so middleware can amend it
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int) { w.status = code }
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) { return w.body.Write(data) }
+
+func (w *bufferedWriter) WriteString(s string) (int, error) { return w.body.WriteString(s) }
+
+func (w *bufferedWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int { return w.body.Len() }
+
+func (w *bufferedWriter) Written() bool { return w.body.Len() > 0 || w.status != 0 }
+
+func (w *bufferedWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.Status())
+	w.ResponseWriter.Write(w.body.Bytes())
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/p2p"
+)
+
+// NetHandler serves the p2p network endpoints
+type NetHandler struct {
+	node   *p2p.Node
+	logger *zap.Logger
+}
+
+// peerInfo is the public view of a connected peer
+type peerInfo struct {
+	ID             p2p.ID    `json:"id"`
+	Moniker        string    `json:"moniker"`
+	Address        string    `json:"address"`
+	ListenAddr     string    `json:"listen_addr"`
+	Version        string    `json:"version"`
+	Outbound       bool      `json:"outbound"`
+	Persistent     bool      `json:"persistent"`
+	Score          int64     `json:"score"`
+	ConnectedSince time.Time `json:"connected_since"`
+}
+
+type banPeerRequest struct {
+	Duration string `json:"duration"` // Go duration such as "24h"; the node default when empty
+	Reason   string `json:"reason"`
+}
+
+// NewNetHandler creates a handler for the p2p node
+func NewNetHandler(node *p2p.Node, logger *zap.Logger) *NetHandler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &NetHandler{node: node, logger: logger}
+}
+
+// GetPeers handles GET /net/peers
+func (h *NetHandler) GetPeers(c *gin.Context) {
+	peers := h.node.Peers()
+	list := make([]peerInfo, 0, len(peers))
+	for _, peer := range peers {
+		info := peer.NodeInfo()
+		list = append(list, peerInfo{
+			ID:             peer.ID(),
+			Moniker:        info.Moniker,
+			Address:        peer.SocketAddr().String(),
+			ListenAddr:     info.ListenAddr,
+			Version:        info.Version,
+			Outbound:       peer.IsOutbound(),
+			Persistent:     peer.IsPersistent(),
+			Score:          peer.Score(),
+			ConnectedSince: peer.CreatedAt(),
+		})
+	}
+
+	outbound, inbound := h.node.NumPeers()
+	c.JSON(http.StatusOK, gin.H{
+		"node_info":       h.node.NodeInfo(),
+		"peers":           list,
+		"count":           len(list),
+		"outbound":        outbound,
+		"inbound":         inbound,
+		"known_addresses": h.node.AddrBook().Size(),
+	})
+}
+
+// GetBannedPeers handles GET /admin/peers/banned
+func (h *NetHandler) GetBannedPeers(c *gin.Context) {
+	bans := h.node.AddrBook().Bans()
+	c.JSON(http.StatusOK, gin.H{"bans": bans, "count": len(bans)})
+}
+
+// BanPeer handles POST /admin/peers/:id/ban
+func (h *NetHandler) BanPeer(c *gin.Context) {
+	var req banPeerRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	duration := time.Duration(0)
+	if req.Duration != "" {
+		var err error
+		if duration, err = time.ParseDuration(req.Duration); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid duration: " + err.Error()})
+			return
+		}
+	}
+	reason := req.Reason
+	if reason == "" {
+		reason = "banned by operator"
+	}
+
+	ban, err := h.node.BanPeer(p2p.ID(c.Param("id")), duration, reason)
+	if err != nil {
+		respondNetError(c, err)
+		return
+	}
+	h.logger.Info("Peer banned via admin API", zap.String("peer", string(ban.ID)), zap.String("client_ip", c.ClientIP()))
+	c.JSON(http.StatusOK, gin.H{"success": true, "ban": ban})
+}
+
+// UnbanPeer handles POST /admin/peers/:id/unban
+func (h *NetHandler) UnbanPeer(c *gin.Context) {
+	id := p2p.ID(c.Param("id"))
+	if err := h.node.UnbanPeer(id); err != nil {
+		respondNetError(c, err)
+		return
+	}
+	h.logger.Info("Peer unbanned via admin API", zap.String("peer", string(id)), zap.String("client_ip", c.ClientIP()))
+	c.JSON(http.StatusOK, gin.H{"success": true, "id": id})
+}
+
+func respondNetError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, p2p.ErrPeerNotBanned) {
+		status = http.StatusNotFound
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
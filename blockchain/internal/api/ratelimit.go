@@ -0,0 +1,240 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/cache"
+)
+
+// ErrInvalidRateLimit is returned for rate limit and quota settings that do
+// not parse
+var ErrInvalidRateLimit = errors.New("invalid rate limit")
+
+// RouteLimit applies a token bucket to the routes under a path prefix
+type RouteLimit struct {
+	Prefix string
+	Limit  cache.Limit
+}
+
+// RateLimitConfig configures the rate limiter
+type RateLimitConfig struct {
+	Store cache.Store
+	// Default applies to routes without a RouteLimit
+	Default cache.Limit
+	// Routes override Default; the longest matching prefix wins
+	Routes []RouteLimit
+	// Quotas are daily request allowances by API key tier. Clients whose
+	// tier has no quota, and anonymous clients, have none.
+	Quotas map[string]int64
+	Logger *zap.Logger
+}
+
+// RateLimiter limits requests per client and route group. Clients are told
+// apart by API key or JWT subject, and by IP when they send neither.
+type RateLimiter struct {
+	store  cache.Store
+	logger *zap.Logger
+
+	mu     sync.RWMutex
+	routes []RouteLimit
+	quotas map[string]int64
+}
+
+// NewRateLimiter creates a rate limiter
+func NewRateLimiter(cfg *RateLimitConfig) *RateLimiter {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	rl := &RateLimiter{store: cfg.Store, logger: logger}
+	rl.SetLimits(cfg.Default, cfg.Routes, cfg.Quotas)
+	return rl
+}
+
+// SetLimits replaces the limits and quotas of a running limiter. Buckets
+// and quota counts already in the store are kept.
+func (rl *RateLimiter) SetLimits(def cache.Limit, routes []RouteLimit, quotas map[string]int64) {
+	sorted := append([]RouteLimit{{Prefix: "", Limit: def}}, routes...)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i].Prefix) > len(sorted[j].Prefix) })
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.routes, rl.quotas = sorted, quotas
+}
+
+// Handler is middleware enforcing the limits. It sets X-RateLimit-Limit,
+// X-RateLimit-Remaining and X-RateLimit-Reset (seconds until the bucket is
+// full), and the X-Quota-* equivalents for clients with a daily quota, and
+// refuses requests over either with 429 and Retry-After. It must run
+// after Authenticate. When the store fails, requests are allowed.
+func (rl *RateLimiter) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client := rl.client(c)
+		ctx := c.Request.Context()
+
+		route := rl.route(c)
+		if route.Limit.Rate > 0 {
+			res, err := rl.store.Take(ctx, "rl:"+route.Prefix+":"+client, route.Limit)
+			if err != nil {
+				rl.logger.Warn("Rate limit store failed", zap.Error(err))
+			} else {
+				c.Header("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+				c.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+				c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+				if !res.Allowed {
+					rl.reject(c, res.RetryAfter, "rate limit exceeded")
+					return
+				}
+			}
+		}
+
+		if principal := PrincipalFrom(c); principal != nil {
+			if quota := rl.quota(principal.Tier); quota > 0 {
+				now := time.Now().UTC()
+				midnight := now.Truncate(24 * time.Hour).Add(24 * time.Hour)
+				key := "quota:" + now.Format("20060102") + ":" + client
+				used, err := rl.store.Incr(ctx, key, midnight.Sub(now)+time.Minute)
+				if err != nil {
+					rl.logger.Warn("Quota store failed", zap.Error(err))
+				} else {
+					c.Header("X-Quota-Limit", strconv.FormatInt(quota, 10))
+					c.Header("X-Quota-Remaining", strconv.FormatInt(max64(quota-used, 0), 10))
+					c.Header("X-Quota-Reset", strconv.Itoa(ceilSeconds(midnight.Sub(now))))
+					if used > quota {
+						rl.reject(c, midnight.Sub(now), "daily quota exceeded for tier "+principal.Tier)
+						return
+					}
+				}
+			}
+		}
+		c.Next()
+	}
+}
+
+// client identifies the caller for limits and quotas
+func (rl *RateLimiter) client(c *gin.Context) string {
+	if principal := PrincipalFrom(c); principal != nil {
+		if principal.KeyID != "" {
+			return "key:" + principal.KeyID
+		}
+		return principal.Method + ":" + principal.Subject
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// route finds the limit of the longest prefix matching the request's route
+func (rl *RateLimiter) route(c *gin.Context) RouteLimit {
+	path := c.FullPath()
+	if path == "" {
+		path = c.Request.URL.Path
+	}
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	for _, route := range rl.routes[:len(rl.routes)-1] {
+		if strings.HasPrefix(path, route.Prefix) {
+			return route
+		}
+	}
+	return rl.routes[len(rl.routes)-1] // the default
+}
+
+func (rl *RateLimiter) quota(tier string) int64 {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	return rl.quotas[tier]
+}
+
+func (rl *RateLimiter) reject(c *gin.Context, retryAfter time.Duration, msg string) {
+	seconds := ceilSeconds(retryAfter)
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error":       msg,
+		"retry_after": seconds,
+	})
+}
+
+// ParseRouteLimits parses "prefix=rate:burst" pairs separated by commas,
+// such as "/api/v1/transactions/broadcast=2:5,/api/v1/blocks=50:100". Rate
+// is requests per second; a rate of 0 leaves the routes unlimited.
+func ParseRouteLimits(s string) ([]RouteLimit, error) {
+	var routes []RouteLimit
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		prefix, spec, ok := strings.Cut(part, "=")
+		if !ok || !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("%w: %q is not prefix=rate:burst", ErrInvalidRateLimit, part)
+		}
+		limit, err := ParseLimit(spec)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, RouteLimit{Prefix: prefix, Limit: limit})
+	}
+	return routes, nil
+}
+
+// ParseLimit parses "rate:burst"; burst defaults to twice the rate
+func ParseLimit(s string) (cache.Limit, error) {
+	rateText, burstText, hasBurst := strings.Cut(strings.TrimSpace(s), ":")
+	rate, err := strconv.ParseFloat(rateText, 64)
+	if err != nil || rate < 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+		return cache.Limit{}, fmt.Errorf("%w: invalid rate %q", ErrInvalidRateLimit, rateText)
+	}
+	burst := int(math.Ceil(rate * 2))
+	if hasBurst {
+		if burst, err = strconv.Atoi(burstText); err != nil || burst < 1 {
+			return cache.Limit{}, fmt.Errorf("%w: invalid burst %q", ErrInvalidRateLimit, burstText)
+		}
+	}
+	if rate > 0 && burst < 1 {
+		burst = 1
+	}
+	return cache.Limit{Rate: rate, Burst: burst}, nil
+}
+
+// ParseQuotas parses "tier=requests" pairs separated by commas, such as
+// "free=10000,pro=1000000"
+func ParseQuotas(s string) (map[string]int64, error) {
+	quotas := make(map[string]int64)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		tier, text, ok := strings.Cut(part, "=")
+		quota, err := strconv.ParseInt(text, 10, 64)
+		if !ok || tier == "" || err != nil || quota < 0 {
+			return nil, fmt.Errorf("%w: %q is not tier=requests", ErrInvalidRateLimit, part)
+		}
+		quotas[tier] = quota
+	}
+	return quotas, nil
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/vindexchain/blockchain/internal/cache"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    cache.Limit
+		wantErr bool
+	}{
+		{in: "10", want: cache.Limit{Rate: 10, Burst: 20}},
+		{in: "10:15", want: cache.Limit{Rate: 10, Burst: 15}},
+		{in: " 2:5 ", want: cache.Limit{Rate: 2, Burst: 5}},
+		{in: "0.2", want: cache.Limit{Rate: 0.2, Burst: 1}},
+		{in: "0.1:3", want: cache.Limit{Rate: 0.1, Burst: 3}},
+		{in: "0", want: cache.Limit{Rate: 0, Burst: 0}},
+		{in: "", wantErr: true},
+		{in: "fast", wantErr: true},
+		{in: "-1", wantErr: true},
+		{in: "Inf", wantErr: true},
+		{in: "NaN", wantErr: true},
+		{in: "10:0", wantErr: true},
+		{in: "10:", wantErr: true},
+		{in: "10:x", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidRateLimit) {
+				t.Errorf("ParseLimit(%q) = %+v, %v; want ErrInvalidRateLimit", tt.in, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseLimit(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseRouteLimits(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []RouteLimit
+		wantErr bool
+	}{
+		{in: "", want: nil},
+		{in: " , ", want: nil},
+		{
+			in: "/api/v1/transactions/broadcast=2:5, /api/v1/blocks=50",
+			want: []RouteLimit{
+				{Prefix: "/api/v1/transactions/broadcast", Limit: cache.Limit{Rate: 2, Burst: 5}},
+				{Prefix: "/api/v1/blocks", Limit: cache.Limit{Rate: 50, Burst: 100}},
+			},
+		},
+		{in: "/api/v1/status=0", want: []RouteLimit{{Prefix: "/api/v1/status", Limit: cache.Limit{}}}},
+		{in: "api/v1/blocks=5", wantErr: true},
+		{in: "/api/v1/blocks", wantErr: true},
+		{in: "/api/v1/blocks=x:1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRouteLimits(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidRateLimit) {
+				t.Errorf("ParseRouteLimits(%q) = %+v, %v; want ErrInvalidRateLimit", tt.in, got, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseRouteLimits(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseQuotas(t *testing.T) {
+	got, err := ParseQuotas("free=10000, pro=1000000")
+	if err != nil || !reflect.DeepEqual(got, map[string]int64{"free": 10000, "pro": 1000000}) {
+		t.Errorf("ParseQuotas = %v, %v", got, err)
+	}
+	for _, in := range []string{"free", "=5", "free=-1", "free=lots"} {
+		if _, err := ParseQuotas(in); !errors.Is(err, ErrInvalidRateLimit) {
+			t.Errorf("ParseQuotas(%q) = %v; want ErrInvalidRateLimit", in, err)
+		}
+	}
+}
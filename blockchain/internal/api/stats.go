@@ -0,0 +1,47 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/bank"
+)
+
+// StatsHandler serves ledger statistics of the native denom
+type StatsHandler struct {
+	bank   *bank.Keeper
+	denom  string
+	logger *zap.Logger
+}
+
+// NewStatsHandler creates a statistics handler for a denom
+func NewStatsHandler(bankKeeper *bank.Keeper, denom string, logger *zap.Logger) *StatsHandler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &StatsHandler{bank: bankKeeper, denom: denom, logger: logger}
+}
+
+// AddLedgerBurned is middleware for GET /stats/burn that adds a
+// "ledger_burned" field to the burn statistics of the handler after it:
+// the total of the denom burned by swap fees, name sales, token burns and
+// auction forfeits. The rest of the response is left as it is.
+func (h *StatsHandler) AddLedgerBurned() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		buf := &bufferedWriter{ResponseWriter: c.Writer}
+		c.Writer = buf
+		c.Next()
+		c.Writer = buf.ResponseWriter
+
+		var body map[string]json.RawMessage
+		if buf.Status() != http.StatusOK || json.Unmarshal(buf.body.Bytes(), &body) != nil || body == nil {
+			buf.flush()
+			return
+		}
+		body["ledger_burned"], _ = json.Marshal(h.bank.GetBurned(h.denom))
+		c.JSON(http.StatusOK, body)
+	}
+}
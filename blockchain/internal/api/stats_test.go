@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/vindexchain/blockchain/internal/bank"
+)
+
+func TestAddLedgerBurned(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ledger := bank.NewKeeper(nil)
+	if err := ledger.Mint("vindex1alice000000000000000", "oc", 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := ledger.Burn("vindex1alice000000000000000", "oc", 40); err != nil {
+		t.Fatal(err)
+	}
+	stats := NewStatsHandler(ledger, "oc", nil)
+
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantStatus int
+		wantBody   map[string]interface{}
+	}{
+		{
+			name:       "adds the ledger total",
+			status:     http.StatusOK,
+			body:       `{"total_burned": "12", "burn_rate": "0.001"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   map[string]interface{}{"total_burned": "12", "burn_rate": "0.001", "ledger_burned": float64(40)},
+		},
+		{
+			name:       "error passes through",
+			status:     http.StatusInternalServerError,
+			body:       `{"error": "stats unavailable"}`,
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   map[string]interface{}{"error": "stats unavailable"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/stats/burn", stats.AddLedgerBurned(), func(c *gin.Context) {
+				c.Data(tt.status, "application/json", []byte(tt.body))
+			})
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stats/burn", nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d; want %d", w.Code, tt.wantStatus)
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if len(body) != len(tt.wantBody) {
+				t.Errorf("body = %v; want %v", body, tt.wantBody)
+			}
+			for key, want := range tt.wantBody {
+				if body[key] != want {
+					t.Errorf("%s = %v; want %v", key, body[key], want)
+				}
+			}
+		})
+	}
+}
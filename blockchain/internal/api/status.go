@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/blocksync"
+	"github.com/vindexchain/blockchain/internal/p2p"
+)
+
+// StatusHandler serves the node status, including block sync progress
+type StatusHandler struct {
+	node      *p2p.Node
+	blockSync *blocksync.Reactor
+	logger    *zap.Logger
+}
+
+// NewStatusHandler creates a status handler
+func NewStatusHandler(node *p2p.Node, blockSync *blocksync.Reactor, logger *zap.Logger) *StatusHandler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &StatusHandler{node: node, blockSync: blockSync, logger: logger}
+}
+
+// GetStatus handles GET /status
+func (h *StatusHandler) GetStatus(c *gin.Context) {
+	info := h.node.NodeInfo()
+	sync := h.blockSync.Status()
+	outbound, inbound := h.node.NumPeers()
+	c.JSON(http.StatusOK, gin.H{
+		"chain_id":          info.ChainID,
+		"node_id":           info.ID,
+		"moniker":           info.Moniker,
+		"version":           info.Version,
+		"catching_up":       sync.CatchingUp,
+		"latest_height":     sync.LatestHeight,
+		"latest_block_hash": sync.LatestBlockHash,
+		"latest_block_time": sync.LatestBlockTime,
+		"target_height":     sync.TargetHeight,
+		"peers":             outbound + inbound,
+	})
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/app"
+	"github.com/vindexchain/blockchain/internal/bank"
+	"github.com/vindexchain/blockchain/internal/tokens"
+)
+
+// TokenAdminHandler serves the token lifecycle endpoints. Its POST endpoints take messages signed by their sender and add them to the mempool.
+type TokenAdminHandler struct {
+	factory *tokens.TokenFactory
+	mempool *app.Mempool
+	logger  *zap.Logger
+}
+
+// NewTokenAdminHandler creates a handler for token admin operations
+func NewTokenAdminHandler(factory *tokens.TokenFactory, mempool *app.Mempool, logger *zap.Logger) *TokenAdminHandler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &TokenAdminHandler{factory: factory, mempool: mempool, logger: logger}
+}
+
+// GetHolders handles GET /tokens/:denom/holders
+func (h *TokenAdminHandler) GetHolders(c *gin.Context) {
+	denom := c.Param("denom")
+
+	holders, err := h.factory.Holders(denom)
+	if err != nil {
+		respondTokenError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"denom":   denom,
+		"holders": holders,
+		"count":   len(holders),
+	})
+}
+
+// CreateToken handles POST /tokens/create
+func (h *TokenAdminHandler) CreateToken(c *gin.Context) {
+	submitTx(c, h.mempool, tokens.MsgCreateToken{}.Type())
+}
+
+// Mint handles POST /tokens/mint
+func (h *TokenAdminHandler) Mint(c *gin.Context) {
+	submitTx(c, h.mempool, tokens.MsgMint{}.Type())
+}
+
+// Burn handles POST /tokens/burn
+func (h *TokenAdminHandler) Burn(c *gin.Context) {
+	submitTx(c, h.mempool, tokens.MsgBurn{}.Type())
+}
+
+// ChangeAdmin handles POST /tokens/change-admin
+func (h *TokenAdminHandler) ChangeAdmin(c *gin.Context) {
+	submitTx(c, h.mempool, tokens.MsgChangeAdmin{}.Type())
+}
+
+// RenounceAdmin handles POST /tokens/renounce-admin
+func (h *TokenAdminHandler) RenounceAdmin(c *gin.Context) {
+	submitTx(c, h.mempool, tokens.MsgRenounceAdmin{}.Type())
+}
+
+// Freeze handles POST /tokens/freeze
+func (h *TokenAdminHandler) Freeze(c *gin.Context) {
+	submitTx(c, h.mempool, tokens.MsgFreeze{}.Type())
+}
+
+// Unfreeze handles POST /tokens/unfreeze
+func (h *TokenAdminHandler) Unfreeze(c *gin.Context) {
+	submitTx(c, h.mempool, tokens.MsgUnfreeze{}.Type())
+}
+
+func respondTokenError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, tokens.ErrTokenNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, tokens.ErrUnauthorized):
+		status = http.StatusForbidden
+	case errors.Is(err, tokens.ErrMaxSupplyExceeded), errors.Is(err, tokens.ErrFrozen), errors.Is(err, bank.ErrInsufficientFunds):
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
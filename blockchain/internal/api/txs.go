@@ -0,0 +1,173 @@
+package api
+
+import (
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/indexer"
+)
+
+// TxSearchHandler serves indexed transaction and block event queries
+type TxSearchHandler struct {
+	indexer *indexer.TxIndexer
+	logger  *zap.Logger
+}
+
+// NewTxSearchHandler creates a handler for the transaction indexer
+func NewTxSearchHandler(txIndexer *indexer.TxIndexer, logger *zap.Logger) *TxSearchHandler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &TxSearchHandler{indexer: txIndexer, logger: logger}
+}
+
+// SearchTransactions handles GET /transactions/search?query=&page=&per_page=&order_by=
+// query is a conjunction of conditions such as
+// transfer.recipient='vindex1...' AND tx.memo CONTAINS 'INV-2024' AND tx.height>100.
+func (h *TxSearchHandler) SearchTransactions(c *gin.Context) {
+	q, ok := parseSearchQuery(c)
+	if !ok {
+		return
+	}
+	opts, ok := parseSearchOptions(c, "asc")
+	if !ok {
+		return
+	}
+
+	txs, total, err := h.indexer.SearchTxs(q, opts)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"query":    q.String(),
+		"txs":      txs,
+		"total":    total,
+		"page":     opts.Page,
+		"per_page": opts.PerPage,
+	})
+}
+
+// SearchBlocks handles GET /blocks/search?query=&page=&per_page=&order_by=
+// over the events blocks emit outside transactions, keyed by block.height
+func (h *TxSearchHandler) SearchBlocks(c *gin.Context) {
+	q, ok := parseSearchQuery(c)
+	if !ok {
+		return
+	}
+	opts, ok := parseSearchOptions(c, "asc")
+	if !ok {
+		return
+	}
+
+	heights, total, err := h.indexer.SearchBlocks(q, opts)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"query":    q.String(),
+		"heights":  heights,
+		"total":    total,
+		"page":     opts.Page,
+		"per_page": opts.PerPage,
+	})
+}
+
+// GetTransaction handles GET /transactions/:hash with the indexed result
+// and events of the transaction
+func (h *TxSearchHandler) GetTransaction(c *gin.Context) {
+	hash, err := hex.DecodeString(strings.TrimPrefix(c.Param("hash"), "0x"))
+	if err != nil || len(hash) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid transaction hash"})
+		return
+	}
+	tx, err := h.indexer.GetTx(hash)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "tx": tx})
+}
+
+// GetAccountTransactions handles GET /accounts/:address/transactions?page=&per_page=&order_by=
+// with the transactions that sent funds from or to the address, newest
+// first unless order_by=asc
+func (h *TxSearchHandler) GetAccountTransactions(c *gin.Context) {
+	opts, ok := parseSearchOptions(c, "desc")
+	if !ok {
+		return
+	}
+
+	address := c.Param("address")
+	txs, total, err := h.indexer.AccountTxs(address, opts)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"address":  address,
+		"txs":      txs,
+		"total":    total,
+		"page":     opts.Page,
+		"per_page": opts.PerPage,
+	})
+}
+
+func (h *TxSearchHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, indexer.ErrInvalidQuery), errors.Is(err, indexer.ErrKeyNotIndexed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, indexer.ErrTxNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error("Transaction index query failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "transaction index query failed"})
+	}
+}
+
+func parseSearchQuery(c *gin.Context) (*indexer.Query, bool) {
+	raw := c.Query("query")
+	if raw == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "query is required"})
+		return nil, false
+	}
+	q, err := indexer.ParseQuery(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return q, true
+}
+
+func parseSearchOptions(c *gin.Context, defaultOrder string) (indexer.SearchOptions, bool) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid page"})
+		return indexer.SearchOptions{}, false
+	}
+	perPage, err := strconv.Atoi(c.DefaultQuery("per_page", "30"))
+	if err != nil || perPage < 1 || perPage > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "per_page must be between 1 and 100"})
+		return indexer.SearchOptions{}, false
+	}
+	opts := indexer.SearchOptions{Page: page, PerPage: perPage}
+	switch c.DefaultQuery("order_by", defaultOrder) {
+	case "asc":
+	case "desc":
+		opts.Desc = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order_by must be asc or desc"})
+		return indexer.SearchOptions{}, false
+	}
+	return opts, true
+}
@@ -0,0 +1,199 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/accounts"
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+const (
+	defaultMaxMempoolTxs = 5000
+	defaultMaxTxBytes    = 64 * 1024
+)
+
+var (
+	// ErrMempoolFull is returned when the mempool holds its maximum number
+	// of transactions
+	ErrMempoolFull = errors.New("mempool is full")
+	// ErrTxInMempool is returned for a transaction that is already waiting
+	ErrTxInMempool = errors.New("transaction is already in the mempool")
+	// ErrTxTooLarge is returned for transactions over the size limit
+	ErrTxTooLarge = errors.New("transaction is too large")
+)
+
+// MempoolConfig configures the mempool
+type MempoolConfig struct {
+	Router     *Router
+	Accounts   *accounts.Keeper
+	MaxTxs     int // 0 for 5000
+	MaxTxBytes int // 0 for 64 KiB
+	Logger     *zap.Logger
+}
+
+// Mempool holds checked transactions until a proposer reaps them into a
+// block. An account's transactions must carry consecutive sequences
+// following its committed one, so they apply in the order they arrived.
+type Mempool struct {
+	router     *Router
+	accounts   *accounts.Keeper
+	maxTxs     int
+	maxTxBytes int
+	logger     *zap.Logger
+
+	mu     sync.Mutex
+	txs    []*mempoolTx      // in arrival order
+	hashes map[string]bool   // of txs
+	next   map[string]uint64 // signer -> sequence after its waiting transactions
+}
+
+type mempoolTx struct {
+	data     []byte
+	hash     string
+	signer   string
+	sequence uint64
+}
+
+// NewMempool creates an empty mempool
+func NewMempool(cfg *MempoolConfig) *Mempool {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	maxTxs := cfg.MaxTxs
+	if maxTxs <= 0 {
+		maxTxs = defaultMaxMempoolTxs
+	}
+	maxTxBytes := cfg.MaxTxBytes
+	if maxTxBytes <= 0 {
+		maxTxBytes = defaultMaxTxBytes
+	}
+	return &Mempool{
+		router:     cfg.Router,
+		accounts:   cfg.Accounts,
+		maxTxs:     maxTxs,
+		maxTxBytes: maxTxBytes,
+		logger:     logger,
+		hashes:     make(map[string]bool),
+		next:       make(map[string]uint64),
+	}
+}
+
+// Add checks a transaction and queues it for the next blocks. It returns
+// the hash the transaction's result is indexed under.
+func (m *Mempool) Add(data []byte) (types.HexBytes, error) {
+	if len(data) > m.maxTxBytes {
+		return nil, fmt.Errorf("%w: %d bytes, at most %d", ErrTxTooLarge, len(data), m.maxTxBytes)
+	}
+	tx, signer, err := m.router.CheckTx(data)
+	if err != nil {
+		return nil, err
+	}
+	hash := types.TxHash(data)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.hashes[string(hash)] {
+		return nil, ErrTxInMempool
+	}
+	if len(m.txs) >= m.maxTxs {
+		return nil, ErrMempoolFull
+	}
+	if signer != "" {
+		if next := m.nextSequence(signer); tx.Sequence != next {
+			return nil, fmt.Errorf("%w: %s is at sequence %d, message has %d", accounts.ErrWrongSequence, signer, next, tx.Sequence)
+		}
+		m.next[signer] = tx.Sequence + 1
+	}
+	m.txs = append(m.txs, &mempoolTx{data: data, hash: string(hash), signer: signer, sequence: tx.Sequence})
+	m.hashes[string(hash)] = true
+	return hash, nil
+}
+
+// NextSequence returns the sequence the next transaction of an address
+// must carry, counting its transactions waiting in the mempool
+func (m *Mempool) NextSequence(address string) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.nextSequence(address)
+}
+
+func (m *Mempool) nextSequence(address string) uint64 {
+	if next, ok := m.next[address]; ok {
+		return next
+	}
+	return m.accounts.Sequence(address)
+}
+
+// Reap returns up to maxTxs waiting transactions in arrival order, or all
+// of them when maxTxs is not positive. They stay in the mempool until a
+// block including them is committed.
+func (m *Mempool) Reap(maxTxs int) [][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := len(m.txs)
+	if maxTxs > 0 && maxTxs < n {
+		n = maxTxs
+	}
+	txs := make([][]byte, n)
+	for i := range txs {
+		txs[i] = m.txs[i].data
+	}
+	return txs
+}
+
+// Size returns the number of waiting transactions
+func (m *Mempool) Size() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.txs)
+}
+
+// Update is registered as a block results listener. It removes the
+// committed block's transactions and the ones the block made stale, such
+// as transactions of an account whose sequence moved past them.
+func (m *Mempool) Update(results *types.BlockResults) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	committed := make(map[string]bool, len(results.TxResults))
+	for _, result := range results.TxResults {
+		committed[string(result.Hash)] = true
+	}
+
+	m.next = make(map[string]uint64)
+	kept := m.txs[:0]
+	dropped := 0
+	for _, tx := range m.txs {
+		if committed[tx.hash] {
+			delete(m.hashes, tx.hash)
+			continue
+		}
+		if tx.signer != "" {
+			if tx.sequence != m.nextSequence(tx.signer) {
+				delete(m.hashes, tx.hash)
+				dropped++
+				continue
+			}
+			m.next[tx.signer] = tx.sequence + 1
+		}
+		kept = append(kept, tx)
+	}
+	for i := len(kept); i < len(m.txs); i++ {
+		m.txs[i] = nil
+	}
+	m.txs = kept
+
+	if dropped > 0 {
+		m.logger.Debug("Dropped stale transactions from the mempool",
+			zap.Int64("height", results.Height),
+			zap.Int("count", dropped),
+		)
+	}
+}
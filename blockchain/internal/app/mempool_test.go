@@ -0,0 +1,83 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vindexchain/blockchain/internal/accounts"
+)
+
+func TestMempoolAdd(t *testing.T) {
+	r, keeper, _ := newTestRouter(t)
+	m := NewMempool(&MempoolConfig{Router: r, Accounts: keeper, MaxTxs: 3, MaxTxBytes: 1024})
+	alice, bob := newTestAccount(t), newTestAccount(t)
+
+	first := alice.note(t, 0, "one")
+	tests := []struct {
+		name string
+		tx   []byte
+		err  error
+	}{
+		{"first", first, nil},
+		{"duplicate", first, ErrTxInMempool},
+		{"skipped sequence", alice.note(t, 2, "three"), accounts.ErrWrongSequence},
+		{"next sequence", alice.note(t, 1, "two"), nil},
+		{"too large", alice.note(t, 2, string(make([]byte, 1024))), ErrTxTooLarge},
+		{"invalid", alice.note(t, 2, ""), errEmptyNote},
+		{"other account", bob.note(t, 0, "hi"), nil},
+		{"full", alice.note(t, 2, "three"), ErrMempoolFull},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := m.Add(tt.tx); !errors.Is(err, tt.err) {
+				t.Errorf("Add = %v; want %v", err, tt.err)
+			}
+		})
+	}
+
+	if size := m.Size(); size != 3 {
+		t.Errorf("size = %d; want 3", size)
+	}
+	if next := m.NextSequence(alice.address); next != 2 {
+		t.Errorf("alice's next sequence = %d; want 2", next)
+	}
+	if next := m.NextSequence(newTestAccount(t).address); next != 0 {
+		t.Errorf("new account's next sequence = %d; want 0", next)
+	}
+}
+
+func TestMempoolUpdate(t *testing.T) {
+	r, keeper, _ := newTestRouter(t)
+	m := NewMempool(&MempoolConfig{Router: r, Accounts: keeper})
+	alice := newTestAccount(t)
+
+	for seq, text := range []string{"one", "two", "three"} {
+		if _, err := m.Add(alice.note(t, uint64(seq), text)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reaped := m.Reap(2)
+	if len(reaped) != 2 || m.Size() != 3 {
+		t.Fatalf("reaped %d of %d transactions", len(reaped), m.Size())
+	}
+
+	m.Update(r.DeliverBlock(1, time.Now(), reaped))
+	if size := m.Size(); size != 1 {
+		t.Errorf("size after the block = %d; want 1", size)
+	}
+	if next := m.NextSequence(alice.address); next != 3 {
+		t.Errorf("alice's next sequence = %d; want 3", next)
+	}
+
+	// A block from another node uses up the sequence of the waiting
+	// transaction, which can no longer apply
+	competing := [][]byte{alice.note(t, 2, "elsewhere")}
+	m.Update(r.DeliverBlock(2, time.Now(), competing))
+	if size := m.Size(); size != 0 {
+		t.Errorf("size after the competing block = %d; want 0", size)
+	}
+	if next := m.NextSequence(alice.address); next != 3 {
+		t.Errorf("alice's next sequence = %d; want 3", next)
+	}
+}
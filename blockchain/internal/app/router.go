@@ -0,0 +1,241 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/accounts"
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+// Result codes of delivered transactions
+const (
+	CodeOK           uint32 = 0
+	CodeInvalidTx    uint32 = 1 // undecodable or of an unknown type
+	CodeUnauthorized uint32 = 2 // bad signature or sequence
+	CodeFailed       uint32 = 3 // the message was refused by its module
+)
+
+// Context is the block a message executes in. Handlers must take the time
+// from it rather than the clock, so every node reaches the same state.
+type Context struct {
+	Height int64
+	Time   time.Time
+
+	events []types.Event
+}
+
+// EmitEvent adds an event to the result of the executing transaction
+func (ctx *Context) EmitEvent(eventType string, attributes ...types.EventAttribute) {
+	ctx.events = append(ctx.events, types.Event{Type: eventType, Attributes: attributes})
+}
+
+// Attribute returns an event attribute with a value formatted by fmt
+func Attribute(key string, value interface{}) types.EventAttribute {
+	return types.EventAttribute{Key: key, Value: fmt.Sprint(value)}
+}
+
+// Config configures the router
+type Config struct {
+	Accounts *accounts.Keeper
+	Logger   *zap.Logger
+}
+
+// Router executes the module messages of blocks. Modules register a
+// handler per message type; a transaction's signature and sequence are
+// checked before its handler runs.
+type Router struct {
+	accounts *accounts.Keeper
+	logger   *zap.Logger
+
+	routes map[string]*route
+	mu     sync.Mutex // serializes block execution
+}
+
+// route checks and executes the messages of one type
+type route struct {
+	// check decodes the message and checks it without changing state,
+	// returning the account that signed it
+	check func(tx *Tx) (signer string, err error)
+	// deliver checks the message, uses up the sender's sequence and runs
+	// the handler
+	deliver func(ctx *Context, tx *Tx) (signer string, err error)
+}
+
+// NewRouter creates a router without routes
+func NewRouter(cfg *Config) *Router {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Router{
+		accounts: cfg.Accounts,
+		logger:   logger,
+		routes:   make(map[string]*route),
+	}
+}
+
+// Handle routes the messages of type M, signed by their sender, to handle.
+// The message type is the one M reports.
+func Handle[M any, PM interface {
+	*M
+	accounts.Msg
+}](r *Router, handle func(ctx *Context, msg M) error) {
+	var zero M
+	r.add(PM(&zero).Type(), &route{
+		check: func(tx *Tx) (string, error) {
+			msg := PM(new(M))
+			if err := r.accounts.CheckSignature(&tx.SignedMsg, msg); err != nil {
+				return "", err
+			}
+			return msg.Signer(), validateBasic(msg)
+		},
+		deliver: func(ctx *Context, tx *Tx) (string, error) {
+			var msg M
+			if err := r.accounts.Verify(&tx.SignedMsg, PM(&msg)); err != nil {
+				return "", err
+			}
+			return PM(&msg).Signer(), handle(ctx, msg)
+		},
+	})
+}
+
+// HandleUnsigned routes messages of msgType that carry their own
+// authorization, such as a governance signature and nonce, to handle
+func HandleUnsigned[M any](r *Router, msgType string, handle func(ctx *Context, msg M) error) {
+	decode := func(tx *Tx) (M, error) {
+		var msg M
+		if len(tx.Msg) == 0 {
+			return msg, fmt.Errorf("%w: msg cannot be empty", ErrInvalidTx)
+		}
+		if err := json.Unmarshal(tx.Msg, &msg); err != nil {
+			return msg, fmt.Errorf("%w: %v", ErrInvalidTx, err)
+		}
+		return msg, nil
+	}
+	r.add(msgType, &route{
+		check: func(tx *Tx) (string, error) {
+			msg, err := decode(tx)
+			if err != nil {
+				return "", err
+			}
+			return "", validateBasic(msg)
+		},
+		deliver: func(ctx *Context, tx *Tx) (string, error) {
+			msg, err := decode(tx)
+			if err != nil {
+				return "", err
+			}
+			return "", handle(ctx, msg)
+		},
+	})
+}
+
+func (r *Router) add(msgType string, rt *route) {
+	if _, exists := r.routes[msgType]; exists {
+		panic(fmt.Sprintf("message type %s is routed twice", msgType))
+	}
+	r.routes[msgType] = rt
+}
+
+// validateBasic runs the stateless checks of messages that have them
+func validateBasic(msg interface{}) error {
+	if v, ok := msg.(interface{ ValidateBasic() error }); ok {
+		return v.ValidateBasic()
+	}
+	return nil
+}
+
+// CheckTx decodes a transaction and checks its message and signature
+// without executing it or using up the sequence. It returns the decoded
+// transaction and the account that signed it, empty for unsigned messages.
+func (r *Router) CheckTx(data []byte) (*Tx, string, error) {
+	tx, err := DecodeTx(data)
+	if err != nil {
+		return nil, "", err
+	}
+	rt, ok := r.routes[tx.Type]
+	if !ok {
+		return nil, "", fmt.Errorf("%w: %s", ErrUnknownMsgType, tx.Type)
+	}
+	signer, err := rt.check(tx)
+	if err != nil {
+		return nil, "", err
+	}
+	return tx, signer, nil
+}
+
+// DeliverBlock executes the transactions of a block in order at the
+// block's height and time and returns their results. A transaction that
+// fails has a non-zero code and its error as the log; the messages of the
+// other transactions still apply.
+func (r *Router) DeliverBlock(height int64, blockTime time.Time, txs [][]byte) *types.BlockResults {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	results := &types.BlockResults{
+		Height:    height,
+		Time:      blockTime,
+		Events:    []types.Event{},
+		TxResults: make([]*types.TxResult, 0, len(txs)),
+	}
+	for i, data := range txs {
+		result := &types.TxResult{
+			Height: height,
+			Index:  uint32(i),
+			Hash:   types.TxHash(data),
+			Tx:     data,
+			Events: []types.Event{},
+		}
+		ctx := &Context{Height: height, Time: blockTime}
+		msgType, signer, err := r.deliver(ctx, data)
+		if err != nil {
+			result.Code = errorCode(err)
+			result.Log = err.Error()
+			r.logger.Debug("Transaction failed",
+				zap.Int64("height", height),
+				zap.Uint32("index", result.Index),
+				zap.String("type", msgType),
+				zap.Error(err),
+			)
+		} else {
+			message := []types.EventAttribute{Attribute("action", msgType)}
+			if signer != "" {
+				message = append(message, Attribute("sender", signer))
+			}
+			result.Events = append([]types.Event{{Type: "message", Attributes: message}}, ctx.events...)
+		}
+		results.TxResults = append(results.TxResults, result)
+	}
+	return results
+}
+
+func (r *Router) deliver(ctx *Context, data []byte) (string, string, error) {
+	tx, err := DecodeTx(data)
+	if err != nil {
+		return "", "", err
+	}
+	rt, ok := r.routes[tx.Type]
+	if !ok {
+		return tx.Type, "", fmt.Errorf("%w: %s", ErrUnknownMsgType, tx.Type)
+	}
+	signer, err := rt.deliver(ctx, tx)
+	return tx.Type, signer, err
+}
+
+func errorCode(err error) uint32 {
+	switch {
+	case errors.Is(err, ErrInvalidTx), errors.Is(err, ErrUnknownMsgType):
+		return CodeInvalidTx
+	case errors.Is(err, accounts.ErrInvalidSignature), errors.Is(err, accounts.ErrSignerMismatch),
+		errors.Is(err, accounts.ErrWrongSequence):
+		return CodeUnauthorized
+	default:
+		return CodeFailed
+	}
+}
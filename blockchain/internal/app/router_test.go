@@ -0,0 +1,176 @@
+package app
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vindexchain/blockchain/internal/accounts"
+)
+
+const testChainID = "vindex-test"
+
+var errEmptyNote = errors.New("text cannot be empty")
+
+// msgNote is a message that appends its text to a note list
+type msgNote struct {
+	Sender string `json:"sender"`
+	Text   string `json:"text"`
+}
+
+func (m msgNote) Type() string   { return "test/note" }
+func (m msgNote) Signer() string { return m.Sender }
+
+func (m msgNote) ValidateBasic() error {
+	if m.Text == "" {
+		return errEmptyNote
+	}
+	return nil
+}
+
+// notes records the messages delivered by a test router
+type notes struct {
+	texts []string
+	times []time.Time
+}
+
+func newTestRouter(t *testing.T) (*Router, *accounts.Keeper, *notes) {
+	t.Helper()
+	keeper := accounts.NewKeeper(&accounts.Config{ChainID: testChainID})
+	r := NewRouter(&Config{Accounts: keeper})
+	n := &notes{}
+	Handle(r, func(ctx *Context, msg msgNote) error {
+		if msg.Text == "refused" {
+			return errors.New("note refused")
+		}
+		n.texts = append(n.texts, msg.Text)
+		n.times = append(n.times, ctx.Time)
+		ctx.EmitEvent("note", Attribute("length", len(msg.Text)))
+		return nil
+	})
+	return r, keeper, n
+}
+
+type testAccount struct {
+	priv    ed25519.PrivateKey
+	address string
+}
+
+func newTestAccount(t *testing.T) testAccount {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testAccount{priv: priv, address: accounts.Address(pub)}
+}
+
+func (a testAccount) note(t *testing.T, sequence uint64, text string) []byte {
+	t.Helper()
+	msg := msgNote{Sender: a.address, Text: text}
+	signed, err := accounts.Sign(a.priv, testChainID, sequence, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := NewTx(msg.Type(), signed).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDeliverBlock(t *testing.T) {
+	r, keeper, n := newTestRouter(t)
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	blockTime := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// bob signs a message as alice
+	forged := func() []byte {
+		msg := msgNote{Sender: alice.address, Text: "forged"}
+		signed, err := accounts.Sign(bob.priv, testChainID, 0, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := NewTx(msg.Type(), signed).Encode()
+		return data
+	}()
+
+	tests := []struct {
+		name string
+		tx   []byte
+		code uint32
+	}{
+		{"delivered", alice.note(t, 0, "hello"), CodeOK},
+		{"refused by the handler", alice.note(t, 1, "refused"), CodeFailed},
+		{"replayed sequence", alice.note(t, 1, "again"), CodeUnauthorized},
+		{"future sequence", alice.note(t, 5, "later"), CodeUnauthorized},
+		{"wrong signer", forged, CodeUnauthorized},
+		{"unknown type", []byte(`{"type":"test/unknown","msg":{}}`), CodeInvalidTx},
+		{"undecodable", []byte("not json"), CodeInvalidTx},
+		{"second account", bob.note(t, 0, "hi"), CodeOK},
+	}
+	txs := make([][]byte, len(tests))
+	for i, tt := range tests {
+		txs[i] = tt.tx
+	}
+
+	results := r.DeliverBlock(7, blockTime, txs)
+	if results.Height != 7 || !results.Time.Equal(blockTime) || len(results.TxResults) != len(tests) {
+		t.Fatalf("results at height %d, time %v with %d txs", results.Height, results.Time, len(results.TxResults))
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := results.TxResults[i]
+			if result.Code != tt.code {
+				t.Errorf("code = %d (%s); want %d", result.Code, result.Log, tt.code)
+			}
+			if result.Index != uint32(i) || result.Height != 7 {
+				t.Errorf("result at height %d, index %d", result.Height, result.Index)
+			}
+			if tt.code == CodeOK && (len(result.Events) != 2 || result.Events[0].Type != "message" || result.Events[1].Type != "note") {
+				t.Errorf("events = %+v; want message and note", result.Events)
+			}
+			if tt.code != CodeOK && (result.Log == "" || len(result.Events) != 0) {
+				t.Errorf("failed tx has log %q and events %+v", result.Log, result.Events)
+			}
+		})
+	}
+
+	if want := []string{"hello", "hi"}; len(n.texts) != 2 || n.texts[0] != want[0] || n.texts[1] != want[1] {
+		t.Errorf("delivered notes = %v; want %v", n.texts, want)
+	}
+	for _, at := range n.times {
+		if !at.Equal(blockTime) {
+			t.Errorf("handler ran at %v; want the block time %v", at, blockTime)
+		}
+	}
+	// A message its module refused still used up its sequence
+	if seq := keeper.Sequence(alice.address); seq != 2 {
+		t.Errorf("alice's sequence = %d; want 2", seq)
+	}
+}
+
+func TestCheckTx(t *testing.T) {
+	r, keeper, _ := newTestRouter(t)
+	alice := newTestAccount(t)
+
+	tx, signer, err := r.CheckTx(alice.note(t, 0, "hello"))
+	if err != nil || signer != alice.address || tx.Type != "test/note" {
+		t.Fatalf("CheckTx = %+v, %q, %v", tx, signer, err)
+	}
+	if seq := keeper.Sequence(alice.address); seq != 0 {
+		t.Errorf("CheckTx used up the sequence: %d", seq)
+	}
+
+	if _, _, err := r.CheckTx(alice.note(t, 0, "")); !errors.Is(err, errEmptyNote) {
+		t.Errorf("empty note: %v; want its ValidateBasic error", err)
+	}
+	if _, _, err := r.CheckTx([]byte(`{"type":"test/unknown","msg":{}}`)); !errors.Is(err, ErrUnknownMsgType) {
+		t.Errorf("unknown type: %v; want ErrUnknownMsgType", err)
+	}
+	if _, _, err := r.CheckTx([]byte(`{"msg":{}}`)); !errors.Is(err, ErrInvalidTx) {
+		t.Errorf("missing type: %v; want ErrInvalidTx", err)
+	}
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/vindexchain/blockchain/internal/accounts"
+)
+
+var (
+	// ErrInvalidTx is returned for transactions that cannot be decoded
+	ErrInvalidTx = errors.New("invalid transaction")
+	// ErrUnknownMsgType is returned for transactions whose message type has
+	// no route
+	ErrUnknownMsgType = errors.New("unknown message type")
+)
+
+// Tx is a module message as it is stored in a block: the message type,
+// which selects the route that executes it, and the message signed by its
+// sender. Messages that carry their own authorization, such as governance
+// updates, leave the signature fields empty.
+type Tx struct {
+	Type string `json:"type"`
+	accounts.SignedMsg
+}
+
+// NewTx wraps a signed message of a type into a transaction
+func NewTx(msgType string, signed *accounts.SignedMsg) *Tx {
+	return &Tx{Type: msgType, SignedMsg: *signed}
+}
+
+// Encode returns the bytes a block carries for the transaction
+func (tx *Tx) Encode() ([]byte, error) {
+	return json.Marshal(tx)
+}
+
+// DecodeTx decodes the bytes of a block transaction
+func DecodeTx(data []byte) (*Tx, error) {
+	var tx Tx
+	if err := json.Unmarshal(data, &tx); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTx, err)
+	}
+	if tx.Type == "" {
+		return nil, fmt.Errorf("%w: missing message type", ErrInvalidTx)
+	}
+	return &tx, nil
+}
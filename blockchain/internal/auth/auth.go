@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrInvalidCredentials is returned for tokens that are malformed,
+	// expired, revoked or signed with the wrong secret
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrUnknownScope is returned when parsing a scope that does not exist
+	ErrUnknownScope = errors.New("unknown scope")
+)
+
+// Scope is a permission granted to an API key or JWT
+type Scope string
+
+// Scopes
+const (
+	// ScopeRead identifies a client on read routes, which are public
+	ScopeRead Scope = "read"
+	// ScopeBroadcast allows routes that sign transactions with node keys,
+	// such as staking/delegate and tokens/create
+	ScopeBroadcast Scope = "broadcast"
+	// ScopeAdmin allows node administration; it includes read and broadcast
+	ScopeAdmin Scope = "admin"
+	// ScopeCompliance allows KYC submissions and lookups. Admin does not
+	// include it, so access to personal data is granted explicitly.
+	ScopeCompliance Scope = "compliance"
+)
+
+var knownScopes = map[Scope]bool{
+	ScopeRead:       true,
+	ScopeBroadcast:  true,
+	ScopeAdmin:      true,
+	ScopeCompliance: true,
+}
+
+// ParseScopes parses a comma- or space-separated list of scopes
+func ParseScopes(s string) ([]Scope, error) {
+	seen := make(map[Scope]bool)
+	var scopes []Scope
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		scope := Scope(strings.ToLower(field))
+		if !knownScopes[scope] {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownScope, field)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	sort.Slice(scopes, func(i, j int) bool { return scopes[i] < scopes[j] })
+	return scopes, nil
+}
+
+// Principal is an authenticated API client
+type Principal struct {
+	Subject string  `json:"subject"` // JWT subject or API key name
+	KeyID   string  `json:"key_id,omitempty"`
+	Method  string  `json:"method"` // "jwt" or "api_key"
+	Scopes  []Scope `json:"scopes"`
+	Tier    string  `json:"tier,omitempty"`
+}
+
+// Has reports whether the principal was granted scope
+func (p *Principal) Has(scope Scope) bool {
+	for _, s := range p.Scopes {
+		if s == scope || (s == ScopeAdmin && scope != ScopeCompliance) {
+			return true
+		}
+	}
+	return false
+}
+
+// Config configures the authenticator
+type Config struct {
+	// JWTSecret verifies HS256 tokens
+	JWTSecret string
+	// Keys holds the API keys; nil accepts JWTs only
+	Keys *KeyStore
+	// AdminKey is a static key with the admin scope, kept for deployments
+	// that predate API keys; empty disables it
+	AdminKey string
+	Logger   *zap.Logger
+}
+
+// Authenticator verifies bearer tokens, which are either JWTs or API keys
+type Authenticator struct {
+	jwtSecret []byte
+	keys      *KeyStore
+	adminKey  string
+	logger    *zap.Logger
+}
+
+// NewAuthenticator creates an authenticator
+func NewAuthenticator(cfg *Config) *Authenticator {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Authenticator{
+		jwtSecret: []byte(cfg.JWTSecret),
+		keys:      cfg.Keys,
+		adminKey:  cfg.AdminKey,
+		logger:    logger,
+	}
+}
+
+// Authenticate returns the principal a token belongs to
+func (a *Authenticator) Authenticate(token string) (*Principal, error) {
+	switch {
+	case strings.HasPrefix(token, keyPrefix):
+		if a.keys == nil {
+			return nil, ErrInvalidCredentials
+		}
+		key, err := a.keys.Verify(token)
+		if err != nil {
+			return nil, err
+		}
+		return &Principal{Subject: key.Name, KeyID: key.ID, Method: "api_key", Scopes: key.Scopes, Tier: key.Tier}, nil
+	case strings.Count(token, ".") == 2:
+		return verifyJWT(token, a.jwtSecret)
+	case a.adminKey != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.adminKey)) == 1:
+		return &Principal{Subject: "admin", Method: "api_key", Scopes: []Scope{ScopeAdmin}}, nil
+	}
+	return nil, ErrInvalidCredentials
+}
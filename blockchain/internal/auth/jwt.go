@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// clockSkew is the leeway allowed on exp and nbf
+const clockSkew = 30 * time.Second
+
+// claims are the JWT claims the API reads. Scope holds space-separated
+// scopes, as in OAuth 2.0 access tokens; Tier selects the daily quota.
+type claims struct {
+	Scope string `json:"scope"`
+	Tier  string `json:"tier,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// verifyJWT checks an HS256 token and its expiry and returns its principal
+func verifyJWT(token string, secret []byte) (*Principal, error) {
+	var c claims
+	_, err := jwt.ParseWithClaims(token, &c, func(*jwt.Token) (interface{}, error) {
+		return secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired(), jwt.WithLeeway(clockSkew))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	scopes, err := ParseScopes(c.Scope)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	return &Principal{Subject: c.Subject, Method: "jwt", Scopes: scopes, Tier: c.Tier}, nil
+}
+
+// SignJWT issues an HS256 token for subject with scopes in a quota tier,
+// valid for ttl
+func SignJWT(secret []byte, subject string, scopes []Scope, tier string, ttl time.Duration) (string, error) {
+	scope := ""
+	for i, s := range scopes {
+		if i > 0 {
+			scope += " "
+		}
+		scope += string(s)
+	}
+	now := time.Now()
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
+		Scope: scope,
+		Tier:  tier,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}).SignedString(secret)
+}
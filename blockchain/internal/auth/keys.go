@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrKeyNotFound is returned when revoking a key that does not exist
+var ErrKeyNotFound = errors.New("API key not found")
+
+// API keys are "vdx_<id>_<secret>". The id finds the key; only a SHA-256
+// of the secret is stored.
+const keyPrefix = "vdx_"
+
+// reloadInterval is how often the key file is checked for changes made by
+// `vindexchain apikeys` while the node runs
+const reloadInterval = time.Second
+
+// APIKey is a stored API key
+type APIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Hash      string     `json:"hash"` // hex SHA-256 of the secret
+	Scopes    []Scope    `json:"scopes"`
+	Tier      string     `json:"tier,omitempty"` // selects the daily quota
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Revoked reports whether the key was revoked
+func (k *APIKey) Revoked() bool { return k.RevokedAt != nil }
+
+type keyFile struct {
+	Keys []*APIKey `json:"keys"`
+}
+
+// KeyStore keeps API keys in a JSON file, which the CLI edits and the node
+// reloads when it changes
+type KeyStore struct {
+	path string
+
+	mu        sync.Mutex
+	keys      map[string]*APIKey
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// OpenKeyStore loads the keys in path. A missing file is an empty store.
+func OpenKeyStore(path string) (*KeyStore, error) {
+	s := &KeyStore{path: path, keys: make(map[string]*APIKey)}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Create adds a key with scopes in a quota tier and returns it and the
+// token, which is shown only once
+func (s *KeyStore) Create(name string, scopes []Scope, tier string) (string, *APIKey, error) {
+	if len(scopes) == 0 {
+		return "", nil, fmt.Errorf("%w: an API key needs at least one scope", ErrUnknownScope)
+	}
+	id := make([]byte, 8)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	key := &APIKey{
+		ID:        hex.EncodeToString(id),
+		Name:      name,
+		Scopes:    scopes,
+		Tier:      tier,
+		CreatedAt: time.Now().UTC(),
+	}
+	secretText := base64.RawURLEncoding.EncodeToString(secret)
+	key.Hash = hashSecret(secretText)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return "", nil, err
+	}
+	s.keys[key.ID] = key
+	if err := s.save(); err != nil {
+		return "", nil, err
+	}
+	return keyPrefix + key.ID + "_" + secretText, key, nil
+}
+
+// Revoke revokes the key with id. Revoked keys stay listed.
+func (s *KeyStore) Revoke(id string) (*APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+	if !key.Revoked() {
+		now := time.Now().UTC()
+		key.RevokedAt = &now
+		if err := s.save(); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// List returns every key, oldest first
+func (s *KeyStore) List() ([]*APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	keys := make([]*APIKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+// Verify returns the unrevoked key a token belongs to
+func (s *KeyStore) Verify(token string) (*APIKey, error) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(token, keyPrefix), "_")
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.checkedAt) >= reloadInterval {
+		if err := s.load(); err != nil {
+			return nil, err
+		}
+	}
+	key, ok := s.keys[id]
+	if !ok || key.Revoked() || subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(key.Hash)) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	return key, nil
+}
+
+// load rereads the file if it changed since it was last read
+func (s *KeyStore) load() error {
+	s.checkedAt = time.Now()
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		s.keys = make(map[string]*APIKey)
+		s.modTime = time.Time{}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(s.modTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("invalid API key file %s: %w", s.path, err)
+	}
+	keys := make(map[string]*APIKey, len(file.Keys))
+	for _, key := range file.Keys {
+		keys[key.ID] = key
+	}
+	s.keys, s.modTime = keys, info.ModTime()
+	return nil
+}
+
+// save writes the keys atomically, readable only by the node's user
+func (s *KeyStore) save() error {
+	file := keyFile{Keys: make([]*APIKey, 0, len(s.keys))}
+	for _, key := range s.keys {
+		file.Keys = append(file.Keys, key)
+	}
+	sort.Slice(file.Keys, func(i, j int) bool { return file.Keys[i].CreatedAt.Before(file.Keys[j].CreatedAt) })
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime = info.ModTime()
+	}
+	return nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
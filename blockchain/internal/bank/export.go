@@ -0,0 +1,110 @@
+package bank
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Keys of the ledger in the state store, relative to the module prefix.
+// Amounts are stored as decimal strings.
+const (
+	balancesKeyPrefix = "balances/" // balances/{address}/{denom}
+	supplyKeyPrefix   = "supply/"   // supply/{denom}
+	burnedKeyPrefix   = "burned/"   // burned/{denom}
+)
+
+// ExportState returns every non-zero balance, supply and burned total
+func (k *Keeper) ExportState() (map[string][]byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	state := make(map[string][]byte)
+	for address, coins := range k.balances {
+		for denom, amount := range coins {
+			state[balanceKey(address, denom)] = formatAmount(amount)
+		}
+	}
+	for denom, amount := range k.supply {
+		if amount > 0 {
+			state[supplyKeyPrefix+denom] = formatAmount(amount)
+		}
+	}
+	for denom, amount := range k.burned {
+		if amount > 0 {
+			state[burnedKeyPrefix+denom] = formatAmount(amount)
+		}
+	}
+	return state, nil
+}
+
+// ImportState replaces the ledger with exported state
+func (k *Keeper) ImportState(state map[string][]byte) error {
+	balances := make(map[string]map[string]uint64)
+	supply := make(map[string]uint64)
+	burned := make(map[string]uint64)
+	for key, value := range state {
+		amount, err := strconv.ParseUint(string(value), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid amount under %s: %w", key, err)
+		}
+		switch {
+		case strings.HasPrefix(key, balancesKeyPrefix):
+			address, denom, ok := strings.Cut(strings.TrimPrefix(key, balancesKeyPrefix), "/")
+			if !ok || address == "" || denom == "" {
+				return fmt.Errorf("invalid balance key %s", key)
+			}
+			if balances[address] == nil {
+				balances[address] = make(map[string]uint64)
+			}
+			balances[address][denom] = amount
+		case strings.HasPrefix(key, supplyKeyPrefix):
+			supply[strings.TrimPrefix(key, supplyKeyPrefix)] = amount
+		case strings.HasPrefix(key, burnedKeyPrefix):
+			burned[strings.TrimPrefix(key, burnedKeyPrefix)] = amount
+		default:
+			return fmt.Errorf("unknown ledger key %s", key)
+		}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.balances, k.supply, k.burned = balances, supply, burned
+	k.changed = make(map[string]bool)
+	return nil
+}
+
+// ExportChanges returns the balances, supplies and burned totals changed
+// since the last export, with nil values for the ones now zero
+func (k *Keeper) ExportChanges() (map[string][]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	changes := make(map[string][]byte, len(k.changed))
+	for key := range k.changed {
+		var amount uint64
+		switch {
+		case strings.HasPrefix(key, balancesKeyPrefix):
+			address, denom, _ := strings.Cut(strings.TrimPrefix(key, balancesKeyPrefix), "/")
+			amount = k.balances[address][denom]
+		case strings.HasPrefix(key, supplyKeyPrefix):
+			amount = k.supply[strings.TrimPrefix(key, supplyKeyPrefix)]
+		case strings.HasPrefix(key, burnedKeyPrefix):
+			amount = k.burned[strings.TrimPrefix(key, burnedKeyPrefix)]
+		}
+		changes[key] = nil
+		if amount > 0 {
+			changes[key] = formatAmount(amount)
+		}
+	}
+	k.changed = make(map[string]bool)
+	return changes, nil
+}
+
+func balanceKey(address, denom string) string {
+	return balancesKeyPrefix + address + "/" + denom
+}
+
+func formatAmount(amount uint64) []byte {
+	return strconv.AppendUint(nil, amount, 10)
+}
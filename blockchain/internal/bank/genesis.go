@@ -0,0 +1,134 @@
+package bank
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Coin is an amount of a denom. Genesis files carry amounts as decimal
+// strings.
+type Coin struct {
+	Denom  string `json:"denom"`
+	Amount string `json:"amount"`
+}
+
+// Balance is the genesis allocation of an address
+type Balance struct {
+	Address string `json:"address"`
+	Coins   []Coin `json:"coins"`
+}
+
+// Genesis is the bank section of the genesis file. Supply is optional;
+// when set it must match the sum of the balances.
+type Genesis struct {
+	Balances []Balance `json:"balances"`
+	Supply   []Coin    `json:"supply"`
+}
+
+// LoadGenesis reads the bank section (app_state.bank) of a genesis file
+func LoadGenesis(path string) (*Genesis, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc struct {
+		AppState struct {
+			Bank Genesis `json:"bank"`
+		} `json:"app_state"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse genesis file %s: %w", path, err)
+	}
+	if err := doc.AppState.Bank.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid bank genesis in %s: %w", path, err)
+	}
+	return &doc.AppState.Bank, nil
+}
+
+// Validate checks the allocations and the declared supply. A genesis
+// without a bank section is empty and valid.
+func (g *Genesis) Validate() error {
+	_, err := g.totals()
+	return err
+}
+
+// totals returns the amount allocated of every denom
+func (g *Genesis) totals() (map[string]uint64, error) {
+	totals := make(map[string]uint64)
+	seen := make(map[string]bool)
+	for _, balance := range g.Balances {
+		if err := ValidateAddress(balance.Address); err != nil {
+			return nil, err
+		}
+		for _, coin := range balance.Coins {
+			amount, err := coin.parse()
+			if err != nil {
+				return nil, fmt.Errorf("balance of %s: %w", balance.Address, err)
+			}
+			key := balanceKey(balance.Address, coin.Denom)
+			if seen[key] {
+				return nil, fmt.Errorf("balance of %s: %s is allocated twice", balance.Address, coin.Denom)
+			}
+			seen[key] = true
+			if totals[coin.Denom] > ^uint64(0)-amount {
+				return nil, fmt.Errorf("%w: total %s allocated", ErrOverflow, coin.Denom)
+			}
+			totals[coin.Denom] += amount
+		}
+	}
+
+	if len(g.Supply) == 0 {
+		return totals, nil
+	}
+	declared := make(map[string]bool, len(g.Supply))
+	for _, coin := range g.Supply {
+		amount, err := coin.parse()
+		if err != nil {
+			return nil, fmt.Errorf("supply: %w", err)
+		}
+		if amount != totals[coin.Denom] {
+			return nil, fmt.Errorf("supply of %s is %d, balances add up to %d", coin.Denom, amount, totals[coin.Denom])
+		}
+		declared[coin.Denom] = true
+	}
+	for denom, total := range totals {
+		if !declared[denom] {
+			return nil, fmt.Errorf("supply of %s is missing, balances add up to %d", denom, total)
+		}
+	}
+	return totals, nil
+}
+
+func (c Coin) parse() (uint64, error) {
+	if c.Denom == "" {
+		return 0, fmt.Errorf("denom cannot be empty")
+	}
+	amount, err := strconv.ParseUint(c.Amount, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s amount %q", c.Denom, c.Amount)
+	}
+	if amount == 0 {
+		return 0, fmt.Errorf("%s: %w", c.Denom, ErrInvalidAmount)
+	}
+	return amount, nil
+}
+
+// InitGenesis mints the genesis allocations into an empty ledger. Once the
+// chain has committed a version, the stored ledger replaces them.
+func (k *Keeper) InitGenesis(g *Genesis) error {
+	if err := g.Validate(); err != nil {
+		return err
+	}
+	for _, balance := range g.Balances {
+		for _, coin := range balance.Coins {
+			amount, _ := coin.parse()
+			if err := k.Mint(balance.Address, coin.Denom, amount); err != nil {
+				return fmt.Errorf("failed to allocate %s to %s: %w", coin.Denom, balance.Address, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package bank
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadGenesis(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string // no file is written when empty
+		wantErr     bool
+		wantBalance uint64 // alice's oc after InitGenesis
+		wantSupply  uint64
+	}{
+		{name: "missing file", wantErr: true},
+		{name: "malformed", file: `{"app_state": {"bank": `, wantErr: true},
+		{name: "no bank section", file: `{"app_state": {"domains": {}}}`},
+		{name: "empty allocations", file: `{"app_state": {"bank": {"balances": [], "supply": []}}}`},
+		{
+			name:        "allocations",
+			file:        `{"app_state": {"bank": {"balances": [{"address": "` + alice + `", "coins": [{"denom": "oc", "amount": "700"}]}, {"address": "` + bob + `", "coins": [{"denom": "oc", "amount": "300"}]}]}}}`,
+			wantBalance: 700, wantSupply: 1000,
+		},
+		{
+			name:        "matching supply",
+			file:        `{"app_state": {"bank": {"balances": [{"address": "` + alice + `", "coins": [{"denom": "oc", "amount": "700"}]}], "supply": [{"denom": "oc", "amount": "700"}]}}}`,
+			wantBalance: 700, wantSupply: 700,
+		},
+		{name: "supply mismatch", file: `{"app_state": {"bank": {"balances": [{"address": "` + alice + `", "coins": [{"denom": "oc", "amount": "700"}]}], "supply": [{"denom": "oc", "amount": "701"}]}}}`, wantErr: true},
+		{name: "supply missing a denom", file: `{"app_state": {"bank": {"balances": [{"address": "` + alice + `", "coins": [{"denom": "oc", "amount": "1"}, {"denom": "x", "amount": "1"}]}], "supply": [{"denom": "oc", "amount": "1"}]}}}`, wantErr: true},
+		{name: "invalid address", file: `{"app_state": {"bank": {"balances": [{"address": "cosmos1abc", "coins": [{"denom": "oc", "amount": "1"}]}]}}}`, wantErr: true},
+		{name: "zero amount", file: `{"app_state": {"bank": {"balances": [{"address": "` + alice + `", "coins": [{"denom": "oc", "amount": "0"}]}]}}}`, wantErr: true},
+		{name: "amount not a number", file: `{"app_state": {"bank": {"balances": [{"address": "` + alice + `", "coins": [{"denom": "oc", "amount": "1.5"}]}]}}}`, wantErr: true},
+		{name: "allocated twice", file: `{"app_state": {"bank": {"balances": [{"address": "` + alice + `", "coins": [{"denom": "oc", "amount": "1"}]}, {"address": "` + alice + `", "coins": [{"denom": "oc", "amount": "1"}]}]}}}`, wantErr: true},
+		{name: "total overflows", file: `{"app_state": {"bank": {"balances": [{"address": "` + alice + `", "coins": [{"denom": "oc", "amount": "18446744073709551615"}]}, {"address": "` + bob + `", "coins": [{"denom": "oc", "amount": "1"}]}]}}}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "genesis.json")
+			if tt.file != "" {
+				if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			genesis, err := LoadGenesis(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadGenesis = %v; want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			k := NewKeeper(nil)
+			if err := k.InitGenesis(genesis); err != nil {
+				t.Fatal(err)
+			}
+			if got := k.GetBalance(alice, "oc"); got != tt.wantBalance {
+				t.Errorf("alice holds %d; want %d", got, tt.wantBalance)
+			}
+			if got := k.GetSupply("oc"); got != tt.wantSupply {
+				t.Errorf("supply = %d; want %d", got, tt.wantSupply)
+			}
+		})
+	}
+}
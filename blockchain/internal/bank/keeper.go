@@ -0,0 +1,257 @@
+package bank
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrInsufficientFunds is returned when an account cannot cover a debit
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrInvalidAmount is returned for zero amounts
+	ErrInvalidAmount = errors.New("amount must be positive")
+	// ErrOverflow is returned when a credit would overflow a balance or the supply
+	ErrOverflow = errors.New("amount overflows uint64")
+)
+
+// SendRestriction lets other modules veto a transfer of a denom. It runs
+// while the ledger is locked and must not call back into the Keeper.
+type SendRestriction func(from, to, denom string) error
+
+// Holder is a single account balance for a denom
+type Holder struct {
+	Address string `json:"address"`
+	Amount  uint64 `json:"amount"`
+}
+
+// Keeper tracks balances, supply and burned totals for every denom
+type Keeper struct {
+	mu           sync.RWMutex
+	balances     map[string]map[string]uint64 // address -> denom -> amount
+	supply       map[string]uint64
+	burned       map[string]uint64
+	changed      map[string]bool // state keys changed since the last export
+	restrictions []SendRestriction
+	logger       *zap.Logger
+}
+
+const modulePrefix = "module:"
+
+// NewKeeper creates an empty ledger
+func NewKeeper(logger *zap.Logger) *Keeper {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Keeper{
+		balances: make(map[string]map[string]uint64),
+		supply:   make(map[string]uint64),
+		burned:   make(map[string]uint64),
+		changed:  make(map[string]bool),
+		logger:   logger,
+	}
+}
+
+// ModuleAddress returns the ledger address owned by a module
+func ModuleAddress(module string) string {
+	return modulePrefix + module
+}
+
+// IsModuleAddress reports whether an address is owned by a module
+func IsModuleAddress(address string) bool {
+	return strings.HasPrefix(address, modulePrefix)
+}
+
+// ValidateAddress checks that an address is a user address with the vindex prefix
+func ValidateAddress(address string) error {
+	if !strings.HasPrefix(address, "vindex1") || len(address) < 20 || len(address) > 90 {
+		return fmt.Errorf("invalid address %q", address)
+	}
+	return nil
+}
+
+// AddSendRestriction registers a hook that is consulted before every Send
+func (k *Keeper) AddSendRestriction(restriction SendRestriction) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.restrictions = append(k.restrictions, restriction)
+}
+
+// GetBalance returns the balance of an address for a denom
+func (k *Keeper) GetBalance(address, denom string) uint64 {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.balances[address][denom]
+}
+
+// GetBalances returns every non-zero balance of an address by denom
+func (k *Keeper) GetBalances(address string) map[string]uint64 {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	balances := make(map[string]uint64, len(k.balances[address]))
+	for denom, amount := range k.balances[address] {
+		balances[denom] = amount
+	}
+	return balances
+}
+
+// GetSupply returns the circulating supply of a denom
+func (k *Keeper) GetSupply(denom string) uint64 {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.supply[denom]
+}
+
+// GetBurned returns the total amount of a denom ever burned
+func (k *Keeper) GetBurned(denom string) uint64 {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.burned[denom]
+}
+
+// Send moves coins between two addresses
+func (k *Keeper) Send(from, to, denom string, amount uint64) error {
+	if amount == 0 {
+		return ErrInvalidAmount
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for _, restriction := range k.restrictions {
+		if err := restriction(from, to, denom); err != nil {
+			return err
+		}
+	}
+
+	if k.balances[from][denom] < amount {
+		return fmt.Errorf("%w: %s has %d%s, needs %d%s", ErrInsufficientFunds, from, k.balances[from][denom], denom, amount, denom)
+	}
+	if from != to && k.balances[to][denom] > ^uint64(0)-amount {
+		return ErrOverflow
+	}
+
+	k.sub(from, denom, amount)
+	k.add(to, denom, amount)
+	return nil
+}
+
+// Mint creates new coins and credits them to an address
+func (k *Keeper) Mint(to, denom string, amount uint64) error {
+	if amount == 0 {
+		return ErrInvalidAmount
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.supply[denom] > ^uint64(0)-amount {
+		return ErrOverflow
+	}
+
+	k.supply[denom] += amount
+	k.changed[supplyKeyPrefix+denom] = true
+	k.add(to, denom, amount)
+
+	k.logger.Debug("Minted coins",
+		zap.String("denom", denom),
+		zap.String("to", to),
+		zap.Uint64("amount", amount),
+	)
+	return nil
+}
+
+// Burn destroys coins held by an address
+func (k *Keeper) Burn(from, denom string, amount uint64) error {
+	if amount == 0 {
+		return ErrInvalidAmount
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.balances[from][denom] < amount {
+		return fmt.Errorf("%w: %s has %d%s, needs %d%s", ErrInsufficientFunds, from, k.balances[from][denom], denom, amount, denom)
+	}
+
+	k.sub(from, denom, amount)
+	k.supply[denom] -= amount
+	k.burned[denom] += amount
+	k.changed[supplyKeyPrefix+denom] = true
+	k.changed[burnedKeyPrefix+denom] = true
+
+	k.logger.Debug("Burned coins",
+		zap.String("denom", denom),
+		zap.String("from", from),
+		zap.Uint64("amount", amount),
+	)
+	return nil
+}
+
+// Unmint takes back coins minted by an operation that failed afterwards.
+// Unlike Burn it does not count them as burned.
+func (k *Keeper) Unmint(from, denom string, amount uint64) error {
+	if amount == 0 {
+		return ErrInvalidAmount
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.balances[from][denom] < amount {
+		return fmt.Errorf("%w: %s has %d%s, needs %d%s", ErrInsufficientFunds, from, k.balances[from][denom], denom, amount, denom)
+	}
+
+	k.sub(from, denom, amount)
+	k.supply[denom] -= amount
+	k.changed[supplyKeyPrefix+denom] = true
+	return nil
+}
+
+// Holders returns every account holding a denom, largest balance first
+func (k *Keeper) Holders(denom string) []Holder {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	holders := make([]Holder, 0)
+	for address, coins := range k.balances {
+		if amount := coins[denom]; amount > 0 {
+			holders = append(holders, Holder{Address: address, Amount: amount})
+		}
+	}
+
+	sort.Slice(holders, func(i, j int) bool {
+		if holders[i].Amount != holders[j].Amount {
+			return holders[i].Amount > holders[j].Amount
+		}
+		return holders[i].Address < holders[j].Address
+	})
+	return holders
+}
+
+func (k *Keeper) add(address, denom string, amount uint64) {
+	coins, ok := k.balances[address]
+	if !ok {
+		coins = make(map[string]uint64)
+		k.balances[address] = coins
+	}
+	coins[denom] += amount
+	k.changed[balanceKey(address, denom)] = true
+}
+
+func (k *Keeper) sub(address, denom string, amount uint64) {
+	coins := k.balances[address]
+	coins[denom] -= amount
+	k.changed[balanceKey(address, denom)] = true
+	if coins[denom] == 0 {
+		delete(coins, denom)
+	}
+	if len(coins) == 0 {
+		delete(k.balances, address)
+	}
+}
@@ -0,0 +1,176 @@
+package bank
+
+import (
+	"errors"
+	"testing"
+)
+
+const (
+	alice = "vindex1alice000000000000000"
+	bob   = "vindex1bob00000000000000000"
+)
+
+// newTestKeeper returns a ledger where alice holds all 100oc
+func newTestKeeper(t *testing.T) *Keeper {
+	t.Helper()
+	k := NewKeeper(nil)
+	if err := k.Mint(alice, "oc", 100); err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestSend(t *testing.T) {
+	errBlocked := errors.New("blocked")
+	tests := []struct {
+		name      string
+		from, to  string
+		amount    uint64
+		blocked   bool
+		wantErr   error
+		wantAlice uint64
+		wantBob   uint64
+	}{
+		{name: "partial", from: alice, to: bob, amount: 40, wantAlice: 60, wantBob: 40},
+		{name: "whole balance", from: alice, to: bob, amount: 100, wantAlice: 0, wantBob: 100},
+		{name: "to itself", from: alice, to: alice, amount: 100, wantAlice: 100},
+		{name: "zero", from: alice, to: bob, wantErr: ErrInvalidAmount, wantAlice: 100},
+		{name: "more than the balance", from: alice, to: bob, amount: 101, wantErr: ErrInsufficientFunds, wantAlice: 100},
+		{name: "empty account", from: bob, to: alice, amount: 1, wantErr: ErrInsufficientFunds, wantAlice: 100},
+		{name: "restricted", from: alice, to: bob, amount: 1, blocked: true, wantErr: errBlocked, wantAlice: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := newTestKeeper(t)
+			if tt.blocked {
+				k.AddSendRestriction(func(from, to, denom string) error { return errBlocked })
+			}
+			if err := k.Send(tt.from, tt.to, "oc", tt.amount); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Send = %v; want %v", err, tt.wantErr)
+			}
+			if a, b := k.GetBalance(alice, "oc"), k.GetBalance(bob, "oc"); a != tt.wantAlice || b != tt.wantBob {
+				t.Errorf("alice holds %d, bob %d; want %d, %d", a, b, tt.wantAlice, tt.wantBob)
+			}
+			if supply := k.GetSupply("oc"); supply != 100 {
+				t.Errorf("supply = %d; a send must not change it", supply)
+			}
+		})
+	}
+}
+
+func TestMintBurnUnmint(t *testing.T) {
+	tests := []struct {
+		name        string
+		op          func(k *Keeper) error
+		wantErr     error
+		wantBalance uint64
+		wantSupply  uint64
+		wantBurned  uint64
+	}{
+		{
+			name:        "mint",
+			op:          func(k *Keeper) error { return k.Mint(alice, "oc", 50) },
+			wantBalance: 150, wantSupply: 150,
+		},
+		{
+			name:        "mint past the supply",
+			op:          func(k *Keeper) error { return k.Mint(alice, "oc", ^uint64(0)-99) },
+			wantErr:     ErrOverflow,
+			wantBalance: 100, wantSupply: 100,
+		},
+		{
+			name:        "mint zero",
+			op:          func(k *Keeper) error { return k.Mint(alice, "oc", 0) },
+			wantErr:     ErrInvalidAmount,
+			wantBalance: 100, wantSupply: 100,
+		},
+		{
+			name:        "burn",
+			op:          func(k *Keeper) error { return k.Burn(alice, "oc", 30) },
+			wantBalance: 70, wantSupply: 70, wantBurned: 30,
+		},
+		{
+			name:        "burn more than held",
+			op:          func(k *Keeper) error { return k.Burn(alice, "oc", 101) },
+			wantErr:     ErrInsufficientFunds,
+			wantBalance: 100, wantSupply: 100,
+		},
+		{
+			name:        "unmint is not counted as burned",
+			op:          func(k *Keeper) error { return k.Unmint(alice, "oc", 30) },
+			wantBalance: 70, wantSupply: 70,
+		},
+		{
+			name:        "unmint more than held",
+			op:          func(k *Keeper) error { return k.Unmint(alice, "oc", 101) },
+			wantErr:     ErrInsufficientFunds,
+			wantBalance: 100, wantSupply: 100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := newTestKeeper(t)
+			if err := tt.op(k); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v; want %v", err, tt.wantErr)
+			}
+			if got := k.GetBalance(alice, "oc"); got != tt.wantBalance {
+				t.Errorf("balance = %d; want %d", got, tt.wantBalance)
+			}
+			if got := k.GetSupply("oc"); got != tt.wantSupply {
+				t.Errorf("supply = %d; want %d", got, tt.wantSupply)
+			}
+			if got := k.GetBurned("oc"); got != tt.wantBurned {
+				t.Errorf("burned = %d; want %d", got, tt.wantBurned)
+			}
+		})
+	}
+}
+
+func TestExportChanges(t *testing.T) {
+	k := newTestKeeper(t)
+	if _, err := k.ExportChanges(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := k.Send(alice, bob, "oc", 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.Burn(bob, "oc", 10); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := k.ExportChanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"balances/" + alice + "/oc": "",
+		"balances/" + bob + "/oc":   "90",
+		"supply/oc":                 "90",
+		"burned/oc":                 "10",
+	}
+	if len(changes) != len(want) {
+		t.Errorf("changes = %q; want %q", changes, want)
+	}
+	for key, value := range want {
+		got, ok := changes[key]
+		if !ok || string(got) != value || (value == "" && got != nil) {
+			t.Errorf("%s = %q; want %q", key, got, value)
+		}
+	}
+	if changes, _ := k.ExportChanges(); len(changes) != 0 {
+		t.Errorf("changes exported twice: %q", changes)
+	}
+
+	// The exported state rebuilds the same ledger
+	state, err := k.ExportState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := NewKeeper(nil)
+	if err := restored.ImportState(state); err != nil {
+		t.Fatal(err)
+	}
+	if restored.GetBalance(bob, "oc") != 90 || restored.GetSupply("oc") != 90 || restored.GetBurned("oc") != 10 || restored.GetBalance(alice, "oc") != 0 {
+		t.Errorf("restored ledger differs: %+v", restored.balances)
+	}
+}
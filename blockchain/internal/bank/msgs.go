@@ -0,0 +1,34 @@
+package bank
+
+import "fmt"
+
+// MsgSend transfers an amount of a denom between two accounts
+type MsgSend struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Denom  string `json:"denom"`
+	Amount uint64 `json:"amount"`
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgSend) ValidateBasic() error {
+	if err := ValidateAddress(m.From); err != nil {
+		return fmt.Errorf("from: %w", err)
+	}
+	if err := ValidateAddress(m.To); err != nil {
+		return fmt.Errorf("to: %w", err)
+	}
+	if m.Denom == "" {
+		return fmt.Errorf("denom cannot be empty")
+	}
+	if m.Amount == 0 {
+		return ErrInvalidAmount
+	}
+	return nil
+}
+
+// Type implements accounts.Msg
+func (m MsgSend) Type() string { return "bank/send" }
+
+// Signer implements accounts.Msg
+func (m MsgSend) Signer() string { return m.From }
@@ -0,0 +1,21 @@
+package bank
+
+import (
+	"github.com/vindexchain/blockchain/internal/app"
+)
+
+// RegisterRoutes routes the transfer messages of blocks to the ledger
+func RegisterRoutes(r *app.Router, k *Keeper) {
+	app.Handle(r, func(ctx *app.Context, msg MsgSend) error {
+		if err := k.Send(msg.From, msg.To, msg.Denom, msg.Amount); err != nil {
+			return err
+		}
+		ctx.EmitEvent("transfer",
+			app.Attribute("sender", msg.From),
+			app.Attribute("recipient", msg.To),
+			app.Attribute("denom", msg.Denom),
+			app.Attribute("amount", msg.Amount),
+		)
+		return nil
+	})
+}
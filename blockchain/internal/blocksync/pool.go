@@ -0,0 +1,242 @@
+package blocksync
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vindexchain/blockchain/internal/p2p"
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+const (
+	maxPendingRequests        = 600 // heights requested ahead of the next block to apply
+	maxPendingRequestsPerPeer = 20
+	requestTimeout            = 15 * time.Second
+	minCatchUpWait            = 5 * time.Second // time to hear from peers before declaring ourselves caught up
+)
+
+type poolPeer struct {
+	id      p2p.ID
+	base    int64
+	height  int64
+	pending int
+}
+
+type blockRequest struct {
+	height int64
+	peerID p2p.ID
+	sentAt time.Time
+	block  *types.Block
+	commit *types.Commit
+}
+
+// blockPool tracks which peer has which heights and which heights have been
+// requested from whom. Blocks are handed out strictly in height order.
+type blockPool struct {
+	mu        sync.Mutex
+	height    int64 // next height to apply
+	peers     map[p2p.ID]*poolPeer
+	requests  map[int64]*blockRequest
+	startTime time.Time
+}
+
+func newBlockPool(startHeight int64) *blockPool {
+	return &blockPool{
+		height:    startHeight,
+		peers:     make(map[p2p.ID]*poolPeer),
+		requests:  make(map[int64]*blockRequest),
+		startTime: time.Now(),
+	}
+}
+
+// setPeerRange records the heights a peer reported having
+func (p *blockPool) setPeerRange(id p2p.ID, base, height int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	peer := p.peers[id]
+	if peer == nil {
+		peer = &poolPeer{id: id}
+		p.peers[id] = peer
+	}
+	peer.base, peer.height = base, height
+}
+
+// removePeer forgets a peer and frees the heights it was asked for
+func (p *blockPool) removePeer(id p2p.ID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.removePeerLocked(id)
+}
+
+func (p *blockPool) removePeerLocked(id p2p.ID) {
+	delete(p.peers, id)
+	for height, req := range p.requests {
+		if req.peerID == id && req.block == nil {
+			delete(p.requests, height)
+		}
+	}
+}
+
+// maxPeerHeight returns the highest height any peer reported
+func (p *blockPool) maxPeerHeight() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.maxPeerHeightLocked()
+}
+
+func (p *blockPool) maxPeerHeightLocked() int64 {
+	var highest int64
+	for _, peer := range p.peers {
+		highest = max(highest, peer.height)
+	}
+	return highest
+}
+
+// isCaughtUp reports whether we have applied every block our peers know of.
+// We wait a little after starting so peers have time to report.
+func (p *blockPool) isCaughtUp() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.peers) == 0 || time.Since(p.startTime) < minCatchUpWait {
+		return false
+	}
+	return p.height > p.maxPeerHeightLocked()
+}
+
+// nextRequests assigns unrequested heights to peers that have them, least
+// loaded peer first, and returns the new requests
+func (p *blockPool) nextRequests() []*blockRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	peers := make([]*poolPeer, 0, len(p.peers))
+	for _, peer := range p.peers {
+		peers = append(peers, peer)
+	}
+
+	var created []*blockRequest
+	maxHeight := min(p.height+maxPendingRequests, p.maxPeerHeightLocked()+1)
+	for height := p.height; height < maxHeight; height++ {
+		if _, requested := p.requests[height]; requested {
+			continue
+		}
+		sort.Slice(peers, func(i, j int) bool { return peers[i].pending < peers[j].pending })
+		for _, peer := range peers {
+			if peer.pending >= maxPendingRequestsPerPeer || height < peer.base || height > peer.height {
+				continue
+			}
+			req := &blockRequest{height: height, peerID: peer.id, sentAt: time.Now()}
+			p.requests[height] = req
+			peer.pending++
+			created = append(created, req)
+			break
+		}
+	}
+	return created
+}
+
+// addBlock stores a block we asked peerID for. It reports false for blocks
+// nobody asked that peer for.
+func (p *blockPool) addBlock(peerID p2p.ID, block *types.Block, commit *types.Commit) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	req := p.requests[block.Height]
+	if req == nil || req.peerID != peerID || req.block != nil {
+		return false
+	}
+	req.block, req.commit = block, commit
+	if peer := p.peers[peerID]; peer != nil {
+		peer.pending--
+	}
+	return true
+}
+
+// noBlock handles a peer telling us it does not have a height after all
+func (p *blockPool) noBlock(peerID p2p.ID, height int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	req := p.requests[height]
+	if req == nil || req.peerID != peerID || req.block != nil {
+		return
+	}
+	delete(p.requests, height)
+	if peer := p.peers[peerID]; peer != nil {
+		peer.pending--
+		peer.height = min(peer.height, height-1)
+	}
+}
+
+// peek returns the next block to apply once it has arrived
+func (p *blockPool) peek() *blockRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	req := p.requests[p.height]
+	if req == nil || req.block == nil {
+		return nil
+	}
+	return req
+}
+
+// pop advances past the block returned by peek
+func (p *blockPool) pop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.requests, p.height)
+	p.height++
+}
+
+// redo drops the block at height and the peer that sent it, so the height
+// is requested again from someone else
+func (p *blockPool) redo(height int64) p2p.ID {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	req := p.requests[height]
+	if req == nil {
+		return ""
+	}
+	delete(p.requests, height)
+	p.removePeerLocked(req.peerID)
+	return req.peerID
+}
+
+// retry drops the block at height but keeps the peer that sent it, for
+// blocks that were valid but failed to apply locally
+func (p *blockPool) retry(height int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.requests, height)
+}
+
+// timedOut returns the peers that sat on a request for too long. Their
+// requests are freed.
+func (p *blockPool) timedOut() []p2p.ID {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	seen := make(map[p2p.ID]bool)
+	var slow []p2p.ID
+	for _, req := range p.requests {
+		if req.block == nil && time.Since(req.sentAt) > requestTimeout && !seen[req.peerID] {
+			seen[req.peerID] = true
+			slow = append(slow, req.peerID)
+		}
+	}
+	for _, id := range slow {
+		p.removePeerLocked(id)
+	}
+	return slow
+}
+
+func (p *blockPool) currentHeight() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.height
+}
@@ -0,0 +1,437 @@
+package blocksync
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/p2p"
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+// BlocksyncChannel carries block sync messages
+const BlocksyncChannel = byte(0x40)
+
+const (
+	requestInterval      = 10 * time.Millisecond
+	statusUpdateInterval = 10 * time.Second
+	switchCheckInterval  = time.Second
+
+	// maxApplyAttempts bounds how often a block that fails to apply is
+	// fetched again before block sync gives up
+	maxApplyAttempts = 5
+)
+
+// errLoadBlock is returned by verify when our own last block cannot be read
+var errLoadBlock = errors.New("failed to load our last block")
+
+// Block sync message types
+const (
+	msgStatusRequest  = "status_request"
+	msgStatusResponse = "status_response"
+	msgBlockRequest   = "block_request"
+	msgBlockResponse  = "block_response"
+	msgNoBlock        = "no_block_response"
+)
+
+type message struct {
+	Type   string        `json:"type"`
+	Base   int64         `json:"base,omitempty"`
+	Height int64         `json:"height,omitempty"`
+	Block  *types.Block  `json:"block,omitempty"`
+	Commit *types.Commit `json:"commit,omitempty"`
+}
+
+// Chain is the block store and state machine that block sync serves blocks
+// from and applies downloaded blocks to
+type Chain interface {
+	// Base returns the lowest height still stored
+	Base() int64
+	// Height returns the height of the last applied block
+	Height() int64
+	// LoadBlock returns a stored block and the commit that finalized it
+	LoadBlock(height int64) (*types.Block, *types.Commit, error)
+	// Validators returns the validator set that signs the next block
+	Validators() *types.ValidatorSet
+	// ApplyBlock executes a verified block and stores it with its commit
+	ApplyBlock(block *types.Block, commit *types.Commit) error
+}
+
+// Config configures the block sync reactor
+type Config struct {
+	ChainID string
+	Chain   Chain
+	// Enabled makes the node download missing blocks from peers before
+	// starting consensus. When false consensus starts right away and the
+	// reactor only serves blocks.
+	Enabled bool
+	// WaitForStateSync holds block sync back until SwitchToBlockSync is
+	// called, after state sync has restored a snapshot
+	WaitForStateSync bool
+	// SwitchToConsensus is called once, when the node has caught up
+	SwitchToConsensus func(height int64)
+	// OnFailure is called when block sync stops because the node cannot
+	// apply blocks
+	OnFailure func(err error)
+	Logger    *zap.Logger
+}
+
+// Status is the sync progress reported by the status endpoint
+type Status struct {
+	CatchingUp      bool      `json:"catching_up"`
+	LatestHeight    int64     `json:"latest_height"`
+	LatestBlockHash string    `json:"latest_block_hash"`
+	LatestBlockTime time.Time `json:"latest_block_time"`
+	TargetHeight    int64     `json:"target_height"`
+}
+
+// Reactor downloads blocks from peers in parallel, verifies their commits
+// against the known validator set and applies them in order. Once no peer
+// is ahead of us it hands over to consensus.
+type Reactor struct {
+	p2p.BaseReactor
+
+	config *Config
+	chain  Chain
+	pool   *blockPool
+	logger *zap.Logger
+
+	mu         sync.RWMutex
+	catchingUp bool
+	syncing    bool // the pool is running
+
+	applyFailures int // consecutive failures at the pool's height
+
+	blockReceived chan struct{}
+	quit          chan struct{}
+}
+
+// NewReactor creates a block sync reactor
+func NewReactor(cfg *Config) *Reactor {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Reactor{
+		config:        cfg,
+		chain:         cfg.Chain,
+		logger:        logger,
+		catchingUp:    cfg.Enabled || cfg.WaitForStateSync,
+		blockReceived: make(chan struct{}, 1),
+		quit:          make(chan struct{}),
+	}
+}
+
+// Channels returns the block sync channel
+func (r *Reactor) Channels() []byte { return []byte{BlocksyncChannel} }
+
+// Start begins syncing, or switches to consensus straight away when block
+// sync is disabled
+func (r *Reactor) Start() error {
+	if r.config.WaitForStateSync {
+		return nil
+	}
+	r.startSync()
+	return nil
+}
+
+// SwitchToBlockSync starts syncing from the height state sync restored
+func (r *Reactor) SwitchToBlockSync() {
+	r.startSync()
+}
+
+func (r *Reactor) startSync() {
+	if !r.config.Enabled {
+		r.switchToConsensus()
+		return
+	}
+
+	pool := newBlockPool(r.chain.Height() + 1)
+	r.mu.Lock()
+	r.pool = pool
+	r.syncing = true
+	r.mu.Unlock()
+
+	r.logger.Info("Starting block sync", zap.Int64("height", r.chain.Height()))
+	go r.poolRoutine()
+}
+
+// activePool returns the block pool while blocks are being downloaded
+func (r *Reactor) activePool() *blockPool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if !r.syncing {
+		return nil
+	}
+	return r.pool
+}
+
+// Stop stops syncing
+func (r *Reactor) Stop() {
+	close(r.quit)
+}
+
+// IsCatchingUp reports whether the node is still downloading blocks
+func (r *Reactor) IsCatchingUp() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.catchingUp
+}
+
+// Status returns the sync progress
+func (r *Reactor) Status() Status {
+	status := Status{CatchingUp: r.IsCatchingUp(), LatestHeight: r.chain.Height()}
+	if status.LatestHeight > 0 {
+		if block, _, err := r.chain.LoadBlock(status.LatestHeight); err == nil {
+			status.LatestBlockHash = block.Hash().String()
+			status.LatestBlockTime = block.Time
+		}
+	}
+	status.TargetHeight = status.LatestHeight
+	if pool := r.activePool(); pool != nil {
+		status.TargetHeight = max(status.TargetHeight, pool.maxPeerHeight())
+	}
+	return status
+}
+
+// AddPeer exchanges status with a new peer
+func (r *Reactor) AddPeer(peer *p2p.Peer) {
+	r.send(peer, message{Type: msgStatusResponse, Base: r.chain.Base(), Height: r.chain.Height()})
+	if r.IsCatchingUp() {
+		r.send(peer, message{Type: msgStatusRequest})
+	}
+}
+
+// RemovePeer frees the heights the peer was asked for
+func (r *Reactor) RemovePeer(peer *p2p.Peer, reason error) {
+	if pool := r.activePool(); pool != nil {
+		pool.removePeer(peer.ID())
+	}
+}
+
+// Receive serves block and status requests and collects responses
+func (r *Reactor) Receive(chID byte, peer *p2p.Peer, msgBytes []byte) {
+	var msg message
+	if err := json.Unmarshal(msgBytes, &msg); err != nil {
+		r.Node.ReportPeer(peer, p2p.BehaviourBadMessage)
+		return
+	}
+
+	switch msg.Type {
+	case msgStatusRequest:
+		r.send(peer, message{Type: msgStatusResponse, Base: r.chain.Base(), Height: r.chain.Height()})
+
+	case msgStatusResponse:
+		if msg.Base < 0 || msg.Height < msg.Base {
+			r.Node.ReportPeer(peer, p2p.BehaviourBadMessage)
+			return
+		}
+		if pool := r.activePool(); pool != nil {
+			pool.setPeerRange(peer.ID(), msg.Base, msg.Height)
+		}
+
+	case msgBlockRequest:
+		block, commit, err := r.chain.LoadBlock(msg.Height)
+		if err != nil || block == nil {
+			r.send(peer, message{Type: msgNoBlock, Height: msg.Height})
+			return
+		}
+		r.send(peer, message{Type: msgBlockResponse, Block: block, Commit: commit})
+
+	case msgBlockResponse:
+		if msg.Block == nil || msg.Commit == nil {
+			r.Node.ReportPeer(peer, p2p.BehaviourBadMessage)
+			return
+		}
+		pool := r.activePool()
+		if pool == nil || !pool.addBlock(peer.ID(), msg.Block, msg.Commit) {
+			// Late answers after a timeout or the switch to consensus are harmless
+			r.logger.Debug("Ignoring unrequested block", zap.String("peer", string(peer.ID())), zap.Int64("height", msg.Block.Height))
+			return
+		}
+		select {
+		case r.blockReceived <- struct{}{}:
+		default:
+		}
+
+	case msgNoBlock:
+		if pool := r.activePool(); pool != nil {
+			pool.noBlock(peer.ID(), msg.Height)
+		}
+
+	default:
+		r.Node.ReportPeer(peer, p2p.BehaviourBadMessage)
+	}
+}
+
+func (r *Reactor) send(peer *p2p.Peer, msg message) {
+	bz, err := json.Marshal(msg)
+	if err != nil {
+		r.logger.Error("Failed to encode block sync message", zap.Error(err))
+		return
+	}
+	peer.TrySend(BlocksyncChannel, bz)
+}
+
+func (r *Reactor) poolRoutine() {
+	requestTicker := time.NewTicker(requestInterval)
+	defer requestTicker.Stop()
+	statusTicker := time.NewTicker(statusUpdateInterval)
+	defer statusTicker.Stop()
+	switchTicker := time.NewTicker(switchCheckInterval)
+	defer switchTicker.Stop()
+
+	r.broadcastStatusRequest()
+	lastReport := time.Now()
+	for {
+		select {
+		case <-r.quit:
+			return
+
+		case <-requestTicker.C:
+			for _, req := range r.pool.nextRequests() {
+				if peer := r.Node.Peer(req.peerID); peer != nil {
+					r.send(peer, message{Type: msgBlockRequest, Height: req.height})
+				}
+			}
+			for _, id := range r.pool.timedOut() {
+				r.logger.Info("Peer timed out serving blocks", zap.String("peer", string(id)))
+			}
+
+		case <-statusTicker.C:
+			r.broadcastStatusRequest()
+
+		case <-switchTicker.C:
+			if r.pool.isCaughtUp() {
+				r.logger.Info("Caught up with peers", zap.Int64("height", r.chain.Height()))
+				r.switchToConsensus()
+				return
+			}
+			if time.Since(lastReport) >= statusUpdateInterval {
+				lastReport = time.Now()
+				r.logger.Info("Block sync progress",
+					zap.Int64("height", r.chain.Height()),
+					zap.Int64("target_height", r.pool.maxPeerHeight()),
+				)
+			}
+
+		case <-r.blockReceived:
+			if err := r.applyReadyBlocks(); err != nil {
+				r.fail(err)
+				return
+			}
+		}
+	}
+}
+
+// applyReadyBlocks applies consecutive downloaded blocks until one is
+// missing. It returns an error once a block has failed on our side
+// maxApplyAttempts times in a row.
+func (r *Reactor) applyReadyBlocks() error {
+	for {
+		select {
+		case <-r.quit:
+			return nil
+		default:
+		}
+
+		req := r.pool.peek()
+		if req == nil {
+			return nil
+		}
+		err := r.verify(req.block, req.commit)
+		if err != nil && !errors.Is(err, errLoadBlock) {
+			r.logger.Warn("Received invalid block",
+				zap.Int64("height", req.height),
+				zap.String("peer", string(req.peerID)),
+				zap.Error(err),
+			)
+			r.pool.redo(req.height)
+			if peer := r.Node.Peer(req.peerID); peer != nil {
+				r.Node.ReportPeer(peer, p2p.BehaviourInvalidBlock)
+			}
+			continue
+		}
+		if err == nil {
+			err = r.chain.ApplyBlock(req.block, req.commit)
+		}
+		if err != nil {
+			// The block is signed by the validators, so the fault is ours;
+			// fetch it again and retry rather than blaming the peer
+			r.applyFailures++
+			r.logger.Error("Failed to apply block",
+				zap.Int64("height", req.height),
+				zap.Int("attempt", r.applyFailures),
+				zap.Error(err),
+			)
+			if r.applyFailures >= maxApplyAttempts {
+				return fmt.Errorf("block %d failed %d times: %w", req.height, r.applyFailures, err)
+			}
+			r.pool.retry(req.height)
+			return nil
+		}
+		r.applyFailures = 0
+		r.pool.pop()
+		if peer := r.Node.Peer(req.peerID); peer != nil {
+			r.Node.ReportPeer(peer, p2p.BehaviourValidBlock)
+		}
+	}
+}
+
+// fail stops syncing without handing over to consensus and reports err
+func (r *Reactor) fail(err error) {
+	r.mu.Lock()
+	r.syncing = false
+	r.mu.Unlock()
+
+	r.logger.Error("Block sync stopped", zap.Error(err))
+	if r.config.OnFailure != nil {
+		r.config.OnFailure(err)
+	}
+}
+
+// verify checks a downloaded block and that its commit is signed by more
+// than 2/3 of the current validator set
+func (r *Reactor) verify(block *types.Block, commit *types.Commit) error {
+	if block.ChainID != r.config.ChainID {
+		return fmt.Errorf("%w: block is for chain %q", types.ErrInvalidBlock, block.ChainID)
+	}
+	if err := block.ValidateBasic(); err != nil {
+		return err
+	}
+	vals := r.chain.Validators()
+	if !bytes.Equal(block.ValidatorsHash, vals.Hash()) {
+		return fmt.Errorf("%w: validators hash does not match our validator set", types.ErrInvalidBlock)
+	}
+	if last := r.chain.Height(); last > 0 {
+		prev, _, err := r.chain.LoadBlock(last)
+		if err != nil {
+			return fmt.Errorf("%w %d: %v", errLoadBlock, last, err)
+		}
+		if !bytes.Equal(block.LastBlockHash, prev.Hash()) {
+			return fmt.Errorf("%w: block does not extend our chain", types.ErrInvalidBlock)
+		}
+	}
+	return vals.VerifyCommitLight(r.config.ChainID, block.Hash(), block.Height, commit)
+}
+
+func (r *Reactor) broadcastStatusRequest() {
+	bz, _ := json.Marshal(message{Type: msgStatusRequest})
+	r.Node.Broadcast(BlocksyncChannel, bz)
+}
+
+func (r *Reactor) switchToConsensus() {
+	r.mu.Lock()
+	r.catchingUp = false
+	r.syncing = false
+	r.mu.Unlock()
+
+	if r.config.SwitchToConsensus != nil {
+		r.config.SwitchToConsensus(r.chain.Height())
+	}
+}
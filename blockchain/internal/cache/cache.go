@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrClosed is returned by stores used after Close
+var ErrClosed = errors.New("cache is closed")
+
+// Store is a shared key-value cache with expiring entries and token bucket
+// rate limits. Redis shares one store between API nodes; the in-process LRU
+// serves a single node.
+type Store interface {
+	// Get returns the value under key, or nil if there is none or it expired
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores value under key until ttl elapses
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Take removes one token from the bucket under key, refilling it at
+	// limit.Rate per second up to limit.Burst tokens
+	Take(ctx context.Context, key string, limit Limit) (*Result, error)
+	// Incr adds one to the counter under key and returns the new count. A
+	// new counter expires ttl after its first increment.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// Backend names the store in logs and status output
+	Backend() string
+	Close() error
+}
+
+// Limit is a token bucket: Burst requests at once, refilled at Rate per
+// second
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result is the outcome of taking a token
+type Result struct {
+	Allowed   bool
+	Limit     int // the bucket size
+	Remaining int // whole tokens left
+	// RetryAfter is how long until the next token, when not allowed
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+}
+
+// Config configures the cache store
+type Config struct {
+	// RedisURL selects a Redis store shared by API nodes; empty uses the
+	// in-process LRU
+	RedisURL string
+	// Size is the number of entries the LRU holds
+	Size   int
+	Logger *zap.Logger
+}
+
+const defaultSize = 10000
+
+// Open connects to Redis when a URL is configured and falls back to an
+// in-process LRU when none is, or when Redis cannot be reached at startup.
+// Rate limits in the LRU only apply to this node.
+func Open(cfg *Config) Store {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	size := cfg.Size
+	if size <= 0 {
+		size = defaultSize
+	}
+	if cfg.RedisURL == "" {
+		return NewLRU(size)
+	}
+
+	store, err := NewRedis(cfg.RedisURL)
+	if err != nil {
+		logger.Warn("Redis unavailable, caching and rate limiting in process", zap.Error(err))
+		return NewLRU(size)
+	}
+	return store
+}
+
+// bucket refills tokens from the time of the last take
+type bucket struct {
+	Tokens float64
+	Last   time.Time
+}
+
+// take refills the bucket up to now and removes a token if one is left
+func (b *bucket) take(limit Limit, now time.Time) *Result {
+	burst := float64(limit.Burst)
+	if elapsed := now.Sub(b.Last).Seconds(); elapsed > 0 {
+		b.Tokens += elapsed * limit.Rate
+	}
+	if b.Tokens > burst {
+		b.Tokens = burst
+	}
+	b.Last = now
+	allowed := b.Tokens >= 1
+	if allowed {
+		b.Tokens--
+	}
+	return tokenResult(allowed, b.Tokens, limit)
+}
+
+// tokenResult describes a bucket left with tokens after a take
+func tokenResult(allowed bool, tokens float64, limit Limit) *Result {
+	res := &Result{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: int(tokens),
+		Reset:     secondsDuration((float64(limit.Burst) - tokens) / limit.Rate),
+	}
+	if !allowed {
+		res.RetryAfter = secondsDuration((1 - tokens) / limit.Rate)
+	}
+	return res
+}
+
+func secondsDuration(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// lruStore is an in-process Store that evicts the least recently used
+// entries beyond its size
+type lruStore struct {
+	mu     sync.Mutex
+	size   int
+	order  *list.List // front is most recently used
+	items  map[string]*list.Element
+	closed bool
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	bucket  *bucket
+	count   *int64
+	expires time.Time // zero for buckets, which expire by eviction
+}
+
+// NewLRU creates an in-process store holding at most size entries
+func NewLRU(size int) Store {
+	return &lruStore{size: size, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+// Get implements Store
+func (s *lruStore) Get(_ context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrClosed
+	}
+	el, ok := s.items[key]
+	if !ok {
+		return nil, nil
+	}
+	entry := el.Value.(*lruEntry)
+	if entry.bucket != nil || entry.count != nil {
+		return nil, nil
+	}
+	if time.Now().After(entry.expires) {
+		s.remove(el)
+		return nil, nil
+	}
+	s.order.MoveToFront(el)
+	return entry.value, nil
+}
+
+// Set implements Store
+func (s *lruStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	s.put(&lruEntry{key: key, value: append([]byte{}, value...), expires: time.Now().Add(ttl)})
+	return nil
+}
+
+// Take implements Store
+func (s *lruStore) Take(_ context.Context, key string, limit Limit) (*Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrClosed
+	}
+	now := time.Now()
+	var b *bucket
+	if el, ok := s.items[key]; ok && el.Value.(*lruEntry).bucket != nil {
+		b = el.Value.(*lruEntry).bucket
+		s.order.MoveToFront(el)
+	} else {
+		b = &bucket{Tokens: float64(limit.Burst), Last: now}
+		s.put(&lruEntry{key: key, bucket: b})
+	}
+	return b.take(limit, now), nil
+}
+
+// Incr implements Store
+func (s *lruStore) Incr(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, ErrClosed
+	}
+	now := time.Now()
+	if el, ok := s.items[key]; ok {
+		entry := el.Value.(*lruEntry)
+		if entry.count != nil && now.Before(entry.expires) {
+			*entry.count++
+			s.order.MoveToFront(el)
+			return *entry.count, nil
+		}
+	}
+	count := int64(1)
+	s.put(&lruEntry{key: key, count: &count, expires: now.Add(ttl)})
+	return count, nil
+}
+
+// Backend implements Store
+func (s *lruStore) Backend() string { return "lru" }
+
+// Close implements Store
+func (s *lruStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.items = nil
+	s.order.Init()
+	return nil
+}
+
+func (s *lruStore) put(entry *lruEntry) {
+	if el, ok := s.items[entry.key]; ok {
+		el.Value = entry
+		s.order.MoveToFront(el)
+		return
+	}
+	s.items[entry.key] = s.order.PushFront(entry)
+	for s.order.Len() > s.size {
+		s.remove(s.order.Back())
+	}
+}
+
+func (s *lruStore) remove(el *list.Element) {
+	s.order.Remove(el)
+	delete(s.items, el.Value.(*lruEntry).key)
+}
@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix namespaces the node's keys in a shared Redis
+const keyPrefix = "vindex:"
+
+// takeScript refills and takes from a token bucket atomically, so every API
+// node sharing the Redis draws from the same bucket. Clocks of different
+// nodes may disagree slightly; a bucket never refills backwards.
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(state[1])
+local last = tonumber(state[2])
+if tokens == nil or last == nil then
+	tokens = burst
+	last = now
+end
+if now > last then
+	tokens = tokens + (now - last) / 1000 * rate
+	last = now
+end
+if tokens > burst then
+	tokens = burst
+end
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', tostring(last))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// incrScript increments a counter and sets its expiry on creation
+var incrScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count
+`)
+
+// redisStore is a Store shared by every node using the same Redis
+type redisStore struct {
+	client *redis.Client
+}
+
+// NewRedis connects to the Redis at url, such as redis://localhost:6379/0,
+// and checks that it answers
+func NewRedis(url string) (Store, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+	client := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("redis %s: %w", opts.Addr, err)
+	}
+	return &redisStore{client: client}, nil
+}
+
+// Get implements Store
+func (s *redisStore) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := s.client.Get(ctx, keyPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return value, err
+}
+
+// Set implements Store
+func (s *redisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, keyPrefix+key, value, ttl).Err()
+}
+
+// Take implements Store
+func (s *redisStore) Take(ctx context.Context, key string, limit Limit) (*Result, error) {
+	now := time.Now().UnixMilli()
+	res, err := takeScript.Run(ctx, s.client, []string{keyPrefix + key}, limit.Rate, limit.Burst, now).Slice()
+	if err != nil {
+		return nil, err
+	}
+	if len(res) != 2 {
+		return nil, fmt.Errorf("unexpected rate limit reply %v", res)
+	}
+	allowed, _ := res[0].(int64)
+	raw, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected rate limit reply %v", res)
+	}
+	return tokenResult(allowed == 1, tokens, limit), nil
+}
+
+// Incr implements Store
+func (s *redisStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return incrScript.Run(ctx, s.client, []string{keyPrefix + key}, ttl.Milliseconds()).Int64()
+}
+
+// Backend implements Store
+func (s *redisStore) Backend() string { return "redis" }
+
+// Close implements Store
+func (s *redisStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,327 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var (
+	// ErrNoSQL is returned when migrating a backend without SQL
+	ErrNoSQL = errors.New("backend has no SQL schema to migrate")
+	// ErrSchemaOutdated is returned when migrations are pending
+	ErrSchemaOutdated = errors.New("database schema is outdated")
+	// ErrMigrationModified is returned when an applied migration no longer
+	// matches its file, or the database has migrations this binary lacks
+	ErrMigrationModified = errors.New("applied migration does not match")
+	// ErrIrreversibleMigration is returned when rolling back a migration
+	// without a down file
+	ErrIrreversibleMigration = errors.New("migration cannot be rolled back")
+)
+
+// migrationLock is the advisory lock held while migrating, so nodes sharing
+// a database do not migrate it at the same time
+const migrationLock = 0x6d696772 // "migr"
+
+const migrationsSchema = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    BIGINT PRIMARY KEY,
+	name       TEXT NOT NULL,
+	checksum   TEXT NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL
+);
+`
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is an embedded schema change. Files are named
+// NNNN_name.up.sql and, optionally, NNNN_name.down.sql.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // SHA-256 of Up and Down
+}
+
+// MigrationStatus is a migration and whether it has been applied
+type MigrationStatus struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Checksum  string     `json:"checksum"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	// Modified is set when the applied checksum differs from the file's
+	Modified bool `json:"modified,omitempty"`
+	// Missing is set for applied migrations with no file in this binary
+	Missing bool `json:"missing,omitempty"`
+}
+
+// Migrations returns the embedded migrations in version order
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		data, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		m.Checksum = migrationChecksum(m.Up, m.Down)
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// migrationChecksum hashes both directions so that editing a down file
+// is caught as well. The lengths keep "a"+"bc" distinct from "ab"+"c".
+func migrationChecksum(up, down string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d:%s%d:%s", len(up), up, len(down), down)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Migrator applies and rolls back the embedded migrations
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	logger     *zap.Logger
+}
+
+// NewMigrator creates a migrator for a database. Backends without SQL have
+// nothing to migrate and return ErrNoSQL.
+func NewMigrator(db Database, logger *zap.Logger) (*Migrator, error) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if db.SQL() == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoSQL, db.Backend())
+	}
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db.SQL(), migrations: migrations, logger: logger}, nil
+}
+
+// Status lists every embedded or applied migration in version order
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	if _, err := m.db.ExecContext(ctx, migrationsSchema); err != nil {
+		return nil, err
+	}
+	rows, err := m.db.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]MigrationStatus)
+	for rows.Next() {
+		var s MigrationStatus
+		var at time.Time
+		if err := rows.Scan(&s.Version, &s.Name, &s.Checksum, &at); err != nil {
+			return nil, err
+		}
+		at = at.UTC()
+		s.AppliedAt = &at
+		applied[s.Version] = s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		s := MigrationStatus{Version: migration.Version, Name: migration.Name, Checksum: migration.Checksum}
+		if a, ok := applied[migration.Version]; ok {
+			s.AppliedAt = a.AppliedAt
+			s.Modified = a.Checksum != migration.Checksum
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, s)
+	}
+	for _, a := range applied {
+		a.Missing = true
+		statuses = append(statuses, a)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Verify checks that every applied migration matches its file. With
+// requireCurrent it also fails while migrations are pending.
+func (m *Migrator) Verify(ctx context.Context, requireCurrent bool) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	var pending []int64
+	for _, s := range statuses {
+		switch {
+		case s.Missing:
+			return fmt.Errorf("%w: %d_%s is applied but unknown to this binary", ErrMigrationModified, s.Version, s.Name)
+		case s.Modified:
+			return fmt.Errorf("%w: %d_%s was changed after it was applied", ErrMigrationModified, s.Version, s.Name)
+		case s.AppliedAt == nil:
+			pending = append(pending, s.Version)
+		}
+	}
+	if requireCurrent && len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending migrations, run `vindexchain db migrate up`", ErrSchemaOutdated, len(pending))
+	}
+	return nil
+}
+
+// Up applies pending migrations up to and including target, or all of them
+// if target is 0, each in its own transaction. It returns the migrations
+// applied.
+func (m *Migrator) Up(ctx context.Context, target int64) ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		if err := m.Verify(ctx, false); err != nil {
+			return err
+		}
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		done := make(map[int64]bool)
+		for _, s := range statuses {
+			done[s.Version] = s.AppliedAt != nil
+		}
+		for _, migration := range m.migrations {
+			if target > 0 && migration.Version > target {
+				break
+			}
+			if done[migration.Version] {
+				continue
+			}
+			err := m.apply(ctx, conn, migration.Up, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, `
+					INSERT INTO schema_migrations (version, name, checksum, applied_at)
+					VALUES ($1, $2, $3, $4)`,
+					migration.Version, migration.Name, migration.Checksum, time.Now().UTC(),
+				)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			m.logger.Info("Applied migration", zap.Int64("version", migration.Version), zap.String("name", migration.Name))
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the latest steps applied migrations, newest first. It
+// returns the migrations rolled back.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var rolledBack []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		if err := m.Verify(ctx, false); err != nil {
+			return err
+		}
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		byVersion := make(map[int64]Migration)
+		for _, migration := range m.migrations {
+			byVersion[migration.Version] = migration
+		}
+		for i := len(statuses) - 1; i >= 0 && len(rolledBack) < steps; i-- {
+			if statuses[i].AppliedAt == nil {
+				continue
+			}
+			migration := byVersion[statuses[i].Version]
+			if migration.Down == "" {
+				return fmt.Errorf("%w: %d_%s has no down file", ErrIrreversibleMigration, migration.Version, migration.Name)
+			}
+			err := m.apply(ctx, conn, migration.Down, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("rollback of %d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			m.logger.Info("Rolled back migration", zap.Int64("version", migration.Version), zap.String("name", migration.Name))
+			rolledBack = append(rolledBack, migration)
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+// apply runs a migration script and records it in one transaction
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, script string, record func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// locked runs fn holding the migration lock on a dedicated connection
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLock); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLock)
+	return fn(conn)
+}
@@ -0,0 +1,159 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no embedded migrations")
+	}
+	for i, m := range migrations {
+		if i > 0 && m.Version <= migrations[i-1].Version {
+			t.Errorf("migration %d_%s is out of order", m.Version, m.Name)
+		}
+		if m.Checksum != migrationChecksum(m.Up, m.Down) {
+			t.Errorf("migration %d_%s checksum %s does not cover its up and down files", m.Version, m.Name, m.Checksum)
+		}
+	}
+
+	// The key-value store holds chain data and must never be dropped
+	if first := migrations[0]; first.Version != 1 || first.Name != "kv_store" || first.Down != "" {
+		t.Errorf("first migration is %d_%s with down %q; want an irreversible 1_kv_store", first.Version, first.Name, first.Down)
+	}
+}
+
+func TestMigrationChecksum(t *testing.T) {
+	base := migrationChecksum("CREATE TABLE t ();", "DROP TABLE t;")
+	tests := []struct {
+		name     string
+		up, down string
+	}{
+		{"edited up", "CREATE TABLE u ();", "DROP TABLE t;"},
+		{"edited down", "CREATE TABLE t ();", "DROP TABLE u;"},
+		{"no down", "CREATE TABLE t ();", ""},
+		{"shifted boundary", "CREATE TABLE t ();DROP", " TABLE t;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if migrationChecksum(tt.up, tt.down) == base {
+				t.Error("checksum did not change")
+			}
+		})
+	}
+}
+
+func TestNewMigratorWithoutSQL(t *testing.T) {
+	db, err := Initialize("mem://")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewMigrator(db, nil); !errors.Is(err, ErrNoSQL) {
+		t.Errorf("NewMigrator on mem:// = %v; want ErrNoSQL", err)
+	}
+}
+
+// TestMigratorChecksums runs against the Postgres database named by
+// VINDEX_TEST_DATABASE_URL, which it migrates and leaves migrated
+func TestMigratorChecksums(t *testing.T) {
+	url := os.Getenv("VINDEX_TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("VINDEX_TEST_DATABASE_URL is not set")
+	}
+	ctx := context.Background()
+	db, err := Initialize(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	m, err := NewMigrator(db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Verify(ctx, true); err != nil {
+		t.Fatalf("Verify after Up: %v", err)
+	}
+
+	exec := func(t *testing.T, query string, args ...interface{}) {
+		t.Helper()
+		if _, err := db.SQL().ExecContext(ctx, query, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	latest := m.migrations[len(m.migrations)-1]
+
+	tests := []struct {
+		name    string
+		tamper  string
+		restore func(t *testing.T)
+		check   func(s MigrationStatus) bool
+	}{
+		{
+			name:   "modified",
+			tamper: `UPDATE schema_migrations SET checksum = 'edited' WHERE version = 1`,
+			restore: func(t *testing.T) {
+				exec(t, `UPDATE schema_migrations SET checksum = $1 WHERE version = 1`, m.migrations[0].Checksum)
+			},
+			check: func(s MigrationStatus) bool { return s.Version == 1 && s.Modified },
+		},
+		{
+			name:    "missing",
+			tamper:  `INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (999999, 'future', 'x', now())`,
+			restore: func(t *testing.T) { exec(t, `DELETE FROM schema_migrations WHERE version = 999999`) },
+			check:   func(s MigrationStatus) bool { return s.Version == 999999 && s.Missing },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exec(t, tt.tamper)
+			defer tt.restore(t)
+
+			statuses, err := m.Status(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			found := false
+			for _, s := range statuses {
+				found = found || tt.check(s)
+			}
+			if !found {
+				t.Errorf("status does not report the migration as %s: %+v", tt.name, statuses)
+			}
+			if err := m.Verify(ctx, false); !errors.Is(err, ErrMigrationModified) {
+				t.Errorf("Verify = %v; want ErrMigrationModified", err)
+			}
+			if _, err := m.Up(ctx, 0); !errors.Is(err, ErrMigrationModified) {
+				t.Errorf("Up = %v; want ErrMigrationModified", err)
+			}
+		})
+	}
+
+	if latest.Down != "" {
+		rolledBack, err := m.Down(ctx, 1)
+		if err != nil || len(rolledBack) != 1 || rolledBack[0].Version != latest.Version {
+			t.Fatalf("Down(1) = %v, %v; want %d_%s", rolledBack, err, latest.Version, latest.Name)
+		}
+		if err := m.Verify(ctx, true); !errors.Is(err, ErrSchemaOutdated) {
+			t.Errorf("Verify with a pending migration = %v; want ErrSchemaOutdated", err)
+		}
+		if _, err := m.Up(ctx, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := m.Down(ctx, len(m.migrations)); !errors.Is(err, ErrIrreversibleMigration) {
+		t.Errorf("rolling back every migration = %v; want ErrIrreversibleMigration", err)
+	}
+	if _, err := m.Up(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,6 @@
+-- Key-value store used by the Postgres backend. BYTEA compares bytewise, so
+-- key order matches the embedded backends.
+CREATE TABLE IF NOT EXISTS kv_store (
+	key   BYTEA PRIMARY KEY,
+	value BYTEA NOT NULL
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS dex_candles;
+DROP TABLE IF EXISTS dex_trades;
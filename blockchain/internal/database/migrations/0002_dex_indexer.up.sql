@@ -0,0 +1,30 @@
+-- DEX trades and the OHLCV candles built from them
+CREATE TABLE IF NOT EXISTS dex_trades (
+	id          BIGSERIAL PRIMARY KEY,
+	pool_id     BIGINT NOT NULL,
+	trade_type  TEXT NOT NULL,
+	trader      TEXT NOT NULL,
+	denom_in    TEXT NOT NULL,
+	denom_out   TEXT NOT NULL,
+	amount_in   NUMERIC(20, 0) NOT NULL,
+	amount_out  NUMERIC(20, 0) NOT NULL,
+	fee         NUMERIC(20, 0) NOT NULL,
+	burned      NUMERIC(20, 0) NOT NULL,
+	price       DOUBLE PRECISION NOT NULL,
+	executed_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS dex_trades_pool_idx ON dex_trades (pool_id, id DESC);
+
+CREATE TABLE IF NOT EXISTS dex_candles (
+	pool_id   BIGINT NOT NULL,
+	interval  TEXT NOT NULL,
+	open_time TIMESTAMPTZ NOT NULL,
+	open      DOUBLE PRECISION NOT NULL,
+	high      DOUBLE PRECISION NOT NULL,
+	low       DOUBLE PRECISION NOT NULL,
+	close     DOUBLE PRECISION NOT NULL,
+	volume_a  NUMERIC(30, 0) NOT NULL,
+	volume_b  NUMERIC(30, 0) NOT NULL,
+	trades    BIGINT NOT NULL,
+	PRIMARY KEY (pool_id, interval, open_time)
+);
@@ -0,0 +1,3 @@
+CREATE SEQUENCE IF NOT EXISTS dex_trades_id_seq OWNED BY dex_trades.id;
+SELECT setval('dex_trades_id_seq', COALESCE((SELECT MAX(id) FROM dex_trades), 0) + 1, false);
+ALTER TABLE dex_trades ALTER COLUMN id SET DEFAULT nextval('dex_trades_id_seq');
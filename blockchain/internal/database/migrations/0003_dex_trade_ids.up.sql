@@ -0,0 +1,4 @@
+-- Trades are stored under the IDs the DEX assigns them, so a block applied
+-- again after a crash does not index its trades twice
+ALTER TABLE dex_trades ALTER COLUMN id DROP DEFAULT;
+DROP SEQUENCE IF EXISTS dex_trades_id_seq;
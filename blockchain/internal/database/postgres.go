@@ -0,0 +1,166 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"github.com/vindexchain/blockchain/internal/kv"
+)
+
+const connectTimeout = 10 * time.Second
+
+// kvWriteLock is the advisory lock that serializes key-value transactions,
+// so read-modify-write updates behave as they do on the embedded backends
+const kvWriteLock = 0x76696e64 // "vind"
+
+// postgresDB keeps the key-value store in the kv_store table, which the
+// migrations create
+type postgresDB struct {
+	db *sql.DB
+}
+
+func openPostgres(url string) (Database, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return &postgresDB{db: db}, nil
+}
+
+func (p *postgresDB) Backend() string { return BackendPostgres }
+
+func (p *postgresDB) SQL() *sql.DB { return p.db }
+
+// Get implements kv.DB
+func (p *postgresDB) Get(key []byte) ([]byte, error) {
+	return getRow(p.db.QueryRow(`SELECT value FROM kv_store WHERE key = $1`, key))
+}
+
+// Iterate implements kv.DB
+func (p *postgresDB) Iterate(prefix []byte, fn func(key, value []byte) error) error {
+	return p.IterateRange(prefix, kv.PrefixEnd(prefix), fn)
+}
+
+// IterateRange implements kv.DB
+func (p *postgresDB) IterateRange(start, end []byte, fn func(key, value []byte) error) error {
+	rows, err := queryRange(p.db, start, end)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key, value []byte
+		if err := rows.Scan(&key, &value); err != nil {
+			return err
+		}
+		if err := fn(key, value); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Update implements kv.DB
+func (p *postgresDB) Update(fn func(tx kv.Tx) error) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, kvWriteLock); err != nil {
+		return err
+	}
+	if err := fn(postgresTx{tx: tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Close implements kv.DB
+func (p *postgresDB) Close() error { return p.db.Close() }
+
+type postgresTx struct {
+	tx *sql.Tx
+}
+
+func (t postgresTx) Get(key []byte) ([]byte, error) {
+	return getRow(t.tx.QueryRow(`SELECT value FROM kv_store WHERE key = $1`, key))
+}
+
+// Iterate reads every row before calling fn, since the connection cannot
+// run other statements while a result set is open
+func (t postgresTx) Iterate(prefix []byte, fn func(key, value []byte) error) error {
+	rows, err := queryRange(t.tx, prefix, kv.PrefixEnd(prefix))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var keys, values [][]byte
+	for rows.Next() {
+		var key, value []byte
+		if err := rows.Scan(&key, &value); err != nil {
+			return err
+		}
+		keys, values = append(keys, key), append(values, value)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+	for i := range keys {
+		if err := fn(keys[i], values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t postgresTx) Set(key, value []byte) error {
+	_, err := t.tx.Exec(`
+		INSERT INTO kv_store (key, value) VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value`,
+		key, value,
+	)
+	return err
+}
+
+func (t postgresTx) Delete(key []byte) error {
+	_, err := t.tx.Exec(`DELETE FROM kv_store WHERE key = $1`, key)
+	return err
+}
+
+func getRow(row *sql.Row) ([]byte, error) {
+	var value []byte
+	err := row.Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return value, err
+}
+
+// queryRange selects the rows with keys in [start, end) in key order
+func queryRange(q interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}, start, end []byte) (*sql.Rows, error) {
+	var upper interface{}
+	if end != nil {
+		upper = end
+	}
+	return q.Query(`
+		SELECT key, value FROM kv_store
+		WHERE key >= $1 AND ($2::bytea IS NULL OR key < $2)
+		ORDER BY key`,
+		append([]byte{}, start...), upper,
+	)
+}
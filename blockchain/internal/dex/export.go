@@ -0,0 +1,154 @@
+package dex
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Keys of the DEX in the state store, relative to the module prefix. The
+// recent trades are not state; the trade indexer keeps their history.
+const (
+	poolsKeyPrefix  = "pools/"  // pools/{id} holds the pool
+	sharesKeyPrefix = "shares/" // shares/{id}/{owner} holds the LP shares
+	locksKey        = "locks"   // every LP lock
+	nextPoolIDKey   = "next_pool_id"
+	nextTradeIDKey  = "next_trade_id"
+)
+
+// ExportState returns every pool, LP share balance and lock, and the next
+// pool and trade IDs
+func (k *Keeper) ExportState() (map[string][]byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	state := make(map[string][]byte)
+	for id, pool := range k.pools {
+		data, err := json.Marshal(pool)
+		if err != nil {
+			return nil, err
+		}
+		state[poolKey(id)] = data
+	}
+	for id, owners := range k.shares {
+		for owner, shares := range owners {
+			if shares > 0 {
+				state[sharesKey(id, owner)] = strconv.AppendUint(nil, shares, 10)
+			}
+		}
+	}
+	if len(k.locks) > 0 {
+		data, err := json.Marshal(k.locks)
+		if err != nil {
+			return nil, err
+		}
+		state[locksKey] = data
+	}
+	state[nextPoolIDKey] = strconv.AppendUint(nil, k.nextID, 10)
+	state[nextTradeIDKey] = strconv.AppendUint(nil, k.nextTradeID, 10)
+	return state, nil
+}
+
+// ImportState replaces the pools, LP shares and locks with exported state
+func (k *Keeper) ImportState(state map[string][]byte) error {
+	pools := make(map[uint64]*Pool)
+	pairs := make(map[string]uint64)
+	shares := make(map[uint64]map[string]uint64)
+	var locks []*Lock
+	nextID, nextTradeID := uint64(1), uint64(1)
+
+	for key, value := range state {
+		var err error
+		switch {
+		case strings.HasPrefix(key, poolsKeyPrefix):
+			var pool Pool
+			if err = json.Unmarshal(value, &pool); err == nil {
+				pools[pool.ID] = &pool
+				pairs[pairKey(pool.DenomA, pool.DenomB)] = pool.ID
+			}
+		case strings.HasPrefix(key, sharesKeyPrefix):
+			rawID, owner, ok := strings.Cut(strings.TrimPrefix(key, sharesKeyPrefix), "/")
+			if !ok || owner == "" {
+				return fmt.Errorf("invalid LP share key %s", key)
+			}
+			var id, amount uint64
+			if id, err = strconv.ParseUint(rawID, 10, 64); err == nil {
+				if amount, err = strconv.ParseUint(string(value), 10, 64); err == nil {
+					if shares[id] == nil {
+						shares[id] = make(map[string]uint64)
+					}
+					shares[id][owner] = amount
+				}
+			}
+		case key == locksKey:
+			err = json.Unmarshal(value, &locks)
+		case key == nextPoolIDKey:
+			nextID, err = strconv.ParseUint(string(value), 10, 64)
+		case key == nextTradeIDKey:
+			nextTradeID, err = strconv.ParseUint(string(value), 10, 64)
+		default:
+			err = fmt.Errorf("unknown key")
+		}
+		if err != nil {
+			return fmt.Errorf("invalid DEX state under %s: %w", key, err)
+		}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.pools, k.pairs, k.shares, k.locks = pools, pairs, shares, locks
+	k.nextID, k.nextTradeID = nextID, nextTradeID
+	k.changed = make(map[string]bool)
+	return nil
+}
+
+// ExportChanges returns the keys changed since the last export, with nil
+// values for emptied LP share balances and locks
+func (k *Keeper) ExportChanges() (map[string][]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	changes := make(map[string][]byte, len(k.changed))
+	for key := range k.changed {
+		var value []byte
+		switch {
+		case strings.HasPrefix(key, poolsKeyPrefix):
+			id, _ := strconv.ParseUint(strings.TrimPrefix(key, poolsKeyPrefix), 10, 64)
+			data, err := json.Marshal(k.pools[id])
+			if err != nil {
+				return nil, err
+			}
+			value = data
+		case strings.HasPrefix(key, sharesKeyPrefix):
+			rawID, owner, _ := strings.Cut(strings.TrimPrefix(key, sharesKeyPrefix), "/")
+			id, _ := strconv.ParseUint(rawID, 10, 64)
+			if shares := k.shares[id][owner]; shares > 0 {
+				value = strconv.AppendUint(nil, shares, 10)
+			}
+		case key == locksKey:
+			if len(k.locks) > 0 {
+				data, err := json.Marshal(k.locks)
+				if err != nil {
+					return nil, err
+				}
+				value = data
+			}
+		case key == nextPoolIDKey:
+			value = strconv.AppendUint(nil, k.nextID, 10)
+		case key == nextTradeIDKey:
+			value = strconv.AppendUint(nil, k.nextTradeID, 10)
+		}
+		changes[key] = value
+	}
+	k.changed = make(map[string]bool)
+	return changes, nil
+}
+
+func poolKey(id uint64) string {
+	return poolsKeyPrefix + strconv.FormatUint(id, 10)
+}
+
+func sharesKey(id uint64, owner string) string {
+	return sharesKeyPrefix + strconv.FormatUint(id, 10) + "/" + owner
+}
@@ -0,0 +1,270 @@
+package dex
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/bank"
+)
+
+// ModuleName is the name of the DEX module; pool reserves live in its account
+const ModuleName = "dex"
+
+var (
+	// ErrPoolNotFound is returned for unknown pool IDs
+	ErrPoolNotFound = errors.New("pool not found")
+	// ErrPoolExists is returned when a pool for the pair already exists
+	ErrPoolExists = errors.New("pool already exists for pair")
+	// ErrInsufficientLiquidity is returned when deposits are too small to mint shares
+	ErrInsufficientLiquidity = errors.New("insufficient liquidity")
+	// ErrSlippage is returned when a swap or liquidity change misses its limit
+	ErrSlippage = errors.New("slippage limit exceeded")
+	// ErrInvalidRoute is returned when a route does not connect the requested denoms
+	ErrInvalidRoute = errors.New("invalid route")
+	// ErrSharesLocked is returned when withdrawing LP shares that are still locked
+	ErrSharesLocked = errors.New("LP shares are locked")
+)
+
+const (
+	defaultSwapFeeBps = 30 // 0.30%
+	defaultBurnShare  = 20 // 20% of every swap fee is burned
+	defaultMaxTrades  = 10000
+	defaultMaxHops    = 3
+)
+
+// Config holds DEX parameters
+type Config struct {
+	// SwapFeeBps is the swap fee in basis points, charged on the input
+	// amount, and BurnShare the percent of it that is burned instead of
+	// paid to LPs. Zero is a valid setting for both; nil uses the default.
+	SwapFeeBps *uint64
+	BurnShare  *uint64
+	MaxTrades  int // number of recent trades kept in memory
+	MaxHops    int // longest route considered by Quote
+	Logger     *zap.Logger
+}
+
+// Keeper owns every pool, LP share balance and LP lock
+type Keeper struct {
+	mu          sync.RWMutex
+	bank        *bank.Keeper
+	config      *Config
+	pools       map[uint64]*Pool
+	pairs       map[string]uint64            // pair key -> pool ID
+	shares      map[uint64]map[string]uint64 // pool ID -> owner -> shares
+	locks       []*Lock
+	trades      []Trade // oldest first, capped at MaxTrades
+	listeners   []TradeListener
+	nextID      uint64
+	nextTradeID uint64
+	swapFeeBps  uint64
+	burnShare   uint64
+	changed     map[string]bool // state keys changed since the last export
+	logger      *zap.Logger
+}
+
+// NewKeeper creates a new DEX keeper backed by the ledger
+func NewKeeper(bankKeeper *bank.Keeper, cfg *Config) *Keeper {
+	swapFeeBps, burnShare := uint64(defaultSwapFeeBps), uint64(defaultBurnShare)
+	if cfg.SwapFeeBps != nil {
+		swapFeeBps = *cfg.SwapFeeBps
+	}
+	if cfg.BurnShare != nil {
+		burnShare = *cfg.BurnShare
+	}
+	if cfg.MaxTrades <= 0 {
+		cfg.MaxTrades = defaultMaxTrades
+	}
+	if cfg.MaxHops <= 0 {
+		cfg.MaxHops = defaultMaxHops
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &Keeper{
+		bank:        bankKeeper,
+		config:      cfg,
+		pools:       make(map[uint64]*Pool),
+		pairs:       make(map[string]uint64),
+		shares:      make(map[uint64]map[string]uint64),
+		nextID:      1,
+		nextTradeID: 1,
+		swapFeeBps:  swapFeeBps,
+		burnShare:   burnShare,
+		changed:     make(map[string]bool),
+		logger:      logger,
+	}
+}
+
+// SeedPool opens a pool funded by funder and credits the LP shares to owner,
+// locked for lockFor from block time now. The token factory uses it to turn the
+// liquidity share of the creation fee into an OC$ pool for every new token.
+func (k *Keeper) SeedPool(funder, owner, denomA string, amountA uint64, denomB string, amountB uint64, lockFor time.Duration, now time.Time) (uint64, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	pool, shares, err := k.createPool(funder, owner, denomA, amountA, denomB, amountB, now)
+	if err != nil {
+		return 0, err
+	}
+
+	if lockFor > 0 {
+		k.locks = append(k.locks, &Lock{
+			PoolID:   pool.ID,
+			Owner:    owner,
+			Shares:   shares,
+			UnlockAt: now.Add(lockFor),
+		})
+		k.changed[locksKey] = true
+	}
+
+	k.logger.Info("Pool seeded",
+		zap.Uint64("pool_id", pool.ID),
+		zap.String("owner", owner),
+		zap.Uint64("shares", shares),
+		zap.Duration("lock", lockFor),
+	)
+	return pool.ID, nil
+}
+
+// GetPool returns a pool by ID
+func (k *Keeper) GetPool(id uint64) (*Pool, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	pool, ok := k.pools[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrPoolNotFound, id)
+	}
+	copied := *pool
+	return &copied, nil
+}
+
+// GetPools returns every pool ordered by ID
+func (k *Keeper) GetPools() []*Pool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	pools := make([]*Pool, 0, len(k.pools))
+	for _, pool := range k.pools {
+		copied := *pool
+		pools = append(pools, &copied)
+	}
+	sort.Slice(pools, func(i, j int) bool { return pools[i].ID < pools[j].ID })
+	return pools
+}
+
+// GetShares returns the LP shares an owner holds in a pool
+func (k *Keeper) GetShares(poolID uint64, owner string) uint64 {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.shares[poolID][owner]
+}
+
+// LockedShares returns the LP shares of an owner that are still locked
+func (k *Keeper) LockedShares(poolID uint64, owner string, now time.Time) uint64 {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.lockedShares(poolID, owner, now)
+}
+
+// GetLocks returns the LP locks that have not expired yet
+func (k *Keeper) GetLocks(now time.Time) []Lock {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	locks := make([]Lock, 0)
+	for _, lock := range k.locks {
+		if now.Before(lock.UnlockAt) {
+			locks = append(locks, *lock)
+		}
+	}
+	return locks
+}
+
+// createPool moves the initial deposit into the module account and mints
+// the first shares. Callers must hold k.mu.
+func (k *Keeper) createPool(funder, owner, denomA string, amountA uint64, denomB string, amountB uint64, now time.Time) (*Pool, uint64, error) {
+	if denomA == "" || denomB == "" || denomA == denomB {
+		return nil, 0, fmt.Errorf("pool needs two different denoms")
+	}
+	if _, exists := k.pairs[pairKey(denomA, denomB)]; exists {
+		return nil, 0, fmt.Errorf("%w: %s", ErrPoolExists, pairKey(denomA, denomB))
+	}
+
+	total := initialShares(amountA, amountB)
+	if total <= MinimumLiquidity {
+		return nil, 0, fmt.Errorf("%w: initial deposit must mint more than %d shares", ErrInsufficientLiquidity, MinimumLiquidity)
+	}
+
+	moduleAddr := bank.ModuleAddress(ModuleName)
+	if err := k.bank.Send(funder, moduleAddr, denomA, amountA); err != nil {
+		return nil, 0, err
+	}
+	if err := k.bank.Send(funder, moduleAddr, denomB, amountB); err != nil {
+		// Return the first leg so a failed pool creation does not strand funds
+		return nil, 0, k.revert(moduleAddr, funder, denomA, amountA, err)
+	}
+
+	pool := &Pool{
+		ID:          k.nextID,
+		DenomA:      denomA,
+		DenomB:      denomB,
+		ReserveA:    amountA,
+		ReserveB:    amountB,
+		TotalShares: total,
+		SwapFeeBps:  k.swapFeeBps,
+		Creator:     owner,
+		CreatedAt:   now,
+	}
+	k.nextID++
+	k.pools[pool.ID] = pool
+	k.pairs[pairKey(denomA, denomB)] = pool.ID
+
+	ownerShares := total - MinimumLiquidity
+	k.shares[pool.ID] = map[string]uint64{
+		moduleAddr: MinimumLiquidity,
+		owner:      ownerShares,
+	}
+	k.changed[poolKey(pool.ID)] = true
+	k.changed[sharesKey(pool.ID, moduleAddr)] = true
+	k.changed[sharesKey(pool.ID, owner)] = true
+	k.changed[nextPoolIDKey] = true
+
+	return pool, ownerShares, nil
+}
+
+// revert moves back the first leg of a transfer whose second leg failed
+// with cause. If that fails too the funds are stuck where they are, so it
+// is logged and returned along with cause.
+func (k *Keeper) revert(from, to, denom string, amount uint64, cause error) error {
+	if err := k.bank.Send(from, to, denom, amount); err != nil {
+		k.logger.Error("Failed to revert transfer",
+			zap.String("from", from),
+			zap.String("to", to),
+			zap.String("denom", denom),
+			zap.Uint64("amount", amount),
+			zap.Error(err),
+		)
+		return errors.Join(cause, fmt.Errorf("failed to return %d%s to %s: %w", amount, denom, to, err))
+	}
+	return cause
+}
+
+// lockedShares sums unexpired locks. Callers must hold k.mu.
+func (k *Keeper) lockedShares(poolID uint64, owner string, now time.Time) uint64 {
+	var locked uint64
+	for _, lock := range k.locks {
+		if lock.PoolID == poolID && lock.Owner == owner && now.Before(lock.UnlockAt) {
+			locked += lock.Shares
+		}
+	}
+	return locked
+}
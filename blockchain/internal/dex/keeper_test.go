@@ -0,0 +1,116 @@
+package dex
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vindexchain/blockchain/internal/bank"
+)
+
+const (
+	alice = "vindex1alice000000000000000"
+	bob   = "vindex1bob00000000000000000"
+)
+
+var blockTime = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newTestKeeper returns a DEX keeper whose traders alice and bob each hold
+// 1e12 of denoms "a", "b" and "c"
+func newTestKeeper(t *testing.T, cfg *Config) (*Keeper, *bank.Keeper) {
+	t.Helper()
+	ledger := bank.NewKeeper(nil)
+	for _, trader := range []string{alice, bob} {
+		for _, denom := range []string{"a", "b", "c"} {
+			if err := ledger.Mint(trader, denom, 1e12); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	return NewKeeper(ledger, cfg), ledger
+}
+
+func uint64Ptr(v uint64) *uint64 { return &v }
+
+func TestNewKeeperFees(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        *Config
+		wantFeeBps uint64
+		wantBurned uint64 // of a 1,000,000 swap
+	}{
+		{"defaults", &Config{}, defaultSwapFeeBps, 600},
+		{"no fee", &Config{SwapFeeBps: uint64Ptr(0)}, 0, 0},
+		{"no burn", &Config{BurnShare: uint64Ptr(0)}, defaultSwapFeeBps, 0},
+		{"custom", &Config{SwapFeeBps: uint64Ptr(100), BurnShare: uint64Ptr(50)}, 100, 5000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, _ := newTestKeeper(t, tt.cfg)
+			pool, err := k.CreatePool(MsgCreatePool{Creator: alice, DenomA: "a", AmountA: 1e9, DenomB: "b", AmountB: 1e9}, blockTime)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pool.SwapFeeBps != tt.wantFeeBps {
+				t.Errorf("pool fee = %d bps; want %d", pool.SwapFeeBps, tt.wantFeeBps)
+			}
+			quote, err := k.SwapExactIn(MsgSwapExactIn{Sender: bob, Route: []uint64{pool.ID}, DenomIn: "a", AmountIn: 1e6}, blockTime)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if burned := quote.Hops[0].Burned; burned != tt.wantBurned {
+				t.Errorf("burned %d; want %d", burned, tt.wantBurned)
+			}
+		})
+	}
+}
+
+func TestCreatePoolRevertsFirstLeg(t *testing.T) {
+	errBlocked := errors.New("blocked")
+	tests := []struct {
+		name       string
+		block      func(from, to, denom string) bool
+		wantRefund bool
+	}{
+		{
+			name:       "second leg fails",
+			block:      func(from, to, denom string) bool { return denom == "b" },
+			wantRefund: true,
+		},
+		{
+			name: "refund fails too",
+			block: func(from, to, denom string) bool {
+				return denom == "b" || from == bank.ModuleAddress(ModuleName)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, ledger := newTestKeeper(t, nil)
+			ledger.AddSendRestriction(func(from, to, denom string) error {
+				if tt.block(from, to, denom) {
+					return errBlocked
+				}
+				return nil
+			})
+
+			_, err := k.CreatePool(MsgCreatePool{Creator: alice, DenomA: "a", AmountA: 1e9, DenomB: "b", AmountB: 1e9}, blockTime)
+			if !errors.Is(err, errBlocked) {
+				t.Fatalf("CreatePool = %v; want the blocked transfer", err)
+			}
+			if pools := k.GetPools(); len(pools) != 0 {
+				t.Errorf("a failed creation left %d pools", len(pools))
+			}
+			refunded := ledger.GetBalance(alice, "a") == 1e12
+			if refunded != tt.wantRefund {
+				t.Errorf("alice holds %d a; refunded = %v, want %v", ledger.GetBalance(alice, "a"), refunded, tt.wantRefund)
+			}
+			if !tt.wantRefund && err.Error() == errBlocked.Error() {
+				t.Error("the failed refund is missing from the error")
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package dex
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/bank"
+)
+
+// LiquidityResult reports the amounts moved by a liquidity change
+type LiquidityResult struct {
+	PoolID  uint64 `json:"pool_id"`
+	AmountA uint64 `json:"amount_a"`
+	AmountB uint64 `json:"amount_b"`
+	Shares  uint64 `json:"shares"`
+}
+
+// CreatePool opens a new pool funded by the creator at block time now
+func (k *Keeper) CreatePool(msg MsgCreatePool, now time.Time) (*Pool, error) {
+	if err := msg.ValidateBasic(); err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	pool, shares, err := k.createPool(msg.Creator, msg.Creator, msg.DenomA, msg.AmountA, msg.DenomB, msg.AmountB, now)
+	if err != nil {
+		return nil, err
+	}
+
+	k.logger.Info("Pool created",
+		zap.Uint64("pool_id", pool.ID),
+		zap.String("pair", pairKey(pool.DenomA, pool.DenomB)),
+		zap.String("creator", msg.Creator),
+		zap.Uint64("shares", shares),
+	)
+
+	copied := *pool
+	return &copied, nil
+}
+
+// AddLiquidity deposits both denoms at the current pool ratio. The side that
+// would exceed its maximum is scaled down, so at most one maximum is used in full.
+func (k *Keeper) AddLiquidity(msg MsgAddLiquidity) (*LiquidityResult, error) {
+	if err := msg.ValidateBasic(); err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	pool, ok := k.pools[msg.PoolID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrPoolNotFound, msg.PoolID)
+	}
+
+	amountA, amountB := msg.MaxAmountA, mulDivUp(msg.MaxAmountA, pool.ReserveB, pool.ReserveA)
+	if amountB > msg.MaxAmountB {
+		amountA, amountB = mulDivUp(msg.MaxAmountB, pool.ReserveA, pool.ReserveB), msg.MaxAmountB
+		if amountA > msg.MaxAmountA {
+			amountA = msg.MaxAmountA
+		}
+	}
+
+	shares := mulDiv(amountA, pool.TotalShares, pool.ReserveA)
+	if sharesB := mulDiv(amountB, pool.TotalShares, pool.ReserveB); sharesB < shares {
+		shares = sharesB
+	}
+	if shares == 0 {
+		return nil, fmt.Errorf("%w: deposit too small to mint shares", ErrInsufficientLiquidity)
+	}
+	if shares < msg.MinShares {
+		return nil, fmt.Errorf("%w: would mint %d shares, minimum is %d", ErrSlippage, shares, msg.MinShares)
+	}
+
+	moduleAddr := bank.ModuleAddress(ModuleName)
+	if err := k.bank.Send(msg.Sender, moduleAddr, pool.DenomA, amountA); err != nil {
+		return nil, err
+	}
+	if err := k.bank.Send(msg.Sender, moduleAddr, pool.DenomB, amountB); err != nil {
+		return nil, k.revert(moduleAddr, msg.Sender, pool.DenomA, amountA, err)
+	}
+
+	pool.ReserveA += amountA
+	pool.ReserveB += amountB
+	pool.TotalShares += shares
+	k.shares[pool.ID][msg.Sender] += shares
+	k.changed[poolKey(pool.ID)] = true
+	k.changed[sharesKey(pool.ID, msg.Sender)] = true
+
+	k.logger.Info("Liquidity added",
+		zap.Uint64("pool_id", pool.ID),
+		zap.String("sender", msg.Sender),
+		zap.Uint64("amount_a", amountA),
+		zap.Uint64("amount_b", amountB),
+		zap.Uint64("shares", shares),
+	)
+
+	return &LiquidityResult{PoolID: pool.ID, AmountA: amountA, AmountB: amountB, Shares: shares}, nil
+}
+
+// RemoveLiquidity burns LP shares that are unlocked at block time now and
+// returns the proportional reserves
+func (k *Keeper) RemoveLiquidity(msg MsgRemoveLiquidity, now time.Time) (*LiquidityResult, error) {
+	if err := msg.ValidateBasic(); err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	pool, ok := k.pools[msg.PoolID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrPoolNotFound, msg.PoolID)
+	}
+
+	owned := k.shares[pool.ID][msg.Sender]
+	locked := k.lockedShares(pool.ID, msg.Sender, now)
+	if locked > owned {
+		locked = owned
+	}
+	if msg.Shares > owned-locked {
+		if msg.Shares <= owned {
+			return nil, fmt.Errorf("%w: %d of %d shares are locked", ErrSharesLocked, locked, owned)
+		}
+		return nil, fmt.Errorf("%w: owns %d shares, tried to remove %d", bank.ErrInsufficientFunds, owned, msg.Shares)
+	}
+
+	amountA := mulDiv(msg.Shares, pool.ReserveA, pool.TotalShares)
+	amountB := mulDiv(msg.Shares, pool.ReserveB, pool.TotalShares)
+	if amountA == 0 || amountB == 0 {
+		return nil, fmt.Errorf("%w: withdrawal too small", ErrInsufficientLiquidity)
+	}
+	if amountA < msg.MinAmountA || amountB < msg.MinAmountB {
+		return nil, fmt.Errorf("%w: would return %d/%d, minimum is %d/%d", ErrSlippage, amountA, amountB, msg.MinAmountA, msg.MinAmountB)
+	}
+
+	moduleAddr := bank.ModuleAddress(ModuleName)
+	if err := k.bank.Send(moduleAddr, msg.Sender, pool.DenomA, amountA); err != nil {
+		return nil, err
+	}
+	if err := k.bank.Send(moduleAddr, msg.Sender, pool.DenomB, amountB); err != nil {
+		return nil, k.revert(msg.Sender, moduleAddr, pool.DenomA, amountA, err)
+	}
+
+	pool.ReserveA -= amountA
+	pool.ReserveB -= amountB
+	pool.TotalShares -= msg.Shares
+	k.shares[pool.ID][msg.Sender] -= msg.Shares
+	if k.shares[pool.ID][msg.Sender] == 0 {
+		delete(k.shares[pool.ID], msg.Sender)
+	}
+	k.changed[poolKey(pool.ID)] = true
+	k.changed[sharesKey(pool.ID, msg.Sender)] = true
+
+	k.logger.Info("Liquidity removed",
+		zap.Uint64("pool_id", pool.ID),
+		zap.String("sender", msg.Sender),
+		zap.Uint64("amount_a", amountA),
+		zap.Uint64("amount_b", amountB),
+		zap.Uint64("shares", msg.Shares),
+	)
+
+	return &LiquidityResult{PoolID: pool.ID, AmountA: amountA, AmountB: amountB, Shares: msg.Shares}, nil
+}
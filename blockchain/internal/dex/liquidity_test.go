@@ -0,0 +1,156 @@
+package dex
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vindexchain/blockchain/internal/bank"
+)
+
+// 3e9 "a" and 7e9 "b" mint floor(sqrt(21e18)) shares
+const (
+	testReserveA = 3e9
+	testReserveB = 7e9
+	testShares   = 4582575694
+)
+
+func newTestPool(t *testing.T) (*Keeper, *bank.Keeper, uint64) {
+	t.Helper()
+	k, ledger := newTestKeeper(t, nil)
+	pool, err := k.CreatePool(MsgCreatePool{Creator: alice, DenomA: "a", AmountA: testReserveA, DenomB: "b", AmountB: testReserveB}, blockTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pool.TotalShares != testShares || k.GetShares(pool.ID, alice) != testShares-MinimumLiquidity {
+		t.Fatalf("pool minted %d shares, %d to alice", pool.TotalShares, k.GetShares(pool.ID, alice))
+	}
+	return k, ledger, pool.ID
+}
+
+func TestAddLiquidity(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxA, maxB uint64
+		minShares  uint64
+		wantA      uint64
+		wantB      uint64
+		wantShares uint64
+		wantErr    error
+	}{
+		{name: "a limits", maxA: 1e6, maxB: 1e7, wantA: 1e6, wantB: 2333334, wantShares: 1527525},
+		{name: "b limits", maxA: 1e6, maxB: 1e6, wantA: 428572, wantB: 1e6, wantShares: 654653},
+		{name: "dust", maxA: 10, maxB: 1e6, wantA: 10, wantB: 24, wantShares: 15},
+		{name: "no shares", maxA: 1, maxB: 1, wantErr: ErrInsufficientLiquidity},
+		{name: "slippage", maxA: 1e6, maxB: 1e7, minShares: 1527526, wantErr: ErrSlippage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, ledger, id := newTestPool(t)
+			result, err := k.AddLiquidity(MsgAddLiquidity{Sender: bob, PoolID: id, MaxAmountA: tt.maxA, MaxAmountB: tt.maxB, MinShares: tt.minShares})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AddLiquidity = %v; want %v", err, tt.wantErr)
+			}
+			pool, _ := k.GetPool(id)
+			if tt.wantErr != nil {
+				if pool.ReserveA != testReserveA || pool.ReserveB != testReserveB || ledger.GetBalance(bob, "a") != 1e12 {
+					t.Error("a refused deposit moved funds")
+				}
+				return
+			}
+
+			if result.AmountA != tt.wantA || result.AmountB != tt.wantB || result.Shares != tt.wantShares {
+				t.Errorf("deposited %d/%d for %d shares; want %d/%d for %d", result.AmountA, result.AmountB, result.Shares, tt.wantA, tt.wantB, tt.wantShares)
+			}
+			if got := k.GetShares(id, bob); got != tt.wantShares {
+				t.Errorf("bob owns %d shares; want %d", got, tt.wantShares)
+			}
+			if a, b := ledger.GetBalance(bob, "a"), ledger.GetBalance(bob, "b"); a != 1e12-tt.wantA || b != 1e12-tt.wantB {
+				t.Errorf("bob holds %d a, %d b", a, b)
+			}
+			if pool.ReserveA != testReserveA+tt.wantA || pool.ReserveB != testReserveB+tt.wantB || pool.TotalShares != testShares+tt.wantShares {
+				t.Errorf("pool = %d/%d with %d shares", pool.ReserveA, pool.ReserveB, pool.TotalShares)
+			}
+		})
+	}
+}
+
+func TestRemoveLiquidity(t *testing.T) {
+	tests := []struct {
+		name       string
+		shares     uint64
+		minA, minB uint64
+		wantA      uint64
+		wantB      uint64
+		wantErr    error
+	}{
+		{name: "half", shares: testShares / 2, wantA: 15e8, wantB: 35e8},
+		{name: "rounds down", shares: 1e6, wantA: 654653, wantB: 1527525},
+		{name: "smallest", shares: 2, wantA: 1, wantB: 3},
+		{name: "nothing of a", shares: 1, wantErr: ErrInsufficientLiquidity},
+		{name: "slippage", shares: 1e6, minA: 654654, wantErr: ErrSlippage},
+		{name: "more than owned", shares: testShares, wantErr: bank.ErrInsufficientFunds},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, ledger, id := newTestPool(t)
+			result, err := k.RemoveLiquidity(MsgRemoveLiquidity{Sender: alice, PoolID: id, Shares: tt.shares, MinAmountA: tt.minA, MinAmountB: tt.minB}, blockTime)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RemoveLiquidity = %v; want %v", err, tt.wantErr)
+			}
+			pool, _ := k.GetPool(id)
+			if tt.wantErr != nil {
+				if pool.TotalShares != testShares || k.GetShares(id, alice) != testShares-MinimumLiquidity {
+					t.Error("a refused withdrawal burned shares")
+				}
+				return
+			}
+
+			if result.AmountA != tt.wantA || result.AmountB != tt.wantB {
+				t.Errorf("withdrew %d/%d; want %d/%d", result.AmountA, result.AmountB, tt.wantA, tt.wantB)
+			}
+			if got := k.GetShares(id, alice); got != testShares-MinimumLiquidity-tt.shares {
+				t.Errorf("alice owns %d shares", got)
+			}
+			if a, b := ledger.GetBalance(alice, "a"), ledger.GetBalance(alice, "b"); a != 1e12-testReserveA+tt.wantA || b != 1e12-testReserveB+tt.wantB {
+				t.Errorf("alice holds %d a, %d b", a, b)
+			}
+			moduleAddr := bank.ModuleAddress(ModuleName)
+			if pool.ReserveA != ledger.GetBalance(moduleAddr, "a") || pool.ReserveB != ledger.GetBalance(moduleAddr, "b") {
+				t.Errorf("reserves %d/%d differ from the module balance", pool.ReserveA, pool.ReserveB)
+			}
+		})
+	}
+}
+
+func TestRemoveLockedLiquidity(t *testing.T) {
+	k, ledger := newTestKeeper(t, nil)
+	if err := ledger.Mint(bank.ModuleAddress("tokens"), "a", 1e9); err != nil {
+		t.Fatal(err)
+	}
+	if err := ledger.Mint(bank.ModuleAddress("tokens"), "b", 1e9); err != nil {
+		t.Fatal(err)
+	}
+	id, err := k.SeedPool(bank.ModuleAddress("tokens"), alice, "a", 1e9, "b", 1e9, time.Hour, blockTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	owned := k.GetShares(id, alice)
+
+	tests := []struct {
+		name    string
+		at      time.Time
+		wantErr error
+	}{
+		{"while locked", blockTime.Add(time.Hour - time.Second), ErrSharesLocked},
+		{"once unlocked", blockTime.Add(time.Hour), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := k.RemoveLiquidity(MsgRemoveLiquidity{Sender: alice, PoolID: id, Shares: owned}, tt.at)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("RemoveLiquidity = %v; want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package dex
+
+import (
+	"fmt"
+
+	"github.com/vindexchain/blockchain/internal/bank"
+)
+
+// MsgCreatePool opens a new pool with an initial deposit of both denoms
+type MsgCreatePool struct {
+	Creator string `json:"creator"`
+	DenomA  string `json:"denom_a"`
+	AmountA uint64 `json:"amount_a"`
+	DenomB  string `json:"denom_b"`
+	AmountB uint64 `json:"amount_b"`
+}
+
+// MsgAddLiquidity deposits up to MaxAmountA/MaxAmountB at the current pool ratio
+type MsgAddLiquidity struct {
+	Sender     string `json:"sender"`
+	PoolID     uint64 `json:"pool_id"`
+	MaxAmountA uint64 `json:"max_amount_a"`
+	MaxAmountB uint64 `json:"max_amount_b"`
+	MinShares  uint64 `json:"min_shares"`
+}
+
+// MsgRemoveLiquidity burns LP shares for the underlying reserves
+type MsgRemoveLiquidity struct {
+	Sender     string `json:"sender"`
+	PoolID     uint64 `json:"pool_id"`
+	Shares     uint64 `json:"shares"`
+	MinAmountA uint64 `json:"min_amount_a"`
+	MinAmountB uint64 `json:"min_amount_b"`
+}
+
+// MsgSwapExactIn sells exactly AmountIn of DenomIn along Route
+type MsgSwapExactIn struct {
+	Sender       string   `json:"sender"`
+	Route        []uint64 `json:"route"` // pool IDs, in order
+	DenomIn      string   `json:"denom_in"`
+	AmountIn     uint64   `json:"amount_in"`
+	MinAmountOut uint64   `json:"min_amount_out"`
+}
+
+// MsgSwapExactOut buys exactly AmountOut along Route, spending at most MaxAmountIn
+type MsgSwapExactOut struct {
+	Sender      string   `json:"sender"`
+	Route       []uint64 `json:"route"` // pool IDs, in order
+	DenomIn     string   `json:"denom_in"`
+	MaxAmountIn uint64   `json:"max_amount_in"`
+	AmountOut   uint64   `json:"amount_out"`
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgCreatePool) ValidateBasic() error {
+	if err := bank.ValidateAddress(m.Creator); err != nil {
+		return fmt.Errorf("creator: %w", err)
+	}
+	if m.DenomA == "" || m.DenomB == "" || m.DenomA == m.DenomB {
+		return fmt.Errorf("pool needs two different denoms")
+	}
+	if m.AmountA == 0 || m.AmountB == 0 {
+		return bank.ErrInvalidAmount
+	}
+	return nil
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgAddLiquidity) ValidateBasic() error {
+	if err := bank.ValidateAddress(m.Sender); err != nil {
+		return fmt.Errorf("sender: %w", err)
+	}
+	if m.MaxAmountA == 0 || m.MaxAmountB == 0 {
+		return bank.ErrInvalidAmount
+	}
+	return nil
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgRemoveLiquidity) ValidateBasic() error {
+	if err := bank.ValidateAddress(m.Sender); err != nil {
+		return fmt.Errorf("sender: %w", err)
+	}
+	if m.Shares == 0 {
+		return bank.ErrInvalidAmount
+	}
+	return nil
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgSwapExactIn) ValidateBasic() error {
+	if err := bank.ValidateAddress(m.Sender); err != nil {
+		return fmt.Errorf("sender: %w", err)
+	}
+	if err := validateRoute(m.Route); err != nil {
+		return err
+	}
+	if m.DenomIn == "" {
+		return fmt.Errorf("denom in cannot be empty")
+	}
+	if m.AmountIn == 0 {
+		return bank.ErrInvalidAmount
+	}
+	return nil
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgSwapExactOut) ValidateBasic() error {
+	if err := bank.ValidateAddress(m.Sender); err != nil {
+		return fmt.Errorf("sender: %w", err)
+	}
+	if err := validateRoute(m.Route); err != nil {
+		return err
+	}
+	if m.DenomIn == "" {
+		return fmt.Errorf("denom in cannot be empty")
+	}
+	if m.AmountOut == 0 || m.MaxAmountIn == 0 {
+		return bank.ErrInvalidAmount
+	}
+	return nil
+}
+
+func validateRoute(route []uint64) error {
+	if len(route) == 0 {
+		return fmt.Errorf("%w: route cannot be empty", ErrInvalidRoute)
+	}
+	seen := make(map[uint64]bool, len(route))
+	for _, id := range route {
+		if seen[id] {
+			return fmt.Errorf("%w: pool %d appears twice", ErrInvalidRoute, id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// Type implements accounts.Msg
+func (m MsgCreatePool) Type() string { return "dex/create-pool" }
+
+// Signer implements accounts.Msg
+func (m MsgCreatePool) Signer() string { return m.Creator }
+
+// Type implements accounts.Msg
+func (m MsgAddLiquidity) Type() string { return "dex/add-liquidity" }
+
+// Signer implements accounts.Msg
+func (m MsgAddLiquidity) Signer() string { return m.Sender }
+
+// Type implements accounts.Msg
+func (m MsgRemoveLiquidity) Type() string { return "dex/remove-liquidity" }
+
+// Signer implements accounts.Msg
+func (m MsgRemoveLiquidity) Signer() string { return m.Sender }
+
+// Type implements accounts.Msg
+func (m MsgSwapExactIn) Type() string { return "dex/swap-exact-in" }
+
+// Signer implements accounts.Msg
+func (m MsgSwapExactIn) Signer() string { return m.Sender }
+
+// Type implements accounts.Msg
+func (m MsgSwapExactOut) Type() string { return "dex/swap-exact-out" }
+
+// Signer implements accounts.Msg
+func (m MsgSwapExactOut) Signer() string { return m.Sender }
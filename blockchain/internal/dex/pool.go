@@ -0,0 +1,195 @@
+package dex
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// MinimumLiquidity is the amount of shares permanently locked in every pool
+// on creation so the share price can never be inflated from zero
+const MinimumLiquidity uint64 = 1000
+
+// Pool is a constant-product (x*y=k) liquidity pool
+type Pool struct {
+	ID          uint64    `json:"id"`
+	DenomA      string    `json:"denom_a"`
+	DenomB      string    `json:"denom_b"`
+	ReserveA    uint64    `json:"reserve_a"`
+	ReserveB    uint64    `json:"reserve_b"`
+	TotalShares uint64    `json:"total_shares"`
+	SwapFeeBps  uint64    `json:"swap_fee_bps"`
+	Creator     string    `json:"creator"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Lock keeps LP shares from being withdrawn until UnlockAt
+type Lock struct {
+	PoolID   uint64    `json:"pool_id"`
+	Owner    string    `json:"owner"`
+	Shares   uint64    `json:"shares"`
+	UnlockAt time.Time `json:"unlock_at"`
+}
+
+// Reserve returns the reserve held for a denom
+func (p *Pool) Reserve(denom string) uint64 {
+	if denom == p.DenomA {
+		return p.ReserveA
+	}
+	if denom == p.DenomB {
+		return p.ReserveB
+	}
+	return 0
+}
+
+// HasDenom reports whether the pool trades a denom
+func (p *Pool) HasDenom(denom string) bool {
+	return denom == p.DenomA || denom == p.DenomB
+}
+
+// initialShares returns sqrt(amountA * amountB), the share supply minted
+// when a pool is first funded
+func initialShares(amountA, amountB uint64) uint64 {
+	product := new(big.Int).Mul(new(big.Int).SetUint64(amountA), new(big.Int).SetUint64(amountB))
+	return product.Sqrt(product).Uint64()
+}
+
+// pairKey identifies a pool by its unordered denom pair
+func pairKey(denomA, denomB string) string {
+	if denomA > denomB {
+		denomA, denomB = denomB, denomA
+	}
+	return denomA + "/" + denomB
+}
+
+// SpotPrice returns the price of DenomA in units of DenomB
+func (p *Pool) SpotPrice() float64 {
+	if p.ReserveA == 0 {
+		return 0
+	}
+	return float64(p.ReserveB) / float64(p.ReserveA)
+}
+
+// otherDenom returns the denom on the opposite side of the pool
+func (p *Pool) otherDenom(denom string) string {
+	if denom == p.DenomA {
+		return p.DenomB
+	}
+	return p.DenomA
+}
+
+// swapResult is the outcome of a single hop
+type swapResult struct {
+	amountIn  uint64
+	amountOut uint64
+	fee       uint64 // total fee charged on amountIn
+	burned    uint64 // part of fee that is burned
+}
+
+// quoteExactIn prices selling amountIn of denomIn into the pool
+func (p *Pool) quoteExactIn(denomIn string, amountIn uint64, burnShare uint64) (swapResult, error) {
+	if !p.HasDenom(denomIn) {
+		return swapResult{}, fmt.Errorf("%w: pool %d does not trade %s", ErrInvalidRoute, p.ID, denomIn)
+	}
+	reserveIn, reserveOut := p.Reserve(denomIn), p.Reserve(p.otherDenom(denomIn))
+	if reserveIn == 0 || reserveOut == 0 {
+		return swapResult{}, fmt.Errorf("%w: pool %d is empty", ErrInsufficientLiquidity, p.ID)
+	}
+
+	fee := mulDivUp(amountIn, p.SwapFeeBps, 10000)
+	if fee >= amountIn {
+		return swapResult{}, fmt.Errorf("%w: amount %d is too small to cover the swap fee", ErrInsufficientLiquidity, amountIn)
+	}
+	net := amountIn - fee
+
+	amountOut := mulDiv(reserveOut, net, addSaturating(reserveIn, net))
+	if amountOut == 0 || amountOut >= reserveOut {
+		return swapResult{}, fmt.Errorf("%w: pool %d cannot fill %d%s", ErrInsufficientLiquidity, p.ID, amountIn, denomIn)
+	}
+
+	return swapResult{
+		amountIn:  amountIn,
+		amountOut: amountOut,
+		fee:       fee,
+		burned:    fee * burnShare / 100,
+	}, nil
+}
+
+// quoteExactOut prices buying exactly amountOut of the denom opposite denomIn
+func (p *Pool) quoteExactOut(denomIn string, amountOut uint64, burnShare uint64) (swapResult, error) {
+	if !p.HasDenom(denomIn) {
+		return swapResult{}, fmt.Errorf("%w: pool %d does not trade %s", ErrInvalidRoute, p.ID, denomIn)
+	}
+	reserveIn, reserveOut := p.Reserve(denomIn), p.Reserve(p.otherDenom(denomIn))
+	if amountOut == 0 || amountOut >= reserveOut {
+		return swapResult{}, fmt.Errorf("%w: pool %d cannot provide %d", ErrInsufficientLiquidity, p.ID, amountOut)
+	}
+
+	// net = ceil(reserveIn * amountOut / (reserveOut - amountOut))
+	net := mulDivUp(reserveIn, amountOut, reserveOut-amountOut)
+	// amountIn = ceil(net * 10000 / (10000 - fee))
+	amountIn := mulDivUp(net, 10000, 10000-p.SwapFeeBps)
+
+	// Re-price forwards so rounding can only ever favour the pool
+	result, err := p.quoteExactIn(denomIn, amountIn, burnShare)
+	if err != nil {
+		return swapResult{}, err
+	}
+	for result.amountOut < amountOut {
+		amountIn++
+		if result, err = p.quoteExactIn(denomIn, amountIn, burnShare); err != nil {
+			return swapResult{}, err
+		}
+	}
+	result.amountOut = amountOut
+	return result, nil
+}
+
+// apply updates the reserves after a hop; the burned part of the fee leaves
+// the pool while the rest stays in as LP revenue
+func (p *Pool) apply(denomIn string, result swapResult) {
+	if denomIn == p.DenomA {
+		p.ReserveA += result.amountIn - result.burned
+		p.ReserveB -= result.amountOut
+	} else {
+		p.ReserveB += result.amountIn - result.burned
+		p.ReserveA -= result.amountOut
+	}
+}
+
+// mulDiv returns floor(a*b/c) without intermediate overflow
+func mulDiv(a, b, c uint64) uint64 {
+	if c == 0 {
+		return 0
+	}
+	result := new(big.Int).Mul(new(big.Int).SetUint64(a), new(big.Int).SetUint64(b))
+	result.Quo(result, new(big.Int).SetUint64(c))
+	if !result.IsUint64() {
+		return ^uint64(0)
+	}
+	return result.Uint64()
+}
+
+// mulDivUp returns ceil(a*b/c) without intermediate overflow
+func mulDivUp(a, b, c uint64) uint64 {
+	if c == 0 {
+		return 0
+	}
+	product := new(big.Int).Mul(new(big.Int).SetUint64(a), new(big.Int).SetUint64(b))
+	divisor := new(big.Int).SetUint64(c)
+	quotient, remainder := new(big.Int).QuoRem(product, divisor, new(big.Int))
+	if remainder.Sign() > 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	if !quotient.IsUint64() {
+		return ^uint64(0)
+	}
+	return quotient.Uint64()
+}
+
+func addSaturating(a, b uint64) uint64 {
+	if a > ^uint64(0)-b {
+		return ^uint64(0)
+	}
+	return a + b
+}
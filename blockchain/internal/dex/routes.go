@@ -0,0 +1,74 @@
+package dex
+
+import (
+	"github.com/vindexchain/blockchain/internal/app"
+)
+
+// RegisterRoutes routes the pool and swap messages of blocks to the keeper
+func RegisterRoutes(r *app.Router, k *Keeper) {
+	app.Handle(r, func(ctx *app.Context, msg MsgCreatePool) error {
+		pool, err := k.CreatePool(msg, ctx.Time)
+		if err != nil {
+			return err
+		}
+		ctx.EmitEvent("create_pool",
+			app.Attribute("pool_id", pool.ID),
+			app.Attribute("pair", pairKey(pool.DenomA, pool.DenomB)),
+		)
+		return nil
+	})
+	app.Handle(r, func(ctx *app.Context, msg MsgAddLiquidity) error {
+		result, err := k.AddLiquidity(msg)
+		if err != nil {
+			return err
+		}
+		emitLiquidity(ctx, "add_liquidity", result)
+		return nil
+	})
+	app.Handle(r, func(ctx *app.Context, msg MsgRemoveLiquidity) error {
+		result, err := k.RemoveLiquidity(msg, ctx.Time)
+		if err != nil {
+			return err
+		}
+		emitLiquidity(ctx, "remove_liquidity", result)
+		return nil
+	})
+	app.Handle(r, func(ctx *app.Context, msg MsgSwapExactIn) error {
+		quote, err := k.SwapExactIn(msg, ctx.Time)
+		if err != nil {
+			return err
+		}
+		emitSwap(ctx, quote)
+		return nil
+	})
+	app.Handle(r, func(ctx *app.Context, msg MsgSwapExactOut) error {
+		quote, err := k.SwapExactOut(msg, ctx.Time)
+		if err != nil {
+			return err
+		}
+		emitSwap(ctx, quote)
+		return nil
+	})
+}
+
+func emitLiquidity(ctx *app.Context, eventType string, result *LiquidityResult) {
+	ctx.EmitEvent(eventType,
+		app.Attribute("pool_id", result.PoolID),
+		app.Attribute("amount_a", result.AmountA),
+		app.Attribute("amount_b", result.AmountB),
+		app.Attribute("shares", result.Shares),
+	)
+}
+
+func emitSwap(ctx *app.Context, quote *Quote) {
+	for _, hop := range quote.Hops {
+		ctx.EmitEvent("swap",
+			app.Attribute("pool_id", hop.PoolID),
+			app.Attribute("denom_in", hop.DenomIn),
+			app.Attribute("amount_in", hop.AmountIn),
+			app.Attribute("denom_out", hop.DenomOut),
+			app.Attribute("amount_out", hop.AmountOut),
+			app.Attribute("burned", hop.Burned),
+		)
+	}
+}
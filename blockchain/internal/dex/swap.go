@@ -0,0 +1,373 @@
+package dex
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/bank"
+)
+
+const (
+	// TradeBuy is a trade that buys the pool's DenomA
+	TradeBuy = "buy"
+	// TradeSell is a trade that sells the pool's DenomA
+	TradeSell = "sell"
+)
+
+// TradeListener is notified of every executed trade. Listeners run while the
+// keeper is locked and must not block or call back into it.
+type TradeListener func(trade Trade)
+
+// Trade is a single executed hop of a swap
+type Trade struct {
+	ID        uint64    `json:"id"`
+	PoolID    uint64    `json:"pool_id"`
+	Type      string    `json:"type"` // TradeBuy or TradeSell
+	Trader    string    `json:"trader"`
+	DenomIn   string    `json:"denom_in"`
+	DenomOut  string    `json:"denom_out"`
+	AmountIn  uint64    `json:"amount_in"`
+	AmountOut uint64    `json:"amount_out"`
+	Fee       uint64    `json:"fee"`    // charged in DenomIn
+	Burned    uint64    `json:"burned"` // part of Fee that was burned
+	Price     float64   `json:"price"`  // execution price of the pool's DenomA in DenomB
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Hop is one leg of a quoted or executed route
+type Hop struct {
+	PoolID    uint64 `json:"pool_id"`
+	DenomIn   string `json:"denom_in"`
+	DenomOut  string `json:"denom_out"`
+	AmountIn  uint64 `json:"amount_in"`
+	AmountOut uint64 `json:"amount_out"`
+	Fee       uint64 `json:"fee"`
+	Burned    uint64 `json:"burned"`
+}
+
+// Quote is the simulated outcome of a swap along a route
+type Quote struct {
+	Route     []uint64 `json:"route"`
+	DenomIn   string   `json:"denom_in"`
+	DenomOut  string   `json:"denom_out"`
+	AmountIn  uint64   `json:"amount_in"`
+	AmountOut uint64   `json:"amount_out"`
+	Hops      []Hop    `json:"hops"`
+}
+
+// SwapExactIn sells exactly AmountIn along the route, recording the trades
+// at block time now
+func (k *Keeper) SwapExactIn(msg MsgSwapExactIn, now time.Time) (*Quote, error) {
+	if err := msg.ValidateBasic(); err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	quote, err := k.simulateExactIn(msg.Route, msg.DenomIn, msg.AmountIn)
+	if err != nil {
+		return nil, err
+	}
+	if quote.AmountOut < msg.MinAmountOut {
+		return nil, fmt.Errorf("%w: would receive %d%s, minimum is %d", ErrSlippage, quote.AmountOut, quote.DenomOut, msg.MinAmountOut)
+	}
+
+	if err := k.execute(msg.Sender, quote, now); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+// SwapExactOut buys exactly AmountOut along the route, recording the trades
+// at block time now
+func (k *Keeper) SwapExactOut(msg MsgSwapExactOut, now time.Time) (*Quote, error) {
+	if err := msg.ValidateBasic(); err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	quote, err := k.simulateExactOut(msg.Route, msg.DenomIn, msg.AmountOut)
+	if err != nil {
+		return nil, err
+	}
+	if quote.AmountIn > msg.MaxAmountIn {
+		return nil, fmt.Errorf("%w: would spend %d%s, maximum is %d", ErrSlippage, quote.AmountIn, quote.DenomIn, msg.MaxAmountIn)
+	}
+
+	if err := k.execute(msg.Sender, quote, now); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+// QuoteExactIn finds the route with the best output for selling amountIn
+func (k *Keeper) QuoteExactIn(denomIn, denomOut string, amountIn uint64) (*Quote, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	var best *Quote
+	for _, route := range k.findRoutes(denomIn, denomOut) {
+		quote, err := k.simulateExactIn(route, denomIn, amountIn)
+		if err != nil {
+			continue
+		}
+		if best == nil || quote.AmountOut > best.AmountOut {
+			best = quote
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("%w: no route from %s to %s", ErrInvalidRoute, denomIn, denomOut)
+	}
+	return best, nil
+}
+
+// QuoteExactOut finds the cheapest route for buying amountOut
+func (k *Keeper) QuoteExactOut(denomIn, denomOut string, amountOut uint64) (*Quote, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	var best *Quote
+	for _, route := range k.findRoutes(denomIn, denomOut) {
+		quote, err := k.simulateExactOut(route, denomIn, amountOut)
+		if err != nil {
+			continue
+		}
+		if best == nil || quote.AmountIn < best.AmountIn {
+			best = quote
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("%w: no route from %s to %s", ErrInvalidRoute, denomIn, denomOut)
+	}
+	return best, nil
+}
+
+// AddTradeListener registers a listener for executed trades
+func (k *Keeper) AddTradeListener(listener TradeListener) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.listeners = append(k.listeners, listener)
+}
+
+// GetTrades returns recent trades, newest first. A zero poolID matches every pool.
+func (k *Keeper) GetTrades(poolID uint64, limit int) []Trade {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	trades := make([]Trade, 0)
+	for i := len(k.trades) - 1; i >= 0 && (limit <= 0 || len(trades) < limit); i-- {
+		if poolID == 0 || k.trades[i].PoolID == poolID {
+			trades = append(trades, k.trades[i])
+		}
+	}
+	return trades
+}
+
+// simulateExactIn prices a route forwards. Callers must hold k.mu.
+func (k *Keeper) simulateExactIn(route []uint64, denomIn string, amountIn uint64) (*Quote, error) {
+	pools, denoms, err := k.resolveRoute(route, denomIn)
+	if err != nil {
+		return nil, err
+	}
+
+	hops := make([]Hop, len(pools))
+	amount := amountIn
+	for i, pool := range pools {
+		result, err := pool.quoteExactIn(denoms[i], amount, k.burnShare)
+		if err != nil {
+			return nil, err
+		}
+		hops[i] = newHop(pool.ID, denoms[i], denoms[i+1], result)
+		amount = result.amountOut
+	}
+
+	return newQuote(route, hops), nil
+}
+
+// simulateExactOut prices a route backwards from the desired output.
+// Callers must hold k.mu.
+func (k *Keeper) simulateExactOut(route []uint64, denomIn string, amountOut uint64) (*Quote, error) {
+	pools, denoms, err := k.resolveRoute(route, denomIn)
+	if err != nil {
+		return nil, err
+	}
+
+	hops := make([]Hop, len(pools))
+	amount := amountOut
+	for i := len(pools) - 1; i >= 0; i-- {
+		result, err := pools[i].quoteExactOut(denoms[i], amount, k.burnShare)
+		if err != nil {
+			return nil, err
+		}
+		hops[i] = newHop(pools[i].ID, denoms[i], denoms[i+1], result)
+		amount = result.amountIn
+	}
+
+	return newQuote(route, hops), nil
+}
+
+// resolveRoute returns the pools of a route and the denom entering each hop,
+// plus the final output denom. Callers must hold k.mu.
+func (k *Keeper) resolveRoute(route []uint64, denomIn string) ([]*Pool, []string, error) {
+	if err := validateRoute(route); err != nil {
+		return nil, nil, err
+	}
+
+	pools := make([]*Pool, len(route))
+	denoms := make([]string, len(route)+1)
+	denoms[0] = denomIn
+	for i, id := range route {
+		pool, ok := k.pools[id]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: %d", ErrPoolNotFound, id)
+		}
+		if !pool.HasDenom(denoms[i]) {
+			return nil, nil, fmt.Errorf("%w: pool %d does not trade %s", ErrInvalidRoute, id, denoms[i])
+		}
+		pools[i] = pool
+		denoms[i+1] = pool.otherDenom(denoms[i])
+	}
+	return pools, denoms, nil
+}
+
+// execute moves funds and updates reserves for a simulated route. Intermediate
+// amounts never leave the module account, since every pool's reserves live there.
+// Callers must hold k.mu.
+func (k *Keeper) execute(trader string, quote *Quote, now time.Time) error {
+	moduleAddr := bank.ModuleAddress(ModuleName)
+	if err := k.bank.Send(trader, moduleAddr, quote.DenomIn, quote.AmountIn); err != nil {
+		return err
+	}
+	if err := k.bank.Send(moduleAddr, trader, quote.DenomOut, quote.AmountOut); err != nil {
+		return k.revert(moduleAddr, trader, quote.DenomIn, quote.AmountIn, err)
+	}
+
+	for _, hop := range quote.Hops {
+		pool := k.pools[hop.PoolID]
+		pool.apply(hop.DenomIn, swapResult{
+			amountIn:  hop.AmountIn,
+			amountOut: hop.AmountOut,
+			fee:       hop.Fee,
+			burned:    hop.Burned,
+		})
+		k.changed[poolKey(pool.ID)] = true
+		if hop.Burned > 0 {
+			if err := k.bank.Burn(moduleAddr, hop.DenomIn, hop.Burned); err != nil {
+				k.logger.Error("Failed to burn swap fee", zap.Uint64("pool_id", pool.ID), zap.Error(err))
+			}
+		}
+		k.recordTrade(trader, pool, hop, now)
+	}
+
+	k.logger.Info("Swap executed",
+		zap.String("trader", trader),
+		zap.Uint64s("route", quote.Route),
+		zap.String("denom_in", quote.DenomIn),
+		zap.Uint64("amount_in", quote.AmountIn),
+		zap.String("denom_out", quote.DenomOut),
+		zap.Uint64("amount_out", quote.AmountOut),
+	)
+	return nil
+}
+
+// recordTrade appends a trade to the in-memory history. Callers must hold k.mu.
+func (k *Keeper) recordTrade(trader string, pool *Pool, hop Hop, now time.Time) {
+	side, price := TradeSell, float64(hop.AmountOut)/float64(hop.AmountIn)
+	if hop.DenomIn != pool.DenomA {
+		side, price = TradeBuy, float64(hop.AmountIn)/float64(hop.AmountOut)
+	}
+
+	trade := Trade{
+		ID:        k.nextTradeID,
+		PoolID:    pool.ID,
+		Type:      side,
+		Trader:    trader,
+		DenomIn:   hop.DenomIn,
+		DenomOut:  hop.DenomOut,
+		AmountIn:  hop.AmountIn,
+		AmountOut: hop.AmountOut,
+		Fee:       hop.Fee,
+		Burned:    hop.Burned,
+		Price:     price,
+		Timestamp: now,
+	}
+	k.trades = append(k.trades, trade)
+	k.nextTradeID++
+	k.changed[nextTradeIDKey] = true
+
+	for _, listener := range k.listeners {
+		listener(trade)
+	}
+
+	if overflow := len(k.trades) - k.config.MaxTrades; overflow > 0 {
+		k.trades = append(k.trades[:0], k.trades[overflow:]...)
+	}
+}
+
+// findRoutes lists every simple path of at most MaxHops pools from denomIn to
+// denomOut. Callers must hold k.mu.
+func (k *Keeper) findRoutes(denomIn, denomOut string) [][]uint64 {
+	byDenom := make(map[string][]*Pool)
+	for _, pool := range k.pools {
+		byDenom[pool.DenomA] = append(byDenom[pool.DenomA], pool)
+		byDenom[pool.DenomB] = append(byDenom[pool.DenomB], pool)
+	}
+	for _, pools := range byDenom {
+		sort.Slice(pools, func(i, j int) bool { return pools[i].ID < pools[j].ID })
+	}
+
+	var routes [][]uint64
+	visited := map[string]bool{denomIn: true}
+	var walk func(denom string, path []uint64)
+	walk = func(denom string, path []uint64) {
+		if len(path) == k.config.MaxHops {
+			return
+		}
+		for _, pool := range byDenom[denom] {
+			next := pool.otherDenom(denom)
+			if visited[next] {
+				continue
+			}
+			route := append(append([]uint64(nil), path...), pool.ID)
+			if next == denomOut {
+				routes = append(routes, route)
+				continue
+			}
+			visited[next] = true
+			walk(next, route)
+			visited[next] = false
+		}
+	}
+	walk(denomIn, nil)
+
+	return routes
+}
+
+func newHop(poolID uint64, denomIn, denomOut string, result swapResult) Hop {
+	return Hop{
+		PoolID:    poolID,
+		DenomIn:   denomIn,
+		DenomOut:  denomOut,
+		AmountIn:  result.amountIn,
+		AmountOut: result.amountOut,
+		Fee:       result.fee,
+		Burned:    result.burned,
+	}
+}
+
+func newQuote(route []uint64, hops []Hop) *Quote {
+	return &Quote{
+		Route:     append([]uint64(nil), route...),
+		DenomIn:   hops[0].DenomIn,
+		DenomOut:  hops[len(hops)-1].DenomOut,
+		AmountIn:  hops[0].AmountIn,
+		AmountOut: hops[len(hops)-1].AmountOut,
+		Hops:      hops,
+	}
+}
@@ -0,0 +1,104 @@
+package dex
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/vindexchain/blockchain/internal/bank"
+)
+
+func TestSwapRounding(t *testing.T) {
+	// Every case trades "a" for "b" against a 1e9/1e9 pool with the default
+	// 0.30% fee, 20% of which is burned
+	tests := []struct {
+		name       string
+		exactOut   bool
+		amount     uint64 // in for exact-in swaps, out for exact-out swaps
+		wantIn     uint64
+		wantOut    uint64
+		wantFee    uint64
+		wantBurned uint64
+		wantErr    error
+	}{
+		{name: "exact in", amount: 1e6, wantIn: 1e6, wantOut: 996006, wantFee: 3000, wantBurned: 600},
+		{name: "fee rounds up", amount: 334, wantIn: 334, wantOut: 331, wantFee: 2},
+		{name: "half the reserve", amount: 1e9, wantIn: 1e9, wantOut: 499248873, wantFee: 3e6, wantBurned: 6e5},
+		{name: "too small for the fee", amount: 1, wantErr: ErrInsufficientLiquidity},
+		{name: "output rounds to zero", amount: 2, wantErr: ErrInsufficientLiquidity},
+		{name: "exact out", exactOut: true, amount: 1e6, wantIn: 1004015, wantOut: 1e6, wantFee: 3013, wantBurned: 602},
+		{name: "input rounds up", exactOut: true, amount: 331, wantIn: 333, wantOut: 331, wantFee: 1},
+		{name: "smallest output", exactOut: true, amount: 1, wantIn: 3, wantOut: 1, wantFee: 1},
+		{name: "whole reserve", exactOut: true, amount: 1e9, wantErr: ErrInsufficientLiquidity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, ledger := newTestKeeper(t, nil)
+			pool, err := k.CreatePool(MsgCreatePool{Creator: alice, DenomA: "a", AmountA: 1e9, DenomB: "b", AmountB: 1e9}, blockTime)
+			if err != nil {
+				t.Fatal(err)
+			}
+			before := new(big.Int).Mul(new(big.Int).SetUint64(pool.ReserveA), new(big.Int).SetUint64(pool.ReserveB))
+
+			var quote *Quote
+			if tt.exactOut {
+				quote, err = k.SwapExactOut(MsgSwapExactOut{Sender: bob, Route: []uint64{pool.ID}, DenomIn: "a", MaxAmountIn: 1e12, AmountOut: tt.amount}, blockTime)
+			} else {
+				quote, err = k.SwapExactIn(MsgSwapExactIn{Sender: bob, Route: []uint64{pool.ID}, DenomIn: "a", AmountIn: tt.amount}, blockTime)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("swap = %v; want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if got := ledger.GetBalance(bob, "a"); got != 1e12 {
+					t.Errorf("a failed swap left bob with %d a", got)
+				}
+				return
+			}
+
+			hop := quote.Hops[0]
+			if quote.AmountIn != tt.wantIn || quote.AmountOut != tt.wantOut || hop.Fee != tt.wantFee || hop.Burned != tt.wantBurned {
+				t.Errorf("swapped %d for %d, fee %d, burned %d; want %d for %d, fee %d, burned %d",
+					quote.AmountIn, quote.AmountOut, hop.Fee, hop.Burned, tt.wantIn, tt.wantOut, tt.wantFee, tt.wantBurned)
+			}
+			if a, b := ledger.GetBalance(bob, "a"), ledger.GetBalance(bob, "b"); a != 1e12-tt.wantIn || b != 1e12+tt.wantOut {
+				t.Errorf("bob holds %d a, %d b", a, b)
+			}
+
+			// The reserves match the module account and only grow in value
+			after, _ := k.GetPool(pool.ID)
+			moduleAddr := bank.ModuleAddress(ModuleName)
+			if after.ReserveA != 1e9+tt.wantIn-tt.wantBurned || after.ReserveB != 1e9-tt.wantOut {
+				t.Errorf("reserves = %d/%d", after.ReserveA, after.ReserveB)
+			}
+			if after.ReserveA != ledger.GetBalance(moduleAddr, "a") || after.ReserveB != ledger.GetBalance(moduleAddr, "b") {
+				t.Errorf("reserves %d/%d differ from the module balance %d/%d", after.ReserveA, after.ReserveB,
+					ledger.GetBalance(moduleAddr, "a"), ledger.GetBalance(moduleAddr, "b"))
+			}
+			if burned := ledger.GetBurned("a"); burned != tt.wantBurned {
+				t.Errorf("ledger burned %d a; want %d", burned, tt.wantBurned)
+			}
+			product := new(big.Int).Mul(new(big.Int).SetUint64(after.ReserveA), new(big.Int).SetUint64(after.ReserveB))
+			if product.Cmp(before) < 0 {
+				t.Errorf("the swap shrank the reserve product from %s to %s", before, product)
+			}
+		})
+	}
+}
+
+func TestSwapSlippage(t *testing.T) {
+	k, ledger := newTestKeeper(t, nil)
+	pool, err := k.CreatePool(MsgCreatePool{Creator: alice, DenomA: "a", AmountA: 1e9, DenomB: "b", AmountB: 1e9}, blockTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.SwapExactIn(MsgSwapExactIn{Sender: bob, Route: []uint64{pool.ID}, DenomIn: "a", AmountIn: 1e6, MinAmountOut: 996007}, blockTime); !errors.Is(err, ErrSlippage) {
+		t.Errorf("exact in below the minimum: %v; want ErrSlippage", err)
+	}
+	if _, err := k.SwapExactOut(MsgSwapExactOut{Sender: bob, Route: []uint64{pool.ID}, DenomIn: "a", MaxAmountIn: 1004014, AmountOut: 1e6}, blockTime); !errors.Is(err, ErrSlippage) {
+		t.Errorf("exact out above the maximum: %v; want ErrSlippage", err)
+	}
+	if a, b := ledger.GetBalance(bob, "a"), ledger.GetBalance(bob, "b"); a != 1e12 || b != 1e12 {
+		t.Errorf("refused swaps moved bob's funds to %d a, %d b", a, b)
+	}
+}
@@ -0,0 +1,375 @@
+package domains
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/bank"
+)
+
+// Auction phases
+const (
+	PhaseCommit = "commit"
+	PhaseReveal = "reveal"
+	PhaseSettle = "settle" // reveal window closed, waiting for FinalizeAuction
+)
+
+// Auction is a commit-reveal sealed-bid (Vickrey) auction for a premium name.
+// The winner pays the second highest revealed bid, or the minimum bid when
+// there is only one.
+type Auction struct {
+	Name          string    `json:"name"`
+	UnicodeName   string    `json:"unicode_name"`
+	StartedBy     string    `json:"started_by"`
+	StartedAt     time.Time `json:"started_at"`
+	CommitEnd     time.Time `json:"commit_end"`
+	RevealEnd     time.Time `json:"reveal_end"`
+	MinBid        uint64    `json:"min_bid"`
+	Denom         string    `json:"denom"`
+	Bids          int       `json:"bids"`
+	Revealed      int       `json:"revealed"`
+	HighestBidder string    `json:"highest_bidder,omitempty"`
+	HighestBid    uint64    `json:"highest_bid,omitempty"`
+	SecondBid     uint64    `json:"second_bid,omitempty"`
+	Phase         string    `json:"phase"`
+
+	label        string
+	unicodeLabel string
+	bids         map[string]*sealedBid // bidder -> bid
+}
+
+type sealedBid struct {
+	commitment string
+	deposit    uint64
+	revealed   bool
+	settled    bool // refunded or forfeited by FinalizeAuction
+}
+
+// AuctionResult reports how an auction was settled
+type AuctionResult struct {
+	Name     string `json:"name"`
+	Winner   string `json:"winner,omitempty"` // empty when no bid was revealed
+	Price    uint64 `json:"price"`
+	Burned   uint64 `json:"burned"`    // burn share of the price
+	Forfeits uint64 `json:"forfeited"` // burned deposits of unrevealed bids
+}
+
+// BidCommitment returns the hex encoded commitment a bidder submits during
+// the commit phase: sha256("<name>|<bidder>|<amount>|<salt>")
+func BidCommitment(name, bidder string, amount uint64, salt string) (string, error) {
+	label, _, err := Normalize(name)
+	if err != nil {
+		return "", err
+	}
+	return bidCommitment(label, bidder, amount, salt), nil
+}
+
+func bidCommitment(label, bidder string, amount uint64, salt string) string {
+	sum := sha256.Sum256([]byte(FullName(label) + "|" + bidder + "|" + strconv.FormatUint(amount, 10) + "|" + salt))
+	return hex.EncodeToString(sum[:])
+}
+
+// StartAuction opens an auction for an available premium name
+func (ds *DomainSystem) StartAuction(msg MsgStartAuction, now time.Time) (*Auction, error) {
+	if err := msg.ValidateBasic(); err != nil {
+		return nil, err
+	}
+	label, unicodeLabel, _ := Normalize(msg.Name)
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if !ds.isPremium(unicodeLabel) {
+		return nil, fmt.Errorf("%s is not a premium name; register it directly", FullName(label))
+	}
+	if _, open := ds.auctions[label]; open {
+		return nil, fmt.Errorf("%w: an auction for %s is already open", ErrAuctionPhase, FullName(label))
+	}
+	if err := ds.checkRegistrable(label, unicodeLabel, now); err != nil {
+		return nil, err
+	}
+	minBid, err := ds.registrationPrice(unicodeLabel, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	auction := &Auction{
+		Name:         FullName(label),
+		UnicodeName:  FullName(unicodeLabel),
+		StartedBy:    msg.Sender,
+		StartedAt:    now,
+		CommitEnd:    now.Add(ds.config.CommitPeriod),
+		RevealEnd:    now.Add(ds.config.CommitPeriod + ds.config.RevealPeriod),
+		MinBid:       minBid,
+		Denom:        ds.config.FeeDenom,
+		label:        label,
+		unicodeLabel: unicodeLabel,
+		bids:         make(map[string]*sealedBid),
+	}
+	ds.auctions[label] = auction
+	ds.changed[auctionsKeyPrefix+label] = true
+
+	ds.logger.Info("Domain auction started",
+		zap.String("name", auction.Name),
+		zap.String("started_by", msg.Sender),
+		zap.Time("commit_end", auction.CommitEnd),
+		zap.Time("reveal_end", auction.RevealEnd),
+	)
+
+	return auction.view(now), nil
+}
+
+// CommitBid places a sealed bid. The deposit is escrowed and must cover the
+// hidden bid; overbidding the deposit conceals the real amount.
+func (ds *DomainSystem) CommitBid(msg MsgCommitBid, now time.Time) error {
+	if err := msg.ValidateBasic(); err != nil {
+		return err
+	}
+	label, _, _ := Normalize(msg.Name)
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	auction, err := ds.openAuction(label, PhaseCommit, now)
+	if err != nil {
+		return err
+	}
+	if _, exists := auction.bids[msg.Bidder]; exists {
+		return fmt.Errorf("%w: %s already committed a bid", ErrInvalidBid, msg.Bidder)
+	}
+	if msg.Deposit < auction.MinBid {
+		return fmt.Errorf("%w: deposit must be at least the minimum bid of %d%s", ErrInvalidBid, auction.MinBid, auction.Denom)
+	}
+
+	if err := ds.bank.Send(msg.Bidder, bank.ModuleAddress(ModuleName), auction.Denom, msg.Deposit); err != nil {
+		return err
+	}
+	auction.bids[msg.Bidder] = &sealedBid{commitment: msg.Commitment, deposit: msg.Deposit}
+	auction.Bids++
+	ds.changed[auctionsKeyPrefix+label] = true
+	ds.changed[bidKey(label, msg.Bidder)] = true
+
+	ds.logger.Info("Domain bid committed",
+		zap.String("name", auction.Name),
+		zap.String("bidder", msg.Bidder),
+	)
+	return nil
+}
+
+// RevealBid opens a committed bid during the reveal phase
+func (ds *DomainSystem) RevealBid(msg MsgRevealBid, now time.Time) error {
+	if err := msg.ValidateBasic(); err != nil {
+		return err
+	}
+	label, _, _ := Normalize(msg.Name)
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	auction, err := ds.openAuction(label, PhaseReveal, now)
+	if err != nil {
+		return err
+	}
+	bid, ok := auction.bids[msg.Bidder]
+	if !ok {
+		return fmt.Errorf("%w: %s has no committed bid", ErrInvalidBid, msg.Bidder)
+	}
+	if bid.revealed {
+		return fmt.Errorf("%w: bid already revealed", ErrInvalidBid)
+	}
+	if bidCommitment(label, msg.Bidder, msg.Amount, msg.Salt) != bid.commitment {
+		return fmt.Errorf("%w: amount and salt do not match the commitment", ErrInvalidBid)
+	}
+	if msg.Amount > bid.deposit {
+		return fmt.Errorf("%w: bid %d exceeds deposit %d", ErrInvalidBid, msg.Amount, bid.deposit)
+	}
+	if msg.Amount < auction.MinBid {
+		return fmt.Errorf("%w: bid %d is below the minimum bid %d", ErrInvalidBid, msg.Amount, auction.MinBid)
+	}
+
+	bid.revealed = true
+	auction.Revealed++
+	switch {
+	case msg.Amount > auction.HighestBid:
+		auction.SecondBid = auction.HighestBid
+		auction.HighestBid = msg.Amount
+		auction.HighestBidder = msg.Bidder
+	case msg.Amount > auction.SecondBid:
+		auction.SecondBid = msg.Amount
+	}
+	ds.changed[auctionsKeyPrefix+label] = true
+	ds.changed[bidKey(label, msg.Bidder)] = true
+
+	ds.logger.Info("Domain bid revealed",
+		zap.String("name", auction.Name),
+		zap.String("bidder", msg.Bidder),
+		zap.Uint64("amount", msg.Amount),
+	)
+	return nil
+}
+
+// FinalizeAuction settles an auction once the reveal window has closed. The
+// winner is registered for one year and refunded the difference between its
+// deposit and the second price; other revealed bidders get their deposits
+// back and unrevealed deposits are burned.
+func (ds *DomainSystem) FinalizeAuction(msg MsgFinalizeAuction, now time.Time) (*AuctionResult, error) {
+	if err := msg.ValidateBasic(); err != nil {
+		return nil, err
+	}
+	label, _, _ := Normalize(msg.Name)
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	auction, err := ds.openAuction(label, PhaseSettle, now)
+	if err != nil {
+		return nil, err
+	}
+
+	moduleAddr := bank.ModuleAddress(ModuleName)
+	result := &AuctionResult{Name: auction.Name, Winner: auction.HighestBidder}
+	if result.Winner != "" {
+		result.Price = auction.SecondBid
+		if result.Price < auction.MinBid {
+			result.Price = auction.MinBid
+		}
+	}
+
+	// Refund and forfeit deposits before the price is split so that the
+	// module account only holds the winner's payment afterwards. Settled
+	// bids are marked, so finalizing again after a failure pays no refund
+	// twice.
+	bidders := make([]string, 0, len(auction.bids))
+	for bidder := range auction.bids {
+		bidders = append(bidders, bidder)
+	}
+	sort.Strings(bidders)
+	var forfeited []string
+	for _, bidder := range bidders {
+		bid := auction.bids[bidder]
+		if bid.settled {
+			continue
+		}
+		refund := bid.deposit
+		switch {
+		case !bid.revealed:
+			result.Forfeits += bid.deposit
+			forfeited = append(forfeited, bidder)
+			continue
+		case bidder == result.Winner:
+			refund -= result.Price
+		}
+		if refund > 0 {
+			if err := ds.bank.Send(moduleAddr, bidder, auction.Denom, refund); err != nil {
+				return nil, fmt.Errorf("failed to refund %s: %w", bidder, err)
+			}
+		}
+		bid.settled = true
+		ds.changed[bidKey(label, bidder)] = true
+	}
+	if result.Forfeits > 0 {
+		if err := ds.bank.Burn(moduleAddr, auction.Denom, result.Forfeits); err != nil {
+			return nil, err
+		}
+		for _, bidder := range forfeited {
+			auction.bids[bidder].settled = true
+			ds.changed[bidKey(label, bidder)] = true
+		}
+	}
+
+	if result.Winner != "" {
+		_, burned, err := ds.settle(moduleAddr, bank.ModuleAddress("fee_collector"), result.Price)
+		if err != nil {
+			return nil, err
+		}
+		result.Burned = burned
+		ds.assign(auction.label, auction.unicodeLabel, result.Winner, 1, now)
+	}
+	ds.deleteAuction(label)
+
+	ds.logger.Info("Domain auction finalized",
+		zap.String("name", auction.Name),
+		zap.String("winner", result.Winner),
+		zap.Uint64("price", result.Price),
+		zap.Uint64("forfeited", result.Forfeits),
+	)
+
+	return result, nil
+}
+
+// GetAuction returns the open auction for a name
+func (ds *DomainSystem) GetAuction(name string) (*Auction, error) {
+	label, _, err := Normalize(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	auction, ok := ds.auctions[label]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrAuctionNotFound, FullName(label))
+	}
+	return auction.view(time.Now().UTC()), nil
+}
+
+// GetAuctions returns every open auction ordered by reveal deadline
+func (ds *DomainSystem) GetAuctions() []*Auction {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	now := time.Now().UTC()
+	auctions := make([]*Auction, 0, len(ds.auctions))
+	for _, auction := range ds.auctions {
+		auctions = append(auctions, auction.view(now))
+	}
+	sort.Slice(auctions, func(i, j int) bool { return auctions[i].RevealEnd.Before(auctions[j].RevealEnd) })
+	return auctions
+}
+
+// isPremium reports whether a name is short enough to be sold only at auction
+func (ds *DomainSystem) isPremium(unicodeLabel string) bool {
+	return len([]rune(unicodeLabel)) <= ds.config.AuctionMaxLength
+}
+
+// openAuction returns an auction that is in the given phase
+func (ds *DomainSystem) openAuction(label, phase string, now time.Time) (*Auction, error) {
+	auction, ok := ds.auctions[label]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrAuctionNotFound, FullName(label))
+	}
+	if current := auction.phase(now); current != phase {
+		return nil, fmt.Errorf("%w: %s is in the %s phase", ErrAuctionPhase, auction.Name, current)
+	}
+	return auction, nil
+}
+
+func (a *Auction) phase(now time.Time) string {
+	switch {
+	case now.Before(a.CommitEnd):
+		return PhaseCommit
+	case now.Before(a.RevealEnd):
+		return PhaseReveal
+	default:
+		return PhaseSettle
+	}
+}
+
+// view returns a copy of the public auction state. The leading bid is only
+// shown once the commit phase is over.
+func (a *Auction) view(now time.Time) *Auction {
+	copied := *a
+	copied.bids = nil
+	copied.Phase = a.phase(now)
+	if copied.Phase == PhaseCommit {
+		copied.HighestBidder, copied.HighestBid, copied.SecondBid = "", 0, 0
+	}
+	return &copied
+}
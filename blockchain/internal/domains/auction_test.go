@@ -0,0 +1,145 @@
+package domains
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vindexchain/blockchain/internal/bank"
+)
+
+const (
+	alice = "vindex1alice000000000000000"
+	bob   = "vindex1bob00000000000000000"
+	carol = "vindex1carol000000000000000"
+)
+
+var blockTime = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newTestSystem returns a domain system with a flat 1000oc registration
+// fee, so auctions have a minimum bid of 1000, and alice, bob and carol
+// holding 1e6oc each
+func newTestSystem(t *testing.T) (*DomainSystem, *bank.Keeper) {
+	t.Helper()
+	ledger := bank.NewKeeper(nil)
+	for _, address := range []string{alice, bob, carol} {
+		if err := ledger.Mint(address, defaultFeeDenom, 1e6); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ds := NewDomainSystem(ledger, &Config{RegistrationFee: 1000, RenewalFee: 1000, PriceTiers: []PriceTier{}})
+	return ds, ledger
+}
+
+func TestFinalizeAuction(t *testing.T) {
+	type bid struct {
+		bidder  string
+		amount  uint64
+		deposit uint64
+		reveal  bool
+	}
+	tests := []struct {
+		name         string
+		bids         []bid // revealed in this order
+		wantWinner   string
+		wantPrice    uint64
+		wantBurned   uint64
+		wantForfeits uint64
+	}{
+		{
+			name:       "second price",
+			bids:       []bid{{alice, 5000, 6000, true}, {bob, 3000, 3000, true}},
+			wantWinner: alice, wantPrice: 3000, wantBurned: 300,
+		},
+		{
+			name:       "single bid pays the minimum",
+			bids:       []bid{{alice, 5000, 5000, true}},
+			wantWinner: alice, wantPrice: 1000, wantBurned: 100,
+		},
+		{
+			name:       "tie goes to the first reveal",
+			bids:       []bid{{bob, 4000, 4000, true}, {alice, 4000, 9000, true}},
+			wantWinner: bob, wantPrice: 4000, wantBurned: 400,
+		},
+		{
+			name:       "unrevealed deposit is forfeited",
+			bids:       []bid{{alice, 5000, 5000, true}, {bob, 8000, 8000, false}},
+			wantWinner: alice, wantPrice: 1000, wantBurned: 100, wantForfeits: 8000,
+		},
+		{
+			name:         "no revealed bid",
+			bids:         []bid{{bob, 2000, 2000, false}},
+			wantForfeits: 2000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds, ledger := newTestSystem(t)
+			if _, err := ds.StartAuction(MsgStartAuction{Sender: carol, Name: "abc"}, blockTime); err != nil {
+				t.Fatal(err)
+			}
+			for _, b := range tt.bids {
+				commitment, _ := BidCommitment("abc", b.bidder, b.amount, "salt")
+				if err := ds.CommitBid(MsgCommitBid{Bidder: b.bidder, Name: "abc", Commitment: commitment, Deposit: b.deposit}, blockTime); err != nil {
+					t.Fatal(err)
+				}
+			}
+			revealAt := blockTime.Add(defaultCommitPeriod)
+			for _, b := range tt.bids {
+				if b.reveal {
+					if err := ds.RevealBid(MsgRevealBid{Bidder: b.bidder, Name: "abc", Amount: b.amount, Salt: "salt"}, revealAt); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			if _, err := ds.FinalizeAuction(MsgFinalizeAuction{Sender: carol, Name: "abc"}, revealAt); !errors.Is(err, ErrAuctionPhase) {
+				t.Fatalf("finalize during the reveal phase: %v; want ErrAuctionPhase", err)
+			}
+			settleAt := revealAt.Add(defaultRevealPeriod)
+			result, err := ds.FinalizeAuction(MsgFinalizeAuction{Sender: carol, Name: "abc"}, settleAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Winner != tt.wantWinner || result.Price != tt.wantPrice || result.Burned != tt.wantBurned || result.Forfeits != tt.wantForfeits {
+				t.Errorf("result = %+v; want winner %q, price %d, burned %d, forfeits %d",
+					result, tt.wantWinner, tt.wantPrice, tt.wantBurned, tt.wantForfeits)
+			}
+
+			// Revealed losers get their deposit back, the winner pays the
+			// price and unrevealed deposits are gone
+			for _, b := range tt.bids {
+				want := uint64(1e6)
+				switch {
+				case !b.reveal:
+					want -= b.deposit
+				case b.bidder == tt.wantWinner:
+					want -= tt.wantPrice
+				}
+				if got := ledger.GetBalance(b.bidder, defaultFeeDenom); got != want {
+					t.Errorf("%s holds %d; want %d", b.bidder, got, want)
+				}
+			}
+			if got := ledger.GetBalance(bank.ModuleAddress(ModuleName), defaultFeeDenom); got != 0 {
+				t.Errorf("the domains module still holds %d", got)
+			}
+			if got := ledger.GetBalance(bank.ModuleAddress("fee_collector"), defaultFeeDenom); got != tt.wantPrice-tt.wantBurned {
+				t.Errorf("fee collector holds %d; want %d", got, tt.wantPrice-tt.wantBurned)
+			}
+			if got := ledger.GetBurned(defaultFeeDenom); got != tt.wantBurned+tt.wantForfeits {
+				t.Errorf("burned %d; want %d", got, tt.wantBurned+tt.wantForfeits)
+			}
+
+			domain, err := ds.GetDomain("abc")
+			switch {
+			case tt.wantWinner == "" && !errors.Is(err, ErrDomainNotFound):
+				t.Errorf("GetDomain = %+v, %v; want ErrDomainNotFound", domain, err)
+			case tt.wantWinner != "" && (err != nil || domain.Owner != tt.wantWinner || !domain.ExpiresAt.Equal(settleAt.Add(Year))):
+				t.Errorf("GetDomain = %+v, %v; want owned by %s for a year", domain, err, tt.wantWinner)
+			}
+			if _, err := ds.GetAuction("abc"); !errors.Is(err, ErrAuctionNotFound) {
+				t.Errorf("the auction is still open: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package domains
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// homoglyphs maps characters that render like a Latin letter or digit onto
+// that prototype. It covers the lookalikes that survive IDNA lowercasing.
+var homoglyphs = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'с': 'c', 'ԁ': 'd', 'е': 'e', 'һ': 'h', 'і': 'i', 'ј': 'j',
+	'к': 'k', 'ӏ': 'l', 'м': 'm', 'о': 'o', 'р': 'p', 'ԛ': 'q', 'г': 'r',
+	'ѕ': 's', 'т': 't', 'ѵ': 'v', 'ԝ': 'w', 'х': 'x', 'у': 'y',
+	// Greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o',
+	'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'γ': 'y', 'ω': 'w',
+	// Latin lookalikes
+	'ı': 'i', 'ȷ': 'j', 'ɡ': 'g', 'ɑ': 'a', 'ʟ': 'l', 'ɩ': 'i', 'ᴢ': 'z',
+}
+
+// looseHomoglyphs are digits and letter pairs that read as a single letter.
+// They also merge distinct ASCII words ("modern" and "modem"), so two
+// all-ASCII labels are never compared by skeleton; only the reserved list
+// and labels with non-ASCII characters are.
+var looseHomoglyphs = strings.NewReplacer("0", "o", "1", "l", "rn", "m", "vv", "w", "cl", "d")
+
+// allowedScriptSets are the script combinations that legitimately share a
+// label; any other mix of scripts is rejected as a spoofing risk
+var allowedScriptSets = [][]string{
+	{"Han", "Hiragana", "Katakana"}, // Japanese
+	{"Han", "Hangul"},               // Korean
+}
+
+// skeleton reduces a Unicode label to a form where confusable labels collide:
+// accents are stripped and lookalike characters mapped to Latin prototypes
+func skeleton(unicodeLabel string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(unicodeLabel) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if prototype, ok := homoglyphs[r]; ok {
+			r = prototype
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return looseHomoglyphs.Replace(b.String())
+}
+
+// comparableSkeletons reports whether two labels with the same skeleton are
+// confusable: at least one of them must contain a non-ASCII character
+func comparableSkeletons(unicodeLabel, other string) bool {
+	return !isASCII(unicodeLabel) || !isASCII(other)
+}
+
+// addSkeleton indexes a registered label under its skeleton once
+func addSkeleton(skeletons map[string][]string, sk, label string) {
+	for _, other := range skeletons[sk] {
+		if other == label {
+			return
+		}
+	}
+	skeletons[sk] = append(skeletons[sk], label)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// checkScripts rejects labels that mix writing systems, such as Latin with
+// Cyrillic. Digits and hyphens belong to no script and are always allowed.
+func checkScripts(unicodeLabel string) error {
+	seen := make(map[string]bool)
+	for _, r := range unicodeLabel {
+		if name := script(r); name != "" {
+			seen[name] = true
+		}
+	}
+	if len(seen) <= 1 {
+		return nil
+	}
+
+	scripts := make([]string, 0, len(seen))
+	for name := range seen {
+		scripts = append(scripts, name)
+	}
+	sort.Strings(scripts)
+
+	for _, allowed := range allowedScriptSets {
+		if subset(scripts, allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: mixes %s scripts", ErrConfusable, strings.Join(scripts, " and "))
+}
+
+// script returns the Unicode script of a rune, or "" for Common and Inherited
+func script(r rune) string {
+	for name, table := range unicode.Scripts {
+		if name == "Common" || name == "Inherited" {
+			continue
+		}
+		if unicode.Is(table, r) {
+			return name
+		}
+	}
+	return ""
+}
+
+func subset(values, allowed []string) bool {
+	for _, v := range values {
+		found := false
+		for _, a := range allowed {
+			if v == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,440 @@
+package domains
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/bank"
+)
+
+const (
+	// ModuleName is the name of the domain module
+	ModuleName = "domains"
+
+	// Year is the registration period unit
+	Year = 365 * 24 * time.Hour
+
+	defaultFeeDenom    = "oc"
+	defaultGracePeriod = 30 * 24 * time.Hour
+	defaultMaxYears    = 10
+
+	defaultBurnShare        = 10
+	defaultAuctionMaxLength = 3
+	defaultCommitPeriod     = 72 * time.Hour
+	defaultRevealPeriod     = 48 * time.Hour
+)
+
+var (
+	// ErrInvalidName is returned for names that fail normalization
+	ErrInvalidName = errors.New("invalid domain name")
+	// ErrDomainNotFound is returned for names that were never registered
+	ErrDomainNotFound = errors.New("domain not found")
+	// ErrDomainTaken is returned when registering a name that is active or in its grace period
+	ErrDomainTaken = errors.New("domain is already registered")
+	// ErrDomainExpired is returned when renewing a name whose grace period has ended
+	ErrDomainExpired = errors.New("domain has expired")
+	// ErrInvalidYears is returned for registration periods outside the allowed range
+	ErrInvalidYears = errors.New("invalid registration period")
+	// ErrUnauthorized is returned when the sender does not own the name
+	ErrUnauthorized = errors.New("sender is not the domain owner")
+	// ErrSubdomainExists is returned when creating a subdomain that already exists
+	ErrSubdomainExists = errors.New("subdomain already exists")
+	// ErrNoPrimaryName is returned by reverse lookups for addresses without a verified primary name
+	ErrNoPrimaryName = errors.New("no primary name set for address")
+	// ErrAuctionRequired is returned when registering a premium name directly
+	ErrAuctionRequired = errors.New("premium names are only sold at auction")
+	// ErrListingNotFound is returned for names that are not for sale
+	ErrListingNotFound = errors.New("domain is not listed for sale")
+	// ErrDomainListed is returned when changing a name that is held in escrow
+	ErrDomainListed = errors.New("domain is listed for sale")
+	// ErrAuctionNotFound is returned for names without an open auction
+	ErrAuctionNotFound = errors.New("auction not found")
+	// ErrAuctionPhase is returned for bids, reveals or settlements outside their window
+	ErrAuctionPhase = errors.New("auction is not in the required phase")
+	// ErrInvalidBid is returned for commitments or reveals that do not check out
+	ErrInvalidBid = errors.New("invalid bid")
+	// ErrNameReserved is returned when registering a reserved name without a claim
+	ErrNameReserved = errors.New("domain name is reserved")
+	// ErrConfusable is returned for names that mix scripts or imitate a protected name
+	ErrConfusable = errors.New("domain name is confusable with a protected name")
+	// ErrInvalidSignature is returned for reserved name claims and updates
+	// not signed by their authority
+	ErrInvalidSignature = errors.New("invalid authority signature")
+)
+
+// Domain statuses
+const (
+	StatusActive  = "active"
+	StatusGrace   = "grace"   // expired but still renewable; not yet open to re-registration
+	StatusExpired = "expired" // anyone may register the name again
+)
+
+// Config holds domain system parameters
+type Config struct {
+	RegistrationFee uint64 // first year of a new registration
+	RenewalFee      uint64 // every further year, and every renewed year
+	FeeDenom        string
+	GracePeriod     time.Duration
+	MaxYears        uint32      // furthest a registration may extend into the future
+	PriceTiers      []PriceTier // short name multipliers; DefaultPriceTiers when nil
+
+	// BurnShare is the percent of marketplace and auction proceeds burned
+	BurnShare int
+	// AuctionMaxLength is the length up to which names are premium and only
+	// sold through sealed-bid auctions
+	AuctionMaxLength int
+	CommitPeriod     time.Duration
+	RevealPeriod     time.Duration
+
+	// Reserved names are withheld from registration, usually loaded from genesis
+	Reserved []ReservedName
+	// ClaimAuthority is the hex encoded ed25519 key that signs reserved name
+	// claims; reserved names cannot be claimed when it is empty
+	ClaimAuthority string
+	// GovernanceKey is the hex encoded ed25519 key that signs updates of
+	// the reserved list; the list cannot be updated when it is empty
+	GovernanceKey string
+
+	Logger *zap.Logger
+}
+
+// Domain is a registered .vindex name
+type Domain struct {
+	Name         string    `json:"name"`         // ASCII (punycode) form including the TLD
+	UnicodeName  string    `json:"unicode_name"` // display form including the TLD
+	Owner        string    `json:"owner"`
+	RegisteredAt time.Time `json:"registered_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	Status       string    `json:"status"`
+	Records      Records   `json:"records"`
+}
+
+// DomainSystem registers and renews .vindex names. Methods that change
+// names take the time of the block executing them as now, so every node
+// reaches the same state.
+type DomainSystem struct {
+	mu      sync.RWMutex
+	bank    *bank.Keeper
+	config  *Config
+	domains map[string]*Domain // ASCII label -> domain
+	logger  *zap.Logger
+
+	subdomains map[string]map[string]*Subdomain // parent label -> subdomain label -> subdomain
+	primary    map[string]string                // address -> ASCII name, verified on lookup
+	listings   map[string]*Listing              // label -> fixed-price listing
+	auctions   map[string]*Auction              // label -> open auction
+
+	reserved          map[string]*ReservedName // label -> reserved entry
+	reservedSkeletons map[string]string        // skeleton -> reserved label
+	skeletons         map[string][]string      // skeleton -> registered labels
+	claimKey          ed25519.PublicKey
+	governanceKey     ed25519.PublicKey
+	reservedNonce     uint64 // nonce the next reserved list update must carry
+
+	changed map[string]bool // state keys changed since the last export
+}
+
+// NewDomainSystem creates a new domain system backed by the ledger
+func NewDomainSystem(bankKeeper *bank.Keeper, cfg *Config) *DomainSystem {
+	if cfg.FeeDenom == "" {
+		cfg.FeeDenom = defaultFeeDenom
+	}
+	if cfg.GracePeriod == 0 {
+		cfg.GracePeriod = defaultGracePeriod
+	}
+	if cfg.MaxYears == 0 {
+		cfg.MaxYears = defaultMaxYears
+	}
+	if cfg.PriceTiers == nil {
+		cfg.PriceTiers = DefaultPriceTiers
+	}
+	cfg.PriceTiers = sortTiers(cfg.PriceTiers)
+	if cfg.BurnShare == 0 {
+		cfg.BurnShare = defaultBurnShare
+	}
+	if cfg.AuctionMaxLength == 0 {
+		cfg.AuctionMaxLength = defaultAuctionMaxLength
+	}
+	if cfg.CommitPeriod == 0 {
+		cfg.CommitPeriod = defaultCommitPeriod
+	}
+	if cfg.RevealPeriod == 0 {
+		cfg.RevealPeriod = defaultRevealPeriod
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	ds := &DomainSystem{
+		bank:    bankKeeper,
+		config:  cfg,
+		domains: make(map[string]*Domain),
+		logger:  logger,
+
+		subdomains: make(map[string]map[string]*Subdomain),
+		primary:    make(map[string]string),
+		listings:   make(map[string]*Listing),
+		auctions:   make(map[string]*Auction),
+
+		reserved:          make(map[string]*ReservedName),
+		reservedSkeletons: make(map[string]string),
+		skeletons:         make(map[string][]string),
+
+		changed: make(map[string]bool),
+	}
+
+	for _, entry := range cfg.Reserved {
+		if err := ds.addReserved(entry); err != nil {
+			logger.Warn("Skipping invalid reserved name", zap.Error(err))
+		}
+	}
+	if cfg.ClaimAuthority != "" {
+		key, err := hex.DecodeString(cfg.ClaimAuthority)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			logger.Error("Invalid reserved name claim authority; claims are disabled",
+				zap.String("claim_authority", cfg.ClaimAuthority))
+		} else {
+			ds.claimKey = ed25519.PublicKey(key)
+		}
+	}
+	if cfg.GovernanceKey != "" {
+		key, err := hex.DecodeString(cfg.GovernanceKey)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			logger.Error("Invalid reserved name governance key; updates are disabled",
+				zap.String("governance_key", cfg.GovernanceKey))
+		} else {
+			ds.governanceKey = ed25519.PublicKey(key)
+		}
+	}
+
+	return ds
+}
+
+// RegistrationPrice returns the cost of registering a name for years
+func (ds *DomainSystem) RegistrationPrice(name string, years uint32) (uint64, error) {
+	_, unicodeLabel, err := Normalize(name)
+	if err != nil {
+		return 0, err
+	}
+	return ds.registrationPrice(unicodeLabel, years)
+}
+
+// RenewalPrice returns the cost of renewing a name for years
+func (ds *DomainSystem) RenewalPrice(name string, years uint32) (uint64, error) {
+	_, unicodeLabel, err := Normalize(name)
+	if err != nil {
+		return 0, err
+	}
+	return ds.renewalPrice(unicodeLabel, years)
+}
+
+// RegisterDomain registers a new name, or takes over one whose grace period
+// has ended. Reserved names and names confusable with protected ones are refused.
+func (ds *DomainSystem) RegisterDomain(msg MsgRegisterDomain, now time.Time) (*Domain, error) {
+	if err := msg.ValidateBasic(); err != nil {
+		return nil, err
+	}
+	if msg.Years > ds.config.MaxYears {
+		return nil, fmt.Errorf("%w: at most %d years", ErrInvalidYears, ds.config.MaxYears)
+	}
+	label, unicodeLabel, _ := Normalize(msg.Name)
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if err := ds.checkRegistrable(label, unicodeLabel, now); err != nil {
+		return nil, err
+	}
+	if ds.isPremium(unicodeLabel) {
+		return nil, fmt.Errorf("%w: %s", ErrAuctionRequired, FullName(label))
+	}
+
+	price, err := ds.registrationPrice(unicodeLabel, msg.Years)
+	if err != nil {
+		return nil, err
+	}
+	if err := ds.chargeFee(msg.Owner, price); err != nil {
+		return nil, err
+	}
+	domain := ds.assign(label, unicodeLabel, msg.Owner, msg.Years, now)
+
+	ds.logger.Info("Domain registered",
+		zap.String("name", domain.Name),
+		zap.String("owner", domain.Owner),
+		zap.Uint32("years", msg.Years),
+		zap.Uint64("price", price),
+	)
+
+	return ds.view(domain, now), nil
+}
+
+// RenewDomain extends a registration that has not passed its grace period
+func (ds *DomainSystem) RenewDomain(msg MsgRenewDomain, now time.Time) (*Domain, error) {
+	if err := msg.ValidateBasic(); err != nil {
+		return nil, err
+	}
+	label, unicodeLabel, _ := Normalize(msg.Name)
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	domain, ok := ds.domains[label]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrDomainNotFound, FullName(label))
+	}
+	if ds.status(domain, now) == StatusExpired {
+		return nil, fmt.Errorf("%w: %s must be registered again", ErrDomainExpired, domain.Name)
+	}
+
+	expiresAt := domain.ExpiresAt.Add(time.Duration(msg.Years) * Year)
+	if limit := now.Add(time.Duration(ds.config.MaxYears) * Year); expiresAt.After(limit) {
+		return nil, fmt.Errorf("%w: registrations cannot extend more than %d years ahead", ErrInvalidYears, ds.config.MaxYears)
+	}
+
+	price, err := ds.renewalPrice(unicodeLabel, msg.Years)
+	if err != nil {
+		return nil, err
+	}
+	if err := ds.chargeFee(msg.Sender, price); err != nil {
+		return nil, err
+	}
+	domain.ExpiresAt = expiresAt
+	ds.changed[namesKeyPrefix+label] = true
+
+	ds.logger.Info("Domain renewed",
+		zap.String("name", domain.Name),
+		zap.String("sender", msg.Sender),
+		zap.Uint32("years", msg.Years),
+		zap.Time("expires_at", expiresAt),
+	)
+
+	return ds.view(domain, now), nil
+}
+
+// GetDomain returns a registered name, including one past its grace period
+// until somebody registers it again
+func (ds *DomainSystem) GetDomain(name string) (*Domain, error) {
+	label, _, err := Normalize(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	domain, ok := ds.domains[label]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrDomainNotFound, FullName(label))
+	}
+	return ds.view(domain, time.Now().UTC()), nil
+}
+
+// GetDomains returns the names held by owner, or every name when owner is
+// empty, ordered by name
+func (ds *DomainSystem) GetDomains(owner string) []*Domain {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	now := time.Now().UTC()
+	domains := make([]*Domain, 0)
+	for _, domain := range ds.domains {
+		if owner != "" && domain.Owner != owner {
+			continue
+		}
+		domains = append(domains, ds.view(domain, now))
+	}
+	sort.Slice(domains, func(i, j int) bool { return domains[i].Name < domains[j].Name })
+	return domains
+}
+
+// checkAvailable fails if a name is registered and not past its grace period
+func (ds *DomainSystem) checkAvailable(label string, now time.Time) error {
+	if existing, ok := ds.domains[label]; ok && ds.status(existing, now) != StatusExpired {
+		return fmt.Errorf("%w: %s", ErrDomainTaken, existing.Name)
+	}
+	return nil
+}
+
+// assign records a new registration. A name taken over after expiry starts
+// without the previous owner's records, subdomains or listing.
+func (ds *DomainSystem) assign(label, unicodeLabel, owner string, years uint32, now time.Time) *Domain {
+	domain := &Domain{
+		Name:         FullName(label),
+		UnicodeName:  FullName(unicodeLabel),
+		Owner:        owner,
+		RegisteredAt: now,
+		ExpiresAt:    now.Add(time.Duration(years) * Year),
+	}
+	ds.domains[label] = domain
+	addSkeleton(ds.skeletons, skeleton(unicodeLabel), label)
+	ds.deleteSubdomains(label)
+	delete(ds.listings, label)
+	ds.changed[namesKeyPrefix+label] = true
+	ds.changed[listingsKeyPrefix+label] = true
+	return domain
+}
+
+// status classifies a domain relative to its expiry and grace period
+func (ds *DomainSystem) status(domain *Domain, now time.Time) string {
+	switch {
+	case now.Before(domain.ExpiresAt):
+		return StatusActive
+	case now.Before(domain.ExpiresAt.Add(ds.config.GracePeriod)):
+		return StatusGrace
+	default:
+		return StatusExpired
+	}
+}
+
+// view returns a copy of a domain with its current status
+func (ds *DomainSystem) view(domain *Domain, now time.Time) *Domain {
+	copied := *domain
+	copied.Status = ds.status(domain, now)
+	copied.Records = domain.Records.clone()
+	return &copied
+}
+
+func (ds *DomainSystem) registrationPrice(unicodeLabel string, years uint32) (uint64, error) {
+	if years == 0 {
+		return 0, fmt.Errorf("%w: years must be at least 1", ErrInvalidYears)
+	}
+	renewals, err := mul(ds.config.RenewalFee, uint64(years-1))
+	if err != nil {
+		return 0, err
+	}
+	base := ds.config.RegistrationFee + renewals
+	if base < renewals {
+		return 0, bank.ErrOverflow
+	}
+	return mul(base, multiplier(ds.config.PriceTiers, unicodeLabel))
+}
+
+func (ds *DomainSystem) renewalPrice(unicodeLabel string, years uint32) (uint64, error) {
+	if years == 0 {
+		return 0, fmt.Errorf("%w: years must be at least 1", ErrInvalidYears)
+	}
+	base, err := mul(ds.config.RenewalFee, uint64(years))
+	if err != nil {
+		return 0, err
+	}
+	return mul(base, multiplier(ds.config.PriceTiers, unicodeLabel))
+}
+
+// chargeFee moves a registration or renewal payment to the fee collector
+func (ds *DomainSystem) chargeFee(payer string, amount uint64) error {
+	if amount == 0 {
+		return nil
+	}
+	if err := ds.bank.Send(payer, bank.ModuleAddress("fee_collector"), ds.config.FeeDenom, amount); err != nil {
+		return fmt.Errorf("failed to pay %d%s: %w", amount, ds.config.FeeDenom, err)
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+package domains
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Keys of the domain system in the state store, relative to the module
+// prefix. Labels are the ASCII (punycode) form and never contain a slash.
+const (
+	namesKeyPrefix      = "names/"      // names/{label} holds the domain
+	subdomainsKeyPrefix = "subdomains/" // subdomains/{parent}/{label}
+	primaryKeyPrefix    = "primary/"    // primary/{address} holds the name
+	listingsKeyPrefix   = "listings/"   // listings/{label}
+	auctionsKeyPrefix   = "auctions/"   // auctions/{label}
+	bidsKeyPrefix       = "bids/"       // bids/{label}/{bidder} holds a sealed bid
+	reservedKeyPrefix   = "reserved/"   // reserved/{label}
+	reservedNonceKey    = "reserved_nonce"
+)
+
+// storedBid is a sealed bid as kept in the state store
+type storedBid struct {
+	Commitment string `json:"commitment"`
+	Deposit    uint64 `json:"deposit"`
+	Revealed   bool   `json:"revealed"`
+	Settled    bool   `json:"settled,omitempty"`
+}
+
+// ExportState returns every domain, subdomain, primary name, listing,
+// auction with its sealed bids and reserved name, and the nonce of the
+// next reserved list update
+func (ds *DomainSystem) ExportState() (map[string][]byte, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	state := make(map[string][]byte)
+	put := func(key string, v interface{}) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		state[key] = data
+		return nil
+	}
+
+	for label, domain := range ds.domains {
+		if err := put(namesKeyPrefix+label, domain); err != nil {
+			return nil, err
+		}
+	}
+	for parent, subs := range ds.subdomains {
+		for label, sub := range subs {
+			if err := put(subdomainKey(parent, label), sub); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for address, name := range ds.primary {
+		state[primaryKeyPrefix+address] = []byte(name)
+	}
+	for label, listing := range ds.listings {
+		if err := put(listingsKeyPrefix+label, listing); err != nil {
+			return nil, err
+		}
+	}
+	for label, auction := range ds.auctions {
+		if err := put(auctionsKeyPrefix+label, auction); err != nil {
+			return nil, err
+		}
+		for bidder, bid := range auction.bids {
+			stored := storedBid{Commitment: bid.commitment, Deposit: bid.deposit, Revealed: bid.revealed, Settled: bid.settled}
+			if err := put(bidKey(label, bidder), stored); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for label, entry := range ds.reserved {
+		if err := put(reservedKeyPrefix+label, entry); err != nil {
+			return nil, err
+		}
+	}
+	state[reservedNonceKey] = strconv.AppendUint(nil, ds.reservedNonce, 10)
+	return state, nil
+}
+
+// ImportState replaces the domain system's state with exported state. The
+// name skeletons are derived again from the imported names.
+func (ds *DomainSystem) ImportState(state map[string][]byte) error {
+	domains := make(map[string]*Domain)
+	subdomains := make(map[string]map[string]*Subdomain)
+	primary := make(map[string]string)
+	listings := make(map[string]*Listing)
+	auctions := make(map[string]*Auction)
+	bids := make(map[string]map[string]*sealedBid)
+	var reserved []ReservedName
+	var reservedNonce uint64
+
+	for key, value := range state {
+		var err error
+		switch {
+		case strings.HasPrefix(key, namesKeyPrefix):
+			var domain Domain
+			if err = json.Unmarshal(value, &domain); err == nil {
+				domains[strings.TrimPrefix(key, namesKeyPrefix)] = &domain
+			}
+		case strings.HasPrefix(key, subdomainsKeyPrefix):
+			parent, label, ok := strings.Cut(strings.TrimPrefix(key, subdomainsKeyPrefix), "/")
+			if !ok {
+				return fmt.Errorf("invalid subdomain key %s", key)
+			}
+			var sub Subdomain
+			if err = json.Unmarshal(value, &sub); err == nil {
+				if subdomains[parent] == nil {
+					subdomains[parent] = make(map[string]*Subdomain)
+				}
+				subdomains[parent][label] = &sub
+			}
+		case strings.HasPrefix(key, primaryKeyPrefix):
+			primary[strings.TrimPrefix(key, primaryKeyPrefix)] = string(value)
+		case strings.HasPrefix(key, listingsKeyPrefix):
+			var listing Listing
+			if err = json.Unmarshal(value, &listing); err == nil {
+				listings[strings.TrimPrefix(key, listingsKeyPrefix)] = &listing
+			}
+		case strings.HasPrefix(key, auctionsKeyPrefix):
+			var auction Auction
+			if err = json.Unmarshal(value, &auction); err == nil {
+				auction.label, auction.unicodeLabel, err = Normalize(auction.Name)
+				auctions[strings.TrimPrefix(key, auctionsKeyPrefix)] = &auction
+			}
+		case strings.HasPrefix(key, bidsKeyPrefix):
+			label, bidder, ok := strings.Cut(strings.TrimPrefix(key, bidsKeyPrefix), "/")
+			if !ok {
+				return fmt.Errorf("invalid bid key %s", key)
+			}
+			var bid storedBid
+			if err = json.Unmarshal(value, &bid); err == nil {
+				if bids[label] == nil {
+					bids[label] = make(map[string]*sealedBid)
+				}
+				bids[label][bidder] = &sealedBid{commitment: bid.Commitment, deposit: bid.Deposit, revealed: bid.Revealed, settled: bid.Settled}
+			}
+		case strings.HasPrefix(key, reservedKeyPrefix):
+			var entry ReservedName
+			if err = json.Unmarshal(value, &entry); err == nil {
+				reserved = append(reserved, entry)
+			}
+		case key == reservedNonceKey:
+			reservedNonce, err = strconv.ParseUint(string(value), 10, 64)
+		default:
+			err = fmt.Errorf("unknown key")
+		}
+		if err != nil {
+			return fmt.Errorf("invalid domain state under %s: %w", key, err)
+		}
+	}
+
+	skeletons := make(map[string][]string, len(domains))
+	for label, domain := range domains {
+		_, unicodeLabel, err := Normalize(domain.Name)
+		if err != nil {
+			return fmt.Errorf("invalid domain state under %s%s: %w", namesKeyPrefix, label, err)
+		}
+		addSkeleton(skeletons, skeleton(unicodeLabel), label)
+	}
+	for label, auction := range auctions {
+		auction.bids = bids[label]
+		if auction.bids == nil {
+			auction.bids = make(map[string]*sealedBid)
+		}
+	}
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.domains, ds.subdomains, ds.primary = domains, subdomains, primary
+	ds.listings, ds.auctions, ds.skeletons = listings, auctions, skeletons
+	ds.reserved = make(map[string]*ReservedName, len(reserved))
+	ds.reservedSkeletons = make(map[string]string, len(reserved))
+	for _, entry := range reserved {
+		if err := ds.addReserved(entry); err != nil {
+			return err
+		}
+	}
+	ds.reservedNonce = reservedNonce
+	ds.changed = make(map[string]bool)
+	return nil
+}
+
+// ExportChanges returns the keys changed since the last export, with nil
+// values for removed names, subdomains, listings, auctions and bids
+func (ds *DomainSystem) ExportChanges() (map[string][]byte, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	changes := make(map[string][]byte, len(ds.changed))
+	put := func(key string, v interface{}, ok bool) error {
+		if !ok {
+			changes[key] = nil
+			return nil
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		changes[key] = data
+		return nil
+	}
+
+	for key := range ds.changed {
+		var err error
+		switch {
+		case strings.HasPrefix(key, namesKeyPrefix):
+			domain, ok := ds.domains[strings.TrimPrefix(key, namesKeyPrefix)]
+			err = put(key, domain, ok)
+		case strings.HasPrefix(key, subdomainsKeyPrefix):
+			parent, label, _ := strings.Cut(strings.TrimPrefix(key, subdomainsKeyPrefix), "/")
+			sub, ok := ds.subdomains[parent][label]
+			err = put(key, sub, ok)
+		case strings.HasPrefix(key, primaryKeyPrefix):
+			changes[key] = nil
+			if name, ok := ds.primary[strings.TrimPrefix(key, primaryKeyPrefix)]; ok {
+				changes[key] = []byte(name)
+			}
+		case strings.HasPrefix(key, listingsKeyPrefix):
+			listing, ok := ds.listings[strings.TrimPrefix(key, listingsKeyPrefix)]
+			err = put(key, listing, ok)
+		case strings.HasPrefix(key, auctionsKeyPrefix):
+			auction, ok := ds.auctions[strings.TrimPrefix(key, auctionsKeyPrefix)]
+			err = put(key, auction, ok)
+		case strings.HasPrefix(key, bidsKeyPrefix):
+			label, bidder, _ := strings.Cut(strings.TrimPrefix(key, bidsKeyPrefix), "/")
+			var bid *sealedBid
+			if auction, ok := ds.auctions[label]; ok {
+				bid = auction.bids[bidder]
+			}
+			var stored storedBid
+			if bid != nil {
+				stored = storedBid{Commitment: bid.commitment, Deposit: bid.deposit, Revealed: bid.revealed, Settled: bid.settled}
+			}
+			err = put(key, stored, bid != nil)
+		case strings.HasPrefix(key, reservedKeyPrefix):
+			entry, ok := ds.reserved[strings.TrimPrefix(key, reservedKeyPrefix)]
+			err = put(key, entry, ok)
+		case key == reservedNonceKey:
+			changes[key] = strconv.AppendUint(nil, ds.reservedNonce, 10)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	ds.changed = make(map[string]bool)
+	return changes, nil
+}
+
+// deleteSubdomains removes every subdomain of a name. Callers must hold ds.mu.
+func (ds *DomainSystem) deleteSubdomains(label string) {
+	for sub := range ds.subdomains[label] {
+		ds.changed[subdomainKey(label, sub)] = true
+	}
+	delete(ds.subdomains, label)
+}
+
+// deleteAuction removes a settled auction and its bids. Callers must hold ds.mu.
+func (ds *DomainSystem) deleteAuction(label string) {
+	if auction, ok := ds.auctions[label]; ok {
+		for bidder := range auction.bids {
+			ds.changed[bidKey(label, bidder)] = true
+		}
+	}
+	delete(ds.auctions, label)
+	ds.changed[auctionsKeyPrefix+label] = true
+}
+
+func subdomainKey(parent, label string) string {
+	return subdomainsKeyPrefix + parent + "/" + label
+}
+
+func bidKey(label, bidder string) string {
+	return bidsKeyPrefix + label + "/" + bidder
+}
@@ -0,0 +1,237 @@
+package domains
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/bank"
+)
+
+// Listing is a fixed-price offer for a name held in escrow by the module
+type Listing struct {
+	Name     string    `json:"name"`
+	Seller   string    `json:"seller"`
+	Price    uint64    `json:"price"`
+	Denom    string    `json:"denom"`
+	ListedAt time.Time `json:"listed_at"`
+}
+
+// Sale reports how the proceeds of a sale were split
+type Sale struct {
+	Name   string `json:"name"`
+	Seller string `json:"seller"`
+	Buyer  string `json:"buyer"`
+	Price  uint64 `json:"price"`
+	Paid   uint64 `json:"paid"`   // amount received by the seller
+	Burned uint64 `json:"burned"` // amount burned
+}
+
+// TransferDomain gives a name to another owner. Records are cleared so the
+// name does not keep resolving to the previous owner's addresses.
+func (ds *DomainSystem) TransferDomain(msg MsgTransferDomain, now time.Time) (*Domain, error) {
+	if err := msg.ValidateBasic(); err != nil {
+		return nil, err
+	}
+	label, _, _ := Normalize(msg.Name)
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	domain, err := ds.activeDomain(label, now)
+	if err != nil {
+		return nil, err
+	}
+	if _, listed := ds.listings[label]; listed {
+		return nil, fmt.Errorf("%w: cancel the listing of %s first", ErrDomainListed, domain.Name)
+	}
+	if domain.Owner != msg.Sender {
+		return nil, fmt.Errorf("%w: %s", ErrUnauthorized, domain.Name)
+	}
+
+	ds.changeOwner(label, domain, msg.Recipient)
+
+	ds.logger.Info("Domain transferred",
+		zap.String("name", domain.Name),
+		zap.String("from", msg.Sender),
+		zap.String("to", msg.Recipient),
+	)
+
+	return ds.view(domain, now), nil
+}
+
+// ListDomain offers a name for sale at a fixed price. The name moves into
+// module escrow until it is bought or the listing is cancelled.
+func (ds *DomainSystem) ListDomain(msg MsgListDomain, now time.Time) (*Listing, error) {
+	if err := msg.ValidateBasic(); err != nil {
+		return nil, err
+	}
+	label, _, _ := Normalize(msg.Name)
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	domain, err := ds.activeDomain(label, now)
+	if err != nil {
+		return nil, err
+	}
+	if _, listed := ds.listings[label]; listed {
+		return nil, fmt.Errorf("%w: %s", ErrDomainListed, domain.Name)
+	}
+	if domain.Owner != msg.Sender {
+		return nil, fmt.Errorf("%w: %s", ErrUnauthorized, domain.Name)
+	}
+
+	listing := &Listing{
+		Name:     domain.Name,
+		Seller:   msg.Sender,
+		Price:    msg.Price,
+		Denom:    ds.config.FeeDenom,
+		ListedAt: now,
+	}
+	ds.listings[label] = listing
+	domain.Owner = bank.ModuleAddress(ModuleName)
+	ds.changed[listingsKeyPrefix+label] = true
+	ds.changed[namesKeyPrefix+label] = true
+
+	ds.logger.Info("Domain listed",
+		zap.String("name", domain.Name),
+		zap.String("seller", msg.Sender),
+		zap.Uint64("price", msg.Price),
+	)
+
+	copied := *listing
+	return &copied, nil
+}
+
+// CancelListing returns an escrowed name to its seller
+func (ds *DomainSystem) CancelListing(msg MsgCancelListing) error {
+	if err := msg.ValidateBasic(); err != nil {
+		return err
+	}
+	label, _, _ := Normalize(msg.Name)
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	listing, ok := ds.listings[label]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrListingNotFound, FullName(label))
+	}
+	if listing.Seller != msg.Sender {
+		return fmt.Errorf("%w: %s", ErrUnauthorized, listing.Name)
+	}
+
+	ds.domains[label].Owner = listing.Seller
+	delete(ds.listings, label)
+	ds.changed[namesKeyPrefix+label] = true
+	ds.changed[listingsKeyPrefix+label] = true
+
+	ds.logger.Info("Domain listing cancelled", zap.String("name", listing.Name))
+	return nil
+}
+
+// BuyDomain pays the listing price and takes the name out of escrow. The
+// seller receives the price minus the burn share.
+func (ds *DomainSystem) BuyDomain(msg MsgBuyDomain, now time.Time) (*Sale, error) {
+	if err := msg.ValidateBasic(); err != nil {
+		return nil, err
+	}
+	label, _, _ := Normalize(msg.Name)
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	listing, ok := ds.listings[label]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrListingNotFound, FullName(label))
+	}
+	domain, err := ds.activeDomain(label, now)
+	if err != nil {
+		return nil, err
+	}
+	if listing.Price > msg.MaxPrice {
+		return nil, fmt.Errorf("%w: price is %d%s, maximum is %d%s", ErrInvalidBid, listing.Price, listing.Denom, msg.MaxPrice, listing.Denom)
+	}
+	if msg.Buyer == listing.Seller {
+		return nil, fmt.Errorf("seller cannot buy their own listing; cancel it instead")
+	}
+
+	paid, burned, err := ds.settle(msg.Buyer, listing.Seller, listing.Price)
+	if err != nil {
+		return nil, err
+	}
+	delete(ds.listings, label)
+	ds.changed[listingsKeyPrefix+label] = true
+	ds.changeOwner(label, domain, msg.Buyer)
+
+	ds.logger.Info("Domain sold",
+		zap.String("name", domain.Name),
+		zap.String("seller", listing.Seller),
+		zap.String("buyer", msg.Buyer),
+		zap.Uint64("price", listing.Price),
+		zap.Uint64("burned", burned),
+	)
+
+	return &Sale{
+		Name:   domain.Name,
+		Seller: listing.Seller,
+		Buyer:  msg.Buyer,
+		Price:  listing.Price,
+		Paid:   paid,
+		Burned: burned,
+	}, nil
+}
+
+// GetListings returns every open listing ordered by name
+func (ds *DomainSystem) GetListings() []*Listing {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	listings := make([]*Listing, 0, len(ds.listings))
+	for _, listing := range ds.listings {
+		copied := *listing
+		listings = append(listings, &copied)
+	}
+	sort.Slice(listings, func(i, j int) bool { return listings[i].Name < listings[j].Name })
+	return listings
+}
+
+// changeOwner hands a name to a new owner with empty records and no
+// subdomains, so nothing the previous owner set up keeps resolving
+func (ds *DomainSystem) changeOwner(label string, domain *Domain, owner string) {
+	domain.Owner = owner
+	domain.Records = Records{}
+	ds.deleteSubdomains(label)
+	ds.changed[namesKeyPrefix+label] = true
+}
+
+// settle moves a payment from payer to payee, burning the configured share
+func (ds *DomainSystem) settle(payer, payee string, amount uint64) (paid, burned uint64, err error) {
+	denom := ds.config.FeeDenom
+	if balance := ds.bank.GetBalance(payer, denom); balance < amount {
+		return 0, 0, fmt.Errorf("%w: price is %d%s, balance is %d%s", bank.ErrInsufficientFunds, amount, denom, balance, denom)
+	}
+
+	share := uint64(ds.config.BurnShare)
+	burned = amount/100*share + amount%100*share/100
+	paid = amount - burned
+	moduleAddr := bank.ModuleAddress(ModuleName)
+
+	if burned > 0 {
+		if err := ds.bank.Send(payer, moduleAddr, denom, burned); err != nil {
+			return 0, 0, err
+		}
+		if err := ds.bank.Burn(moduleAddr, denom, burned); err != nil {
+			return 0, 0, err
+		}
+	}
+	if paid > 0 {
+		if err := ds.bank.Send(payer, payee, denom, paid); err != nil {
+			return 0, 0, err
+		}
+	}
+	return paid, burned, nil
+}
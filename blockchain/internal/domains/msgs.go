@@ -0,0 +1,421 @@
+package domains
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/vindexchain/blockchain/internal/bank"
+)
+
+// MsgRegisterDomain registers a name for a number of years
+type MsgRegisterDomain struct {
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+	Years uint32 `json:"years"`
+}
+
+// MsgRenewDomain extends a registration; anyone may pay for a renewal
+type MsgRenewDomain struct {
+	Sender string `json:"sender"`
+	Name   string `json:"name"`
+	Years  uint32 `json:"years"`
+}
+
+// MsgSetRecords replaces the records of a name or subdomain
+type MsgSetRecords struct {
+	Sender  string  `json:"sender"`
+	Name    string  `json:"name"`
+	Records Records `json:"records"`
+}
+
+// MsgCreateSubdomain creates a subdomain such as pay.alice.vindex owned by Owner
+type MsgCreateSubdomain struct {
+	Sender string `json:"sender"`
+	Name   string `json:"name"`
+	Owner  string `json:"owner"`
+}
+
+// MsgDeleteSubdomain removes a subdomain
+type MsgDeleteSubdomain struct {
+	Sender string `json:"sender"`
+	Name   string `json:"name"`
+}
+
+// MsgSetPrimaryName sets the name reverse lookups return for the sender
+type MsgSetPrimaryName struct {
+	Sender string `json:"sender"`
+	Name   string `json:"name"`
+}
+
+// MsgTransferDomain gives a name to another address
+type MsgTransferDomain struct {
+	Sender    string `json:"sender"`
+	Name      string `json:"name"`
+	Recipient string `json:"recipient"`
+}
+
+// MsgListDomain offers a name for sale at a fixed price
+type MsgListDomain struct {
+	Sender string `json:"sender"`
+	Name   string `json:"name"`
+	Price  uint64 `json:"price"`
+}
+
+// MsgCancelListing withdraws a name from sale
+type MsgCancelListing struct {
+	Sender string `json:"sender"`
+	Name   string `json:"name"`
+}
+
+// MsgBuyDomain buys a listed name; MaxPrice guards against a changed listing
+type MsgBuyDomain struct {
+	Buyer    string `json:"buyer"`
+	Name     string `json:"name"`
+	MaxPrice uint64 `json:"max_price"`
+}
+
+// MsgStartAuction opens a sealed-bid auction for a premium name
+type MsgStartAuction struct {
+	Sender string `json:"sender"`
+	Name   string `json:"name"`
+}
+
+// MsgCommitBid places a sealed bid; Commitment is the hex output of BidCommitment
+type MsgCommitBid struct {
+	Bidder     string `json:"bidder"`
+	Name       string `json:"name"`
+	Commitment string `json:"commitment"`
+	Deposit    uint64 `json:"deposit"`
+}
+
+// MsgRevealBid opens a sealed bid
+type MsgRevealBid struct {
+	Bidder string `json:"bidder"`
+	Name   string `json:"name"`
+	Amount uint64 `json:"amount"`
+	Salt   string `json:"salt"`
+}
+
+// MsgFinalizeAuction settles an auction after its reveal window; anyone may send it
+type MsgFinalizeAuction struct {
+	Sender string `json:"sender"`
+	Name   string `json:"name"`
+}
+
+// MsgClaimReservedName registers a reserved name; Signature is the claim
+// authority's hex encoded ed25519 signature over ClaimSignBytes
+type MsgClaimReservedName struct {
+	Owner     string `json:"owner"`
+	Name      string `json:"name"`
+	Years     uint32 `json:"years"`
+	Signature string `json:"signature"`
+}
+
+// MsgUpdateReservedNames changes the reserved list; Signature is the
+// governance key's hex encoded ed25519 signature over ReservedUpdateSignBytes
+type MsgUpdateReservedNames struct {
+	Add       []ReservedName `json:"add,omitempty"`
+	Remove    []string       `json:"remove,omitempty"`
+	Nonce     uint64         `json:"nonce"`
+	Signature string         `json:"signature"`
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgRegisterDomain) ValidateBasic() error {
+	if err := bank.ValidateAddress(m.Owner); err != nil {
+		return fmt.Errorf("owner: %w", err)
+	}
+	if _, _, err := Normalize(m.Name); err != nil {
+		return err
+	}
+	if m.Years == 0 {
+		return fmt.Errorf("%w: years must be at least 1", ErrInvalidYears)
+	}
+	return nil
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgRenewDomain) ValidateBasic() error {
+	if err := bank.ValidateAddress(m.Sender); err != nil {
+		return fmt.Errorf("sender: %w", err)
+	}
+	if _, _, err := Normalize(m.Name); err != nil {
+		return err
+	}
+	if m.Years == 0 {
+		return fmt.Errorf("%w: years must be at least 1", ErrInvalidYears)
+	}
+	return nil
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgSetRecords) ValidateBasic() error {
+	if err := bank.ValidateAddress(m.Sender); err != nil {
+		return fmt.Errorf("sender: %w", err)
+	}
+	if _, err := ParseName(m.Name); err != nil {
+		return err
+	}
+	return m.Records.Validate()
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgCreateSubdomain) ValidateBasic() error {
+	if err := bank.ValidateAddress(m.Sender); err != nil {
+		return fmt.Errorf("sender: %w", err)
+	}
+	if err := bank.ValidateAddress(m.Owner); err != nil {
+		return fmt.Errorf("owner: %w", err)
+	}
+	return validateSubdomainName(m.Name)
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgDeleteSubdomain) ValidateBasic() error {
+	if err := bank.ValidateAddress(m.Sender); err != nil {
+		return fmt.Errorf("sender: %w", err)
+	}
+	return validateSubdomainName(m.Name)
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgSetPrimaryName) ValidateBasic() error {
+	if err := bank.ValidateAddress(m.Sender); err != nil {
+		return fmt.Errorf("sender: %w", err)
+	}
+	_, err := ParseName(m.Name)
+	return err
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgTransferDomain) ValidateBasic() error {
+	if err := validateSenderAndName(m.Sender, m.Name); err != nil {
+		return err
+	}
+	if err := bank.ValidateAddress(m.Recipient); err != nil {
+		return fmt.Errorf("recipient: %w", err)
+	}
+	if m.Recipient == m.Sender {
+		return fmt.Errorf("recipient already owns the domain")
+	}
+	return nil
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgListDomain) ValidateBasic() error {
+	if err := validateSenderAndName(m.Sender, m.Name); err != nil {
+		return err
+	}
+	if m.Price == 0 {
+		return bank.ErrInvalidAmount
+	}
+	return nil
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgCancelListing) ValidateBasic() error {
+	return validateSenderAndName(m.Sender, m.Name)
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgBuyDomain) ValidateBasic() error {
+	if err := validateSenderAndName(m.Buyer, m.Name); err != nil {
+		return err
+	}
+	if m.MaxPrice == 0 {
+		return bank.ErrInvalidAmount
+	}
+	return nil
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgStartAuction) ValidateBasic() error {
+	return validateSenderAndName(m.Sender, m.Name)
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgCommitBid) ValidateBasic() error {
+	if err := validateSenderAndName(m.Bidder, m.Name); err != nil {
+		return err
+	}
+	if decoded, err := hex.DecodeString(m.Commitment); err != nil || len(decoded) != sha256.Size {
+		return fmt.Errorf("%w: commitment must be a hex encoded sha256 hash", ErrInvalidBid)
+	}
+	if m.Deposit == 0 {
+		return bank.ErrInvalidAmount
+	}
+	return nil
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgRevealBid) ValidateBasic() error {
+	if err := validateSenderAndName(m.Bidder, m.Name); err != nil {
+		return err
+	}
+	if m.Amount == 0 {
+		return bank.ErrInvalidAmount
+	}
+	if m.Salt == "" {
+		return fmt.Errorf("%w: salt cannot be empty", ErrInvalidBid)
+	}
+	return nil
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgFinalizeAuction) ValidateBasic() error {
+	return validateSenderAndName(m.Sender, m.Name)
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgClaimReservedName) ValidateBasic() error {
+	if err := bank.ValidateAddress(m.Owner); err != nil {
+		return fmt.Errorf("owner: %w", err)
+	}
+	if _, _, err := Normalize(m.Name); err != nil {
+		return err
+	}
+	if m.Years == 0 {
+		return fmt.Errorf("%w: years must be at least 1", ErrInvalidYears)
+	}
+	if decoded, err := hex.DecodeString(m.Signature); err != nil || len(decoded) != ed25519.SignatureSize {
+		return fmt.Errorf("%w: signature must be a hex encoded ed25519 signature", ErrInvalidSignature)
+	}
+	return nil
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgUpdateReservedNames) ValidateBasic() error {
+	if len(m.Add) == 0 && len(m.Remove) == 0 {
+		return fmt.Errorf("nothing to update")
+	}
+	for _, entry := range m.Add {
+		if _, _, err := Normalize(entry.Name); err != nil {
+			return fmt.Errorf("add %q: %w", entry.Name, err)
+		}
+	}
+	for _, name := range m.Remove {
+		if _, _, err := Normalize(name); err != nil {
+			return fmt.Errorf("remove %q: %w", name, err)
+		}
+	}
+	if decoded, err := hex.DecodeString(m.Signature); err != nil || len(decoded) != ed25519.SignatureSize {
+		return fmt.Errorf("%w: signature must be a hex encoded ed25519 signature", ErrInvalidSignature)
+	}
+	return nil
+}
+
+// validateSenderAndName checks the common sender and top-level name fields
+func validateSenderAndName(sender, name string) error {
+	if err := bank.ValidateAddress(sender); err != nil {
+		return fmt.Errorf("sender: %w", err)
+	}
+	_, _, err := Normalize(name)
+	return err
+}
+
+func validateSubdomainName(name string) error {
+	parsed, err := ParseName(name)
+	if err != nil {
+		return err
+	}
+	if parsed.Subdomain == "" {
+		return fmt.Errorf("%w: %q is not a subdomain", ErrInvalidName, name)
+	}
+	return nil
+}
+
+// Type implements accounts.Msg
+func (m MsgRegisterDomain) Type() string { return "domains/register" }
+
+// Signer implements accounts.Msg
+func (m MsgRegisterDomain) Signer() string { return m.Owner }
+
+// Type implements accounts.Msg
+func (m MsgRenewDomain) Type() string { return "domains/renew" }
+
+// Signer implements accounts.Msg
+func (m MsgRenewDomain) Signer() string { return m.Sender }
+
+// Type implements accounts.Msg
+func (m MsgSetRecords) Type() string { return "domains/set-records" }
+
+// Signer implements accounts.Msg
+func (m MsgSetRecords) Signer() string { return m.Sender }
+
+// Type implements accounts.Msg
+func (m MsgCreateSubdomain) Type() string { return "domains/create-subdomain" }
+
+// Signer implements accounts.Msg
+func (m MsgCreateSubdomain) Signer() string { return m.Sender }
+
+// Type implements accounts.Msg
+func (m MsgDeleteSubdomain) Type() string { return "domains/delete-subdomain" }
+
+// Signer implements accounts.Msg
+func (m MsgDeleteSubdomain) Signer() string { return m.Sender }
+
+// Type implements accounts.Msg
+func (m MsgSetPrimaryName) Type() string { return "domains/set-primary-name" }
+
+// Signer implements accounts.Msg
+func (m MsgSetPrimaryName) Signer() string { return m.Sender }
+
+// Type implements accounts.Msg
+func (m MsgTransferDomain) Type() string { return "domains/transfer" }
+
+// Signer implements accounts.Msg
+func (m MsgTransferDomain) Signer() string { return m.Sender }
+
+// Type implements accounts.Msg
+func (m MsgListDomain) Type() string { return "domains/list" }
+
+// Signer implements accounts.Msg
+func (m MsgListDomain) Signer() string { return m.Sender }
+
+// Type implements accounts.Msg
+func (m MsgCancelListing) Type() string { return "domains/cancel-listing" }
+
+// Signer implements accounts.Msg
+func (m MsgCancelListing) Signer() string { return m.Sender }
+
+// Type implements accounts.Msg
+func (m MsgBuyDomain) Type() string { return "domains/buy" }
+
+// Signer implements accounts.Msg
+func (m MsgBuyDomain) Signer() string { return m.Buyer }
+
+// Type implements accounts.Msg
+func (m MsgStartAuction) Type() string { return "domains/start-auction" }
+
+// Signer implements accounts.Msg
+func (m MsgStartAuction) Signer() string { return m.Sender }
+
+// Type implements accounts.Msg
+func (m MsgCommitBid) Type() string { return "domains/commit-bid" }
+
+// Signer implements accounts.Msg
+func (m MsgCommitBid) Signer() string { return m.Bidder }
+
+// Type implements accounts.Msg
+func (m MsgRevealBid) Type() string { return "domains/reveal-bid" }
+
+// Signer implements accounts.Msg
+func (m MsgRevealBid) Signer() string { return m.Bidder }
+
+// Type implements accounts.Msg
+func (m MsgFinalizeAuction) Type() string { return "domains/finalize-auction" }
+
+// Signer implements accounts.Msg
+func (m MsgFinalizeAuction) Signer() string { return m.Sender }
+
+// Type implements accounts.Msg
+func (m MsgClaimReservedName) Type() string { return "domains/claim-reserved-name" }
+
+// Signer implements accounts.Msg
+func (m MsgClaimReservedName) Signer() string { return m.Owner }
+
+// Type names the message in transactions. It has no signer account: the
+// governance signature authorizes it.
+func (m MsgUpdateReservedNames) Type() string { return "domains/update-reserved-names" }
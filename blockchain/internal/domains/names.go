@@ -0,0 +1,163 @@
+package domains
+
+import (
+	"fmt"
+	"math/bits"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+
+	"github.com/vindexchain/blockchain/internal/bank"
+)
+
+const (
+	// TLD is the suffix every name is registered under
+	TLD = "vindex"
+
+	// MinNameLength and MaxNameLength bound a label in characters
+	MinNameLength = 3
+	MaxNameLength = 63
+)
+
+// labelRegexp matches the ASCII (punycode) form of a label
+var labelRegexp = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// PriceTier multiplies the base fees for names of at most MaxLength characters
+type PriceTier struct {
+	MaxLength  int    `json:"max_length"`
+	Multiplier uint64 `json:"multiplier"`
+}
+
+// DefaultPriceTiers make short names progressively more expensive
+var DefaultPriceTiers = []PriceTier{
+	{MaxLength: 3, Multiplier: 100},
+	{MaxLength: 4, Multiplier: 25},
+	{MaxLength: 5, Multiplier: 5},
+}
+
+// Name is a parsed .vindex name, optionally with one subdomain label
+type Name struct {
+	Label            string // ASCII (punycode) form of the registered label
+	Subdomain        string // ASCII form of the subdomain label, empty for a top-level name
+	UnicodeLabel     string
+	UnicodeSubdomain string
+}
+
+// String returns the ASCII form including the TLD
+func (n Name) String() string {
+	if n.Subdomain == "" {
+		return FullName(n.Label)
+	}
+	return n.Subdomain + "." + FullName(n.Label)
+}
+
+// Unicode returns the display form including the TLD
+func (n Name) Unicode() string {
+	if n.UnicodeSubdomain == "" {
+		return FullName(n.UnicodeLabel)
+	}
+	return n.UnicodeSubdomain + "." + FullName(n.UnicodeLabel)
+}
+
+// Normalize converts a user supplied name into its canonical ASCII label and
+// its Unicode display form. The .vindex suffix is optional; internationalized
+// names are mapped with UTS #46 and encoded as punycode.
+func Normalize(name string) (label, unicodeLabel string, err error) {
+	name = trimTLD(name)
+	if strings.Contains(name, ".") {
+		return "", "", fmt.Errorf("%w: %q must be a single label", ErrInvalidName, name)
+	}
+	return normalizeLabel(name, MinNameLength)
+}
+
+// ParseName normalizes a name that may carry one subdomain label, such as
+// pay.alice.vindex
+func ParseName(name string) (Name, error) {
+	parts := strings.Split(trimTLD(name), ".")
+	if len(parts) > 2 {
+		return Name{}, fmt.Errorf("%w: only one subdomain level is supported", ErrInvalidName)
+	}
+
+	var (
+		parsed Name
+		err    error
+	)
+	parsed.Label, parsed.UnicodeLabel, err = normalizeLabel(parts[len(parts)-1], MinNameLength)
+	if err != nil {
+		return Name{}, err
+	}
+	if len(parts) == 2 {
+		parsed.Subdomain, parsed.UnicodeSubdomain, err = normalizeLabel(parts[0], 1)
+		if err != nil {
+			return Name{}, err
+		}
+	}
+	return parsed, nil
+}
+
+// trimTLD strips surrounding space, a trailing dot and the optional .vindex suffix
+func trimTLD(name string) string {
+	name = strings.TrimSuffix(strings.TrimSpace(name), ".")
+	if lower := strings.ToLower(name); strings.HasSuffix(lower, "."+TLD) {
+		name = name[:len(name)-len(TLD)-1]
+	}
+	return name
+}
+
+func normalizeLabel(name string, minLength int) (label, unicodeLabel string, err error) {
+	if name == "" {
+		return "", "", fmt.Errorf("%w: name cannot be empty", ErrInvalidName)
+	}
+
+	label, err = idna.Lookup.ToASCII(name)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrInvalidName, err)
+	}
+	unicodeLabel, err = idna.Lookup.ToUnicode(label)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrInvalidName, err)
+	}
+
+	if n := utf8.RuneCountInString(unicodeLabel); n < minLength || n > MaxNameLength {
+		return "", "", fmt.Errorf("%w: labels must be %d-%d characters", ErrInvalidName, minLength, MaxNameLength)
+	}
+	if len(label) > MaxNameLength || !labelRegexp.MatchString(label) {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	return label, unicodeLabel, nil
+}
+
+// FullName appends the TLD to a label
+func FullName(label string) string {
+	return label + "." + TLD
+}
+
+// multiplier returns the price tier multiplier for a Unicode label
+func multiplier(tiers []PriceTier, unicodeLabel string) uint64 {
+	n := utf8.RuneCountInString(unicodeLabel)
+	for _, tier := range tiers {
+		if n <= tier.MaxLength {
+			return tier.Multiplier
+		}
+	}
+	return 1
+}
+
+// sortTiers orders tiers by length so the tightest match wins
+func sortTiers(tiers []PriceTier) []PriceTier {
+	sorted := append([]PriceTier(nil), tiers...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].MaxLength < sorted[j].MaxLength })
+	return sorted
+}
+
+// mul multiplies fees, failing instead of wrapping around
+func mul(a, b uint64) (uint64, error) {
+	hi, lo := bits.Mul64(a, b)
+	if hi != 0 {
+		return 0, bank.ErrOverflow
+	}
+	return lo, nil
+}
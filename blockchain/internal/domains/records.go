@@ -0,0 +1,369 @@
+package domains
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/bank"
+)
+
+const (
+	maxRecordKeys      = 32
+	maxAddressLength   = 128
+	maxTextLength      = 1024
+	maxContentHashSize = 256
+)
+
+// recordKeyRegexp matches chain identifiers and text record keys
+var recordKeyRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// contentHashPrefixes are the content hash schemes accepted in records
+var contentHashPrefixes = []string{"ipfs://", "ipns://", "ar://", "bzz://", "0x"}
+
+// Records are the typed records a name resolves to
+type Records struct {
+	Address     string            `json:"address,omitempty"`      // primary vindex1... address
+	Addresses   map[string]string `json:"addresses,omitempty"`    // chain (btc, eth, sol, ...) -> address
+	Text        map[string]string `json:"text,omitempty"`         // avatar, url, email, ...
+	ContentHash string            `json:"content_hash,omitempty"` // ipfs://, ipns://, ar://, bzz:// or 0x-hex
+}
+
+// Subdomain is an owner-managed name below a registered domain
+type Subdomain struct {
+	Name        string    `json:"name"`
+	UnicodeName string    `json:"unicode_name"`
+	Owner       string    `json:"owner"`
+	CreatedAt   time.Time `json:"created_at"`
+	Records     Records   `json:"records"`
+}
+
+// Resolution is the result of resolving a name or subdomain
+type Resolution struct {
+	Name        string    `json:"name"`
+	UnicodeName string    `json:"unicode_name"`
+	Owner       string    `json:"owner"`
+	ExpiresAt   time.Time `json:"expires_at"` // expiry of the registered parent name
+	Records     Records   `json:"records"`
+}
+
+// Validate checks record contents
+func (r Records) Validate() error {
+	if r.Address != "" {
+		if err := bank.ValidateAddress(r.Address); err != nil {
+			return fmt.Errorf("address: %w", err)
+		}
+	}
+	if err := validateRecordMap("addresses", r.Addresses, maxAddressLength); err != nil {
+		return err
+	}
+	if err := validateRecordMap("text", r.Text, maxTextLength); err != nil {
+		return err
+	}
+	if r.ContentHash != "" {
+		if len(r.ContentHash) > maxContentHashSize {
+			return fmt.Errorf("content hash cannot exceed %d bytes", maxContentHashSize)
+		}
+		valid := false
+		for _, prefix := range contentHashPrefixes {
+			if strings.HasPrefix(r.ContentHash, prefix) && len(r.ContentHash) > len(prefix) {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("content hash must start with one of %s", strings.Join(contentHashPrefixes, ", "))
+		}
+	}
+	return nil
+}
+
+func validateRecordMap(kind string, records map[string]string, maxValue int) error {
+	if len(records) > maxRecordKeys {
+		return fmt.Errorf("%s: at most %d records", kind, maxRecordKeys)
+	}
+	for key, value := range records {
+		if !recordKeyRegexp.MatchString(key) {
+			return fmt.Errorf("%s: invalid key %q", kind, key)
+		}
+		if value == "" || len(value) > maxValue {
+			return fmt.Errorf("%s: value for %q must be 1-%d bytes", kind, key, maxValue)
+		}
+	}
+	return nil
+}
+
+func (r Records) clone() Records {
+	copied := r
+	copied.Addresses = cloneMap(r.Addresses)
+	copied.Text = cloneMap(r.Text)
+	return copied
+}
+
+func cloneMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+	return copied
+}
+
+// SetRecords replaces the records of a name or subdomain; only its owner may
+// change them
+func (ds *DomainSystem) SetRecords(msg MsgSetRecords, now time.Time) error {
+	if err := msg.ValidateBasic(); err != nil {
+		return err
+	}
+	name, _ := ParseName(msg.Name)
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	domain, err := ds.activeDomain(name.Label, now)
+	if err != nil {
+		return err
+	}
+
+	if name.Subdomain == "" {
+		if domain.Owner != msg.Sender {
+			return fmt.Errorf("%w: %s", ErrUnauthorized, domain.Name)
+		}
+		domain.Records = msg.Records.clone()
+		ds.changed[namesKeyPrefix+name.Label] = true
+	} else {
+		sub, ok := ds.subdomains[name.Label][name.Subdomain]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrDomainNotFound, name)
+		}
+		if sub.Owner != msg.Sender {
+			return fmt.Errorf("%w: %s", ErrUnauthorized, sub.Name)
+		}
+		sub.Records = msg.Records.clone()
+		ds.changed[subdomainKey(name.Label, name.Subdomain)] = true
+	}
+
+	ds.logger.Info("Domain records updated",
+		zap.String("name", name.String()),
+		zap.String("sender", msg.Sender),
+	)
+	return nil
+}
+
+// CreateSubdomain lets a domain owner hand out a subdomain
+func (ds *DomainSystem) CreateSubdomain(msg MsgCreateSubdomain, now time.Time) (*Subdomain, error) {
+	if err := msg.ValidateBasic(); err != nil {
+		return nil, err
+	}
+	name, _ := ParseName(msg.Name)
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	domain, err := ds.activeDomain(name.Label, now)
+	if err != nil {
+		return nil, err
+	}
+	if domain.Owner != msg.Sender {
+		return nil, fmt.Errorf("%w: %s", ErrUnauthorized, domain.Name)
+	}
+	if _, exists := ds.subdomains[name.Label][name.Subdomain]; exists {
+		return nil, fmt.Errorf("%w: %s", ErrSubdomainExists, name)
+	}
+
+	sub := &Subdomain{
+		Name:        name.String(),
+		UnicodeName: name.Unicode(),
+		Owner:       msg.Owner,
+		CreatedAt:   now,
+	}
+	if ds.subdomains[name.Label] == nil {
+		ds.subdomains[name.Label] = make(map[string]*Subdomain)
+	}
+	ds.subdomains[name.Label][name.Subdomain] = sub
+	ds.changed[subdomainKey(name.Label, name.Subdomain)] = true
+
+	ds.logger.Info("Subdomain created",
+		zap.String("name", sub.Name),
+		zap.String("owner", sub.Owner),
+	)
+
+	copied := *sub
+	return &copied, nil
+}
+
+// DeleteSubdomain removes a subdomain; only the parent domain owner may delete it
+func (ds *DomainSystem) DeleteSubdomain(msg MsgDeleteSubdomain, now time.Time) error {
+	if err := msg.ValidateBasic(); err != nil {
+		return err
+	}
+	name, _ := ParseName(msg.Name)
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	domain, err := ds.activeDomain(name.Label, now)
+	if err != nil {
+		return err
+	}
+	if domain.Owner != msg.Sender {
+		return fmt.Errorf("%w: %s", ErrUnauthorized, domain.Name)
+	}
+	if _, ok := ds.subdomains[name.Label][name.Subdomain]; !ok {
+		return fmt.Errorf("%w: %s", ErrDomainNotFound, name)
+	}
+	delete(ds.subdomains[name.Label], name.Subdomain)
+	ds.changed[subdomainKey(name.Label, name.Subdomain)] = true
+
+	ds.logger.Info("Subdomain deleted", zap.String("name", name.String()))
+	return nil
+}
+
+// GetSubdomains returns the subdomains of a registered name ordered by name
+func (ds *DomainSystem) GetSubdomains(name string) ([]*Subdomain, error) {
+	label, _, err := Normalize(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	if _, ok := ds.domains[label]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrDomainNotFound, FullName(label))
+	}
+	subs := make([]*Subdomain, 0, len(ds.subdomains[label]))
+	for _, sub := range ds.subdomains[label] {
+		copied := *sub
+		copied.Records = sub.Records.clone()
+		subs = append(subs, &copied)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].Name < subs[j].Name })
+	return subs, nil
+}
+
+// SetPrimaryName sets the name returned by reverse lookups of the sender.
+// The name must currently resolve to the sender's address.
+func (ds *DomainSystem) SetPrimaryName(msg MsgSetPrimaryName, now time.Time) error {
+	if err := msg.ValidateBasic(); err != nil {
+		return err
+	}
+	name, _ := ParseName(msg.Name)
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	resolution, err := ds.resolve(name, now)
+	if err != nil {
+		return err
+	}
+	if resolution.Records.Address != msg.Sender {
+		return fmt.Errorf("%s does not resolve to %s", resolution.Name, msg.Sender)
+	}
+	ds.primary[msg.Sender] = resolution.Name
+	ds.changed[primaryKeyPrefix+msg.Sender] = true
+
+	ds.logger.Info("Primary name set",
+		zap.String("address", msg.Sender),
+		zap.String("name", resolution.Name),
+	)
+	return nil
+}
+
+// Resolve returns the records of an active name or subdomain
+func (ds *DomainSystem) Resolve(name string) (*Resolution, error) {
+	parsed, err := ParseName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	return ds.resolve(parsed, time.Now().UTC())
+}
+
+// ResolveAddress resolves a name to its primary vindex1 address
+func (ds *DomainSystem) ResolveAddress(name string) (string, error) {
+	resolution, err := ds.Resolve(name)
+	if err != nil {
+		return "", err
+	}
+	if resolution.Records.Address == "" {
+		return "", fmt.Errorf("%w: %s has no address record", ErrDomainNotFound, resolution.Name)
+	}
+	return resolution.Records.Address, nil
+}
+
+// ReverseLookup returns the primary name of an address. A primary name only
+// counts while it still resolves back to the address.
+func (ds *DomainSystem) ReverseLookup(address string) (*Resolution, error) {
+	if err := bank.ValidateAddress(address); err != nil {
+		return nil, err
+	}
+
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	name, ok := ds.primary[address]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoPrimaryName, address)
+	}
+	parsed, err := ParseName(name)
+	if err != nil {
+		return nil, err
+	}
+	resolution, err := ds.resolve(parsed, time.Now().UTC())
+	if err != nil || resolution.Records.Address != address {
+		return nil, fmt.Errorf("%w: %s", ErrNoPrimaryName, address)
+	}
+	return resolution, nil
+}
+
+func (ds *DomainSystem) resolve(name Name, now time.Time) (*Resolution, error) {
+	domain, err := ds.activeDomain(name.Label, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if name.Subdomain == "" {
+		return &Resolution{
+			Name:        domain.Name,
+			UnicodeName: domain.UnicodeName,
+			Owner:       domain.Owner,
+			ExpiresAt:   domain.ExpiresAt,
+			Records:     domain.Records.clone(),
+		}, nil
+	}
+
+	sub, ok := ds.subdomains[name.Label][name.Subdomain]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrDomainNotFound, name)
+	}
+	return &Resolution{
+		Name:        sub.Name,
+		UnicodeName: sub.UnicodeName,
+		Owner:       sub.Owner,
+		ExpiresAt:   domain.ExpiresAt,
+		Records:     sub.Records.clone(),
+	}, nil
+}
+
+// activeDomain returns a registered name that has not expired. Names in their
+// grace period stop resolving and cannot be edited until renewed.
+func (ds *DomainSystem) activeDomain(label string, now time.Time) (*Domain, error) {
+	domain, ok := ds.domains[label]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrDomainNotFound, FullName(label))
+	}
+	if ds.status(domain, now) != StatusActive {
+		return nil, fmt.Errorf("%w: %s", ErrDomainExpired, domain.Name)
+	}
+	return domain, nil
+}
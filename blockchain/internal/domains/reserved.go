@@ -0,0 +1,337 @@
+package domains
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Availability reasons reported for names that cannot be registered
+const (
+	ReasonRegistered  = "registered"
+	ReasonGracePeriod = "grace_period"
+	ReasonReserved    = "reserved"
+	ReasonConfusable  = "confusable"
+	ReasonPremium     = "premium"      // only sold at auction
+	ReasonAuctionOpen = "auction_open" // an auction is already running
+)
+
+// ReservedName is a name withheld from open registration, such as a
+// trademark. It can only be claimed with a signature from the claim authority.
+type ReservedName struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Genesis is the domains section of the genesis file
+type Genesis struct {
+	Reserved       []ReservedName `json:"reserved"`
+	ClaimAuthority string         `json:"claim_authority"` // hex encoded ed25519 public key
+	GovernanceKey  string         `json:"governance_key"`  // hex encoded ed25519 public key
+}
+
+// Availability explains whether a name can be registered and why not
+type Availability struct {
+	Name        string  `json:"name"`
+	UnicodeName string  `json:"unicode_name"`
+	Available   bool    `json:"available"`
+	Reason      string  `json:"reason,omitempty"`
+	Detail      string  `json:"detail,omitempty"`
+	Domain      *Domain `json:"domain,omitempty"`
+}
+
+// LoadGenesis reads the domains section (app_state.domains) of a genesis file
+func LoadGenesis(path string) (*Genesis, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc struct {
+		AppState struct {
+			Domains Genesis `json:"domains"`
+		} `json:"app_state"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse genesis file %s: %w", path, err)
+	}
+	if err := doc.AppState.Domains.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid domains genesis in %s: %w", path, err)
+	}
+	return &doc.AppState.Domains, nil
+}
+
+// Validate checks the reserved names and keys. A genesis without a domains
+// section is empty and valid.
+func (g *Genesis) Validate() error {
+	for _, entry := range g.Reserved {
+		if _, _, err := Normalize(entry.Name); err != nil {
+			return fmt.Errorf("reserved name %q: %w", entry.Name, err)
+		}
+	}
+	for _, key := range []struct{ field, value string }{
+		{"claim_authority", g.ClaimAuthority},
+		{"governance_key", g.GovernanceKey},
+	} {
+		if key.value == "" {
+			continue
+		}
+		if b, err := hex.DecodeString(key.value); err != nil || len(b) != ed25519.PublicKeySize {
+			return fmt.Errorf("%s must be a hex encoded ed25519 public key", key.field)
+		}
+	}
+	return nil
+}
+
+// ClaimSignBytes returns the message the claim authority signs to let owner
+// register a reserved name for years
+func ClaimSignBytes(name, owner string, years uint32) ([]byte, error) {
+	label, _, err := Normalize(name)
+	if err != nil {
+		return nil, err
+	}
+	return claimSignBytes(label, owner, years), nil
+}
+
+func claimSignBytes(label, owner string, years uint32) []byte {
+	return []byte("vindex-reserved-claim|" + FullName(label) + "|" + owner + "|" + strconv.FormatUint(uint64(years), 10))
+}
+
+// ClaimReservedName registers a reserved name for the holder named in the
+// claim authority's signature
+func (ds *DomainSystem) ClaimReservedName(msg MsgClaimReservedName, now time.Time) (*Domain, error) {
+	if err := msg.ValidateBasic(); err != nil {
+		return nil, err
+	}
+	if msg.Years > ds.config.MaxYears {
+		return nil, fmt.Errorf("%w: at most %d years", ErrInvalidYears, ds.config.MaxYears)
+	}
+	label, unicodeLabel, _ := Normalize(msg.Name)
+	signature, _ := hex.DecodeString(msg.Signature)
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if _, reserved := ds.reserved[label]; !reserved {
+		return nil, fmt.Errorf("%s is not reserved; register it directly", FullName(label))
+	}
+	if ds.claimKey == nil {
+		return nil, fmt.Errorf("%w: no claim authority is configured", ErrInvalidSignature)
+	}
+	if !ed25519.Verify(ds.claimKey, claimSignBytes(label, msg.Owner, msg.Years), signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	if err := ds.checkAvailable(label, now); err != nil {
+		return nil, err
+	}
+	price, err := ds.registrationPrice(unicodeLabel, msg.Years)
+	if err != nil {
+		return nil, err
+	}
+	if err := ds.chargeFee(msg.Owner, price); err != nil {
+		return nil, err
+	}
+	domain := ds.assign(label, unicodeLabel, msg.Owner, msg.Years, now)
+
+	ds.logger.Info("Reserved domain claimed",
+		zap.String("name", domain.Name),
+		zap.String("owner", domain.Owner),
+		zap.Uint32("years", msg.Years),
+	)
+
+	return ds.view(domain, now), nil
+}
+
+// ReservedUpdateSignBytes returns the message the governance key signs to
+// apply an update of the reserved list
+func ReservedUpdateSignBytes(msg MsgUpdateReservedNames) ([]byte, error) {
+	data, err := json.Marshal(struct {
+		Nonce  uint64         `json:"nonce"`
+		Add    []ReservedName `json:"add"`
+		Remove []string       `json:"remove"`
+	}{msg.Nonce, msg.Add, msg.Remove})
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte("vindex-reserved-update|"), data...), nil
+}
+
+// UpdateReservedNames adds and removes reserved names. The update must be
+// signed by the governance key and carry the next update nonce, so a
+// signed update is applied once.
+func (ds *DomainSystem) UpdateReservedNames(msg MsgUpdateReservedNames) error {
+	if err := msg.ValidateBasic(); err != nil {
+		return err
+	}
+	signBytes, err := ReservedUpdateSignBytes(msg)
+	if err != nil {
+		return err
+	}
+	signature, _ := hex.DecodeString(msg.Signature)
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if ds.governanceKey == nil {
+		return fmt.Errorf("%w: no governance key is configured", ErrInvalidSignature)
+	}
+	if !ed25519.Verify(ds.governanceKey, signBytes, signature) {
+		return ErrInvalidSignature
+	}
+	if msg.Nonce != ds.reservedNonce {
+		return fmt.Errorf("%w: update nonce is %d, expected %d", ErrInvalidSignature, msg.Nonce, ds.reservedNonce)
+	}
+	for _, name := range msg.Remove {
+		label, _, _ := Normalize(name)
+		ds.removeReserved(label)
+	}
+	for _, entry := range msg.Add {
+		if err := ds.addReserved(entry); err != nil {
+			return err
+		}
+	}
+
+	ds.reservedNonce++
+	ds.changed[reservedNonceKey] = true
+
+	ds.logger.Info("Reserved names updated",
+		zap.Uint64("nonce", msg.Nonce),
+		zap.Int("added", len(msg.Add)),
+		zap.Int("removed", len(msg.Remove)),
+	)
+	return nil
+}
+
+// ReservedUpdateNonce returns the nonce the next reserved list update must
+// be signed with
+func (ds *DomainSystem) ReservedUpdateNonce() uint64 {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return ds.reservedNonce
+}
+
+// GetReservedNames returns the reserved names ordered by name
+func (ds *DomainSystem) GetReservedNames() []ReservedName {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	names := make([]ReservedName, 0, len(ds.reserved))
+	for _, entry := range ds.reserved {
+		names = append(names, *entry)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i].Name < names[j].Name })
+	return names
+}
+
+// Availability reports whether a name can be registered directly and, if
+// not, why
+func (ds *DomainSystem) Availability(name string) (*Availability, error) {
+	label, unicodeLabel, err := Normalize(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	now := time.Now().UTC()
+	result := &Availability{Name: FullName(label), UnicodeName: FullName(unicodeLabel), Available: true}
+	if domain, ok := ds.domains[label]; ok {
+		result.Domain = ds.view(domain, now)
+	}
+
+	err = ds.checkRegistrable(label, unicodeLabel, now)
+	switch {
+	case err == nil && ds.isPremium(unicodeLabel):
+		if _, open := ds.auctions[label]; open {
+			result.Reason = ReasonAuctionOpen
+			err = fmt.Errorf("an auction for %s is running", result.Name)
+		} else {
+			result.Reason = ReasonPremium
+			err = ErrAuctionRequired
+		}
+	case errors.Is(err, ErrDomainTaken):
+		result.Reason = ReasonRegistered
+		if result.Domain.Status == StatusGrace {
+			result.Reason = ReasonGracePeriod
+		}
+	case errors.Is(err, ErrNameReserved):
+		result.Reason = ReasonReserved
+	case errors.Is(err, ErrConfusable):
+		result.Reason = ReasonConfusable
+	}
+	if err != nil {
+		result.Available = false
+		result.Detail = err.Error()
+	}
+	return result, nil
+}
+
+// checkRegistrable runs the checks shared by registrations and auctions:
+// the name must be free, not reserved and not confusable with a reserved or
+// registered name
+func (ds *DomainSystem) checkRegistrable(label, unicodeLabel string, now time.Time) error {
+	if err := ds.checkAvailable(label, now); err != nil {
+		return err
+	}
+	if entry, reserved := ds.reserved[label]; reserved {
+		return fmt.Errorf("%w: %s (%s)", ErrNameReserved, entry.Name, entry.Reason)
+	}
+	if err := checkScripts(unicodeLabel); err != nil {
+		return err
+	}
+
+	sk := skeleton(unicodeLabel)
+	if other, ok := ds.reservedSkeletons[sk]; ok && other != label {
+		return fmt.Errorf("%w: looks like reserved name %s", ErrConfusable, FullName(other))
+	}
+	for _, other := range ds.skeletons[sk] {
+		if other == label {
+			continue
+		}
+		domain, ok := ds.domains[other]
+		if ok && comparableSkeletons(unicodeLabel, domain.UnicodeName) && ds.status(domain, now) != StatusExpired {
+			return fmt.Errorf("%w: looks like registered name %s", ErrConfusable, domain.Name)
+		}
+	}
+	return nil
+}
+
+func (ds *DomainSystem) addReserved(entry ReservedName) error {
+	label, unicodeLabel, err := Normalize(entry.Name)
+	if err != nil {
+		return fmt.Errorf("reserved name %q: %w", entry.Name, err)
+	}
+	if entry.Reason == "" {
+		entry.Reason = "reserved"
+	}
+	entry.Name = FullName(label)
+
+	ds.removeReserved(label)
+	ds.reserved[label] = &entry
+	ds.reservedSkeletons[skeleton(unicodeLabel)] = label
+	ds.changed[reservedKeyPrefix+label] = true
+	return nil
+}
+
+func (ds *DomainSystem) removeReserved(label string) {
+	if _, ok := ds.reserved[label]; !ok {
+		return
+	}
+	delete(ds.reserved, label)
+	ds.changed[reservedKeyPrefix+label] = true
+	for sk, other := range ds.reservedSkeletons {
+		if other == label {
+			delete(ds.reservedSkeletons, sk)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package domains
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadGenesis(t *testing.T) {
+	key := strings.Repeat("ab", 32)
+	tests := []struct {
+		name         string
+		file         string // no file is written when empty
+		wantErr      bool
+		wantReserved int
+	}{
+		{name: "missing file", wantErr: true},
+		{name: "malformed", file: `{"app_state": {"domains": `, wantErr: true},
+		{name: "wrong type", file: `{"app_state": {"domains": {"reserved": "google"}}}`, wantErr: true},
+		{name: "no domains section", file: `{"app_state": {"bank": {}}}`},
+		{name: "no app state", file: `{"chain_id": "vindexchain-1"}`},
+		{
+			name:         "reserved names and keys",
+			file:         `{"app_state": {"domains": {"reserved": [{"name": "google"}, {"name": "bank.vindex"}], "claim_authority": "` + key + `", "governance_key": "` + key + `"}}}`,
+			wantReserved: 2,
+		},
+		{name: "invalid reserved name", file: `{"app_state": {"domains": {"reserved": [{"name": "-bad-"}]}}}`, wantErr: true},
+		{name: "short claim authority", file: `{"app_state": {"domains": {"claim_authority": "abcd"}}}`, wantErr: true},
+		{name: "governance key not hex", file: `{"app_state": {"domains": {"governance_key": "` + strings.Repeat("zz", 32) + `"}}}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "genesis.json")
+			if tt.file != "" {
+				if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			genesis, err := LoadGenesis(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadGenesis = %v; want error %v", err, tt.wantErr)
+			}
+			if err == nil && len(genesis.Reserved) != tt.wantReserved {
+				t.Errorf("%d reserved names; want %d", len(genesis.Reserved), tt.wantReserved)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package domains
+
+import (
+	"time"
+
+	"github.com/vindexchain/blockchain/internal/app"
+)
+
+// RegisterRoutes routes the name, record, market and auction messages of
+// blocks to the domain system
+func RegisterRoutes(r *app.Router, ds *DomainSystem) {
+	app.Handle(r, func(ctx *app.Context, msg MsgRegisterDomain) error {
+		domain, err := ds.RegisterDomain(msg, ctx.Time)
+		return emitDomain(ctx, "register_domain", domain, err)
+	})
+	app.Handle(r, func(ctx *app.Context, msg MsgRenewDomain) error {
+		domain, err := ds.RenewDomain(msg, ctx.Time)
+		return emitDomain(ctx, "renew_domain", domain, err)
+	})
+	app.Handle(r, func(ctx *app.Context, msg MsgClaimReservedName) error {
+		domain, err := ds.ClaimReservedName(msg, ctx.Time)
+		return emitDomain(ctx, "claim_reserved_name", domain, err)
+	})
+	app.Handle(r, func(ctx *app.Context, msg MsgTransferDomain) error {
+		domain, err := ds.TransferDomain(msg, ctx.Time)
+		return emitDomain(ctx, "transfer_domain", domain, err)
+	})
+	app.Handle(r, func(ctx *app.Context, msg MsgSetRecords) error { return ds.SetRecords(msg, ctx.Time) })
+	app.Handle(r, func(ctx *app.Context, msg MsgCreateSubdomain) error {
+		_, err := ds.CreateSubdomain(msg, ctx.Time)
+		return err
+	})
+	app.Handle(r, func(ctx *app.Context, msg MsgDeleteSubdomain) error { return ds.DeleteSubdomain(msg, ctx.Time) })
+	app.Handle(r, func(ctx *app.Context, msg MsgSetPrimaryName) error { return ds.SetPrimaryName(msg, ctx.Time) })
+
+	app.Handle(r, func(ctx *app.Context, msg MsgListDomain) error {
+		_, err := ds.ListDomain(msg, ctx.Time)
+		return err
+	})
+	app.Handle(r, func(ctx *app.Context, msg MsgCancelListing) error { return ds.CancelListing(msg) })
+	app.Handle(r, func(ctx *app.Context, msg MsgBuyDomain) error {
+		sale, err := ds.BuyDomain(msg, ctx.Time)
+		if err != nil {
+			return err
+		}
+		ctx.EmitEvent("buy_domain",
+			app.Attribute("name", sale.Name),
+			app.Attribute("seller", sale.Seller),
+			app.Attribute("buyer", sale.Buyer),
+			app.Attribute("price", sale.Price),
+			app.Attribute("burned", sale.Burned),
+		)
+		return nil
+	})
+
+	app.Handle(r, func(ctx *app.Context, msg MsgStartAuction) error {
+		auction, err := ds.StartAuction(msg, ctx.Time)
+		if err != nil {
+			return err
+		}
+		ctx.EmitEvent("start_auction",
+			app.Attribute("name", auction.Name),
+			app.Attribute("commit_end", auction.CommitEnd.Format(time.RFC3339)),
+			app.Attribute("reveal_end", auction.RevealEnd.Format(time.RFC3339)),
+		)
+		return nil
+	})
+	app.Handle(r, func(ctx *app.Context, msg MsgCommitBid) error { return ds.CommitBid(msg, ctx.Time) })
+	app.Handle(r, func(ctx *app.Context, msg MsgRevealBid) error { return ds.RevealBid(msg, ctx.Time) })
+	app.Handle(r, func(ctx *app.Context, msg MsgFinalizeAuction) error {
+		result, err := ds.FinalizeAuction(msg, ctx.Time)
+		if err != nil {
+			return err
+		}
+		ctx.EmitEvent("finalize_auction",
+			app.Attribute("name", result.Name),
+			app.Attribute("winner", result.Winner),
+			app.Attribute("price", result.Price),
+			app.Attribute("forfeited", result.Forfeits),
+		)
+		return nil
+	})
+
+	// The governance signature and nonce the update carries authorize it
+	app.HandleUnsigned(r, MsgUpdateReservedNames{}.Type(), func(ctx *app.Context, msg MsgUpdateReservedNames) error {
+		return ds.UpdateReservedNames(msg)
+	})
+}
+
+func emitDomain(ctx *app.Context, eventType string, domain *Domain, err error) error {
+	if err != nil {
+		return err
+	}
+	ctx.EmitEvent(eventType,
+		app.Attribute("name", domain.Name),
+		app.Attribute("owner", domain.Owner),
+		app.Attribute("expires_at", domain.ExpiresAt.Format(time.RFC3339)),
+	)
+	return nil
+}
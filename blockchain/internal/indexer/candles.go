@@ -0,0 +1,80 @@
+package indexer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/vindexchain/blockchain/internal/dex"
+)
+
+// Intervals are the candle resolutions maintained for every pool
+var Intervals = map[string]time.Duration{
+	"1m": time.Minute,
+	"5m": 5 * time.Minute,
+	"1h": time.Hour,
+	"1d": 24 * time.Hour,
+}
+
+// Candle is an OHLCV bar for a pool. Prices are the pool's DenomA quoted in
+// DenomB; VolumeA and VolumeB are the traded amounts of each side.
+type Candle struct {
+	PoolID   uint64    `json:"pool_id"`
+	Interval string    `json:"interval"`
+	OpenTime time.Time `json:"open_time"`
+	Open     float64   `json:"open"`
+	High     float64   `json:"high"`
+	Low      float64   `json:"low"`
+	Close    float64   `json:"close"`
+	VolumeA  uint64    `json:"volume_a"`
+	VolumeB  uint64    `json:"volume_b"`
+	Trades   uint64    `json:"trades"`
+}
+
+// ParseInterval validates an interval name
+func ParseInterval(interval string) (time.Duration, error) {
+	d, ok := Intervals[interval]
+	if !ok {
+		return 0, fmt.Errorf("unsupported interval %q (use 1m, 5m, 1h or 1d)", interval)
+	}
+	return d, nil
+}
+
+// tradeVolumes returns the amounts of the pool's DenomA and DenomB in a trade
+func tradeVolumes(trade dex.Trade) (uint64, uint64) {
+	if trade.Type == dex.TradeSell {
+		return trade.AmountIn, trade.AmountOut
+	}
+	return trade.AmountOut, trade.AmountIn
+}
+
+// newCandle opens a candle for the bucket a trade falls into
+func newCandle(trade dex.Trade, interval string, d time.Duration) *Candle {
+	volumeA, volumeB := tradeVolumes(trade)
+	return &Candle{
+		PoolID:   trade.PoolID,
+		Interval: interval,
+		OpenTime: trade.Timestamp.UTC().Truncate(d),
+		Open:     trade.Price,
+		High:     trade.Price,
+		Low:      trade.Price,
+		Close:    trade.Price,
+		VolumeA:  volumeA,
+		VolumeB:  volumeB,
+		Trades:   1,
+	}
+}
+
+// add folds a trade into the candle
+func (c *Candle) add(trade dex.Trade) {
+	volumeA, volumeB := tradeVolumes(trade)
+	if trade.Price > c.High {
+		c.High = trade.Price
+	}
+	if trade.Price < c.Low {
+		c.Low = trade.Price
+	}
+	c.Close = trade.Price
+	c.VolumeA += volumeA
+	c.VolumeB += volumeB
+	c.Trades++
+}
@@ -0,0 +1,213 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/database"
+	"github.com/vindexchain/blockchain/internal/dex"
+)
+
+const maxCandles = 1000
+
+// Config holds DEX indexer parameters
+type Config struct {
+	Database database.Database
+	Logger   *zap.Logger
+}
+
+type candleKey struct {
+	poolID   uint64
+	interval string
+}
+
+type subscription struct {
+	key candleKey
+	ch  chan Candle
+}
+
+// tradeStore persists trades and the candles built from them. Trades are
+// stored under their IDs; persist skips the ones already stored.
+type tradeStore interface {
+	persist(ctx context.Context, trades []dex.Trade) error
+	candles(ctx context.Context, poolID uint64, interval string, from, to time.Time) ([]Candle, error)
+	trades(ctx context.Context, poolID, cursor uint64, limit int) ([]dex.Trade, error)
+}
+
+// DexIndexer persists DEX trades, maintains OHLCV candles and fans out live
+// candle updates to subscribers
+type DexIndexer struct {
+	config *Config
+	store  tradeStore
+	logger *zap.Logger
+
+	pendingMu sync.Mutex
+	pending   []dex.Trade // executed since the last flush, oldest first
+
+	mu      sync.Mutex
+	live    map[candleKey]*Candle
+	subs    map[uint64]*subscription
+	nextSub uint64
+}
+
+// NewDexIndexer creates a new DEX indexer. Trades and candles go to tables
+// on SQL backends and to the key-value store on the others.
+func NewDexIndexer(cfg *Config) *DexIndexer {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	var store tradeStore
+	if db := cfg.Database.SQL(); db != nil {
+		store = newSQLTradeStore(db)
+	} else {
+		store = newKVTradeStore(cfg.Database)
+	}
+
+	return &DexIndexer{
+		config: cfg,
+		store:  store,
+		logger: logger,
+		live:   make(map[candleKey]*Candle),
+		subs:   make(map[uint64]*subscription),
+	}
+}
+
+// Start starts serving live candles
+func (ix *DexIndexer) Start() error {
+	ix.logger.Info("DEX indexer started", zap.String("backend", ix.config.Database.Backend()))
+	return nil
+}
+
+// Stop closes the live candle subscriptions. Trades not flushed belong to a
+// block that was not committed and are dropped; the block executes them
+// again. The database is left open for its owner to close.
+func (ix *DexIndexer) Stop() {
+	ix.mu.Lock()
+	for id, sub := range ix.subs {
+		close(sub.ch)
+		delete(ix.subs, id)
+	}
+	ix.mu.Unlock()
+
+	ix.logger.Info("DEX indexer stopped")
+}
+
+// HandleTrade is registered as a dex.TradeListener. It updates the live
+// candles right away and holds the trade until the block executing it is
+// committed, since the DEX keeper calls it while locked.
+func (ix *DexIndexer) HandleTrade(trade dex.Trade) {
+	ix.updateLive(trade)
+
+	ix.pendingMu.Lock()
+	ix.pending = append(ix.pending, trade)
+	ix.pendingMu.Unlock()
+}
+
+// Flush persists the trades executed since the last flush. It runs before
+// the state commits version, the block executing them, so an error aborts
+// the commit instead of losing trades. A block applied again after a crash
+// executes its trades under the same IDs, and the stored ones are skipped.
+func (ix *DexIndexer) Flush(version int64) error {
+	ix.pendingMu.Lock()
+	defer ix.pendingMu.Unlock()
+
+	if len(ix.pending) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := ix.store.persist(ctx, ix.pending); err != nil {
+		return fmt.Errorf("failed to index %d DEX trades of block %d: %w", len(ix.pending), version, err)
+	}
+	ix.pending = nil
+	return nil
+}
+
+// Subscribe streams live candle updates for a pool and interval. The returned
+// function cancels the subscription.
+func (ix *DexIndexer) Subscribe(poolID uint64, interval string) (<-chan Candle, func(), error) {
+	if _, err := ParseInterval(interval); err != nil {
+		return nil, nil, err
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	id := ix.nextSub
+	ix.nextSub++
+	sub := &subscription{
+		key: candleKey{poolID: poolID, interval: interval},
+		ch:  make(chan Candle, 16),
+	}
+	ix.subs[id] = sub
+
+	cancel := func() {
+		ix.mu.Lock()
+		defer ix.mu.Unlock()
+		if _, ok := ix.subs[id]; ok {
+			close(sub.ch)
+			delete(ix.subs, id)
+		}
+	}
+	return sub.ch, cancel, nil
+}
+
+// Candles returns stored candles with OpenTime in [from, to)
+func (ix *DexIndexer) Candles(ctx context.Context, poolID uint64, interval string, from, to time.Time) ([]Candle, error) {
+	if _, err := ParseInterval(interval); err != nil {
+		return nil, err
+	}
+	return ix.store.candles(ctx, poolID, interval, from.UTC(), to.UTC())
+}
+
+// Trades pages through stored trades newest first. A zero poolID matches every
+// pool; cursor is the ID of the last trade of the previous page (0 for the
+// first page). The returned cursor is 0 when there are no more trades.
+func (ix *DexIndexer) Trades(ctx context.Context, poolID, cursor uint64, limit int) ([]dex.Trade, uint64, error) {
+	trades, err := ix.store.trades(ctx, poolID, cursor, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var next uint64
+	if len(trades) == limit {
+		next = trades[len(trades)-1].ID
+	}
+	return trades, next, nil
+}
+
+// updateLive folds a trade into the in-memory candles and notifies subscribers
+func (ix *DexIndexer) updateLive(trade dex.Trade) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	for interval, d := range Intervals {
+		key := candleKey{poolID: trade.PoolID, interval: interval}
+		candle, ok := ix.live[key]
+		if ok && candle.OpenTime.Equal(trade.Timestamp.UTC().Truncate(d)) {
+			candle.add(trade)
+		} else {
+			candle = newCandle(trade, interval, d)
+			ix.live[key] = candle
+		}
+
+		for _, sub := range ix.subs {
+			if sub.key != key {
+				continue
+			}
+			select {
+			case sub.ch <- *candle:
+			default:
+				// Slow subscriber; it will catch up on the next update
+			}
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package indexer
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/vindexchain/blockchain/internal/dex"
+	"github.com/vindexchain/blockchain/internal/kv"
+)
+
+// Trades are keyed by their inverted ID so that ascending iteration returns
+// the newest first
+var (
+	tradePrefix       = []byte("dex/trades/")
+	poolTradePrefix   = []byte("dex/pool_trades/")
+	candlePrefix      = []byte("dex/candles/")
+	errPageFull       = errors.New("page full")
+	errCandleRangeEnd = errors.New("end of candle range")
+)
+
+// kvTradeStore keeps trades and candles in the key-value store of backends
+// without SQL, such as single-node devnets
+type kvTradeStore struct {
+	db kv.DB
+}
+
+func newKVTradeStore(db kv.DB) *kvTradeStore {
+	return &kvTradeStore{db: db}
+}
+
+func (s *kvTradeStore) persist(_ context.Context, trades []dex.Trade) error {
+	return s.db.Update(func(tx kv.Tx) error {
+		for _, trade := range trades {
+			stored, err := tx.Get(tradeKey(trade.ID))
+			if err != nil {
+				return err
+			}
+			if stored != nil {
+				continue
+			}
+
+			trade.Timestamp = trade.Timestamp.UTC()
+			data, err := json.Marshal(trade)
+			if err != nil {
+				return err
+			}
+			if err := tx.Set(tradeKey(trade.ID), data); err != nil {
+				return err
+			}
+			if err := tx.Set(poolTradeKey(trade.PoolID, trade.ID), binary.BigEndian.AppendUint64(nil, trade.ID)); err != nil {
+				return err
+			}
+			if err := addCandles(tx, trade); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// addCandles folds a trade into its stored candle of every interval
+func addCandles(tx kv.Tx, trade dex.Trade) error {
+	for interval, d := range Intervals {
+		candle := newCandle(trade, interval, d)
+		key := candleStoreKey(trade.PoolID, interval, candle.OpenTime)
+		stored, err := tx.Get(key)
+		if err != nil {
+			return err
+		}
+		if stored != nil {
+			var existing Candle
+			if err := json.Unmarshal(stored, &existing); err != nil {
+				return err
+			}
+			existing.add(trade)
+			candle = &existing
+		}
+		data, err := json.Marshal(candle)
+		if err != nil {
+			return err
+		}
+		if err := tx.Set(key, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *kvTradeStore) candles(_ context.Context, poolID uint64, interval string, from, to time.Time) ([]Candle, error) {
+	candles := make([]Candle, 0)
+	err := s.db.Iterate(candleRangePrefix(poolID, interval), func(key, value []byte) error {
+		openTime := time.Unix(int64(binary.BigEndian.Uint64(key[len(key)-8:])), 0).UTC()
+		if openTime.Before(from) {
+			return nil
+		}
+		if !openTime.Before(to) || len(candles) == maxCandles {
+			return errCandleRangeEnd
+		}
+		var candle Candle
+		if err := json.Unmarshal(value, &candle); err != nil {
+			return err
+		}
+		candles = append(candles, candle)
+		return nil
+	})
+	if errors.Is(err, errCandleRangeEnd) {
+		err = nil
+	}
+	return candles, err
+}
+
+func (s *kvTradeStore) trades(_ context.Context, poolID, cursor uint64, limit int) ([]dex.Trade, error) {
+	trades := make([]dex.Trade, 0, limit)
+	if poolID == 0 {
+		err := s.db.Iterate(tradePrefix, func(key, value []byte) error {
+			if cursor != 0 && tradeIDFromKey(key) >= cursor {
+				return nil
+			}
+			if len(trades) == limit {
+				return errPageFull
+			}
+			var trade dex.Trade
+			if err := json.Unmarshal(value, &trade); err != nil {
+				return err
+			}
+			trades = append(trades, trade)
+			return nil
+		})
+		if errors.Is(err, errPageFull) {
+			err = nil
+		}
+		return trades, err
+	}
+
+	ids := make([]uint64, 0, limit)
+	err := s.db.Iterate(poolTradePrefixFor(poolID), func(key, _ []byte) error {
+		id := tradeIDFromKey(key)
+		if cursor != 0 && id >= cursor {
+			return nil
+		}
+		if len(ids) == limit {
+			return errPageFull
+		}
+		ids = append(ids, id)
+		return nil
+	})
+	if err != nil && !errors.Is(err, errPageFull) {
+		return nil, err
+	}
+	for _, id := range ids {
+		data, err := s.db.Get(tradeKey(id))
+		if err != nil {
+			return nil, err
+		}
+		var trade dex.Trade
+		if err := json.Unmarshal(data, &trade); err != nil {
+			return nil, err
+		}
+		trades = append(trades, trade)
+	}
+	return trades, nil
+}
+
+func tradeKey(id uint64) []byte {
+	return binary.BigEndian.AppendUint64(append([]byte{}, tradePrefix...), math.MaxUint64-id)
+}
+
+func poolTradePrefixFor(poolID uint64) []byte {
+	return binary.BigEndian.AppendUint64(append([]byte{}, poolTradePrefix...), poolID)
+}
+
+func poolTradeKey(poolID, id uint64) []byte {
+	return binary.BigEndian.AppendUint64(poolTradePrefixFor(poolID), math.MaxUint64-id)
+}
+
+// tradeIDFromKey reads the inverted ID at the end of a trade key
+func tradeIDFromKey(key []byte) uint64 {
+	return math.MaxUint64 - binary.BigEndian.Uint64(key[len(key)-8:])
+}
+
+func candleRangePrefix(poolID uint64, interval string) []byte {
+	key := binary.BigEndian.AppendUint64(append([]byte{}, candlePrefix...), poolID)
+	key = append(key, interval...)
+	return append(key, '/')
+}
+
+func candleStoreKey(poolID uint64, interval string, openTime time.Time) []byte {
+	return binary.BigEndian.AppendUint64(candleRangePrefix(poolID, interval), uint64(openTime.Unix()))
+}
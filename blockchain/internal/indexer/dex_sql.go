@@ -0,0 +1,130 @@
+package indexer
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/vindexchain/blockchain/internal/dex"
+)
+
+// sqlTradeStore keeps trades and candles in the Postgres tables created by
+// the dex_indexer migration
+type sqlTradeStore struct {
+	db *sql.DB
+}
+
+func newSQLTradeStore(db *sql.DB) *sqlTradeStore {
+	return &sqlTradeStore{db: db}
+}
+
+func (s *sqlTradeStore) persist(ctx context.Context, trades []dex.Trade) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, trade := range trades {
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO dex_trades (id, pool_id, trade_type, trader, denom_in, denom_out,
+			                        amount_in, amount_out, fee, burned, price, executed_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7::numeric, $8::numeric, $9::numeric, $10::numeric, $11, $12)
+			ON CONFLICT (id) DO NOTHING`,
+			int64(trade.ID), int64(trade.PoolID), trade.Type, trade.Trader, trade.DenomIn, trade.DenomOut,
+			numeric(trade.AmountIn), numeric(trade.AmountOut), numeric(trade.Fee), numeric(trade.Burned),
+			trade.Price, trade.Timestamp.UTC(),
+		)
+		if err != nil {
+			return err
+		}
+		if inserted, err := res.RowsAffected(); err != nil {
+			return err
+		} else if inserted == 0 {
+			continue // already stored before a crash
+		}
+
+		for interval, d := range Intervals {
+			candle := newCandle(trade, interval, d)
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO dex_candles (pool_id, interval, open_time, open, high, low, close, volume_a, volume_b, trades)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8::numeric, $9::numeric, 1)
+				ON CONFLICT (pool_id, interval, open_time) DO UPDATE SET
+					high     = GREATEST(dex_candles.high, EXCLUDED.high),
+					low      = LEAST(dex_candles.low, EXCLUDED.low),
+					close    = EXCLUDED.close,
+					volume_a = dex_candles.volume_a + EXCLUDED.volume_a,
+					volume_b = dex_candles.volume_b + EXCLUDED.volume_b,
+					trades   = dex_candles.trades + 1`,
+				int64(candle.PoolID), interval, candle.OpenTime, candle.Open, candle.High, candle.Low, candle.Close,
+				numeric(candle.VolumeA), numeric(candle.VolumeB),
+			); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *sqlTradeStore) candles(ctx context.Context, poolID uint64, interval string, from, to time.Time) ([]Candle, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT open_time, open, high, low, close, volume_a, volume_b, trades
+		FROM dex_candles
+		WHERE pool_id = $1 AND interval = $2 AND open_time >= $3 AND open_time < $4
+		ORDER BY open_time
+		LIMIT $5`,
+		int64(poolID), interval, from, to, maxCandles,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candles := make([]Candle, 0)
+	for rows.Next() {
+		candle := Candle{PoolID: poolID, Interval: interval}
+		if err := rows.Scan(&candle.OpenTime, &candle.Open, &candle.High, &candle.Low, &candle.Close,
+			&candle.VolumeA, &candle.VolumeB, &candle.Trades); err != nil {
+			return nil, err
+		}
+		candle.OpenTime = candle.OpenTime.UTC()
+		candles = append(candles, candle)
+	}
+	return candles, rows.Err()
+}
+
+func (s *sqlTradeStore) trades(ctx context.Context, poolID, cursor uint64, limit int) ([]dex.Trade, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, pool_id, trade_type, trader, denom_in, denom_out,
+		       amount_in, amount_out, fee, burned, price, executed_at
+		FROM dex_trades
+		WHERE ($1::bigint = 0 OR pool_id = $1) AND ($2::bigint = 0 OR id < $2)
+		ORDER BY id DESC
+		LIMIT $3`,
+		int64(poolID), int64(cursor), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trades := make([]dex.Trade, 0, limit)
+	for rows.Next() {
+		var trade dex.Trade
+		if err := rows.Scan(&trade.ID, &trade.PoolID, &trade.Type, &trade.Trader, &trade.DenomIn, &trade.DenomOut,
+			&trade.AmountIn, &trade.AmountOut, &trade.Fee, &trade.Burned, &trade.Price, &trade.Timestamp); err != nil {
+			return nil, err
+		}
+		trade.Timestamp = trade.Timestamp.UTC()
+		trades = append(trades, trade)
+	}
+	return trades, rows.Err()
+}
+
+// numeric formats a uint64 for a NUMERIC column; database/sql rejects uint64
+// values with the high bit set
+func numeric(v uint64) string {
+	return strconv.FormatUint(v, 10)
+}
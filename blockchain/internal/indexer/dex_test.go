@@ -0,0 +1,76 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vindexchain/blockchain/internal/database"
+	"github.com/vindexchain/blockchain/internal/dex"
+)
+
+type failingTradeStore struct {
+	tradeStore
+}
+
+func (failingTradeStore) persist(context.Context, []dex.Trade) error {
+	return errors.New("database down")
+}
+
+func testTrade(id uint64, at time.Time) dex.Trade {
+	return dex.Trade{
+		ID: id, PoolID: 1, Type: dex.TradeSell, Trader: "alice",
+		DenomIn: "a", DenomOut: "b", AmountIn: 100, AmountOut: 99, Price: 0.99,
+		Timestamp: at,
+	}
+}
+
+func TestDexIndexerFlush(t *testing.T) {
+	db, err := database.Initialize("mem://")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ix := NewDexIndexer(&Config{Database: db})
+	ctx := context.Background()
+	at := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	ix.HandleTrade(testTrade(1, at))
+	ix.HandleTrade(testTrade(2, at))
+	if trades, _, err := ix.Trades(ctx, 0, 0, 10); err != nil || len(trades) != 0 {
+		t.Fatalf("before flush: %d trades, %v; want none stored", len(trades), err)
+	}
+
+	// A failed flush keeps the trades for the retried commit
+	store := ix.store
+	ix.store = failingTradeStore{store}
+	if err := ix.Flush(1); err == nil {
+		t.Fatal("flush into a failing store succeeded")
+	}
+	ix.store = store
+	if err := ix.Flush(1); err != nil {
+		t.Fatal(err)
+	}
+
+	// A block applied again after a crash executes the same trades
+	ix.HandleTrade(testTrade(2, at))
+	ix.HandleTrade(testTrade(3, at))
+	if err := ix.Flush(2); err != nil {
+		t.Fatal(err)
+	}
+
+	trades, _, err := ix.Trades(ctx, 0, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 3 || trades[0].ID != 3 || trades[2].ID != 1 {
+		t.Fatalf("stored trades %+v; want IDs 3, 2, 1", trades)
+	}
+	candles, err := ix.Candles(ctx, 1, "1m", at, at.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candles) != 1 || candles[0].Trades != 3 || candles[0].VolumeA != 300 {
+		t.Fatalf("candles %+v; want one of 3 trades and volume 300", candles)
+	}
+}
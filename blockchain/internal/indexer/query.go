@@ -0,0 +1,252 @@
+package indexer
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidQuery is returned for queries that do not parse
+var ErrInvalidQuery = errors.New("invalid query")
+
+// Operator compares an attribute value with a query operand
+type Operator int
+
+// Operators
+const (
+	OpEqual Operator = iota
+	OpLess
+	OpLessEqual
+	OpGreater
+	OpGreaterEqual
+	OpContains
+	OpExists
+)
+
+var operatorNames = map[Operator]string{
+	OpEqual:        "=",
+	OpLess:         "<",
+	OpLessEqual:    "<=",
+	OpGreater:      ">",
+	OpGreaterEqual: ">=",
+	OpContains:     "CONTAINS",
+	OpExists:       "EXISTS",
+}
+
+func (op Operator) String() string { return operatorNames[op] }
+
+// Condition matches events with an attribute under Key whose value compares
+// to Value. Numeric operands compare numerically and match only numeric
+// values; string operands compare as strings.
+type Condition struct {
+	Key    string
+	Op     Operator
+	Value  string
+	Number *big.Rat // set for numeric operands
+}
+
+// Query is a conjunction of conditions, written as
+//
+//	transfer.recipient='vindex1...' AND tx.height>100 AND tx.memo CONTAINS 'invoice'
+//
+// Keys are an event type and attribute key joined with a dot. tx.height
+// and tx.hash refer to the transaction itself. A quote inside a quoted
+// value is written twice:
+//
+//	tx.memo='it''s paid'
+type Query struct {
+	Conditions []Condition
+}
+
+// ParseQuery parses a query string
+func ParseQuery(s string) (*Query, error) {
+	p := &queryParser{input: s}
+	q := &Query{}
+	for {
+		cond, err := p.condition()
+		if err != nil {
+			return nil, err
+		}
+		q.Conditions = append(q.Conditions, cond)
+
+		p.skipSpace()
+		if p.done() {
+			return q, nil
+		}
+		if word := p.word(); !strings.EqualFold(word, "AND") {
+			return nil, p.errorf("expected AND, got %q", word)
+		}
+	}
+}
+
+// String formats the query in its canonical form
+func (q *Query) String() string {
+	parts := make([]string, len(q.Conditions))
+	for i, c := range q.Conditions {
+		parts[i] = c.String()
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// String formats the condition
+func (c Condition) String() string {
+	switch {
+	case c.Op == OpExists:
+		return c.Key + " EXISTS"
+	case c.Number != nil:
+		return fmt.Sprintf("%s %s %s", c.Key, c.Op, c.Value)
+	default:
+		return fmt.Sprintf("%s %s '%s'", c.Key, c.Op, strings.ReplaceAll(c.Value, "'", "''"))
+	}
+}
+
+// Matches reports whether an attribute value satisfies the condition
+func (c Condition) Matches(value string) bool {
+	switch c.Op {
+	case OpExists:
+		return true
+	case OpContains:
+		return strings.Contains(value, c.Value)
+	}
+
+	var cmp int
+	if c.Number != nil {
+		n, ok := new(big.Rat).SetString(value)
+		if !ok {
+			return false
+		}
+		cmp = n.Cmp(c.Number)
+	} else {
+		cmp = strings.Compare(value, c.Value)
+	}
+	switch c.Op {
+	case OpEqual:
+		return cmp == 0
+	case OpLess:
+		return cmp < 0
+	case OpLessEqual:
+		return cmp <= 0
+	case OpGreater:
+		return cmp > 0
+	case OpGreaterEqual:
+		return cmp >= 0
+	}
+	return false
+}
+
+type queryParser struct {
+	input string
+	pos   int
+}
+
+func (p *queryParser) condition() (Condition, error) {
+	p.skipSpace()
+	key := p.take(func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("._-/", r)
+	})
+	if !strings.Contains(key, ".") || strings.HasPrefix(key, ".") || strings.HasSuffix(key, ".") {
+		return Condition{}, p.errorf("expected a key such as transfer.recipient, got %q", key)
+	}
+	cond := Condition{Key: key}
+
+	p.skipSpace()
+	switch {
+	case p.consume("<="):
+		cond.Op = OpLessEqual
+	case p.consume(">="):
+		cond.Op = OpGreaterEqual
+	case p.consume("="):
+		cond.Op = OpEqual
+	case p.consume("<"):
+		cond.Op = OpLess
+	case p.consume(">"):
+		cond.Op = OpGreater
+	default:
+		switch word := p.word(); strings.ToUpper(word) {
+		case "CONTAINS":
+			cond.Op = OpContains
+		case "EXISTS":
+			cond.Op = OpExists
+			return cond, nil
+		default:
+			return Condition{}, p.errorf("expected an operator after %s, got %q", key, word)
+		}
+	}
+
+	p.skipSpace()
+	if p.consume("'") {
+		value, err := p.quoted()
+		if err != nil {
+			return Condition{}, err
+		}
+		cond.Value = value
+		return cond, nil
+	}
+	if cond.Op == OpContains {
+		return Condition{}, p.errorf("CONTAINS needs a quoted string")
+	}
+	number := p.take(func(r rune) bool { return unicode.IsDigit(r) || r == '.' || r == '-' })
+	n, ok := new(big.Rat).SetString(number)
+	if number == "" || !ok {
+		return Condition{}, p.errorf("expected a quoted string or a number after %s %s", key, cond.Op)
+	}
+	cond.Value, cond.Number = number, n
+	return cond, nil
+}
+
+// quoted reads the rest of a quoted string, in which a doubled quote
+// stands for one quote
+func (p *queryParser) quoted() (string, error) {
+	var b strings.Builder
+	for {
+		end := strings.IndexByte(p.input[p.pos:], '\'')
+		if end < 0 {
+			return "", p.errorf("unterminated string")
+		}
+		b.WriteString(p.input[p.pos : p.pos+end])
+		p.pos += end + 1
+		if !p.consume("'") {
+			return b.String(), nil
+		}
+		b.WriteByte('\'')
+	}
+}
+
+func (p *queryParser) word() string {
+	p.skipSpace()
+	return p.take(func(r rune) bool { return unicode.IsLetter(r) })
+}
+
+func (p *queryParser) take(accept func(r rune) bool) string {
+	start := p.pos
+	for p.pos < len(p.input) {
+		r := rune(p.input[p.pos])
+		if r > unicode.MaxASCII || !accept(r) {
+			break
+		}
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+func (p *queryParser) consume(s string) bool {
+	if strings.HasPrefix(p.input[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *queryParser) done() bool { return p.pos >= len(p.input) }
+
+func (p *queryParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w at position %d: %s", ErrInvalidQuery, p.pos, fmt.Sprintf(format, args...))
+}
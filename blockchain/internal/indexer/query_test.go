@@ -0,0 +1,92 @@
+package indexer
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string // canonical form; empty when the query is invalid
+	}{
+		{"string equality", "transfer.recipient='vindex1abc'", "transfer.recipient = 'vindex1abc'"},
+		{"number", "tx.height>100", "tx.height > 100"},
+		{"decimal", "swap.price <= 1.5", "swap.price <= 1.5"},
+		{"negative", "pool.delta>=-3", "pool.delta >= -3"},
+		{"contains", "tx.memo CONTAINS 'invoice'", "tx.memo CONTAINS 'invoice'"},
+		{"exists", "tx.memo exists", "tx.memo EXISTS"},
+		{"conjunction", "a.b='x' and tx.height<5 AND c.d EXISTS", "a.b = 'x' AND tx.height < 5 AND c.d EXISTS"},
+		{"spaces in string", "tx.memo='two words'", "tx.memo = 'two words'"},
+		{"empty string", "tx.memo=''", "tx.memo = ''"},
+		{"escaped quote", "tx.memo='it''s'", "tx.memo = 'it''s'"},
+		{"only a quote", "tx.memo=''''", "tx.memo = ''''"},
+		{"surrounding space", "  tx.height = 7  ", "tx.height = 7"},
+
+		{"empty", "", ""},
+		{"key without dot", "height=5", ""},
+		{"key ending in dot", "tx.=5", ""},
+		{"missing operator", "tx.height 5", ""},
+		{"missing operand", "tx.height=", ""},
+		{"unterminated string", "tx.memo='abc", ""},
+		{"unterminated after escape", "tx.memo='abc''", ""},
+		{"unquoted contains", "tx.memo CONTAINS abc", ""},
+		{"not a number", "tx.height=abc", ""},
+		{"or", "a.b=1 OR c.d=2", ""},
+		{"trailing and", "a.b=1 AND", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := ParseQuery(tt.query)
+			if tt.want == "" {
+				if !errors.Is(err, ErrInvalidQuery) {
+					t.Fatalf("ParseQuery(%q) = %v, %v; want ErrInvalidQuery", tt.query, q, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseQuery(%q): %v", tt.query, err)
+			}
+			if got := q.String(); got != tt.want {
+				t.Errorf("ParseQuery(%q) = %s; want %s", tt.query, got, tt.want)
+			}
+			again, err := ParseQuery(q.String())
+			if err != nil || again.String() != tt.want {
+				t.Errorf("canonical form %q does not parse back: %v", q.String(), err)
+			}
+		})
+	}
+}
+
+func TestConditionMatches(t *testing.T) {
+	tests := []struct {
+		query string
+		value string
+		want  bool
+	}{
+		{"tx.height=10", "10", true},
+		{"tx.height=10", "10.0", true},
+		{"tx.height<10", "9", true},
+		{"tx.height<10", "10", false},
+		{"tx.height>9", "10", true}, // numeric, not lexical
+		{"tx.height>=10", "10", true},
+		{"tx.height<=10", "11", false},
+		{"tx.height>1", "abc", false},
+		{"a.b='10'", "10.0", false},
+		{"a.b>'abc'", "abd", true},
+		{"a.b CONTAINS 'voice'", "invoice", true},
+		{"a.b CONTAINS 'voice'", "receipt", false},
+		{"a.b='it''s'", "it's", true},
+		{"a.b EXISTS", "", true},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q): %v", tt.query, err)
+		}
+		if got := q.Conditions[0].Matches(tt.value); got != tt.want {
+			t.Errorf("%s matches %q = %v; want %v", tt.query, tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,605 @@
+package indexer
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/kv"
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+var (
+	// ErrTxNotFound is returned for transactions that are not indexed
+	ErrTxNotFound = errors.New("transaction not found")
+	// ErrKeyNotIndexed is returned for queries on attributes the node does
+	// not index
+	ErrKeyNotIndexed = errors.New("key is not indexed")
+)
+
+// Keys every transaction and block can be searched by, whatever the index
+// configuration
+const (
+	TxHashKey      = "tx.hash"
+	TxHeightKey    = "tx.height"
+	BlockHeightKey = "block.height"
+)
+
+// AccountKeys are the attributes naming the accounts a transaction moved
+// funds between. AccountTxs searches those of them that are indexed.
+var AccountKeys = []string{"transfer.sender", "transfer.recipient"}
+
+const (
+	defaultPerPage = 30
+	maxPerPage     = 100
+)
+
+// Index layout. Attribute entries end in the height and index of the
+// transaction, or the height of the block, so the value between the key and
+// that suffix may hold any bytes.
+var (
+	txHashPrefix     = []byte("tx/hash/")
+	txHeightPrefix   = []byte("tx/height/")
+	txAttrPrefix     = []byte("tx/attr/")
+	blockAttrPrefix  = []byte("block/attr/")
+	blockEventPrefix = []byte("block/events/")
+)
+
+// TxConfig configures the transaction and block event indexer
+type TxConfig struct {
+	DB kv.DB
+	// IndexKeys lists the attributes to index as type.key, for example
+	// transfer.recipient or tx.memo; "*" indexes every attribute
+	IndexKeys []string
+	Logger    *zap.Logger
+}
+
+// TxIndexer stores transaction results and the event attributes emitted by
+// transactions and blocks, and answers attribute queries over them
+type TxIndexer struct {
+	db       kv.DB
+	indexAll bool
+	keys     map[string]bool
+	logger   *zap.Logger
+}
+
+// txRef locates a transaction by block position
+type txRef struct {
+	height int64
+	index  uint32
+}
+
+// SearchOptions selects a page of search results
+type SearchOptions struct {
+	Page    int  // 1-based
+	PerPage int  // at most 100
+	Desc    bool // newest first
+}
+
+// NewTxIndexer creates a transaction indexer
+func NewTxIndexer(cfg *TxConfig) *TxIndexer {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	ix := &TxIndexer{db: cfg.DB, keys: make(map[string]bool), logger: logger}
+	for _, key := range cfg.IndexKeys {
+		key = strings.TrimSpace(key)
+		if key == "*" {
+			ix.indexAll = true
+		} else if key != "" {
+			ix.keys[key] = true
+		}
+	}
+	return ix
+}
+
+// Indexed reports whether attributes under key are indexed
+func (ix *TxIndexer) Indexed(key string) bool {
+	switch key {
+	case TxHashKey, TxHeightKey, BlockHeightKey:
+		return true
+	}
+	return ix.indexAll || ix.keys[key]
+}
+
+// HandleBlockResults is registered as a block results listener and indexes
+// every committed block
+func (ix *TxIndexer) HandleBlockResults(results *types.BlockResults) {
+	if err := ix.IndexBlock(results); err != nil {
+		ix.logger.Error("Failed to index block", zap.Int64("height", results.Height), zap.Error(err))
+	}
+}
+
+// IndexBlock stores the results of a block's transactions and the indexed
+// attributes of its events. Indexing a block again replaces it: the entries
+// of the previous results are deleted first.
+func (ix *TxIndexer) IndexBlock(results *types.BlockResults) error {
+	return ix.db.Update(func(tx kv.Tx) error {
+		previous := make(map[string][]byte) // height index key -> hash
+		err := tx.Iterate(prefixed(txHeightPrefix, binary.BigEndian.AppendUint64(nil, uint64(results.Height))), func(key, hash []byte) error {
+			previous[string(key)] = append([]byte{}, hash...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if err := unindexBlock(tx, results.Height, previous); err != nil {
+			return err
+		}
+		for _, result := range results.TxResults {
+			data, err := json.Marshal(result)
+			if err != nil {
+				return err
+			}
+			ref := txRef{height: result.Height, index: result.Index}
+			if err := tx.Set(prefixed(txHashPrefix, result.Hash), data); err != nil {
+				return err
+			}
+			if err := tx.Set(txHeightKey(ref), result.Hash); err != nil {
+				return err
+			}
+			for _, event := range result.Events {
+				for _, attr := range event.Attributes {
+					key := event.Type + "." + attr.Key
+					if !ix.Indexed(key) {
+						continue
+					}
+					if err := tx.Set(attrKey(txAttrPrefix, key, attr.Value, refBytes(ref)), result.Hash); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		height := binary.BigEndian.AppendUint64(nil, uint64(results.Height))
+		events, err := json.Marshal(results.Events)
+		if err != nil {
+			return err
+		}
+		if err := tx.Set(prefixed(blockEventPrefix, height), events); err != nil {
+			return err
+		}
+		for _, event := range results.Events {
+			for _, attr := range event.Attributes {
+				key := event.Type + "." + attr.Key
+				if !ix.Indexed(key) {
+					continue
+				}
+				if err := tx.Set(attrKey(blockAttrPrefix, key, attr.Value, height), height); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// unindexBlock deletes the entries stored for a block and its transactions,
+// given by their height index keys. Attributes are deleted whether or not
+// they are indexed now, so entries written under an earlier index
+// configuration go too.
+func unindexBlock(tx kv.Tx, height int64, txs map[string][]byte) error {
+	for key, hash := range txs {
+		if err := tx.Delete([]byte(key)); err != nil {
+			return err
+		}
+		data, err := tx.Get(prefixed(txHashPrefix, hash))
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			continue
+		}
+		var result types.TxResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			return err
+		}
+		if result.Height != height {
+			// The hash was indexed again at another height since
+			continue
+		}
+		ref := txRef{height: result.Height, index: result.Index}
+		for _, event := range result.Events {
+			for _, attr := range event.Attributes {
+				if err := tx.Delete(attrKey(txAttrPrefix, event.Type+"."+attr.Key, attr.Value, refBytes(ref))); err != nil {
+					return err
+				}
+			}
+		}
+		if err := tx.Delete(prefixed(txHashPrefix, hash)); err != nil {
+			return err
+		}
+	}
+
+	heightKey := binary.BigEndian.AppendUint64(nil, uint64(height))
+	data, err := tx.Get(prefixed(blockEventPrefix, heightKey))
+	if err != nil || data == nil {
+		return err
+	}
+	var events []types.Event
+	if err := json.Unmarshal(data, &events); err != nil {
+		return err
+	}
+	for _, event := range events {
+		for _, attr := range event.Attributes {
+			if err := tx.Delete(attrKey(blockAttrPrefix, event.Type+"."+attr.Key, attr.Value, heightKey)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GetTx returns an indexed transaction by hash
+func (ix *TxIndexer) GetTx(hash []byte) (*types.TxResult, error) {
+	data, err := ix.db.Get(prefixed(txHashPrefix, hash))
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("%w: %X", ErrTxNotFound, hash)
+	}
+	var result types.TxResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SearchTxs returns a page of the transactions matching every condition of
+// the query, ordered by height and index, and the number of matches
+func (ix *TxIndexer) SearchTxs(q *Query, opts SearchOptions) ([]*types.TxResult, int, error) {
+	matches, err := ix.matchQuery(q)
+	if err != nil {
+		return nil, 0, err
+	}
+	return ix.page(matches, opts)
+}
+
+// AccountTxs returns a page of the transactions that sent funds from or to
+// an address, and the number of them
+func (ix *TxIndexer) AccountTxs(address string, opts SearchOptions) ([]*types.TxResult, int, error) {
+	matches := make(map[txRef]bool)
+	searched := false
+	for _, key := range AccountKeys {
+		if !ix.Indexed(key) {
+			continue
+		}
+		searched = true
+		refs, err := ix.matchQuery(&Query{Conditions: []Condition{{Key: key, Op: OpEqual, Value: address}}})
+		if err != nil {
+			return nil, 0, err
+		}
+		for ref := range refs {
+			matches[ref] = true
+		}
+	}
+	if !searched {
+		return nil, 0, fmt.Errorf("%w: %s", ErrKeyNotIndexed, strings.Join(AccountKeys, ", "))
+	}
+	return ix.page(matches, opts)
+}
+
+func (ix *TxIndexer) matchQuery(q *Query) (map[txRef]bool, error) {
+	if err := ix.checkIndexed(q, TxHeightKey); err != nil {
+		return nil, err
+	}
+	var matches map[txRef]bool
+	for _, cond := range orderConditions(q.Conditions) {
+		refs, err := ix.matchTxs(cond, matches)
+		if err != nil {
+			return nil, err
+		}
+		matches = refs
+		if len(matches) == 0 {
+			break
+		}
+	}
+	return matches, nil
+}
+
+// page loads a page of matched transactions in block order
+func (ix *TxIndexer) page(matches map[txRef]bool, opts SearchOptions) ([]*types.TxResult, int, error) {
+	refs := make([]txRef, 0, len(matches))
+	for ref := range matches {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].height != refs[j].height {
+			return refs[i].height < refs[j].height
+		}
+		return refs[i].index < refs[j].index
+	})
+	if opts.Desc {
+		for i, j := 0, len(refs)-1; i < j; i, j = i+1, j-1 {
+			refs[i], refs[j] = refs[j], refs[i]
+		}
+	}
+
+	start, end := opts.bounds(len(refs))
+	results := make([]*types.TxResult, 0, end-start)
+	for _, ref := range refs[start:end] {
+		hash, err := ix.db.Get(txHeightKey(ref))
+		if err != nil {
+			return nil, 0, err
+		}
+		result, err := ix.GetTx(hash)
+		if err != nil {
+			return nil, 0, err
+		}
+		results = append(results, result)
+	}
+	return results, len(refs), nil
+}
+
+// SearchBlocks returns a page of the heights of blocks whose events match
+// every condition of the query, and the number of matches
+func (ix *TxIndexer) SearchBlocks(q *Query, opts SearchOptions) ([]int64, int, error) {
+	if err := ix.checkIndexed(q, BlockHeightKey); err != nil {
+		return nil, 0, err
+	}
+
+	var matches map[int64]bool
+	for _, cond := range orderConditions(q.Conditions) {
+		heights, err := ix.matchBlocks(cond, matches)
+		if err != nil {
+			return nil, 0, err
+		}
+		matches = heights
+		if len(matches) == 0 {
+			break
+		}
+	}
+
+	heights := make([]int64, 0, len(matches))
+	for height := range matches {
+		heights = append(heights, height)
+	}
+	sort.Slice(heights, func(i, j int) bool {
+		if opts.Desc {
+			return heights[i] > heights[j]
+		}
+		return heights[i] < heights[j]
+	})
+	start, end := opts.bounds(len(heights))
+	return heights[start:end], len(heights), nil
+}
+
+// BlockEvents returns the events a block emitted outside its transactions
+func (ix *TxIndexer) BlockEvents(height int64) ([]types.Event, error) {
+	data, err := ix.db.Get(prefixed(blockEventPrefix, binary.BigEndian.AppendUint64(nil, uint64(height))))
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var events []types.Event
+	return events, json.Unmarshal(data, &events)
+}
+
+func (ix *TxIndexer) checkIndexed(q *Query, heightKey string) error {
+	for _, cond := range q.Conditions {
+		if cond.Key == TxHashKey && heightKey == BlockHeightKey {
+			return fmt.Errorf("%w: %s applies to transactions", ErrInvalidQuery, TxHashKey)
+		}
+		if (cond.Key == TxHeightKey || cond.Key == BlockHeightKey) && cond.Key != heightKey {
+			return fmt.Errorf("%w: use %s", ErrInvalidQuery, heightKey)
+		}
+		if !ix.Indexed(cond.Key) {
+			return fmt.Errorf("%w: %s", ErrKeyNotIndexed, cond.Key)
+		}
+	}
+	return nil
+}
+
+// matchTxs returns the transactions matching a condition, limited to
+// within if it is not nil
+func (ix *TxIndexer) matchTxs(cond Condition, within map[txRef]bool) (map[txRef]bool, error) {
+	matches := make(map[txRef]bool)
+	add := func(ref txRef) {
+		if within == nil || within[ref] {
+			matches[ref] = true
+		}
+	}
+
+	switch cond.Key {
+	case TxHashKey:
+		if cond.Op != OpEqual {
+			return nil, fmt.Errorf("%w: %s only supports =", ErrInvalidQuery, TxHashKey)
+		}
+		hash, err := hex.DecodeString(strings.TrimPrefix(cond.Value, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+		}
+		result, err := ix.GetTx(hash)
+		if errors.Is(err, ErrTxNotFound) {
+			return matches, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		add(txRef{height: result.Height, index: result.Index})
+		return matches, nil
+
+	case TxHeightKey:
+		err := scanHeights(ix.db, txHeightPrefix, cond, func(key []byte) {
+			add(parseRef(key[len(key)-12:]))
+		})
+		return matches, err
+	}
+
+	err := scanAttr(ix.db, txAttrPrefix, cond, 12, func(suffix []byte) {
+		add(parseRef(suffix))
+	})
+	return matches, err
+}
+
+func (ix *TxIndexer) matchBlocks(cond Condition, within map[int64]bool) (map[int64]bool, error) {
+	matches := make(map[int64]bool)
+	add := func(height int64) {
+		if within == nil || within[height] {
+			matches[height] = true
+		}
+	}
+	if cond.Key == BlockHeightKey {
+		err := scanHeights(ix.db, blockEventPrefix, cond, func(key []byte) {
+			add(int64(binary.BigEndian.Uint64(key[len(blockEventPrefix):])))
+		})
+		return matches, err
+	}
+	err := scanAttr(ix.db, blockAttrPrefix, cond, 8, func(suffix []byte) {
+		add(int64(binary.BigEndian.Uint64(suffix)))
+	})
+	return matches, err
+}
+
+// scanAttr calls fn with the position suffix of every entry under the
+// condition's key whose value matches
+func scanAttr(db kv.DB, prefix []byte, cond Condition, suffixLen int, fn func(suffix []byte)) error {
+	keyPrefix := attrKey(prefix, cond.Key, "", nil)
+	scan := keyPrefix
+	if cond.Op == OpEqual && cond.Number == nil {
+		// Exact string matches only need the entries with that value
+		scan = attrKey(prefix, cond.Key, cond.Value, nil)
+	}
+	return db.Iterate(scan, func(key, _ []byte) error {
+		if len(key) < len(keyPrefix)+suffixLen+1 {
+			return nil
+		}
+		value := key[len(keyPrefix) : len(key)-suffixLen-1]
+		if cond.Matches(string(value)) {
+			fn(key[len(key)-suffixLen:])
+		}
+		return nil
+	})
+}
+
+// scanHeights calls fn with every key under a height-ordered prefix whose
+// height matches a height condition. Integer bounds are turned into a key
+// range; other conditions scan the whole index.
+func scanHeights(db kv.DB, prefix []byte, cond Condition, fn func(key []byte)) error {
+	start, end := prefix, kv.PrefixEnd(prefix)
+	if cond.Number != nil && cond.Number.IsInt() && cond.Number.Num().IsInt64() {
+		// The range only narrows the scan; Matches still decides
+		h := cond.Number.Num().Int64()
+		lo, hi := int64(0), int64(-1)
+		switch cond.Op {
+		case OpEqual:
+			lo, hi = h, h
+		case OpLess:
+			hi = h - 1
+		case OpLessEqual:
+			hi = h
+		case OpGreater:
+			lo = h + 1
+		case OpGreaterEqual:
+			lo = h
+		}
+		if lo < 0 {
+			lo = 0
+		}
+		switch cond.Op {
+		case OpEqual, OpLess, OpLessEqual:
+			if hi < lo {
+				return nil
+			}
+			end = heightKey(prefix, hi+1)
+		}
+		start = heightKey(prefix, lo)
+	}
+	return db.IterateRange(start, end, func(key, _ []byte) error {
+		height := int64(binary.BigEndian.Uint64(key[len(prefix):]))
+		if cond.Matches(strconv.FormatInt(height, 10)) {
+			fn(append([]byte{}, key...))
+		}
+		return nil
+	})
+}
+
+func heightKey(prefix []byte, height int64) []byte {
+	return prefixed(prefix, binary.BigEndian.AppendUint64(nil, uint64(height)))
+}
+
+// orderConditions puts the cheapest and most selective conditions first,
+// so later ones only have to be checked against few candidates
+func orderConditions(conds []Condition) []Condition {
+	rank := func(c Condition) int {
+		switch {
+		case c.Key == TxHashKey:
+			return 0
+		case c.Op == OpEqual && c.Number == nil:
+			return 1
+		case c.Key == TxHeightKey || c.Key == BlockHeightKey:
+			return 2
+		case c.Op == OpExists:
+			return 4
+		default:
+			return 3
+		}
+	}
+	ordered := append([]Condition{}, conds...)
+	sort.SliceStable(ordered, func(i, j int) bool { return rank(ordered[i]) < rank(ordered[j]) })
+	return ordered
+}
+
+func (o SearchOptions) bounds(total int) (int, int) {
+	perPage := o.PerPage
+	if perPage <= 0 {
+		perPage = defaultPerPage
+	}
+	if perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+	page := o.Page
+	if page < 1 {
+		page = 1
+	}
+	start := (page - 1) * perPage
+	if start > total {
+		start = total
+	}
+	end := start + perPage
+	if end > total {
+		end = total
+	}
+	return start, end
+}
+
+func prefixed(prefix, key []byte) []byte {
+	return append(append([]byte{}, prefix...), key...)
+}
+
+// attrKey builds prefix | key | 0x00 | value | 0x00 | suffix. With a nil
+// suffix it is the prefix of all entries with that key and value, or, with
+// an empty value, of all entries with that key.
+func attrKey(prefix []byte, key, value string, suffix []byte) []byte {
+	buf := make([]byte, 0, len(prefix)+len(key)+len(value)+2+len(suffix))
+	buf = append(buf, prefix...)
+	buf = append(buf, key...)
+	buf = append(buf, 0)
+	if value == "" && suffix == nil {
+		return buf
+	}
+	buf = append(buf, value...)
+	buf = append(buf, 0)
+	return append(buf, suffix...)
+}
+
+func refBytes(ref txRef) []byte {
+	buf := binary.BigEndian.AppendUint64(nil, uint64(ref.height))
+	return binary.BigEndian.AppendUint32(buf, ref.index)
+}
+
+func parseRef(b []byte) txRef {
+	return txRef{height: int64(binary.BigEndian.Uint64(b)), index: binary.BigEndian.Uint32(b[8:])}
+}
+
+func txHeightKey(ref txRef) []byte {
+	return prefixed(txHeightPrefix, refBytes(ref))
+}
@@ -0,0 +1,105 @@
+package indexer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/vindexchain/blockchain/internal/kv"
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+func transferEvents(sender, recipient string) []types.Event {
+	return []types.Event{{Type: "transfer", Attributes: []types.EventAttribute{
+		{Key: "sender", Value: sender},
+		{Key: "recipient", Value: recipient},
+	}}}
+}
+
+func TestTxIndexerSearch(t *testing.T) {
+	ix := NewTxIndexer(&TxConfig{DB: kv.NewMemDB(), IndexKeys: []string{"transfer.sender", "transfer.recipient"}})
+	for height := int64(1); height <= 3; height++ {
+		err := ix.IndexBlock(&types.BlockResults{
+			Height: height,
+			TxResults: []*types.TxResult{
+				{Height: height, Index: 0, Hash: []byte{byte(height), 0}, Events: transferEvents("alice", "bob")},
+				{Height: height, Index: 1, Hash: []byte{byte(height), 1}, Events: transferEvents("bob", "carol")},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"transfer.sender='alice'", 3},
+		{"transfer.recipient='bob' AND tx.height>1", 2},
+		{"transfer.sender='bob' AND transfer.recipient='carol' AND tx.height=2", 1},
+		{"transfer.sender='dave'", 0},
+		{"tx.height<=3", 6},
+		{"tx.height>2", 2},
+		{"tx.height>=2", 4},
+		{"tx.height>3", 0},
+		{"tx.height>=-1", 6},
+		{"tx.height<1", 0},
+		{"tx.height>1.5", 4},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, total, err := ix.SearchTxs(q, SearchOptions{})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		if total != tt.want {
+			t.Errorf("%s matched %d transactions; want %d", tt.query, total, tt.want)
+		}
+	}
+
+	q, _ := ParseQuery("tx.memo='x'")
+	if _, _, err := ix.SearchTxs(q, SearchOptions{}); !errors.Is(err, ErrKeyNotIndexed) {
+		t.Errorf("query on an unindexed key: %v; want ErrKeyNotIndexed", err)
+	}
+}
+
+func TestTxIndexerReindexBlock(t *testing.T) {
+	db := kv.NewMemDB()
+	ix := NewTxIndexer(&TxConfig{DB: db, IndexKeys: []string{"*"}})
+	index := func(hash byte, sender, blockValue string) {
+		t.Helper()
+		err := ix.IndexBlock(&types.BlockResults{
+			Height:    5,
+			Events:    []types.Event{{Type: "block", Attributes: []types.EventAttribute{{Key: "proposer", Value: blockValue}}}},
+			TxResults: []*types.TxResult{{Height: 5, Hash: []byte{hash}, Events: transferEvents(sender, "bob")}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	index(1, "alice", "val1")
+	index(2, "carol", "val2")
+
+	if _, err := ix.GetTx([]byte{1}); !errors.Is(err, ErrTxNotFound) {
+		t.Errorf("replaced transaction is still indexed: %v", err)
+	}
+	for query, want := range map[string]int{
+		"transfer.sender='alice'":  0,
+		"transfer.sender='carol'":  1,
+		"transfer.recipient='bob'": 1,
+	} {
+		q, _ := ParseQuery(query)
+		if _, total, err := ix.SearchTxs(q, SearchOptions{}); err != nil || total != want {
+			t.Errorf("%s matched %d transactions (%v); want %d", query, total, err, want)
+		}
+	}
+	for query, want := range map[string]int{"block.proposer='val1'": 0, "block.proposer='val2'": 1} {
+		q, _ := ParseQuery(query)
+		if _, total, err := ix.SearchBlocks(q, SearchOptions{}); err != nil || total != want {
+			t.Errorf("%s matched %d blocks (%v); want %d", query, total, err, want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package kv
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var boltBucket = []byte("kv")
+
+// boltDB keeps all keys in one bucket of a bbolt file
+type boltDB struct {
+	db *bolt.DB
+}
+
+// NewBoltDB opens or creates a bbolt database file
+func NewBoltDB(path string) (DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &boltDB{db: db}, nil
+}
+
+// Get implements DB
+func (b *boltDB) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := b.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(boltBucket).Get(key); v != nil {
+			value = append([]byte{}, v...)
+		}
+		return nil
+	})
+	return value, err
+}
+
+// Iterate implements DB
+func (b *boltDB) Iterate(prefix []byte, fn func(key, value []byte) error) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		return iterateBucket(tx.Bucket(boltBucket), prefix, PrefixEnd(prefix), fn)
+	})
+}
+
+// IterateRange implements DB
+func (b *boltDB) IterateRange(start, end []byte, fn func(key, value []byte) error) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		return iterateBucket(tx.Bucket(boltBucket), start, end, fn)
+	})
+}
+
+func iterateBucket(bucket *bolt.Bucket, start, end []byte, fn func(key, value []byte) error) error {
+	c := bucket.Cursor()
+	for k, v := c.Seek(start); k != nil && (end == nil || bytes.Compare(k, end) < 0); k, v = c.Next() {
+		if err := fn(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Update implements DB
+func (b *boltDB) Update(fn func(tx Tx) error) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return fn(boltTx{bucket: tx.Bucket(boltBucket)})
+	})
+}
+
+// Close implements DB
+func (b *boltDB) Close() error { return b.db.Close() }
+
+type boltTx struct {
+	bucket *bolt.Bucket
+}
+
+func (t boltTx) Get(key []byte) ([]byte, error) {
+	if v := t.bucket.Get(key); v != nil {
+		return append([]byte{}, v...), nil
+	}
+	return nil, nil
+}
+
+func (t boltTx) Iterate(prefix []byte, fn func(key, value []byte) error) error {
+	return iterateBucket(t.bucket, prefix, PrefixEnd(prefix), fn)
+}
+
+func (t boltTx) Set(key, value []byte) error { return t.bucket.Put(key, value) }
+
+func (t boltTx) Delete(key []byte) error { return t.bucket.Delete(key) }
@@ -0,0 +1,52 @@
+package kv
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ErrClosed is returned by databases used after Close
+var ErrClosed = errors.New("database is closed")
+
+// DB is an ordered key-value store with atomic write transactions
+type DB interface {
+	// Get returns a copy of the value under key, or nil if there is none
+	Get(key []byte) ([]byte, error)
+	// Iterate calls fn for every key with prefix in ascending order until fn
+	// returns an error. The slices are only valid during the call.
+	Iterate(prefix []byte, fn func(key, value []byte) error) error
+	// IterateRange is Iterate over the keys from start up to but not
+	// including end. A nil end has no upper bound.
+	IterateRange(start, end []byte, fn func(key, value []byte) error) error
+	// Update runs fn in a write transaction that is committed if fn
+	// returns nil and discarded otherwise
+	Update(fn func(tx Tx) error) error
+	Close() error
+}
+
+// Tx is a write transaction. Reads see the transaction's own writes.
+type Tx interface {
+	Get(key []byte) ([]byte, error)
+	// Iterate is DB.Iterate within the transaction. fn must not write.
+	Iterate(prefix []byte, fn func(key, value []byte) error) error
+	Set(key, value []byte) error
+	Delete(key []byte) error
+}
+
+// PrefixEnd returns the first key after every key with prefix, or nil if
+// there is none
+func PrefixEnd(prefix []byte) []byte {
+	end := append([]byte{}, prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// inRange reports whether key is in [start, end)
+func inRange(key, start, end []byte) bool {
+	return bytes.Compare(key, start) >= 0 && (end == nil || bytes.Compare(key, end) < 0)
+}
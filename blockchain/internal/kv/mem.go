@@ -0,0 +1,144 @@
+package kv
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+)
+
+// memDB is an in-memory DB for tests and throwaway devnets
+type memDB struct {
+	mu     sync.RWMutex
+	data   map[string][]byte
+	closed bool
+}
+
+// NewMemDB creates an empty in-memory database
+func NewMemDB() DB {
+	return &memDB{data: make(map[string][]byte)}
+}
+
+// Get implements DB
+func (m *memDB) Get(key []byte) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return nil, ErrClosed
+	}
+	if v, ok := m.data[string(key)]; ok {
+		return append([]byte{}, v...), nil
+	}
+	return nil, nil
+}
+
+// Iterate implements DB
+func (m *memDB) Iterate(prefix []byte, fn func(key, value []byte) error) error {
+	return m.IterateRange(prefix, PrefixEnd(prefix), fn)
+}
+
+// IterateRange implements DB
+func (m *memDB) IterateRange(start, end []byte, fn func(key, value []byte) error) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return ErrClosed
+	}
+
+	keys := make([]string, 0)
+	for k := range m.data {
+		if inRange([]byte(k), start, end) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := fn([]byte(k), m.data[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Update implements DB
+func (m *memDB) Update(fn func(tx Tx) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+
+	tx := &memTx{db: m, writes: make(map[string][]byte)}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	for k, v := range tx.writes {
+		if v == nil {
+			delete(m.data, k)
+		} else {
+			m.data[k] = v
+		}
+	}
+	return nil
+}
+
+// Close implements DB
+func (m *memDB) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	return nil
+}
+
+// memTx stages writes until the transaction commits; nil marks a delete
+type memTx struct {
+	db     *memDB
+	writes map[string][]byte
+}
+
+func (t *memTx) Get(key []byte) ([]byte, error) {
+	if v, ok := t.writes[string(key)]; ok {
+		if v == nil {
+			return nil, nil
+		}
+		return append([]byte{}, v...), nil
+	}
+	if v, ok := t.db.data[string(key)]; ok {
+		return append([]byte{}, v...), nil
+	}
+	return nil, nil
+}
+
+func (t *memTx) Iterate(prefix []byte, fn func(key, value []byte) error) error {
+	keys := make([]string, 0)
+	for k := range t.db.data {
+		if _, staged := t.writes[k]; !staged && bytes.HasPrefix([]byte(k), prefix) {
+			keys = append(keys, k)
+		}
+	}
+	for k, v := range t.writes {
+		if v != nil && bytes.HasPrefix([]byte(k), prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v, ok := t.writes[k]
+		if !ok {
+			v = t.db.data[k]
+		}
+		if err := fn([]byte(k), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *memTx) Set(key, value []byte) error {
+	t.writes[string(key)] = append([]byte{}, value...)
+	return nil
+}
+
+func (t *memTx) Delete(key []byte) error {
+	t.writes[string(key)] = nil
+	return nil
+}
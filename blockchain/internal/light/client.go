@@ -0,0 +1,343 @@
+package light
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+// Mode selects how the client gets from a trusted header to a newer one
+type Mode string
+
+const (
+	// ModeSkipping verifies the target header directly when enough of the
+	// trusted validators signed it and bisects otherwise
+	ModeSkipping Mode = "skipping"
+	// ModeSequential verifies every header between the trusted one and the
+	// target
+	ModeSequential Mode = "sequential"
+)
+
+const defaultMaxClockDrift = 10 * time.Second
+
+// TrustOptions is the header the client trusts without verification. It
+// must come from a source the user trusts, such as a block explorer they
+// run or a friend's node.
+type TrustOptions struct {
+	// Period is how long a verified header can be used to verify newer
+	// ones. It must be shorter than the unbonding period so the validators
+	// that signed it can still be slashed for signing a fork.
+	Period time.Duration
+	Height int64
+	Hash   types.HexBytes
+}
+
+// ValidateBasic checks the trust options
+func (o TrustOptions) ValidateBasic() error {
+	if o.Period <= 0 {
+		return errors.New("trusting period must be positive")
+	}
+	if o.Height <= 0 {
+		return errors.New("trusted height must be positive")
+	}
+	if len(o.Hash) != 32 {
+		return fmt.Errorf("trusted hash must be 32 bytes, got %d", len(o.Hash))
+	}
+	return nil
+}
+
+// Config configures the light client
+type Config struct {
+	ChainID      string
+	TrustOptions TrustOptions
+	Primary      Provider
+	// Witnesses are cross-checked against every header the primary serves.
+	// Without witnesses the client cannot detect a primary serving a fork.
+	Witnesses     []Provider
+	Store         *Store // defaults to an in-memory store
+	Mode          Mode   // defaults to ModeSkipping
+	TrustLevel    types.Fraction
+	MaxClockDrift time.Duration
+	Logger        *zap.Logger
+}
+
+// Client verifies headers served by an untrusted primary node, starting
+// from a trusted header, and checks them against witness nodes
+type Client struct {
+	config  *Config
+	chainID string
+	primary Provider
+	store   *Store
+	logger  *zap.Logger
+
+	mu sync.Mutex // serializes verification
+
+	witnessMu sync.RWMutex
+	witnesses []Provider
+}
+
+// NewClient creates a light client and establishes trust in the header
+// given by the trust options, or resumes from the store if it holds a
+// header that has not expired
+func NewClient(ctx context.Context, cfg *Config) (*Client, error) {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if cfg.ChainID == "" {
+		return nil, errors.New("chain ID is required")
+	}
+	if cfg.Primary == nil {
+		return nil, errors.New("a primary provider is required")
+	}
+	if err := cfg.TrustOptions.ValidateBasic(); err != nil {
+		return nil, err
+	}
+	if cfg.TrustLevel == (types.Fraction{}) {
+		cfg.TrustLevel = DefaultTrustLevel
+	}
+	if err := ValidateTrustLevel(cfg.TrustLevel); err != nil {
+		return nil, err
+	}
+	switch cfg.Mode {
+	case "":
+		cfg.Mode = ModeSkipping
+	case ModeSkipping, ModeSequential:
+	default:
+		return nil, fmt.Errorf("unknown verification mode %q", cfg.Mode)
+	}
+	if cfg.MaxClockDrift == 0 {
+		cfg.MaxClockDrift = defaultMaxClockDrift
+	}
+	store := cfg.Store
+	if store == nil {
+		store, _ = NewStore("", 0)
+	}
+	if len(cfg.Witnesses) == 0 {
+		logger.Warn("No witnesses configured; forks served by the primary will not be detected")
+	}
+
+	c := &Client{
+		config:    cfg,
+		chainID:   cfg.ChainID,
+		primary:   cfg.Primary,
+		store:     store,
+		logger:    logger,
+		witnesses: append([]Provider(nil), cfg.Witnesses...),
+	}
+	if err := c.initialize(ctx, time.Now()); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// initialize establishes the first trusted header
+func (c *Client) initialize(ctx context.Context, now time.Time) error {
+	opts := c.config.TrustOptions
+
+	if stored, err := c.store.LightBlock(opts.Height); err == nil && !bytes.Equal(stored.Hash(), opts.Hash) {
+		return fmt.Errorf("trusted store has hash %s at height %d, trust options say %s; remove the store to start over", stored.Hash(), opts.Height, opts.Hash)
+	}
+	if latest, err := c.store.Latest(); err == nil && !HeaderExpired(latest.Header, opts.Period, now) {
+		c.logger.Info("Resuming from the trusted store", zap.Int64("height", latest.Height()), zap.String("hash", latest.Hash().String()))
+		return nil
+	}
+
+	lb, err := c.primary.LightBlock(ctx, opts.Height)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the trusted header: %w", err)
+	}
+	if !bytes.Equal(lb.Hash(), opts.Hash) {
+		return fmt.Errorf("%w: primary has hash %s at the trusted height %d, expected %s", ErrInvalidHeader, lb.Hash(), opts.Height, opts.Hash)
+	}
+	if HeaderExpired(lb.Header, opts.Period, now) {
+		return fmt.Errorf("%w: the trusted header is from %s; pick a more recent one", ErrHeaderExpired, lb.Header.Time)
+	}
+	if err := lb.ValidatorSet.VerifyCommitLight(c.chainID, lb.Hash(), lb.Height(), lb.Commit); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+	}
+	if err := c.compareFirstHeader(ctx, lb); err != nil {
+		return err
+	}
+
+	c.logger.Info("Trusting header", zap.Int64("height", lb.Height()), zap.String("hash", lb.Hash().String()))
+	return c.store.Save(lb)
+}
+
+// ChainID returns the chain the client verifies
+func (c *Client) ChainID() string { return c.chainID }
+
+// Primary returns the provider headers are fetched from
+func (c *Client) Primary() Provider { return c.primary }
+
+// Witnesses returns the witnesses that have not been dropped as faulty
+func (c *Client) Witnesses() []Provider {
+	c.witnessMu.RLock()
+	defer c.witnessMu.RUnlock()
+	return append([]Provider(nil), c.witnesses...)
+}
+
+// TrustedLightBlock returns a verified light block from the store, or the
+// latest one when height is 0
+func (c *Client) TrustedLightBlock(height int64) (*types.LightBlock, error) {
+	if height == 0 {
+		return c.store.Latest()
+	}
+	return c.store.LightBlock(height)
+}
+
+// Update verifies the primary's latest header
+func (c *Client) Update(ctx context.Context, now time.Time) (*types.LightBlock, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	latest, err := c.store.Latest()
+	if err != nil {
+		return nil, err
+	}
+	lb, err := c.primary.LightBlock(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	if lb.Height() <= latest.Height() {
+		return latest, nil
+	}
+	return c.verifyForwards(ctx, latest, lb, now)
+}
+
+// VerifyLightBlockAtHeight fetches the header at height from the primary
+// and verifies it, unless it is already trusted
+func (c *Client) VerifyLightBlockAtHeight(ctx context.Context, height int64, now time.Time) (*types.LightBlock, error) {
+	if height <= 0 {
+		return nil, errors.New("height must be positive")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if lb, err := c.store.LightBlock(height); err == nil {
+		return lb, nil
+	}
+
+	// Prefer verifying forwards from a lower trusted header, which checks
+	// signatures; below the oldest one, or once it has expired, only hash
+	// links are left to follow
+	trusted, err := c.store.Before(height)
+	if err != nil || HeaderExpired(trusted.Header, c.config.TrustOptions.Period, now) {
+		after, err := c.store.After(height)
+		if err != nil {
+			return nil, err
+		}
+		return c.verifyBackwards(ctx, after, height, now)
+	}
+
+	lb, err := c.primary.LightBlock(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	return c.verifyForwards(ctx, trusted, lb, now)
+}
+
+// verifyForwards verifies target from the primary, cross-checks it with
+// the witnesses and stores the headers verified on the way
+func (c *Client) verifyForwards(ctx context.Context, trusted, target *types.LightBlock, now time.Time) (*types.LightBlock, error) {
+	trace, err := c.verify(ctx, c.primary, trusted, target, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify header %d from primary %s: %w", target.Height(), c.primary.ID(), err)
+	}
+	if err := c.detectDivergence(ctx, trace, now); err != nil {
+		return nil, err
+	}
+	if err := c.store.Save(trace[1:]...); err != nil {
+		return nil, err
+	}
+	c.logger.Debug("Verified header", zap.Int64("height", target.Height()), zap.String("hash", target.Hash().String()))
+	return target, nil
+}
+
+// verify returns the chain of headers from trusted to target that
+// verification went through, both included
+func (c *Client) verify(ctx context.Context, source Provider, trusted, target *types.LightBlock, now time.Time) ([]*types.LightBlock, error) {
+	if c.config.Mode == ModeSequential {
+		return c.verifySequential(ctx, source, trusted, target, now)
+	}
+	return c.verifySkipping(ctx, source, trusted, target, now)
+}
+
+func (c *Client) verifySequential(ctx context.Context, source Provider, trusted, target *types.LightBlock, now time.Time) ([]*types.LightBlock, error) {
+	trace := []*types.LightBlock{trusted}
+	for height := trusted.Height() + 1; height <= target.Height(); height++ {
+		next := target
+		if height < target.Height() {
+			var err error
+			if next, err = source.LightBlock(ctx, height); err != nil {
+				return nil, err
+			}
+		}
+		if err := VerifyAdjacent(c.chainID, trusted, next, c.config.TrustOptions.Period, now, c.config.MaxClockDrift); err != nil {
+			return nil, fmt.Errorf("height %d: %w", height, err)
+		}
+		trace = append(trace, next)
+		trusted = next
+	}
+	return trace, nil
+}
+
+// verifySkipping tries to verify the target directly from the trusted
+// header. When too few of the trusted validators signed it, it verifies the
+// header halfway in between first, recursively.
+func (c *Client) verifySkipping(ctx context.Context, source Provider, trusted, target *types.LightBlock, now time.Time) ([]*types.LightBlock, error) {
+	trace := []*types.LightBlock{trusted}
+	pending := []*types.LightBlock{target}
+	for len(pending) > 0 {
+		candidate := pending[len(pending)-1]
+		err := Verify(c.chainID, trusted, candidate, c.config.TrustOptions.Period, now, c.config.MaxClockDrift, c.config.TrustLevel)
+		switch {
+		case err == nil:
+			trace = append(trace, candidate)
+			trusted = candidate
+			pending = pending[:len(pending)-1]
+
+		case errors.Is(err, ErrNewValSetCantBeTrusted):
+			pivot := trusted.Height() + (candidate.Height()-trusted.Height())/2
+			lb, err := source.LightBlock(ctx, pivot)
+			if err != nil {
+				return nil, err
+			}
+			pending = append(pending, lb)
+
+		default:
+			return nil, fmt.Errorf("height %d: %w", candidate.Height(), err)
+		}
+	}
+	return trace, nil
+}
+
+// verifyBackwards follows hash links down from a trusted header
+func (c *Client) verifyBackwards(ctx context.Context, trusted *types.LightBlock, height int64, now time.Time) (*types.LightBlock, error) {
+	if HeaderExpired(trusted.Header, c.config.TrustOptions.Period, now) {
+		return nil, fmt.Errorf("%w: header %d from %s", ErrHeaderExpired, trusted.Height(), trusted.Header.Time)
+	}
+	above := trusted
+	for h := trusted.Height() - 1; h >= height; h-- {
+		lb, err := c.primary.LightBlock(ctx, h)
+		if err != nil {
+			return nil, err
+		}
+		if err := VerifyBackwards(c.chainID, lb.Header, above.Header); err != nil {
+			return nil, fmt.Errorf("failed to verify header %d from primary %s: %w", h, c.primary.ID(), err)
+		}
+		above = lb
+	}
+	if err := c.store.Save(above); err != nil {
+		return nil, err
+	}
+	return above, nil
+}
@@ -0,0 +1,137 @@
+package light
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+// ErrLightClientAttack is returned when the primary and a witness serve
+// different headers for the same height that both verify from the trusted
+// header. One of them is on a fork signed by validators that should be
+// slashed; the client cannot tell which, so it stops trusting either.
+var ErrLightClientAttack = errors.New("light client attack detected")
+
+type witnessResult struct {
+	witness Provider
+	faulty  bool
+	err     error
+}
+
+// compareFirstHeader checks the trusted header against the witnesses, so a
+// client is not bootstrapped from a primary on another chain or fork
+func (c *Client) compareFirstHeader(ctx context.Context, lb *types.LightBlock) error {
+	for _, witness := range c.Witnesses() {
+		wlb, err := witness.LightBlock(ctx, lb.Height())
+		if err != nil {
+			c.logger.Warn("Witness did not return the trusted header", zap.String("witness", witness.ID()), zap.Error(err))
+			continue
+		}
+		if !bytes.Equal(wlb.Hash(), lb.Hash()) {
+			return fmt.Errorf("%w: witness %s has hash %s at the trusted height %d, primary has %s",
+				ErrLightClientAttack, witness.ID(), wlb.Hash(), lb.Height(), lb.Hash())
+		}
+	}
+	return nil
+}
+
+// detectDivergence asks every witness for the header the primary just
+// served. A witness with a different header has its header verified from
+// the same trusted root: if that succeeds there is a fork, otherwise the
+// witness is faulty and dropped.
+func (c *Client) detectDivergence(ctx context.Context, trace []*types.LightBlock, now time.Time) error {
+	witnesses := c.Witnesses()
+	if len(witnesses) == 0 {
+		return nil
+	}
+	root, target := trace[0], trace[len(trace)-1]
+
+	results := make([]witnessResult, len(witnesses))
+	var wg sync.WaitGroup
+	for i, witness := range witnesses {
+		wg.Add(1)
+		go func(i int, witness Provider) {
+			defer wg.Done()
+			results[i] = c.compareWithWitness(ctx, witness, root, target, now)
+		}(i, witness)
+	}
+	wg.Wait()
+
+	var faulty []Provider
+	for _, res := range results {
+		switch {
+		case res.faulty:
+			c.logger.Warn("Dropping faulty witness", zap.String("witness", res.witness.ID()), zap.Error(res.err))
+			faulty = append(faulty, res.witness)
+		case errors.Is(res.err, ErrLightClientAttack):
+			c.logger.Error("Primary and witness disagree",
+				zap.String("primary", c.primary.ID()),
+				zap.String("witness", res.witness.ID()),
+				zap.Error(res.err),
+			)
+			return res.err
+		case res.err != nil:
+			// A witness that is down or behind cannot contradict the primary
+			c.logger.Debug("Witness unavailable", zap.String("witness", res.witness.ID()), zap.Error(res.err))
+		}
+	}
+	c.removeWitnesses(faulty)
+	return nil
+}
+
+func (c *Client) compareWithWitness(ctx context.Context, witness Provider, root, target *types.LightBlock, now time.Time) witnessResult {
+	wlb, err := witness.LightBlock(ctx, target.Height())
+	if err != nil {
+		return witnessResult{witness: witness, faulty: !unavailable(err), err: err}
+	}
+	if bytes.Equal(wlb.Hash(), target.Hash()) {
+		return witnessResult{witness: witness}
+	}
+
+	if _, err := c.verify(ctx, witness, root, wlb, now); err != nil {
+		err = fmt.Errorf("conflicting header %d does not verify: %w", wlb.Height(), err)
+		return witnessResult{witness: witness, faulty: !unavailable(err), err: err}
+	}
+	return witnessResult{witness: witness, err: fmt.Errorf("%w: height %d has hash %s on primary %s and %s on witness %s",
+		ErrLightClientAttack, target.Height(), target.Hash(), c.primary.ID(), wlb.Hash(), witness.ID())}
+}
+
+// unavailable reports whether err means the provider could not answer, as
+// opposed to answering with something invalid
+func unavailable(err error) bool {
+	return errors.Is(err, ErrLightBlockNotFound) || errors.Is(err, ErrNoResponse) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func (c *Client) removeWitnesses(faulty []Provider) {
+	if len(faulty) == 0 {
+		return
+	}
+	c.witnessMu.Lock()
+	defer c.witnessMu.Unlock()
+
+	kept := c.witnesses[:0]
+	for _, witness := range c.witnesses {
+		drop := false
+		for _, f := range faulty {
+			if f == witness {
+				drop = true
+				break
+			}
+		}
+		if !drop {
+			kept = append(kept, witness)
+		}
+	}
+	c.witnesses = kept
+	if len(kept) == 0 {
+		c.logger.Warn("All witnesses have been dropped; forks will no longer be detected")
+	}
+}
@@ -0,0 +1,93 @@
+package light
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+var (
+	// ErrLightBlockNotFound is returned by providers that do not have the
+	// requested height, usually because it is not produced yet or was pruned
+	ErrLightBlockNotFound = errors.New("light block not found")
+	// ErrNoResponse is returned when a provider cannot be reached
+	ErrNoResponse = errors.New("provider did not respond")
+)
+
+// Provider is a full node the light client fetches light blocks from
+type Provider interface {
+	// ID identifies the provider in logs and errors
+	ID() string
+	// LightBlock returns the light block at height, or the latest one when
+	// height is 0
+	LightBlock(ctx context.Context, height int64) (*types.LightBlock, error)
+}
+
+// httpProvider fetches light blocks from a node's REST API
+type httpProvider struct {
+	chainID string
+	baseURL string
+	client  *http.Client
+}
+
+// NewHTTPProvider creates a provider for the node API at baseURL, e.g.
+// http://localhost:1317
+func NewHTTPProvider(chainID, baseURL string) Provider {
+	return &httpProvider{
+		chainID: chainID,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// ID implements Provider
+func (p *httpProvider) ID() string { return p.baseURL }
+
+// LightBlock implements Provider
+func (p *httpProvider) LightBlock(ctx context.Context, height int64) (*types.LightBlock, error) {
+	h := "latest"
+	if height > 0 {
+		h = strconv.FormatInt(height, 10)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/api/v1/light_blocks/"+h, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrNoResponse, p.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%w: height %d from %s", ErrLightBlockNotFound, height, p.baseURL)
+	case resp.StatusCode >= 400:
+		return nil, fmt.Errorf("%w: %s returned %s", ErrNoResponse, p.baseURL, resp.Status)
+	}
+
+	var body struct {
+		LightBlock *types.LightBlock `json:"light_block"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode light block from %s: %w", p.baseURL, err)
+	}
+	lb := body.LightBlock
+	if lb == nil || lb.Header == nil {
+		return nil, fmt.Errorf("%w: empty response from %s", ErrLightBlockNotFound, p.baseURL)
+	}
+	if height > 0 && lb.Height() != height {
+		return nil, fmt.Errorf("%w: asked %s for height %d, got %d", ErrInvalidHeader, p.baseURL, height, lb.Height())
+	}
+	if err := lb.ValidateBasic(p.chainID); err != nil {
+		return nil, fmt.Errorf("%w: from %s: %v", ErrInvalidHeader, p.baseURL, err)
+	}
+	return lb, nil
+}
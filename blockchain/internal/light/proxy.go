@@ -0,0 +1,303 @@
+package light
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+const (
+	// headerWaitTimeout bounds how long a query waits for the header that
+	// commits to its state; proofs for the latest state need the next block
+	headerWaitTimeout  = 15 * time.Second
+	headerPollInterval = 500 * time.Millisecond
+
+	maxResponseSize = 16 << 20
+)
+
+// ProxyConfig configures the light client proxy
+type ProxyConfig struct {
+	Client     *Client
+	PrimaryURL string
+	Routes     []Route // defaults to DefaultRoutes
+	Logger     *zap.Logger
+}
+
+// Proxy serves the node API locally, answering queries only with values
+// proven against headers the light client verified. Transactions and other
+// writes are forwarded unchanged: a lying primary can drop them but cannot
+// forge them.
+type Proxy struct {
+	config  *ProxyConfig
+	client  *Client
+	primary *url.URL
+	routes  []Route
+	http    *http.Client
+	forward *httputil.ReverseProxy
+	logger  *zap.Logger
+}
+
+// NewProxy creates a light client proxy in front of the primary node
+func NewProxy(cfg *ProxyConfig) (*Proxy, error) {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	primary, err := url.Parse(strings.TrimRight(cfg.PrimaryURL, "/"))
+	if err != nil || primary.Scheme == "" || primary.Host == "" {
+		return nil, fmt.Errorf("invalid primary URL %q", cfg.PrimaryURL)
+	}
+	routes := cfg.Routes
+	if routes == nil {
+		routes = DefaultRoutes
+	}
+	return &Proxy{
+		config:  cfg,
+		client:  cfg.Client,
+		primary: primary,
+		routes:  routes,
+		http:    &http.Client{Timeout: 30 * time.Second},
+		forward: httputil.NewSingleHostReverseProxy(primary),
+		logger:  logger,
+	}, nil
+}
+
+// ServeHTTP implements http.Handler
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		p.forward.ServeHTTP(w, r)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/status" || r.URL.Path == "/api/v1/status":
+		p.status(w, r)
+	case strings.HasPrefix(r.URL.Path, "/api/v1/light_blocks/"):
+		p.lightBlock(w, r, strings.TrimPrefix(r.URL.Path, "/api/v1/light_blocks/"))
+	default:
+		route, params, ok := MatchRoute(p.routes, r.URL.Path)
+		if !ok {
+			writeJSON(w, http.StatusNotImplemented, map[string]interface{}{"error": "responses from this endpoint cannot be verified"})
+			return
+		}
+		p.provenQuery(w, r, route, params)
+	}
+}
+
+func (p *Proxy) status(w http.ResponseWriter, r *http.Request) {
+	latest, err := p.client.TrustedLightBlock(0)
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{"error": err.Error()})
+		return
+	}
+	witnesses := make([]string, 0)
+	for _, witness := range p.client.Witnesses() {
+		witnesses = append(witnesses, witness.ID())
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"chain_id":              p.client.ChainID(),
+		"primary":               p.client.Primary().ID(),
+		"witnesses":             witnesses,
+		"latest_trusted_height": latest.Height(),
+		"latest_trusted_hash":   latest.Hash(),
+		"latest_trusted_time":   latest.Header.Time,
+	})
+}
+
+func (p *Proxy) lightBlock(w http.ResponseWriter, r *http.Request, h string) {
+	var (
+		lb  *types.LightBlock
+		err error
+	)
+	if h == "latest" {
+		lb, err = p.client.Update(r.Context(), time.Now())
+	} else {
+		height, perr := strconv.ParseInt(h, 10, 64)
+		if perr != nil || height <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid height"})
+			return
+		}
+		lb, err = p.client.VerifyLightBlockAtHeight(r.Context(), height, time.Now())
+	}
+	if err != nil {
+		p.respondError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "light_block": lb})
+}
+
+// provenQuery asks the primary for the value with a proof, verifies the
+// header that commits to it and the proof, and returns only the proven value
+func (p *Proxy) provenQuery(w http.ResponseWriter, r *http.Request, route Route, params map[string]string) {
+	query := r.URL.Query()
+	query.Set("prove", "true")
+	target := *p.primary
+	target.Path += r.URL.Path
+	target.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, target.String(), nil)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+		return
+	}
+	resp, err := p.http.Do(req)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]interface{}{"error": fmt.Sprintf("failed to reach primary: %v", err)})
+		return
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]interface{}{"error": err.Error()})
+		return
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		// Other errors carry no proof; they are passed on as they are
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(resp.StatusCode)
+		w.Write(data)
+		return
+	}
+
+	value, height, err := p.verifyResponse(r.Context(), route, params, data)
+	if err != nil {
+		p.logger.Warn("Rejected response from primary", zap.String("path", r.URL.Path), zap.Error(err))
+		p.respondError(w, err)
+		return
+	}
+	if value == nil {
+		if resp.StatusCode != http.StatusNotFound {
+			p.respondError(w, fmt.Errorf("%w: primary returned a value it proved absent", types.ErrInvalidProof))
+			return
+		}
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "not found", "height": height})
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		p.respondError(w, fmt.Errorf("%w: primary reported a proven value as missing", types.ErrInvalidProof))
+		return
+	}
+	if route.Field == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(value)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":   true,
+		route.Field: json.RawMessage(value),
+		"height":    height,
+	})
+}
+
+// verifyResponse checks the proof in a response and returns the proven
+// value, or nil if the proof shows the key holds nothing
+func (p *Proxy) verifyResponse(ctx context.Context, route Route, params map[string]string, data []byte) ([]byte, int64, error) {
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, 0, fmt.Errorf("%w: undecodable response: %v", types.ErrInvalidProof, err)
+	}
+	rawProof, ok := body["proof"]
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: primary returned no proof", types.ErrInvalidProof)
+	}
+	var proof types.ValueProof
+	if err := json.Unmarshal(rawProof, &proof); err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", types.ErrInvalidProof, err)
+	}
+	if key := route.StateKey(params); !bytes.Equal(proof.Key, key) {
+		return nil, 0, fmt.Errorf("%w: proof is for key %q, expected %q", types.ErrInvalidProof, proof.Key, key)
+	}
+
+	lb, err := p.waitForHeader(ctx, proof.Height+1)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := proof.Verify(lb.Header.AppHash); err != nil {
+		return nil, 0, err
+	}
+	if !proof.Exists() {
+		return nil, proof.Height, nil
+	}
+
+	// The proof covers the stored value; the response must be that value
+	delete(body, "proof")
+	returned := data
+	if route.Field != "" {
+		returned = body[route.Field]
+	} else if returned, err = json.Marshal(body); err != nil {
+		return nil, 0, err
+	}
+	if !jsonEqual(returned, proof.Value) {
+		return nil, 0, fmt.Errorf("%w: response does not match the proven value", types.ErrInvalidProof)
+	}
+	return proof.Value, proof.Height, nil
+}
+
+// waitForHeader verifies the header at height, waiting for it to be
+// produced if needed
+func (p *Proxy) waitForHeader(ctx context.Context, height int64) (*types.LightBlock, error) {
+	ctx, cancel := context.WithTimeout(ctx, headerWaitTimeout)
+	defer cancel()
+	for {
+		lb, err := p.client.VerifyLightBlockAtHeight(ctx, height, time.Now())
+		if !errors.Is(err, ErrLightBlockNotFound) {
+			return lb, err
+		}
+		select {
+		case <-time.After(headerPollInterval):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: header %d was not produced in time", ErrLightBlockNotFound, height)
+		}
+	}
+}
+
+func (p *Proxy) respondError(w http.ResponseWriter, err error) {
+	status := http.StatusBadGateway
+	switch {
+	case errors.Is(err, ErrLightClientAttack), errors.Is(err, ErrHeaderExpired):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, ErrLightBlockNotFound):
+		status = http.StatusNotFound
+	}
+	writeJSON(w, status, map[string]interface{}{"error": err.Error()})
+}
+
+// jsonEqual compares two JSON documents by value, keeping numbers exact
+func jsonEqual(a, b []byte) bool {
+	decode := func(data []byte) (interface{}, error) {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var v interface{}
+		err := dec.Decode(&v)
+		return v, err
+	}
+	va, err := decode(a)
+	if err != nil {
+		return false
+	}
+	vb, err := decode(b)
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,83 @@
+package light
+
+import (
+	"strings"
+)
+
+// Route is a query endpoint whose response can carry a proof for one key of
+// the application state. The node attaches the proof when asked with
+// ?prove=true; the proxy checks it against a verified app hash.
+type Route struct {
+	// Path is the endpoint pattern, with :name for path parameters
+	Path string
+	// Key is the state key the endpoint reads; {name} is replaced by the
+	// path parameter
+	Key string
+	// Field is the response field holding the stored value, or empty when
+	// the whole response is the stored value
+	Field string
+}
+
+// DefaultRoutes are the provable query endpoints of the node API. Each key
+// must be committed to the state store with exactly the value the endpoint
+// returns; domain auctions are left out because their responses derive the
+// phase from the current time and hide bids during the commit phase.
+var DefaultRoutes = []Route{
+	{Path: "/api/v1/accounts/:address", Key: "accounts/{address}", Field: "account"},
+	{Path: "/api/v1/accounts/:address/balance", Key: "balances/{address}", Field: "balance"},
+	{Path: "/api/v1/staking/validators/:address", Key: "staking/validators/{address}", Field: "validator"},
+	{Path: "/api/v1/staking/delegations/:address", Key: "staking/delegations/{address}", Field: "delegations"},
+	{Path: "/api/v1/tokens/:denom", Key: "tokens/{denom}", Field: "token"},
+	{Path: "/api/v1/dex/pools/:id", Key: "dex/pools/{id}", Field: "pool"},
+}
+
+// FindRoute returns the route registered for a path pattern
+func FindRoute(routes []Route, pattern string) (Route, bool) {
+	for _, route := range routes {
+		if route.Path == pattern {
+			return route, true
+		}
+	}
+	return Route{}, false
+}
+
+// MatchRoute returns the route matching a request path and its parameters
+func MatchRoute(routes []Route, path string) (Route, map[string]string, bool) {
+	for _, route := range routes {
+		if params, ok := route.match(path); ok {
+			return route, params, true
+		}
+	}
+	return Route{}, nil, false
+}
+
+func (r Route) match(path string) (map[string]string, bool) {
+	want := strings.Split(strings.Trim(r.Path, "/"), "/")
+	got := strings.Split(strings.Trim(path, "/"), "/")
+	if len(want) != len(got) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, seg := range want {
+		if strings.HasPrefix(seg, ":") {
+			if got[i] == "" {
+				return nil, false
+			}
+			params[seg[1:]] = got[i]
+			continue
+		}
+		if seg != got[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// StateKey fills the key template with the path parameters
+func (r Route) StateKey(params map[string]string) []byte {
+	key := r.Key
+	for name, value := range params {
+		key = strings.ReplaceAll(key, "{"+name+"}", value)
+	}
+	return []byte(key)
+}
@@ -0,0 +1,148 @@
+package light
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+// DefaultMaxStoredBlocks is how many verified light blocks a store keeps
+const DefaultMaxStoredBlocks = 1000
+
+// Store keeps verified light blocks, ordered by height. With a path it is
+// saved to a JSON file after every change, so a restarted client resumes
+// from its latest trusted header rather than the configured trust options.
+type Store struct {
+	mu        sync.RWMutex
+	path      string
+	maxBlocks int
+	blocks    []*types.LightBlock // ascending height
+}
+
+// NewStore opens the store at path, or creates an in-memory store when path
+// is empty
+func NewStore(path string, maxBlocks int) (*Store, error) {
+	if maxBlocks <= 0 {
+		maxBlocks = DefaultMaxStoredBlocks
+	}
+	s := &Store{path: path, maxBlocks: maxBlocks}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.blocks); err != nil {
+		return nil, fmt.Errorf("failed to decode light block store %s: %w", path, err)
+	}
+	sort.Slice(s.blocks, func(i, j int) bool { return s.blocks[i].Height() < s.blocks[j].Height() })
+	return s, nil
+}
+
+// Save adds verified light blocks, dropping the oldest beyond the limit
+func (s *Store) Save(lbs ...*types.LightBlock) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, lb := range lbs {
+		i := s.search(lb.Height())
+		if i < len(s.blocks) && s.blocks[i].Height() == lb.Height() {
+			s.blocks[i] = lb
+			continue
+		}
+		s.blocks = append(s.blocks, nil)
+		copy(s.blocks[i+1:], s.blocks[i:])
+		s.blocks[i] = lb
+	}
+	if extra := len(s.blocks) - s.maxBlocks; extra > 0 {
+		s.blocks = append([]*types.LightBlock(nil), s.blocks[extra:]...)
+	}
+	return s.persist()
+}
+
+// LightBlock returns the stored light block at height
+func (s *Store) LightBlock(height int64) (*types.LightBlock, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.search(height)
+	if i < len(s.blocks) && s.blocks[i].Height() == height {
+		return s.blocks[i], nil
+	}
+	return nil, fmt.Errorf("%w: height %d is not in the trusted store", ErrLightBlockNotFound, height)
+}
+
+// Latest returns the highest stored light block
+func (s *Store) Latest() (*types.LightBlock, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.blocks) == 0 {
+		return nil, fmt.Errorf("%w: the trusted store is empty", ErrLightBlockNotFound)
+	}
+	return s.blocks[len(s.blocks)-1], nil
+}
+
+// Before returns the highest stored light block below height
+func (s *Store) Before(height int64) (*types.LightBlock, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if i := s.search(height); i > 0 {
+		return s.blocks[i-1], nil
+	}
+	return nil, fmt.Errorf("%w: no trusted light block below height %d", ErrLightBlockNotFound, height)
+}
+
+// After returns the lowest stored light block above height
+func (s *Store) After(height int64) (*types.LightBlock, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.search(height + 1)
+	if i < len(s.blocks) {
+		return s.blocks[i], nil
+	}
+	return nil, fmt.Errorf("%w: no trusted light block above height %d", ErrLightBlockNotFound, height)
+}
+
+// Size returns the number of stored light blocks
+func (s *Store) Size() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.blocks)
+}
+
+// search returns the index of the first block at or above height
+func (s *Store) search(height int64) int {
+	return sort.Search(len(s.blocks), func(i int) bool { return s.blocks[i].Height() >= height })
+}
+
+func (s *Store) persist() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(s.blocks)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,162 @@
+package light
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+var (
+	// ErrHeaderExpired is returned when the trusted header is older than the
+	// trusting period; its validators may have unbonded and can no longer be
+	// held accountable
+	ErrHeaderExpired = errors.New("trusted header has expired")
+	// ErrInvalidHeader is returned for headers that fail verification
+	ErrInvalidHeader = errors.New("invalid header")
+	// ErrNewValSetCantBeTrusted is returned by skipping verification when too
+	// few trusted validators signed the new header; an intermediate header
+	// has to be verified first
+	ErrNewValSetCantBeTrusted = errors.New("new validator set cannot be trusted")
+)
+
+// DefaultTrustLevel is the share of the trusted validator set that must
+// sign a header for skipping verification to accept it. Tolerating up to
+// 1/3 faulty validators, any 1/3 includes at least one honest one.
+var DefaultTrustLevel = types.Fraction{Numerator: 1, Denominator: 3}
+
+// ValidateTrustLevel checks that the trust level is within [1/3, 1]
+func ValidateTrustLevel(lvl types.Fraction) error {
+	if lvl.Denominator <= 0 || lvl.Numerator*3 < lvl.Denominator || lvl.Numerator > lvl.Denominator {
+		return fmt.Errorf("trust level must be within [1/3, 1], got %s", lvl)
+	}
+	return nil
+}
+
+// HeaderExpired reports whether the header is older than the trusting period
+func HeaderExpired(h *types.Header, trustingPeriod time.Duration, now time.Time) bool {
+	return !h.Time.Add(trustingPeriod).After(now)
+}
+
+// VerifyAdjacent verifies the header directly after the trusted one. Its
+// validator set must be the one the trusted header named as next, and more
+// than 2/3 of it must have signed.
+func VerifyAdjacent(
+	chainID string,
+	trusted, untrusted *types.LightBlock,
+	trustingPeriod time.Duration,
+	now time.Time,
+	maxClockDrift time.Duration,
+) error {
+	if untrusted.Height() != trusted.Height()+1 {
+		return fmt.Errorf("%w: headers must be adjacent", ErrInvalidHeader)
+	}
+	if HeaderExpired(trusted.Header, trustingPeriod, now) {
+		return fmt.Errorf("%w: header %d from %s", ErrHeaderExpired, trusted.Height(), trusted.Header.Time)
+	}
+	if err := verifyNewHeaderAndVals(chainID, trusted, untrusted, now, maxClockDrift); err != nil {
+		return err
+	}
+	if !bytes.Equal(untrusted.Header.LastBlockHash, trusted.Hash()) {
+		return fmt.Errorf("%w: header does not link to the trusted header", ErrInvalidHeader)
+	}
+	if !bytes.Equal(untrusted.Header.ValidatorsHash, trusted.Header.NextValidatorsHash) {
+		return fmt.Errorf("%w: validator set differs from the one the trusted header named", ErrInvalidHeader)
+	}
+	if err := untrusted.ValidatorSet.VerifyCommitLight(chainID, untrusted.Hash(), untrusted.Height(), untrusted.Commit); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+	}
+	return nil
+}
+
+// VerifyNonAdjacent verifies a header any number of heights past the
+// trusted one. Validators of the trusted set holding more than trustLevel
+// of its power must have signed it, and more than 2/3 of its own set.
+func VerifyNonAdjacent(
+	chainID string,
+	trusted, untrusted *types.LightBlock,
+	trustingPeriod time.Duration,
+	now time.Time,
+	maxClockDrift time.Duration,
+	trustLevel types.Fraction,
+) error {
+	if untrusted.Height() == trusted.Height()+1 {
+		return fmt.Errorf("%w: headers must be non-adjacent", ErrInvalidHeader)
+	}
+	if HeaderExpired(trusted.Header, trustingPeriod, now) {
+		return fmt.Errorf("%w: header %d from %s", ErrHeaderExpired, trusted.Height(), trusted.Header.Time)
+	}
+	if err := verifyNewHeaderAndVals(chainID, trusted, untrusted, now, maxClockDrift); err != nil {
+		return err
+	}
+
+	err := trusted.ValidatorSet.VerifyCommitLightTrusting(chainID, untrusted.Commit, trustLevel)
+	if errors.Is(err, types.ErrNotEnoughVotingPower) {
+		return fmt.Errorf("%w: %v", ErrNewValSetCantBeTrusted, err)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+	}
+
+	// Checked last: it is the most expensive and a header that fails the
+	// trust check is the common case during bisection
+	if err := untrusted.ValidatorSet.VerifyCommitLight(chainID, untrusted.Hash(), untrusted.Height(), untrusted.Commit); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+	}
+	return nil
+}
+
+// Verify verifies untrusted against trusted, adjacent or skipping as the
+// heights require
+func Verify(
+	chainID string,
+	trusted, untrusted *types.LightBlock,
+	trustingPeriod time.Duration,
+	now time.Time,
+	maxClockDrift time.Duration,
+	trustLevel types.Fraction,
+) error {
+	if untrusted.Height() == trusted.Height()+1 {
+		return VerifyAdjacent(chainID, trusted, untrusted, trustingPeriod, now, maxClockDrift)
+	}
+	return VerifyNonAdjacent(chainID, trusted, untrusted, trustingPeriod, now, maxClockDrift, trustLevel)
+}
+
+// VerifyBackwards checks that untrusted is the parent of the trusted header
+// by following the hash link
+func VerifyBackwards(chainID string, untrusted, trusted *types.Header) error {
+	if err := untrusted.ValidateBasic(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+	}
+	if untrusted.ChainID != chainID {
+		return fmt.Errorf("%w: header is for chain %q, expected %q", ErrInvalidHeader, untrusted.ChainID, chainID)
+	}
+	if untrusted.Height+1 != trusted.Height {
+		return fmt.Errorf("%w: expected height %d, got %d", ErrInvalidHeader, trusted.Height-1, untrusted.Height)
+	}
+	if !untrusted.Time.Before(trusted.Time) {
+		return fmt.Errorf("%w: header time %s is not before the trusted header time %s", ErrInvalidHeader, untrusted.Time, trusted.Time)
+	}
+	if !bytes.Equal(untrusted.Hash(), trusted.LastBlockHash) {
+		return fmt.Errorf("%w: header hash does not match the trusted header's last block hash", ErrInvalidHeader)
+	}
+	return nil
+}
+
+func verifyNewHeaderAndVals(chainID string, trusted, untrusted *types.LightBlock, now time.Time, maxClockDrift time.Duration) error {
+	if err := untrusted.ValidateBasic(chainID); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+	}
+	if untrusted.Height() <= trusted.Height() {
+		return fmt.Errorf("%w: height %d is not above the trusted height %d", ErrInvalidHeader, untrusted.Height(), trusted.Height())
+	}
+	if !untrusted.Header.Time.After(trusted.Header.Time) {
+		return fmt.Errorf("%w: header time %s is not after the trusted header time %s", ErrInvalidHeader, untrusted.Header.Time, trusted.Header.Time)
+	}
+	if untrusted.Header.Time.After(now.Add(maxClockDrift)) {
+		return fmt.Errorf("%w: header time %s is in the future (now %s, max clock drift %s)", ErrInvalidHeader, untrusted.Header.Time, now, maxClockDrift)
+	}
+	return nil
+}
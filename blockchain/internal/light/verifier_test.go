@@ -0,0 +1,237 @@
+package light
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+const testChainID = "vindex-test"
+
+var (
+	genesisTime    = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	trustingPeriod = 14 * 24 * time.Hour
+	maxClockDrift  = 10 * time.Second
+)
+
+// testKeys returns n deterministic validator keys
+func testKeys(n int, seed byte) []ed25519.PrivateKey {
+	keys := make([]ed25519.PrivateKey, n)
+	for i := range keys {
+		s := sha256.Sum256([]byte{seed, byte(i)})
+		keys[i] = ed25519.NewKeyFromSeed(s[:])
+	}
+	return keys
+}
+
+func validatorSet(keys []ed25519.PrivateKey) *types.ValidatorSet {
+	vals := make([]*types.Validator, len(keys))
+	for i, key := range keys {
+		vals[i] = types.NewValidator(key.Public().(ed25519.PublicKey), 10)
+	}
+	return types.NewValidatorSet(vals)
+}
+
+// lightBlock builds a block at height on top of last, signed by the first
+// signers of keys
+func lightBlock(height int64, last *types.LightBlock, keys, nextKeys []ed25519.PrivateKey, signers int) *types.LightBlock {
+	vals := validatorSet(keys)
+	header := &types.Header{
+		ChainID:            testChainID,
+		Height:             height,
+		Time:               genesisTime.Add(time.Duration(height) * 5 * time.Second),
+		ValidatorsHash:     vals.Hash(),
+		NextValidatorsHash: validatorSet(nextKeys).Hash(),
+		AppHash:            []byte{byte(height)},
+	}
+	if last != nil {
+		header.LastBlockHash = last.Hash()
+	} else if height > 1 {
+		parent := sha256.Sum256([]byte{byte(height - 1)}) // a parent the test does not need
+		header.LastBlockHash = parent[:]
+	}
+
+	byAddress := make(map[string]ed25519.PrivateKey, len(keys))
+	for _, key := range keys[:signers] {
+		byAddress[types.AddressFromPubKey(key.Public().(ed25519.PublicKey)).String()] = key
+	}
+	commit := &types.Commit{Height: height, BlockHash: header.Hash(), Signatures: make([]types.CommitSig, vals.Size())}
+	for i, val := range vals.Validators {
+		key, ok := byAddress[val.Address.String()]
+		if !ok {
+			continue
+		}
+		commit.Signatures[i] = types.CommitSig{ValidatorAddress: val.Address, Timestamp: header.Time}
+		commit.Signatures[i].Signature = ed25519.Sign(key, commit.VoteSignBytes(testChainID, i))
+	}
+	return &types.LightBlock{Header: header, Commit: commit, ValidatorSet: vals}
+}
+
+func TestVerify(t *testing.T) {
+	keys := testKeys(4, 1)
+	others := testKeys(4, 2)
+	mixed := append(append([]ed25519.PrivateKey{}, keys[:1]...), others[:3]...) // one of four trusted validators stays
+
+	trusted := lightBlock(1, nil, keys, keys, 4)
+	now := trusted.Header.Time.Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		untrusted func() *types.LightBlock
+		now       time.Time
+		wantErr   error
+	}{
+		{
+			name:      "adjacent",
+			untrusted: func() *types.LightBlock { return lightBlock(2, trusted, keys, keys, 3) },
+		},
+		{
+			name:      "adjacent signed by half the power",
+			untrusted: func() *types.LightBlock { return lightBlock(2, trusted, keys, keys, 2) },
+			wantErr:   ErrInvalidHeader,
+		},
+		{
+			name: "adjacent not linked to the trusted header",
+			untrusted: func() *types.LightBlock {
+				return lightBlock(2, lightBlock(1, nil, keys, others, 4), keys, keys, 4)
+			},
+			wantErr: ErrInvalidHeader,
+		},
+		{
+			name:      "adjacent with validators the trusted header did not name",
+			untrusted: func() *types.LightBlock { return lightBlock(2, trusted, others, others, 4) },
+			wantErr:   ErrInvalidHeader,
+		},
+		{
+			name: "adjacent with a forged signature",
+			untrusted: func() *types.LightBlock {
+				lb := lightBlock(2, trusted, keys, keys, 4)
+				lb.Commit.Signatures[0].Signature[0] ^= 1
+				return lb
+			},
+			wantErr: ErrInvalidHeader,
+		},
+		{
+			name: "adjacent for another chain",
+			untrusted: func() *types.LightBlock {
+				lb := lightBlock(2, trusted, keys, keys, 4)
+				lb.Header.ChainID = "other"
+				return lb
+			},
+			wantErr: ErrInvalidHeader,
+		},
+		{
+			name:      "trusted header expired",
+			untrusted: func() *types.LightBlock { return lightBlock(2, trusted, keys, keys, 4) },
+			now:       trusted.Header.Time.Add(trustingPeriod),
+			wantErr:   ErrHeaderExpired,
+		},
+		{
+			name:      "header from the future",
+			untrusted: func() *types.LightBlock { return lightBlock(2, trusted, keys, keys, 4) },
+			now:       trusted.Header.Time.Add(-maxClockDrift),
+			wantErr:   ErrInvalidHeader,
+		},
+		{
+			name:      "skipping with the same validators",
+			untrusted: func() *types.LightBlock { return lightBlock(50, lightBlock(49, nil, keys, keys, 4), keys, keys, 3) },
+		},
+		{
+			name:      "skipping to a set with 1/4 of the trusted power",
+			untrusted: func() *types.LightBlock { return lightBlock(50, lightBlock(49, nil, mixed, mixed, 4), mixed, mixed, 4) },
+			wantErr:   ErrNewValSetCantBeTrusted,
+		},
+		{
+			name:      "skipping to a set with 1/2 of the trusted power",
+			untrusted: func() *types.LightBlock { return lightBlock(50, nil, append(keys[:2:2], others[:2]...), others, 4) },
+		},
+		{
+			name:      "skipping without a quorum of the new set",
+			untrusted: func() *types.LightBlock { return lightBlock(50, nil, append(keys[:2:2], others[:2]...), others, 2) },
+			wantErr:   ErrInvalidHeader,
+		},
+		{
+			name:      "not above the trusted height",
+			untrusted: func() *types.LightBlock { return trusted },
+			wantErr:   ErrInvalidHeader,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := tt.now
+			if at.IsZero() {
+				at = now
+			}
+			untrusted := tt.untrusted()
+			if untrusted.Height() > 2 {
+				at = untrusted.Header.Time.Add(time.Hour)
+			}
+			err := Verify(testChainID, trusted, untrusted, trustingPeriod, at, maxClockDrift, DefaultTrustLevel)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Verify: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify = %v; want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyBackwards(t *testing.T) {
+	keys := testKeys(4, 1)
+	parent := lightBlock(9, nil, keys, keys, 4)
+	child := lightBlock(10, parent, keys, keys, 4)
+
+	if err := VerifyBackwards(testChainID, parent.Header, child.Header); err != nil {
+		t.Fatalf("VerifyBackwards: %v", err)
+	}
+
+	forged := *parent.Header
+	forged.AppHash = []byte("forged")
+	tests := map[string]*types.Header{
+		"forged parent":  &forged,
+		"not the parent": lightBlock(8, nil, keys, keys, 4).Header,
+		"other chain":    func() *types.Header { h := *parent.Header; h.ChainID = "other"; return &h }(),
+	}
+	for name, header := range tests {
+		if err := VerifyBackwards(testChainID, header, child.Header); !errors.Is(err, ErrInvalidHeader) {
+			t.Errorf("%s: VerifyBackwards = %v; want ErrInvalidHeader", name, err)
+		}
+	}
+}
+
+func TestValidateTrustLevel(t *testing.T) {
+	tests := []struct {
+		level types.Fraction
+		valid bool
+	}{
+		{types.Fraction{Numerator: 1, Denominator: 3}, true},
+		{types.Fraction{Numerator: 2, Denominator: 3}, true},
+		{types.Fraction{Numerator: 1, Denominator: 1}, true},
+		{types.Fraction{Numerator: 1, Denominator: 4}, false},
+		{types.Fraction{Numerator: 4, Denominator: 3}, false},
+		{types.Fraction{Numerator: 1, Denominator: 0}, false},
+	}
+	for _, tt := range tests {
+		if err := ValidateTrustLevel(tt.level); (err == nil) != tt.valid {
+			t.Errorf("ValidateTrustLevel(%s) = %v; want valid %v", tt.level, err, tt.valid)
+		}
+	}
+}
+
+func TestHeaderExpired(t *testing.T) {
+	h := &types.Header{Time: genesisTime}
+	if HeaderExpired(h, trustingPeriod, genesisTime.Add(trustingPeriod-time.Second)) {
+		t.Error("header expired before the end of the trusting period")
+	}
+	if !HeaderExpired(h, trustingPeriod, genesisTime.Add(trustingPeriod)) {
+		t.Error("header still trusted at the end of the trusting period")
+	}
+}
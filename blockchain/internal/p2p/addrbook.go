@@ -0,0 +1,663 @@
+package p2p
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	mrand "math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Address book layout. Addresses learned from gossip go into "new" buckets;
+// once we have connected to an address it moves to an "old" bucket. Bucket
+// placement is keyed by a secret and the network group of the address and
+// its source, so a single peer cannot fill the book with its own addresses.
+const (
+	newBucketCount        = 256
+	oldBucketCount        = 64
+	bucketSize            = 64
+	newBucketsPerGroup    = 32
+	oldBucketsPerGroup    = 4
+	maxNewBucketsPerAddr  = 4
+	needAddressThreshold  = 1000
+	maxGetSelection       = 250
+	minGetSelection       = 32
+	getSelectionPercent   = 23
+	maxFailedAttempts     = 10
+	numMissingDays        = 30
+	addrBookSaveInterval  = 2 * time.Minute
+	bucketTypeNew         = "new"
+	bucketTypeOld         = "old"
+	defaultBiasTowardsNew = 30
+)
+
+var (
+	// ErrAddrBookNonRoutable is returned for addresses that cannot be dialed
+	ErrAddrBookNonRoutable = errors.New("address is not routable")
+	// ErrPeerBanned is returned for addresses and connections of banned peers
+	ErrPeerBanned = errors.New("peer is banned")
+	// ErrPeerNotBanned is returned when lifting a ban that does not exist
+	ErrPeerNotBanned = errors.New("peer is not banned")
+)
+
+// Ban keeps a peer from connecting or being dialed until it expires
+type Ban struct {
+	ID     ID          `json:"id"`
+	Addr   *NetAddress `json:"addr,omitempty"` // restored to the book when the ban ends
+	Reason string      `json:"reason"`
+	Since  time.Time   `json:"since"`
+	Until  time.Time   `json:"until"`
+}
+
+type knownAddress struct {
+	Addr        *NetAddress `json:"addr"`
+	Src         ID          `json:"src"`
+	Attempts    int         `json:"attempts"`
+	LastAttempt time.Time   `json:"last_attempt"`
+	LastSuccess time.Time   `json:"last_success"`
+	BucketType  string      `json:"bucket_type"`
+	Buckets     []int       `json:"buckets"`
+}
+
+// isBad reports whether an address has failed often enough to be dropped
+func (ka *knownAddress) isBad(now time.Time) bool {
+	if ka.LastAttempt.After(now.Add(-time.Minute)) {
+		return false
+	}
+	if ka.LastSuccess.IsZero() && ka.Attempts >= 3 {
+		return true
+	}
+	if ka.Attempts >= maxFailedAttempts && now.Sub(ka.LastSuccess) > 7*24*time.Hour {
+		return true
+	}
+	return now.Sub(ka.LastAttempt) > numMissingDays*24*time.Hour && !ka.LastAttempt.IsZero()
+}
+
+// AddrBook stores the addresses of known peers and persists them to disk
+type AddrBook struct {
+	mu       sync.Mutex
+	filePath string
+	key      string
+	logger   *zap.Logger
+	rand     *mrand.Rand
+
+	ourAddrs   map[string]bool
+	addrLookup map[ID]*knownAddress
+	banned     map[ID]*Ban
+	bucketsNew []map[string]*knownAddress
+	bucketsOld []map[string]*knownAddress
+	nNew       int
+	nOld       int
+}
+
+type addrBookJSON struct {
+	Key    string          `json:"key"`
+	Addrs  []*knownAddress `json:"addrs"`
+	Banned []*Ban          `json:"banned,omitempty"`
+}
+
+// NewAddrBook creates an address book backed by filePath. Call Load to read
+// a previously saved book.
+func NewAddrBook(filePath string, logger *zap.Logger) *AddrBook {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	var seed [8]byte
+	rand.Read(seed[:])
+	var key [12]byte
+	rand.Read(key[:])
+
+	book := &AddrBook{
+		filePath:   filePath,
+		key:        hex.EncodeToString(key[:]),
+		logger:     logger,
+		rand:       mrand.New(mrand.NewSource(int64(binary.LittleEndian.Uint64(seed[:])))),
+		ourAddrs:   make(map[string]bool),
+		addrLookup: make(map[ID]*knownAddress),
+		banned:     make(map[ID]*Ban),
+	}
+	book.initBuckets()
+	return book
+}
+
+func (a *AddrBook) initBuckets() {
+	a.bucketsNew = make([]map[string]*knownAddress, newBucketCount)
+	for i := range a.bucketsNew {
+		a.bucketsNew[i] = make(map[string]*knownAddress)
+	}
+	a.bucketsOld = make([]map[string]*knownAddress, oldBucketCount)
+	for i := range a.bucketsOld {
+		a.bucketsOld[i] = make(map[string]*knownAddress)
+	}
+	a.addrLookup = make(map[ID]*knownAddress)
+	a.nNew, a.nOld = 0, 0
+}
+
+// Load reads the address book from disk. A missing file leaves the book empty.
+func (a *AddrBook) Load() error {
+	data, err := os.ReadFile(a.filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var stored addrBookJSON
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("failed to parse address book %s: %w", a.filePath, err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.key = stored.Key
+	a.initBuckets()
+	for _, ka := range stored.Addrs {
+		if ka.Addr == nil || ka.Addr.ID.Validate() != nil {
+			continue
+		}
+		buckets := a.bucketsNew
+		if ka.BucketType == bucketTypeOld {
+			buckets = a.bucketsOld
+		}
+		kept := ka.Buckets[:0]
+		for _, idx := range ka.Buckets {
+			if idx >= 0 && idx < len(buckets) {
+				buckets[idx][ka.Addr.String()] = ka
+				kept = append(kept, idx)
+			}
+		}
+		if len(kept) == 0 {
+			continue
+		}
+		ka.Buckets = kept
+		a.addrLookup[ka.Addr.ID] = ka
+		if ka.BucketType == bucketTypeOld {
+			a.nOld++
+		} else {
+			a.nNew++
+		}
+	}
+	a.banned = make(map[ID]*Ban, len(stored.Banned))
+	for _, ban := range stored.Banned {
+		if ban != nil && ban.ID.Validate() == nil {
+			a.banned[ban.ID] = ban
+		}
+	}
+	a.expireBans(time.Now().UTC())
+	a.logger.Info("Loaded address book", zap.Int("new", a.nNew), zap.Int("old", a.nOld), zap.Int("banned", len(a.banned)))
+	return nil
+}
+
+// Save writes the address book to disk
+func (a *AddrBook) Save() error {
+	a.mu.Lock()
+	a.expireBans(time.Now().UTC())
+	stored := addrBookJSON{Key: a.key, Addrs: make([]*knownAddress, 0, len(a.addrLookup))}
+	for _, ka := range a.addrLookup {
+		copied := *ka
+		copied.Buckets = append([]int(nil), ka.Buckets...)
+		stored.Addrs = append(stored.Addrs, &copied)
+	}
+	for _, ban := range a.banned {
+		copied := *ban
+		stored.Banned = append(stored.Banned, &copied)
+	}
+	a.mu.Unlock()
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(a.filePath), 0o700); err != nil {
+		return err
+	}
+	tmp := a.filePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, a.filePath)
+}
+
+// AddOurAddress records one of our own addresses so it is never stored or dialed
+func (a *AddrBook) AddOurAddress(addr *NetAddress) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.ourAddrs[addr.String()] = true
+}
+
+// AddAddress adds an address learned from src. Addresses already in an old
+// bucket are left alone; new addresses may be placed in a few more buckets
+// the more often they are heard of.
+func (a *AddrBook) AddAddress(addr *NetAddress, src ID) error {
+	if addr == nil || addr.ID.Validate() != nil {
+		return ErrInvalidAddress
+	}
+	if !isRoutable(addr.Host) {
+		return fmt.Errorf("%w: %s", ErrAddrBookNonRoutable, addr)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.ourAddrs[addr.String()] {
+		return nil
+	}
+	if a.isBannedLocked(addr.ID, time.Now().UTC()) {
+		return fmt.Errorf("%w: %s", ErrPeerBanned, addr.ID)
+	}
+
+	ka := a.addrLookup[addr.ID]
+	if ka != nil {
+		if ka.BucketType == bucketTypeOld || len(ka.Buckets) >= maxNewBucketsPerAddr {
+			return nil
+		}
+		// The more buckets an address is in, the less likely it is added again
+		if a.rand.Intn(2*len(ka.Buckets)) != 0 {
+			return nil
+		}
+		if !ka.Addr.Equal(addr) {
+			// Same node, new endpoint: keep the existing entry
+			return nil
+		}
+	} else {
+		ka = &knownAddress{Addr: addr, Src: src, BucketType: bucketTypeNew}
+	}
+
+	idx := a.newBucketIndex(addr, src)
+	a.addToNewBucket(ka, idx)
+	return nil
+}
+
+// MarkAttempt records a failed dial attempt
+func (a *AddrBook) MarkAttempt(addr *NetAddress) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ka := a.addrLookup[addr.ID]
+	if ka == nil {
+		return
+	}
+	ka.Attempts++
+	ka.LastAttempt = time.Now().UTC()
+	// Addresses that never worked, or stopped working, are dropped
+	if (ka.LastSuccess.IsZero() && ka.Attempts >= 3) || ka.Attempts >= maxFailedAttempts {
+		a.removeFromAllBuckets(ka)
+	}
+}
+
+// MarkGood records a successful connection and moves the address to an old
+// bucket
+func (a *AddrBook) MarkGood(id ID) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ka := a.addrLookup[id]
+	if ka == nil {
+		return
+	}
+	now := time.Now().UTC()
+	ka.Attempts = 0
+	ka.LastAttempt = now
+	ka.LastSuccess = now
+	if ka.BucketType == bucketTypeNew {
+		a.moveToOld(ka)
+	}
+}
+
+// RemoveAddress forgets an address
+func (a *AddrBook) RemoveAddress(addr *NetAddress) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if ka := a.addrLookup[addr.ID]; ka != nil {
+		a.removeFromAllBuckets(ka)
+	}
+}
+
+// HasAddress reports whether the book knows an address
+func (a *AddrBook) HasAddress(addr *NetAddress) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ka := a.addrLookup[addr.ID]
+	return ka != nil && ka.Addr.Equal(addr)
+}
+
+// Size returns the number of known addresses
+func (a *AddrBook) Size() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.nNew + a.nOld
+}
+
+// NeedMoreAddrs reports whether the book should ask peers for addresses
+func (a *AddrBook) NeedMoreAddrs() bool {
+	return a.Size() < needAddressThreshold
+}
+
+// PickAddress chooses a random address to dial. biasTowardsNew is the
+// percentage chance (0-100) of picking from the new buckets.
+func (a *AddrBook) PickAddress(biasTowardsNew int) *NetAddress {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.nNew+a.nOld == 0 {
+		return nil
+	}
+	biasTowardsNew = max(0, min(100, biasTowardsNew))
+
+	buckets := a.bucketsOld
+	switch {
+	case a.nOld == 0:
+		buckets = a.bucketsNew
+	case a.nNew > 0 && a.rand.Intn(100) < biasTowardsNew:
+		buckets = a.bucketsNew
+	}
+
+	// Pick a random non-empty bucket, then a random entry in it
+	for {
+		bucket := buckets[a.rand.Intn(len(buckets))]
+		if len(bucket) == 0 {
+			continue
+		}
+		target := a.rand.Intn(len(bucket))
+		for _, ka := range bucket {
+			if target == 0 {
+				return ka.Addr
+			}
+			target--
+		}
+	}
+}
+
+// GetSelection returns a random sample of known addresses to share with a peer
+func (a *AddrBook) GetSelection() []*NetAddress {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	all := make([]*NetAddress, 0, len(a.addrLookup))
+	for _, ka := range a.addrLookup {
+		all = append(all, ka.Addr)
+	}
+	num := min(maxGetSelection, max(min(minGetSelection, len(all)), len(all)*getSelectionPercent/100))
+	a.rand.Shuffle(len(all), func(i, j int) { all[i], all[j] = all[j], all[i] })
+	return all[:num]
+}
+
+// Ban bans a peer for duration and removes its address from the book. A
+// second ban of the same peer replaces the first.
+func (a *AddrBook) Ban(id ID, duration time.Duration, reason string) Ban {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now().UTC()
+	ban := &Ban{ID: id, Reason: reason, Since: now, Until: now.Add(duration)}
+	if previous := a.banned[id]; previous != nil {
+		ban.Addr = previous.Addr
+	}
+	if ka := a.addrLookup[id]; ka != nil {
+		ban.Addr = ka.Addr
+		a.removeFromAllBuckets(ka)
+	}
+	a.banned[id] = ban
+	return *ban
+}
+
+// Unban lifts a ban and restores the peer's address
+func (a *AddrBook) Unban(id ID) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ban := a.banned[id]
+	if ban == nil {
+		return fmt.Errorf("%w: %s", ErrPeerNotBanned, id)
+	}
+	a.liftBan(ban)
+	return nil
+}
+
+// IsBanned reports whether a peer is currently banned
+func (a *AddrBook) IsBanned(id ID) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.isBannedLocked(id, time.Now().UTC())
+}
+
+// IsBannedHost reports whether a banned peer was last known at host, so
+// inbound connections from it can be dropped before the handshake
+func (a *AddrBook) IsBannedHost(host string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now().UTC()
+	for id, ban := range a.banned {
+		if ban.Addr != nil && ban.Addr.Host == host && a.isBannedLocked(id, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// Bans returns the active bans ordered by expiry
+func (a *AddrBook) Bans() []Ban {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.expireBans(time.Now().UTC())
+	bans := make([]Ban, 0, len(a.banned))
+	for _, ban := range a.banned {
+		bans = append(bans, *ban)
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].Until.Before(bans[j].Until) })
+	return bans
+}
+
+func (a *AddrBook) isBannedLocked(id ID, now time.Time) bool {
+	ban := a.banned[id]
+	if ban == nil {
+		return false
+	}
+	if now.Before(ban.Until) {
+		return true
+	}
+	a.liftBan(ban)
+	return false
+}
+
+func (a *AddrBook) expireBans(now time.Time) {
+	for _, ban := range a.banned {
+		if !now.Before(ban.Until) {
+			a.liftBan(ban)
+		}
+	}
+}
+
+// liftBan removes a ban and puts the address back into a new bucket
+func (a *AddrBook) liftBan(ban *Ban) {
+	delete(a.banned, ban.ID)
+	if ban.Addr == nil || a.addrLookup[ban.ID] != nil {
+		return
+	}
+	ka := &knownAddress{Addr: ban.Addr, Src: ban.ID, BucketType: bucketTypeNew}
+	a.addToNewBucket(ka, a.newBucketIndex(ban.Addr, ban.ID))
+}
+
+// saveRoutine writes the book to disk periodically until quit is closed
+func (a *AddrBook) saveRoutine(quit <-chan struct{}) {
+	ticker := time.NewTicker(addrBookSaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := a.Save(); err != nil {
+				a.logger.Error("Failed to save address book", zap.Error(err))
+			}
+		case <-quit:
+			return
+		}
+	}
+}
+
+func (a *AddrBook) addToNewBucket(ka *knownAddress, idx int) {
+	for _, existing := range ka.Buckets {
+		if existing == idx {
+			return
+		}
+	}
+	bucket := a.bucketsNew[idx]
+	if len(bucket) >= bucketSize {
+		a.expireNew(idx)
+	}
+	bucket[ka.Addr.String()] = ka
+	ka.Buckets = append(ka.Buckets, idx)
+	if len(ka.Buckets) == 1 {
+		a.addrLookup[ka.Addr.ID] = ka
+		a.nNew++
+	}
+}
+
+// expireNew makes room in a full new bucket, preferring to drop a bad
+// address and otherwise the oldest one
+func (a *AddrBook) expireNew(idx int) {
+	now := time.Now().UTC()
+	var oldest *knownAddress
+	for _, ka := range a.bucketsNew[idx] {
+		if ka.isBad(now) {
+			a.removeFromBucket(ka, bucketTypeNew, idx)
+			return
+		}
+		if oldest == nil || ka.LastAttempt.Before(oldest.LastAttempt) {
+			oldest = ka
+		}
+	}
+	if oldest != nil {
+		a.removeFromBucket(oldest, bucketTypeNew, idx)
+	}
+}
+
+// moveToOld promotes an address from the new buckets to an old bucket. If
+// that bucket is full its oldest entry is demoted back to a new bucket.
+func (a *AddrBook) moveToOld(ka *knownAddress) {
+	for _, idx := range append([]int(nil), ka.Buckets...) {
+		delete(a.bucketsNew[idx], ka.Addr.String())
+	}
+	ka.Buckets = nil
+	a.nNew--
+
+	idx := a.oldBucketIndex(ka.Addr)
+	bucket := a.bucketsOld[idx]
+	if len(bucket) >= bucketSize {
+		var oldest *knownAddress
+		for _, candidate := range bucket {
+			if oldest == nil || candidate.LastSuccess.Before(oldest.LastSuccess) {
+				oldest = candidate
+			}
+		}
+		delete(bucket, oldest.Addr.String())
+		a.nOld--
+		oldest.BucketType = bucketTypeNew
+		oldest.Buckets = nil
+		delete(a.addrLookup, oldest.Addr.ID)
+		a.addToNewBucket(oldest, a.newBucketIndex(oldest.Addr, oldest.Src))
+	}
+
+	ka.BucketType = bucketTypeOld
+	ka.Buckets = []int{idx}
+	bucket[ka.Addr.String()] = ka
+	a.nOld++
+}
+
+func (a *AddrBook) removeFromBucket(ka *knownAddress, bucketType string, idx int) {
+	buckets := a.bucketsNew
+	if bucketType == bucketTypeOld {
+		buckets = a.bucketsOld
+	}
+	delete(buckets[idx], ka.Addr.String())
+
+	kept := ka.Buckets[:0]
+	for _, existing := range ka.Buckets {
+		if existing != idx {
+			kept = append(kept, existing)
+		}
+	}
+	ka.Buckets = kept
+	if len(ka.Buckets) == 0 {
+		delete(a.addrLookup, ka.Addr.ID)
+		if bucketType == bucketTypeOld {
+			a.nOld--
+		} else {
+			a.nNew--
+		}
+	}
+}
+
+func (a *AddrBook) removeFromAllBuckets(ka *knownAddress) {
+	for _, idx := range append([]int(nil), ka.Buckets...) {
+		a.removeFromBucket(ka, ka.BucketType, idx)
+	}
+}
+
+// newBucketIndex spreads each source group over a limited number of buckets
+func (a *AddrBook) newBucketIndex(addr *NetAddress, src ID) int {
+	srcGroup := string(src)
+	if srcKa := a.addrLookup[src]; srcKa != nil {
+		srcGroup = groupKey(srcKa.Addr.Host)
+	}
+	hash1 := a.hash(groupKey(addr.Host), srcGroup)
+	hash2 := a.hash(srcGroup, fmt.Sprint(hash1%newBucketsPerGroup))
+	return int(hash2 % newBucketCount)
+}
+
+// oldBucketIndex spreads each network group over a limited number of buckets
+func (a *AddrBook) oldBucketIndex(addr *NetAddress) int {
+	hash1 := a.hash(addr.String())
+	hash2 := a.hash(groupKey(addr.Host), fmt.Sprint(hash1%oldBucketsPerGroup))
+	return int(hash2 % oldBucketCount)
+}
+
+func (a *AddrBook) hash(parts ...string) uint64 {
+	h := sha256.New()
+	h.Write([]byte(a.key))
+	for _, part := range parts {
+		h.Write([]byte{0})
+		h.Write([]byte(part))
+	}
+	return binary.BigEndian.Uint64(h.Sum(nil)[:8])
+}
+
+// groupKey returns the network group of a host: its /16 for IPv4, /32 for
+// IPv6 and the name itself for hostnames
+func groupKey(host string) string {
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return host
+	case ip.To4() != nil:
+		return ip.Mask(net.CIDRMask(16, 32)).String()
+	default:
+		return ip.Mask(net.CIDRMask(32, 128)).String()
+	}
+}
+
+// isRoutable rejects unspecified addresses. Loopback and private ranges stay
+// allowed so local and private testnets keep working.
+func isRoutable(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return host != ""
+	}
+	return !ip.IsUnspecified() && !ip.IsMulticast()
+}
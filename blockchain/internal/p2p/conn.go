@@ -0,0 +1,262 @@
+package p2p
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Every frame on a secret connection has the same sealed size so message
+// lengths are not visible on the wire
+const (
+	dataLenSize     = 4
+	dataMaxSize     = 1024
+	totalFrameSize  = dataLenSize + dataMaxSize
+	sealedFrameSize = totalFrameSize + chacha20poly1305.Overhead
+
+	authMsgSize = ed25519.PublicKeySize + ed25519.SignatureSize
+)
+
+var (
+	// ErrHandshakeFailed is returned when the remote side fails to
+	// authenticate during the secret connection handshake
+	ErrHandshakeFailed = errors.New("secret connection handshake failed")
+	// ErrFrameCorrupt is returned for frames that fail authentication
+	ErrFrameCorrupt = errors.New("secret connection frame failed authentication")
+	// ErrNonceOverflow is returned once a connection has sealed 2^64 frames
+	ErrNonceOverflow = errors.New("secret connection nonce overflow")
+)
+
+var secretConnKeyInfo = []byte("VINDEXCHAIN_SECRET_CONNECTION_KEY_AND_CHALLENGE_GEN")
+
+// SecretConnection is an authenticated, encrypted connection. Both sides
+// agree on session keys with an ephemeral X25519 exchange and then prove
+// their identity by signing a challenge derived from that exchange with
+// their node key (Station-to-Station). Frames are sealed with
+// ChaCha20-Poly1305 under a separate key and nonce counter per direction.
+type SecretConnection struct {
+	conn      net.Conn
+	remPubKey ed25519.PublicKey
+	sendAEAD  cipher.AEAD
+	recvAEAD  cipher.AEAD
+
+	sendMtx   sync.Mutex
+	sendNonce [chacha20poly1305.NonceSize]byte
+
+	recvMtx    sync.Mutex
+	recvNonce  [chacha20poly1305.NonceSize]byte
+	recvBuffer []byte
+}
+
+// MakeSecretConnection runs the handshake over conn and returns the
+// encrypted connection. The caller should set a deadline on conn.
+func MakeSecretConnection(conn net.Conn, privKey ed25519.PrivateKey) (*SecretConnection, error) {
+	var locEphPriv [32]byte
+	if _, err := rand.Read(locEphPriv[:]); err != nil {
+		return nil, err
+	}
+	locEphPub, err := curve25519.X25519(locEphPriv[:], curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+
+	remEphPub, err := exchange(conn, locEphPub, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: ephemeral key exchange: %v", ErrHandshakeFailed, err)
+	}
+	// X25519 rejects low order points, which would force a known shared secret
+	shared, err := curve25519.X25519(locEphPriv[:], remEphPub)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrHandshakeFailed, err)
+	}
+
+	loEph, hiEph := locEphPub, remEphPub
+	locIsLeast := bytes.Compare(locEphPub, remEphPub) < 0
+	if !locIsLeast {
+		loEph, hiEph = remEphPub, locEphPub
+	}
+	recvKey, sendKey, challenge, err := deriveSecrets(shared, loEph, hiEph, locIsLeast)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := &SecretConnection{conn: conn}
+	if sc.sendAEAD, err = chacha20poly1305.New(sendKey); err != nil {
+		return nil, err
+	}
+	if sc.recvAEAD, err = chacha20poly1305.New(recvKey); err != nil {
+		return nil, err
+	}
+
+	// Authenticate: sign the challenge, which binds both ephemeral keys
+	authMsg := make([]byte, 0, authMsgSize)
+	authMsg = append(authMsg, privKey.Public().(ed25519.PublicKey)...)
+	authMsg = append(authMsg, ed25519.Sign(privKey, challenge)...)
+	remAuth, err := exchange(sc, authMsg, authMsgSize)
+	if err != nil {
+		return nil, fmt.Errorf("%w: authentication: %v", ErrHandshakeFailed, err)
+	}
+	remPubKey := ed25519.PublicKey(remAuth[:ed25519.PublicKeySize])
+	if !ed25519.Verify(remPubKey, challenge, remAuth[ed25519.PublicKeySize:]) {
+		return nil, fmt.Errorf("%w: challenge signature does not verify", ErrHandshakeFailed)
+	}
+	if bytes.Equal(remPubKey, privKey.Public().(ed25519.PublicKey)) {
+		return nil, ErrSelfConnection
+	}
+	sc.remPubKey = remPubKey
+
+	return sc, nil
+}
+
+// exchange writes msg and reads a message of the same size concurrently, so
+// the handshake cannot deadlock on unbuffered transports
+func exchange(rw io.ReadWriter, msg []byte, size int) ([]byte, error) {
+	writeErr := make(chan error, 1)
+	go func() {
+		_, err := rw.Write(msg)
+		writeErr <- err
+	}()
+
+	remote := make([]byte, size)
+	if _, err := io.ReadFull(rw, remote); err != nil {
+		return nil, err
+	}
+	if err := <-writeErr; err != nil {
+		return nil, err
+	}
+	return remote, nil
+}
+
+// deriveSecrets expands the shared secret into a key per direction and an
+// authentication challenge. The side with the lower ephemeral key receives
+// with the first key and sends with the second.
+func deriveSecrets(shared, loEph, hiEph []byte, locIsLeast bool) (recvKey, sendKey, challenge []byte, err error) {
+	info := make([]byte, 0, len(secretConnKeyInfo)+len(loEph)+len(hiEph))
+	info = append(info, secretConnKeyInfo...)
+	info = append(info, loEph...)
+	info = append(info, hiEph...)
+
+	out := make([]byte, 2*chacha20poly1305.KeySize+32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, nil, info), out); err != nil {
+		return nil, nil, nil, err
+	}
+	first := out[:chacha20poly1305.KeySize]
+	second := out[chacha20poly1305.KeySize : 2*chacha20poly1305.KeySize]
+	challenge = out[2*chacha20poly1305.KeySize:]
+	if locIsLeast {
+		return first, second, challenge, nil
+	}
+	return second, first, challenge, nil
+}
+
+// RemotePubKey returns the authenticated node key of the remote side
+func (sc *SecretConnection) RemotePubKey() ed25519.PublicKey {
+	return sc.remPubKey
+}
+
+// Write splits data into frames and seals each one
+func (sc *SecretConnection) Write(data []byte) (int, error) {
+	sc.sendMtx.Lock()
+	defer sc.sendMtx.Unlock()
+
+	var frame [totalFrameSize]byte
+	sealed := make([]byte, 0, sealedFrameSize)
+	n := 0
+	for len(data) > 0 {
+		chunk := data
+		if len(chunk) > dataMaxSize {
+			chunk = data[:dataMaxSize]
+		}
+		data = data[len(chunk):]
+
+		binary.LittleEndian.PutUint32(frame[:dataLenSize], uint32(len(chunk)))
+		copy(frame[dataLenSize:], chunk)
+		clear(frame[dataLenSize+len(chunk):])
+
+		sealed = sc.sendAEAD.Seal(sealed[:0], sc.sendNonce[:], frame[:], nil)
+		if err := incrNonce(&sc.sendNonce); err != nil {
+			return n, err
+		}
+		if _, err := sc.conn.Write(sealed); err != nil {
+			return n, err
+		}
+		n += len(chunk)
+	}
+	return n, nil
+}
+
+// Read returns decrypted data, opening a new frame when the previous one has
+// been consumed
+func (sc *SecretConnection) Read(data []byte) (int, error) {
+	sc.recvMtx.Lock()
+	defer sc.recvMtx.Unlock()
+
+	if len(sc.recvBuffer) > 0 {
+		n := copy(data, sc.recvBuffer)
+		sc.recvBuffer = sc.recvBuffer[n:]
+		return n, nil
+	}
+
+	sealed := make([]byte, sealedFrameSize)
+	if _, err := io.ReadFull(sc.conn, sealed); err != nil {
+		return 0, err
+	}
+	frame, err := sc.recvAEAD.Open(sealed[:0], sc.recvNonce[:], sealed, nil)
+	if err != nil {
+		return 0, ErrFrameCorrupt
+	}
+	if err := incrNonce(&sc.recvNonce); err != nil {
+		return 0, err
+	}
+
+	length := binary.LittleEndian.Uint32(frame[:dataLenSize])
+	if length > dataMaxSize {
+		return 0, fmt.Errorf("%w: frame length %d exceeds %d", ErrFrameCorrupt, length, dataMaxSize)
+	}
+	chunk := frame[dataLenSize : dataLenSize+length]
+	n := copy(data, chunk)
+	sc.recvBuffer = chunk[n:]
+	return n, nil
+}
+
+// Close closes the underlying connection
+func (sc *SecretConnection) Close() error { return sc.conn.Close() }
+
+// LocalAddr returns the local network address
+func (sc *SecretConnection) LocalAddr() net.Addr { return sc.conn.LocalAddr() }
+
+// RemoteAddr returns the remote network address
+func (sc *SecretConnection) RemoteAddr() net.Addr { return sc.conn.RemoteAddr() }
+
+// SetDeadline sets the read and write deadlines of the underlying connection
+func (sc *SecretConnection) SetDeadline(t time.Time) error { return sc.conn.SetDeadline(t) }
+
+// SetReadDeadline sets the read deadline of the underlying connection
+func (sc *SecretConnection) SetReadDeadline(t time.Time) error { return sc.conn.SetReadDeadline(t) }
+
+// SetWriteDeadline sets the write deadline of the underlying connection
+func (sc *SecretConnection) SetWriteDeadline(t time.Time) error { return sc.conn.SetWriteDeadline(t) }
+
+// incrNonce increments the little endian frame counter held in the last 8
+// bytes of the nonce
+func incrNonce(nonce *[chacha20poly1305.NonceSize]byte) error {
+	counter := binary.LittleEndian.Uint64(nonce[4:])
+	if counter == ^uint64(0) {
+		return ErrNonceOverflow
+	}
+	binary.LittleEndian.PutUint64(nonce[4:], counter+1)
+	return nil
+}
@@ -0,0 +1,184 @@
+package p2p
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// IDByteLength is the length of a node ID in bytes before hex encoding
+const IDByteLength = 20
+
+// ErrInvalidAddress is returned for peer addresses that are not id@host:port
+var ErrInvalidAddress = errors.New("invalid peer address")
+
+// ID is a node's hex encoded identity, derived from its ed25519 public key
+type ID string
+
+// PubKeyToID derives the node ID of a public key: the first 20 bytes of its
+// sha256 hash, hex encoded
+func PubKeyToID(pubKey ed25519.PublicKey) ID {
+	sum := sha256.Sum256(pubKey)
+	return ID(hex.EncodeToString(sum[:IDByteLength]))
+}
+
+// Validate checks that an ID is well formed
+func (id ID) Validate() error {
+	decoded, err := hex.DecodeString(string(id))
+	if err != nil || len(decoded) != IDByteLength || strings.ToLower(string(id)) != string(id) {
+		return fmt.Errorf("invalid node ID %q: must be %d lowercase hex characters", id, IDByteLength*2)
+	}
+	return nil
+}
+
+// NodeKey is the long-lived key that identifies a node and authenticates
+// its connections
+type NodeKey struct {
+	PrivKey ed25519.PrivateKey
+}
+
+type nodeKeyJSON struct {
+	ID      ID     `json:"id"`
+	PrivKey string `json:"priv_key"`
+}
+
+// GenNodeKey creates a new random node key
+func GenNodeKey() (*NodeKey, error) {
+	_, privKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeKey{PrivKey: privKey}, nil
+}
+
+// LoadOrGenNodeKey loads the node key at path, creating and saving a new one
+// if the file does not exist
+func LoadOrGenNodeKey(path string) (*NodeKey, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		key, err := GenNodeKey()
+		if err != nil {
+			return nil, err
+		}
+		if err := key.Save(path); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var stored nodeKeyJSON
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse node key %s: %w", path, err)
+	}
+	privKey, err := hex.DecodeString(stored.PrivKey)
+	if err != nil || len(privKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("node key %s does not hold a valid ed25519 private key", path)
+	}
+	return &NodeKey{PrivKey: ed25519.PrivateKey(privKey)}, nil
+}
+
+// Save writes the node key to path, readable only by the owner
+func (k *NodeKey) Save(path string) error {
+	data, err := json.MarshalIndent(nodeKeyJSON{ID: k.ID(), PrivKey: hex.EncodeToString(k.PrivKey)}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// PubKey returns the public half of the node key
+func (k *NodeKey) PubKey() ed25519.PublicKey {
+	return k.PrivKey.Public().(ed25519.PublicKey)
+}
+
+// ID returns the node ID of the key
+func (k *NodeKey) ID() ID {
+	return PubKeyToID(k.PubKey())
+}
+
+// NetAddress is a dialable peer address of the form id@host:port
+type NetAddress struct {
+	ID   ID     `json:"id"`
+	Host string `json:"host"`
+	Port uint16 `json:"port"`
+}
+
+// NewNetAddress builds an address from a node ID and a host:port pair
+func NewNetAddress(id ID, hostPort string) (*NetAddress, error) {
+	host, portStr, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAddress, err)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil || port == 0 {
+		return nil, fmt.Errorf("%w: invalid port %q", ErrInvalidAddress, portStr)
+	}
+	if host == "" {
+		return nil, fmt.Errorf("%w: missing host in %q", ErrInvalidAddress, hostPort)
+	}
+	if id != "" {
+		if err := id.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidAddress, err)
+		}
+	}
+	return &NetAddress{ID: id, Host: host, Port: uint16(port)}, nil
+}
+
+// ParseNetAddress parses an id@host:port address
+func ParseNetAddress(addr string) (*NetAddress, error) {
+	addr = strings.TrimPrefix(strings.TrimSpace(addr), "tcp://")
+	id, hostPort, ok := strings.Cut(addr, "@")
+	if !ok {
+		return nil, fmt.Errorf("%w: %q must be id@host:port", ErrInvalidAddress, addr)
+	}
+	return NewNetAddress(ID(id), hostPort)
+}
+
+// ParseNetAddresses parses a comma separated list of id@host:port addresses
+// such as the PersistentPeers and Seeds settings
+func ParseNetAddresses(list string) ([]*NetAddress, error) {
+	var addrs []*NetAddress
+	for _, entry := range strings.Split(list, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		addr, err := ParseNetAddress(entry)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+// DialString returns the host:port part of the address
+func (a *NetAddress) DialString() string {
+	return net.JoinHostPort(a.Host, strconv.Itoa(int(a.Port)))
+}
+
+// String returns the address as id@host:port
+func (a *NetAddress) String() string {
+	if a.ID == "" {
+		return a.DialString()
+	}
+	return string(a.ID) + "@" + a.DialString()
+}
+
+// Equal reports whether two addresses point at the same node and endpoint
+func (a *NetAddress) Equal(other *NetAddress) bool {
+	return other != nil && a.ID == other.ID && a.Host == other.Host && a.Port == other.Port
+}
@@ -0,0 +1,662 @@
+package p2p
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrDuplicatePeer is returned when a peer is already connected
+	ErrDuplicatePeer = errors.New("peer is already connected")
+	// ErrNodeStopped is returned for operations on a node that is not running
+	ErrNodeStopped = errors.New("p2p node is not running")
+	// ErrMaxPeers is returned when an inbound peer exceeds the inbound limit
+	ErrMaxPeers = errors.New("too many peers")
+)
+
+const (
+	defaultMaxInboundPeers  = 40
+	defaultMaxOutboundPeers = 10
+	defaultMaxHandshakes    = 16
+
+	initialReconnectBackoff = time.Second
+	maxReconnectBackoff     = 10 * time.Minute
+)
+
+// Config holds the p2p node settings
+type Config struct {
+	ListenAddr      string
+	ExternalAddress string // host:port advertised to peers; the listen address when empty
+	ChainID         string
+	Moniker         string
+	Version         string   // software version advertised in the handshake
+	NodeKey         *NodeKey // a throwaway key is generated when nil
+	PersistentPeers string   // comma separated id@host:port list, always reconnected
+	Seeds           string   // comma separated id@host:port list, asked for addresses
+	SeedMode        bool     // crawl the network and serve addresses instead of keeping peers
+	AddrBookFile    string
+
+	MaxNumInboundPeers  int // persistent peers do not count against the limits
+	MaxNumOutboundPeers int
+	// MaxConcurrentHandshakes bounds the inbound connections being upgraded
+	// at once; connections beyond it are closed
+	MaxConcurrentHandshakes int
+
+	HandshakeTimeout time.Duration
+	DialTimeout      time.Duration
+	MaxMessageSize   int
+
+	// RateLimit applies to every channel of every peer; ChannelRateLimits
+	// overrides it for individual channels
+	RateLimit         RateLimit
+	ChannelRateLimits map[byte]RateLimit
+	BanDuration       time.Duration // how long peers whose score drops too low stay banned
+
+	// TLSServerConfig and TLSClientConfig, when set, require mutual TLS
+	// under the secret connection, for sentries that only accept peers
+	// with a certificate from their CA. Both must be set together.
+	TLSServerConfig *tls.Config
+	TLSClientConfig *tls.Config
+
+	Logger *zap.Logger
+}
+
+// Node accepts and dials peers over the encrypted transport and routes
+// their messages to reactors by channel
+type Node struct {
+	config    *Config
+	nodeKey   *NodeKey
+	nodeInfo  NodeInfo
+	transport *Transport
+	logger    *zap.Logger
+
+	reactors       []Reactor
+	reactorsByChID map[byte]Reactor
+	channelLimits  map[byte]RateLimit
+	addrBook       *AddrBook
+
+	mu              sync.RWMutex
+	peers           map[ID]*Peer
+	dialing         map[ID]bool
+	persistentPeers []*NetAddress
+	reconnecting    map[ID]bool
+	handshakes      chan struct{} // one slot per inbound handshake in progress
+	running         bool
+	quit            chan struct{}
+}
+
+// NewNode creates a p2p node. Reactors must be added before Start.
+func NewNode(cfg *Config) *Node {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if cfg.MaxMessageSize == 0 {
+		cfg.MaxMessageSize = defaultMaxMessageSize
+	}
+	if cfg.MaxNumInboundPeers == 0 {
+		cfg.MaxNumInboundPeers = defaultMaxInboundPeers
+	}
+	if cfg.MaxNumOutboundPeers == 0 {
+		cfg.MaxNumOutboundPeers = defaultMaxOutboundPeers
+	}
+	if cfg.MaxConcurrentHandshakes == 0 {
+		cfg.MaxConcurrentHandshakes = defaultMaxHandshakes
+	}
+	if cfg.BanDuration == 0 {
+		cfg.BanDuration = defaultBanDuration
+	}
+	cfg.RateLimit = cfg.RateLimit.withDefaults(RateLimit{
+		SendRate:    defaultSendRate,
+		RecvRate:    defaultRecvRate,
+		RecvMsgRate: defaultRecvMsgRate,
+	})
+
+	nodeKey := cfg.NodeKey
+	if nodeKey == nil {
+		var err error
+		if nodeKey, err = GenNodeKey(); err != nil {
+			logger.Fatal("Failed to generate node key", zap.Error(err))
+		}
+		logger.Warn("No node key configured; using a temporary identity", zap.String("node_id", string(nodeKey.ID())))
+	}
+
+	persistentPeers, err := ParseNetAddresses(cfg.PersistentPeers)
+	if err != nil {
+		logger.Error("Ignoring invalid persistent peers", zap.Error(err))
+		persistentPeers = nil
+	}
+	seeds, err := ParseNetAddresses(cfg.Seeds)
+	if err != nil {
+		logger.Error("Ignoring invalid seeds", zap.Error(err))
+		seeds = nil
+	}
+
+	n := &Node{
+		config:          cfg,
+		nodeKey:         nodeKey,
+		logger:          logger,
+		reactorsByChID:  make(map[byte]Reactor),
+		addrBook:        NewAddrBook(cfg.AddrBookFile, logger),
+		peers:           make(map[ID]*Peer),
+		dialing:         make(map[ID]bool),
+		persistentPeers: persistentPeers,
+		reconnecting:    make(map[ID]bool),
+		handshakes:      make(chan struct{}, cfg.MaxConcurrentHandshakes),
+		quit:            make(chan struct{}),
+	}
+	n.AddReactor(NewPexReactor(&PexConfig{
+		AddrBook:    n.addrBook,
+		Seeds:       seeds,
+		SeedMode:    cfg.SeedMode,
+		MaxOutbound: cfg.MaxNumOutboundPeers,
+		Logger:      logger,
+	}))
+	return n
+}
+
+// AddReactor registers a reactor for its channels. It panics if a channel
+// is already taken, which is a wiring bug.
+func (n *Node) AddReactor(reactor Reactor) {
+	for _, chID := range reactor.Channels() {
+		if _, taken := n.reactorsByChID[chID]; taken {
+			panic(fmt.Sprintf("p2p channel %#x registered twice", chID))
+		}
+		n.reactorsByChID[chID] = reactor
+	}
+	n.reactors = append(n.reactors, reactor)
+	reactor.SetNode(n)
+}
+
+// Start listens for peers, starts the reactors and dials the persistent peers
+func (n *Node) Start() error {
+	n.transport = NewTransport(n.nodeKey, NodeInfo{}, n.config.HandshakeTimeout, n.config.DialTimeout, n.logger)
+	if n.config.TLSServerConfig != nil || n.config.TLSClientConfig != nil {
+		if n.config.TLSServerConfig == nil || n.config.TLSClientConfig == nil {
+			return errors.New("p2p TLS needs both a server and a client config")
+		}
+		n.transport.SetTLS(n.config.TLSServerConfig, n.config.TLSClientConfig)
+	}
+	if err := n.transport.Listen(n.config.ListenAddr); err != nil {
+		return err
+	}
+	n.nodeInfo = n.makeNodeInfo()
+	n.transport.nodeInfo = n.nodeInfo
+	n.channelLimits = make(map[byte]RateLimit, len(n.reactorsByChID))
+	for chID := range n.reactorsByChID {
+		n.channelLimits[chID] = n.config.ChannelRateLimits[chID].withDefaults(n.config.RateLimit)
+	}
+
+	for _, reactor := range n.reactors {
+		if err := reactor.Start(); err != nil {
+			n.transport.Close()
+			return err
+		}
+	}
+
+	n.mu.Lock()
+	n.running = true
+	n.mu.Unlock()
+
+	go n.acceptRoutine()
+	for _, addr := range n.persistentPeers {
+		go n.reconnectToPeer(addr, 0)
+	}
+
+	n.logger.Info("P2P node started",
+		zap.String("node_id", string(n.nodeInfo.ID)),
+		zap.String("listen_addr", n.transport.ListenAddr().String()),
+		zap.String("chain_id", n.nodeInfo.ChainID),
+		zap.Bool("seed_mode", n.config.SeedMode),
+	)
+	return nil
+}
+
+// Stop disconnects every peer and stops the reactors
+func (n *Node) Stop() {
+	n.mu.Lock()
+	if !n.running {
+		n.mu.Unlock()
+		return
+	}
+	n.running = false
+	close(n.quit)
+	peers := make([]*Peer, 0, len(n.peers))
+	for _, peer := range n.peers {
+		peers = append(peers, peer)
+	}
+	n.mu.Unlock()
+
+	n.transport.Close()
+	for _, peer := range peers {
+		n.removePeer(peer, ErrNodeStopped)
+	}
+	for _, reactor := range n.reactors {
+		reactor.Stop()
+	}
+	n.logger.Info("P2P node stopped")
+}
+
+// ID returns the node's ID
+func (n *Node) ID() ID { return n.nodeKey.ID() }
+
+// NodeInfo returns the node info advertised to peers
+func (n *Node) NodeInfo() NodeInfo { return n.nodeInfo }
+
+// AddrBook returns the address book of known peers
+func (n *Node) AddrBook() *AddrBook { return n.addrBook }
+
+// Peers returns the connected peers ordered by ID
+func (n *Node) Peers() []*Peer {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	peers := make([]*Peer, 0, len(n.peers))
+	for _, peer := range n.peers {
+		peers = append(peers, peer)
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].ID() < peers[j].ID() })
+	return peers
+}
+
+// Peer returns a connected peer, or nil
+func (n *Node) Peer(id ID) *Peer {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.peers[id]
+}
+
+// NumPeers returns the number of outbound and inbound peers
+func (n *Node) NumPeers() (outbound, inbound int) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	for _, peer := range n.peers {
+		if peer.IsOutbound() {
+			outbound++
+		} else {
+			inbound++
+		}
+	}
+	return outbound, inbound
+}
+
+// NumDialing returns the number of outbound connections in progress
+func (n *Node) NumDialing() int {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return len(n.dialing)
+}
+
+// IsDialingOrConnected reports whether a peer is connected or being dialed
+func (n *Node) IsDialingOrConnected(id ID) bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	_, connected := n.peers[id]
+	return connected || n.dialing[id]
+}
+
+// DialPeer connects to a peer unless it is ourselves, already connected or
+// being dialed
+func (n *Node) DialPeer(addr *NetAddress) error {
+	if addr.ID == n.ID() {
+		return ErrSelfConnection
+	}
+	if n.addrBook.IsBanned(addr.ID) {
+		return fmt.Errorf("%w: %s", ErrPeerBanned, addr.ID)
+	}
+
+	n.mu.Lock()
+	if !n.running {
+		n.mu.Unlock()
+		return ErrNodeStopped
+	}
+	if _, connected := n.peers[addr.ID]; connected || n.dialing[addr.ID] {
+		n.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrDuplicatePeer, addr.ID)
+	}
+	n.dialing[addr.ID] = true
+	n.mu.Unlock()
+
+	defer func() {
+		n.mu.Lock()
+		delete(n.dialing, addr.ID)
+		n.mu.Unlock()
+	}()
+
+	upgraded, err := n.transport.Dial(addr)
+	if err != nil {
+		return err
+	}
+	return n.addPeer(upgraded, addr, true)
+}
+
+// Broadcast sends a message to every peer that serves the channel
+func (n *Node) Broadcast(chID byte, msg []byte) {
+	for _, peer := range n.Peers() {
+		go peer.Send(chID, msg)
+	}
+}
+
+// StopPeerForError disconnects a peer after a connection or protocol error
+func (n *Node) StopPeerForError(peer *Peer, reason error) {
+	n.logger.Info("Stopping peer", zap.String("peer", string(peer.ID())), zap.Error(reason))
+	n.removePeer(peer, reason)
+}
+
+// StopPeerGracefully disconnects a peer without reporting an error
+func (n *Node) StopPeerGracefully(peer *Peer) {
+	n.removePeer(peer, nil)
+}
+
+// ReportPeer adjusts a peer's score. A peer whose score falls to the ban
+// threshold is banned for the configured ban duration; persistent peers are
+// only disconnected.
+func (n *Node) ReportPeer(peer *Peer, behaviour Behaviour) {
+	score := peer.adjustScore(behaviour)
+	if score > banScore {
+		return
+	}
+
+	reason := fmt.Errorf("peer score dropped to %d after %s", score, behaviour)
+	if peer.IsPersistent() {
+		n.StopPeerForError(peer, reason)
+		return
+	}
+	if _, err := n.BanPeer(peer.ID(), n.config.BanDuration, reason.Error()); err != nil {
+		n.StopPeerForError(peer, reason)
+	}
+}
+
+// BanPeer bans a peer for duration, or the configured ban duration when
+// zero, and disconnects it if connected. The ban is stored in the address
+// book and survives restarts.
+func (n *Node) BanPeer(id ID, duration time.Duration, reason string) (Ban, error) {
+	if err := id.Validate(); err != nil {
+		return Ban{}, err
+	}
+	if id == n.ID() {
+		return Ban{}, ErrSelfConnection
+	}
+	if duration == 0 {
+		duration = n.config.BanDuration
+	}
+	if duration < 0 {
+		return Ban{}, fmt.Errorf("ban duration must be positive")
+	}
+
+	ban := n.addrBook.Ban(id, duration, reason)
+	if err := n.addrBook.Save(); err != nil {
+		n.logger.Error("Failed to save address book", zap.Error(err))
+	}
+	n.logger.Warn("Banned peer",
+		zap.String("peer", string(id)),
+		zap.Time("until", ban.Until),
+		zap.String("reason", reason),
+	)
+	if peer := n.Peer(id); peer != nil {
+		n.removePeer(peer, fmt.Errorf("%w: %s", ErrPeerBanned, reason))
+	}
+	return ban, nil
+}
+
+// UnbanPeer lifts a peer's ban
+func (n *Node) UnbanPeer(id ID) error {
+	if err := id.Validate(); err != nil {
+		return err
+	}
+	if err := n.addrBook.Unban(id); err != nil {
+		return err
+	}
+	if err := n.addrBook.Save(); err != nil {
+		n.logger.Error("Failed to save address book", zap.Error(err))
+	}
+	n.logger.Info("Unbanned peer", zap.String("peer", string(id)))
+	return nil
+}
+
+// acceptRoutine accepts inbound connections and upgrades each in its own
+// goroutine, so a slow handshake cannot hold up the listener
+func (n *Node) acceptRoutine() {
+	for {
+		conn, err := n.transport.Accept()
+		if err != nil {
+			select {
+			case <-n.quit:
+				return
+			default:
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			n.logger.Debug("Failed to accept connection", zap.Error(err))
+			continue
+		}
+
+		if err := n.screenInbound(conn.RemoteAddr()); err != nil {
+			conn.Close()
+			n.logger.Debug("Rejected inbound connection", zap.Stringer("remote_addr", conn.RemoteAddr()), zap.Error(err))
+			continue
+		}
+		select {
+		case n.handshakes <- struct{}{}:
+		default:
+			conn.Close()
+			n.logger.Debug("Rejected inbound connection: too many handshakes in progress",
+				zap.Stringer("remote_addr", conn.RemoteAddr()))
+			continue
+		}
+		go n.upgradeInbound(conn)
+	}
+}
+
+// screenInbound rejects connections from banned hosts, and from
+// non-persistent hosts while the inbound peers and handshakes in progress
+// already fill the inbound limit, before any handshake work is done
+func (n *Node) screenInbound(remoteAddr net.Addr) error {
+	host, _, err := net.SplitHostPort(remoteAddr.String())
+	if err != nil {
+		return err
+	}
+	if n.addrBook.IsBannedHost(host) {
+		return fmt.Errorf("%w: %s", ErrPeerBanned, host)
+	}
+	for _, addr := range n.persistentPeers {
+		if addr.Host == host {
+			return nil
+		}
+	}
+
+	n.mu.RLock()
+	inbound := n.numInboundLocked() + len(n.handshakes)
+	n.mu.RUnlock()
+	if inbound >= n.config.MaxNumInboundPeers {
+		return fmt.Errorf("%w: %d inbound peers connected or connecting", ErrMaxPeers, inbound)
+	}
+	return nil
+}
+
+// upgradeInbound runs the handshake of an accepted connection and adds the
+// peer, then frees the connection's handshake slot
+func (n *Node) upgradeInbound(conn net.Conn) {
+	defer func() { <-n.handshakes }()
+
+	remoteAddr := conn.RemoteAddr()
+	upgraded, err := n.transport.UpgradeInbound(conn)
+	if err != nil {
+		n.logger.Debug("Rejected inbound connection", zap.Stringer("remote_addr", remoteAddr), zap.Error(err))
+		return
+	}
+	addr, err := NewNetAddress(upgraded.nodeInfo.ID, upgraded.conn.RemoteAddr().String())
+	if err != nil {
+		upgraded.conn.Close()
+		return
+	}
+	if err := n.addPeer(upgraded, addr, false); err != nil {
+		upgraded.conn.Close()
+		n.logger.Debug("Rejected inbound peer", zap.String("peer", addr.String()), zap.Error(err))
+	}
+}
+
+// addPeer registers an upgraded connection and hands the peer to the reactors
+func (n *Node) addPeer(upgraded *upgradedConn, addr *NetAddress, outbound bool) error {
+	if n.addrBook.IsBanned(upgraded.nodeInfo.ID) {
+		return fmt.Errorf("%w: %s", ErrPeerBanned, upgraded.nodeInfo.ID)
+	}
+
+	peer := newPeer(upgraded, addr, outbound, n.isPersistent(upgraded.nodeInfo.ID), n.config.MaxMessageSize, n.channelLimits, n.logger)
+	peer.onReceive = n.receive
+	peer.onError = n.StopPeerForError
+	peer.onBehaviour = n.ReportPeer
+
+	n.mu.Lock()
+	if !n.running {
+		n.mu.Unlock()
+		return ErrNodeStopped
+	}
+	if _, exists := n.peers[peer.ID()]; exists {
+		n.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrDuplicatePeer, peer.ID())
+	}
+	if !outbound && !peer.IsPersistent() && n.numInboundLocked() >= n.config.MaxNumInboundPeers {
+		n.mu.Unlock()
+		return fmt.Errorf("%w: %d inbound peers connected", ErrMaxPeers, n.config.MaxNumInboundPeers)
+	}
+	n.peers[peer.ID()] = peer
+	n.mu.Unlock()
+
+	for _, reactor := range n.reactors {
+		reactor.AddPeer(peer)
+	}
+	peer.start()
+
+	n.logger.Info("Peer connected",
+		zap.String("peer", addr.String()),
+		zap.String("moniker", peer.NodeInfo().Moniker),
+		zap.Bool("outbound", outbound),
+	)
+	return nil
+}
+
+func (n *Node) removePeer(peer *Peer, reason error) {
+	n.mu.Lock()
+	if current, ok := n.peers[peer.ID()]; !ok || current != peer {
+		n.mu.Unlock()
+		return
+	}
+	delete(n.peers, peer.ID())
+	n.mu.Unlock()
+
+	peer.stop()
+	for _, reactor := range n.reactors {
+		reactor.RemovePeer(peer, reason)
+	}
+
+	if peer.IsPersistent() && !errors.Is(reason, ErrNodeStopped) {
+		for _, addr := range n.persistentPeers {
+			if addr.ID == peer.ID() {
+				go n.reconnectToPeer(addr, initialReconnectBackoff)
+			}
+		}
+	}
+}
+
+// reconnectToPeer dials a persistent peer until it connects, backing off
+// exponentially up to maxReconnectBackoff between attempts
+func (n *Node) reconnectToPeer(addr *NetAddress, backoff time.Duration) {
+	n.mu.Lock()
+	if n.reconnecting[addr.ID] {
+		n.mu.Unlock()
+		return
+	}
+	n.reconnecting[addr.ID] = true
+	n.mu.Unlock()
+
+	defer func() {
+		n.mu.Lock()
+		delete(n.reconnecting, addr.ID)
+		n.mu.Unlock()
+	}()
+
+	for {
+		if backoff > 0 {
+			// Jitter keeps peers that dropped together from redialing in lockstep
+			wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff)))
+			select {
+			case <-n.quit:
+				return
+			case <-time.After(wait):
+			}
+		}
+
+		err := n.DialPeer(addr)
+		if err == nil || errors.Is(err, ErrDuplicatePeer) || errors.Is(err, ErrNodeStopped) {
+			return
+		}
+		backoff = min(max(2*backoff, initialReconnectBackoff), maxReconnectBackoff)
+		n.logger.Warn("Failed to dial persistent peer",
+			zap.String("addr", addr.String()),
+			zap.Duration("retry_in", backoff),
+			zap.Error(err),
+		)
+	}
+}
+
+func (n *Node) numInboundLocked() int {
+	inbound := 0
+	for _, peer := range n.peers {
+		if !peer.IsOutbound() {
+			inbound++
+		}
+	}
+	return inbound
+}
+
+func (n *Node) receive(chID byte, peer *Peer, msg []byte) {
+	reactor, ok := n.reactorsByChID[chID]
+	if !ok {
+		n.StopPeerForError(peer, fmt.Errorf("message on unknown channel %#x", chID))
+		return
+	}
+	reactor.Receive(chID, peer, msg)
+}
+
+func (n *Node) isPersistent(id ID) bool {
+	for _, addr := range n.persistentPeers {
+		if addr.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// makeNodeInfo describes this node once it is listening
+func (n *Node) makeNodeInfo() NodeInfo {
+	channels := make([]byte, 0, len(n.reactorsByChID))
+	for chID := range n.reactorsByChID {
+		channels = append(channels, chID)
+	}
+	sort.Slice(channels, func(i, j int) bool { return channels[i] < channels[j] })
+
+	listenAddr := n.config.ExternalAddress
+	if listenAddr == "" {
+		listenAddr = n.transport.ListenAddr().String()
+	}
+	return NodeInfo{
+		ID:              n.ID(),
+		ListenAddr:      listenAddr,
+		ChainID:         n.config.ChainID,
+		Version:         n.config.Version,
+		ProtocolVersion: ProtocolVersion,
+		Channels:        channels,
+		Moniker:         n.config.Moniker,
+	}
+}
@@ -0,0 +1,103 @@
+package p2p
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ProtocolVersion is the version of the peer wire protocol. Peers must run
+// the same protocol version to connect.
+const ProtocolVersion uint32 = 1
+
+const (
+	maxMonikerLength = 64
+	maxNumChannels   = 16
+)
+
+var (
+	// ErrSelfConnection is returned when a node dials itself
+	ErrSelfConnection = errors.New("connection to self")
+	// ErrChainIDMismatch is returned for peers on a different chain
+	ErrChainIDMismatch = errors.New("peer is on a different chain")
+	// ErrIncompatibleVersion is returned for peers speaking another protocol version
+	ErrIncompatibleVersion = errors.New("incompatible protocol version")
+	// ErrNoCommonChannels is returned for peers that share no channel with us
+	ErrNoCommonChannels = errors.New("peer has no channels in common")
+	// ErrPeerIDMismatch is returned when a dialed peer authenticates with another ID
+	ErrPeerIDMismatch = errors.New("peer ID does not match the dialed address")
+)
+
+// NodeInfo is exchanged during the handshake and describes what a node runs
+type NodeInfo struct {
+	ID              ID     `json:"id"`
+	ListenAddr      string `json:"listen_addr"` // host:port other nodes can dial
+	ChainID         string `json:"chain_id"`
+	Version         string `json:"version"` // software version
+	ProtocolVersion uint32 `json:"protocol_version"`
+	Channels        []byte `json:"channels"`
+	Moniker         string `json:"moniker"`
+}
+
+// Validate checks the fields of a node info received from a peer
+func (ni NodeInfo) Validate() error {
+	if err := ni.ID.Validate(); err != nil {
+		return err
+	}
+	if ni.ChainID == "" {
+		return fmt.Errorf("node info: chain ID cannot be empty")
+	}
+	if len(ni.Moniker) > maxMonikerLength {
+		return fmt.Errorf("node info: moniker cannot exceed %d bytes", maxMonikerLength)
+	}
+	if len(ni.Channels) > maxNumChannels {
+		return fmt.Errorf("node info: at most %d channels", maxNumChannels)
+	}
+	seen := make(map[byte]bool, len(ni.Channels))
+	for _, ch := range ni.Channels {
+		if seen[ch] {
+			return fmt.Errorf("node info: duplicate channel %#x", ch)
+		}
+		seen[ch] = true
+	}
+	if ni.ListenAddr != "" {
+		if _, err := NewNetAddress(ni.ID, ni.ListenAddr); err != nil {
+			return fmt.Errorf("node info: listen address: %w", err)
+		}
+	}
+	return nil
+}
+
+// CompatibleWith checks that a peer runs the same chain and protocol and
+// shares at least one channel
+func (ni NodeInfo) CompatibleWith(other NodeInfo) error {
+	if ni.ChainID != other.ChainID {
+		return fmt.Errorf("%w: %s, expected %s", ErrChainIDMismatch, other.ChainID, ni.ChainID)
+	}
+	if ni.ProtocolVersion != other.ProtocolVersion {
+		return fmt.Errorf("%w: peer speaks %d, we speak %d", ErrIncompatibleVersion, other.ProtocolVersion, ni.ProtocolVersion)
+	}
+	if len(ni.Channels) == 0 {
+		return nil
+	}
+	for _, ch := range other.Channels {
+		if ni.HasChannel(ch) {
+			return nil
+		}
+	}
+	return ErrNoCommonChannels
+}
+
+// HasChannel reports whether the node serves a channel
+func (ni NodeInfo) HasChannel(ch byte) bool {
+	for _, c := range ni.Channels {
+		if c == ch {
+			return true
+		}
+	}
+	return false
+}
+
+// NetAddress returns the advertised dialable address of the node
+func (ni NodeInfo) NetAddress() (*NetAddress, error) {
+	return NewNetAddress(ni.ID, ni.ListenAddr)
+}
@@ -0,0 +1,220 @@
+package p2p
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultMaxMessageSize = 4 << 20 // 4 MiB
+	sendQueueSize         = 256
+	sendTimeout           = 10 * time.Second
+	msgHeaderSize         = 5 // 4 byte length + channel ID
+)
+
+type envelope struct {
+	chID byte
+	msg  []byte
+}
+
+// Peer is a connected remote node. Messages are multiplexed over the
+// secret connection as length-prefixed frames tagged with a channel ID.
+type Peer struct {
+	conn       *SecretConnection
+	nodeInfo   NodeInfo
+	outbound   bool
+	persistent bool
+	socketAddr *NetAddress // address we dialed, or the remote address of an inbound connection
+	createdAt  time.Time
+	logger     *zap.Logger
+
+	maxMessageSize int
+	limiters       map[byte]*channelLimiter
+	sendQueue      chan envelope
+	quit           chan struct{}
+	stopOnce       sync.Once
+
+	scoreMu sync.Mutex
+	score   int64
+
+	onReceive   func(chID byte, peer *Peer, msg []byte)
+	onError     func(peer *Peer, err error)
+	onBehaviour func(peer *Peer, behaviour Behaviour)
+}
+
+func newPeer(upgraded *upgradedConn, socketAddr *NetAddress, outbound, persistent bool, maxMessageSize int, limits map[byte]RateLimit, logger *zap.Logger) *Peer {
+	limiters := make(map[byte]*channelLimiter, len(limits))
+	for chID, limit := range limits {
+		limiters[chID] = newChannelLimiter(limit)
+	}
+	return &Peer{
+		conn:           upgraded.conn,
+		nodeInfo:       upgraded.nodeInfo,
+		outbound:       outbound,
+		persistent:     persistent,
+		socketAddr:     socketAddr,
+		createdAt:      time.Now().UTC(),
+		logger:         logger.With(zap.String("peer", string(upgraded.nodeInfo.ID))),
+		maxMessageSize: maxMessageSize,
+		limiters:       limiters,
+		sendQueue:      make(chan envelope, sendQueueSize),
+		quit:           make(chan struct{}),
+	}
+}
+
+// ID returns the peer's node ID
+func (p *Peer) ID() ID { return p.nodeInfo.ID }
+
+// NodeInfo returns the node info the peer sent during the handshake
+func (p *Peer) NodeInfo() NodeInfo { return p.nodeInfo }
+
+// IsOutbound reports whether we dialed the peer
+func (p *Peer) IsOutbound() bool { return p.outbound }
+
+// IsPersistent reports whether the peer is one of the configured persistent peers
+func (p *Peer) IsPersistent() bool { return p.persistent }
+
+// SocketAddr returns the address the connection was made to or from
+func (p *Peer) SocketAddr() *NetAddress { return p.socketAddr }
+
+// CreatedAt returns when the connection was established
+func (p *Peer) CreatedAt() time.Time { return p.createdAt }
+
+// Send queues a message, waiting while the channel is over its send rate
+// or the send queue is full. It returns false if the peer does not serve
+// the channel, is stopped or stays congested past the send timeout.
+func (p *Peer) Send(chID byte, msg []byte) bool {
+	if !p.nodeInfo.HasChannel(chID) {
+		return false
+	}
+	timer := time.NewTimer(sendTimeout)
+	defer timer.Stop()
+	if limiter := p.limiters[chID]; limiter != nil {
+		if delay := limiter.send.wait(float64(len(msg))); delay > 0 {
+			throttle := time.NewTimer(delay)
+			defer throttle.Stop()
+			select {
+			case <-throttle.C:
+			case <-p.quit:
+				return false
+			case <-timer.C:
+				return false
+			}
+		}
+	}
+	select {
+	case p.sendQueue <- envelope{chID: chID, msg: msg}:
+		return true
+	case <-p.quit:
+		return false
+	case <-timer.C:
+		return false
+	}
+}
+
+// TrySend queues a message without waiting. It returns false when the
+// channel is over its send rate.
+func (p *Peer) TrySend(chID byte, msg []byte) bool {
+	if !p.nodeInfo.HasChannel(chID) {
+		return false
+	}
+	if limiter := p.limiters[chID]; limiter != nil && !limiter.send.allow(float64(len(msg))) {
+		return false
+	}
+	select {
+	case p.sendQueue <- envelope{chID: chID, msg: msg}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *Peer) start() {
+	go p.sendRoutine()
+	go p.recvRoutine()
+}
+
+// stop closes the connection; it is safe to call more than once
+func (p *Peer) stop() {
+	p.stopOnce.Do(func() {
+		close(p.quit)
+		p.conn.Close()
+	})
+}
+
+func (p *Peer) sendRoutine() {
+	w := bufio.NewWriterSize(p.conn, dataMaxSize*4)
+	var header [msgHeaderSize]byte
+	for {
+		select {
+		case <-p.quit:
+			return
+		case env := <-p.sendQueue:
+			binary.BigEndian.PutUint32(header[:4], uint32(len(env.msg)))
+			header[4] = env.chID
+			if _, err := w.Write(header[:]); err != nil {
+				p.fail(err)
+				return
+			}
+			if _, err := w.Write(env.msg); err != nil {
+				p.fail(err)
+				return
+			}
+			// Batch queued messages into as few frames as possible
+			if len(p.sendQueue) == 0 {
+				if err := w.Flush(); err != nil {
+					p.fail(err)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (p *Peer) recvRoutine() {
+	r := bufio.NewReaderSize(p.conn, dataMaxSize*4)
+	var header [msgHeaderSize]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			p.fail(err)
+			return
+		}
+		size := binary.BigEndian.Uint32(header[:4])
+		if int64(size) > int64(p.maxMessageSize) {
+			p.fail(fmt.Errorf("message of %d bytes exceeds the %d byte limit", size, p.maxMessageSize))
+			return
+		}
+		msg := make([]byte, size)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			p.fail(err)
+			return
+		}
+		select {
+		case <-p.quit:
+			return
+		default:
+		}
+		if limiter := p.limiters[header[4]]; limiter != nil && !limiter.allowRecv(len(msg)) {
+			// Flooded messages are dropped and cost the peer score
+			p.onBehaviour(p, BehaviourRateLimited)
+			continue
+		}
+		p.onReceive(header[4], p, msg)
+	}
+}
+
+// fail reports a connection error once and stops the peer
+func (p *Peer) fail(err error) {
+	select {
+	case <-p.quit:
+		return
+	default:
+	}
+	p.onError(p, err)
+}
@@ -0,0 +1,347 @@
+package p2p
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// PexChannel carries peer exchange messages
+const PexChannel = byte(0x00)
+
+const (
+	defaultEnsurePeersPeriod = 30 * time.Second
+	seedCrawlPeriod          = 30 * time.Second
+	seedDisconnectDelay      = 3 * time.Second
+	minReceiveRequestPeriod  = 10 * time.Second
+	maxAddrsPerMessage       = maxGetSelection
+)
+
+// PEX message types
+const (
+	pexRequest = "request"
+	pexAddrs   = "addrs"
+)
+
+type pexMessage struct {
+	Type  string        `json:"type"`
+	Addrs []*NetAddress `json:"addrs,omitempty"`
+}
+
+// PexConfig configures the peer exchange reactor
+type PexConfig struct {
+	AddrBook          *AddrBook
+	Seeds             []*NetAddress
+	SeedMode          bool // only crawl the network and hand out addresses
+	MaxOutbound       int
+	EnsurePeersPeriod time.Duration
+	Logger            *zap.Logger
+}
+
+// PexReactor keeps the address book filled by asking peers for addresses
+// and dials new outbound peers until the outbound limit is reached
+type PexReactor struct {
+	BaseReactor
+
+	config *PexConfig
+	book   *AddrBook
+	logger *zap.Logger
+
+	mu              sync.Mutex
+	requestsSent    map[ID]bool      // peers we asked for addresses and expect an answer from
+	lastRequestSent map[ID]time.Time // keeps our own requests under the peer's rate limit
+	lastReceivedReq map[ID]time.Time // rate limit of address requests per peer
+
+	quit chan struct{}
+}
+
+// NewPexReactor creates a peer exchange reactor
+func NewPexReactor(cfg *PexConfig) *PexReactor {
+	if cfg.EnsurePeersPeriod == 0 {
+		cfg.EnsurePeersPeriod = defaultEnsurePeersPeriod
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &PexReactor{
+		config:          cfg,
+		book:            cfg.AddrBook,
+		logger:          logger,
+		requestsSent:    make(map[ID]bool),
+		lastRequestSent: make(map[ID]time.Time),
+		lastReceivedReq: make(map[ID]time.Time),
+		quit:            make(chan struct{}),
+	}
+}
+
+// Channels returns the PEX channel
+func (r *PexReactor) Channels() []byte { return []byte{PexChannel} }
+
+// Start loads the address book and starts dialing or crawling
+func (r *PexReactor) Start() error {
+	if err := r.book.Load(); err != nil {
+		return err
+	}
+	if addr, err := r.Node.NodeInfo().NetAddress(); err == nil {
+		r.book.AddOurAddress(addr)
+	}
+	go r.book.saveRoutine(r.quit)
+	if r.config.SeedMode {
+		go r.crawlRoutine()
+	} else {
+		go r.ensurePeersRoutine()
+	}
+	return nil
+}
+
+// Stop stops dialing and saves the address book
+func (r *PexReactor) Stop() {
+	close(r.quit)
+	if err := r.book.Save(); err != nil {
+		r.logger.Error("Failed to save address book", zap.Error(err))
+	}
+}
+
+// AddPeer asks outbound peers for addresses when the book runs low and
+// records the advertised address of inbound peers
+func (r *PexReactor) AddPeer(peer *Peer) {
+	if peer.IsOutbound() {
+		r.book.MarkGood(peer.ID())
+		if r.book.NeedMoreAddrs() {
+			r.requestAddrs(peer)
+		}
+		return
+	}
+
+	addr, err := peer.NodeInfo().NetAddress()
+	if err != nil {
+		return
+	}
+	if err := r.book.AddAddress(addr, peer.ID()); err != nil {
+		r.logger.Debug("Not adding inbound peer address", zap.String("addr", addr.String()), zap.Error(err))
+	}
+}
+
+// RemovePeer forgets the peer's pending request state
+func (r *PexReactor) RemovePeer(peer *Peer, reason error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.requestsSent, peer.ID())
+	delete(r.lastRequestSent, peer.ID())
+	delete(r.lastReceivedReq, peer.ID())
+}
+
+// Receive answers address requests and stores addresses we asked for
+func (r *PexReactor) Receive(chID byte, peer *Peer, msgBytes []byte) {
+	var msg pexMessage
+	if err := json.Unmarshal(msgBytes, &msg); err != nil {
+		r.Node.StopPeerForError(peer, fmt.Errorf("invalid pex message: %w", err))
+		return
+	}
+
+	switch msg.Type {
+	case pexRequest:
+		if err := r.checkRequestRate(peer); err != nil {
+			r.Node.StopPeerForError(peer, err)
+			return
+		}
+		r.sendAddrs(peer, r.book.GetSelection())
+		if r.config.SeedMode && !peer.IsOutbound() {
+			// Seeds hand out addresses and hang up to make room for others
+			time.AfterFunc(seedDisconnectDelay, func() { r.Node.StopPeerGracefully(peer) })
+		}
+
+	case pexAddrs:
+		if err := r.receiveAddrs(peer, msg.Addrs); err != nil {
+			r.Node.StopPeerForError(peer, err)
+			return
+		}
+		r.Node.ReportPeer(peer, BehaviourUsefulMessage)
+
+	default:
+		r.Node.StopPeerForError(peer, fmt.Errorf("unknown pex message type %q", msg.Type))
+	}
+}
+
+func (r *PexReactor) checkRequestRate(peer *Peer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if last, ok := r.lastReceivedReq[peer.ID()]; ok && now.Sub(last) < minReceiveRequestPeriod {
+		return fmt.Errorf("peer sent address requests too often")
+	}
+	r.lastReceivedReq[peer.ID()] = now
+	return nil
+}
+
+func (r *PexReactor) requestAddrs(peer *Peer) {
+	r.mu.Lock()
+	if r.requestsSent[peer.ID()] || time.Since(r.lastRequestSent[peer.ID()]) < 2*minReceiveRequestPeriod {
+		r.mu.Unlock()
+		return
+	}
+	r.requestsSent[peer.ID()] = true
+	r.lastRequestSent[peer.ID()] = time.Now()
+	r.mu.Unlock()
+
+	msg, _ := json.Marshal(pexMessage{Type: pexRequest})
+	peer.Send(PexChannel, msg)
+}
+
+// receiveAddrs stores addresses from a peer. Unsolicited answers are a
+// protocol violation.
+func (r *PexReactor) receiveAddrs(peer *Peer, addrs []*NetAddress) error {
+	r.mu.Lock()
+	if !r.requestsSent[peer.ID()] {
+		r.mu.Unlock()
+		return fmt.Errorf("received unsolicited addresses")
+	}
+	delete(r.requestsSent, peer.ID())
+	r.mu.Unlock()
+
+	if len(addrs) > maxAddrsPerMessage {
+		return fmt.Errorf("received %d addresses, at most %d allowed", len(addrs), maxAddrsPerMessage)
+	}
+	for _, addr := range addrs {
+		if addr == nil {
+			continue
+		}
+		if _, err := NewNetAddress(addr.ID, addr.DialString()); err != nil {
+			return err
+		}
+		if err := r.book.AddAddress(addr, peer.ID()); err != nil {
+			r.logger.Debug("Skipping gossiped address", zap.String("addr", addr.String()), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+func (r *PexReactor) sendAddrs(peer *Peer, addrs []*NetAddress) {
+	msg, _ := json.Marshal(pexMessage{Type: pexAddrs, Addrs: addrs})
+	peer.Send(PexChannel, msg)
+}
+
+func (r *PexReactor) ensurePeersRoutine() {
+	r.ensurePeers()
+	ticker := time.NewTicker(r.config.EnsurePeersPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.ensurePeers()
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// ensurePeers dials addresses from the book until the outbound limit is
+// reached. Seeds are only used when the book is empty.
+func (r *PexReactor) ensurePeers() {
+	outbound, _ := r.Node.NumPeers()
+	dialing := r.Node.NumDialing()
+	need := r.config.MaxOutbound - outbound - dialing
+	if need <= 0 {
+		return
+	}
+
+	// Favour tried addresses while we have few peers, new ones once settled
+	bias := max(defaultBiasTowardsNew, min(90, 10*outbound+10))
+	picked := make(map[ID]bool)
+	for attempts := 0; len(picked) < need && attempts < need*3; attempts++ {
+		addr := r.book.PickAddress(bias)
+		if addr == nil {
+			break
+		}
+		if picked[addr.ID] || r.Node.IsDialingOrConnected(addr.ID) {
+			continue
+		}
+		picked[addr.ID] = true
+		go r.dial(addr)
+	}
+
+	if r.book.Size() == 0 && len(r.config.Seeds) > 0 {
+		for _, seed := range r.config.Seeds {
+			if !r.Node.IsDialingOrConnected(seed.ID) {
+				go r.dialSeed(seed)
+			}
+		}
+		return
+	}
+
+	// Ask a random peer for more addresses when the book runs low
+	if r.book.NeedMoreAddrs() {
+		if peers := r.Node.Peers(); len(peers) > 0 {
+			r.requestAddrs(peers[rand.Intn(len(peers))])
+		}
+	}
+}
+
+func (r *PexReactor) dial(addr *NetAddress) {
+	if err := r.Node.DialPeer(addr); err != nil {
+		r.book.MarkAttempt(addr)
+		r.logger.Debug("Failed to dial peer", zap.String("addr", addr.String()), zap.Error(err))
+	}
+}
+
+func (r *PexReactor) dialSeed(seed *NetAddress) {
+	if err := r.Node.DialPeer(seed); err != nil {
+		r.logger.Warn("Failed to dial seed", zap.String("addr", seed.String()), zap.Error(err))
+		return
+	}
+	if peer := r.Node.Peer(seed.ID); peer != nil {
+		r.requestAddrs(peer)
+	}
+}
+
+// crawlRoutine is the seed mode loop: connect to known addresses, collect
+// their address books and disconnect again
+func (r *PexReactor) crawlRoutine() {
+	r.crawl()
+	ticker := time.NewTicker(seedCrawlPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.crawl()
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+func (r *PexReactor) crawl() {
+	for _, seed := range r.config.Seeds {
+		if !r.Node.IsDialingOrConnected(seed.ID) {
+			go r.dialSeed(seed)
+		}
+	}
+
+	for _, peer := range r.Node.Peers() {
+		if peer.IsOutbound() && time.Since(peer.CreatedAt()) > seedDisconnectDelay && !peer.IsPersistent() {
+			r.Node.StopPeerGracefully(peer)
+		}
+	}
+
+	for i := 0; i < r.config.MaxOutbound; i++ {
+		addr := r.book.PickAddress(defaultBiasTowardsNew)
+		if addr == nil {
+			return
+		}
+		if r.Node.IsDialingOrConnected(addr.ID) {
+			continue
+		}
+		go func(addr *NetAddress) {
+			r.dial(addr)
+			if peer := r.Node.Peer(addr.ID); peer != nil {
+				r.requestAddrs(peer)
+			}
+		}(addr)
+	}
+}
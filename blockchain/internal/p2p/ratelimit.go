@@ -0,0 +1,110 @@
+package p2p
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultSendRate    = 5 << 20 // 5 MiB/s per channel
+	defaultRecvRate    = 5 << 20
+	defaultRecvMsgRate = 1000 // messages per second per channel
+)
+
+// RateLimit caps the traffic of one channel of one peer. Zero fields fall
+// back to the node defaults; negative fields disable the limit.
+type RateLimit struct {
+	SendRate    int64   // bytes per second
+	RecvRate    int64   // bytes per second
+	RecvMsgRate float64 // messages per second
+}
+
+func (l RateLimit) withDefaults(def RateLimit) RateLimit {
+	if l.SendRate == 0 {
+		l.SendRate = def.SendRate
+	}
+	if l.RecvRate == 0 {
+		l.RecvRate = def.RecvRate
+	}
+	if l.RecvMsgRate == 0 {
+		l.RecvMsgRate = def.RecvMsgRate
+	}
+	return l
+}
+
+// tokenBucket refills at rate tokens per second up to one second worth of
+// tokens. A take larger than the balance drives it negative, so messages
+// bigger than the burst still pass and are paid off afterwards.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket returns nil for a non-positive rate, meaning no limit
+func newTokenBucket(rate float64) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	return &tokenBucket{rate: rate, tokens: rate, last: time.Now()}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = min(b.rate, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// allow takes n tokens if the bucket is not in debt
+func (b *tokenBucket) allow(n float64) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(time.Now())
+	if b.tokens < 0 {
+		return false
+	}
+	b.tokens -= n
+	return true
+}
+
+// wait takes n tokens and returns how long the caller has to wait before
+// the bucket is out of debt again
+func (b *tokenBucket) wait(n float64) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(time.Now())
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// channelLimiter holds the buckets of one channel of one peer
+type channelLimiter struct {
+	send     *tokenBucket
+	recv     *tokenBucket
+	recvMsgs *tokenBucket
+}
+
+func newChannelLimiter(limit RateLimit) *channelLimiter {
+	return &channelLimiter{
+		send:     newTokenBucket(float64(limit.SendRate)),
+		recv:     newTokenBucket(float64(limit.RecvRate)),
+		recvMsgs: newTokenBucket(limit.RecvMsgRate),
+	}
+}
+
+// allowRecv reports whether a received message of size bytes is within the
+// channel's limits
+func (l *channelLimiter) allowRecv(size int) bool {
+	return l.recvMsgs.allow(1) && l.recv.allow(float64(size))
+}
@@ -0,0 +1,46 @@
+package p2p
+
+// Reactor handles the messages of one or more channels. Reactors are added
+// to the node before it starts and are told about every peer that connects
+// or disconnects.
+type Reactor interface {
+	// Channels returns the channel IDs the reactor receives messages on
+	Channels() []byte
+	// SetNode gives the reactor access to the node, for broadcasting and
+	// stopping misbehaving peers
+	SetNode(node *Node)
+	// Start is called when the node starts
+	Start() error
+	// Stop is called when the node stops
+	Stop()
+	// AddPeer is called once a peer has completed the handshake
+	AddPeer(peer *Peer)
+	// RemovePeer is called when a peer disconnects
+	RemovePeer(peer *Peer, reason error)
+	// Receive is called for every message on one of the reactor's channels.
+	// It runs on the peer's receive goroutine and must not block for long.
+	Receive(chID byte, peer *Peer, msg []byte)
+}
+
+// BaseReactor provides no-op implementations of the Reactor methods
+type BaseReactor struct {
+	Node *Node
+}
+
+// SetNode stores the node
+func (r *BaseReactor) SetNode(node *Node) { r.Node = node }
+
+// Start does nothing
+func (r *BaseReactor) Start() error { return nil }
+
+// Stop does nothing
+func (r *BaseReactor) Stop() {}
+
+// AddPeer does nothing
+func (r *BaseReactor) AddPeer(peer *Peer) {}
+
+// RemovePeer does nothing
+func (r *BaseReactor) RemovePeer(peer *Peer, reason error) {}
+
+// Receive does nothing
+func (r *BaseReactor) Receive(chID byte, peer *Peer, msg []byte) {}
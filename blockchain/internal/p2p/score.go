@@ -0,0 +1,53 @@
+package p2p
+
+import "time"
+
+// Behaviour is something a peer did that changes its score. Reactors report
+// behaviours with Node.ReportPeer.
+type Behaviour string
+
+// Peer behaviours
+const (
+	BehaviourUsefulMessage Behaviour = "useful_message"
+	BehaviourValidBlock    Behaviour = "valid_block"
+	BehaviourBadMessage    Behaviour = "bad_message"
+	BehaviourInvalidTx     Behaviour = "invalid_tx"
+	BehaviourInvalidVote   Behaviour = "invalid_vote"
+	BehaviourInvalidBlock  Behaviour = "invalid_block"
+	BehaviourRateLimited   Behaviour = "rate_limited"
+)
+
+// behaviourScores is how much each behaviour moves a peer's score. An
+// invalid block alone gets a fresh peer banned.
+var behaviourScores = map[Behaviour]int64{
+	BehaviourUsefulMessage: 1,
+	BehaviourValidBlock:    5,
+	BehaviourBadMessage:    -20,
+	BehaviourInvalidTx:     -10,
+	BehaviourInvalidVote:   -50,
+	BehaviourInvalidBlock:  -100,
+	BehaviourRateLimited:   -5,
+}
+
+const (
+	// maxPeerScore caps the credit a peer can build up with useful messages
+	maxPeerScore = 100
+	// banScore is the score at which a peer is banned
+	banScore           = -100
+	defaultBanDuration = 24 * time.Hour
+)
+
+// Score returns the peer's current score. Peers start at zero.
+func (p *Peer) Score() int64 {
+	p.scoreMu.Lock()
+	defer p.scoreMu.Unlock()
+	return p.score
+}
+
+// adjustScore applies a behaviour and returns the new score
+func (p *Peer) adjustScore(behaviour Behaviour) int64 {
+	p.scoreMu.Lock()
+	defer p.scoreMu.Unlock()
+	p.score = min(maxPeerScore, p.score+behaviourScores[behaviour])
+	return p.score
+}
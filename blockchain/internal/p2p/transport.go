@@ -0,0 +1,214 @@
+package p2p
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultHandshakeTimeout = 20 * time.Second
+	defaultDialTimeout      = 3 * time.Second
+
+	maxNodeInfoSize = 10240
+)
+
+// upgradedConn is a connection that completed the handshake
+type upgradedConn struct {
+	conn     *SecretConnection
+	nodeInfo NodeInfo
+}
+
+// Transport accepts and dials TCP connections and upgrades them to
+// authenticated secret connections with a node info exchange
+type Transport struct {
+	nodeKey          *NodeKey
+	nodeInfo         NodeInfo
+	handshakeTimeout time.Duration
+	dialTimeout      time.Duration
+	listener         net.Listener
+	tlsServer        *tls.Config
+	tlsClient        *tls.Config
+	logger           *zap.Logger
+}
+
+// NewTransport creates a transport for a node key and the node info it
+// advertises
+func NewTransport(nodeKey *NodeKey, nodeInfo NodeInfo, handshakeTimeout, dialTimeout time.Duration, logger *zap.Logger) *Transport {
+	if handshakeTimeout == 0 {
+		handshakeTimeout = defaultHandshakeTimeout
+	}
+	if dialTimeout == 0 {
+		dialTimeout = defaultDialTimeout
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Transport{
+		nodeKey:          nodeKey,
+		nodeInfo:         nodeInfo,
+		handshakeTimeout: handshakeTimeout,
+		dialTimeout:      dialTimeout,
+		logger:           logger,
+	}
+}
+
+// SetTLS wraps connections in mutual TLS before the secret connection
+// handshake, so only peers holding a certificate the configs trust can
+// connect. server must require and verify client certificates.
+func (t *Transport) SetTLS(server, client *tls.Config) {
+	t.tlsServer, t.tlsClient = server, client
+}
+
+// Listen starts accepting TCP connections on addr
+func (t *Transport) Listen(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	t.listener = listener
+	return nil
+}
+
+// ListenAddr returns the address the transport is listening on
+func (t *Transport) ListenAddr() net.Addr {
+	if t.listener == nil {
+		return nil
+	}
+	return t.listener.Addr()
+}
+
+// Accept waits for the next inbound connection. It does not upgrade it, so
+// the caller can screen the connection and run the handshake elsewhere.
+func (t *Transport) Accept() (net.Conn, error) {
+	return t.listener.Accept()
+}
+
+// UpgradeInbound runs the handshake on an accepted connection, closing the
+// connection if it fails
+func (t *Transport) UpgradeInbound(conn net.Conn) (*upgradedConn, error) {
+	if t.tlsServer != nil {
+		conn = tls.Server(conn, t.tlsServer)
+	}
+	upgraded, err := t.upgrade(conn, "")
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return upgraded, nil
+}
+
+// Dial connects to addr and upgrades the connection. The remote node must
+// authenticate with the ID in the address.
+func (t *Transport) Dial(addr *NetAddress) (*upgradedConn, error) {
+	conn, err := net.DialTimeout("tcp", addr.DialString(), t.dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if t.tlsClient != nil {
+		conn = tls.Client(conn, t.tlsClient)
+	}
+	upgraded, err := t.upgrade(conn, addr.ID)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return upgraded, nil
+}
+
+// Close stops listening
+func (t *Transport) Close() error {
+	if t.listener == nil {
+		return nil
+	}
+	return t.listener.Close()
+}
+
+// upgrade runs the secret connection handshake and the node info exchange,
+// rejecting peers that are not who we dialed or cannot talk to us
+func (t *Transport) upgrade(conn net.Conn, dialedID ID) (*upgradedConn, error) {
+	if err := conn.SetDeadline(time.Now().Add(t.handshakeTimeout)); err != nil {
+		return nil, err
+	}
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		if err := tlsConn.Handshake(); err != nil {
+			return nil, fmt.Errorf("tls handshake: %w", err)
+		}
+	}
+
+	sc, err := MakeSecretConnection(conn, t.nodeKey.PrivKey)
+	if err != nil {
+		return nil, err
+	}
+	remoteID := PubKeyToID(sc.RemotePubKey())
+	if dialedID != "" && remoteID != dialedID {
+		return nil, fmt.Errorf("%w: dialed %s, connected to %s", ErrPeerIDMismatch, dialedID, remoteID)
+	}
+
+	remoteInfo, err := t.exchangeNodeInfo(sc)
+	if err != nil {
+		return nil, err
+	}
+	if remoteInfo.ID != remoteID {
+		return nil, fmt.Errorf("%w: node info claims %s, key is %s", ErrPeerIDMismatch, remoteInfo.ID, remoteID)
+	}
+	if remoteInfo.ID == t.nodeInfo.ID {
+		return nil, ErrSelfConnection
+	}
+	if err := t.nodeInfo.CompatibleWith(remoteInfo); err != nil {
+		return nil, err
+	}
+
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+	return &upgradedConn{conn: sc, nodeInfo: remoteInfo}, nil
+}
+
+// exchangeNodeInfo sends our node info and reads the peer's, each as a
+// length-prefixed JSON document
+func (t *Transport) exchangeNodeInfo(sc *SecretConnection) (NodeInfo, error) {
+	local, err := json.Marshal(t.nodeInfo)
+	if err != nil {
+		return NodeInfo{}, err
+	}
+	msg := make([]byte, 4+len(local))
+	binary.BigEndian.PutUint32(msg, uint32(len(local)))
+	copy(msg[4:], local)
+
+	writeErr := make(chan error, 1)
+	go func() {
+		_, err := sc.Write(msg)
+		writeErr <- err
+	}()
+
+	var remote NodeInfo
+	var header [4]byte
+	if _, err := io.ReadFull(sc, header[:]); err != nil {
+		return NodeInfo{}, fmt.Errorf("failed to read node info: %w", err)
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxNodeInfoSize {
+		return NodeInfo{}, fmt.Errorf("node info of %d bytes exceeds %d", size, maxNodeInfoSize)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(sc, body); err != nil {
+		return NodeInfo{}, fmt.Errorf("failed to read node info: %w", err)
+	}
+	if err := json.Unmarshal(body, &remote); err != nil {
+		return NodeInfo{}, fmt.Errorf("failed to decode node info: %w", err)
+	}
+	if err := <-writeErr; err != nil {
+		return NodeInfo{}, fmt.Errorf("failed to send node info: %w", err)
+	}
+	if err := remote.Validate(); err != nil {
+		return NodeInfo{}, err
+	}
+	return remote, nil
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrUnknownDependency is returned for services depending on a service
+	// that was not added
+	ErrUnknownDependency = errors.New("unknown service dependency")
+	// ErrDependencyCycle is returned when services depend on each other
+	ErrDependencyCycle = errors.New("service dependency cycle")
+	// ErrStopTimeout is returned for services that did not stop in time
+	ErrStopTimeout = errors.New("service did not stop in time")
+)
+
+// DefaultStopTimeout bounds how long a service may take to stop
+const DefaultStopTimeout = 10 * time.Second
+
+// Service is a part of the node the supervisor starts and stops. Start
+// returns once the service runs; services that block while running, like
+// an HTTP server, set Run instead. All three are optional.
+type Service struct {
+	Name      string
+	DependsOn []string
+	Start     func(ctx context.Context) error
+	// Run blocks until the service stops. An error fails the supervisor
+	// unless the service is being stopped.
+	Run  func(ctx context.Context) error
+	Stop func(ctx context.Context) error
+	// StopTimeout overrides the supervisor's
+	StopTimeout time.Duration
+}
+
+// Config configures the supervisor
+type Config struct {
+	StopTimeout time.Duration // DefaultStopTimeout when 0
+	Logger      *zap.Logger
+}
+
+// Supervisor starts services after the services they depend on and stops
+// them in reverse order, each within a timeout. A service failing while
+// running is reported on Failed instead of exiting the process, so the
+// node can still stop the others cleanly.
+type Supervisor struct {
+	stopTimeout time.Duration
+	logger      *zap.Logger
+
+	mu       sync.Mutex
+	services []*Service
+	started  []*running
+	failed   chan error
+	stopping bool
+}
+
+// running is a started service
+type running struct {
+	service *Service
+	cancel  context.CancelFunc
+	done    chan struct{} // closed when Run returns
+}
+
+// NewSupervisor creates a supervisor
+func NewSupervisor(cfg *Config) *Supervisor {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	stopTimeout := cfg.StopTimeout
+	if stopTimeout == 0 {
+		stopTimeout = DefaultStopTimeout
+	}
+	return &Supervisor{stopTimeout: stopTimeout, logger: logger, failed: make(chan error, 1)}
+}
+
+// Add registers a service. Services must be added before Start.
+func (s *Supervisor) Add(svc *Service) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.services = append(s.services, svc)
+}
+
+// Start starts the services in dependency order. If one fails to start,
+// those already started are stopped and the error is returned.
+func (s *Supervisor) Start(ctx context.Context) error {
+	s.mu.Lock()
+	order, err := s.order()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, svc := range order {
+		svcCtx, cancel := context.WithCancel(ctx)
+		r := &running{service: svc, cancel: cancel, done: make(chan struct{})}
+		if svc.Start != nil {
+			if err := svc.Start(svcCtx); err != nil {
+				cancel()
+				s.Stop()
+				return fmt.Errorf("start %s: %w", svc.Name, err)
+			}
+		}
+		if svc.Run != nil {
+			go s.run(svcCtx, r)
+		} else {
+			close(r.done)
+		}
+
+		s.mu.Lock()
+		s.started = append(s.started, r)
+		s.mu.Unlock()
+		s.logger.Debug("Started service", zap.String("service", svc.Name))
+	}
+	return nil
+}
+
+func (s *Supervisor) run(ctx context.Context, r *running) {
+	defer close(r.done)
+	err := r.service.Run(ctx)
+	s.mu.Lock()
+	stopping := s.stopping
+	s.mu.Unlock()
+	if err != nil && !stopping {
+		s.Fail(r.service.Name, err)
+	}
+}
+
+// Fail reports that a service failed, for failures outside Start and Run
+// such as a service started later by another
+func (s *Supervisor) Fail(name string, err error) {
+	s.logger.Error("Service failed", zap.String("service", name), zap.Error(err))
+	select {
+	case s.failed <- fmt.Errorf("%s: %w", name, err):
+	default:
+	}
+}
+
+// Failed receives the first service failure
+func (s *Supervisor) Failed() <-chan error {
+	return s.failed
+}
+
+// Stop stops the started services in reverse dependency order. A service
+// that does not stop within its timeout is left behind so the others can
+// still stop. The errors of all services are returned together.
+func (s *Supervisor) Stop() error {
+	s.mu.Lock()
+	s.stopping = true
+	started := s.started
+	s.started = nil
+	s.mu.Unlock()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		if err := s.stop(started[i]); err != nil {
+			s.logger.Error("Failed to stop service", zap.String("service", started[i].service.Name), zap.Error(err))
+			errs = append(errs, fmt.Errorf("stop %s: %w", started[i].service.Name, err))
+		} else {
+			s.logger.Debug("Stopped service", zap.String("service", started[i].service.Name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// stop cancels a service's context, calls its Stop and waits for its Run
+// to return, all within the stop timeout
+func (s *Supervisor) stop(r *running) error {
+	timeout := r.service.StopTimeout
+	if timeout == 0 {
+		timeout = s.stopTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	stopped := make(chan error, 1)
+	go func() {
+		var err error
+		if r.service.Stop != nil {
+			err = r.service.Stop(ctx)
+		}
+		r.cancel()
+		<-r.done
+		stopped <- err
+	}()
+	select {
+	case err := <-stopped:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("%w after %s", ErrStopTimeout, timeout)
+	}
+}
+
+// order sorts the services so each comes after its dependencies, keeping
+// the order they were added in otherwise
+func (s *Supervisor) order() ([]*Service, error) {
+	byName := make(map[string]*Service, len(s.services))
+	for _, svc := range s.services {
+		byName[svc.Name] = svc
+	}
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	marks := make(map[string]int, len(s.services))
+	order := make([]*Service, 0, len(s.services))
+
+	var visit func(svc *Service, path []string) error
+	visit = func(svc *Service, path []string) error {
+		switch marks[svc.Name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w: %v", ErrDependencyCycle, append(path, svc.Name))
+		}
+		marks[svc.Name] = visiting
+		for _, name := range svc.DependsOn {
+			dep, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("%w: %s depends on %s", ErrUnknownDependency, svc.Name, name)
+			}
+			if err := visit(dep, append(path, svc.Name)); err != nil {
+				return err
+			}
+		}
+		marks[svc.Name] = visited
+		order = append(order, svc)
+		return nil
+	}
+	for _, svc := range s.services {
+		if err := visit(svc, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder logs the order services start and stop in
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.events...)
+}
+
+func (r *recorder) service(name string, dependsOn ...string) *Service {
+	return &Service{
+		Name:      name,
+		DependsOn: dependsOn,
+		Start: func(context.Context) error {
+			r.add("start " + name)
+			return nil
+		},
+		Stop: func(context.Context) error {
+			r.add("stop " + name)
+			return nil
+		},
+	}
+}
+
+func TestSupervisorOrder(t *testing.T) {
+	rec := &recorder{}
+	s := NewSupervisor(&Config{})
+	s.Add(rec.service("http", "blockchain", "p2p"))
+	s.Add(rec.service("p2p", "blockchain"))
+	s.Add(rec.service("wal"))
+	s.Add(rec.service("blockchain", "wal"))
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"start wal", "start blockchain", "start p2p", "start http",
+		"stop http", "stop p2p", "stop blockchain", "stop wal",
+	}
+	if got := rec.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v; want %v", got, want)
+	}
+}
+
+func TestSupervisorDependencyErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		services [][]string // name followed by its dependencies
+		wantErr  error
+	}{
+		{"unknown", [][]string{{"a", "missing"}}, ErrUnknownDependency},
+		{"self", [][]string{{"a", "a"}}, ErrDependencyCycle},
+		{"cycle", [][]string{{"a", "b"}, {"b", "c"}, {"c", "a"}}, ErrDependencyCycle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{}
+			s := NewSupervisor(&Config{})
+			for _, svc := range tt.services {
+				s.Add(rec.service(svc[0], svc[1:]...))
+			}
+			if err := s.Start(context.Background()); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Start = %v; want %v", err, tt.wantErr)
+			}
+			if got := rec.get(); len(got) != 0 {
+				t.Errorf("services ran despite the error: %v", got)
+			}
+		})
+	}
+}
+
+func TestSupervisorStartFailure(t *testing.T) {
+	rec := &recorder{}
+	errBoom := errors.New("boom")
+	s := NewSupervisor(&Config{})
+	s.Add(rec.service("a"))
+	s.Add(rec.service("b", "a"))
+	s.Add(&Service{
+		Name:      "c",
+		DependsOn: []string{"b"},
+		Start:     func(context.Context) error { return errBoom },
+		Stop: func(context.Context) error {
+			rec.add("stop c")
+			return nil
+		},
+	})
+	s.Add(rec.service("d", "c"))
+
+	if err := s.Start(context.Background()); !errors.Is(err, errBoom) {
+		t.Fatalf("Start = %v; want %v", err, errBoom)
+	}
+	want := []string{"start a", "start b", "stop b", "stop a"}
+	if got := rec.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v; want %v", got, want)
+	}
+}
+
+func TestSupervisorStopTimeout(t *testing.T) {
+	rec := &recorder{}
+	release := make(chan struct{})
+	defer close(release)
+
+	s := NewSupervisor(&Config{StopTimeout: time.Second})
+	s.Add(rec.service("a"))
+	s.Add(&Service{
+		Name:        "stuck",
+		DependsOn:   []string{"a"},
+		StopTimeout: 20 * time.Millisecond,
+		Stop: func(context.Context) error {
+			<-release // ignores its context
+			return nil
+		},
+	})
+	s.Add(&Service{
+		Name:      "blocking-run",
+		DependsOn: []string{"stuck"},
+		Run: func(context.Context) error {
+			<-release // ignores cancellation
+			return nil
+		},
+		StopTimeout: 20 * time.Millisecond,
+	})
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	err := s.Stop()
+	if !errors.Is(err, ErrStopTimeout) {
+		t.Fatalf("Stop = %v; want ErrStopTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Stop took %s; the per-service timeouts are 20ms", elapsed)
+	}
+	// The stuck services are left behind, and the others still stop
+	if got := rec.get(); !reflect.DeepEqual(got, []string{"start a", "stop a"}) {
+		t.Errorf("events = %v", got)
+	}
+}
+
+func TestSupervisorFail(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	t.Run("run error", func(t *testing.T) {
+		s := NewSupervisor(&Config{})
+		s.Add(&Service{Name: "crashes", Run: func(context.Context) error { return errBoom }})
+		if err := s.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		defer s.Stop()
+		select {
+		case err := <-s.Failed():
+			if !errors.Is(err, errBoom) {
+				t.Errorf("failure = %v; want %v", err, errBoom)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("no failure reported")
+		}
+	})
+
+	t.Run("first failure wins", func(t *testing.T) {
+		s := NewSupervisor(&Config{})
+		s.Fail("consensus", errBoom)
+		s.Fail("wal", errors.New("later")) // must not block
+		err := <-s.Failed()
+		if !errors.Is(err, errBoom) || err.Error() != "consensus: boom" {
+			t.Errorf("failure = %v; want consensus: boom", err)
+		}
+		select {
+		case err := <-s.Failed():
+			t.Errorf("second failure delivered: %v", err)
+		default:
+		}
+	})
+
+	t.Run("run error while stopping", func(t *testing.T) {
+		s := NewSupervisor(&Config{})
+		s.Add(&Service{Name: "server", Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}})
+		if err := s.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Stop(); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case err := <-s.Failed():
+			t.Errorf("stopping reported a failure: %v", err)
+		default:
+		}
+	})
+}
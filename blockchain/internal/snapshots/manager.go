@@ -0,0 +1,131 @@
+package snapshots
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// ErrSnapshotInProgress is returned when a snapshot is requested while
+// another is being taken
+var ErrSnapshotInProgress = errors.New("a snapshot is already being taken")
+
+// Snapshotter is implemented by the application to dump and restore its
+// state
+type Snapshotter interface {
+	// Snapshot writes the state as of height to w. It runs concurrently
+	// with block execution, so it must read a fixed version of the state.
+	Snapshot(height int64, w io.Writer) error
+	// Restore replaces the state with the snapshot read from r
+	Restore(height int64, format uint32, r io.Reader) error
+}
+
+// Config configures the snapshot manager
+type Config struct {
+	Store       *Store
+	Snapshotter Snapshotter
+	Interval    int64 // take a snapshot every Interval blocks; 0 disables periodic snapshots
+	KeepRecent  int   // number of snapshot heights to keep; 0 keeps all
+	ChunkSize   int
+	Logger      *zap.Logger
+}
+
+// Manager takes snapshots at the configured interval and restores local
+// snapshots
+type Manager struct {
+	config *Config
+	store  *Store
+	logger *zap.Logger
+
+	mu         sync.Mutex
+	inProgress bool
+}
+
+// NewManager creates a snapshot manager
+func NewManager(cfg *Config) *Manager {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if cfg.ChunkSize == 0 {
+		cfg.ChunkSize = DefaultChunkSize
+	}
+	return &Manager{config: cfg, store: cfg.Store, logger: logger}
+}
+
+// Store returns the snapshot store
+func (m *Manager) Store() *Store { return m.store }
+
+// OnCommit is called after every committed block and takes a snapshot in
+// the background when the height is a multiple of the interval
+func (m *Manager) OnCommit(height int64) {
+	if m.config.Interval <= 0 || height%m.config.Interval != 0 {
+		return
+	}
+	go func() {
+		if _, err := m.Create(height); err != nil {
+			if errors.Is(err, ErrSnapshotInProgress) {
+				m.logger.Warn("Skipping snapshot; the previous one is still running", zap.Int64("height", height))
+				return
+			}
+			m.logger.Error("Failed to take snapshot", zap.Int64("height", height), zap.Error(err))
+		}
+	}()
+}
+
+// Create takes a snapshot of the state at height and prunes old snapshots
+func (m *Manager) Create(height int64) (*Snapshot, error) {
+	m.mu.Lock()
+	if m.inProgress {
+		m.mu.Unlock()
+		return nil, ErrSnapshotInProgress
+	}
+	m.inProgress = true
+	m.mu.Unlock()
+
+	defer func() {
+		m.mu.Lock()
+		m.inProgress = false
+		m.mu.Unlock()
+	}()
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(m.config.Snapshotter.Snapshot(height, pw))
+	}()
+	snapshot, err := m.store.Save(height, CurrentFormat, pr, m.config.ChunkSize)
+	pr.CloseWithError(err)
+	if err != nil {
+		return nil, err
+	}
+	m.logger.Info("Took state snapshot",
+		zap.Int64("height", snapshot.Height),
+		zap.Uint32("chunks", snapshot.Chunks),
+		zap.Int64("size", snapshot.Size),
+		zap.String("hash", snapshot.Hash.String()),
+	)
+
+	pruned, err := m.store.Prune(m.config.KeepRecent)
+	if err != nil {
+		m.logger.Error("Failed to prune snapshots", zap.Error(err))
+	} else if pruned > 0 {
+		m.logger.Info("Pruned old snapshots", zap.Int("pruned", pruned))
+	}
+	return snapshot, nil
+}
+
+// Restore replaces the application state with a local snapshot
+func (m *Manager) Restore(height int64, format uint32) error {
+	if format != CurrentFormat {
+		return fmt.Errorf("%w: unsupported format %d", ErrInvalidSnapshot, format)
+	}
+	r, err := m.store.Open(height, format)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return m.config.Snapshotter.Restore(height, format, r)
+}
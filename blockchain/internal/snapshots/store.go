@@ -0,0 +1,332 @@
+package snapshots
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+const (
+	// CurrentFormat is the snapshot format written by this version: the
+	// application's snapshot stream cut into fixed size chunks
+	CurrentFormat uint32 = 1
+
+	// DefaultChunkSize is the size of every chunk but the last. Chunks are
+	// sent to peers in a single p2p message, so they stay well below the
+	// message size limit.
+	DefaultChunkSize = 2 << 20 // 2 MiB
+
+	metadataFile = "metadata.json"
+)
+
+var (
+	// ErrSnapshotNotFound is returned for heights and formats with no complete snapshot
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+	// ErrSnapshotExists is returned when saving a snapshot that is already stored
+	ErrSnapshotExists = errors.New("snapshot already exists")
+	// ErrChunkHashMismatch is returned for chunks that do not match the snapshot metadata
+	ErrChunkHashMismatch = errors.New("chunk hash mismatch")
+	// ErrInvalidSnapshot is returned for snapshot metadata that does not add up
+	ErrInvalidSnapshot = errors.New("invalid snapshot")
+)
+
+// Snapshot describes a stored state snapshot. Hash commits to the chunk
+// hashes, so two peers offering the same hash offer the same chunks.
+type Snapshot struct {
+	Height      int64            `json:"height"`
+	Format      uint32           `json:"format"`
+	Chunks      uint32           `json:"chunks"`
+	Hash        types.HexBytes   `json:"hash"`
+	ChunkHashes []types.HexBytes `json:"chunk_hashes"`
+	Size        int64            `json:"size"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+// Validate checks that the metadata is consistent with itself
+func (s *Snapshot) Validate() error {
+	if s.Height <= 0 {
+		return fmt.Errorf("%w: height must be positive", ErrInvalidSnapshot)
+	}
+	if s.Chunks == 0 || int(s.Chunks) != len(s.ChunkHashes) {
+		return fmt.Errorf("%w: %d chunks with %d chunk hashes", ErrInvalidSnapshot, s.Chunks, len(s.ChunkHashes))
+	}
+	if !bytes.Equal(s.Hash, snapshotHash(s.ChunkHashes)) {
+		return fmt.Errorf("%w: hash does not match the chunk hashes", ErrInvalidSnapshot)
+	}
+	return nil
+}
+
+// VerifyChunk checks a chunk against the metadata
+func (s *Snapshot) VerifyChunk(index uint32, chunk []byte) error {
+	if index >= s.Chunks {
+		return fmt.Errorf("%w: chunk %d of %d", ErrInvalidSnapshot, index, s.Chunks)
+	}
+	sum := sha256.Sum256(chunk)
+	if !bytes.Equal(sum[:], s.ChunkHashes[index]) {
+		return fmt.Errorf("%w: chunk %d of snapshot %d", ErrChunkHashMismatch, index, s.Height)
+	}
+	return nil
+}
+
+func snapshotHash(chunkHashes []types.HexBytes) types.HexBytes {
+	h := sha256.New()
+	for _, chunkHash := range chunkHashes {
+		h.Write(chunkHash)
+	}
+	return h.Sum(nil)
+}
+
+// Store keeps snapshots on disk, one directory per height and format. The
+// metadata file is written last, so directories without one are partial
+// snapshots and are ignored.
+type Store struct {
+	mu  sync.RWMutex
+	dir string
+}
+
+// NewStore opens or creates a snapshot store in dir
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Dir returns the store directory
+func (s *Store) Dir() string { return s.dir }
+
+func (s *Store) path(height int64, format uint32, elems ...string) string {
+	return filepath.Join(append([]string{s.dir, strconv.FormatInt(height, 10), strconv.FormatUint(uint64(format), 10)}, elems...)...)
+}
+
+func chunkFile(index uint32) string {
+	return fmt.Sprintf("chunk-%06d", index)
+}
+
+// Save reads a snapshot stream from r, cuts it into chunks and stores it
+func (s *Store) Save(height int64, format uint32, r io.Reader, chunkSize int) (*Snapshot, error) {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	// Chunks are written without holding the lock so peers can still be
+	// served while a snapshot is being taken; until the metadata exists the
+	// directory is invisible to readers
+	if _, err := s.Get(height, format); err == nil {
+		return nil, fmt.Errorf("%w: height %d format %d", ErrSnapshotExists, height, format)
+	}
+	dir := s.path(height, format)
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{Height: height, Format: format, CreatedAt: time.Now().UTC()}
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			sum := sha256.Sum256(buf[:n])
+			if werr := os.WriteFile(filepath.Join(dir, chunkFile(snapshot.Chunks)), buf[:n], 0o644); werr != nil {
+				os.RemoveAll(dir)
+				return nil, werr
+			}
+			snapshot.ChunkHashes = append(snapshot.ChunkHashes, sum[:])
+			snapshot.Chunks++
+			snapshot.Size += int64(n)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			os.RemoveAll(dir)
+			return nil, err
+		}
+	}
+	if snapshot.Chunks == 0 {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("%w: empty snapshot stream", ErrInvalidSnapshot)
+	}
+	snapshot.Hash = snapshotHash(snapshot.ChunkHashes)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writeMetadata(snapshot); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+func (s *Store) writeMetadata(snapshot *Snapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := s.path(snapshot.Height, snapshot.Format, metadataFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Get returns the metadata of a stored snapshot
+func (s *Store) Get(height int64, format uint32) (*Snapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.get(height, format)
+}
+
+func (s *Store) get(height int64, format uint32) (*Snapshot, error) {
+	data, err := os.ReadFile(s.path(height, format, metadataFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: height %d format %d", ErrSnapshotNotFound, height, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	return &snapshot, nil
+}
+
+// List returns the stored snapshots, newest first
+func (s *Store) List() ([]*Snapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	heights, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var list []*Snapshot
+	for _, heightDir := range heights {
+		height, err := strconv.ParseInt(heightDir.Name(), 10, 64)
+		if err != nil || !heightDir.IsDir() {
+			continue
+		}
+		formats, err := os.ReadDir(filepath.Join(s.dir, heightDir.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, formatDir := range formats {
+			format, err := strconv.ParseUint(formatDir.Name(), 10, 32)
+			if err != nil {
+				continue
+			}
+			if snapshot, err := s.get(height, uint32(format)); err == nil {
+				list = append(list, snapshot)
+			}
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Height != list[j].Height {
+			return list[i].Height > list[j].Height
+		}
+		return list[i].Format > list[j].Format
+	})
+	return list, nil
+}
+
+// LoadChunk returns one chunk of a stored snapshot
+func (s *Store) LoadChunk(height int64, format uint32, index uint32) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot, err := s.get(height, format)
+	if err != nil {
+		return nil, err
+	}
+	if index >= snapshot.Chunks {
+		return nil, fmt.Errorf("%w: chunk %d of %d", ErrSnapshotNotFound, index, snapshot.Chunks)
+	}
+	return os.ReadFile(s.path(height, format, chunkFile(index)))
+}
+
+// Open returns the snapshot stream, verifying every chunk as it is read
+func (s *Store) Open(height int64, format uint32) (io.ReadCloser, error) {
+	snapshot, err := s.Get(height, format)
+	if err != nil {
+		return nil, err
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		for i := uint32(0); i < snapshot.Chunks; i++ {
+			chunk, err := s.LoadChunk(height, format, i)
+			if err == nil {
+				err = snapshot.VerifyChunk(i, chunk)
+			}
+			if err == nil {
+				_, err = pw.Write(chunk)
+			}
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		pw.Close()
+	}()
+	return pr, nil
+}
+
+// Delete removes a snapshot
+func (s *Store) Delete(height int64, format uint32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.get(height, format); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(s.path(height, format)); err != nil {
+		return err
+	}
+	// Drop the height directory once its last format is gone
+	os.Remove(filepath.Join(s.dir, strconv.FormatInt(height, 10)))
+	return nil
+}
+
+// Prune deletes all but the keepRecent newest snapshot heights. Zero keeps
+// everything.
+func (s *Store) Prune(keepRecent int) (int, error) {
+	if keepRecent <= 0 {
+		return 0, nil
+	}
+	list, err := s.List()
+	if err != nil {
+		return 0, err
+	}
+
+	pruned := 0
+	heights := 0
+	var lastHeight int64
+	for _, snapshot := range list {
+		if snapshot.Height != lastHeight {
+			heights++
+			lastHeight = snapshot.Height
+		}
+		if heights <= keepRecent {
+			continue
+		}
+		if err := s.Delete(snapshot.Height, snapshot.Format); err != nil {
+			return pruned, err
+		}
+		pruned++
+	}
+	return pruned, nil
+}
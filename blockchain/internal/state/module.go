@@ -0,0 +1,134 @@
+package state
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Module is application state kept in memory by a keeper and persisted in
+// the store under a key prefix the module owns. Every Commit writes the
+// keys the module changed since the last one, so the module is part of the
+// app hash, survives restarts and travels in snapshots.
+type Module interface {
+	// ExportState returns the module's whole state as keys, relative to
+	// the module prefix, and non-empty values
+	ExportState() (map[string][]byte, error)
+	// ExportChanges returns the keys changed since the last export or
+	// import, with their values or nil for deleted keys, and forgets them
+	ExportChanges() (map[string][]byte, error)
+	// ImportState replaces the module's state with exported keys and values
+	ImportState(state map[string][]byte) error
+}
+
+type registeredModule struct {
+	prefix  string
+	module  Module
+	genesis bool              // nothing is stored yet; the next Commit stores the whole state
+	unsaved map[string][]byte // changes exported but not committed yet
+}
+
+// RegisterModule persists a module under prefix. Once the chain has
+// committed a version the module's state is replaced with the one stored
+// in it; before that the module keeps its genesis state, which the first
+// Commit stores. Prefixes must not overlap other modules or keys set
+// directly.
+func (s *Store) RegisterModule(prefix string, module Module) error {
+	if prefix == "" {
+		return ErrEmptyKey
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range s.modules {
+		if strings.HasPrefix(m.prefix, prefix) || strings.HasPrefix(prefix, m.prefix) {
+			return fmt.Errorf("state module prefix %q overlaps %q", prefix, m.prefix)
+		}
+	}
+
+	m := &registeredModule{prefix: prefix, module: module, genesis: true, unsaved: make(map[string][]byte)}
+	keys := 0
+	if s.last.Version > 0 {
+		var err error
+		if keys, err = s.importModule(m); err != nil {
+			return err
+		}
+	}
+	s.modules = append(s.modules, m)
+	s.logger.Info("Registered state module",
+		zap.String("prefix", prefix),
+		zap.Int("keys", keys),
+	)
+	return nil
+}
+
+// importModule loads a module's keys from the latest version into it and
+// returns how many there are
+func (s *Store) importModule(m *registeredModule) (int, error) {
+	stored := make(map[string][]byte)
+	prefix := []byte(m.prefix)
+	err := walk(s.db, s.last.Hash, func(n *node) error {
+		if bytes.HasPrefix(n.key, prefix) {
+			stored[string(n.key[len(prefix):])] = n.value
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := m.module.ImportState(stored); err != nil {
+		return 0, fmt.Errorf("failed to import state module %q: %w", m.prefix, err)
+	}
+	m.genesis = false
+	m.unsaved = make(map[string][]byte)
+	return len(stored), nil
+}
+
+// reloadModules imports every module again after the latest version was
+// replaced by a rollback or a snapshot restore
+func (s *Store) reloadModules() error {
+	for _, m := range s.modules {
+		if _, err := s.importModule(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportModules returns the keys every module changed since the last
+// commit, with nil values for deleted keys. A module's first commit
+// stores its whole genesis state. The changes stay unsaved until
+// committed, so a failed commit writes them with the next one.
+func (s *Store) exportModules() (map[string][]byte, error) {
+	changed := make(map[string][]byte)
+	for _, m := range s.modules {
+		changes, err := m.module.ExportChanges()
+		if err != nil {
+			return nil, fmt.Errorf("failed to export state module %q: %w", m.prefix, err)
+		}
+		if m.genesis {
+			if changes, err = m.module.ExportState(); err != nil {
+				return nil, fmt.Errorf("failed to export state module %q: %w", m.prefix, err)
+			}
+		}
+		for key, value := range changes {
+			if value != nil && len(value) == 0 {
+				return nil, fmt.Errorf("state module %q: %w: %s", m.prefix, ErrEmptyValue, key)
+			}
+			m.unsaved[key] = value
+		}
+		for key, value := range m.unsaved {
+			changed[m.prefix+key] = value
+		}
+	}
+	return changed, nil
+}
+
+// modulesCommitted marks the exported module changes as stored
+func (s *Store) modulesCommitted() {
+	for _, m := range s.modules {
+		m.genesis = false
+		m.unsaved = make(map[string][]byte)
+	}
+}
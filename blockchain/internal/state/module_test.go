@@ -0,0 +1,208 @@
+package state
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/vindexchain/blockchain/internal/kv"
+	"github.com/vindexchain/blockchain/internal/snapshots"
+)
+
+// mapModule is a module whose state is a plain map
+type mapModule struct {
+	values  map[string]string
+	changed map[string]bool
+}
+
+func newMapModule(values map[string]string) *mapModule {
+	m := &mapModule{values: make(map[string]string), changed: make(map[string]bool)}
+	for k, v := range values {
+		m.values[k] = v
+	}
+	return m
+}
+
+func (m *mapModule) set(key, value string) {
+	m.values[key] = value
+	m.changed[key] = true
+}
+
+func (m *mapModule) remove(key string) {
+	delete(m.values, key)
+	m.changed[key] = true
+}
+
+func (m *mapModule) ExportState() (map[string][]byte, error) {
+	state := make(map[string][]byte, len(m.values))
+	for k, v := range m.values {
+		state[k] = []byte(v)
+	}
+	return state, nil
+}
+
+func (m *mapModule) ExportChanges() (map[string][]byte, error) {
+	changes := make(map[string][]byte, len(m.changed))
+	for k := range m.changed {
+		changes[k] = nil
+		if v, ok := m.values[k]; ok {
+			changes[k] = []byte(v)
+		}
+	}
+	m.changed = make(map[string]bool)
+	return changes, nil
+}
+
+func (m *mapModule) ImportState(state map[string][]byte) error {
+	m.changed = make(map[string]bool)
+	m.values = make(map[string]string, len(state))
+	for k, v := range state {
+		m.values[k] = string(v)
+	}
+	return nil
+}
+
+func TestModuleCommitAndReload(t *testing.T) {
+	db := kv.NewMemDB()
+	s := newTestStore(t, db, PruningOptions{Strategy: PruningNothing})
+	bank := newMapModule(map[string]string{"alice": "100", "bob": "5"})
+	if err := s.RegisterModule("bank/", bank); err != nil {
+		t.Fatal(err)
+	}
+	v1 := commit(t, s, map[string]string{"other": "x"})
+
+	proof, err := s.ProveState([]byte("bank/alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(proof.Value) != "100" || proof.Verify(v1.Hash) != nil {
+		t.Errorf("bank/alice = %q; want a verified 100", proof.Value)
+	}
+
+	bank.set("alice", "90")
+	bank.remove("bob")
+	bank.set("carol", "15")
+	commit(t, s, nil)
+	for key, want := range map[string]string{"bank/alice": "90", "bank/bob": "", "bank/carol": "15", "other": "x"} {
+		if value, err := s.Get([]byte(key)); err != nil || string(value) != want {
+			t.Errorf("%s = %q, %v; want %q", key, value, err, want)
+		}
+	}
+
+	// A restarted node gets the committed state, not its genesis state
+	reopened := newTestStore(t, db, PruningOptions{Strategy: PruningNothing})
+	restarted := newMapModule(map[string]string{"alice": "100", "bob": "5"})
+	if err := reopened.RegisterModule("bank/", restarted); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"alice": "90", "carol": "15"}; !sameValues(restarted.values, want) {
+		t.Errorf("restarted module = %v; want %v", restarted.values, want)
+	}
+
+	if err := reopened.Rollback(v1.Version); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"alice": "100", "bob": "5"}; !sameValues(restarted.values, want) {
+		t.Errorf("module after rollback = %v; want %v", restarted.values, want)
+	}
+}
+
+func TestModuleChangesSurviveFailedCommit(t *testing.T) {
+	fail := true
+	s, err := NewStore(&Config{
+		DB:      kv.NewMemDB(),
+		Pruning: PruningOptions{Strategy: PruningNothing},
+		BeforeCommit: func(version int64) error {
+			if version == 2 && fail {
+				return errors.New("disk full")
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bank := newMapModule(map[string]string{"alice": "100"})
+	if err := s.RegisterModule("bank/", bank); err != nil {
+		t.Fatal(err)
+	}
+	commit(t, s, nil)
+
+	bank.set("alice", "90")
+	bank.set("bob", "10")
+	if _, err := s.Commit(); err == nil {
+		t.Fatal("commit succeeded; want the BeforeCommit error")
+	}
+	fail = false
+	commit(t, s, nil)
+	for key, want := range map[string]string{"bank/alice": "90", "bank/bob": "10"} {
+		if value, err := s.Get([]byte(key)); err != nil || string(value) != want {
+			t.Errorf("%s = %q, %v; want %q", key, value, err, want)
+		}
+	}
+}
+
+func TestModuleSnapshotRestore(t *testing.T) {
+	src := newTestStore(t, kv.NewMemDB(), PruningOptions{Strategy: PruningNothing})
+	if err := src.RegisterModule("names/", newMapModule(map[string]string{"alice": "addr1"})); err != nil {
+		t.Fatal(err)
+	}
+	id := commit(t, src, map[string]string{"k": "v"})
+
+	var buf bytes.Buffer
+	if err := src.Snapshot(id.Version, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := newTestStore(t, kv.NewMemDB(), PruningOptions{Strategy: PruningNothing})
+	names := newMapModule(nil)
+	if err := dst.RegisterModule("names/", names); err != nil {
+		t.Fatal(err)
+	}
+	if err := dst.Restore(id.Version, snapshots.CurrentFormat, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := dst.LastCommitID(); got.Version != id.Version || !bytes.Equal(got.Hash, id.Hash) {
+		t.Errorf("restored at %+v; want %+v", got, id)
+	}
+	if names.values["alice"] != "addr1" {
+		t.Errorf("restored module = %v", names.values)
+	}
+	if next := commit(t, dst, nil); next.Version != id.Version+1 || !bytes.Equal(next.Hash, id.Hash) {
+		t.Errorf("commit after restore changed the state: %+v", next)
+	}
+}
+
+func TestRegisterModuleErrors(t *testing.T) {
+	s := newTestStore(t, kv.NewMemDB(), PruningOptions{Strategy: PruningNothing})
+	if err := s.RegisterModule("dex/", newMapModule(nil)); err != nil {
+		t.Fatal(err)
+	}
+	for _, prefix := range []string{"dex/", "dex/pools/", "de"} {
+		if err := s.RegisterModule(prefix, newMapModule(nil)); err == nil {
+			t.Errorf("prefix %q overlapping dex/ was registered", prefix)
+		}
+	}
+	if err := s.RegisterModule("", newMapModule(nil)); !errors.Is(err, ErrEmptyKey) {
+		t.Errorf("empty prefix: %v; want ErrEmptyKey", err)
+	}
+
+	if err := s.RegisterModule("bad/", newMapModule(map[string]string{"empty": ""})); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Commit(); !errors.Is(err, ErrEmptyValue) {
+		t.Errorf("commit of an empty module value: %v; want ErrEmptyValue", err)
+	}
+}
+
+func sameValues(got, want map[string]string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for k, v := range want {
+		if got[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,200 @@
+package state
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/vindexchain/blockchain/internal/kv"
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+var errCorruptNode = errors.New("corrupt state node")
+
+const (
+	nodeLeaf  byte = 0
+	nodeInner byte = 1
+)
+
+// node is a sparse Merkle tree node. A subtree holding a single key is
+// stored as its leaf, wherever in the tree that subtree starts.
+type node struct {
+	kind byte
+	// leaf
+	path  []byte
+	key   []byte
+	value []byte
+	// inner
+	left  []byte
+	right []byte
+}
+
+func newLeaf(key, value []byte) *node {
+	return &node{kind: nodeLeaf, path: types.SparsePath(key), key: key, value: value}
+}
+
+func newInner(left, right []byte) *node {
+	return &node{kind: nodeInner, left: left, right: right}
+}
+
+func (n *node) isLeaf() bool { return n.kind == nodeLeaf }
+
+func (n *node) hash() []byte {
+	if n.isLeaf() {
+		return types.SparseLeafHash(n.path, n.value)
+	}
+	return types.SparseInnerHash(n.left, n.right)
+}
+
+func (n *node) encode() []byte {
+	if !n.isLeaf() {
+		buf := make([]byte, 0, 1+2*sha256.Size)
+		buf = append(buf, nodeInner)
+		buf = append(buf, n.left...)
+		return append(buf, n.right...)
+	}
+	buf := make([]byte, 0, 1+binary.MaxVarintLen64+len(n.key)+len(n.value))
+	buf = append(buf, nodeLeaf)
+	buf = binary.AppendUvarint(buf, uint64(len(n.key)))
+	buf = append(buf, n.key...)
+	return append(buf, n.value...)
+}
+
+func decodeNode(data []byte) (*node, error) {
+	if len(data) == 0 {
+		return nil, errCorruptNode
+	}
+	switch data[0] {
+	case nodeInner:
+		if len(data) != 1+2*sha256.Size {
+			return nil, errCorruptNode
+		}
+		return newInner(data[1:1+sha256.Size], data[1+sha256.Size:]), nil
+	case nodeLeaf:
+		keyLen, n := binary.Uvarint(data[1:])
+		if n <= 0 || uint64(len(data)-1-n) < keyLen {
+			return nil, errCorruptNode
+		}
+		key := data[1+n : 1+n+int(keyLen)]
+		return newLeaf(key, data[1+n+int(keyLen):]), nil
+	default:
+		return nil, errCorruptNode
+	}
+}
+
+// Nodes are stored under their hash with the number of references to them:
+// one from every stored parent and one from every version whose root they
+// are. A node is deleted when its last reference goes.
+var (
+	nodePrefix    = []byte("n/")
+	versionPrefix = []byte("r/")
+	latestKey     = []byte("m/latest")
+)
+
+func nodeKey(hash []byte) []byte { return append(append([]byte{}, nodePrefix...), hash...) }
+
+func versionKey(version int64) []byte {
+	return binary.BigEndian.AppendUint64(append([]byte{}, versionPrefix...), uint64(version))
+}
+
+func isEmpty(hash []byte) bool { return bytes.Equal(hash, types.EmptySparseRoot) }
+
+// getter reads records from the database or from inside a transaction
+type getter interface {
+	Get(key []byte) ([]byte, error)
+}
+
+func loadNode(g getter, hash []byte) (*node, error) {
+	data, err := g.Get(nodeKey(hash))
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("%w: node %X is missing", errCorruptNode, hash)
+	}
+	_, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, errCorruptNode
+	}
+	return decodeNode(data[n:])
+}
+
+// nodeWriter changes nodes and their reference counts inside a transaction
+type nodeWriter struct {
+	tx kv.Tx
+}
+
+// put stores n unless an identical node exists, and returns its hash. The
+// new node holds a reference to each child; it gains its own reference
+// when a parent or a version root is stored.
+func (w nodeWriter) put(n *node) ([]byte, error) {
+	hash := n.hash()
+	existing, err := w.tx.Get(nodeKey(hash))
+	if err != nil || existing != nil {
+		return hash, err
+	}
+	if !n.isLeaf() {
+		for _, child := range [][]byte{n.left, n.right} {
+			if err := w.ref(child); err != nil {
+				return nil, err
+			}
+		}
+	}
+	record := binary.AppendUvarint(nil, 0)
+	return hash, w.tx.Set(nodeKey(hash), append(record, n.encode()...))
+}
+
+// ref adds a reference to a stored node
+func (w nodeWriter) ref(hash []byte) error {
+	if isEmpty(hash) {
+		return nil
+	}
+	refs, data, err := w.record(hash)
+	if err != nil {
+		return err
+	}
+	return w.tx.Set(nodeKey(hash), append(binary.AppendUvarint(nil, refs+1), data...))
+}
+
+// unref drops a reference to a node, deleting it and dropping its
+// references to its children when it was the last one
+func (w nodeWriter) unref(hash []byte) error {
+	if isEmpty(hash) {
+		return nil
+	}
+	refs, data, err := w.record(hash)
+	if err != nil {
+		return err
+	}
+	if refs > 1 {
+		return w.tx.Set(nodeKey(hash), append(binary.AppendUvarint(nil, refs-1), data...))
+	}
+	if err := w.tx.Delete(nodeKey(hash)); err != nil {
+		return err
+	}
+	n, err := decodeNode(data)
+	if err != nil || n.isLeaf() {
+		return err
+	}
+	if err := w.unref(n.left); err != nil {
+		return err
+	}
+	return w.unref(n.right)
+}
+
+func (w nodeWriter) record(hash []byte) (uint64, []byte, error) {
+	data, err := w.tx.Get(nodeKey(hash))
+	if err != nil {
+		return 0, nil, err
+	}
+	if data == nil {
+		return 0, nil, fmt.Errorf("%w: node %X is missing", errCorruptNode, hash)
+	}
+	refs, n := binary.Uvarint(data)
+	if n <= 0 {
+		return 0, nil, errCorruptNode
+	}
+	return refs, data[n:], nil
+}
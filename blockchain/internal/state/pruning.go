@@ -0,0 +1,65 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidPruning is returned for unknown or inconsistent pruning options
+var ErrInvalidPruning = errors.New("invalid pruning options")
+
+// Pruning strategies
+const (
+	// PruningDefault keeps the last 362880 versions, about three weeks of
+	// 5 second blocks, and prunes every 10 commits
+	PruningDefault = "default"
+	// PruningNothing keeps every version, as archive nodes do
+	PruningNothing = "nothing"
+	// PruningEverything keeps only the last 2 versions and prunes every 10
+	// commits
+	PruningEverything = "everything"
+	// PruningCustom keeps KeepRecent versions and prunes every Interval
+	// commits
+	PruningCustom = "custom"
+)
+
+// PruningOptions decides which old versions of the state are deleted
+type PruningOptions struct {
+	Strategy   string
+	KeepRecent int64 // number of recent versions to keep, the latest included
+	Interval   int64 // prune every Interval commits; 0 never prunes
+}
+
+// NewPruningOptions returns the options for a strategy. keepRecent and
+// interval are only used by the custom strategy.
+func NewPruningOptions(strategy string, keepRecent, interval int64) (PruningOptions, error) {
+	switch strategy {
+	case PruningDefault, "":
+		return PruningOptions{Strategy: PruningDefault, KeepRecent: 362880, Interval: 10}, nil
+	case PruningNothing:
+		return PruningOptions{Strategy: PruningNothing}, nil
+	case PruningEverything:
+		return PruningOptions{Strategy: PruningEverything, KeepRecent: 2, Interval: 10}, nil
+	case PruningCustom:
+		if keepRecent < 1 {
+			return PruningOptions{}, fmt.Errorf("%w: keep-recent must be at least 1", ErrInvalidPruning)
+		}
+		if interval < 1 {
+			return PruningOptions{}, fmt.Errorf("%w: interval must be at least 1", ErrInvalidPruning)
+		}
+		return PruningOptions{Strategy: PruningCustom, KeepRecent: keepRecent, Interval: interval}, nil
+	default:
+		return PruningOptions{}, fmt.Errorf("%w: unknown strategy %q", ErrInvalidPruning, strategy)
+	}
+}
+
+// shouldPrune reports whether versions are pruned after committing version
+func (o PruningOptions) shouldPrune(version int64) bool {
+	return o.Interval > 0 && o.KeepRecent > 0 && version%o.Interval == 0
+}
+
+// pruneBelow returns the oldest version that is kept after committing
+// version
+func (o PruningOptions) pruneBelow(version int64) int64 {
+	return version - o.KeepRecent + 1
+}
@@ -0,0 +1,150 @@
+package state
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/kv"
+	"github.com/vindexchain/blockchain/internal/snapshots"
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+// maxSnapshotItem bounds the size of a key or value read from a snapshot
+const maxSnapshotItem = 64 << 20
+
+// Snapshot writes every key and value of the version committed at height
+// to w, in tree order, as length-prefixed pairs. The version is kept from
+// pruning until the export finishes, and blocks keep being committed
+// meanwhile.
+func (s *Store) Snapshot(height int64, w io.Writer) error {
+	s.mu.Lock()
+	root, err := s.root(height)
+	if err == nil {
+		s.pinned[height]++
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		s.mu.Lock()
+		if s.pinned[height]--; s.pinned[height] == 0 {
+			delete(s.pinned, height)
+		}
+		s.mu.Unlock()
+	}()
+
+	bw := bufio.NewWriter(w)
+	var buf []byte
+	err = walk(s.db, root, func(n *node) error {
+		buf = binary.AppendUvarint(buf[:0], uint64(len(n.key)))
+		buf = append(buf, n.key...)
+		buf = binary.AppendUvarint(buf, uint64(len(n.value)))
+		buf = append(buf, n.value...)
+		_, err := bw.Write(buf)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Restore replaces the whole state with a snapshot taken at height, which
+// becomes the latest version, and reloads the registered modules from it.
+// The caller checks the resulting app hash.
+func (s *Store) Restore(height int64, format uint32, r io.Reader) error {
+	if format != snapshots.CurrentFormat {
+		return fmt.Errorf("%w: unsupported format %d", snapshots.ErrInvalidSnapshot, format)
+	}
+
+	var sets []change
+	br := bufio.NewReader(r)
+	for {
+		key, err := readSnapshotItem(br)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		value, err := readSnapshotItem(br)
+		if err != nil {
+			return fmt.Errorf("%w: %v", snapshots.ErrInvalidSnapshot, err)
+		}
+		if len(key) == 0 || len(value) == 0 {
+			return fmt.Errorf("%w: empty key or value", snapshots.ErrInvalidSnapshot)
+		}
+		sets = append(sets, change{path: types.SparsePath(key), key: key, value: value})
+	}
+	for i := 1; i < len(sets); i++ {
+		if string(sets[i-1].path) >= string(sets[i].path) {
+			return fmt.Errorf("%w: keys out of order", snapshots.ErrInvalidSnapshot)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var existing [][]byte
+	err := s.db.Iterate(nil, func(key, _ []byte) error {
+		existing = append(existing, append([]byte{}, key...))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var root []byte
+	err = s.db.Update(func(tx kv.Tx) error {
+		for _, key := range existing {
+			if err := tx.Delete(key); err != nil {
+				return err
+			}
+		}
+		w := nodeWriter{tx: tx}
+		var err error
+		if root, err = w.buildSets(0, sets); err != nil {
+			return err
+		}
+		if err := tx.Set(versionKey(height), root); err != nil {
+			return err
+		}
+		if err := w.ref(root); err != nil {
+			return err
+		}
+		return tx.Set(latestKey, binary.AppendVarint(nil, height))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore state: %w", err)
+	}
+
+	s.last = CommitID{Version: height, Hash: root}
+	s.pending = make(map[string][]byte)
+	s.logger.Info("Restored state",
+		zap.Int64("version", height),
+		zap.Int("keys", len(sets)),
+		zap.String("hash", s.last.Hash.String()),
+	)
+	return s.reloadModules()
+}
+
+func readSnapshotItem(r *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxSnapshotItem {
+		return nil, fmt.Errorf("%w: item of %d bytes", snapshots.ErrInvalidSnapshot, size)
+	}
+	item := make([]byte, size)
+	if _, err := io.ReadFull(r, item); err != nil {
+		return nil, fmt.Errorf("%w: %v", snapshots.ErrInvalidSnapshot, err)
+	}
+	return item, nil
+}
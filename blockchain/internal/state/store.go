@@ -0,0 +1,425 @@
+package state
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/kv"
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+var (
+	// ErrVersionNotFound is returned for versions that were never committed
+	// or have been pruned
+	ErrVersionNotFound = errors.New("state version not found")
+	// ErrVersionInUse is returned when deleting the latest version or one
+	// that is being exported
+	ErrVersionInUse = errors.New("state version is in use")
+	// ErrEmptyKey is returned when setting an empty key
+	ErrEmptyKey = errors.New("state key cannot be empty")
+	// ErrEmptyValue is returned when setting an empty value; delete the key
+	// instead, since proofs treat an empty value as no value
+	ErrEmptyValue = errors.New("state value cannot be empty")
+)
+
+var errStopIteration = errors.New("stop iteration")
+
+// CommitID identifies a committed version of the state. Hash is the app
+// hash the next block header commits to.
+type CommitID struct {
+	Version int64          `json:"version"`
+	Hash    types.HexBytes `json:"hash"`
+}
+
+// Config configures the state store
+type Config struct {
+	DB      kv.DB
+	Pruning PruningOptions
+	// BeforeCommit is called with the version about to be committed; an
+	// error aborts the commit. The node logs the start of the block to its
+	// write-ahead log here.
+	BeforeCommit func(version int64) error
+	Logger       *zap.Logger
+}
+
+// Store is the authenticated application state: a sparse Merkle tree kept
+// in an embedded key-value database. Writes are buffered until Commit,
+// which stores them as a new version; one version is committed per block,
+// so versions are block heights. Old versions stay readable and provable
+// until they are pruned.
+type Store struct {
+	db           kv.DB
+	pruning      PruningOptions
+	beforeCommit func(version int64) error
+	logger       *zap.Logger
+
+	mu      sync.RWMutex
+	last    CommitID
+	pending map[string][]byte // nil deletes the key
+	pinned  map[int64]int     // versions being exported, not to be pruned
+	modules []*registeredModule
+}
+
+// NewStore opens the state store and loads its latest version
+func NewStore(cfg *Config) (*Store, error) {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	s := &Store{
+		db:           cfg.DB,
+		pruning:      cfg.Pruning,
+		beforeCommit: cfg.BeforeCommit,
+		logger:       logger,
+		last:         CommitID{Hash: types.EmptySparseRoot},
+		pending:      make(map[string][]byte),
+		pinned:       make(map[int64]int),
+	}
+
+	data, err := cfg.DB.Get(latestKey)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		version, n := binary.Varint(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("%w: latest version record", errCorruptNode)
+		}
+		root, err := s.root(version)
+		if err != nil {
+			return nil, err
+		}
+		s.last = CommitID{Version: version, Hash: root}
+	}
+	logger.Info("Loaded state",
+		zap.Int64("version", s.last.Version),
+		zap.String("hash", s.last.Hash.String()),
+		zap.String("pruning", s.pruning.Strategy),
+	)
+	return s, nil
+}
+
+// Get returns the value under key in the working state, or nil
+func (s *Store) Get(key []byte) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if value, ok := s.pending[string(key)]; ok {
+		return append([]byte(nil), value...), nil
+	}
+	return get(s.db, s.last.Hash, key)
+}
+
+// Has reports whether key has a value in the working state
+func (s *Store) Has(key []byte) (bool, error) {
+	value, err := s.Get(key)
+	return value != nil, err
+}
+
+// Set sets the value under key in the working state
+func (s *Store) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return ErrEmptyKey
+	}
+	if len(value) == 0 {
+		return ErrEmptyValue
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[string(key)] = append([]byte{}, value...)
+	return nil
+}
+
+// Delete removes key from the working state
+func (s *Store) Delete(key []byte) error {
+	if len(key) == 0 {
+		return ErrEmptyKey
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[string(key)] = nil
+	return nil
+}
+
+// Commit stores the working state and the state of the registered modules
+// as the next version, and prunes old versions when the pruning interval
+// is reached
+func (s *Store) Commit() (CommitID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed, err := s.exportModules()
+	if err != nil {
+		return CommitID{}, err
+	}
+	for key, value := range s.pending {
+		if _, ok := changed[key]; !ok {
+			changed[key] = value
+		}
+	}
+	changes := make([]change, 0, len(changed))
+	for key, value := range changed {
+		changes = append(changes, change{path: types.SparsePath([]byte(key)), key: []byte(key), value: value})
+	}
+	sort.Slice(changes, func(i, j int) bool { return bytes.Compare(changes[i].path, changes[j].path) < 0 })
+
+	version := s.last.Version + 1
+	var prune []int64
+	if s.pruning.shouldPrune(version) {
+		var err error
+		if prune, err = s.prunable(s.pruning.pruneBelow(version)); err != nil {
+			return CommitID{}, err
+		}
+	}
+
+	if s.beforeCommit != nil {
+		if err := s.beforeCommit(version); err != nil {
+			return CommitID{}, fmt.Errorf("failed to commit state version %d: %w", version, err)
+		}
+	}
+
+	var root []byte
+	err = s.db.Update(func(tx kv.Tx) error {
+		w := nodeWriter{tx: tx}
+		var err error
+		if root, err = w.update(s.last.Hash, 0, changes); err != nil {
+			return err
+		}
+		if err := tx.Set(versionKey(version), root); err != nil {
+			return err
+		}
+		if err := w.ref(root); err != nil {
+			return err
+		}
+		if err := tx.Set(latestKey, binary.AppendVarint(nil, version)); err != nil {
+			return err
+		}
+		for _, v := range prune {
+			if err := deleteVersion(w, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return CommitID{}, fmt.Errorf("failed to commit state version %d: %w", version, err)
+	}
+
+	s.last = CommitID{Version: version, Hash: root}
+	s.pending = make(map[string][]byte)
+	s.modulesCommitted()
+	if len(prune) > 0 {
+		s.logger.Debug("Pruned state versions",
+			zap.Int64("from", prune[0]),
+			zap.Int64("to", prune[len(prune)-1]),
+			zap.Int("count", len(prune)),
+		)
+	}
+	return s.last, nil
+}
+
+// prunable returns the stored versions below keepFrom that are not pinned
+func (s *Store) prunable(keepFrom int64) ([]int64, error) {
+	var versions []int64
+	err := s.db.Iterate(versionPrefix, func(key, _ []byte) error {
+		version := int64(binary.BigEndian.Uint64(key[len(versionPrefix):]))
+		if version >= keepFrom {
+			return errStopIteration
+		}
+		if s.pinned[version] == 0 {
+			versions = append(versions, version)
+		}
+		return nil
+	})
+	if errors.Is(err, errStopIteration) {
+		err = nil
+	}
+	return versions, err
+}
+
+// LastCommitID returns the latest committed version
+func (s *Store) LastCommitID() CommitID {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.last
+}
+
+// AppHash returns the root hash of the latest committed version
+func (s *Store) AppHash() []byte {
+	return s.LastCommitID().Hash
+}
+
+// GetVersioned returns the value under key as of a committed version
+func (s *Store) GetVersioned(key []byte, version int64) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	root, err := s.root(version)
+	if err != nil {
+		return nil, err
+	}
+	return get(s.db, root, key)
+}
+
+// ProveVersioned returns the value under key as of a committed version with
+// a proof against that version's hash. Keys with no value get a proof of
+// absence.
+func (s *Store) ProveVersioned(key []byte, version int64) (*types.ValueProof, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	root, err := s.root(version)
+	if err != nil {
+		return nil, err
+	}
+	value, proof, err := prove(s.db, root, key)
+	if err != nil {
+		return nil, err
+	}
+	return &types.ValueProof{Height: version, Key: key, Value: value, Proof: proof}, nil
+}
+
+// ProveState proves the value under key in the latest committed version
+func (s *Store) ProveState(key []byte) (*types.ValueProof, error) {
+	return s.ProveVersioned(key, s.LastCommitID().Version)
+}
+
+// Versions returns the committed versions still stored, oldest first
+func (s *Store) Versions() ([]int64, error) {
+	var versions []int64
+	err := s.db.Iterate(versionPrefix, func(key, _ []byte) error {
+		versions = append(versions, int64(binary.BigEndian.Uint64(key[len(versionPrefix):])))
+		return nil
+	})
+	return versions, err
+}
+
+// Prune deletes the versions older than the keepRecent most recent ones
+// now, instead of at the next pruning interval, and returns how many were
+// deleted. keepRecent 0 keeps as many as the pruning options do.
+func (s *Store) Prune(keepRecent int64) (int, error) {
+	if keepRecent == 0 {
+		keepRecent = s.pruning.KeepRecent
+	}
+	if keepRecent < 1 {
+		return 0, fmt.Errorf("%w: strategy %q keeps every version; give the number of versions to keep", ErrInvalidPruning, s.pruning.Strategy)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prune, err := s.prunable(s.last.Version - keepRecent + 1)
+	if err != nil || len(prune) == 0 {
+		return 0, err
+	}
+	err = s.db.Update(func(tx kv.Tx) error {
+		w := nodeWriter{tx: tx}
+		for _, v := range prune {
+			if err := deleteVersion(w, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	s.logger.Info("Pruned state versions",
+		zap.Int64("from", prune[0]),
+		zap.Int64("to", prune[len(prune)-1]),
+		zap.Int("count", len(prune)),
+	)
+	return len(prune), nil
+}
+
+// Rollback makes version the latest version again, deleting the versions
+// committed after it, discarding uncommitted changes and reloading the
+// registered modules. It recovers the state of a block that was committed
+// but not completely applied.
+func (s *Store) Rollback(version int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if version >= s.last.Version {
+		return nil
+	}
+	root, err := s.root(version)
+	if err != nil {
+		return err
+	}
+	for v := range s.pinned {
+		if v > version && s.pinned[v] > 0 {
+			return fmt.Errorf("%w: %d", ErrVersionInUse, v)
+		}
+	}
+
+	err = s.db.Update(func(tx kv.Tx) error {
+		w := nodeWriter{tx: tx}
+		for v := s.last.Version; v > version; v-- {
+			if err := deleteVersion(w, v); err != nil && !errors.Is(err, ErrVersionNotFound) {
+				return err
+			}
+		}
+		if version == 0 {
+			return tx.Delete(latestKey)
+		}
+		return tx.Set(latestKey, binary.AppendVarint(nil, version))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to roll back state to version %d: %w", version, err)
+	}
+	s.logger.Warn("Rolled back state",
+		zap.Int64("from", s.last.Version),
+		zap.Int64("to", version),
+	)
+	s.last = CommitID{Version: version, Hash: root}
+	s.pending = make(map[string][]byte)
+	return s.reloadModules()
+}
+
+// DeleteVersion deletes a committed version other than the latest
+func (s *Store) DeleteVersion(version int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if version == s.last.Version || s.pinned[version] > 0 {
+		return fmt.Errorf("%w: %d", ErrVersionInUse, version)
+	}
+	return s.db.Update(func(tx kv.Tx) error {
+		return deleteVersion(nodeWriter{tx: tx}, version)
+	})
+}
+
+func deleteVersion(w nodeWriter, version int64) error {
+	root, err := w.tx.Get(versionKey(version))
+	if err != nil {
+		return err
+	}
+	if root == nil {
+		return fmt.Errorf("%w: %d", ErrVersionNotFound, version)
+	}
+	if err := w.tx.Delete(versionKey(version)); err != nil {
+		return err
+	}
+	return w.unref(root)
+}
+
+// Close closes the underlying database. Uncommitted changes are lost.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// root returns the root hash of a committed version
+func (s *Store) root(version int64) ([]byte, error) {
+	if version == 0 {
+		return types.EmptySparseRoot, nil
+	}
+	root, err := s.db.Get(versionKey(version))
+	if err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return nil, fmt.Errorf("%w: %d", ErrVersionNotFound, version)
+	}
+	return root, nil
+}
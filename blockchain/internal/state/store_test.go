@@ -0,0 +1,241 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/vindexchain/blockchain/internal/kv"
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+func newTestStore(t *testing.T, db kv.DB, pruning PruningOptions) *Store {
+	t.Helper()
+	s, err := NewStore(&Config{DB: db, Pruning: pruning})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func commit(t *testing.T, s *Store, sets map[string]string) CommitID {
+	t.Helper()
+	for key, value := range sets {
+		var err error
+		if value == "" {
+			err = s.Delete([]byte(key))
+		} else {
+			err = s.Set([]byte(key), []byte(value))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	id, err := s.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestStoreProofs(t *testing.T) {
+	s := newTestStore(t, kv.NewMemDB(), PruningOptions{Strategy: PruningNothing})
+	v1 := commit(t, s, map[string]string{"a": "1", "b": "2", "c": "3"})
+	v2 := commit(t, s, map[string]string{"a": "10", "c": ""})
+
+	tests := []struct {
+		key     string
+		version CommitID
+		want    string // empty proves absence
+	}{
+		{"a", v1, "1"},
+		{"b", v1, "2"},
+		{"c", v1, "3"},
+		{"d", v1, ""},
+		{"a", v2, "10"},
+		{"b", v2, "2"},
+		{"c", v2, ""},
+		{"", v2, ""},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s@%d", tt.key, tt.version.Version), func(t *testing.T) {
+			proof, err := s.ProveVersioned([]byte(tt.key), tt.version.Version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(proof.Value) != tt.want {
+				t.Fatalf("value = %q; want %q", proof.Value, tt.want)
+			}
+			if err := proof.Verify(tt.version.Hash); err != nil {
+				t.Fatalf("proof does not verify: %v", err)
+			}
+
+			other := v1
+			if tt.version.Version == v1.Version {
+				other = v2
+			}
+			if err := proof.Verify(other.Hash); !errors.Is(err, types.ErrInvalidProof) {
+				t.Errorf("proof verifies against another version: %v", err)
+			}
+			forged := *proof
+			forged.Value = []byte("forged")
+			if err := forged.Verify(tt.version.Hash); !errors.Is(err, types.ErrInvalidProof) {
+				t.Errorf("proof verifies a forged value: %v", err)
+			}
+		})
+	}
+}
+
+func TestStoreHashIsOrderIndependent(t *testing.T) {
+	a := newTestStore(t, kv.NewMemDB(), PruningOptions{Strategy: PruningNothing})
+	commit(t, a, map[string]string{"x": "1"})
+	ha := commit(t, a, map[string]string{"y": "2", "z": "3"})
+
+	b := newTestStore(t, kv.NewMemDB(), PruningOptions{Strategy: PruningNothing})
+	commit(t, b, map[string]string{"z": "3", "x": "1"})
+	hb := commit(t, b, map[string]string{"y": "2"})
+
+	if ha.Hash.String() != hb.Hash.String() {
+		t.Errorf("same state, different hashes: %s and %s", ha.Hash, hb.Hash)
+	}
+
+	empty := newTestStore(t, kv.NewMemDB(), PruningOptions{Strategy: PruningNothing})
+	commit(t, empty, map[string]string{"x": "1"})
+	if id := commit(t, empty, map[string]string{"x": ""}); !isEmpty(id.Hash) {
+		t.Errorf("deleting every key leaves root %s", id.Hash)
+	}
+}
+
+func TestStorePruning(t *testing.T) {
+	tests := []struct {
+		name    string
+		pruning PruningOptions
+		commits int64
+		want    []int64 // versions left
+	}{
+		{"nothing", PruningOptions{Strategy: PruningNothing}, 5, []int64{1, 2, 3, 4, 5}},
+		{"keep 2 every 2", PruningOptions{Strategy: PruningCustom, KeepRecent: 2, Interval: 2}, 5, []int64{3, 4, 5}},
+		{"keep 3 every 1", PruningOptions{Strategy: PruningCustom, KeepRecent: 3, Interval: 1}, 6, []int64{4, 5, 6}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := kv.NewMemDB()
+			s := newTestStore(t, db, tt.pruning)
+			for v := int64(1); v <= tt.commits; v++ {
+				commit(t, s, map[string]string{fmt.Sprint("key", v%3): fmt.Sprint(v), "fixed": "value"})
+			}
+			versions, err := s.Versions()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(versions) != fmt.Sprint(tt.want) {
+				t.Fatalf("versions = %v; want %v", versions, tt.want)
+			}
+			if _, err := s.GetVersioned([]byte("fixed"), tt.want[0]-1); tt.want[0] > 1 && !errors.Is(err, ErrVersionNotFound) {
+				t.Errorf("pruned version is readable: %v", err)
+			}
+			for _, v := range tt.want {
+				if value, err := s.GetVersioned([]byte("fixed"), v); err != nil || string(value) != "value" {
+					t.Errorf("version %d: %q, %v", v, value, err)
+				}
+			}
+		})
+	}
+}
+
+func TestStorePruneLeavesNoOrphans(t *testing.T) {
+	db := kv.NewMemDB()
+	s := newTestStore(t, db, PruningOptions{Strategy: PruningNothing})
+	for v := 1; v <= 20; v++ {
+		commit(t, s, map[string]string{fmt.Sprint("k", v%7): fmt.Sprint(v), fmt.Sprint("d", v-1): ""})
+	}
+	if _, err := s.Prune(1); err != nil {
+		t.Fatal(err)
+	}
+
+	reachable, err := countNodes(db, s.AppHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := 0
+	if err := db.Iterate(nodePrefix, func(_, _ []byte) error { stored++; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if stored != reachable {
+		t.Errorf("%d nodes stored after pruning, %d reachable from the latest root", stored, reachable)
+	}
+	if _, err := s.Prune(0); !errors.Is(err, ErrInvalidPruning) {
+		t.Errorf("Prune(0) without a keep-recent setting: %v", err)
+	}
+}
+
+// countNodes returns the number of nodes reachable from a root
+func countNodes(g getter, hash []byte) (int, error) {
+	if isEmpty(hash) {
+		return 0, nil
+	}
+	n, err := loadNode(g, hash)
+	if err != nil || n.isLeaf() {
+		return 1, err
+	}
+	left, err := countNodes(g, n.left)
+	if err != nil {
+		return 0, err
+	}
+	right, err := countNodes(g, n.right)
+	return 1 + left + right, err
+}
+
+func TestStoreRollbackAndReopen(t *testing.T) {
+	db := kv.NewMemDB()
+	s := newTestStore(t, db, PruningOptions{Strategy: PruningNothing})
+	v1 := commit(t, s, map[string]string{"a": "1"})
+	commit(t, s, map[string]string{"a": "2", "b": "2"})
+	if err := s.Rollback(v1.Version); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.LastCommitID(); got.Version != 1 || got.Hash.String() != v1.Hash.String() {
+		t.Fatalf("after rollback at %+v; want %+v", got, v1)
+	}
+	if value, _ := s.Get([]byte("b")); value != nil {
+		t.Errorf("rolled back key still set: %q", value)
+	}
+
+	reopened := newTestStore(t, db, PruningOptions{Strategy: PruningNothing})
+	if got := reopened.LastCommitID(); got.Version != 1 || got.Hash.String() != v1.Hash.String() {
+		t.Errorf("reopened at %+v; want %+v", got, v1)
+	}
+	if value, _ := reopened.Get([]byte("a")); string(value) != "1" {
+		t.Errorf("reopened a = %q; want 1", value)
+	}
+}
+
+func TestNewPruningOptions(t *testing.T) {
+	tests := []struct {
+		strategy   string
+		keepRecent int64
+		interval   int64
+		want       PruningOptions
+		wantErr    bool
+	}{
+		{strategy: "", want: PruningOptions{Strategy: PruningDefault, KeepRecent: 362880, Interval: 10}},
+		{strategy: PruningNothing, want: PruningOptions{Strategy: PruningNothing}},
+		{strategy: PruningEverything, want: PruningOptions{Strategy: PruningEverything, KeepRecent: 2, Interval: 10}},
+		{strategy: PruningCustom, keepRecent: 100, interval: 5, want: PruningOptions{Strategy: PruningCustom, KeepRecent: 100, Interval: 5}},
+		{strategy: PruningCustom, keepRecent: 0, interval: 5, wantErr: true},
+		{strategy: PruningCustom, keepRecent: 100, interval: 0, wantErr: true},
+		{strategy: "sometimes", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := NewPruningOptions(tt.strategy, tt.keepRecent, tt.interval)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidPruning) {
+				t.Errorf("NewPruningOptions(%q, %d, %d) = %+v, %v; want ErrInvalidPruning", tt.strategy, tt.keepRecent, tt.interval, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("NewPruningOptions(%q, %d, %d) = %+v, %v; want %+v", tt.strategy, tt.keepRecent, tt.interval, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package state
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+// change sets or, with a nil value, deletes the key at path
+type change struct {
+	path  []byte
+	key   []byte
+	value []byte
+}
+
+// update applies changes, sorted by path and all below the subtree at
+// depth, to the subtree with root hash, and returns the new root. Only the
+// nodes of the final tree are stored, however many changes touch them.
+func (w nodeWriter) update(hash []byte, depth int, changes []change) ([]byte, error) {
+	if len(changes) == 0 {
+		return hash, nil
+	}
+	if isEmpty(hash) {
+		return w.build(depth, changes)
+	}
+	n, err := loadNode(w.tx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.isLeaf() {
+		// Rebuild the subtree from the leaf and the changes, unless a
+		// change replaces the leaf
+		i := searchPath(changes, n.path)
+		if i < len(changes) && bytes.Equal(changes[i].path, n.path) {
+			return w.build(depth, changes)
+		}
+		merged := make([]change, 0, len(changes)+1)
+		merged = append(merged, changes[:i]...)
+		merged = append(merged, change{path: n.path, key: n.key, value: n.value})
+		merged = append(merged, changes[i:]...)
+		return w.build(depth, merged)
+	}
+
+	split := splitChanges(changes, depth)
+	left, err := w.update(n.left, depth+1, changes[:split])
+	if err != nil {
+		return nil, err
+	}
+	right, err := w.update(n.right, depth+1, changes[split:])
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(left, n.left) && bytes.Equal(right, n.right) {
+		return hash, nil
+	}
+	return w.join(left, right)
+}
+
+// build stores a subtree holding the keys set by changes
+func (w nodeWriter) build(depth int, changes []change) ([]byte, error) {
+	sets := changes[:0:0]
+	for _, c := range changes {
+		if c.value != nil {
+			sets = append(sets, c)
+		}
+	}
+	return w.buildSets(depth, sets)
+}
+
+func (w nodeWriter) buildSets(depth int, sets []change) ([]byte, error) {
+	switch len(sets) {
+	case 0:
+		return types.EmptySparseRoot, nil
+	case 1:
+		return w.put(newLeaf(sets[0].key, sets[0].value))
+	}
+	split := splitChanges(sets, depth)
+	left, err := w.buildSets(depth+1, sets[:split])
+	if err != nil {
+		return nil, err
+	}
+	right, err := w.buildSets(depth+1, sets[split:])
+	if err != nil {
+		return nil, err
+	}
+	return w.join(left, right)
+}
+
+// join stores the parent of two subtrees. A subtree left with a single leaf
+// collapses to that leaf, so the same keys always give the same root.
+func (w nodeWriter) join(left, right []byte) ([]byte, error) {
+	switch {
+	case isEmpty(left) && isEmpty(right):
+		return types.EmptySparseRoot, nil
+	case isEmpty(left) || isEmpty(right):
+		only := left
+		if isEmpty(left) {
+			only = right
+		}
+		n, err := loadNode(w.tx, only)
+		if err != nil {
+			return nil, err
+		}
+		if n.isLeaf() {
+			return only, nil
+		}
+	}
+	return w.put(newInner(left, right))
+}
+
+// splitChanges returns the index of the first change whose path goes right
+// at depth
+func splitChanges(changes []change, depth int) int {
+	for i, c := range changes {
+		if types.SparseBit(c.path, depth) == 1 {
+			return i
+		}
+	}
+	return len(changes)
+}
+
+func searchPath(changes []change, path []byte) int {
+	lo, hi := 0, len(changes)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if bytes.Compare(changes[mid].path, path) < 0 {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
+
+// find walks from root towards the key's path and returns the leaf where
+// the path ends, or nil for an empty subtree, along with the siblings
+// passed on the way
+func find(g getter, root, key []byte) (*node, []types.HexBytes, error) {
+	path := types.SparsePath(key)
+	var sides []types.HexBytes
+	hash := root
+	for depth := 0; !isEmpty(hash); depth++ {
+		n, err := loadNode(g, hash)
+		if err != nil {
+			return nil, nil, err
+		}
+		if n.isLeaf() {
+			return n, sides, nil
+		}
+		if types.SparseBit(path, depth) == 0 {
+			sides = append(sides, n.right)
+			hash = n.left
+		} else {
+			sides = append(sides, n.left)
+			hash = n.right
+		}
+	}
+	return nil, sides, nil
+}
+
+// get returns the value under key in the tree with the given root
+func get(g getter, root, key []byte) ([]byte, error) {
+	leaf, _, err := find(g, root, key)
+	if err != nil || leaf == nil || !bytes.Equal(leaf.key, key) {
+		return nil, err
+	}
+	return leaf.value, nil
+}
+
+// prove returns the value under key and a proof of it, or of its absence
+func prove(g getter, root, key []byte) ([]byte, *types.SparseMerkleProof, error) {
+	leaf, sides, err := find(g, root, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	proof := &types.SparseMerkleProof{SideNodes: sides}
+	if leaf == nil {
+		return nil, proof, nil
+	}
+	if bytes.Equal(leaf.key, key) {
+		return leaf.value, proof, nil
+	}
+	valueHash := sha256.Sum256(leaf.value)
+	proof.OtherLeafPath = leaf.path
+	proof.OtherLeafValueHash = valueHash[:]
+	return nil, proof, nil
+}
+
+// walk calls fn for every leaf of the tree in path order
+func walk(g getter, hash []byte, fn func(n *node) error) error {
+	if isEmpty(hash) {
+		return nil
+	}
+	n, err := loadNode(g, hash)
+	if err != nil {
+		return err
+	}
+	if n.isLeaf() {
+		return fn(n)
+	}
+	if err := walk(g, n.left, fn); err != nil {
+		return err
+	}
+	return walk(g, n.right, fn)
+}
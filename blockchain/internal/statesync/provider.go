@@ -0,0 +1,155 @@
+package statesync
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/p2p"
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+// State is the consensus state after a block, as needed to continue the
+// chain from a restored snapshot
+type State struct {
+	ChainID         string              `json:"chain_id"`
+	LastBlockHeight int64               `json:"last_block_height"`
+	LastBlockHash   types.HexBytes      `json:"last_block_hash"`
+	LastBlockTime   time.Time           `json:"last_block_time"`
+	AppHash         types.HexBytes      `json:"app_hash"`
+	LastValidators  *types.ValidatorSet `json:"last_validators"` // signed LastBlockHeight
+	Validators      *types.ValidatorSet `json:"validators"`      // sign the next block
+}
+
+// StateProvider returns verified consensus state for a snapshot height
+type StateProvider interface {
+	// State returns the state after height and the commit for height. The
+	// app hash comes from the verified header at height+1.
+	State(height int64) (State, *types.Commit, error)
+}
+
+// peerStateProvider fetches light blocks from peers and verifies them one
+// height at a time, starting from the operator's trusted header
+type peerStateProvider struct {
+	reactor     *Reactor
+	chainID     string
+	trustHeight int64
+	trustHash   types.HexBytes
+	logger      *zap.Logger
+
+	mu      sync.Mutex
+	trusted *types.LightBlock // highest verified light block
+}
+
+// NewPeerStateProvider creates a state provider that verifies every header
+// between the trusted height and the snapshot height
+func NewPeerStateProvider(reactor *Reactor, chainID string, trustHeight int64, trustHash types.HexBytes, logger *zap.Logger) StateProvider {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &peerStateProvider{
+		reactor:     reactor,
+		chainID:     chainID,
+		trustHeight: trustHeight,
+		trustHash:   trustHash,
+		logger:      logger,
+	}
+}
+
+// State implements StateProvider
+func (p *peerStateProvider) State(height int64) (State, *types.Commit, error) {
+	if height < p.trustHeight {
+		return State{}, nil, fmt.Errorf("snapshot height %d is below the trust height %d", height, p.trustHeight)
+	}
+	last, err := p.verifyTo(height)
+	if err != nil {
+		return State{}, nil, err
+	}
+	next, err := p.verifyTo(height + 1)
+	if err != nil {
+		return State{}, nil, err
+	}
+	return State{
+		ChainID:         p.chainID,
+		LastBlockHeight: height,
+		LastBlockHash:   last.Hash(),
+		LastBlockTime:   last.Header.Time,
+		AppHash:         next.Header.AppHash,
+		LastValidators:  last.ValidatorSet,
+		Validators:      next.ValidatorSet,
+	}, last.Commit, nil
+}
+
+// verifyTo verifies light blocks up to height and returns the one at height
+func (p *peerStateProvider) verifyTo(height int64) (*types.LightBlock, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Lower heights are verified again from the trust anchor
+	if p.trusted == nil || height < p.trusted.Height() {
+		anchor, err := p.fetchAnchor()
+		if err != nil {
+			return nil, err
+		}
+		p.trusted = anchor
+	}
+
+	for p.trusted.Height() < height {
+		next := p.trusted.Height() + 1
+		lb, peerID, err := p.reactor.fetchLightBlock(next)
+		if err != nil {
+			return nil, err
+		}
+		if err := verifyAdjacent(p.chainID, p.trusted, lb); err != nil {
+			if peer := p.reactor.Node.Peer(peerID); peer != nil {
+				p.reactor.Node.ReportPeer(peer, p2p.BehaviourInvalidBlock)
+			}
+			return nil, fmt.Errorf("light block %d from %s: %w", next, peerID, err)
+		}
+		p.trusted = lb
+		if next%1000 == 0 {
+			p.logger.Info("Verified headers", zap.Int64("height", next), zap.Int64("target", height))
+		}
+	}
+	return p.trusted, nil
+}
+
+// fetchAnchor fetches the light block at the trust height and checks it
+// against the trusted hash
+func (p *peerStateProvider) fetchAnchor() (*types.LightBlock, error) {
+	lb, _, err := p.reactor.fetchLightBlock(p.trustHeight)
+	if err != nil {
+		return nil, err
+	}
+	if err := lb.ValidateBasic(p.chainID); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(lb.Hash(), p.trustHash) {
+		return nil, fmt.Errorf("header at trust height %d has hash %s, expected %s", p.trustHeight, lb.Hash(), p.trustHash)
+	}
+	if err := lb.ValidatorSet.VerifyCommitLight(p.chainID, lb.Hash(), lb.Height(), lb.Commit); err != nil {
+		return nil, err
+	}
+	return lb, nil
+}
+
+// verifyAdjacent checks that untrusted directly follows trusted and is
+// signed by the validator set trusted named as the next one
+func verifyAdjacent(chainID string, trusted, untrusted *types.LightBlock) error {
+	if err := untrusted.ValidateBasic(chainID); err != nil {
+		return err
+	}
+	if untrusted.Height() != trusted.Height()+1 {
+		return fmt.Errorf("%w: expected height %d, got %d", types.ErrInvalidBlock, trusted.Height()+1, untrusted.Height())
+	}
+	if !bytes.Equal(untrusted.Header.LastBlockHash, trusted.Hash()) {
+		return fmt.Errorf("%w: header does not link to the trusted header", types.ErrInvalidBlock)
+	}
+	if !bytes.Equal(untrusted.Header.ValidatorsHash, trusted.Header.NextValidatorsHash) {
+		return fmt.Errorf("%w: validator set differs from the one the trusted header named", types.ErrInvalidBlock)
+	}
+	return untrusted.ValidatorSet.VerifyCommitLight(chainID, untrusted.Hash(), untrusted.Height(), untrusted.Commit)
+}
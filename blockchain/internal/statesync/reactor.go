@@ -0,0 +1,440 @@
+package statesync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/p2p"
+	"github.com/vindexchain/blockchain/internal/snapshots"
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+// State sync channels
+const (
+	SnapshotChannel   = byte(0x60)
+	ChunkChannel      = byte(0x61)
+	LightBlockChannel = byte(0x62)
+)
+
+const (
+	defaultDiscoveryTime       = 15 * time.Second
+	defaultChunkFetchers       = 4
+	defaultChunkRequestTimeout = 15 * time.Second
+	lightBlockRequestTimeout   = 10 * time.Second
+	maxAdvertisedSnapshots     = 10
+)
+
+// State sync message types
+const (
+	msgSnapshotsRequest   = "snapshots_request"
+	msgSnapshotsResponse  = "snapshots_response"
+	msgChunkRequest       = "chunk_request"
+	msgChunkResponse      = "chunk_response"
+	msgLightBlockRequest  = "light_block_request"
+	msgLightBlockResponse = "light_block_response"
+)
+
+var (
+	// ErrNoSnapshots is returned when no peer offers a usable snapshot
+	ErrNoSnapshots = errors.New("no suitable snapshots found")
+	// ErrAppHashMismatch is returned when a restored state does not match the verified header
+	ErrAppHashMismatch = errors.New("restored app hash does not match the verified header")
+	// ErrNoPeers is returned when no peer can serve a request
+	ErrNoPeers = errors.New("no peers available")
+)
+
+type message struct {
+	Type       string                `json:"type"`
+	Snapshots  []*snapshots.Snapshot `json:"snapshots,omitempty"`
+	Height     int64                 `json:"height,omitempty"`
+	Format     uint32                `json:"format,omitempty"`
+	Index      uint32                `json:"index,omitempty"`
+	Chunk      []byte                `json:"chunk,omitempty"`
+	Missing    bool                  `json:"missing,omitempty"`
+	LightBlock *types.LightBlock     `json:"light_block,omitempty"`
+}
+
+// Chain is the block store and consensus state that state sync serves light
+// blocks from and bootstraps after a restore
+type Chain interface {
+	// Height returns the height of the last applied block
+	Height() int64
+	// LoadBlock returns a stored block and the commit that finalized it
+	LoadBlock(height int64) (*types.Block, *types.Commit, error)
+	// LoadValidators returns the validator set that signed height
+	LoadValidators(height int64) (*types.ValidatorSet, error)
+	// Bootstrap sets the chain state after a snapshot restore, so block
+	// sync and consensus continue from state.LastBlockHeight+1
+	Bootstrap(state State, commit *types.Commit) error
+}
+
+// App is the application whose state is snapshotted and restored
+type App interface {
+	snapshots.Snapshotter
+	// AppHash returns the hash of the current application state
+	AppHash() []byte
+}
+
+// Config configures the state sync reactor
+type Config struct {
+	ChainID string
+	// Enabled restores the newest verifiable snapshot offered by peers when
+	// the node starts without any state
+	Enabled bool
+	// TrustHeight and TrustHash anchor header verification; they must come
+	// from a source the operator trusts
+	TrustHeight   int64
+	TrustHash     types.HexBytes
+	StateProvider StateProvider // defaults to verifying headers from peers, starting at the trust height
+
+	DiscoveryTime       time.Duration
+	ChunkFetchers       int
+	ChunkRequestTimeout time.Duration
+
+	Snapshots *snapshots.Store // snapshots served to peers
+	App       App
+	Chain     Chain
+	// OnSynced is called once with the height the node continues from,
+	// after a restore or straight away when state sync is not needed
+	OnSynced func(height int64)
+	Logger   *zap.Logger
+}
+
+// Reactor serves snapshots, chunks and light blocks to peers and, when
+// enabled, restores the node's state from a snapshot on startup
+type Reactor struct {
+	p2p.BaseReactor
+
+	config *Config
+	logger *zap.Logger
+	pool   *snapshotPool
+
+	mu          sync.Mutex
+	syncing     bool
+	chunkWaits  map[chunkKey]chan chunkResponse
+	lightWaits  map[int64]chan lightBlockResponse
+	restoreDone bool
+
+	quit chan struct{}
+}
+
+type chunkKey struct {
+	height int64
+	format uint32
+	index  uint32
+	peer   p2p.ID
+}
+
+type chunkResponse struct {
+	chunk   []byte
+	missing bool
+}
+
+type lightBlockResponse struct {
+	peer       p2p.ID
+	lightBlock *types.LightBlock
+}
+
+// NewReactor creates a state sync reactor
+func NewReactor(cfg *Config) *Reactor {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if cfg.DiscoveryTime == 0 {
+		cfg.DiscoveryTime = defaultDiscoveryTime
+	}
+	if cfg.ChunkFetchers == 0 {
+		cfg.ChunkFetchers = defaultChunkFetchers
+	}
+	if cfg.ChunkRequestTimeout == 0 {
+		cfg.ChunkRequestTimeout = defaultChunkRequestTimeout
+	}
+	r := &Reactor{
+		config:     cfg,
+		logger:     logger,
+		pool:       newSnapshotPool(),
+		chunkWaits: make(map[chunkKey]chan chunkResponse),
+		lightWaits: make(map[int64]chan lightBlockResponse),
+		quit:       make(chan struct{}),
+	}
+	if cfg.StateProvider == nil {
+		cfg.StateProvider = NewPeerStateProvider(r, cfg.ChainID, cfg.TrustHeight, cfg.TrustHash, logger)
+	}
+	return r
+}
+
+// Channels returns the state sync channels
+func (r *Reactor) Channels() []byte {
+	return []byte{SnapshotChannel, ChunkChannel, LightBlockChannel}
+}
+
+// Start restores a snapshot in the background when the node has no state
+// yet, and otherwise hands over right away
+func (r *Reactor) Start() error {
+	height := r.config.Chain.Height()
+	if !r.config.Enabled || height > 0 {
+		if r.config.Enabled {
+			r.logger.Info("Skipping state sync; the node already has state", zap.Int64("height", height))
+		}
+		r.finish(height)
+		return nil
+	}
+	if r.config.TrustHeight <= 0 || len(r.config.TrustHash) == 0 {
+		return fmt.Errorf("state sync needs a trust height and trust hash")
+	}
+
+	r.mu.Lock()
+	r.syncing = true
+	r.mu.Unlock()
+	go r.syncRoutine()
+	return nil
+}
+
+// Stop aborts a running restore
+func (r *Reactor) Stop() {
+	close(r.quit)
+}
+
+// IsSyncing reports whether a snapshot restore is in progress
+func (r *Reactor) IsSyncing() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.syncing
+}
+
+// AddPeer asks new peers for their snapshots while we are looking for one
+func (r *Reactor) AddPeer(peer *p2p.Peer) {
+	if r.IsSyncing() {
+		r.send(peer, SnapshotChannel, message{Type: msgSnapshotsRequest})
+	}
+}
+
+// RemovePeer forgets the snapshots the peer offered
+func (r *Reactor) RemovePeer(peer *p2p.Peer, reason error) {
+	r.pool.removePeer(peer.ID())
+}
+
+// Receive serves and collects snapshots, chunks and light blocks
+func (r *Reactor) Receive(chID byte, peer *p2p.Peer, msgBytes []byte) {
+	var msg message
+	if err := json.Unmarshal(msgBytes, &msg); err != nil {
+		r.Node.ReportPeer(peer, p2p.BehaviourBadMessage)
+		return
+	}
+
+	switch msg.Type {
+	case msgSnapshotsRequest:
+		r.sendSnapshots(peer)
+
+	case msgSnapshotsResponse:
+		if !r.IsSyncing() {
+			return
+		}
+		for _, snapshot := range msg.Snapshots {
+			if snapshot == nil || snapshot.Validate() != nil {
+				r.Node.ReportPeer(peer, p2p.BehaviourBadMessage)
+				continue
+			}
+			if snapshot.Format == snapshots.CurrentFormat {
+				r.pool.add(peer.ID(), snapshot)
+			}
+		}
+
+	case msgChunkRequest:
+		resp := message{Type: msgChunkResponse, Height: msg.Height, Format: msg.Format, Index: msg.Index}
+		chunk, err := r.loadChunk(msg.Height, msg.Format, msg.Index)
+		if err != nil {
+			resp.Missing = true
+		} else {
+			resp.Chunk = chunk
+		}
+		r.send(peer, ChunkChannel, resp)
+
+	case msgChunkResponse:
+		r.deliverChunk(peer.ID(), msg)
+
+	case msgLightBlockRequest:
+		r.send(peer, LightBlockChannel, message{Type: msgLightBlockResponse, Height: msg.Height, LightBlock: r.loadLightBlock(msg.Height)})
+
+	case msgLightBlockResponse:
+		r.deliverLightBlock(peer.ID(), msg)
+
+	default:
+		r.Node.ReportPeer(peer, p2p.BehaviourBadMessage)
+	}
+}
+
+func (r *Reactor) send(peer *p2p.Peer, chID byte, msg message) bool {
+	bz, err := json.Marshal(msg)
+	if err != nil {
+		r.logger.Error("Failed to encode state sync message", zap.Error(err))
+		return false
+	}
+	return peer.Send(chID, bz)
+}
+
+func (r *Reactor) sendSnapshots(peer *p2p.Peer) {
+	if r.config.Snapshots == nil {
+		return
+	}
+	list, err := r.config.Snapshots.List()
+	if err != nil {
+		r.logger.Error("Failed to list snapshots", zap.Error(err))
+		return
+	}
+	if len(list) > maxAdvertisedSnapshots {
+		list = list[:maxAdvertisedSnapshots]
+	}
+	r.send(peer, SnapshotChannel, message{Type: msgSnapshotsResponse, Snapshots: list})
+}
+
+func (r *Reactor) loadChunk(height int64, format uint32, index uint32) ([]byte, error) {
+	if r.config.Snapshots == nil {
+		return nil, snapshots.ErrSnapshotNotFound
+	}
+	return r.config.Snapshots.LoadChunk(height, format, index)
+}
+
+func (r *Reactor) loadLightBlock(height int64) *types.LightBlock {
+	block, commit, err := r.config.Chain.LoadBlock(height)
+	if err != nil || block == nil || commit == nil {
+		return nil
+	}
+	vals, err := r.config.Chain.LoadValidators(height)
+	if err != nil {
+		return nil
+	}
+	header := block.Header
+	return &types.LightBlock{Header: &header, Commit: commit, ValidatorSet: vals}
+}
+
+// requestChunk asks a peer for a chunk and waits for the answer
+func (r *Reactor) requestChunk(peerID p2p.ID, snapshot *snapshots.Snapshot, index uint32) ([]byte, error) {
+	peer := r.Node.Peer(peerID)
+	if peer == nil {
+		return nil, fmt.Errorf("%w: %s disconnected", ErrNoPeers, peerID)
+	}
+
+	key := chunkKey{height: snapshot.Height, format: snapshot.Format, index: index, peer: peerID}
+	wait := make(chan chunkResponse, 1)
+	r.mu.Lock()
+	r.chunkWaits[key] = wait
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.chunkWaits, key)
+		r.mu.Unlock()
+	}()
+
+	if !r.send(peer, ChunkChannel, message{Type: msgChunkRequest, Height: snapshot.Height, Format: snapshot.Format, Index: index}) {
+		return nil, fmt.Errorf("failed to send chunk request to %s", peerID)
+	}
+	timer := time.NewTimer(r.config.ChunkRequestTimeout)
+	defer timer.Stop()
+	select {
+	case resp := <-wait:
+		if resp.missing {
+			return nil, fmt.Errorf("%w: peer %s does not have chunk %d", snapshots.ErrSnapshotNotFound, peerID, index)
+		}
+		return resp.chunk, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("timed out waiting for chunk %d from %s", index, peerID)
+	case <-r.quit:
+		return nil, p2p.ErrNodeStopped
+	}
+}
+
+func (r *Reactor) deliverChunk(peerID p2p.ID, msg message) {
+	key := chunkKey{height: msg.Height, format: msg.Format, index: msg.Index, peer: peerID}
+	r.mu.Lock()
+	wait := r.chunkWaits[key]
+	r.mu.Unlock()
+	if wait == nil {
+		return
+	}
+	select {
+	case wait <- chunkResponse{chunk: msg.Chunk, missing: msg.Missing}:
+	default:
+	}
+}
+
+// fetchLightBlock asks connected peers in turn for the light block at
+// height. The result is not verified.
+func (r *Reactor) fetchLightBlock(height int64) (*types.LightBlock, p2p.ID, error) {
+	peers := r.Node.Peers()
+	if len(peers) == 0 {
+		return nil, "", ErrNoPeers
+	}
+
+	wait := make(chan lightBlockResponse, len(peers))
+	r.mu.Lock()
+	r.lightWaits[height] = wait
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.lightWaits, height)
+		r.mu.Unlock()
+	}()
+
+	for _, peer := range peers {
+		if !r.send(peer, LightBlockChannel, message{Type: msgLightBlockRequest, Height: height}) {
+			continue
+		}
+		timer := time.NewTimer(lightBlockRequestTimeout)
+		for waiting := true; waiting; {
+			select {
+			case resp := <-wait:
+				if resp.peer != peer.ID() {
+					continue // a late answer to an earlier request
+				}
+				waiting = false
+				if resp.lightBlock != nil && resp.lightBlock.Header != nil && resp.lightBlock.Height() == height {
+					timer.Stop()
+					return resp.lightBlock, peer.ID(), nil
+				}
+			case <-timer.C:
+				waiting = false
+			case <-r.quit:
+				timer.Stop()
+				return nil, "", p2p.ErrNodeStopped
+			}
+		}
+		timer.Stop()
+	}
+	return nil, "", fmt.Errorf("no peer served the light block at height %d", height)
+}
+
+func (r *Reactor) deliverLightBlock(peerID p2p.ID, msg message) {
+	r.mu.Lock()
+	wait := r.lightWaits[msg.Height]
+	r.mu.Unlock()
+	if wait == nil {
+		return
+	}
+	select {
+	case wait <- lightBlockResponse{peer: peerID, lightBlock: msg.LightBlock}:
+	default:
+	}
+}
+
+// finish hands over to block sync exactly once
+func (r *Reactor) finish(height int64) {
+	r.mu.Lock()
+	if r.restoreDone {
+		r.mu.Unlock()
+		return
+	}
+	r.restoreDone = true
+	r.syncing = false
+	r.mu.Unlock()
+
+	if r.config.OnSynced != nil {
+		r.config.OnSynced(height)
+	}
+}
@@ -0,0 +1,241 @@
+package statesync
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/p2p"
+	"github.com/vindexchain/blockchain/internal/snapshots"
+)
+
+const maxChunkAttempts = 5
+
+// snapshotPool collects the snapshots peers offer, keyed by snapshot hash
+type snapshotPool struct {
+	mu       sync.Mutex
+	offers   map[string]*snapshotOffer
+	rejected map[string]bool
+}
+
+type snapshotOffer struct {
+	snapshot *snapshots.Snapshot
+	peers    map[p2p.ID]bool
+}
+
+func newSnapshotPool() *snapshotPool {
+	return &snapshotPool{offers: make(map[string]*snapshotOffer), rejected: make(map[string]bool)}
+}
+
+func (p *snapshotPool) add(peerID p2p.ID, snapshot *snapshots.Snapshot) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := snapshot.Hash.String()
+	if p.rejected[key] {
+		return
+	}
+	offer := p.offers[key]
+	if offer == nil {
+		offer = &snapshotOffer{snapshot: snapshot, peers: make(map[p2p.ID]bool)}
+		p.offers[key] = offer
+	}
+	offer.peers[peerID] = true
+}
+
+func (p *snapshotPool) removePeer(peerID p2p.ID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, offer := range p.offers {
+		delete(offer.peers, peerID)
+		if len(offer.peers) == 0 {
+			delete(p.offers, key)
+		}
+	}
+}
+
+// best returns the highest snapshot and the peers offering it. Among
+// snapshots at the same height the one offered by more peers wins.
+func (p *snapshotPool) best() (*snapshots.Snapshot, []p2p.ID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var best *snapshotOffer
+	for _, offer := range p.offers {
+		if best == nil || offer.snapshot.Height > best.snapshot.Height ||
+			(offer.snapshot.Height == best.snapshot.Height && len(offer.peers) > len(best.peers)) {
+			best = offer
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	peers := make([]p2p.ID, 0, len(best.peers))
+	for id := range best.peers {
+		peers = append(peers, id)
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i] < peers[j] })
+	return best.snapshot, peers
+}
+
+func (p *snapshotPool) reject(snapshot *snapshots.Snapshot) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := snapshot.Hash.String()
+	p.rejected[key] = true
+	delete(p.offers, key)
+}
+
+// syncRoutine discovers snapshots and tries them, best first, until one
+// restores to a verified app hash
+func (r *Reactor) syncRoutine() {
+	for {
+		r.logger.Info("Discovering snapshots", zap.Duration("discovery_time", r.config.DiscoveryTime))
+		bz, _ := json.Marshal(message{Type: msgSnapshotsRequest})
+		r.Node.Broadcast(SnapshotChannel, bz)
+		select {
+		case <-time.After(r.config.DiscoveryTime):
+		case <-r.quit:
+			return
+		}
+
+		for {
+			snapshot, peers := r.pool.best()
+			if snapshot == nil {
+				r.logger.Info("No snapshots offered yet; retrying discovery")
+				break
+			}
+
+			state, err := r.syncSnapshot(snapshot, peers)
+			if err == nil {
+				r.logger.Info("Restored state from snapshot",
+					zap.Int64("height", state.LastBlockHeight),
+					zap.String("app_hash", state.AppHash.String()),
+				)
+				r.finish(state.LastBlockHeight)
+				return
+			}
+			if errors.Is(err, p2p.ErrNodeStopped) {
+				return
+			}
+
+			r.logger.Warn("Rejecting snapshot",
+				zap.Int64("height", snapshot.Height),
+				zap.String("hash", snapshot.Hash.String()),
+				zap.Error(err),
+			)
+			r.pool.reject(snapshot)
+			if errors.Is(err, ErrAppHashMismatch) || errors.Is(err, snapshots.ErrChunkHashMismatch) {
+				for _, id := range peers {
+					if peer := r.Node.Peer(id); peer != nil {
+						r.Node.ReportPeer(peer, p2p.BehaviourInvalidBlock)
+					}
+				}
+			}
+		}
+	}
+}
+
+// syncSnapshot verifies the header for the snapshot height, restores the
+// snapshot and checks the resulting app hash against that header
+func (r *Reactor) syncSnapshot(snapshot *snapshots.Snapshot, peers []p2p.ID) (State, error) {
+	r.logger.Info("Restoring snapshot",
+		zap.Int64("height", snapshot.Height),
+		zap.Uint32("chunks", snapshot.Chunks),
+		zap.Int("peers", len(peers)),
+	)
+
+	state, commit, err := r.config.StateProvider.State(snapshot.Height)
+	if err != nil {
+		return State{}, fmt.Errorf("failed to verify the snapshot height: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+	restored := make(chan error, 1)
+	go func() {
+		err := r.config.App.Restore(snapshot.Height, snapshot.Format, pr)
+		pr.CloseWithError(err)
+		restored <- err
+	}()
+
+	err = r.fetchChunks(snapshot, peers, pw)
+	pw.CloseWithError(err)
+	if restoreErr := <-restored; err == nil {
+		err = restoreErr
+	}
+	if err != nil {
+		return State{}, err
+	}
+
+	if appHash := r.config.App.AppHash(); !bytes.Equal(appHash, state.AppHash) {
+		return State{}, fmt.Errorf("%w: got %X, expected %s", ErrAppHashMismatch, appHash, state.AppHash)
+	}
+	if err := r.config.Chain.Bootstrap(state, commit); err != nil {
+		return State{}, fmt.Errorf("failed to bootstrap chain state: %w", err)
+	}
+	return state, nil
+}
+
+// fetchChunks downloads chunks in parallel batches and writes them to w in
+// order
+func (r *Reactor) fetchChunks(snapshot *snapshots.Snapshot, peers []p2p.ID, w io.Writer) error {
+	batch := uint32(r.config.ChunkFetchers)
+	for start := uint32(0); start < snapshot.Chunks; start += batch {
+		end := min(start+batch, snapshot.Chunks)
+		chunks := make([][]byte, end-start)
+		errs := make([]error, end-start)
+
+		var wg sync.WaitGroup
+		for index := start; index < end; index++ {
+			wg.Add(1)
+			go func(index uint32) {
+				defer wg.Done()
+				chunks[index-start], errs[index-start] = r.fetchChunk(snapshot, peers, index)
+			}(index)
+		}
+		wg.Wait()
+
+		for i, chunk := range chunks {
+			if errs[i] != nil {
+				return errs[i]
+			}
+			if _, err := w.Write(chunk); err != nil {
+				return err
+			}
+		}
+		r.logger.Debug("Fetched snapshot chunks", zap.Uint32("done", end), zap.Uint32("total", snapshot.Chunks))
+	}
+	return nil
+}
+
+// fetchChunk asks the offering peers in turn for a chunk until one returns
+// a chunk matching the snapshot metadata
+func (r *Reactor) fetchChunk(snapshot *snapshots.Snapshot, peers []p2p.ID, index uint32) ([]byte, error) {
+	var lastErr error = ErrNoPeers
+	for attempt := 0; attempt < maxChunkAttempts; attempt++ {
+		peerID := peers[(int(index)+attempt)%len(peers)]
+		chunk, err := r.requestChunk(peerID, snapshot, index)
+		if errors.Is(err, p2p.ErrNodeStopped) {
+			return nil, err
+		}
+		if err == nil {
+			if err = snapshot.VerifyChunk(index, chunk); err == nil {
+				return chunk, nil
+			}
+			if peer := r.Node.Peer(peerID); peer != nil {
+				r.Node.ReportPeer(peer, p2p.BehaviourBadMessage)
+			}
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("failed to fetch chunk %d: %w", index, lastErr)
+}
@@ -0,0 +1,192 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrNoCertificate is returned when TLS is enabled without a certificate
+	// and key
+	ErrNoCertificate = errors.New("TLS certificate and key files are required")
+	// ErrNoClientCA is returned when client certificates are verified
+	// without a client CA file
+	ErrNoClientCA = errors.New("client certificate verification requires a client CA file")
+	// ErrInvalidClientCA is returned for a client CA file without PEM
+	// certificates
+	ErrInvalidClientCA = errors.New("client CA file holds no PEM certificates")
+)
+
+// reloadCheckInterval bounds how often the files are checked for changes
+const reloadCheckInterval = time.Second
+
+// Config names the certificate files of a listener
+type Config struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile holds the PEM certificates that sign, or are, the client
+	// certificates accepted for mTLS. Optional unless clients are verified.
+	ClientCAFile string
+	Logger       *zap.Logger
+}
+
+// Reloader serves a certificate and client CA pool from disk and reloads
+// them when the files change, so certificates can be rotated without a
+// restart. A reload that fails keeps the previous certificate.
+type Reloader struct {
+	config *Config
+	logger *zap.Logger
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	modTimes [3]time.Time
+	checked  time.Time
+}
+
+// NewReloader loads the certificate, key and client CA
+func NewReloader(cfg *Config) (*Reloader, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, ErrNoCertificate
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	r := &Reloader{config: cfg, logger: logger}
+	if err := r.load(r.fileModTimes()); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ServerConfig returns a TLS config for listeners. clientAuth selects how
+// client certificates are checked against the client CA; every mode that
+// verifies them requires a client CA file.
+func (r *Reloader) ServerConfig(clientAuth tls.ClientAuthType) (*tls.Config, error) {
+	if clientAuth >= tls.VerifyClientCertIfGiven && r.config.ClientCAFile == "" {
+		return nil, ErrNoClientCA
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, clientCA := r.current()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+				ClientCAs:    clientCA,
+				ClientAuth:   clientAuth,
+				NextProtos:   []string{"h2", "http/1.1"},
+			}, nil
+		},
+	}, nil
+}
+
+// ClientConfig returns a TLS config for dialing peers that present a
+// certificate signed by the client CA, sending our own certificate. Peers
+// are dialed by IP address, so host names are not checked; the caller
+// must authenticate the peer's identity by other means.
+func (r *Reloader) ClientConfig() (*tls.Config, error) {
+	if r.config.ClientCAFile == "" {
+		return nil, ErrNoClientCA
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			return cert, nil
+		},
+		// The chain is verified in VerifyConnection, without host names
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return errors.New("peer sent no certificate")
+			}
+			_, clientCA := r.current()
+			intermediates := x509.NewCertPool()
+			for _, cert := range state.PeerCertificates[1:] {
+				intermediates.AddCert(cert)
+			}
+			_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+				Roots:         clientCA,
+				Intermediates: intermediates,
+				KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			})
+			return err
+		},
+	}, nil
+}
+
+// current returns the certificate and client CA pool, reloading them
+// first if the files changed
+func (r *Reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checked) >= reloadCheckInterval {
+		r.checked = time.Now()
+		if modTimes := r.fileModTimes(); modTimes != r.modTimes {
+			// A failed reload is retried when the files change again, such
+			// as when the key is written after the certificate
+			err := r.loadLocked(modTimes)
+			r.modTimes = modTimes
+			if err != nil {
+				r.logger.Error("Failed to reload TLS certificate; keeping the previous one",
+					zap.String("cert", r.config.CertFile),
+					zap.Error(err),
+				)
+			} else {
+				r.logger.Info("Reloaded TLS certificate", zap.String("cert", r.config.CertFile))
+			}
+		}
+	}
+	return r.cert, r.clientCA
+}
+
+func (r *Reloader) load(modTimes [3]time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.loadLocked(modTimes)
+}
+
+func (r *Reloader) loadLocked(modTimes [3]time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("load TLS certificate: %w", err)
+	}
+	var clientCA *x509.CertPool
+	if r.config.ClientCAFile != "" {
+		data, err := os.ReadFile(r.config.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("load client CA: %w", err)
+		}
+		clientCA = x509.NewCertPool()
+		if !clientCA.AppendCertsFromPEM(data) {
+			return fmt.Errorf("%w: %s", ErrInvalidClientCA, r.config.ClientCAFile)
+		}
+	}
+	r.cert, r.clientCA, r.modTimes = &cert, clientCA, modTimes
+	return nil
+}
+
+// fileModTimes returns the modification times of the certificate, key and
+// client CA files, zero for files that cannot be read
+func (r *Reloader) fileModTimes() [3]time.Time {
+	var modTimes [3]time.Time
+	for i, path := range []string{r.config.CertFile, r.config.KeyFile, r.config.ClientCAFile} {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			modTimes[i] = info.ModTime()
+		}
+	}
+	return modTimes
+}
@@ -0,0 +1,247 @@
+package tokens
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/bank"
+)
+
+// Mint issues new tokens to a recipient, enforcing the max supply
+func (tf *TokenFactory) Mint(msg MsgMint) error {
+	if err := msg.ValidateBasic(); err != nil {
+		return err
+	}
+
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
+
+	token, err := tf.adminToken(msg.Denom, msg.Sender)
+	if err != nil {
+		return err
+	}
+
+	if token.MaxSupply > 0 {
+		supply := tf.bank.GetSupply(token.Denom)
+		if supply > token.MaxSupply || msg.Amount > token.MaxSupply-supply {
+			return fmt.Errorf("%w: supply %d + %d > %d", ErrMaxSupplyExceeded, supply, msg.Amount, token.MaxSupply)
+		}
+	}
+
+	if err := tf.bank.Mint(msg.Recipient, token.Denom, msg.Amount); err != nil {
+		return err
+	}
+
+	tf.logger.Info("Token minted",
+		zap.String("denom", token.Denom),
+		zap.String("recipient", msg.Recipient),
+		zap.Uint64("amount", msg.Amount),
+	)
+	return nil
+}
+
+// Burn destroys tokens held by the admin
+func (tf *TokenFactory) Burn(msg MsgBurn) error {
+	if err := msg.ValidateBasic(); err != nil {
+		return err
+	}
+
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
+
+	token, err := tf.adminToken(msg.Denom, msg.Sender)
+	if err != nil {
+		return err
+	}
+
+	if err := tf.bank.Burn(msg.Sender, token.Denom, msg.Amount); err != nil {
+		return err
+	}
+
+	tf.logger.Info("Token burned",
+		zap.String("denom", token.Denom),
+		zap.Uint64("amount", msg.Amount),
+	)
+	return nil
+}
+
+// ChangeAdmin transfers admin rights to a new address
+func (tf *TokenFactory) ChangeAdmin(msg MsgChangeAdmin) error {
+	if err := msg.ValidateBasic(); err != nil {
+		return err
+	}
+
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
+
+	token, err := tf.adminToken(msg.Denom, msg.Sender)
+	if err != nil {
+		return err
+	}
+	token.Admin = msg.NewAdmin
+	tf.changed[token.Denom] = true
+
+	tf.logger.Info("Token admin changed",
+		zap.String("denom", token.Denom),
+		zap.String("old_admin", msg.Sender),
+		zap.String("new_admin", msg.NewAdmin),
+	)
+	return nil
+}
+
+// RenounceAdmin removes the admin for good. Any frozen holders are released,
+// since nobody would be left to unfreeze them.
+func (tf *TokenFactory) RenounceAdmin(msg MsgRenounceAdmin) error {
+	if err := msg.ValidateBasic(); err != nil {
+		return err
+	}
+
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
+
+	token, err := tf.adminToken(msg.Denom, msg.Sender)
+	if err != nil {
+		return err
+	}
+	token.Admin = ""
+	tf.changed[token.Denom] = true
+
+	tf.frozenMu.Lock()
+	for address := range tf.frozen[token.Denom] {
+		tf.changed[frozenKey(token.Denom, address)] = true
+	}
+	delete(tf.frozen, token.Denom)
+	tf.frozenMu.Unlock()
+
+	tf.logger.Info("Token admin renounced",
+		zap.String("denom", token.Denom),
+		zap.String("former_admin", msg.Sender),
+	)
+	return nil
+}
+
+// Freeze stops a holder from sending or receiving a freezable token
+func (tf *TokenFactory) Freeze(msg MsgFreeze) error {
+	if err := msg.ValidateBasic(); err != nil {
+		return err
+	}
+
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
+
+	token, err := tf.adminToken(msg.Denom, msg.Sender)
+	if err != nil {
+		return err
+	}
+	if !token.Freezable {
+		return fmt.Errorf("%w: %s", ErrNotFreezable, token.Denom)
+	}
+	if msg.Address == token.Admin {
+		return fmt.Errorf("admin cannot freeze itself")
+	}
+
+	tf.frozenMu.Lock()
+	if tf.frozen[token.Denom] == nil {
+		tf.frozen[token.Denom] = make(map[string]bool)
+	}
+	tf.frozen[token.Denom][msg.Address] = true
+	tf.frozenMu.Unlock()
+	tf.changed[frozenKey(token.Denom, msg.Address)] = true
+
+	tf.logger.Info("Holder frozen",
+		zap.String("denom", token.Denom),
+		zap.String("address", msg.Address),
+	)
+	return nil
+}
+
+// Unfreeze lifts a freeze placed on a holder
+func (tf *TokenFactory) Unfreeze(msg MsgUnfreeze) error {
+	if err := msg.ValidateBasic(); err != nil {
+		return err
+	}
+
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
+
+	token, err := tf.adminToken(msg.Denom, msg.Sender)
+	if err != nil {
+		return err
+	}
+	if !token.Freezable {
+		return fmt.Errorf("%w: %s", ErrNotFreezable, token.Denom)
+	}
+
+	tf.frozenMu.Lock()
+	delete(tf.frozen[token.Denom], msg.Address)
+	tf.frozenMu.Unlock()
+	tf.changed[frozenKey(token.Denom, msg.Address)] = true
+
+	tf.logger.Info("Holder unfrozen",
+		zap.String("denom", token.Denom),
+		zap.String("address", msg.Address),
+	)
+	return nil
+}
+
+// IsFrozen reports whether an address is frozen for a denom
+func (tf *TokenFactory) IsFrozen(denom, address string) bool {
+	tf.frozenMu.RLock()
+	defer tf.frozenMu.RUnlock()
+	return tf.frozen[denom][address]
+}
+
+// Holder is a token balance together with its freeze status
+type Holder struct {
+	bank.Holder
+	Frozen bool `json:"frozen"`
+}
+
+// Holders lists every holder of a factory token, largest balance first
+func (tf *TokenFactory) Holders(denom string) ([]Holder, error) {
+	if _, err := tf.GetToken(denom); err != nil {
+		return nil, err
+	}
+
+	balances := tf.bank.Holders(denom)
+
+	tf.frozenMu.RLock()
+	defer tf.frozenMu.RUnlock()
+
+	holders := make([]Holder, 0, len(balances))
+	for _, balance := range balances {
+		holders = append(holders, Holder{
+			Holder: balance,
+			Frozen: tf.frozen[denom][balance.Address],
+		})
+	}
+	return holders, nil
+}
+
+// adminToken looks up a token and checks that sender is its admin.
+// Callers must hold tf.mu.
+func (tf *TokenFactory) adminToken(denom, sender string) (*Token, error) {
+	token, ok := tf.tokens[denom]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTokenNotFound, denom)
+	}
+	if token.Admin == "" || token.Admin != sender {
+		return nil, ErrUnauthorized
+	}
+	return token, nil
+}
+
+// checkFrozen is registered with the ledger as a send restriction
+func (tf *TokenFactory) checkFrozen(from, to, denom string) error {
+	tf.frozenMu.RLock()
+	defer tf.frozenMu.RUnlock()
+
+	if tf.frozen[denom][from] {
+		return fmt.Errorf("%w: %s", ErrFrozen, from)
+	}
+	if tf.frozen[denom][to] {
+		return fmt.Errorf("%w: %s", ErrFrozen, to)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package tokens
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vindexchain/blockchain/internal/bank"
+)
+
+const (
+	alice = "vindex1alice000000000000000"
+	bob   = "vindex1bob00000000000000000"
+)
+
+var blockTime = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestFactory(t *testing.T, msg MsgCreateToken) (*TokenFactory, *bank.Keeper, *Token) {
+	t.Helper()
+	keeper := bank.NewKeeper(nil)
+	tf := NewTokenFactory(keeper, &Config{})
+	if msg.Creator == "" {
+		msg.Creator = alice
+	}
+	if msg.Name == "" {
+		msg.Name, msg.Symbol = "Test", "TST"
+	}
+	token, err := tf.CreateToken(msg, blockTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tf, keeper, token
+}
+
+func TestFreeze(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		wantErr error
+	}{
+		{"holder", bob, nil},
+		{"dex module", bank.ModuleAddress("dex"), ErrModuleAccount},
+		{"tokens module", bank.ModuleAddress(ModuleName), ErrModuleAccount},
+		{"fee collector", bank.ModuleAddress("fee_collector"), ErrModuleAccount},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tf, _, token := newTestFactory(t, MsgCreateToken{InitialSupply: 100, Freezable: true})
+			err := tf.Freeze(MsgFreeze{Sender: alice, Denom: token.Denom, Address: tt.address})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Freeze(%s) = %v; want %v", tt.address, err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !tf.IsFrozen(token.Denom, tt.address) {
+				t.Errorf("%s is not frozen", tt.address)
+			}
+		})
+	}
+}
+
+func TestMintMaxSupply(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxSupply uint64
+		burnFirst uint64
+		sender    string
+		amount    uint64
+		wantErr   error
+	}{
+		{name: "up to the cap", maxSupply: 1000, amount: 600},
+		{name: "below the cap", maxSupply: 1000, amount: 1},
+		{name: "one over the cap", maxSupply: 1000, amount: 601, wantErr: ErrMaxSupplyExceeded},
+		{name: "overflowing amount", maxSupply: 1000, amount: ^uint64(0), wantErr: ErrMaxSupplyExceeded},
+		{name: "burns free headroom", maxSupply: 1000, burnFirst: 100, amount: 700},
+		{name: "uncapped", amount: ^uint64(0) - 400},
+		{name: "not the admin", maxSupply: 1000, sender: bob, amount: 1, wantErr: ErrUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tf, ledger, token := newTestFactory(t, MsgCreateToken{InitialSupply: 400, MaxSupply: tt.maxSupply})
+			if tt.burnFirst > 0 {
+				if err := tf.Burn(MsgBurn{Sender: alice, Denom: token.Denom, Amount: tt.burnFirst}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			sender := tt.sender
+			if sender == "" {
+				sender = alice
+			}
+			supply := ledger.GetSupply(token.Denom)
+
+			err := tf.Mint(MsgMint{Sender: sender, Denom: token.Denom, Amount: tt.amount, Recipient: bob})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Mint(%d) = %v; want %v", tt.amount, err, tt.wantErr)
+			}
+			wantSupply, wantBalance := supply, uint64(0)
+			if tt.wantErr == nil {
+				wantSupply, wantBalance = supply+tt.amount, tt.amount
+			}
+			if got := ledger.GetSupply(token.Denom); got != wantSupply {
+				t.Errorf("supply = %d; want %d", got, wantSupply)
+			}
+			if got := ledger.GetBalance(bob, token.Denom); got != wantBalance {
+				t.Errorf("bob holds %d; want %d", got, wantBalance)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package tokens
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Keys of the factory in the state store, relative to the module prefix:
+// {denom} holds the token and frozen/{denom}/{address} marks a frozen
+// holder. Denoms never contain a slash, so the two cannot collide.
+const frozenKeyPrefix = "frozen/"
+
+// ExportState returns every token and frozen holder
+func (tf *TokenFactory) ExportState() (map[string][]byte, error) {
+	tf.mu.RLock()
+	defer tf.mu.RUnlock()
+
+	state := make(map[string][]byte, len(tf.tokens))
+	for denom, token := range tf.tokens {
+		data, err := json.Marshal(token)
+		if err != nil {
+			return nil, err
+		}
+		state[denom] = data
+	}
+
+	tf.frozenMu.RLock()
+	defer tf.frozenMu.RUnlock()
+	for denom, holders := range tf.frozen {
+		for address := range holders {
+			state[frozenKey(denom, address)] = []byte{1}
+		}
+	}
+	return state, nil
+}
+
+// ImportState replaces the tokens and frozen holders with exported state
+func (tf *TokenFactory) ImportState(state map[string][]byte) error {
+	tokens := make(map[string]*Token)
+	frozen := make(map[string]map[string]bool)
+	for key, value := range state {
+		if rest, ok := strings.CutPrefix(key, frozenKeyPrefix); ok {
+			denom, address, ok := strings.Cut(rest, "/")
+			if !ok || denom == "" || address == "" {
+				return fmt.Errorf("invalid frozen holder key %s", key)
+			}
+			if frozen[denom] == nil {
+				frozen[denom] = make(map[string]bool)
+			}
+			frozen[denom][address] = true
+			continue
+		}
+		var token Token
+		if err := json.Unmarshal(value, &token); err != nil {
+			return fmt.Errorf("invalid token %s: %w", key, err)
+		}
+		if token.Denom != key {
+			return fmt.Errorf("token %s stored under %s", token.Denom, key)
+		}
+		tokens[key] = &token
+	}
+
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
+	tf.tokens = tokens
+	tf.changed = make(map[string]bool)
+	tf.frozenMu.Lock()
+	tf.frozen = frozen
+	tf.frozenMu.Unlock()
+	return nil
+}
+
+// ExportChanges returns the tokens and frozen holders changed since the
+// last export, with nil values for released holders
+func (tf *TokenFactory) ExportChanges() (map[string][]byte, error) {
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
+	tf.frozenMu.RLock()
+	defer tf.frozenMu.RUnlock()
+
+	changes := make(map[string][]byte, len(tf.changed))
+	for key := range tf.changed {
+		if rest, ok := strings.CutPrefix(key, frozenKeyPrefix); ok {
+			denom, address, _ := strings.Cut(rest, "/")
+			changes[key] = nil
+			if tf.frozen[denom][address] {
+				changes[key] = []byte{1}
+			}
+			continue
+		}
+		data, err := json.Marshal(tf.tokens[key])
+		if err != nil {
+			return nil, err
+		}
+		changes[key] = data
+	}
+	tf.changed = make(map[string]bool)
+	return changes, nil
+}
+
+func frozenKey(denom, address string) string {
+	return frozenKeyPrefix + denom + "/" + address
+}
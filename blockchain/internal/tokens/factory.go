@@ -0,0 +1,314 @@
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/bank"
+)
+
+const (
+	// ModuleName is the name of the token factory module
+	ModuleName = "tokens"
+
+	defaultFeeDenom = "oc"
+)
+
+var (
+	// ErrTokenNotFound is returned for unknown denoms
+	ErrTokenNotFound = errors.New("token not found")
+	// ErrTokenExists is returned when a symbol is already taken
+	ErrTokenExists = errors.New("token already exists")
+	// ErrUnauthorized is returned when the sender is not the token admin
+	ErrUnauthorized = errors.New("sender is not the token admin")
+	// ErrMaxSupplyExceeded is returned when a mint would exceed the max supply
+	ErrMaxSupplyExceeded = errors.New("max supply exceeded")
+	// ErrNotFreezable is returned when freezing a token created without the freeze capability
+	ErrNotFreezable = errors.New("token is not freezable")
+	// ErrFrozen is returned when a frozen holder tries to move a token
+	ErrFrozen = errors.New("account is frozen for this token")
+	// ErrModuleAccount is returned when freezing an address owned by a
+	// module, such as the DEX holding pool reserves
+	ErrModuleAccount = errors.New("module accounts cannot be frozen")
+)
+
+// LiquiditySeeder opens the initial pool for a new token. funder pays both
+// legs and owner receives the LP shares, locked for lockFor from now.
+type LiquiditySeeder interface {
+	SeedPool(funder, owner, denomA string, amountA uint64, denomB string, amountB uint64, lockFor time.Duration, now time.Time) (uint64, error)
+}
+
+// Config holds token factory parameters
+type Config struct {
+	CreationFee    uint64
+	LiquidityShare int // percent of the creation fee sent to liquidity
+	ValidatorShare int // percent of the creation fee sent to validators
+	DevTeamShare   int // percent of the creation fee sent to the dev team
+	LPShare        int // percent of the creation fee sent to LP rewards
+	FeeDenom       string
+	DevTeamAddress string
+
+	// Pools seeds an OC$/token pool with the liquidity share on creation;
+	// when nil the liquidity share stays in the module account
+	Pools               LiquiditySeeder
+	LiquidityLockPeriod time.Duration
+
+	Logger *zap.Logger
+}
+
+// Token is a token created through the factory
+type Token struct {
+	Denom     string    `json:"denom"`
+	Name      string    `json:"name"`
+	Symbol    string    `json:"symbol"`
+	Decimals  uint32    `json:"decimals"`
+	Creator   string    `json:"creator"`
+	Admin     string    `json:"admin"` // empty once renounced
+	MaxSupply uint64    `json:"max_supply"`
+	Freezable bool      `json:"freezable"`
+	PoolID    uint64    `json:"pool_id,omitempty"` // initial OC$ liquidity pool
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TokenFactory creates factory tokens and manages their lifecycle
+type TokenFactory struct {
+	mu      sync.RWMutex
+	bank    *bank.Keeper
+	config  *Config
+	tokens  map[string]*Token
+	changed map[string]bool // state keys changed since the last export
+	logger  *zap.Logger
+
+	// frozen has its own lock because the ledger consults it from inside
+	// Send, which the factory itself calls while holding mu
+	frozenMu sync.RWMutex
+	frozen   map[string]map[string]bool // denom -> address -> frozen
+}
+
+// NewTokenFactory creates a new token factory backed by the ledger
+func NewTokenFactory(bankKeeper *bank.Keeper, cfg *Config) *TokenFactory {
+	if cfg.FeeDenom == "" {
+		cfg.FeeDenom = defaultFeeDenom
+	}
+	if cfg.DevTeamAddress == "" {
+		cfg.DevTeamAddress = bank.ModuleAddress("dev_team")
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	tf := &TokenFactory{
+		bank:    bankKeeper,
+		config:  cfg,
+		tokens:  make(map[string]*Token),
+		changed: make(map[string]bool),
+		frozen:  make(map[string]map[string]bool),
+		logger:  logger,
+	}
+	bankKeeper.AddSendRestriction(tf.checkFrozen)
+
+	return tf
+}
+
+// Denom returns the on-chain denom for a token symbol
+func Denom(symbol string) string {
+	return strings.ToLower(symbol)
+}
+
+// CreateToken charges the creation fee and issues a new token at block
+// time now
+func (tf *TokenFactory) CreateToken(msg MsgCreateToken, now time.Time) (*Token, error) {
+	if err := msg.ValidateBasic(); err != nil {
+		return nil, err
+	}
+
+	denom := Denom(msg.Symbol)
+	if denom == tf.config.FeeDenom {
+		return nil, fmt.Errorf("%w: %s is the native denom", ErrTokenExists, denom)
+	}
+
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
+
+	if _, exists := tf.tokens[denom]; exists {
+		return nil, fmt.Errorf("%w: %s", ErrTokenExists, denom)
+	}
+	if supply := tf.bank.GetSupply(denom); supply > 0 {
+		return nil, fmt.Errorf("%w: %d%s already issued", ErrTokenExists, supply, denom)
+	}
+
+	seed := tf.config.Pools != nil && tf.liquidityAmount() > 0
+	if seed && msg.LiquidityAmount == 0 {
+		return nil, fmt.Errorf("liquidity amount is required to seed the initial pool")
+	}
+
+	liquidity, refundFee, err := tf.chargeCreationFee(msg.Creator)
+	if err != nil {
+		return nil, err
+	}
+
+	token := &Token{
+		Denom:     denom,
+		Name:      msg.Name,
+		Symbol:    msg.Symbol,
+		Decimals:  msg.Decimals,
+		Creator:   msg.Creator,
+		Admin:     msg.Creator,
+		MaxSupply: msg.MaxSupply,
+		Freezable: msg.Freezable,
+		CreatedAt: now,
+	}
+
+	// A failure past this point takes back what was minted and refunds the
+	// fee, so a token that was not created costs nothing
+	creatorSupply := msg.InitialSupply
+	if seed {
+		creatorSupply -= msg.LiquidityAmount
+	}
+	if creatorSupply > 0 {
+		if err := tf.bank.Mint(msg.Creator, denom, creatorSupply); err != nil {
+			refundFee()
+			return nil, err
+		}
+	}
+	unmintCreator := func() {
+		if creatorSupply > 0 {
+			if err := tf.bank.Unmint(msg.Creator, denom, creatorSupply); err != nil {
+				tf.logger.Error("Failed to take back minted supply", zap.String("denom", denom), zap.Error(err))
+			}
+		}
+	}
+
+	if seed {
+		moduleAddr := bank.ModuleAddress(ModuleName)
+		if err := tf.bank.Mint(moduleAddr, denom, msg.LiquidityAmount); err != nil {
+			unmintCreator()
+			refundFee()
+			return nil, err
+		}
+		poolID, err := tf.config.Pools.SeedPool(moduleAddr, msg.Creator, tf.config.FeeDenom, liquidity, denom, msg.LiquidityAmount, tf.config.LiquidityLockPeriod, now)
+		if err != nil {
+			if err := tf.bank.Unmint(moduleAddr, denom, msg.LiquidityAmount); err != nil {
+				tf.logger.Error("Failed to take back minted liquidity", zap.String("denom", denom), zap.Error(err))
+			}
+			unmintCreator()
+			refundFee()
+			return nil, fmt.Errorf("failed to seed initial pool: %w", err)
+		}
+		token.PoolID = poolID
+	}
+	tf.tokens[denom] = token
+	tf.changed[denom] = true
+
+	tf.logger.Info("Token created",
+		zap.String("denom", denom),
+		zap.String("creator", msg.Creator),
+		zap.Uint64("initial_supply", msg.InitialSupply),
+		zap.Uint64("max_supply", msg.MaxSupply),
+		zap.Bool("freezable", msg.Freezable),
+		zap.Uint64("pool_id", token.PoolID),
+	)
+
+	copied := *token
+	return &copied, nil
+}
+
+// GetToken returns a token by denom
+func (tf *TokenFactory) GetToken(denom string) (*Token, error) {
+	tf.mu.RLock()
+	defer tf.mu.RUnlock()
+
+	token, ok := tf.tokens[denom]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTokenNotFound, denom)
+	}
+	copied := *token
+	return &copied, nil
+}
+
+// GetTokens returns every factory token ordered by denom
+func (tf *TokenFactory) GetTokens() []*Token {
+	tf.mu.RLock()
+	defer tf.mu.RUnlock()
+
+	tokens := make([]*Token, 0, len(tf.tokens))
+	for _, token := range tf.tokens {
+		copied := *token
+		tokens = append(tokens, &copied)
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].Denom < tokens[j].Denom })
+	return tokens
+}
+
+// liquidityAmount is the part of the creation fee reserved for liquidity
+func (tf *TokenFactory) liquidityAmount() uint64 {
+	return tf.config.CreationFee * uint64(tf.config.LiquidityShare) / 100
+}
+
+// chargeCreationFee collects the creation fee from the creator and splits it
+// according to the configured shares; any remainder goes to validators. It
+// returns the liquidity share, which is held in the module account, and a
+// function that refunds the fee. A failed charge refunds what it collected.
+func (tf *TokenFactory) chargeCreationFee(creator string) (uint64, func(), error) {
+	fee := tf.config.CreationFee
+	if fee == 0 {
+		return 0, func() {}, nil
+	}
+
+	shares := tf.config.LiquidityShare + tf.config.ValidatorShare + tf.config.DevTeamShare + tf.config.LPShare
+	if shares > 100 {
+		return 0, nil, fmt.Errorf("creation fee shares add up to %d%%", shares)
+	}
+
+	denom := tf.config.FeeDenom
+	if balance := tf.bank.GetBalance(creator, denom); balance < fee {
+		return 0, nil, fmt.Errorf("%w: creation fee is %d%s, balance is %d%s", bank.ErrInsufficientFunds, fee, denom, balance, denom)
+	}
+
+	liquidity := tf.liquidityAmount()
+	devTeam := fee * uint64(tf.config.DevTeamShare) / 100
+	lp := fee * uint64(tf.config.LPShare) / 100
+	validators := fee - liquidity - devTeam - lp
+
+	type split struct {
+		to     string
+		amount uint64
+	}
+	splits := []split{
+		{bank.ModuleAddress(ModuleName), liquidity},
+		{tf.config.DevTeamAddress, devTeam},
+		{bank.ModuleAddress("lp_rewards"), lp},
+		{bank.ModuleAddress("fee_collector"), validators},
+	}
+	var paid []split
+	refund := func() {
+		for _, p := range paid {
+			if err := tf.bank.Send(p.to, creator, denom, p.amount); err != nil {
+				tf.logger.Error("Failed to refund creation fee",
+					zap.String("creator", creator),
+					zap.String("from", p.to),
+					zap.Uint64("amount", p.amount),
+					zap.Error(err),
+				)
+			}
+		}
+	}
+	for _, s := range splits {
+		if s.amount == 0 {
+			continue
+		}
+		if err := tf.bank.Send(creator, s.to, denom, s.amount); err != nil {
+			refund()
+			return 0, nil, fmt.Errorf("failed to pay creation fee: %w", err)
+		}
+		paid = append(paid, s)
+	}
+	return liquidity, refund, nil
+}
@@ -0,0 +1,219 @@
+package tokens
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/vindexchain/blockchain/internal/bank"
+)
+
+var symbolRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]{2,11}$`)
+
+// MsgCreateToken creates a new factory token owned by Creator
+type MsgCreateToken struct {
+	Creator       string `json:"creator"`
+	Name          string `json:"name"`
+	Symbol        string `json:"symbol"`
+	Decimals      uint32 `json:"decimals"`
+	InitialSupply uint64 `json:"initial_supply"`
+	MaxSupply     uint64 `json:"max_supply"` // 0 means uncapped
+	Freezable     bool   `json:"freezable"`
+
+	// LiquidityAmount is the part of InitialSupply paired with the OC$
+	// liquidity share in the token's initial pool
+	LiquidityAmount uint64 `json:"liquidity_amount"`
+}
+
+// MsgMint mints new tokens to a recipient; only the admin may mint
+type MsgMint struct {
+	Sender    string `json:"sender"`
+	Denom     string `json:"denom"`
+	Amount    uint64 `json:"amount"`
+	Recipient string `json:"recipient"`
+}
+
+// MsgBurn burns tokens from the admin's own balance
+type MsgBurn struct {
+	Sender string `json:"sender"`
+	Denom  string `json:"denom"`
+	Amount uint64 `json:"amount"`
+}
+
+// MsgChangeAdmin hands admin rights over to another address
+type MsgChangeAdmin struct {
+	Sender   string `json:"sender"`
+	Denom    string `json:"denom"`
+	NewAdmin string `json:"new_admin"`
+}
+
+// MsgRenounceAdmin permanently gives up admin rights over a token
+type MsgRenounceAdmin struct {
+	Sender string `json:"sender"`
+	Denom  string `json:"denom"`
+}
+
+// MsgFreeze blocks a holder from moving a freezable token
+type MsgFreeze struct {
+	Sender  string `json:"sender"`
+	Denom   string `json:"denom"`
+	Address string `json:"address"`
+}
+
+// MsgUnfreeze lifts a previous freeze
+type MsgUnfreeze struct {
+	Sender  string `json:"sender"`
+	Denom   string `json:"denom"`
+	Address string `json:"address"`
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgCreateToken) ValidateBasic() error {
+	if err := bank.ValidateAddress(m.Creator); err != nil {
+		return fmt.Errorf("creator: %w", err)
+	}
+	if m.Name == "" || len(m.Name) > 64 {
+		return fmt.Errorf("name must be between 1 and 64 characters")
+	}
+	if !symbolRegexp.MatchString(m.Symbol) {
+		return fmt.Errorf("symbol must be 3-12 alphanumeric characters starting with a letter")
+	}
+	if m.Decimals > 18 {
+		return fmt.Errorf("decimals cannot exceed 18")
+	}
+	if m.MaxSupply > 0 && m.InitialSupply > m.MaxSupply {
+		return fmt.Errorf("initial supply %d exceeds max supply %d", m.InitialSupply, m.MaxSupply)
+	}
+	if m.LiquidityAmount > m.InitialSupply {
+		return fmt.Errorf("liquidity amount %d exceeds initial supply %d", m.LiquidityAmount, m.InitialSupply)
+	}
+	return nil
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgMint) ValidateBasic() error {
+	if err := bank.ValidateAddress(m.Sender); err != nil {
+		return fmt.Errorf("sender: %w", err)
+	}
+	if err := bank.ValidateAddress(m.Recipient); err != nil {
+		return fmt.Errorf("recipient: %w", err)
+	}
+	if m.Denom == "" {
+		return fmt.Errorf("denom cannot be empty")
+	}
+	if m.Amount == 0 {
+		return bank.ErrInvalidAmount
+	}
+	return nil
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgBurn) ValidateBasic() error {
+	if err := bank.ValidateAddress(m.Sender); err != nil {
+		return fmt.Errorf("sender: %w", err)
+	}
+	if m.Denom == "" {
+		return fmt.Errorf("denom cannot be empty")
+	}
+	if m.Amount == 0 {
+		return bank.ErrInvalidAmount
+	}
+	return nil
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgChangeAdmin) ValidateBasic() error {
+	if err := bank.ValidateAddress(m.Sender); err != nil {
+		return fmt.Errorf("sender: %w", err)
+	}
+	if err := bank.ValidateAddress(m.NewAdmin); err != nil {
+		return fmt.Errorf("new admin: %w", err)
+	}
+	if m.Denom == "" {
+		return fmt.Errorf("denom cannot be empty")
+	}
+	return nil
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgRenounceAdmin) ValidateBasic() error {
+	if err := bank.ValidateAddress(m.Sender); err != nil {
+		return fmt.Errorf("sender: %w", err)
+	}
+	if m.Denom == "" {
+		return fmt.Errorf("denom cannot be empty")
+	}
+	return nil
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgFreeze) ValidateBasic() error {
+	if err := bank.ValidateAddress(m.Sender); err != nil {
+		return fmt.Errorf("sender: %w", err)
+	}
+	if bank.IsModuleAddress(m.Address) {
+		return fmt.Errorf("%w: %s", ErrModuleAccount, m.Address)
+	}
+	if err := bank.ValidateAddress(m.Address); err != nil {
+		return fmt.Errorf("address: %w", err)
+	}
+	if m.Denom == "" {
+		return fmt.Errorf("denom cannot be empty")
+	}
+	return nil
+}
+
+// ValidateBasic performs stateless checks
+func (m MsgUnfreeze) ValidateBasic() error {
+	if err := bank.ValidateAddress(m.Sender); err != nil {
+		return fmt.Errorf("sender: %w", err)
+	}
+	if err := bank.ValidateAddress(m.Address); err != nil {
+		return fmt.Errorf("address: %w", err)
+	}
+	if m.Denom == "" {
+		return fmt.Errorf("denom cannot be empty")
+	}
+	return nil
+}
+
+// Type implements accounts.Msg
+func (m MsgCreateToken) Type() string { return "tokens/create" }
+
+// Signer implements accounts.Msg
+func (m MsgCreateToken) Signer() string { return m.Creator }
+
+// Type implements accounts.Msg
+func (m MsgMint) Type() string { return "tokens/mint" }
+
+// Signer implements accounts.Msg
+func (m MsgMint) Signer() string { return m.Sender }
+
+// Type implements accounts.Msg
+func (m MsgBurn) Type() string { return "tokens/burn" }
+
+// Signer implements accounts.Msg
+func (m MsgBurn) Signer() string { return m.Sender }
+
+// Type implements accounts.Msg
+func (m MsgChangeAdmin) Type() string { return "tokens/change-admin" }
+
+// Signer implements accounts.Msg
+func (m MsgChangeAdmin) Signer() string { return m.Sender }
+
+// Type implements accounts.Msg
+func (m MsgRenounceAdmin) Type() string { return "tokens/renounce-admin" }
+
+// Signer implements accounts.Msg
+func (m MsgRenounceAdmin) Signer() string { return m.Sender }
+
+// Type implements accounts.Msg
+func (m MsgFreeze) Type() string { return "tokens/freeze" }
+
+// Signer implements accounts.Msg
+func (m MsgFreeze) Signer() string { return m.Sender }
+
+// Type implements accounts.Msg
+func (m MsgUnfreeze) Type() string { return "tokens/unfreeze" }
+
+// Signer implements accounts.Msg
+func (m MsgUnfreeze) Signer() string { return m.Sender }
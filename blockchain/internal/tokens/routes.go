@@ -0,0 +1,27 @@
+package tokens
+
+import (
+	"github.com/vindexchain/blockchain/internal/app"
+)
+
+// RegisterRoutes routes the token messages of blocks to the factory
+func RegisterRoutes(r *app.Router, tf *TokenFactory) {
+	app.Handle(r, func(ctx *app.Context, msg MsgCreateToken) error {
+		token, err := tf.CreateToken(msg, ctx.Time)
+		if err != nil {
+			return err
+		}
+		ctx.EmitEvent("create_token",
+			app.Attribute("denom", token.Denom),
+			app.Attribute("creator", token.Creator),
+			app.Attribute("pool_id", token.PoolID),
+		)
+		return nil
+	})
+	app.Handle(r, func(ctx *app.Context, msg MsgMint) error { return tf.Mint(msg) })
+	app.Handle(r, func(ctx *app.Context, msg MsgBurn) error { return tf.Burn(msg) })
+	app.Handle(r, func(ctx *app.Context, msg MsgChangeAdmin) error { return tf.ChangeAdmin(msg) })
+	app.Handle(r, func(ctx *app.Context, msg MsgRenounceAdmin) error { return tf.RenounceAdmin(msg) })
+	app.Handle(r, func(ctx *app.Context, msg MsgFreeze) error { return tf.Freeze(msg) })
+	app.Handle(r, func(ctx *app.Context, msg MsgUnfreeze) error { return tf.Unfreeze(msg) })
+}
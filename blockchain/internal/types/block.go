@@ -0,0 +1,117 @@
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidBlock is returned for blocks that fail validation
+var ErrInvalidBlock = errors.New("invalid block")
+
+// Header is the part of a block that validators sign and light clients
+// verify. AppHash is the application state after executing the previous
+// block.
+type Header struct {
+	ChainID            string    `json:"chain_id"`
+	Height             int64     `json:"height"`
+	Time               time.Time `json:"time"`
+	LastBlockHash      HexBytes  `json:"last_block_hash"`
+	LastCommitHash     HexBytes  `json:"last_commit_hash"`
+	DataHash           HexBytes  `json:"data_hash"`
+	ValidatorsHash     HexBytes  `json:"validators_hash"`
+	NextValidatorsHash HexBytes  `json:"next_validators_hash"`
+	AppHash            HexBytes  `json:"app_hash"`
+	ProposerAddress    HexBytes  `json:"proposer_address"`
+}
+
+// Hash returns the Merkle root of the header fields, so single fields such
+// as the app hash can be proven against the block hash
+func (h *Header) Hash() HexBytes {
+	if h == nil || len(h.ValidatorsHash) == 0 {
+		return nil
+	}
+	return MerkleRoot(h.fields())
+}
+
+func (h *Header) fields() [][]byte {
+	return [][]byte{
+		[]byte(h.ChainID),
+		int64Bytes(h.Height),
+		int64Bytes(h.Time.UnixNano()),
+		h.LastBlockHash,
+		h.LastCommitHash,
+		h.DataHash,
+		h.ValidatorsHash,
+		h.NextValidatorsHash,
+		h.AppHash,
+		h.ProposerAddress,
+	}
+}
+
+// ValidateBasic checks the header without any chain context
+func (h *Header) ValidateBasic() error {
+	if h.ChainID == "" {
+		return fmt.Errorf("%w: empty chain ID", ErrInvalidBlock)
+	}
+	if h.Height <= 0 {
+		return fmt.Errorf("%w: height must be positive, got %d", ErrInvalidBlock, h.Height)
+	}
+	if h.Height > 1 && len(h.LastBlockHash) == 0 {
+		return fmt.Errorf("%w: missing last block hash", ErrInvalidBlock)
+	}
+	if len(h.ValidatorsHash) == 0 || len(h.NextValidatorsHash) == 0 {
+		return fmt.Errorf("%w: missing validators hash", ErrInvalidBlock)
+	}
+	return nil
+}
+
+// Block is a header, the transactions it orders and the commit for the
+// previous block
+type Block struct {
+	Header     `json:"header"`
+	Txs        [][]byte `json:"txs"`
+	LastCommit *Commit  `json:"last_commit,omitempty"`
+}
+
+// Hash returns the block hash, which is the header hash
+func (b *Block) Hash() HexBytes {
+	if b == nil {
+		return nil
+	}
+	return b.Header.Hash()
+}
+
+// ValidateBasic checks the header and that the data and last commit hashes
+// match the block contents
+func (b *Block) ValidateBasic() error {
+	if err := b.Header.ValidateBasic(); err != nil {
+		return err
+	}
+	if !bytes.Equal(b.DataHash, MerkleRoot(b.Txs)) {
+		return fmt.Errorf("%w: data hash does not match the transactions", ErrInvalidBlock)
+	}
+	if b.Height > 1 {
+		if b.LastCommit == nil {
+			return fmt.Errorf("%w: missing last commit", ErrInvalidBlock)
+		}
+		if err := b.LastCommit.ValidateBasic(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBlock, err)
+		}
+		if b.LastCommit.Height != b.Height-1 || !bytes.Equal(b.LastCommit.BlockHash, b.LastBlockHash) {
+			return fmt.Errorf("%w: last commit is not for the previous block", ErrInvalidBlock)
+		}
+	}
+	if !bytes.Equal(b.LastCommitHash, b.LastCommit.Hash()) {
+		return fmt.Errorf("%w: last commit hash does not match", ErrInvalidBlock)
+	}
+	return nil
+}
+
+func int64Bytes(v int64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(v))
+	return buf[:]
+}
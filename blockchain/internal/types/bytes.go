@@ -0,0 +1,32 @@
+package types
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
+// HexBytes is a byte slice that is shown as lowercase hex in JSON and logs
+type HexBytes []byte
+
+// String returns the hex encoding
+func (b HexBytes) String() string { return hex.EncodeToString(b) }
+
+// MarshalJSON encodes the bytes as a hex string
+func (b HexBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hex.EncodeToString(b))
+}
+
+// UnmarshalJSON decodes a hex string, with or without a 0x prefix
+func (b *HexBytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
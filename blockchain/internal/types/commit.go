@@ -0,0 +1,84 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidCommit is returned for commits that fail validation or do not
+// carry enough voting power
+var ErrInvalidCommit = errors.New("invalid commit")
+
+// CommitSig is one validator's precommit for a block. Validators that did
+// not vote have an empty signature.
+type CommitSig struct {
+	ValidatorAddress HexBytes  `json:"validator_address"`
+	Timestamp        time.Time `json:"timestamp"`
+	Signature        HexBytes  `json:"signature"`
+}
+
+// Absent reports whether the validator did not sign
+func (cs CommitSig) Absent() bool { return len(cs.Signature) == 0 }
+
+// Commit is the set of precommit signatures that finalized a block. The
+// signatures are in the order of the validator set that signed.
+type Commit struct {
+	Height     int64       `json:"height"`
+	Round      int32       `json:"round"`
+	BlockHash  HexBytes    `json:"block_hash"`
+	Signatures []CommitSig `json:"signatures"`
+}
+
+// Hash returns the Merkle root of the commit signatures
+func (c *Commit) Hash() HexBytes {
+	if c == nil {
+		return nil
+	}
+	items := make([][]byte, len(c.Signatures))
+	for i, sig := range c.Signatures {
+		items[i] = append(append(append([]byte{}, sig.ValidatorAddress...), int64Bytes(sig.Timestamp.UnixNano())...), sig.Signature...)
+	}
+	return MerkleRoot(items)
+}
+
+// ValidateBasic checks the commit without a validator set
+func (c *Commit) ValidateBasic() error {
+	if c.Height <= 0 {
+		return fmt.Errorf("%w: height must be positive", ErrInvalidCommit)
+	}
+	if c.Round < 0 {
+		return fmt.Errorf("%w: negative round", ErrInvalidCommit)
+	}
+	if len(c.BlockHash) == 0 {
+		return fmt.Errorf("%w: missing block hash", ErrInvalidCommit)
+	}
+	if len(c.Signatures) == 0 {
+		return fmt.Errorf("%w: no signatures", ErrInvalidCommit)
+	}
+	for i, sig := range c.Signatures {
+		if !sig.Absent() && len(sig.ValidatorAddress) != AddressSize {
+			return fmt.Errorf("%w: signature %d has an invalid validator address", ErrInvalidCommit, i)
+		}
+	}
+	return nil
+}
+
+// VoteSignBytes returns the bytes the validator at idx signed
+func (c *Commit) VoteSignBytes(chainID string, idx int) []byte {
+	return VoteSignBytes(chainID, c.Height, c.Round, c.BlockHash, c.Signatures[idx].Timestamp)
+}
+
+// VoteSignBytes returns the canonical encoding of a precommit
+func VoteSignBytes(chainID string, height int64, round int32, blockHash []byte, timestamp time.Time) []byte {
+	bz, _ := json.Marshal(struct {
+		Type      string    `json:"type"`
+		ChainID   string    `json:"chain_id"`
+		Height    int64     `json:"height"`
+		Round     int32     `json:"round"`
+		BlockHash HexBytes  `json:"block_hash"`
+		Timestamp time.Time `json:"timestamp"`
+	}{"precommit", chainID, height, round, blockHash, timestamp.UTC()})
+	return bz
+}
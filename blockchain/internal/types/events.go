@@ -0,0 +1,48 @@
+package types
+
+import (
+	"crypto/sha256"
+	"time"
+)
+
+// EventAttribute is a key and value of an event. Attributes are indexed by
+// their composite key, the event type and key joined with a dot, such as
+// transfer.recipient.
+type EventAttribute struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Event is emitted by a transaction or a block while it executes. Modules
+// emit events such as transfer with sender, recipient and amount; the
+// ante handler emits tx with the memo and fee.
+type Event struct {
+	Type       string           `json:"type"`
+	Attributes []EventAttribute `json:"attributes"`
+}
+
+// TxResult is a transaction and the outcome of executing it
+type TxResult struct {
+	Height int64    `json:"height"`
+	Index  uint32   `json:"index"`
+	Hash   HexBytes `json:"hash"`
+	Tx     HexBytes `json:"tx"`
+	Code   uint32   `json:"code"` // 0 for success
+	Log    string   `json:"log,omitempty"`
+	Events []Event  `json:"events"`
+}
+
+// BlockResults are the events of a block and the results of its
+// transactions, in block order
+type BlockResults struct {
+	Height    int64       `json:"height"`
+	Time      time.Time   `json:"time"`
+	Events    []Event     `json:"events"` // emitted outside transactions, e.g. by staking at the end of the block
+	TxResults []*TxResult `json:"tx_results"`
+}
+
+// TxHash returns the hash a transaction is known by
+func TxHash(tx []byte) HexBytes {
+	sum := sha256.Sum256(tx)
+	return sum[:]
+}
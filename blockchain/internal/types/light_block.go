@@ -0,0 +1,44 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// LightBlock is what a light client needs to verify a height: the header,
+// the commit that signed it and the validator set that produced it
+type LightBlock struct {
+	Header       *Header       `json:"header"`
+	Commit       *Commit       `json:"commit"`
+	ValidatorSet *ValidatorSet `json:"validator_set"`
+}
+
+// Height returns the header height
+func (lb *LightBlock) Height() int64 { return lb.Header.Height }
+
+// Hash returns the header hash
+func (lb *LightBlock) Hash() HexBytes { return lb.Header.Hash() }
+
+// ValidateBasic checks that the parts of the light block belong together.
+// It does not verify signatures.
+func (lb *LightBlock) ValidateBasic(chainID string) error {
+	if lb.Header == nil || lb.Commit == nil || lb.ValidatorSet == nil {
+		return fmt.Errorf("%w: incomplete light block", ErrInvalidBlock)
+	}
+	if err := lb.Header.ValidateBasic(); err != nil {
+		return err
+	}
+	if lb.Header.ChainID != chainID {
+		return fmt.Errorf("%w: header is for chain %q, expected %q", ErrInvalidBlock, lb.Header.ChainID, chainID)
+	}
+	if err := lb.Commit.ValidateBasic(); err != nil {
+		return err
+	}
+	if lb.Commit.Height != lb.Header.Height || !bytes.Equal(lb.Commit.BlockHash, lb.Header.Hash()) {
+		return fmt.Errorf("%w: commit does not sign the header", ErrInvalidCommit)
+	}
+	if !bytes.Equal(lb.ValidatorSet.Hash(), lb.Header.ValidatorsHash) {
+		return fmt.Errorf("%w: validator set does not match the header", ErrInvalidBlock)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package types
+
+import "crypto/sha256"
+
+// Leaf and inner nodes are hashed with different prefixes (RFC 6962) so a
+// leaf can never be passed off as an inner node
+var (
+	leafPrefix  = []byte{0}
+	innerPrefix = []byte{1}
+)
+
+func leafHash(leaf []byte) []byte {
+	h := sha256.New()
+	h.Write(leafPrefix)
+	h.Write(leaf)
+	return h.Sum(nil)
+}
+
+func innerHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write(innerPrefix)
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// MerkleRoot returns the root of a binary Merkle tree over items. The tree
+// splits at the largest power of two below the item count; an empty list
+// hashes to sha256 of nothing.
+func MerkleRoot(items [][]byte) []byte {
+	switch len(items) {
+	case 0:
+		empty := sha256.Sum256(nil)
+		return empty[:]
+	case 1:
+		return leafHash(items[0])
+	default:
+		k := splitPoint(len(items))
+		return innerHash(MerkleRoot(items[:k]), MerkleRoot(items[k:]))
+	}
+}
+
+// splitPoint returns the largest power of two smaller than n
+func splitPoint(n int) int {
+	k := 1
+	for k*2 < n {
+		k *= 2
+	}
+	return k
+}
@@ -0,0 +1,127 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidProof is returned for Merkle proofs that do not lead to the
+// expected root
+var ErrInvalidProof = errors.New("invalid merkle proof")
+
+// Proof proves that an item is the Index-th of Total items under a
+// MerkleRoot. Aunts are the sibling hashes from the leaf up to the root.
+type Proof struct {
+	Total    int64      `json:"total"`
+	Index    int64      `json:"index"`
+	LeafHash HexBytes   `json:"leaf_hash"`
+	Aunts    []HexBytes `json:"aunts"`
+}
+
+// ProofsFromItems returns the Merkle root of items and a proof for each
+func ProofsFromItems(items [][]byte) ([]byte, []*Proof) {
+	proofs := make([]*Proof, len(items))
+	for i, item := range items {
+		proofs[i] = &Proof{Total: int64(len(items)), Index: int64(i), LeafHash: leafHash(item)}
+	}
+	root := fillAunts(items, proofs)
+	return root, proofs
+}
+
+// fillAunts hashes the subtree over items and appends the sibling of every
+// subtree on the way up to the proofs of its leaves
+func fillAunts(items [][]byte, proofs []*Proof) []byte {
+	switch len(items) {
+	case 0:
+		return MerkleRoot(nil)
+	case 1:
+		return proofs[0].LeafHash
+	default:
+		k := splitPoint(len(items))
+		left := fillAunts(items[:k], proofs[:k])
+		right := fillAunts(items[k:], proofs[k:])
+		for _, p := range proofs[:k] {
+			p.Aunts = append(p.Aunts, right)
+		}
+		for _, p := range proofs[k:] {
+			p.Aunts = append(p.Aunts, left)
+		}
+		return innerHash(left, right)
+	}
+}
+
+// ComputeRootHash returns the root the proof leads to, or nil if the proof
+// does not fit its Total and Index
+func (p *Proof) ComputeRootHash() []byte {
+	if p.Total <= 0 || p.Index < 0 || p.Index >= p.Total {
+		return nil
+	}
+	return computeHashFromAunts(p.Index, p.Total, p.LeafHash, p.Aunts)
+}
+
+func computeHashFromAunts(index, total int64, leaf []byte, aunts []HexBytes) []byte {
+	if total == 1 {
+		if len(aunts) != 0 {
+			return nil
+		}
+		return leaf
+	}
+	if len(aunts) == 0 {
+		return nil
+	}
+	k := int64(splitPoint(int(total)))
+	last := aunts[len(aunts)-1]
+	if index < k {
+		left := computeHashFromAunts(index, k, leaf, aunts[:len(aunts)-1])
+		if left == nil {
+			return nil
+		}
+		return innerHash(left, last)
+	}
+	right := computeHashFromAunts(index-k, total-k, leaf, aunts[:len(aunts)-1])
+	if right == nil {
+		return nil
+	}
+	return innerHash(last, right)
+}
+
+// Verify checks that item is covered by the proof and that the proof leads
+// to root
+func (p *Proof) Verify(root, item []byte) error {
+	if !bytes.Equal(p.LeafHash, leafHash(item)) {
+		return fmt.Errorf("%w: leaf hash does not match the item", ErrInvalidProof)
+	}
+	computed := p.ComputeRootHash()
+	if computed == nil {
+		return fmt.Errorf("%w: malformed proof", ErrInvalidProof)
+	}
+	if !bytes.Equal(computed, root) {
+		return fmt.Errorf("%w: proof leads to %X, expected %X", ErrInvalidProof, computed, root)
+	}
+	return nil
+}
+
+// ValueProof proves that Key held Value in the application state committed
+// at Height, or that it held nothing if Value is empty. The root is the app
+// hash in the header at Height+1.
+type ValueProof struct {
+	Height int64              `json:"height"`
+	Key    HexBytes           `json:"key"`
+	Value  HexBytes           `json:"value,omitempty"`
+	Proof  *SparseMerkleProof `json:"proof"`
+}
+
+// Exists reports whether the proof is for a value rather than its absence
+func (vp *ValueProof) Exists() bool { return len(vp.Value) > 0 }
+
+// Verify checks the proof against a verified app hash
+func (vp *ValueProof) Verify(appHash []byte) error {
+	if vp.Proof == nil {
+		return fmt.Errorf("%w: missing proof", ErrInvalidProof)
+	}
+	if !vp.Exists() {
+		return vp.Proof.VerifyNonMembership(appHash, vp.Key)
+	}
+	return vp.Proof.VerifyMembership(appHash, vp.Key, vp.Value)
+}
@@ -0,0 +1,106 @@
+package types
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+)
+
+// SparsePathBits is the depth of the sparse Merkle tree the application
+// state is kept in: keys are placed at the SHA-256 of the key
+const SparsePathBits = 256
+
+// EmptySparseRoot is the hash of an empty subtree, and so the root of an
+// empty state
+var EmptySparseRoot = make([]byte, sha256.Size)
+
+// SparsePath returns the position of key in the tree
+func SparsePath(key []byte) []byte {
+	sum := sha256.Sum256(key)
+	return sum[:]
+}
+
+// SparseLeafHash hashes a leaf from its path and value
+func SparseLeafHash(path, value []byte) []byte {
+	valueHash := sha256.Sum256(value)
+	return sparseLeafHashFromValueHash(path, valueHash[:])
+}
+
+func sparseLeafHashFromValueHash(path, valueHash []byte) []byte {
+	h := sha256.New()
+	h.Write(leafPrefix)
+	h.Write(path)
+	h.Write(valueHash)
+	return h.Sum(nil)
+}
+
+// SparseInnerHash hashes an inner node from its children
+func SparseInnerHash(left, right []byte) []byte {
+	return innerHash(left, right)
+}
+
+// SparseBit returns the bit of path at depth, counting from the root
+func SparseBit(path []byte, depth int) int {
+	return int(path[depth/8]>>(7-uint(depth%8))) & 1
+}
+
+// SparseMerkleProof proves that a key holds a value, or holds none, in a
+// sparse Merkle tree. A subtree with a single leaf is stored as that leaf,
+// so paths end as soon as they reach a leaf or an empty subtree.
+type SparseMerkleProof struct {
+	// SideNodes are the sibling hashes from the root down to the leaf
+	SideNodes []HexBytes `json:"side_nodes"`
+	// For a key without a value, the leaf found where its path ends, if any
+	OtherLeafPath      HexBytes `json:"other_leaf_path,omitempty"`
+	OtherLeafValueHash HexBytes `json:"other_leaf_value_hash,omitempty"`
+}
+
+// VerifyMembership checks that key holds value under root
+func (p *SparseMerkleProof) VerifyMembership(root, key, value []byte) error {
+	path := SparsePath(key)
+	return p.verify(root, path, SparseLeafHash(path, value))
+}
+
+// VerifyNonMembership checks that key holds no value under root
+func (p *SparseMerkleProof) VerifyNonMembership(root, key []byte) error {
+	path := SparsePath(key)
+	if len(p.OtherLeafPath) == 0 {
+		return p.verify(root, path, EmptySparseRoot)
+	}
+	if bytes.Equal(p.OtherLeafPath, path) {
+		return fmt.Errorf("%w: the other leaf is at the key's own path", ErrInvalidProof)
+	}
+	if len(p.OtherLeafPath) != sha256.Size || len(p.OtherLeafValueHash) != sha256.Size {
+		return fmt.Errorf("%w: malformed other leaf", ErrInvalidProof)
+	}
+	// The other leaf can only sit where the key's path ends if both paths
+	// share the bits above it
+	for depth := range p.SideNodes {
+		if SparseBit(p.OtherLeafPath, depth) != SparseBit(path, depth) {
+			return fmt.Errorf("%w: the other leaf is not on the key's path", ErrInvalidProof)
+		}
+	}
+	return p.verify(root, path, sparseLeafHashFromValueHash(p.OtherLeafPath, p.OtherLeafValueHash))
+}
+
+func (p *SparseMerkleProof) verify(root, path, leaf []byte) error {
+	if len(p.SideNodes) > SparsePathBits {
+		return fmt.Errorf("%w: %d side nodes", ErrInvalidProof, len(p.SideNodes))
+	}
+	hash := leaf
+	for depth := len(p.SideNodes) - 1; depth >= 0; depth-- {
+		side := p.SideNodes[depth]
+		if len(side) != sha256.Size {
+			return fmt.Errorf("%w: malformed side node at depth %d", ErrInvalidProof, depth)
+		}
+		if SparseBit(path, depth) == 0 {
+			hash = SparseInnerHash(hash, side)
+		} else {
+			hash = SparseInnerHash(side, hash)
+		}
+	}
+	if !bytes.Equal(hash, root) {
+		return fmt.Errorf("%w: proof leads to %X, expected %X", ErrInvalidProof, hash, root)
+	}
+	return nil
+}
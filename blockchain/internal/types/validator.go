@@ -0,0 +1,203 @@
+package types
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// AddressSize is the length of a validator address
+const AddressSize = 20
+
+// Validator is a consensus key and its voting power
+type Validator struct {
+	Address     HexBytes `json:"address"`
+	PubKey      HexBytes `json:"pub_key"`
+	VotingPower int64    `json:"voting_power"`
+}
+
+// NewValidator creates a validator whose address is derived from its key
+func NewValidator(pubKey ed25519.PublicKey, votingPower int64) *Validator {
+	return &Validator{Address: AddressFromPubKey(pubKey), PubKey: HexBytes(pubKey), VotingPower: votingPower}
+}
+
+// AddressFromPubKey returns the first 20 bytes of the key's SHA-256
+func AddressFromPubKey(pubKey ed25519.PublicKey) HexBytes {
+	sum := sha256.Sum256(pubKey)
+	return HexBytes(sum[:AddressSize])
+}
+
+func (v *Validator) bytes() []byte {
+	return append(append(append([]byte{}, v.Address...), v.PubKey...), int64Bytes(v.VotingPower)...)
+}
+
+func (v *Validator) verify(msg, sig []byte) bool {
+	return len(v.PubKey) == ed25519.PublicKeySize && ed25519.Verify(ed25519.PublicKey(v.PubKey), msg, sig)
+}
+
+// ValidatorSet is the set of validators for one height, ordered by voting
+// power and then address
+type ValidatorSet struct {
+	Validators []*Validator `json:"validators"`
+}
+
+// NewValidatorSet creates a validator set, copying and sorting vals
+func NewValidatorSet(vals []*Validator) *ValidatorSet {
+	set := &ValidatorSet{Validators: make([]*Validator, len(vals))}
+	for i, val := range vals {
+		copied := *val
+		set.Validators[i] = &copied
+	}
+	sort.Slice(set.Validators, func(i, j int) bool {
+		a, b := set.Validators[i], set.Validators[j]
+		if a.VotingPower != b.VotingPower {
+			return a.VotingPower > b.VotingPower
+		}
+		return bytes.Compare(a.Address, b.Address) < 0
+	})
+	return set
+}
+
+// Size returns the number of validators
+func (vs *ValidatorSet) Size() int { return len(vs.Validators) }
+
+// TotalVotingPower returns the sum of all voting power
+func (vs *ValidatorSet) TotalVotingPower() int64 {
+	var total int64
+	for _, val := range vs.Validators {
+		total += val.VotingPower
+	}
+	return total
+}
+
+// GetByAddress returns the index and validator with address, or -1 and nil
+func (vs *ValidatorSet) GetByAddress(address []byte) (int, *Validator) {
+	for i, val := range vs.Validators {
+		if bytes.Equal(val.Address, address) {
+			return i, val
+		}
+	}
+	return -1, nil
+}
+
+// Hash returns the Merkle root of the validators, as found in headers
+func (vs *ValidatorSet) Hash() HexBytes {
+	items := make([][]byte, len(vs.Validators))
+	for i, val := range vs.Validators {
+		items[i] = val.bytes()
+	}
+	return MerkleRoot(items)
+}
+
+// VerifyCommit checks that a commit for blockHash at height carries valid
+// signatures from more than 2/3 of the voting power. Every signature is
+// checked, so a commit with a single bad signature is rejected.
+func (vs *ValidatorSet) VerifyCommit(chainID string, blockHash []byte, height int64, commit *Commit) error {
+	return vs.verifyCommit(chainID, blockHash, height, commit, false)
+}
+
+// VerifyCommitLight is VerifyCommit but stops as soon as more than 2/3 of
+// the voting power is counted. Light clients and block sync use it.
+func (vs *ValidatorSet) VerifyCommitLight(chainID string, blockHash []byte, height int64, commit *Commit) error {
+	return vs.verifyCommit(chainID, blockHash, height, commit, true)
+}
+
+func (vs *ValidatorSet) verifyCommit(chainID string, blockHash []byte, height int64, commit *Commit, light bool) error {
+	if commit == nil {
+		return fmt.Errorf("%w: missing commit", ErrInvalidCommit)
+	}
+	if err := commit.ValidateBasic(); err != nil {
+		return err
+	}
+	if commit.Height != height {
+		return fmt.Errorf("%w: commit is for height %d, expected %d", ErrInvalidCommit, commit.Height, height)
+	}
+	if !bytes.Equal(commit.BlockHash, blockHash) {
+		return fmt.Errorf("%w: commit is for block %s, expected %s", ErrInvalidCommit, commit.BlockHash, HexBytes(blockHash))
+	}
+	if len(commit.Signatures) != vs.Size() {
+		return fmt.Errorf("%w: %d signatures for %d validators", ErrInvalidCommit, len(commit.Signatures), vs.Size())
+	}
+
+	needed := vs.TotalVotingPower() * 2 / 3
+	var tallied int64
+	for i, sig := range commit.Signatures {
+		if sig.Absent() {
+			continue
+		}
+		val := vs.Validators[i]
+		if !bytes.Equal(sig.ValidatorAddress, val.Address) {
+			return fmt.Errorf("%w: signature %d is from %s, expected %s", ErrInvalidCommit, i, sig.ValidatorAddress, val.Address)
+		}
+		if !val.verify(commit.VoteSignBytes(chainID, i), sig.Signature) {
+			return fmt.Errorf("%w: bad signature from %s", ErrInvalidCommit, val.Address)
+		}
+		tallied += val.VotingPower
+		if light && tallied > needed {
+			return nil
+		}
+	}
+	if tallied <= needed {
+		return fmt.Errorf("%w: signed voting power %d does not exceed 2/3 of %d", ErrInvalidCommit, tallied, vs.TotalVotingPower())
+	}
+	return nil
+}
+
+// ErrNotEnoughVotingPower is returned when the signers of a commit hold too
+// little of a validator set's voting power
+var ErrNotEnoughVotingPower = errors.New("not enough voting power signed")
+
+// Fraction is a share of the total voting power
+type Fraction struct {
+	Numerator   int64 `json:"numerator"`
+	Denominator int64 `json:"denominator"`
+}
+
+// String returns the fraction as "n/d"
+func (f Fraction) String() string { return fmt.Sprintf("%d/%d", f.Numerator, f.Denominator) }
+
+// VerifyCommitLightTrusting checks that validators of this set holding more
+// than trustLevel of its voting power signed the commit. The commit may come
+// from a different validator set, so signatures are matched by address.
+// Light clients use it to trust a header many heights past the last one
+// they verified.
+func (vs *ValidatorSet) VerifyCommitLightTrusting(chainID string, commit *Commit, trustLevel Fraction) error {
+	if commit == nil {
+		return fmt.Errorf("%w: missing commit", ErrInvalidCommit)
+	}
+	if trustLevel.Denominator <= 0 || trustLevel.Numerator <= 0 || trustLevel.Numerator > trustLevel.Denominator {
+		return fmt.Errorf("invalid trust level %s", trustLevel)
+	}
+	if err := commit.ValidateBasic(); err != nil {
+		return err
+	}
+
+	// Compare without dividing so large voting powers do not lose precision
+	total := vs.TotalVotingPower()
+	var tallied int64
+	seen := make(map[int]bool, len(commit.Signatures))
+	for i, sig := range commit.Signatures {
+		if sig.Absent() {
+			continue
+		}
+		idx, val := vs.GetByAddress(sig.ValidatorAddress)
+		if val == nil {
+			continue
+		}
+		if seen[idx] {
+			return fmt.Errorf("%w: double vote from %s", ErrInvalidCommit, val.Address)
+		}
+		seen[idx] = true
+		if !val.verify(commit.VoteSignBytes(chainID, i), sig.Signature) {
+			return fmt.Errorf("%w: bad signature from %s", ErrInvalidCommit, val.Address)
+		}
+		tallied += val.VotingPower
+		if tallied*trustLevel.Denominator > total*trustLevel.Numerator {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %d of %d trusted voting power, need more than %s", ErrNotEnoughVotingPower, tallied, total, trustLevel)
+}
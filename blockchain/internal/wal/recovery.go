@@ -0,0 +1,85 @@
+package wal
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/state"
+)
+
+// Application is the state that recovery rolls back
+type Application interface {
+	LastCommitID() state.CommitID
+	Rollback(version int64) error
+}
+
+// BlockStore is the block store recovery keeps in step with the state
+type BlockStore interface {
+	// Height returns the height of the last stored block
+	Height() int64
+	// RollbackBlocks deletes the blocks stored above height
+	RollbackBlocks(height int64) error
+}
+
+// Recovery describes what Recover found
+type Recovery struct {
+	// Height is the last completely applied height
+	Height int64
+	// PartialHeight is the height that began but did not end, or 0
+	PartialHeight int64
+	// RolledBack is set when the state of PartialHeight was committed and
+	// has been rolled back
+	RolledBack bool
+	// BlocksRolledBack is set when PartialHeight had been stored in the
+	// block store and has been deleted from it
+	BlocksRolledBack bool
+}
+
+// Recover detects a block that began applying before a crash but did not
+// end, and rolls the application state and the block store back to the
+// last completely applied height so the block is fetched and applied
+// again. It must run before the node starts applying blocks. blocks may be
+// nil for tools that only open the state.
+func Recover(w *WAL, app Application, blocks BlockStore, logger *zap.Logger) (*Recovery, error) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	version := app.LastCommitID().Version
+	last := w.LastBoundary()
+	if last == nil || last.Type != RecordBeginBlock {
+		// A clean shutdown, or a log that predates the state
+		return &Recovery{Height: version}, nil
+	}
+
+	rec := &Recovery{Height: last.Height - 1, PartialHeight: last.Height}
+	logger.Warn("Found a partially applied block in the write-ahead log",
+		zap.Int64("height", last.Height),
+		zap.Int64("state_version", version),
+	)
+	switch {
+	case version < rec.Height:
+		return nil, fmt.Errorf("state is at version %d, behind the last applied height %d; restore it from a snapshot", version, rec.Height)
+	case version > rec.Height:
+		if err := app.Rollback(rec.Height); err != nil {
+			return nil, err
+		}
+		rec.RolledBack = true
+	}
+	if blocks != nil {
+		switch stored := blocks.Height(); {
+		case stored > rec.PartialHeight:
+			return nil, fmt.Errorf("block store is at height %d, past the partially applied height %d", stored, rec.PartialHeight)
+		case stored > rec.Height:
+			if err := blocks.RollbackBlocks(rec.Height); err != nil {
+				return nil, fmt.Errorf("failed to roll back the block store to height %d: %w", rec.Height, err)
+			}
+			rec.BlocksRolledBack = true
+		}
+	}
+	if err := w.WriteSync(&Record{Type: RecordRollback, Height: rec.Height}); err != nil {
+		return nil, err
+	}
+	logger.Info("Recovered to the last committed height", zap.Int64("height", rec.Height))
+	return rec, nil
+}
@@ -0,0 +1,151 @@
+package wal
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/vindexchain/blockchain/internal/kv"
+	"github.com/vindexchain/blockchain/internal/state"
+)
+
+// fakeBlocks is a block store holding the blocks up to height
+type fakeBlocks struct {
+	height int64
+}
+
+func (b *fakeBlocks) Height() int64 { return b.height }
+
+func (b *fakeBlocks) RollbackBlocks(height int64) error {
+	b.height = height
+	return nil
+}
+
+func TestRecover(t *testing.T) {
+	tests := []struct {
+		name     string
+		records  func(w *WAL) error
+		versions int64 // state versions committed
+		blocks   int64 // blocks stored; -1 recovers without a block store
+		want     Recovery
+		wantErr  bool
+	}{
+		{
+			name:     "empty log",
+			records:  func(w *WAL) error { return nil },
+			versions: 3,
+			blocks:   3,
+			want:     Recovery{Height: 3},
+		},
+		{
+			name:     "clean shutdown",
+			records:  func(w *WAL) error { return w.EndBlock(3, nil) },
+			versions: 3,
+			blocks:   3,
+			want:     Recovery{Height: 3},
+		},
+		{
+			name:     "crash before the state commit",
+			records:  func(w *WAL) error { return w.BeginBlock(4) },
+			versions: 3,
+			blocks:   3,
+			want:     Recovery{Height: 3, PartialHeight: 4},
+		},
+		{
+			name:     "crash after the state commit",
+			records:  func(w *WAL) error { return w.BeginBlock(4) },
+			versions: 4,
+			blocks:   3,
+			want:     Recovery{Height: 3, PartialHeight: 4, RolledBack: true},
+		},
+		{
+			name:     "crash after the block was stored",
+			records:  func(w *WAL) error { return w.BeginBlock(4) },
+			versions: 4,
+			blocks:   4,
+			want:     Recovery{Height: 3, PartialHeight: 4, RolledBack: true, BlocksRolledBack: true},
+		},
+		{
+			name:     "without a block store",
+			records:  func(w *WAL) error { return w.BeginBlock(4) },
+			versions: 4,
+			blocks:   -1,
+			want:     Recovery{Height: 3, PartialHeight: 4, RolledBack: true},
+		},
+		{
+			name:     "state behind the log",
+			records:  func(w *WAL) error { return w.BeginBlock(4) },
+			versions: 2,
+			blocks:   3,
+			wantErr:  true,
+		},
+		{
+			name:     "block store past the partial height",
+			records:  func(w *WAL) error { return w.BeginBlock(4) },
+			versions: 4,
+			blocks:   5,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "wal")
+			w, err := Open(&Config{Path: path})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+			if err := tt.records(w); err != nil {
+				t.Fatal(err)
+			}
+
+			app, err := state.NewStore(&state.Config{DB: kv.NewMemDB()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for v := int64(1); v <= tt.versions; v++ {
+				if err := app.Set([]byte("height"), []byte{byte(v)}); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := app.Commit(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var blocks BlockStore
+			store := &fakeBlocks{height: tt.blocks}
+			if tt.blocks >= 0 {
+				blocks = store
+			}
+
+			rec, err := Recover(w, app, blocks, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Recover = %+v; want an error", rec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *rec != tt.want {
+				t.Fatalf("Recover = %+v; want %+v", *rec, tt.want)
+			}
+			if got := app.LastCommitID().Version; got != tt.want.Height {
+				t.Errorf("state at version %d; want %d", got, tt.want.Height)
+			}
+			if tt.blocks >= 0 && store.height > tt.want.Height {
+				t.Errorf("block store at height %d; want at most %d", store.height, tt.want.Height)
+			}
+
+			// Recovery ends the partial height, so a restart finds nothing to do
+			if tt.want.PartialHeight > 0 {
+				if last := w.LastBoundary(); last.Type != RecordRollback || last.Height != tt.want.Height {
+					t.Errorf("last boundary = %+v; want a rollback to %d", last, tt.want.Height)
+				}
+				again, err := Recover(w, app, blocks, nil)
+				if err != nil || *again != (Recovery{Height: tt.want.Height}) {
+					t.Errorf("second Recover = %+v, %v", again, err)
+				}
+			}
+		})
+	}
+}
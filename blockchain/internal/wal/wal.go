@@ -0,0 +1,340 @@
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vindexchain/blockchain/internal/types"
+)
+
+var (
+	// ErrClosed is returned for writes to a closed log
+	ErrClosed = errors.New("write-ahead log is closed")
+	// ErrRecordTooLarge is returned for records over maxRecordSize
+	ErrRecordTooLarge = errors.New("write-ahead log record is too large")
+)
+
+const (
+	// DefaultMaxSize is the size above which the log is compacted when a
+	// block ends
+	DefaultMaxSize = 64 << 20
+
+	maxRecordSize = 4 << 20
+	headerSize    = 8 // CRC-32C and length of the payload
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// RecordType tells records apart
+type RecordType string
+
+// Record types
+const (
+	// RecordBeginBlock is written before the state of a block is committed
+	RecordBeginBlock RecordType = "begin_block"
+	// RecordEndBlock is written once a block is completely applied
+	RecordEndBlock RecordType = "end_block"
+	// RecordMessage holds a consensus message, such as a proposal or vote,
+	// to replay when restarting in the middle of a height
+	RecordMessage RecordType = "message"
+	// RecordRollback is written after recovery rolled the state back to
+	// Height, which counts as the end of that height
+	RecordRollback RecordType = "rollback"
+)
+
+// Record is an entry of the log
+type Record struct {
+	Type    RecordType      `json:"type"`
+	Height  int64           `json:"height"`
+	AppHash types.HexBytes  `json:"app_hash,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Time    time.Time       `json:"time"`
+}
+
+// Config configures the log
+type Config struct {
+	Path    string
+	MaxSize int64 // DefaultMaxSize when 0
+	Logger  *zap.Logger
+}
+
+// WAL is the write-ahead log of block application and consensus. Records
+// are framed with a CRC-32C, so a record torn by a crash is detected and
+// dropped on open. Records up to the last ended height are discarded when
+// the log grows over its maximum size.
+type WAL struct {
+	path    string
+	maxSize int64
+	logger  *zap.Logger
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+	// last is the last block boundary: the end of a block, or the begin
+	// of one that has not ended
+	last *Record
+}
+
+// Open opens or creates the log, truncating a torn record at its end
+func Open(cfg *Config) (*WAL, error) {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	maxSize := cfg.MaxSize
+	if maxSize == 0 {
+		maxSize = DefaultMaxSize
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(cfg.Path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	w := &WAL{path: cfg.Path, maxSize: maxSize, logger: logger, file: file}
+
+	end, err := readRecords(file, func(rec *Record) {
+		if rec.Type != RecordMessage {
+			w.last = rec
+		}
+	})
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if end < info.Size() {
+		logger.Warn("Truncating torn record at the end of the write-ahead log",
+			zap.String("path", cfg.Path),
+			zap.Int64("offset", end),
+			zap.Int64("size", info.Size()),
+		)
+		if err := file.Truncate(end); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	if _, err := file.Seek(end, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	w.size = end
+	return w, nil
+}
+
+// LastBoundary returns the last begin_block, end_block or rollback record,
+// or nil for an empty log
+func (w *WAL) LastBoundary() *Record {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.last
+}
+
+// BeginBlock records that the state of height is about to be committed
+func (w *WAL) BeginBlock(height int64) error {
+	return w.WriteSync(&Record{Type: RecordBeginBlock, Height: height})
+}
+
+// EndBlock records that height was completely applied, and compacts the
+// log if it grew over its maximum size
+func (w *WAL) EndBlock(height int64, appHash []byte) error {
+	rec := &Record{Type: RecordEndBlock, Height: height, AppHash: appHash}
+	if err := w.WriteSync(rec); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.size <= w.maxSize {
+		return nil
+	}
+	return w.compactLocked(rec)
+}
+
+// Write appends a record without waiting for it to reach the disk
+func (w *WAL) Write(rec *Record) error {
+	return w.write(rec, false)
+}
+
+// WriteSync appends a record and syncs the log, for records that must
+// survive a crash, like our own votes before they are sent
+func (w *WAL) WriteSync(rec *Record) error {
+	return w.write(rec, true)
+}
+
+func (w *WAL) write(rec *Record, sync bool) error {
+	if rec.Time.IsZero() {
+		rec.Time = time.Now().UTC()
+	}
+	frame, err := encode(rec)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return ErrClosed
+	}
+	if _, err := w.file.Write(frame); err != nil {
+		// Drop a partly written frame so later records stay readable
+		if w.file.Truncate(w.size) == nil {
+			w.file.Seek(w.size, io.SeekStart)
+		}
+		return err
+	}
+	w.size += int64(len(frame))
+	if sync {
+		if err := w.file.Sync(); err != nil {
+			return err
+		}
+	}
+	if rec.Type != RecordMessage {
+		w.last = rec
+	}
+	return nil
+}
+
+// Messages returns the consensus messages written since the last block
+// boundary, for consensus to replay the height it was in
+func (w *WAL) Messages() ([]*Record, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil, ErrClosed
+	}
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	var messages []*Record
+	_, err := readRecords(w.file, func(rec *Record) {
+		if rec.Type == RecordMessage {
+			messages = append(messages, rec)
+		} else {
+			messages = messages[:0]
+		}
+	})
+	if _, seekErr := w.file.Seek(w.size, io.SeekStart); err == nil {
+		err = seekErr
+	}
+	return messages, err
+}
+
+// Close syncs and closes the log
+func (w *WAL) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Sync()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	w.file = nil
+	return err
+}
+
+// compactLocked replaces the log with one holding only rec, the end of
+// the last block, which is all recovery needs
+func (w *WAL) compactLocked(rec *Record) error {
+	frame, err := encode(rec)
+	if err != nil {
+		return err
+	}
+	tmp := w.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(frame); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := os.Rename(tmp, w.path); err != nil {
+		f.Close()
+		return err
+	}
+	w.file.Close()
+	w.file, w.size = f, int64(len(frame))
+	w.logger.Debug("Compacted write-ahead log", zap.Int64("height", rec.Height))
+	return syncDir(filepath.Dir(w.path))
+}
+
+// syncDir makes a rename in dir durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func encode(rec *Record) ([]byte, error) {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) > maxRecordSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrRecordTooLarge, len(payload))
+	}
+	frame := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint32(frame[0:4], crc32.Checksum(payload, crcTable))
+	binary.BigEndian.PutUint32(frame[4:8], uint32(len(payload)))
+	copy(frame[headerSize:], payload)
+	return frame, nil
+}
+
+// readRecords reads records from the current offset of f until the end or
+// the first torn or corrupt record, and returns the offset after the last
+// good one
+func readRecords(f *os.File, fn func(*Record)) (int64, error) {
+	offset, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	r := bufio.NewReader(f)
+	header := make([]byte, headerSize)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return offset, nil
+		}
+		length := binary.BigEndian.Uint32(header[4:8])
+		if length > maxRecordSize {
+			return offset, nil
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return offset, nil
+		}
+		if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(header[0:4]) {
+			return offset, nil
+		}
+		var rec Record
+		if err := json.Unmarshal(payload, &rec); err != nil {
+			return offset, nil
+		}
+		fn(&rec)
+		offset += headerSize + int64(length)
+	}
+}
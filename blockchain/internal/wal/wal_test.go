@@ -0,0 +1,154 @@
+package wal
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func openTest(t *testing.T, path string, maxSize int64) *WAL {
+	t.Helper()
+	w, err := Open(&Config{Path: path, MaxSize: maxSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func message(height int64, data string) *Record {
+	raw, _ := json.Marshal(data)
+	return &Record{Type: RecordMessage, Height: height, Data: raw}
+}
+
+func TestWALReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal")
+	w := openTest(t, path, 0)
+	if last := w.LastBoundary(); last != nil {
+		t.Fatalf("new log has boundary %+v", last)
+	}
+	for _, step := range []func() error{
+		func() error { return w.BeginBlock(1) },
+		func() error { return w.EndBlock(1, []byte{1}) },
+		func() error { return w.Write(message(2, "proposal")) },
+		func() error { return w.BeginBlock(2) },
+		func() error { return w.WriteSync(message(2, "vote")) },
+	} {
+		if err := step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(message(2, "late")); !errors.Is(err, ErrClosed) {
+		t.Errorf("write after close = %v; want ErrClosed", err)
+	}
+
+	w = openTest(t, path, 0)
+	defer w.Close()
+	if last := w.LastBoundary(); last.Type != RecordBeginBlock || last.Height != 2 {
+		t.Errorf("last boundary = %+v; want begin_block 2", last)
+	}
+	messages, err := w.Messages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 || string(messages[0].Data) != `"vote"` {
+		t.Errorf("messages since the boundary = %+v; want the vote", messages)
+	}
+}
+
+func TestWALTornRecord(t *testing.T) {
+	tests := []struct {
+		name string
+		tear func(data []byte) []byte
+		want int64 // height of the last boundary left
+	}{
+		{"truncated payload", func(data []byte) []byte { return data[:len(data)-3] }, 1},
+		{"partial header after the last record", func(data []byte) []byte { return append(data, 0, 0, 0) }, 2},
+		{"corrupt payload", func(data []byte) []byte { data[len(data)-2] ^= 0xff; return data }, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "wal")
+			w := openTest(t, path, 0)
+			if err := w.EndBlock(1, nil); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.BeginBlock(2); err != nil {
+				t.Fatal(err)
+			}
+			w.Close()
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, tt.tear(data), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			w = openTest(t, path, 0)
+			defer w.Close()
+			want := tt.want
+			if last := w.LastBoundary(); last == nil || last.Height != want {
+				t.Fatalf("last boundary = %+v; want height %d", last, want)
+			}
+
+			// The log stays appendable after the torn record is dropped
+			if err := w.EndBlock(want+1, nil); err != nil {
+				t.Fatal(err)
+			}
+			w.Close()
+			w = openTest(t, path, 0)
+			defer w.Close()
+			if last := w.LastBoundary(); last.Type != RecordEndBlock || last.Height != want+1 {
+				t.Errorf("after append, last boundary = %+v", last)
+			}
+		})
+	}
+}
+
+func TestWALCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal")
+	w := openTest(t, path, 512)
+	for h := int64(1); h <= 20; h++ {
+		if err := w.BeginBlock(h); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write(message(h, "vote")); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.EndBlock(h, []byte{byte(h)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() > 512 {
+		t.Errorf("log is %d bytes; want it compacted below 512", info.Size())
+	}
+	w = openTest(t, path, 512)
+	defer w.Close()
+	if last := w.LastBoundary(); last.Type != RecordEndBlock || last.Height != 20 {
+		t.Errorf("last boundary after compaction = %+v; want end_block 20", last)
+	}
+}
+
+func TestRecordTooLarge(t *testing.T) {
+	w := openTest(t, filepath.Join(t.TempDir(), "wal"), 0)
+	defer w.Close()
+	big := make([]byte, maxRecordSize)
+	for i := range big {
+		big[i] = 'a'
+	}
+	if err := w.Write(message(1, string(big))); !errors.Is(err, ErrRecordTooLarge) {
+		t.Errorf("oversized record = %v; want ErrRecordTooLarge", err)
+	}
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -10,15 +12,19 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/vindexchain/blockchain/internal/api"
+	"github.com/vindexchain/blockchain/internal/auth"
+	"github.com/vindexchain/blockchain/internal/bank"
 	"github.com/vindexchain/blockchain/internal/blockchain"
 	"github.com/vindexchain/blockchain/internal/config"
 	"github.com/vindexchain/blockchain/internal/consensus"
 	"github.com/vindexchain/blockchain/internal/database"
+	"github.com/vindexchain/blockchain/internal/kv"
 	"github.com/vindexchain/blockchain/internal/p2p"
 	"github.com/vindexchain/blockchain/internal/staking"
+	"github.com/vindexchain/blockchain/internal/state"
+	"github.com/vindexchain/blockchain/internal/tlsutil"
 	"github.com/vindexchain/blockchain/internal/tokens"
 	"github.com/vindexchain/blockchain/internal/websocket"
 )
@@ -38,13 +44,43 @@ const (
 func main() {
 	// Load configuration
 	cfg := config.LoadConfig()
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	
+	// Open the authenticated application state
+	pruning, err := state.NewPruningOptions(cfg.Pruning, cfg.PruningKeepRecent, cfg.PruningInterval)
+	if err != nil {
+		log.Fatalf("Invalid pruning options: %v", err)
+	}
+	stateDB, err := kv.NewBoltDB(cfg.StateDBPath)
+	if err != nil {
+		log.Fatalf("Failed to open state database: %v", err)
+	}
+	appState, err := state.NewStore(&state.Config{DB: stateDB, Pruning: pruning})
+	if err != nil {
+		log.Fatalf("Failed to open state store: %v", err)
+	}
+	defer appState.Close()
 	
-	// Initialize database
+	// Initialize the indexer database
 	db, err := database.Initialize(cfg.DatabaseURL)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer db.Close()
+	if migrator, err := database.NewMigrator(db, nil); err == nil {
+		if cfg.AutoMigrate {
+			_, err = migrator.Up(context.Background(), 0)
+		} else {
+			err = migrator.Verify(context.Background(), true)
+		}
+		if err != nil {
+			log.Fatalf("Database schema is not ready: %v", err)
+		}
+	} else if !errors.Is(err, database.ErrNoSQL) {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
 
 	// Initialize blockchain core
 	bc := blockchain.NewBlockchain(&blockchain.Config{
@@ -54,6 +90,7 @@ func main() {
 		InitialSupply:   InitialSupply,
 		BlockTime:       BlockTime,
 		Database:        db,
+		State:           appState,
 	})
 
 	// Initialize consensus engine
@@ -67,20 +104,73 @@ func main() {
 	// Initialize staking module
 	stakingModule := staking.NewStakingModule(bc, consensus)
 
+	// Initialize ledger
+	bankKeeper := bank.NewKeeper(nil)
+
 	// Initialize token factory
-	tokenFactory := tokens.NewTokenFactory(bc, &tokens.Config{
+	tokenFactory := tokens.NewTokenFactory(bankKeeper, &tokens.Config{
 		CreationFee:     100000000, // $100 in OC$ (6 decimals)
 		LiquidityShare:  50,        // 50% to liquidity
 		ValidatorShare:  25,        // 25% to validators
 		DevTeamShare:    25,        // 25% to dev team
+		FeeDenom:        NativeDenom,
 	})
 
+	// TLS certificates, reloaded from disk when they are rotated
+	var tlsCerts *tlsutil.Reloader
+	var apiTLS, p2pTLSServer, p2pTLSClient *tls.Config
+	if cfg.TLSEnable || cfg.P2PTLS {
+		tlsCerts, err = tlsutil.NewReloader(&tlsutil.Config{
+			CertFile:     cfg.TLSCertFile,
+			KeyFile:      cfg.TLSKeyFile,
+			ClientCAFile: cfg.TLSClientCAFile,
+		})
+		if err != nil {
+			log.Fatalf("Failed to load TLS certificate: %v", err)
+		}
+	}
+	if cfg.TLSEnable {
+		clientAuth := tls.NoClientCert
+		if cfg.TLSClientCAFile != "" {
+			clientAuth = tls.VerifyClientCertIfGiven
+		}
+		if apiTLS, err = tlsCerts.ServerConfig(clientAuth); err != nil {
+			log.Fatalf("Invalid TLS configuration: %v", err)
+		}
+	}
+	if cfg.P2PTLS {
+		if p2pTLSServer, err = tlsCerts.ServerConfig(tls.RequireAndVerifyClientCert); err != nil {
+			log.Fatalf("Invalid P2P TLS configuration: %v", err)
+		}
+		if p2pTLSClient, err = tlsCerts.ClientConfig(); err != nil {
+			log.Fatalf("Invalid P2P TLS configuration: %v", err)
+		}
+	}
+
 	// Initialize P2P network
+	nodeKey, err := p2p.LoadOrGenNodeKey(cfg.NodeKeyFile)
+	if err != nil {
+		log.Fatalf("Failed to load node key: %v", err)
+	}
 	p2pNode := p2p.NewNode(&p2p.Config{
-		ListenAddr: cfg.P2PListenAddr,
-		ChainID:    ChainID,
-		Blockchain: bc,
-		Consensus:  consensus,
+		ListenAddr:      cfg.P2PListenAddr,
+		ExternalAddress: cfg.P2PExternalAddress,
+		ChainID:         ChainID,
+		Moniker:         cfg.Moniker,
+		Version:         "1.0.0",
+		NodeKey:         nodeKey,
+		PersistentPeers: cfg.PersistentPeers,
+		Seeds:           cfg.Seeds,
+		SeedMode:        cfg.SeedMode,
+		AddrBookFile:    cfg.AddrBookFile,
+
+		MaxNumInboundPeers:      cfg.MaxNumInboundPeers,
+		MaxNumOutboundPeers:     cfg.MaxNumOutboundPeers,
+		RateLimit:               p2p.RateLimit{SendRate: cfg.P2PSendRate, RecvRate: cfg.P2PRecvRate},
+		BanDuration:             cfg.P2PBanDuration,
+		MaxConcurrentHandshakes: cfg.P2PMaxHandshakes,
+		TLSServerConfig:         p2pTLSServer,
+		TLSClientConfig:         p2pTLSClient,
 	})
 
 	// Initialize WebSocket server
@@ -106,7 +196,13 @@ func main() {
 	}()
 
 	go func() {
-		if err := wsServer.Start(cfg.WSListenAddr); err != nil {
+		var err error
+		if apiTLS != nil {
+			err = wsServer.StartTLS(cfg.WSListenAddr, apiTLS)
+		} else {
+			err = wsServer.Start(cfg.WSListenAddr)
+		}
+		if err != nil {
 			log.Fatalf("Failed to start WebSocket server: %v", err)
 		}
 	}()
@@ -117,11 +213,27 @@ func main() {
 	router.Use(gin.Recovery())
 
 	// CORS configuration
-	corsConfig := cors.DefaultConfig()
-	corsConfig.AllowAllOrigins = true
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
-	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	router.Use(cors.New(corsConfig))
+	corsPolicies, err := api.ParseCORSPolicies(&api.CORSConfig{
+		Origins:      cfg.CORSAllowedOrigins,
+		Methods:      cfg.CORSAllowedMethods,
+		Headers:      cfg.CORSAllowedHeaders,
+		Credentials:  cfg.CORSAllowCredentials,
+		MaxAge:       cfg.CORSMaxAge,
+		RouteOrigins: cfg.CORSRouteOrigins,
+	})
+	if err != nil {
+		log.Fatalf("Invalid CORS configuration: %v", err)
+	}
+	for _, policy := range corsPolicies {
+		if policy.ExposesWrites() {
+			log.Printf("Warning: CORS allows any origin to call write routes under %s/*; set VINDEX_CORS_ALLOWED_ORIGINS", policy.Prefix)
+		}
+	}
+	corsHandler, err := api.CORS(corsPolicies)
+	if err != nil {
+		log.Fatalf("Invalid CORS configuration: %v", err)
+	}
+	router.Use(corsHandler)
 
 	// Initialize API handlers
 	apiHandler := api.NewHandler(&api.Config{
@@ -132,6 +244,18 @@ func main() {
 		P2PNode:        p2pNode,
 	})
 
+	// Server-side signing routes need a JWT or API key with the broadcast scope
+	apiKeys, err := auth.OpenKeyStore(cfg.APIKeysFile)
+	if err != nil {
+		log.Fatalf("Failed to load API keys: %v", err)
+	}
+	router.Use(api.Authenticate(auth.NewAuthenticator(&auth.Config{
+		JWTSecret: cfg.JWTSecret,
+		Keys:      apiKeys,
+		AdminKey:  cfg.AdminAPIKey,
+	})))
+	signing := api.RequireScope(auth.ScopeBroadcast)
+
 	// Register API routes
 	v1 := router.Group("/api/v1")
 	{
@@ -155,18 +279,18 @@ func main() {
 		v1.GET("/staking/validators", apiHandler.GetValidators)
 		v1.GET("/staking/validators/:address", apiHandler.GetValidator)
 		v1.GET("/staking/delegations/:address", apiHandler.GetDelegations)
-		v1.POST("/staking/delegate", apiHandler.Delegate)
-		v1.POST("/staking/undelegate", apiHandler.Undelegate)
+		v1.POST("/staking/delegate", signing, apiHandler.Delegate)
+		v1.POST("/staking/undelegate", signing, apiHandler.Undelegate)
 		
 		// Token endpoints
 		v1.GET("/tokens", apiHandler.GetTokens)
 		v1.GET("/tokens/:denom", apiHandler.GetToken)
-		v1.POST("/tokens/create", apiHandler.CreateToken)
+		v1.POST("/tokens/create", signing, apiHandler.CreateToken)
 		
 		// Domain endpoints
 		v1.GET("/domains", apiHandler.GetDomains)
 		v1.GET("/domains/:name", apiHandler.GetDomain)
-		v1.POST("/domains/register", apiHandler.RegisterDomain)
+		v1.POST("/domains/register", signing, apiHandler.RegisterDomain)
 		
 		// Statistics endpoints
 		v1.GET("/stats/supply", apiHandler.GetSupplyStats)
@@ -186,13 +310,20 @@ func main() {
 
 	// Start HTTP server
 	server := &http.Server{
-		Addr:    cfg.HTTPListenAddr,
-		Handler: router,
+		Addr:      cfg.HTTPListenAddr,
+		Handler:   router,
+		TLSConfig: apiTLS,
 	}
 
 	go func() {
-		log.Printf("Starting VindexChain HTTP API server on %s", cfg.HTTPListenAddr)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf("Starting VindexChain HTTP API server on %s (tls=%t)", cfg.HTTPListenAddr, apiTLS != nil)
+		var err error
+		if apiTLS != nil {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Failed to start HTTP server: %v", err)
 		}
 	}()